@@ -0,0 +1,76 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"runtime/debug"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/semantrix/semaroute/internal/server"
+)
+
+// Budgets of the edge profile, checked against config.edge.yaml. The
+// binary's own budget is checked by scripts/edge-check.sh; these bound the
+// part under semaroute's control, from loading the config until /health
+// answers, in-process.
+const (
+	maxEdgeStartup  = 250 * time.Millisecond
+	maxEdgeHeapUsed = 4 << 20 // heap held by the server once built
+)
+
+func TestEdgeProfileBudget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("measures startup time and heap")
+	}
+	t.Setenv("OPENAI_API_KEY", "edge-check")
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	t.Cleanup(func() { debug.SetMemoryLimit(math.MaxInt64) })
+
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	start := time.Now()
+	config, err := loadConfig("../../config.edge.yaml", "edge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := server.NewServer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	srv.GetRouter().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+	startup := time.Since(start)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("/health = %d: %s", recorder.Code, recorder.Body)
+	}
+
+	runtime.GC()
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	heapUsed := int64(after.HeapAlloc) - int64(before.HeapAlloc)
+	runtime.KeepAlive(srv)
+
+	t.Logf("startup %v (budget %v), heap %d KiB (budget %d KiB)", startup, maxEdgeStartup, heapUsed>>10, maxEdgeHeapUsed>>10)
+	if startup > maxEdgeStartup {
+		t.Errorf("startup took %v, over the edge budget of %v", startup, maxEdgeStartup)
+	}
+	if heapUsed > maxEdgeHeapUsed {
+		t.Errorf("server holds %d KiB of heap, over the edge budget of %d KiB", heapUsed>>10, maxEdgeHeapUsed>>10)
+	}
+
+	// The profile keeps the heavy subsystems off
+	if config.Observability.Tracing.Enabled || config.Usage.Enabled || config.Audit.Enabled || config.Cache.Warm.Enabled {
+		t.Errorf("edge profile left tracing, usage, audit or cache warming on")
+	}
+	if config.RoutingPolicy.Type == "semantic" {
+		t.Errorf("edge configuration routes with the semantic policy, which embeds every prompt")
+	}
+}
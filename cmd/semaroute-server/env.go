@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// envReference matches a ${NAME} reference to an environment variable in a
+// config value. Other uses of $ are left alone, so secrets containing one
+// need no escaping.
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces the ${NAME} references in s with the values of the
+// environment variables. A reference to an unset variable is an error, so a
+// missing secret fails the config load instead of becoming empty.
+func expandEnv(s string) (string, error) {
+	var unset []string
+	expanded := envReference.ReplaceAllStringFunc(s, func(reference string) string {
+		name := envReference.FindStringSubmatch(reference)[1]
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			unset = append(unset, name)
+		}
+		return value
+	})
+	if len(unset) > 0 {
+		return "", fmt.Errorf("environment variable %s referenced in the config is not set", strings.Join(unset, ", "))
+	}
+	return expanded, nil
+}
+
+// expandEnvHookFunc returns a decode hook expanding the ${NAME} references in
+// every string of the config, including those in lists and maps.
+func expandEnvHookFunc() mapstructure.DecodeHookFunc {
+	return func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+		if from.Kind() != reflect.String {
+			return data, nil
+		}
+		return expandEnv(reflect.ValueOf(data).String())
+	}
+}
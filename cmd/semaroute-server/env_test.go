@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("SEMAROUTE_TEST_KEY", "sk-test")
+	t.Setenv("SEMAROUTE_TEST_EMPTY", "")
+
+	tests := []struct {
+		in, want string
+	}{
+		{"${SEMAROUTE_TEST_KEY}", "sk-test"},
+		{"Bearer ${SEMAROUTE_TEST_KEY}!", "Bearer sk-test!"},
+		{"${SEMAROUTE_TEST_EMPTY}", ""},
+		{"pa$$word", "pa$$word"},
+		{"$SEMAROUTE_TEST_KEY", "$SEMAROUTE_TEST_KEY"},
+		{"${not a name}", "${not a name}"},
+	}
+	for _, test := range tests {
+		got, err := expandEnv(test.in)
+		if err != nil || got != test.want {
+			t.Errorf("expandEnv(%q) = %q, %v, want %q", test.in, got, err, test.want)
+		}
+	}
+}
+
+func TestExpandEnvRejectsUnsetVariables(t *testing.T) {
+	t.Setenv("SEMAROUTE_TEST_KEY", "sk-test")
+
+	_, err := expandEnv("${SEMAROUTE_TEST_KEY}:${SEMAROUTE_TEST_UNSET}")
+	if err == nil || !strings.Contains(err.Error(), "SEMAROUTE_TEST_UNSET") {
+		t.Fatalf("expandEnv() error = %v, want the unset variable named", err)
+	}
+}
+
+func TestExpandEnvHookReachesNestedValues(t *testing.T) {
+	t.Setenv("SEMAROUTE_TEST_KEY", "sk-test")
+
+	var out struct {
+		Gatekeeper struct {
+			SigningKey string `mapstructure:"signing_key"`
+		} `mapstructure:"gatekeeper"`
+		Providers []struct {
+			APIKey string `mapstructure:"api_key"`
+		} `mapstructure:"providers"`
+		Config map[string]interface{} `mapstructure:"config"`
+	}
+	in := map[string]interface{}{
+		"gatekeeper": map[string]interface{}{"signing_key": "${SEMAROUTE_TEST_KEY}"},
+		"providers":  []interface{}{map[string]interface{}{"api_key": "${SEMAROUTE_TEST_KEY}"}},
+		"config":     map[string]interface{}{"token": "${SEMAROUTE_TEST_KEY}"},
+	}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{DecodeHook: expandEnvHookFunc(), Result: &out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := decoder.Decode(in); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.Gatekeeper.SigningKey != "sk-test" || out.Providers[0].APIKey != "sk-test" || out.Config["token"] != "sk-test" {
+		t.Fatalf("decoded %+v", out)
+	}
+}
+
+func TestLoadConfigFailsOnUnsetVariable(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(config, []byte("providers:\n  openai:\n    api_key: \"${SEMAROUTE_TEST_UNSET}\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadConfig(config, ""); err == nil || !strings.Contains(err.Error(), "SEMAROUTE_TEST_UNSET") {
+		t.Fatalf("loadConfig() error = %v, want the unset variable named", err)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// includeKey lists further configuration files merged over the file that
+// names it.
+const includeKey = "include"
+
+// mergeIncludes merges the files included by a configuration file over the
+// configuration read so far into v, each followed by the files it includes in
+// turn.
+// Entries are paths or glob patterns relative to the including file, or
+// directories whose .yaml and .yml files are merged in name order. Maps merge
+// key by key; any other value, lists included, is replaced by the later file.
+// seen holds the files already read, so that each file is merged once.
+func mergeIncludes(v *viper.Viper, configFile string, includes []string, seen map[string]bool) error {
+	dir := filepath.Dir(configFile)
+	for _, entry := range includes {
+		files, err := includedFiles(dir, entry)
+		if err != nil {
+			return fmt.Errorf("%s: include %q: %w", configFile, entry, err)
+		}
+
+		for _, file := range files {
+			if seen[file] {
+				return fmt.Errorf("%s: %s is included more than once", configFile, file)
+			}
+			seen[file] = true
+
+			included := viper.New()
+			included.SetConfigFile(file)
+			included.SetConfigType("yaml")
+			if err := included.ReadInConfig(); err != nil {
+				return fmt.Errorf("%s: failed to read included file: %w", configFile, err)
+			}
+			nested := included.GetStringSlice(includeKey)
+			if err := v.MergeConfigMap(included.AllSettings()); err != nil {
+				return fmt.Errorf("failed to merge %s: %w", file, err)
+			}
+			if err := mergeIncludes(v, file, nested, seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// includedFiles returns the absolute paths of the files an include entry
+// names, in merge order. A glob pattern may match nothing; a path must exist.
+func includedFiles(dir, entry string) ([]string, error) {
+	if !filepath.IsAbs(entry) {
+		entry = filepath.Join(dir, entry)
+	}
+
+	var files []string
+	if strings.ContainsAny(entry, "*?[") {
+		matches, err := filepath.Glob(entry)
+		if err != nil {
+			return nil, err
+		}
+		files = matches
+	} else {
+		info, err := os.Stat(entry)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return absolute([]string{entry})
+		}
+		entries, err := os.ReadDir(entry)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if ext := filepath.Ext(e.Name()); !e.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				files = append(files, filepath.Join(entry, e.Name()))
+			}
+		}
+	}
+	sort.Strings(files)
+	return absolute(files)
+}
+
+// absolute makes paths absolute, so the same file included through different
+// paths is recognized.
+func absolute(paths []string) ([]string, error) {
+	for i, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		paths[i] = abs
+	}
+	return paths, nil
+}
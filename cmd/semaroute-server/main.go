@@ -4,8 +4,11 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
+	"github.com/mitchellh/mapstructure"
+	"github.com/semantrix/semaroute/internal/catalog"
 	"github.com/semantrix/semaroute/internal/server"
 	"github.com/spf13/viper"
 )
@@ -20,6 +23,7 @@ var (
 func main() {
 	// Parse command line flags
 	configFile := flag.String("config", "config.yaml", "Path to configuration file")
+	profile := flag.String("profile", "", "Configuration preset: default, dev, staging, prod-high-availability or edge (overrides the config file)")
 	showVersion := flag.Bool("version", false, "Show version information")
 	flag.Parse()
 
@@ -32,12 +36,17 @@ func main() {
 	}
 
 	// Load configuration
-	config, err := loadConfig(*configFile)
+	config, err := loadConfig(*configFile, *profile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
 		os.Exit(1)
 	}
 
+	// semaroute-server [flags] migrate [-dry-run] migrates the stores and exits
+	if flag.Arg(0) == "migrate" {
+		os.Exit(runMigrate(config, flag.Args()[1:]))
+	}
+
 	// Create server instance
 	srv, err := server.NewServer(config)
 	if err != nil {
@@ -55,8 +64,9 @@ func main() {
 	srv.WaitForShutdown()
 }
 
-// loadConfig loads configuration from file and environment variables.
-func loadConfig(configFile string) (*server.Config, error) {
+// loadConfig loads configuration from file, the files it includes and
+// environment variables, with the defaults of the selected profile.
+func loadConfig(configFile, profile string) (*server.Config, error) {
 	// Set up Viper
 	viper.SetConfigFile(configFile)
 	viper.SetConfigType("yaml")
@@ -69,35 +79,99 @@ func loadConfig(configFile string) (*server.Config, error) {
 	setDefaults()
 
 	// Read config file
+	var configPath string
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
 			return nil, fmt.Errorf("failed to read config file: %w", err)
 		}
 		// Config file not found, use defaults
 		fmt.Println("Config file not found, using defaults")
+	} else {
+		// Included files are merged over the config file, in order
+		path, err := filepath.Abs(viper.ConfigFileUsed())
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve config file: %w", err)
+		}
+		if err := mergeIncludes(viper.GetViper(), path, viper.GetStringSlice(includeKey), map[string]bool{path: true}); err != nil {
+			return nil, fmt.Errorf("failed to include config files: %w", err)
+		}
+		configPath = path
+	}
+
+	// The profile's defaults replace the general ones; values set in the
+	// config file or environment still take precedence
+	if profile != "" {
+		viper.Set("profile", profile)
+	}
+	setProfileDefaults, ok := profiles[viper.GetString("profile")]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q, expected one of: %s", viper.GetString("profile"), profileNames())
 	}
+	setProfileDefaults()
 
 	// Create config struct
 	var config server.Config
-	if err := viper.Unmarshal(&config); err != nil {
+	// ${NAME} references are replaced with environment variables first;
+	// sizes such as "100MB" decode through their UnmarshalText method
+	decodeHook := viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		expandEnvHookFunc(),
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		mapstructure.TextUnmarshallerHookFunc(),
+	))
+	if err := viper.Unmarshal(&config, decodeHook); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	// Reloading the pricing catalog re-reads its inline entries too
+	if configPath != "" {
+		config.Pricing.ReadModels = pricingModelsReader(configPath)
+	}
+
 	return &config, nil
 }
 
+// pricingModelsReader returns a function reading the inline pricing entries
+// from a config file and the files it includes, as loadConfig does.
+func pricingModelsReader(configFile string) func() ([]catalog.ModelEntry, error) {
+	return func() ([]catalog.ModelEntry, error) {
+		v := viper.New()
+		v.SetConfigFile(configFile)
+		v.SetConfigType("yaml")
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := mergeIncludes(v, configFile, v.GetStringSlice(includeKey), map[string]bool{configFile: true}); err != nil {
+			return nil, fmt.Errorf("failed to include config files: %w", err)
+		}
+
+		var models []catalog.ModelEntry
+		if err := v.UnmarshalKey("pricing.models", &models, viper.DecodeHook(expandEnvHookFunc())); err != nil {
+			return nil, fmt.Errorf("failed to parse pricing.models: %w", err)
+		}
+		return models, nil
+	}
+}
+
 // setDefaults sets sensible default values for configuration.
 func setDefaults() {
+	viper.SetDefault("profile", "default")
+
 	// Server defaults
 	viper.SetDefault("server.port", 8080)
 	viper.SetDefault("server.read_timeout", 30*time.Second)
 	viper.SetDefault("server.write_timeout", 30*time.Second)
 	viper.SetDefault("server.idle_timeout", 60*time.Second)
 	viper.SetDefault("server.shutdown_timeout", 10*time.Second)
+	viper.SetDefault("server.memory_limit", 0)
 
 	// Health check defaults
 	viper.SetDefault("health_check.interval", 30*time.Second)
 	viper.SetDefault("health_check.timeout", 10*time.Second)
+	viper.SetDefault("health_check.prewarm.enabled", false)
+	viper.SetDefault("health_check.prewarm.connections", 4)
+	viper.SetDefault("health_check.prewarm.canaries", 0)
+	viper.SetDefault("health_check.prewarm.timeout", 30*time.Second)
 
 	// Routing policy defaults
 	viper.SetDefault("routing_policy.type", "cost_based")
@@ -107,6 +181,144 @@ func setDefaults() {
 	viper.SetDefault("cache.ttl", 1*time.Hour)
 	viper.SetDefault("cache.max_size", 1000)
 	viper.SetDefault("cache.cleanup_interval", 10*time.Minute)
+	viper.SetDefault("cache.compression", "none")
+	viper.SetDefault("cache.compression_threshold", "4KB")
+	viper.SetDefault("cache.responses", false)
+	viper.SetDefault("cache.key.ignore_user", true)
+	viper.SetDefault("cache.key.ignore_request_id", true)
+	viper.SetDefault("cache.key.normalize_whitespace", false)
+	viper.SetDefault("cache.key.deterministic_only", true)
+	viper.SetDefault("cache.warm.enabled", false)
+	viper.SetDefault("cache.warm.window", 24*time.Hour)
+	viper.SetDefault("cache.warm.min_hits", 2)
+	viper.SetDefault("cache.warm.max_entries", 1000)
+
+	// Invalidation bus defaults
+	viper.SetDefault("invalidation.type", "none")
+	viper.SetDefault("invalidation.redis.channel", "semaroute:invalidation")
+
+	// Rate limit defaults
+	viper.SetDefault("rate_limit.enabled", false)
+	viper.SetDefault("rate_limit.sync_interval", 1*time.Second)
+	viper.SetDefault("rate_limit.ceiling.enabled", false)
+	viper.SetDefault("rate_limit.ceiling.requests_per_second", 0)
+	viper.SetDefault("rate_limit.ceiling.burst", 0)
+	viper.SetDefault("rate_limit.ceiling.max_streams", 0)
+	viper.SetDefault("rate_limit.ceiling.overflow", "reject")
+	viper.SetDefault("rate_limit.ceiling.queue_timeout", 5*time.Second)
+	viper.SetDefault("rate_limit.ceiling.max_queued", 0)
+	viper.SetDefault("rate_limit.priority.enabled", false)
+	viper.SetDefault("rate_limit.priority.max_in_flight", 256)
+	viper.SetDefault("rate_limit.priority.tiers.high.max_queued", 200)
+	viper.SetDefault("rate_limit.priority.tiers.high.queue_timeout", 30*time.Second)
+	viper.SetDefault("rate_limit.priority.tiers.normal.max_queued", 100)
+	viper.SetDefault("rate_limit.priority.tiers.normal.queue_timeout", 10*time.Second)
+	viper.SetDefault("rate_limit.priority.tiers.low.max_queued", 0)
+	viper.SetDefault("rate_limit.priority.tiers.low.queue_timeout", 5*time.Second)
+
+	// Tenancy defaults
+	viper.SetDefault("tenancy.require_api_key", false)
+	viper.SetDefault("tenancy.require_admin_key", false)
+	viper.SetDefault("tenancy.suspended_message", "This account is suspended.")
+	viper.SetDefault("tenancy.state_file", "")
+	viper.SetDefault("tenancy.defaults.temperature", 0.0)
+	viper.SetDefault("tenancy.defaults.max_tokens", 0)
+	viper.SetDefault("tenancy.defaults.system_preamble", "")
+
+	// Tool execution defaults
+	viper.SetDefault("tools.enabled", false)
+	viper.SetDefault("tools.default_timeout", 10*time.Second)
+	viper.SetDefault("tools.max_turns", 5)
+	viper.SetDefault("tools.max_parallel", 4)
+	viper.SetDefault("tools.fan_out_timeout", 30*time.Second)
+
+	// Gatekeeper defaults
+	viper.SetDefault("gatekeeper.enabled", false)
+	viper.SetDefault("gatekeeper.token_ttl", 5*time.Minute)
+	viper.SetDefault("gatekeeper.issuer", "semaroute")
+	viper.SetDefault("gatekeeper.redemption", "proxy")
+
+	// Continuation defaults
+	viper.SetDefault("continuation.enabled", false)
+	viper.SetDefault("continuation.max_continuations", 2)
+	viper.SetDefault("continuation.max_tokens", 0)
+
+	// Response language enforcement defaults
+	viper.SetDefault("language_enforcement.enabled", false)
+	viper.SetDefault("language_enforcement.max_retries", 1)
+	viper.SetDefault("language_enforcement.alternate", false)
+	viper.SetDefault("language_enforcement.min_letters", 20)
+
+	// Stream stall watchdog defaults
+	viper.SetDefault("streaming.stall_timeout", 20*time.Second)
+	viper.SetDefault("streaming.stall_failover", false)
+
+	// Circuit breaker defaults
+	viper.SetDefault("circuit_breaker.enabled", false)
+	viper.SetDefault("circuit_breaker.consecutive_failures", 5)
+	viper.SetDefault("circuit_breaker.error_rate", 0.5)
+	viper.SetDefault("circuit_breaker.min_requests", 20)
+	viper.SetDefault("circuit_breaker.window", 1*time.Minute)
+	viper.SetDefault("circuit_breaker.open_duration", 30*time.Second)
+	viper.SetDefault("circuit_breaker.half_open_probes", 1)
+
+	// Hedged request defaults
+	viper.SetDefault("hedging.enabled", false)
+	viper.SetDefault("hedging.delay", 2*time.Second)
+
+	// Spillover defaults
+	viper.SetDefault("spillover.enabled", false)
+	viper.SetDefault("spillover.max_spillovers", 2)
+
+	// Deferred request defaults
+	viper.SetDefault("deferral.max_wait", 0)
+	viper.SetDefault("deferral.margin", 1*time.Second)
+	viper.SetDefault("deferral.spread", 100*time.Millisecond)
+	viper.SetDefault("deferral.max_queued", 1000)
+
+	// Long-output generation defaults
+	viper.SetDefault("longform.enabled", false)
+	viper.SetDefault("longform.max_sections", 8)
+	viper.SetDefault("longform.section_max_tokens", 2048)
+	viper.SetDefault("longform.plan_max_tokens", 512)
+
+	// Shadow comparison defaults
+	viper.SetDefault("shadow.max_samples", 1000)
+	viper.SetDefault("shadow.max_concurrent", 10)
+	viper.SetDefault("shadow.timeout", 60*time.Second)
+
+	// Usage store defaults
+	viper.SetDefault("usage.enabled", false)
+	viper.SetDefault("usage.path", "data/usage.jsonl")
+	viper.SetDefault("usage.max_records", 100000)
+	viper.SetDefault("usage.rollup_days", 90)
+	viper.SetDefault("usage.logprobs.enabled", false)
+	viper.SetDefault("usage.logprobs.sample_rate", 0.1)
+	viper.SetDefault("usage.logprobs.max_tokens", 256)
+	viper.SetDefault("usage.logprobs.max_top_k", 5)
+	viper.SetDefault("usage.aborted_cost", "prorate")
+
+	// Dataset sampling defaults
+	viper.SetDefault("dataset.enabled", false)
+	viper.SetDefault("dataset.sample_rate", 0.01)
+	viper.SetDefault("dataset.buffer_size", 1000)
+	viper.SetDefault("dataset.flush_interval", 1*time.Minute)
+	viper.SetDefault("dataset.max_batch", 500)
+	viper.SetDefault("dataset.sink.type", "file")
+	viper.SetDefault("dataset.sink.directory", "data/dataset")
+	viper.SetDefault("dataset.sink.timeout", 30*time.Second)
+
+	// Audit log defaults
+	viper.SetDefault("audit.enabled", false)
+	viper.SetDefault("audit.path", "data/audit.jsonl")
+	viper.SetDefault("audit.max_entries", 10000)
+
+	// Schema migration defaults
+	viper.SetDefault("migrations.auto_apply", true)
+
+	// Alerting defaults
+	viper.SetDefault("alerting.enabled", false)
+	viper.SetDefault("alerting.interval", 30*time.Second)
 
 	// Observability defaults
 	viper.SetDefault("observability.logging.level", "info")
@@ -136,4 +348,14 @@ func setDefaults() {
 	viper.SetDefault("providers.anthropic.max_retries", 3)
 	viper.SetDefault("providers.anthropic.retry_delay", 1*time.Second)
 	viper.SetDefault("providers.anthropic.health_check_interval", 30*time.Second)
+
+	viper.SetDefault("providers.watsonx.enabled", false)
+	viper.SetDefault("providers.watsonx.timeout", 60*time.Second)
+	viper.SetDefault("providers.watsonx.max_retries", 3)
+	viper.SetDefault("providers.watsonx.retry_delay", 1*time.Second)
+	viper.SetDefault("providers.watsonx.health_check_interval", 30*time.Second)
+
+	// Plugin defaults
+	viper.SetDefault("plugins.directory", "")
+	viper.SetDefault("plugins.handshake_timeout", 10*time.Second)
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/semantrix/semaroute/internal/server"
+)
+
+// runMigrate implements the migrate command: it lists the schema version of
+// each persistent store and applies the pending migrations, unless -dry-run
+// is given. It returns the exit code.
+func runMigrate(config *server.Config, args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "List pending migrations without applying them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	stores := server.PersistentStores(config)
+	if len(stores) == 0 {
+		fmt.Println("No persistent stores are enabled")
+		return 0
+	}
+
+	for _, store := range stores {
+		version, err := store.Version()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", store.Name, err)
+			return 1
+		}
+		pending, err := store.Pending()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", store.Name, err)
+			return 1
+		}
+		fmt.Printf("%s (%s): schema version %d, latest %d, %d pending\n",
+			store.Name, store.Path, version, store.Latest(), len(pending))
+		if *dryRun {
+			for _, migration := range pending {
+				fmt.Printf("  %d: %s\n", migration.Version, migration.Description)
+			}
+			continue
+		}
+
+		applied, err := store.Migrate()
+		for _, migration := range applied {
+			fmt.Printf("  applied %d: %s\n", migration.Version, migration.Description)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", store.Name, err)
+			return 1
+		}
+	}
+	return 0
+}
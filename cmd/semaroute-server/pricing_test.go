@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPricingModelsReaderFollowsIncludesAndEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SEMAROUTE_TEST_OWNER", "acme")
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("pricing.yaml", `pricing:
+  models:
+    - {provider: "openai", model: "gpt-4o", input_per_1k: 0.005, owner: "${SEMAROUTE_TEST_OWNER}"}
+`)
+	config := write("config.yaml", `include: ["pricing.yaml"]
+pricing:
+  models:
+    - {provider: "openai", model: "gpt-4", input_per_1k: 0.03}
+`)
+
+	models, err := pricingModelsReader(config)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Lists are replaced by the file merged later
+	if len(models) != 1 || models[0].Model != "gpt-4o" || models[0].InputPer1K != 0.005 || models[0].Owner != "acme" {
+		t.Fatalf("models = %+v, want the included entry with ${NAME} expanded", models)
+	}
+
+	os.Remove(config)
+	if _, err := pricingModelsReader(config)(); err == nil {
+		t.Fatal("reading a missing config file succeeded")
+	}
+}
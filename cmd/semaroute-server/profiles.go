@@ -0,0 +1,142 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// profiles are the configuration presets selectable with -profile or the
+// profile key. Each sets coherent defaults across the server, cache,
+// observability and resilience settings on top of setDefaults; values set in
+// the config file or environment still override them.
+var profiles = map[string]func(){
+	"default":                func() {},
+	"dev":                    setDevDefaults,
+	"staging":                setStagingDefaults,
+	"prod-high-availability": setProdHighAvailabilityDefaults,
+	"edge":                   setEdgeDefaults,
+}
+
+// profileNames returns the names of the presets for error messages.
+func profileNames() string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// builtinProviders are the providers with defaults in setDefaults.
+var builtinProviders = []string{"openai", "anthropic", "watsonx"}
+
+// setDevDefaults is for running semaroute on a workstation: readable debug
+// logs on stdout, quick health checks, a small short-lived cache and providers
+// that fail fast instead of retrying.
+func setDevDefaults() {
+	viper.SetDefault("server.shutdown_timeout", 5*time.Second)
+
+	viper.SetDefault("health_check.interval", 10*time.Second)
+
+	viper.SetDefault("cache.ttl", 5*time.Minute)
+	viper.SetDefault("cache.max_size", 100)
+	viper.SetDefault("cache.cleanup_interval", 1*time.Minute)
+
+	for _, name := range builtinProviders {
+		viper.SetDefault("providers."+name+".max_retries", 1)
+		viper.SetDefault("providers."+name+".retry_delay", 200*time.Millisecond)
+	}
+
+	viper.SetDefault("observability.logging.level", "debug")
+	viper.SetDefault("observability.logging.format", "console")
+	viper.SetDefault("observability.logging.output_path", "stdout")
+	viper.SetDefault("observability.logging.error_path", "stderr")
+	viper.SetDefault("observability.logging.development", true)
+	viper.SetDefault("observability.metrics.collect_interval", 10*time.Second)
+	viper.SetDefault("observability.tracing.environment", "development")
+}
+
+// setStagingDefaults mirrors production observability so changes can be
+// verified before rollout: JSON logs on the standard streams, tracing, usage
+// and audit records, and failover of stalled streams.
+func setStagingDefaults() {
+	viper.SetDefault("health_check.interval", 15*time.Second)
+
+	viper.SetDefault("streaming.stall_failover", true)
+
+	viper.SetDefault("usage.enabled", true)
+	viper.SetDefault("audit.enabled", true)
+
+	viper.SetDefault("observability.logging.output_path", "stdout")
+	viper.SetDefault("observability.logging.error_path", "stderr")
+	viper.SetDefault("observability.tracing.enabled", true)
+	viper.SetDefault("observability.tracing.environment", "staging")
+}
+
+// setProdHighAvailabilityDefaults favours availability over spend: unhealthy
+// providers are detected quickly, failing ones are cut off by their circuit
+// breaker, recovered ones are pre-warmed before they take traffic again, slow
+// completions are hedged, stalled streams fail over and in-flight requests get
+// time to finish on shutdown.
+func setProdHighAvailabilityDefaults() {
+	viper.SetDefault("server.shutdown_timeout", 30*time.Second)
+
+	viper.SetDefault("health_check.interval", 10*time.Second)
+	viper.SetDefault("health_check.timeout", 5*time.Second)
+	viper.SetDefault("health_check.prewarm.enabled", true)
+	viper.SetDefault("health_check.prewarm.canaries", 1)
+
+	viper.SetDefault("streaming.stall_failover", true)
+	viper.SetDefault("circuit_breaker.enabled", true)
+	viper.SetDefault("hedging.enabled", true)
+	viper.SetDefault("hedging.delay", 3*time.Second)
+
+	for _, name := range builtinProviders {
+		viper.SetDefault("providers."+name+".max_retries", 2)
+		viper.SetDefault("providers."+name+".retry_delay", 500*time.Millisecond)
+	}
+
+	viper.SetDefault("usage.enabled", true)
+	viper.SetDefault("audit.enabled", true)
+
+	viper.SetDefault("observability.logging.output_path", "stdout")
+	viper.SetDefault("observability.logging.error_path", "stderr")
+	viper.SetDefault("observability.tracing.enabled", true)
+	viper.SetDefault("observability.tracing.environment", "production")
+}
+
+// setEdgeDefaults tightens the defaults for the edge profile, which runs
+// semaroute as a sidecar next to each application pod: heavy subsystems are
+// off, caches and buffers are small, logs go to the standard streams and the
+// Go runtime is held to a soft memory limit.
+func setEdgeDefaults() {
+	viper.SetDefault("server.idle_timeout", 30*time.Second)
+	viper.SetDefault("server.shutdown_timeout", 5*time.Second)
+	viper.SetDefault("server.memory_limit", "24MiB")
+
+	viper.SetDefault("health_check.interval", 60*time.Second)
+
+	viper.SetDefault("cache.max_size", 100)
+	viper.SetDefault("cache.max_memory", "4MB")
+	viper.SetDefault("cache.cleanup_interval", 1*time.Minute)
+	viper.SetDefault("cache.warm.enabled", false)
+
+	viper.SetDefault("tools.max_parallel", 2)
+	viper.SetDefault("longform.enabled", false)
+	viper.SetDefault("shadow.max_samples", 100)
+	viper.SetDefault("shadow.max_concurrent", 2)
+	viper.SetDefault("usage.enabled", false)
+	viper.SetDefault("usage.logprobs.enabled", false)
+	viper.SetDefault("audit.enabled", false)
+	viper.SetDefault("audit.max_entries", 1000)
+	viper.SetDefault("alerting.enabled", false)
+
+	viper.SetDefault("observability.logging.level", "warn")
+	viper.SetDefault("observability.logging.output_path", "stdout")
+	viper.SetDefault("observability.logging.error_path", "stderr")
+	viper.SetDefault("observability.metrics.collect_interval", 60*time.Second)
+	viper.SetDefault("observability.tracing.enabled", false)
+}
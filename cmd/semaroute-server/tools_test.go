@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/semantrix/semaroute/internal/tools"
+	"github.com/spf13/viper"
+)
+
+func TestLoadConfigKeepsToolSchemaKeywords(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(config, []byte(`tools:
+  tools:
+    lookup:
+      schema: |
+        {"type": "object", "required": ["userId"], "additionalProperties": false,
+         "properties": {"userId": {"type": "string", "minLength": 2, "maxLength": 4}}}
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadConfig(config, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := tools.ParseSchema(loaded.Tools.Tools["lookup"].Schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args  string
+		valid bool
+	}{
+		{`{"userId": "ab"}`, true},
+		{`{"userId": "äöüß"}`, true}, // four characters in eight bytes
+		{`{"userId": "a"}`, false},
+		{`{"userId": "abcde"}`, false},
+		{`{"userid": "ab"}`, false},
+		{`{"userId": "ab", "extra": 1}`, false},
+	}
+	for _, tt := range tests {
+		err := tools.ValidateArguments(schema, json.RawMessage(tt.args))
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidateArguments(%s) error = %v, want valid %v", tt.args, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Package adapters translates responses between the Anthropic messages format
+// and the unified, OpenAI-shaped format, in both directions. Tool calls, stop
+// reasons, usage and thinking survive the round trip.
+package adapters
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+// Anthropic stop reasons.
+const (
+	StopReasonEndTurn      = "end_turn"
+	StopReasonStopSequence = "stop_sequence"
+	StopReasonMaxTokens    = "max_tokens"
+	StopReasonToolUse      = "tool_use"
+	StopReasonRefusal      = "refusal"
+	StopReasonPauseTurn    = "pause_turn"
+	StopReasonContextLimit = "model_context_window_exceeded"
+)
+
+// OpenAI finish reasons.
+const (
+	FinishReasonStop          = "stop"
+	FinishReasonLength        = "length"
+	FinishReasonToolCalls     = "tool_calls"
+	FinishReasonFunctionCall  = "function_call"
+	FinishReasonContentFilter = "content_filter"
+)
+
+// AnthropicMessage is a response of the Anthropic messages endpoint.
+type AnthropicMessage struct {
+	ID           string                  `json:"id"`
+	Type         string                  `json:"type"`
+	Role         string                  `json:"role"`
+	Model        string                  `json:"model"`
+	Content      []AnthropicContentBlock `json:"content"`
+	StopReason   string                  `json:"stop_reason"`
+	StopSequence *string                 `json:"stop_sequence"`
+	Usage        AnthropicUsage          `json:"usage"`
+}
+
+// AnthropicContentBlock is one block of message content. Type selects which
+// fields are set: text, thinking, redacted_thinking, tool_use, tool_result or
+// image.
+type AnthropicContentBlock struct {
+	Type      string                `json:"type"`
+	Text      string                `json:"text,omitempty"`
+	Thinking  string                `json:"thinking,omitempty"`
+	Signature string                `json:"signature,omitempty"`
+	Data      string                `json:"data,omitempty"` // redacted_thinking
+	ID        string                `json:"id,omitempty"`
+	Name      string                `json:"name,omitempty"`
+	Input     json.RawMessage       `json:"input,omitempty"`
+	ToolUseID string                `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage       `json:"content,omitempty"` // tool_result: a string or blocks
+	IsError   bool                  `json:"is_error,omitempty"`
+	Source    *AnthropicImageSource `json:"source,omitempty"`
+}
+
+// AnthropicImageSource is the source of an image block: base64 data or a URL.
+type AnthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// AnthropicUsage is the token usage of a response. InputTokens excludes the
+// tokens read from or written to the prompt cache.
+type AnthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// FinishReason maps an Anthropic stop reason to the OpenAI finish reason.
+// Reasons without an equivalent, such as pause_turn, are passed through.
+func FinishReason(stopReason string) string {
+	switch stopReason {
+	case StopReasonEndTurn, StopReasonStopSequence:
+		return FinishReasonStop
+	case StopReasonMaxTokens, StopReasonContextLimit:
+		return FinishReasonLength
+	case StopReasonToolUse:
+		return FinishReasonToolCalls
+	case StopReasonRefusal:
+		return FinishReasonContentFilter
+	default:
+		return stopReason
+	}
+}
+
+// StopReason maps an OpenAI finish reason to the Anthropic stop reason. A
+// stop caused by one of the request's stop sequences reports stop_sequence.
+func StopReason(finishReason, stopSequence string) string {
+	switch finishReason {
+	case FinishReasonStop:
+		if stopSequence != "" {
+			return StopReasonStopSequence
+		}
+		return StopReasonEndTurn
+	case FinishReasonLength:
+		return StopReasonMaxTokens
+	case FinishReasonToolCalls, FinishReasonFunctionCall:
+		return StopReasonToolUse
+	case FinishReasonContentFilter:
+		return StopReasonRefusal
+	default:
+		return finishReason
+	}
+}
+
+// FromAnthropic converts an Anthropic response to the unified format. Text
+// blocks are concatenated, tool_use blocks become tool calls in order, and
+// thinking is returned as reasoning content with its signature. Cache reads
+// and writes are counted in the prompt tokens, as OpenAI does.
+func FromAnthropic(msg AnthropicMessage) *models.ChatResponse {
+	message := models.Message{Role: msg.Role}
+	if message.Role == "" {
+		message.Role = "assistant"
+	}
+
+	var content, reasoning strings.Builder
+	for _, block := range msg.Content {
+		switch block.Type {
+		case "text":
+			content.WriteString(block.Text)
+		case "thinking":
+			reasoning.WriteString(block.Thinking)
+			if block.Signature != "" {
+				message.ReasoningSignature = block.Signature
+			}
+		case "redacted_thinking":
+			message.RedactedReasoning = append(message.RedactedReasoning, block.Data)
+		case "tool_use":
+			message.ToolCalls = append(message.ToolCalls, models.ToolCall{
+				ID:   block.ID,
+				Type: "function",
+				Function: models.ToolCallFunction{
+					Name:      block.Name,
+					Arguments: toolArguments(block.Input),
+				},
+			})
+		}
+	}
+	message.Content = models.TextContent(content.String())
+	message.ReasoningContent = reasoning.String()
+
+	choice := models.Choice{
+		Index:        0,
+		Message:      message,
+		FinishReason: FinishReason(msg.StopReason),
+	}
+	if msg.StopSequence != nil {
+		choice.StopSequence = *msg.StopSequence
+	}
+
+	promptTokens := msg.Usage.InputTokens + msg.Usage.CacheReadInputTokens + msg.Usage.CacheCreationInputTokens
+	usage := models.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: msg.Usage.OutputTokens,
+		TotalTokens:      promptTokens + msg.Usage.OutputTokens,
+	}
+	if msg.Usage.CacheReadInputTokens > 0 || msg.Usage.CacheCreationInputTokens > 0 {
+		usage.PromptTokensDetails = &models.PromptTokensDetails{
+			CachedTokens:        msg.Usage.CacheReadInputTokens,
+			CacheCreationTokens: msg.Usage.CacheCreationInputTokens,
+		}
+	}
+
+	return &models.ChatResponse{
+		ID:      msg.ID,
+		Model:   msg.Model,
+		Choices: []models.Choice{choice},
+		Usage:   usage,
+		Created: time.Now().Unix(),
+	}
+}
+
+// ToAnthropic converts a unified response to the Anthropic format. Only the
+// first choice is kept, since Anthropic returns a single message. Content is
+// ordered as Anthropic emits it: thinking, then text, then tool_use blocks.
+func ToAnthropic(resp *models.ChatResponse) AnthropicMessage {
+	msg := AnthropicMessage{
+		ID:      resp.ID,
+		Type:    "message",
+		Role:    "assistant",
+		Model:   resp.Model,
+		Content: []AnthropicContentBlock{},
+	}
+
+	if len(resp.Choices) > 0 {
+		choice := resp.Choices[0]
+		msg.Content = MessageBlocks(choice.Message)
+		msg.StopReason = StopReason(choice.FinishReason, choice.StopSequence)
+		if choice.StopSequence != "" {
+			stopSequence := choice.StopSequence
+			msg.StopSequence = &stopSequence
+		}
+	}
+
+	// Anthropic reports cache reads and writes apart from the input tokens
+	msg.Usage = AnthropicUsage{
+		InputTokens:  resp.Usage.PromptTokens,
+		OutputTokens: resp.Usage.CompletionTokens,
+	}
+	if details := resp.Usage.PromptTokensDetails; details != nil {
+		msg.Usage.CacheReadInputTokens = details.CachedTokens
+		msg.Usage.CacheCreationInputTokens = details.CacheCreationTokens
+		msg.Usage.InputTokens -= details.CachedTokens + details.CacheCreationTokens
+		if msg.Usage.InputTokens < 0 {
+			msg.Usage.InputTokens = 0
+		}
+	}
+	return msg
+}
+
+// MessageBlocks converts an assistant message to Anthropic content blocks:
+// thinking, then text, then one tool_use block per tool call. Text-only
+// parts are kept; images are not valid in assistant messages.
+func MessageBlocks(message models.Message) []AnthropicContentBlock {
+	blocks := make([]AnthropicContentBlock, 0, len(message.ToolCalls)+2)
+	if message.ReasoningContent != "" || message.ReasoningSignature != "" {
+		blocks = append(blocks, AnthropicContentBlock{
+			Type:      "thinking",
+			Thinking:  message.ReasoningContent,
+			Signature: message.ReasoningSignature,
+		})
+	}
+	for _, data := range message.RedactedReasoning {
+		blocks = append(blocks, AnthropicContentBlock{Type: "redacted_thinking", Data: data})
+	}
+	if text := message.Content.Text(); text != "" {
+		blocks = append(blocks, AnthropicContentBlock{Type: "text", Text: text})
+	}
+	for _, call := range message.ToolCalls {
+		blocks = append(blocks, AnthropicContentBlock{
+			Type:  "tool_use",
+			ID:    call.ID,
+			Name:  call.Function.Name,
+			Input: toolInput(call.Function.Arguments),
+		})
+	}
+	return blocks
+}
+
+// toolArguments encodes a tool_use input as OpenAI function arguments.
+func toolArguments(input json.RawMessage) string {
+	if len(input) == 0 || string(input) == "null" {
+		return "{}"
+	}
+	return string(input)
+}
+
+// toolInput decodes OpenAI function arguments as a tool_use input. Anthropic
+// requires an object, so arguments that are not one are wrapped under
+// "arguments" rather than dropped.
+func toolInput(arguments string) json.RawMessage {
+	trimmed := strings.TrimSpace(arguments)
+	if trimmed == "" {
+		return json.RawMessage(`{}`)
+	}
+	var object map[string]json.RawMessage
+	if json.Unmarshal([]byte(trimmed), &object) == nil && object != nil {
+		return json.RawMessage(trimmed)
+	}
+	wrapped, _ := json.Marshal(map[string]string{"arguments": arguments})
+	return wrapped
+}
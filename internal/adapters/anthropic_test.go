@@ -0,0 +1,242 @@
+package adapters
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+func TestFinishReason(t *testing.T) {
+	tests := map[string]string{
+		StopReasonEndTurn:      FinishReasonStop,
+		StopReasonStopSequence: FinishReasonStop,
+		StopReasonMaxTokens:    FinishReasonLength,
+		StopReasonContextLimit: FinishReasonLength,
+		StopReasonToolUse:      FinishReasonToolCalls,
+		StopReasonRefusal:      FinishReasonContentFilter,
+		StopReasonPauseTurn:    StopReasonPauseTurn,
+		"":                     "",
+	}
+	for stopReason, want := range tests {
+		if got := FinishReason(stopReason); got != want {
+			t.Errorf("FinishReason(%q) = %q, want %q", stopReason, got, want)
+		}
+	}
+}
+
+func TestStopReason(t *testing.T) {
+	tests := []struct {
+		finishReason string
+		stopSequence string
+		want         string
+	}{
+		{FinishReasonStop, "", StopReasonEndTurn},
+		{FinishReasonStop, "###", StopReasonStopSequence},
+		{FinishReasonLength, "", StopReasonMaxTokens},
+		{FinishReasonToolCalls, "", StopReasonToolUse},
+		{FinishReasonFunctionCall, "", StopReasonToolUse},
+		{FinishReasonContentFilter, "", StopReasonRefusal},
+		{"unknown", "", "unknown"},
+	}
+	for _, test := range tests {
+		if got := StopReason(test.finishReason, test.stopSequence); got != test.want {
+			t.Errorf("StopReason(%q, %q) = %q, want %q", test.finishReason, test.stopSequence, got, test.want)
+		}
+	}
+}
+
+func TestFromAnthropic(t *testing.T) {
+	stopSequence := "###"
+	msg := AnthropicMessage{
+		ID:    "msg_1",
+		Model: "claude-sonnet-4",
+		Content: []AnthropicContentBlock{
+			{Type: "thinking", Thinking: "Let me check. ", Signature: "sig"},
+			{Type: "redacted_thinking", Data: "opaque"},
+			{Type: "text", Text: "Checking "},
+			{Type: "text", Text: "the weather."},
+			{Type: "tool_use", ID: "toolu_1", Name: "get_weather", Input: json.RawMessage(`{"city":"Paris"}`)},
+			{Type: "tool_use", ID: "toolu_2", Name: "get_time"},
+		},
+		StopReason:   StopReasonStopSequence,
+		StopSequence: &stopSequence,
+		Usage:        AnthropicUsage{InputTokens: 10, OutputTokens: 5, CacheReadInputTokens: 100, CacheCreationInputTokens: 20},
+	}
+
+	resp := FromAnthropic(msg)
+	if resp.ID != "msg_1" || resp.Model != "claude-sonnet-4" || len(resp.Choices) != 1 {
+		t.Fatalf("FromAnthropic() = %+v", resp)
+	}
+	choice := resp.Choices[0]
+	if choice.FinishReason != FinishReasonStop || choice.StopSequence != "###" {
+		t.Errorf("finish = %q, stop sequence %q", choice.FinishReason, choice.StopSequence)
+	}
+	message := choice.Message
+	if message.Role != "assistant" || message.Content.Text() != "Checking the weather." {
+		t.Errorf("message = %q: %q", message.Role, message.Content.Text())
+	}
+	if message.ReasoningContent != "Let me check. " || message.ReasoningSignature != "sig" || !reflect.DeepEqual(message.RedactedReasoning, []string{"opaque"}) {
+		t.Errorf("reasoning = %q, signature %q, redacted %v", message.ReasoningContent, message.ReasoningSignature, message.RedactedReasoning)
+	}
+	wantCalls := []models.ToolCall{
+		{ID: "toolu_1", Type: "function", Function: models.ToolCallFunction{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+		{ID: "toolu_2", Type: "function", Function: models.ToolCallFunction{Name: "get_time", Arguments: "{}"}},
+	}
+	if !reflect.DeepEqual(message.ToolCalls, wantCalls) {
+		t.Errorf("tool calls = %+v, want %+v", message.ToolCalls, wantCalls)
+	}
+}
+
+func TestUsageCountsCacheTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		usage   AnthropicUsage
+		want    models.Usage
+		details *models.PromptTokensDetails
+	}{
+		{
+			name:  "no cache",
+			usage: AnthropicUsage{InputTokens: 10, OutputTokens: 5},
+			want:  models.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+		},
+		{
+			name:    "cache read",
+			usage:   AnthropicUsage{InputTokens: 10, OutputTokens: 5, CacheReadInputTokens: 90},
+			want:    models.Usage{PromptTokens: 100, CompletionTokens: 5, TotalTokens: 105},
+			details: &models.PromptTokensDetails{CachedTokens: 90},
+		},
+		{
+			name:    "cache read and write",
+			usage:   AnthropicUsage{InputTokens: 10, OutputTokens: 5, CacheReadInputTokens: 90, CacheCreationInputTokens: 50},
+			want:    models.Usage{PromptTokens: 150, CompletionTokens: 5, TotalTokens: 155},
+			details: &models.PromptTokensDetails{CachedTokens: 90, CacheCreationTokens: 50},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			usage := FromAnthropic(AnthropicMessage{Usage: test.usage}).Usage
+			if !reflect.DeepEqual(usage.PromptTokensDetails, test.details) {
+				t.Errorf("details = %+v, want %+v", usage.PromptTokensDetails, test.details)
+			}
+			usage.PromptTokensDetails = nil
+			if usage != test.want {
+				t.Errorf("usage = %+v, want %+v", usage, test.want)
+			}
+
+			// Converting back reports the cache apart from the input tokens
+			back := ToAnthropic(&models.ChatResponse{Usage: FromAnthropic(AnthropicMessage{Usage: test.usage}).Usage}).Usage
+			if back != test.usage {
+				t.Errorf("ToAnthropic() usage = %+v, want %+v", back, test.usage)
+			}
+		})
+	}
+}
+
+func TestToAnthropicWrapsToolArguments(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments string
+		want      string
+	}{
+		{"object", `{"city":"Paris"}`, `{"city":"Paris"}`},
+		{"empty", "", `{}`},
+		{"blank", "  ", `{}`},
+		{"array", `["Paris"]`, `{"arguments":"[\"Paris\"]"}`},
+		{"string", `"Paris"`, `{"arguments":"\"Paris\""}`},
+		{"null", "null", `{"arguments":"null"}`},
+		{"invalid", `{"city":`, `{"arguments":"{\"city\":"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msg := ToAnthropic(&models.ChatResponse{Choices: []models.Choice{{
+				Message: models.Message{ToolCalls: []models.ToolCall{{
+					ID:       "call_1",
+					Type:     "function",
+					Function: models.ToolCallFunction{Name: "get_weather", Arguments: test.arguments},
+				}}},
+				FinishReason: FinishReasonToolCalls,
+			}}})
+			if len(msg.Content) != 1 {
+				t.Fatalf("content = %+v, want one tool_use block", msg.Content)
+			}
+			block := msg.Content[0]
+			if block.Type != "tool_use" || block.ID != "call_1" || block.Name != "get_weather" || string(block.Input) != test.want {
+				t.Errorf("block = %s %s %s %s, want input %s", block.Type, block.ID, block.Name, block.Input, test.want)
+			}
+			if msg.StopReason != StopReasonToolUse {
+				t.Errorf("stop reason = %q, want tool_use", msg.StopReason)
+			}
+		})
+	}
+}
+
+func TestAnthropicRoundTrip(t *testing.T) {
+	stopSequence := "END"
+	tests := []struct {
+		name string
+		msg  AnthropicMessage
+	}{
+		{
+			name: "text",
+			msg: AnthropicMessage{
+				Content:    []AnthropicContentBlock{{Type: "text", Text: "Hello"}},
+				StopReason: StopReasonEndTurn,
+				Usage:      AnthropicUsage{InputTokens: 3, OutputTokens: 1},
+			},
+		},
+		{
+			name: "stop sequence",
+			msg: AnthropicMessage{
+				Content:      []AnthropicContentBlock{{Type: "text", Text: "Hello"}},
+				StopReason:   StopReasonStopSequence,
+				StopSequence: &stopSequence,
+			},
+		},
+		{
+			name: "thinking and tools",
+			msg: AnthropicMessage{
+				Content: []AnthropicContentBlock{
+					{Type: "thinking", Thinking: "Two lookups.", Signature: "sig"},
+					{Type: "redacted_thinking", Data: "opaque"},
+					{Type: "text", Text: "Looking up."},
+					{Type: "tool_use", ID: "toolu_1", Name: "get_weather", Input: json.RawMessage(`{"city":"Paris"}`)},
+					{Type: "tool_use", ID: "toolu_2", Name: "get_time", Input: json.RawMessage(`{}`)},
+				},
+				StopReason: StopReasonToolUse,
+				Usage:      AnthropicUsage{InputTokens: 12, OutputTokens: 30, CacheReadInputTokens: 4000, CacheCreationInputTokens: 200},
+			},
+		},
+		{
+			name: "max tokens",
+			msg: AnthropicMessage{
+				Content:    []AnthropicContentBlock{{Type: "text", Text: "Truncat"}},
+				StopReason: StopReasonMaxTokens,
+			},
+		},
+		{
+			name: "refusal",
+			msg:  AnthropicMessage{Content: []AnthropicContentBlock{}, StopReason: StopReasonRefusal},
+		},
+		{
+			name: "pause turn",
+			msg:  AnthropicMessage{Content: []AnthropicContentBlock{}, StopReason: StopReasonPauseTurn},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.msg.ID = "msg_1"
+			test.msg.Type = "message"
+			test.msg.Role = "assistant"
+			test.msg.Model = "claude-sonnet-4"
+
+			got := ToAnthropic(FromAnthropic(test.msg))
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(test.msg)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("round trip =\n%s\nwant\n%s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
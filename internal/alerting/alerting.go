@@ -0,0 +1,411 @@
+// Package alerting evaluates alert rules over the router's own provider events
+// and sends notifications to webhook and email sinks, for deployments without
+// a Prometheus and Alertmanager stack.
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Conditions a rule can evaluate.
+const (
+	ConditionErrorRate    = "error_rate"    // failed provider requests / provider requests
+	ConditionFallbackRate = "fallback_rate" // requests moved to another provider / provider requests
+	ConditionSpend        = "spend"         // estimated spend in USD
+	ConditionRequests     = "requests"      // provider requests
+	ConditionErrors       = "errors"        // failed provider requests
+)
+
+// Notification statuses.
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+// Defaults applied to unset configuration.
+const (
+	defaultInterval    = 30 * time.Second
+	defaultWindow      = 5 * time.Minute
+	defaultMinRequests = 10
+)
+
+// Config holds configuration for alerting.
+type Config struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"` // how often rules are evaluated
+	Rules    []RuleConfig  `mapstructure:"rules"`
+	Sinks    []SinkConfig  `mapstructure:"sinks"`
+}
+
+// RuleConfig is one alert rule: a condition over a window of provider events,
+// compared against a threshold.
+type RuleConfig struct {
+	Name      string        `mapstructure:"name"`
+	Condition string        `mapstructure:"condition"`
+	Provider  string        `mapstructure:"provider"` // empty evaluates all providers together
+	Window    time.Duration `mapstructure:"window"`
+	Operator  string        `mapstructure:"operator"` // >, >=, < or <=; defaults to >
+	Threshold float64       `mapstructure:"threshold"`
+
+	// MinRequests keeps rate conditions quiet until the window holds enough
+	// requests for the rate to mean something
+	MinRequests int `mapstructure:"min_requests"`
+
+	For            time.Duration `mapstructure:"for"`             // how long the condition must hold before firing
+	RepeatInterval time.Duration `mapstructure:"repeat_interval"` // re-notify while firing; 0 notifies once
+	Severity       string        `mapstructure:"severity"`
+	Sinks          []string      `mapstructure:"sinks"` // sink names; empty sends to every sink
+}
+
+// Notification is sent to sinks when a rule fires or resolves.
+type Notification struct {
+	Rule      string    `json:"rule"`
+	Status    string    `json:"status"`
+	Severity  string    `json:"severity,omitempty"`
+	Condition string    `json:"condition"`
+	Provider  string    `json:"provider,omitempty"`
+	Value     float64   `json:"value"`
+	Operator  string    `json:"operator"`
+	Threshold float64   `json:"threshold"`
+	Window    string    `json:"window"`
+	StartsAt  time.Time `json:"starts_at"`
+	Time      time.Time `json:"time"`
+	Message   string    `json:"message"`
+}
+
+// RuleState reports the current state of a rule.
+type RuleState struct {
+	Rule         string    `json:"rule"`
+	State        string    `json:"state"` // inactive, pending or firing
+	Value        float64   `json:"value"`
+	Since        time.Time `json:"since,omitempty"`
+	LastNotified time.Time `json:"last_notified,omitempty"`
+	LastError    string    `json:"last_error,omitempty"`
+}
+
+// rule is a configured rule with its evaluation state.
+type rule struct {
+	config RuleConfig
+	sinks  []Sink
+
+	state        string
+	value        float64
+	since        time.Time
+	lastNotified time.Time
+	lastError    string
+}
+
+// Engine records provider events and evaluates the rules on an interval. It
+// implements observability.Observer.
+type Engine struct {
+	interval time.Duration
+	rules    []*rule
+	counters *counters
+	logger   *zap.Logger
+
+	mutex sync.Mutex // guards rule state
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// NewEngine validates the rules and sinks.
+func NewEngine(config Config, logger *zap.Logger) (*Engine, error) {
+	sinks := make(map[string]Sink, len(config.Sinks))
+	var allSinks []Sink
+	for _, sinkConfig := range config.Sinks {
+		if _, exists := sinks[sinkConfig.Name]; exists {
+			return nil, fmt.Errorf("duplicate alert sink %q", sinkConfig.Name)
+		}
+		sink, err := newSink(sinkConfig)
+		if err != nil {
+			return nil, err
+		}
+		sinks[sinkConfig.Name] = sink
+		allSinks = append(allSinks, sink)
+	}
+
+	e := &Engine{interval: config.Interval, logger: logger}
+	if e.interval <= 0 {
+		e.interval = defaultInterval
+	}
+
+	names := make(map[string]bool)
+	retention := time.Duration(0)
+	for _, ruleConfig := range config.Rules {
+		if ruleConfig.Name == "" {
+			return nil, fmt.Errorf("alert rule needs a name")
+		}
+		if names[ruleConfig.Name] {
+			return nil, fmt.Errorf("duplicate alert rule %q", ruleConfig.Name)
+		}
+		names[ruleConfig.Name] = true
+
+		switch ruleConfig.Condition {
+		case ConditionErrorRate, ConditionFallbackRate, ConditionSpend, ConditionRequests, ConditionErrors:
+		default:
+			return nil, fmt.Errorf("alert rule %q has unknown condition %q", ruleConfig.Name, ruleConfig.Condition)
+		}
+		switch ruleConfig.Operator {
+		case "":
+			ruleConfig.Operator = ">"
+		case ">", ">=", "<", "<=":
+		default:
+			return nil, fmt.Errorf("alert rule %q has unknown operator %q", ruleConfig.Name, ruleConfig.Operator)
+		}
+		if ruleConfig.Window <= 0 {
+			ruleConfig.Window = defaultWindow
+		}
+		if ruleConfig.MinRequests <= 0 {
+			ruleConfig.MinRequests = defaultMinRequests
+		}
+		if ruleConfig.Window > retention {
+			retention = ruleConfig.Window
+		}
+
+		r := &rule{config: ruleConfig, state: "inactive", sinks: allSinks}
+		if len(ruleConfig.Sinks) > 0 {
+			r.sinks = nil
+			for _, name := range ruleConfig.Sinks {
+				sink, exists := sinks[name]
+				if !exists {
+					return nil, fmt.Errorf("alert rule %q uses unknown sink %q", ruleConfig.Name, name)
+				}
+				r.sinks = append(r.sinks, sink)
+			}
+		}
+		e.rules = append(e.rules, r)
+	}
+
+	e.counters = newCounters(retention)
+	return e, nil
+}
+
+// ObserveProviderRequest records a provider request and whether it failed.
+func (e *Engine) ObserveProviderRequest(providerName string, failed bool) {
+	e.counters.record(providerName, func(c *counts) {
+		c.requests++
+		if failed {
+			c.errors++
+		}
+	})
+}
+
+// ObserveFallback records a request moved away from a failed provider.
+func (e *Engine) ObserveFallback(providerName string) {
+	e.counters.record(providerName, func(c *counts) { c.fallbacks++ })
+}
+
+// ObserveSpend records the estimated cost of a request.
+func (e *Engine) ObserveSpend(providerName string, cost float64) {
+	e.counters.record(providerName, func(c *counts) { c.spend += cost })
+}
+
+// Start begins evaluating rules on the interval.
+func (e *Engine) Start() {
+	e.stop = make(chan struct{})
+	e.done = make(chan struct{})
+
+	go func() {
+		defer close(e.done)
+
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-e.stop:
+				return
+			case now := <-ticker.C:
+				e.evaluate(now)
+			}
+		}
+	}()
+}
+
+// Stop stops evaluating rules.
+func (e *Engine) Stop() {
+	if e.stop == nil {
+		return
+	}
+	close(e.stop)
+	<-e.done
+}
+
+// States returns the current state of every rule.
+func (e *Engine) States() []RuleState {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	states := make([]RuleState, 0, len(e.rules))
+	for _, r := range e.rules {
+		states = append(states, RuleState{
+			Rule:         r.config.Name,
+			State:        r.state,
+			Value:        r.value,
+			Since:        r.since,
+			LastNotified: r.lastNotified,
+			LastError:    r.lastError,
+		})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Rule < states[j].Rule })
+	return states
+}
+
+// evaluate checks every rule and notifies the transitions.
+func (e *Engine) evaluate(now time.Time) {
+	e.counters.prune(now)
+
+	var notifications []pendingNotification
+	e.mutex.Lock()
+	for _, r := range e.rules {
+		if notification, notify := e.step(r, now); notify {
+			notifications = append(notifications, pendingNotification{rule: r, notification: notification})
+		}
+	}
+	e.mutex.Unlock()
+
+	for _, pending := range notifications {
+		e.notify(pending.rule, pending.notification)
+	}
+}
+
+// pendingNotification is a notification to send once the state lock is released.
+type pendingNotification struct {
+	rule         *rule
+	notification Notification
+}
+
+// step advances a rule's state and returns the notification to send, if any.
+func (e *Engine) step(r *rule, now time.Time) (Notification, bool) {
+	value, ok := e.value(r.config, now)
+	r.value = value
+	active := ok && compare(value, r.config.Operator, r.config.Threshold)
+
+	switch {
+	case active && r.state == "inactive":
+		r.state = "pending"
+		r.since = now
+		if r.config.For > 0 {
+			return Notification{}, false
+		}
+		fallthrough
+	case active && r.state == "pending":
+		if now.Sub(r.since) < r.config.For {
+			return Notification{}, false
+		}
+		r.state = StatusFiring
+		r.lastNotified = now
+		return r.notification(StatusFiring, now), true
+	case active && r.state == StatusFiring:
+		if r.config.RepeatInterval > 0 && now.Sub(r.lastNotified) >= r.config.RepeatInterval {
+			r.lastNotified = now
+			return r.notification(StatusFiring, now), true
+		}
+	case !active && r.state == StatusFiring:
+		notification := r.notification(StatusResolved, now)
+		r.state = "inactive"
+		r.since = time.Time{}
+		r.lastNotified = now
+		return notification, true
+	case !active:
+		r.state = "inactive"
+		r.since = time.Time{}
+	}
+	return Notification{}, false
+}
+
+// value computes a rule's condition. Rates are not computed until the window
+// holds MinRequests requests.
+func (e *Engine) value(config RuleConfig, now time.Time) (float64, bool) {
+	c := e.counters.sum(config.Provider, config.Window, now)
+
+	switch config.Condition {
+	case ConditionErrorRate, ConditionFallbackRate:
+		if c.requests < float64(config.MinRequests) {
+			return 0, false
+		}
+		if config.Condition == ConditionErrorRate {
+			return c.errors / c.requests, true
+		}
+		return c.fallbacks / c.requests, true
+	case ConditionSpend:
+		return c.spend, true
+	case ConditionRequests:
+		return c.requests, true
+	default:
+		return c.errors, true
+	}
+}
+
+// compare applies a rule operator.
+func compare(value float64, operator string, threshold float64) bool {
+	switch operator {
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	default:
+		return value > threshold
+	}
+}
+
+// notification builds a notification for the rule's current state.
+func (r *rule) notification(status string, now time.Time) Notification {
+	scope := "all providers"
+	if r.config.Provider != "" {
+		scope = r.config.Provider
+	}
+	message := fmt.Sprintf("%s for %s is %g over the last %s (threshold %s %g)",
+		r.config.Condition, scope, r.value, r.config.Window, r.config.Operator, r.config.Threshold)
+	if status == StatusResolved {
+		message = fmt.Sprintf("%s for %s is back within threshold (%g)", r.config.Condition, scope, r.value)
+	}
+
+	return Notification{
+		Rule:      r.config.Name,
+		Status:    status,
+		Severity:  r.config.Severity,
+		Condition: r.config.Condition,
+		Provider:  r.config.Provider,
+		Value:     r.value,
+		Operator:  r.config.Operator,
+		Threshold: r.config.Threshold,
+		Window:    r.config.Window.String(),
+		StartsAt:  r.since,
+		Time:      now,
+		Message:   message,
+	}
+}
+
+// notify sends a notification to the rule's sinks. Failures are logged and
+// kept in the rule state; notifications are not retried.
+func (e *Engine) notify(r *rule, notification Notification) {
+	var lastError string
+	for _, sink := range r.sinks {
+		err := sink.Send(context.Background(), notification)
+		if err != nil {
+			lastError = fmt.Sprintf("%s: %v", sink.Name(), err)
+			e.logger.Warn("Failed to send alert notification",
+				zap.String("rule", notification.Rule),
+				zap.String("sink", sink.Name()),
+				zap.Error(err))
+			continue
+		}
+		e.logger.Info("Sent alert notification",
+			zap.String("rule", notification.Rule),
+			zap.String("status", notification.Status),
+			zap.String("sink", sink.Name()))
+	}
+
+	e.mutex.Lock()
+	r.lastError = lastError
+	e.mutex.Unlock()
+}
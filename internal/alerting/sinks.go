@@ -0,0 +1,154 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// Sink types.
+const (
+	SinkWebhook = "webhook"
+	SinkEmail   = "email"
+)
+
+// defaultSinkTimeout bounds a notification when the sink sets no timeout.
+const defaultSinkTimeout = 10 * time.Second
+
+// SinkConfig configures where notifications are sent.
+type SinkConfig struct {
+	Name    string        `mapstructure:"name"`
+	Type    string        `mapstructure:"type"` // webhook or email
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// Webhook: the notification is POSTed as JSON
+	URL     string            `mapstructure:"url"`
+	Headers map[string]string `mapstructure:"headers"` // values may reference environment variables
+
+	// Email: sent through an SMTP relay, with PLAIN auth when a username is set
+	SMTPAddress string   `mapstructure:"smtp_address"` // host:port
+	Username    string   `mapstructure:"username"`
+	Password    string   `mapstructure:"password"`
+	From        string   `mapstructure:"from"`
+	To          []string `mapstructure:"to"`
+}
+
+// Sink delivers notifications.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, notification Notification) error
+}
+
+// newSink creates a sink from its configuration.
+func newSink(config SinkConfig) (Sink, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("alert sink needs a name")
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultSinkTimeout
+	}
+
+	switch config.Type {
+	case SinkWebhook:
+		if config.URL == "" {
+			return nil, fmt.Errorf("webhook sink %q needs a url", config.Name)
+		}
+		return &webhookSink{config: config, client: &http.Client{Timeout: config.Timeout}}, nil
+	case SinkEmail:
+		if config.SMTPAddress == "" || config.From == "" || len(config.To) == 0 {
+			return nil, fmt.Errorf("email sink %q needs smtp_address, from and to", config.Name)
+		}
+		return &emailSink{config: config}, nil
+	default:
+		return nil, fmt.Errorf("alert sink %q has unknown type %q", config.Name, config.Type)
+	}
+}
+
+// webhookSink POSTs notifications as JSON.
+type webhookSink struct {
+	config SinkConfig
+	client *http.Client
+}
+
+func (s *webhookSink) Name() string {
+	return s.config.Name
+}
+
+func (s *webhookSink) Send(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range s.config.Headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// emailSink sends notifications as plain text email.
+type emailSink struct {
+	config SinkConfig
+}
+
+func (s *emailSink) Name() string {
+	return s.config.Name
+}
+
+func (s *emailSink) Send(ctx context.Context, notification Notification) error {
+	var message strings.Builder
+	fmt.Fprintf(&message, "From: %s\r\n", s.config.From)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(s.config.To, ", "))
+	fmt.Fprintf(&message, "Subject: [%s] %s\r\n", strings.ToUpper(notification.Status), notification.Rule)
+	fmt.Fprintf(&message, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&message, "%s\r\n\r\n", notification.Message)
+	fmt.Fprintf(&message, "Rule: %s\r\nSeverity: %s\r\nValue: %g\r\nThreshold: %s %g\r\nWindow: %s\r\nSince: %s\r\n",
+		notification.Rule, notification.Severity, notification.Value,
+		notification.Operator, notification.Threshold, notification.Window,
+		notification.StartsAt.Format(time.RFC3339))
+
+	var auth smtp.Auth
+	if s.config.Username != "" {
+		host, _, _ := net.SplitHostPort(s.config.SMTPAddress)
+		auth = smtp.PlainAuth("", s.config.Username, os.ExpandEnv(s.config.Password), host)
+	}
+
+	// net/smtp takes no context, so the timeout bounds the whole exchange
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.config.SMTPAddress, auth, s.config.From, s.config.To, []byte(message.String()))
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("sending email: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,100 @@
+package alerting
+
+import (
+	"sync"
+	"time"
+)
+
+// bucketWidth is the resolution of the rolling counters. Rule windows are
+// rounded to whole buckets.
+const bucketWidth = 10 * time.Second
+
+// counts are the provider events seen in one bucket.
+type counts struct {
+	requests  float64
+	errors    float64
+	fallbacks float64
+	spend     float64
+}
+
+// add adds other to the counts.
+func (c *counts) add(other counts) {
+	c.requests += other.requests
+	c.errors += other.errors
+	c.fallbacks += other.fallbacks
+	c.spend += other.spend
+}
+
+// counters keep per-provider event counts in fixed-width time buckets, long
+// enough for the longest rule window.
+type counters struct {
+	retention time.Duration
+
+	mutex   sync.Mutex
+	buckets map[int64]map[string]*counts // bucket start, then provider
+}
+
+// newCounters creates counters that keep events for the given duration.
+func newCounters(retention time.Duration) *counters {
+	return &counters{retention: retention, buckets: make(map[int64]map[string]*counts)}
+}
+
+// record applies update to the provider's counts in the current bucket.
+func (c *counters) record(provider string, update func(*counts)) {
+	start := time.Now().Truncate(bucketWidth).Unix()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	providers, exists := c.buckets[start]
+	if !exists {
+		providers = make(map[string]*counts)
+		c.buckets[start] = providers
+	}
+	providerCounts, exists := providers[provider]
+	if !exists {
+		providerCounts = &counts{}
+		providers[provider] = providerCounts
+	}
+	update(providerCounts)
+}
+
+// sum returns the counts within the window before now, for one provider or,
+// when provider is empty, for all providers.
+func (c *counters) sum(provider string, window time.Duration, now time.Time) counts {
+	since := now.Add(-window).Truncate(bucketWidth).Unix()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var total counts
+	for start, providers := range c.buckets {
+		if start < since {
+			continue
+		}
+		if provider != "" {
+			if providerCounts, exists := providers[provider]; exists {
+				total.add(*providerCounts)
+			}
+			continue
+		}
+		for _, providerCounts := range providers {
+			total.add(*providerCounts)
+		}
+	}
+	return total
+}
+
+// prune drops buckets older than the retention.
+func (c *counters) prune(now time.Time) {
+	cutoff := now.Add(-c.retention - bucketWidth).Unix()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for start := range c.buckets {
+		if start < cutoff {
+			delete(c.buckets, start)
+		}
+	}
+}
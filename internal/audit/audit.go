@@ -0,0 +1,193 @@
+// Package audit records admin actions in an append-only log.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Config holds configuration for the audit log.
+type Config struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	Path       string `mapstructure:"path"`        // JSON-lines file; empty keeps entries in memory only
+	MaxEntries int    `mapstructure:"max_entries"` // entries kept in memory for queries
+}
+
+// Entry records one admin action.
+type Entry struct {
+	ID         int64           `json:"id"`
+	Time       time.Time       `json:"time"`
+	Actor      string          `json:"actor"`            // tenant of the admin key, or "anonymous"
+	Action     string          `json:"action"`           // such as "cache.purge"
+	Target     string          `json:"target,omitempty"` // what the action changed
+	RemoteAddr string          `json:"remote_addr,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+}
+
+// Query selects audit entries. Zero fields match every entry.
+type Query struct {
+	Actor  string
+	Action string
+	Target string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+}
+
+// matches reports whether the entry is selected by the query.
+func (q Query) matches(entry Entry) bool {
+	return (q.Actor == "" || entry.Actor == q.Actor) &&
+		(q.Action == "" || entry.Action == q.Action) &&
+		(q.Target == "" || entry.Target == q.Target) &&
+		(q.Since.IsZero() || !entry.Time.Before(q.Since)) &&
+		(q.Until.IsZero() || entry.Time.Before(q.Until))
+}
+
+// Log appends admin actions to a file and keeps the most recent ones in
+// memory for queries. The file is only ever appended to; entries beyond
+// MaxEntries stay in the file but are no longer served by Query.
+type Log struct {
+	maxEntries int
+	entries    []Entry
+	nextID     int64
+	file       *os.File
+	mutex      sync.RWMutex
+}
+
+// NewLog creates an audit log, loading the most recent entries of the
+// configured file.
+func NewLog(config Config) (*Log, error) {
+	maxEntries := config.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+
+	l := &Log{maxEntries: maxEntries, nextID: 1}
+	if config.Path == "" {
+		return l, nil
+	}
+
+	entries, err := readEntries(config.Path, maxEntries)
+	if err != nil {
+		return nil, err
+	}
+	l.entries = entries
+	if len(entries) > 0 {
+		l.nextID = entries[len(entries)-1].ID + 1
+	}
+
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0o755); err != nil {
+		return nil, err
+	}
+	l.file, err = os.OpenFile(config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	return l, nil
+}
+
+// Record appends an entry, assigning its ID and, if unset, its time. The
+// entry is written to the file before Record returns.
+func (l *Log) Record(entry Entry) (Entry, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	entry.ID = l.nextID
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	if l.file != nil {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return Entry{}, err
+		}
+		if _, err := l.file.Write(append(line, '\n')); err != nil {
+			return Entry{}, fmt.Errorf("failed to write audit log: %w", err)
+		}
+		if err := l.file.Sync(); err != nil {
+			return Entry{}, fmt.Errorf("failed to write audit log: %w", err)
+		}
+	}
+
+	l.nextID++
+	l.entries = append(l.entries, entry)
+	if len(l.entries) > l.maxEntries {
+		l.entries = l.entries[len(l.entries)-l.maxEntries:]
+	}
+	return entry, nil
+}
+
+// Query returns the entries selected by the query, newest first.
+func (l *Log) Query(query Query) []Entry {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	var result []Entry
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		if !query.matches(l.entries[i]) {
+			continue
+		}
+		result = append(result, l.entries[i])
+		if query.Limit > 0 && len(result) >= query.Limit {
+			break
+		}
+	}
+	return result
+}
+
+// Close closes the audit log file.
+func (l *Log) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// readEntries reads the last max entries of a JSON-lines file. A missing file
+// holds no entries; unreadable lines are skipped.
+func readEntries(path string, max int) ([]Entry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+		if len(entries) > 2*max {
+			entries = append([]Entry(nil), entries[len(entries)-max:]...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	if len(entries) > max {
+		entries = entries[len(entries)-max:]
+	}
+	return entries, nil
+}
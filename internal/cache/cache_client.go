@@ -1,8 +1,13 @@
 package cache
 
 import (
+	"container/list"
 	"context"
+	"encoding/json"
+	"sync"
 	"time"
+
+	"github.com/semantrix/semaroute/internal/observability"
 )
 
 // CacheClient defines the interface for caching operations.
@@ -31,90 +36,179 @@ type CacheConfig struct {
 	Type        string        `mapstructure:"type"`        // memory, redis, etc.
 	TTL         time.Duration `mapstructure:"ttl"`         // default TTL
 	MaxSize     int           `mapstructure:"max_size"`    // maximum number of items
-	MaxMemory   int64         `mapstructure:"max_memory"`  // maximum memory usage in bytes
+	MaxMemory   ByteSize      `mapstructure:"max_memory"`  // maximum memory usage in bytes
 	CleanupInterval time.Duration `mapstructure:"cleanup_interval"`
+
+	// Byte values of at least CompressionThreshold are stored compressed
+	Compression          string   `mapstructure:"compression"` // none, gzip
+	CompressionThreshold ByteSize `mapstructure:"compression_threshold"`
+
+	// Responses enables caching of non-streaming chat completion responses
+	Responses bool       `mapstructure:"responses"`
+	Key       KeyConfig  `mapstructure:"key"`
+	Warm      WarmConfig `mapstructure:"warm"`
 }
 
-// MemoryCache implements an in-memory cache client.
+// WarmConfig controls preloading the response cache at startup with the most
+// frequently requested recent responses from the usage store.
+type WarmConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	Window     time.Duration `mapstructure:"window"`      // how far back to look
+	MinHits    int           `mapstructure:"min_hits"`    // requests needed to qualify
+	MaxEntries int           `mapstructure:"max_entries"` // responses to preload
+}
+
+// itemOverhead approximates the per-entry bookkeeping (key, list element,
+// timestamps) so that many tiny entries still count against MaxMemory.
+const itemOverhead = 128
+
+// MemoryCache implements an in-memory cache client. Entries are evicted in
+// least-recently-used order once either MaxSize entries or MaxMemory bytes
+// would be exceeded, so a few large completions displace many small ones.
 type MemoryCache struct {
-	config CacheConfig
-	data   map[string]*cacheItem
-	// In production, this would use a proper LRU cache implementation
+	config     CacheConfig
+	compressor Compressor
+	metrics    *observability.Metrics
+
+	mutex sync.Mutex
+	data  map[string]*list.Element // of *cacheItem
+	order *list.List               // most recently used first
+	bytes int64
 }
 
 // cacheItem represents a cached item with metadata.
 type cacheItem struct {
+	Key        string
 	Value      interface{}
+	Compressed bool
+	Size       int64
 	ExpiresAt  time.Time
 	CreatedAt  time.Time
 	AccessCount int64
 }
 
 // NewMemoryCache creates a new in-memory cache instance.
-func NewMemoryCache(config CacheConfig) *MemoryCache {
-	return &MemoryCache{
-		config: config,
-		data:   make(map[string]*cacheItem),
+func NewMemoryCache(config CacheConfig, metrics *observability.Metrics) (*MemoryCache, error) {
+	compressor, err := NewCompressor(config.Compression)
+	if err != nil {
+		return nil, err
 	}
+
+	return &MemoryCache{
+		config:     config,
+		compressor: compressor,
+		metrics:    metrics,
+		data:       make(map[string]*list.Element),
+		order:      list.New(),
+	}, nil
 }
 
-// Get retrieves a value from the memory cache.
+// Get retrieves a value from the memory cache. Compressed values are
+// decompressed transparently.
 func (c *MemoryCache) Get(ctx context.Context, key string) (interface{}, bool, error) {
-	item, exists := c.data[key]
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, exists := c.data[key]
 	if !exists {
 		return nil, false, nil
 	}
+	item := element.Value.(*cacheItem)
 
 	// Check if item has expired
 	if time.Now().After(item.ExpiresAt) {
-		delete(c.data, key)
+		c.remove(element)
+		c.recordUsage()
 		return nil, false, nil
 	}
 
-	// Update access count and return value
+	// Update access count and recency
 	item.AccessCount++
+	c.order.MoveToFront(element)
+
+	if item.Compressed {
+		value, err := c.compressor.Decompress(item.Value.([]byte))
+		if err != nil {
+			return nil, false, err
+		}
+		return value, true, nil
+	}
 	return item.Value, true, nil
 }
 
-// Set stores a value in the memory cache.
+// Set stores a value in the memory cache. Byte slices over the compression
+// threshold are compressed when that makes them smaller. A value larger than
+// MaxMemory on its own is not stored.
 func (c *MemoryCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
 	if ttl == 0 {
 		ttl = c.config.TTL
 	}
 
 	item := &cacheItem{
+		Key:        key,
 		Value:      value,
 		ExpiresAt:  time.Now().Add(ttl),
 		CreatedAt:  time.Now(),
 		AccessCount: 0,
 	}
 
-	c.data[key] = item
+	if data, ok := value.([]byte); ok && c.compressor != nil && int64(len(data)) >= int64(c.config.CompressionThreshold) {
+		compressed, err := c.compressor.Compress(data)
+		if err != nil {
+			return err
+		}
+		if len(compressed) < len(data) {
+			item.Value = compressed
+			item.Compressed = true
+		}
+	}
+	item.Size = int64(len(key)) + valueSize(item.Value) + itemOverhead
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
-	// Simple cleanup: remove expired items if we're over the limit
-	if len(c.data) > c.config.MaxSize {
-		c.cleanup()
+	if existing, exists := c.data[key]; exists {
+		c.remove(existing)
 	}
+	if c.config.MaxMemory > 0 && item.Size > int64(c.config.MaxMemory) {
+		c.recordUsage()
+		return nil
+	}
+
+	c.data[key] = c.order.PushFront(item)
+	c.bytes += item.Size
+	c.evict()
+	c.recordUsage()
 
 	return nil
 }
 
 // Delete removes a value from the memory cache.
 func (c *MemoryCache) Delete(ctx context.Context, key string) error {
-	delete(c.data, key)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, exists := c.data[key]; exists {
+		c.remove(element)
+		c.recordUsage()
+	}
 	return nil
 }
 
 // Exists checks if a key exists in the memory cache.
 func (c *MemoryCache) Exists(ctx context.Context, key string) (bool, error) {
-	item, exists := c.data[key]
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, exists := c.data[key]
 	if !exists {
 		return false, nil
 	}
 
 	// Check if item has expired
-	if time.Now().After(item.ExpiresAt) {
-		delete(c.data, key)
+	if time.Now().After(element.Value.(*cacheItem).ExpiresAt) {
+		c.remove(element)
+		c.recordUsage()
 		return false, nil
 	}
 
@@ -123,44 +217,116 @@ func (c *MemoryCache) Exists(ctx context.Context, key string) (bool, error) {
 
 // Clear removes all values from the memory cache.
 func (c *MemoryCache) Clear(ctx context.Context) error {
-	c.data = make(map[string]*cacheItem)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.data = make(map[string]*list.Element)
+	c.order.Init()
+	c.bytes = 0
+	c.recordUsage()
 	return nil
 }
 
 // Close closes the memory cache.
 func (c *MemoryCache) Close() error {
-	c.data = nil
-	return nil
+	return c.Clear(context.Background())
+}
+
+// evict removes expired items and then the least recently used items until
+// the cache is within MaxSize and MaxMemory. The caller must hold the mutex.
+func (c *MemoryCache) evict() {
+	if !c.overLimit() {
+		return
+	}
+
+	c.cleanup()
+	for c.overLimit() {
+		oldest := c.order.Back()
+		if oldest == nil {
+			return
+		}
+		c.remove(oldest)
+	}
+}
+
+// overLimit reports whether either limit is exceeded.
+func (c *MemoryCache) overLimit() bool {
+	if c.config.MaxSize > 0 && len(c.data) > c.config.MaxSize {
+		return true
+	}
+	return c.config.MaxMemory > 0 && c.bytes > int64(c.config.MaxMemory)
 }
 
 // cleanup removes expired items from the cache.
 func (c *MemoryCache) cleanup() {
 	now := time.Now()
-	for key, item := range c.data {
-		if now.After(item.ExpiresAt) {
-			delete(c.data, key)
+	for _, element := range c.data {
+		if now.After(element.Value.(*cacheItem).ExpiresAt) {
+			c.remove(element)
 		}
 	}
 }
 
+// remove deletes an item and releases its bytes.
+func (c *MemoryCache) remove(element *list.Element) {
+	item := c.order.Remove(element).(*cacheItem)
+	delete(c.data, item.Key)
+	c.bytes -= item.Size
+}
+
+// recordUsage publishes the item count and memory use.
+func (c *MemoryCache) recordUsage() {
+	if c.metrics == nil {
+		return
+	}
+	c.metrics.RecordCacheSize("memory", len(c.data))
+	c.metrics.RecordCacheMemory("memory", c.bytes)
+}
+
+// valueSize estimates the memory held by a cached value. Byte slices and
+// strings are exact; other values are measured by their JSON encoding.
+func valueSize(value interface{}) int64 {
+	switch v := value.(type) {
+	case []byte:
+		return int64(len(v))
+	case string:
+		return int64(len(v))
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return 0
+		}
+		return int64(len(encoded))
+	}
+}
+
 // GetStats returns cache statistics.
 func (c *MemoryCache) GetStats() map[string]interface{} {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	now := time.Now()
 	expired := 0
-	totalSize := 0
+	compressed := 0
 
-	for _, item := range c.data {
+	for _, element := range c.data {
+		item := element.Value.(*cacheItem)
 		if now.After(item.ExpiresAt) {
 			expired++
 		}
-		totalSize++
+		if item.Compressed {
+			compressed++
+		}
 	}
 
 	return map[string]interface{}{
-		"total_items":    len(c.data),
-		"expired_items":  expired,
-		"active_items":   totalSize - expired,
-		"max_size":       c.config.MaxSize,
-		"cleanup_needed": expired > 0,
+		"total_items":      len(c.data),
+		"expired_items":    expired,
+		"active_items":     len(c.data) - expired,
+		"compressed_items": compressed,
+		"memory_bytes":     c.bytes,
+		"max_size":         c.config.MaxSize,
+		"max_memory":       int64(c.config.MaxMemory),
+		"cleanup_needed":   expired > 0,
 	}
 }
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a size in bytes. In configuration it may be a plain number or
+// a string with a unit such as "512KB", "100MB" or "1GiB".
+type ByteSize int64
+
+// byteUnits maps unit suffixes to multipliers, longest suffixes first so
+// "MiB" is not mistaken for "B".
+var byteUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+	{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+// UnmarshalText parses a size with an optional unit.
+func (s *ByteSize) UnmarshalText(text []byte) error {
+	value := strings.ToUpper(strings.TrimSpace(string(text)))
+
+	multiplier := int64(1)
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid byte size %q", text)
+	}
+	*s = ByteSize(n * float64(multiplier))
+	return nil
+}
+
+// Compressor compresses cached values.
+type Compressor interface {
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// NewCompressor returns the compressor for an algorithm name, or nil when
+// compression is disabled.
+func NewCompressor(algorithm string) (Compressor, error) {
+	switch algorithm {
+	case "", "none":
+		return nil, nil
+	case "gzip":
+		return gzipCompressor{}, nil
+	default:
+		return nil, fmt.Errorf("unknown cache compression: %s", algorithm)
+	}
+}
+
+// gzipCompressor compresses with gzip at the fastest level, which keeps
+// cache writes cheap while still shrinking text-heavy completions severalfold.
+type gzipCompressor struct{}
+
+// Compress gzips data.
+func (gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, gzip.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress gunzips data.
+func (gzipCompressor) Decompress(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
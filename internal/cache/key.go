@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+// keyVersion is part of every response key. Bump it whenever the canonical
+// form below changes so old entries are never served for new requests.
+const keyVersion = "v1"
+
+// KeyConfig selects which request fields make up a response cache key.
+type KeyConfig struct {
+	// IgnoreUser leaves the end-user identifier out of the key, so
+	// identical prompts from different users share an entry.
+	IgnoreUser bool `mapstructure:"ignore_user"`
+
+	// IgnoreRequestID leaves the client request ID out of the key. Request
+	// IDs are usually unique, so including them disables caching.
+	IgnoreRequestID bool `mapstructure:"ignore_request_id"`
+
+	// NormalizeWhitespace collapses runs of whitespace in message text and
+	// trims it, so formatting-only differences hit the same entry.
+	NormalizeWhitespace bool `mapstructure:"normalize_whitespace"`
+
+	// DeterministicOnly caches only requests with temperature 0.
+	DeterministicOnly bool `mapstructure:"deterministic_only"`
+
+	// Salt is mixed into every key. Changing it invalidates all entries.
+	Salt string `mapstructure:"salt"`
+
+	// TenantSalts are mixed into the keys of the given tenants, isolating
+	// their entries from everyone else's.
+	TenantSalts map[string]string `mapstructure:"tenant_salts"`
+}
+
+// KeyBuilder derives response cache keys from chat requests.
+//
+// A key is "chat:v1:" followed by the hex SHA-256 of the global salt, the
+// tenant salt and the canonical JSON of the request, separated by NUL bytes.
+// The canonical JSON is the request with the excluded fields zeroed; struct
+// fields encode in declaration order and map keys sorted, so the same
+// request always produces the same key across processes and restarts.
+type KeyBuilder struct {
+	config KeyConfig
+}
+
+// NewKeyBuilder creates a key builder.
+func NewKeyBuilder(config KeyConfig) *KeyBuilder {
+	return &KeyBuilder{config: config}
+}
+
+// Key returns the cache key for req on behalf of tenantID, or false if the
+// request must not be cached.
+func (b *KeyBuilder) Key(req models.ChatRequest, tenantID string) (string, bool) {
+	if req.Stream {
+		return "", false
+	}
+	if b.config.DeterministicOnly && req.Temperature != 0 {
+		return "", false
+	}
+
+	// Fields that never affect the response
+	req.Stream = false
+	req.CreatedAt = time.Time{}
+
+	if b.config.IgnoreUser {
+		req.User = ""
+	}
+	if b.config.IgnoreRequestID {
+		req.RequestID = ""
+	}
+
+	messages := make([]models.Message, len(req.Messages))
+	for i, msg := range req.Messages {
+		msg.Timestamp = time.Time{}
+		if b.config.NormalizeWhitespace {
+			msg.Content = normalizeContent(msg.Content)
+		}
+		messages[i] = msg
+	}
+	req.Messages = messages
+
+	canonical, err := json.Marshal(req)
+	if err != nil {
+		return "", false
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(b.config.Salt))
+	hash.Write([]byte{0})
+	hash.Write([]byte(b.config.TenantSalts[tenantID]))
+	hash.Write([]byte{0})
+	hash.Write(canonical)
+
+	return "chat:" + keyVersion + ":" + hex.EncodeToString(hash.Sum(nil)), true
+}
+
+// normalizeContent collapses whitespace in the text parts of content.
+func normalizeContent(content models.Content) models.Content {
+	parts := make([]models.ContentPart, len(content.Parts))
+	for i, part := range content.Parts {
+		if part.Type == "text" {
+			part.Text = strings.Join(strings.Fields(part.Text), " ")
+		}
+		parts[i] = part
+	}
+	return models.Content{Parts: parts}
+}
@@ -0,0 +1,298 @@
+package catalog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// ModelEntry describes a model served by a provider and its token prices.
+// Prices are in USD per 1K tokens.
+type ModelEntry struct {
+	Provider    string  `mapstructure:"provider" json:"provider"`
+	Model       string  `mapstructure:"model" json:"model"` // a trailing "*" matches by prefix
+	InputPer1K  float64 `mapstructure:"input_per_1k" json:"input_per_1k"`
+	OutputPer1K float64 `mapstructure:"output_per_1k" json:"output_per_1k"`
+
+	ContextWindow int `mapstructure:"context_window" json:"context_window,omitempty"` // max prompt + completion tokens, 0 if unknown
+
+	// Capabilities lists optional features such as "vision" and "tools"
+	Capabilities []string `mapstructure:"capabilities" json:"capabilities,omitempty"`
+
+	// BaseModel registers the entry as a fine-tune of another model, such as
+	// ft:gpt-4o-mini:acme::abc123 or a vLLM checkpoint. The provider lists
+	// it among its models, and it inherits the context window and
+	// capabilities of the base model's entry when it sets none.
+	BaseModel    string `mapstructure:"base_model" json:"base_model,omitempty"`
+	Owner        string `mapstructure:"owner" json:"owner,omitempty"`
+	TrainingDate string `mapstructure:"training_date" json:"training_date,omitempty"` // YYYY-MM-DD
+
+	// AllowedTenants restricts the model to these tenants; empty allows all
+	AllowedTenants []string `mapstructure:"allowed_tenants" json:"allowed_tenants,omitempty"`
+}
+
+// IsFineTune reports whether the entry registers a fine-tuned model.
+func (e ModelEntry) IsFineTune() bool {
+	return e.BaseModel != ""
+}
+
+// AllowsTenant reports whether a tenant may use the model.
+func (e ModelEntry) AllowsTenant(tenant string) bool {
+	if len(e.AllowedTenants) == 0 {
+		return true
+	}
+	for _, allowed := range e.AllowedTenants {
+		if allowed == tenant {
+			return true
+		}
+	}
+	return false
+}
+
+// HasCapability reports whether the entry lists a capability.
+func (e ModelEntry) HasCapability(capability string) bool {
+	for _, c := range e.Capabilities {
+		if strings.EqualFold(c, capability) {
+			return true
+		}
+	}
+	return false
+}
+
+// Config holds configuration for the model catalog.
+type Config struct {
+	File   string       `mapstructure:"file"`   // optional YAML file with a top-level "models" list
+	Models []ModelEntry `mapstructure:"models"` // inline entries, overridden by entries from File
+
+	// ReadModels re-reads the inline entries from the configuration on
+	// Reload. Without it, Reload keeps the inline entries given at startup
+	// and only re-reads File.
+	ReadModels func() ([]ModelEntry, error) `mapstructure:"-" json:"-"`
+}
+
+// Catalog holds the per-model pricing used for cost estimation. It can be
+// reloaded at runtime without restarting the server.
+type Catalog struct {
+	config   Config
+	entries  map[string]ModelEntry
+	loadedAt time.Time
+	mutex    sync.RWMutex
+}
+
+// NewCatalog creates a catalog and loads its initial entries.
+func NewCatalog(config Config) (*Catalog, error) {
+	c := &Catalog{
+		config:  config,
+		entries: make(map[string]ModelEntry),
+	}
+
+	if err := c.load(config.Models); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Reload re-reads the inline entries and the catalog file, replacing the
+// current entries atomically. On error the current entries are kept.
+func (c *Catalog) Reload() error {
+	c.mutex.RLock()
+	models := c.config.Models
+	c.mutex.RUnlock()
+
+	if c.config.ReadModels != nil {
+		var err error
+		if models, err = c.config.ReadModels(); err != nil {
+			return fmt.Errorf("failed to re-read inline catalog entries: %w", err)
+		}
+	}
+	return c.load(models)
+}
+
+// load builds the entries from the inline entries and the catalog file and
+// replaces the current ones.
+func (c *Catalog) load(models []ModelEntry) error {
+	entries := make(map[string]ModelEntry)
+	for _, entry := range models {
+		entries[entryKey(entry.Provider, entry.Model)] = entry
+	}
+
+	if c.config.File != "" {
+		fileEntries, err := loadFile(c.config.File)
+		if err != nil {
+			return err
+		}
+		for _, entry := range fileEntries {
+			entries[entryKey(entry.Provider, entry.Model)] = entry
+		}
+	}
+
+	for key, entry := range entries {
+		if entry.Provider == "" || entry.Model == "" {
+			return fmt.Errorf("catalog entry %q must set provider and model", key)
+		}
+		if entry.InputPer1K < 0 || entry.OutputPer1K < 0 {
+			return fmt.Errorf("catalog entry %q has negative pricing", key)
+		}
+		if entry.ContextWindow < 0 {
+			return fmt.Errorf("catalog entry %q has negative context window", key)
+		}
+		if entry.IsFineTune() {
+			if strings.HasSuffix(entry.Model, "*") {
+				return fmt.Errorf("fine-tune %q must name one model, not a prefix", key)
+			}
+			if entry.TrainingDate != "" {
+				if _, err := time.Parse("2006-01-02", entry.TrainingDate); err != nil {
+					return fmt.Errorf("fine-tune %q has training_date %q, expected YYYY-MM-DD", key, entry.TrainingDate)
+				}
+			}
+		}
+	}
+
+	// Fine-tunes inherit what they leave unset from their base model
+	for key, entry := range entries {
+		if !entry.IsFineTune() {
+			continue
+		}
+		base, found := lookup(entries, entry.Provider, entry.BaseModel)
+		if !found {
+			continue
+		}
+		if entry.ContextWindow == 0 {
+			entry.ContextWindow = base.ContextWindow
+		}
+		if len(entry.Capabilities) == 0 {
+			entry.Capabilities = base.Capabilities
+		}
+		entries[key] = entry
+	}
+
+	c.mutex.Lock()
+	c.entries = entries
+	c.config.Models = models
+	c.loadedAt = time.Now()
+	c.mutex.Unlock()
+
+	return nil
+}
+
+// loadFile reads catalog entries from a YAML file.
+func loadFile(path string) ([]ModelEntry, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read catalog file: %w", err)
+	}
+
+	var entries []ModelEntry
+	if err := v.UnmarshalKey("models", &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse catalog file: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Lookup returns the entry for a provider's model. Exact matches win over
+// prefix entries, and longer prefixes win over shorter ones.
+func (c *Catalog) Lookup(provider, model string) (ModelEntry, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return lookup(c.entries, provider, model)
+}
+
+// lookup finds the entry for a provider's model in entries.
+func lookup(entries map[string]ModelEntry, provider, model string) (ModelEntry, bool) {
+	if entry, exists := entries[entryKey(provider, model)]; exists {
+		return entry, true
+	}
+
+	var best ModelEntry
+	found := false
+	for _, entry := range entries {
+		if entry.Provider != provider || !strings.HasSuffix(entry.Model, "*") {
+			continue
+		}
+		prefix := strings.TrimSuffix(entry.Model, "*")
+		if strings.HasPrefix(model, prefix) && (!found || len(prefix) > len(best.Model)-1) {
+			best = entry
+			found = true
+		}
+	}
+
+	return best, found
+}
+
+// EstimateCost returns the cost of a request with the given token counts, if
+// the model is in the catalog.
+func (c *Catalog) EstimateCost(provider, model string, inputTokens, outputTokens int) (float64, bool) {
+	entry, found := c.Lookup(provider, model)
+	if !found {
+		return 0, false
+	}
+
+	return float64(inputTokens)*entry.InputPer1K/1000 + float64(outputTokens)*entry.OutputPer1K/1000, true
+}
+
+// Entries returns all catalog entries sorted by provider and model.
+func (c *Catalog) Entries() []ModelEntry {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	entries := make([]ModelEntry, 0, len(c.entries))
+	for _, entry := range c.entries {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Provider != entries[j].Provider {
+			return entries[i].Provider < entries[j].Provider
+		}
+		return entries[i].Model < entries[j].Model
+	})
+
+	return entries
+}
+
+// FineTunes returns the fine-tuned models registered for a provider, sorted
+// by name.
+func (c *Catalog) FineTunes(provider string) []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var models []string
+	for _, entry := range c.entries {
+		if entry.Provider == provider && entry.IsFineTune() {
+			models = append(models, entry.Model)
+		}
+	}
+	sort.Strings(models)
+	return models
+}
+
+// LoadedAt returns when the catalog was last (re)loaded.
+func (c *Catalog) LoadedAt() time.Time {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.loadedAt
+}
+
+// GetConfig returns the catalog configuration.
+func (c *Catalog) GetConfig() Config {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.config
+}
+
+// entryKey builds the lookup key for a provider's model.
+func entryKey(provider, model string) string {
+	return provider + "/" + model
+}
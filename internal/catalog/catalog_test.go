@@ -0,0 +1,80 @@
+package catalog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReloadRereadsInlineEntriesAndFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pricing.yaml")
+	writeFile(t, file, `models:
+  - {provider: "openai", model: "gpt-4o", input_per_1k: 0.005, output_per_1k: 0.015}
+`)
+
+	inline := []ModelEntry{
+		{Provider: "openai", Model: "gpt-4o", InputPer1K: 1, OutputPer1K: 1},
+		{Provider: "anthropic", Model: "claude-sonnet-4*", InputPer1K: 0.003, OutputPer1K: 0.015},
+	}
+	var readErr error
+	catalog, err := NewCatalog(Config{
+		File:   file,
+		Models: inline,
+		ReadModels: func() ([]ModelEntry, error) {
+			return inline, readErr
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry, _ := catalog.Lookup("openai", "gpt-4o"); entry.InputPer1K != 0.005 {
+		t.Fatalf("gpt-4o input price = %v, want the file's to override the inline entry", entry.InputPer1K)
+	}
+
+	// Inline entries edited in the config are picked up by a reload
+	inline = []ModelEntry{{Provider: "anthropic", Model: "claude-sonnet-4*", InputPer1K: 0.002, OutputPer1K: 0.01}}
+	if err := catalog.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if entry, _ := catalog.Lookup("anthropic", "claude-sonnet-4-20250514"); entry.InputPer1K != 0.002 {
+		t.Fatalf("claude input price = %v after reload, want the edited inline entry", entry.InputPer1K)
+	}
+	if got := catalog.GetConfig().Models; len(got) != 1 {
+		t.Fatalf("GetConfig().Models = %v, want the re-read entries", got)
+	}
+
+	// A failed read or an invalid entry keeps the current catalog
+	readErr = errors.New("config file missing")
+	if err := catalog.Reload(); err == nil {
+		t.Fatal("Reload() error = nil for a failed read")
+	}
+	readErr = nil
+	inline = []ModelEntry{{Provider: "anthropic", Model: "claude-sonnet-4*", InputPer1K: -1}}
+	if err := catalog.Reload(); err == nil {
+		t.Fatal("Reload() error = nil for negative pricing")
+	}
+	if entry, _ := catalog.Lookup("anthropic", "claude-sonnet-4-20250514"); entry.InputPer1K != 0.002 {
+		t.Fatalf("claude input price = %v after failed reloads, want the last good one", entry.InputPer1K)
+	}
+}
+
+func TestReloadWithoutReaderKeepsStartupEntries(t *testing.T) {
+	catalog, err := NewCatalog(Config{Models: []ModelEntry{{Provider: "openai", Model: "gpt-4o", InputPer1K: 0.005}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := catalog.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := catalog.Lookup("openai", "gpt-4o"); !found {
+		t.Fatal("startup entry lost on reload")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,190 @@
+package continuation
+
+import (
+	"context"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/observability"
+	"github.com/semantrix/semaroute/internal/tokenizer"
+)
+
+// FinishReasonLength is the finish reason providers report when output was
+// cut off by max_tokens.
+const FinishReasonLength = "length"
+
+// defaultPrompt asks the model to pick up where it stopped.
+const defaultPrompt = "Continue exactly where you left off, without repeating anything."
+
+// Config holds configuration for automatic continuation of truncated responses.
+type Config struct {
+	Enabled          bool   `mapstructure:"enabled"`
+	MaxContinuations int    `mapstructure:"max_continuations"` // follow-up requests per response
+	MaxTokens        int    `mapstructure:"max_tokens"`        // completion token budget across all parts, 0 for no limit
+	Prompt           string `mapstructure:"prompt"`
+}
+
+// CompleteFunc executes a single chat completion.
+type CompleteFunc func(ctx context.Context, req models.ChatRequest) (*models.ChatResponse, error)
+
+// StreamFunc starts a single streaming chat completion.
+type StreamFunc func(ctx context.Context, req models.ChatRequest) (<-chan models.StreamResponse, error)
+
+// Continuer detects truncated responses and, when enabled, requests
+// continuations from the same provider and stitches them together.
+type Continuer struct {
+	config  Config
+	metrics *observability.Metrics
+}
+
+// NewContinuer creates a new continuer.
+func NewContinuer(config Config, metrics *observability.Metrics) *Continuer {
+	if config.Prompt == "" {
+		config.Prompt = defaultPrompt
+	}
+
+	return &Continuer{
+		config:  config,
+		metrics: metrics,
+	}
+}
+
+// Continue returns resp unchanged unless it was truncated. Truncated responses
+// are extended with continuation requests until the model finishes, the
+// continuation count or token budget is spent, or a continuation fails; the
+// parts are merged into a single response.
+func (c *Continuer) Continue(ctx context.Context, providerName string, req models.ChatRequest, resp *models.ChatResponse, complete CompleteFunc) *models.ChatResponse {
+	if resp == nil || len(resp.Choices) == 0 || resp.Choices[0].FinishReason != FinishReasonLength {
+		return resp
+	}
+
+	c.metrics.RecordTruncation(providerName, req.Model)
+	if !c.config.Enabled {
+		return resp
+	}
+
+	merged := *resp
+	merged.Choices = append([]models.Choice(nil), resp.Choices...)
+	content := merged.Choices[0].Message.Content.Text()
+
+	for i := 0; i < c.config.MaxContinuations; i++ {
+		if c.budgetSpent(merged.Usage.CompletionTokens) {
+			break
+		}
+
+		next, err := complete(ctx, c.continuationRequest(req, content))
+		if err != nil || len(next.Choices) == 0 {
+			// Keep what we have; the response stays marked as truncated
+			break
+		}
+		c.metrics.RecordContinuation(providerName, req.Model)
+
+		content += next.Choices[0].Message.Content.Text()
+		merged.Choices[0].FinishReason = next.Choices[0].FinishReason
+		merged.Usage.PromptTokens += next.Usage.PromptTokens
+		merged.Usage.CompletionTokens += next.Usage.CompletionTokens
+		merged.Usage.TotalTokens += next.Usage.TotalTokens
+
+		if next.Choices[0].FinishReason != FinishReasonLength {
+			break
+		}
+	}
+
+	merged.Choices[0].Message.Content = models.TextContent(content)
+	return &merged
+}
+
+// ContinueStream forwards the chunks of stream and, when the stream ends
+// truncated, transparently appends continuation streams. The intermediate
+// "length" finish reasons are hidden from the client.
+func (c *Continuer) ContinueStream(ctx context.Context, providerName string, req models.ChatRequest, stream <-chan models.StreamResponse, start StreamFunc) <-chan models.StreamResponse {
+	out := make(chan models.StreamResponse)
+
+	go func() {
+		defer close(out)
+
+		var content string
+		continuations := 0
+		current := stream
+
+		for current != nil {
+			next := (<-chan models.StreamResponse)(nil)
+
+			for chunk := range current {
+				truncated := false
+				for _, choice := range chunk.Choices {
+					if choice.Index == 0 {
+						content += choice.Delta.Content.Text()
+						if choice.FinishReason == FinishReasonLength {
+							truncated = true
+						}
+					}
+				}
+
+				if truncated {
+					if continuations == 0 {
+						c.metrics.RecordTruncation(providerName, req.Model)
+					}
+					if c.canContinueStream(providerName, req.Model, content, continuations) {
+						if s, err := start(ctx, c.continuationRequest(req, content)); err == nil {
+							next = s
+							continuations++
+							c.metrics.RecordContinuation(providerName, req.Model)
+							chunk = hideFinishReason(chunk)
+						}
+					}
+				}
+
+				select {
+				case out <- chunk:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			current = next
+		}
+	}()
+
+	return out
+}
+
+// canContinueStream reports whether another continuation is allowed for a stream.
+func (c *Continuer) canContinueStream(providerName, model, content string, continuations int) bool {
+	if !c.config.Enabled || continuations >= c.config.MaxContinuations {
+		return false
+	}
+	return !c.budgetSpent(tokenizer.CountText(providerName, model, content))
+}
+
+// budgetSpent reports whether the completion token budget is used up.
+func (c *Continuer) budgetSpent(completionTokens int) bool {
+	return c.config.MaxTokens > 0 && completionTokens >= c.config.MaxTokens
+}
+
+// continuationRequest builds the follow-up request: the original conversation,
+// the partial answer so far, and the continuation prompt.
+func (c *Continuer) continuationRequest(req models.ChatRequest, partial string) models.ChatRequest {
+	messages := make([]models.Message, 0, len(req.Messages)+2)
+	messages = append(messages, req.Messages...)
+	messages = append(messages,
+		models.Message{Role: "assistant", Content: models.TextContent(partial)},
+		models.Message{Role: "user", Content: models.TextContent(c.config.Prompt)},
+	)
+
+	next := req
+	next.Messages = messages
+	return next
+}
+
+// hideFinishReason clears the truncation marker from a chunk that will be followed by a continuation.
+func hideFinishReason(chunk models.StreamResponse) models.StreamResponse {
+	choices := make([]models.StreamChoice, len(chunk.Choices))
+	copy(choices, chunk.Choices)
+	for i := range choices {
+		if choices[i].Index == 0 {
+			choices[i].FinishReason = ""
+		}
+	}
+	chunk.Choices = choices
+	return chunk
+}
@@ -0,0 +1,312 @@
+// Package dataset samples served chat completions into a dataset for
+// fine-tuning, with personal data scrubbed, for tenants that consented.
+package dataset
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+// Config configures dataset sampling. Only completions of tenants with
+// dataset_consent are sampled.
+type Config struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	SampleRate    float64       `mapstructure:"sample_rate"`    // fraction of eligible completions sampled
+	BufferSize    int           `mapstructure:"buffer_size"`    // samples waiting to be written, beyond which they are dropped
+	FlushInterval time.Duration `mapstructure:"flush_interval"` // how often waiting samples are written
+	MaxBatch      int           `mapstructure:"max_batch"`      // samples written at once; S3 objects hold one batch
+
+	// Scrub lists patterns scrubbed in addition to the built-in ones
+	// (emails, phone numbers, card numbers, SSNs, IBANs, IPs and secrets)
+	Scrub []PatternConfig `mapstructure:"scrub"`
+
+	Sink SinkConfig `mapstructure:"sink"`
+}
+
+// WithDefaults fills in unset fields and validates the configuration.
+func (c Config) WithDefaults() (Config, error) {
+	if c.SampleRate <= 0 || c.SampleRate > 1 {
+		return c, fmt.Errorf("dataset sample_rate must be greater than 0 and at most 1")
+	}
+	if c.BufferSize <= 0 {
+		c.BufferSize = 1000
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = time.Minute
+	}
+	if c.MaxBatch <= 0 {
+		c.MaxBatch = 500
+	}
+	return c, nil
+}
+
+// Sample is a sampled chat completion: the prompt messages followed by the
+// completion as the last assistant message.
+type Sample struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id,omitempty"`
+	Tenant    string    `json:"tenant"`
+	Provider  string    `json:"provider"`
+	Model     string    `json:"model"`
+	Messages  []Message `json:"messages"`
+	Metadata  Metadata  `json:"metadata"`
+}
+
+// Message is a message of a sample. Only text content is kept.
+type Message struct {
+	Role       string            `json:"role"`
+	Content    string            `json:"content"`
+	Name       string            `json:"name,omitempty"`
+	ToolCalls  []models.ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string            `json:"tool_call_id,omitempty"`
+}
+
+// Metadata describes how a sample was served.
+type Metadata struct {
+	PromptTokens     int               `json:"prompt_tokens"`
+	CompletionTokens int               `json:"completion_tokens"`
+	LatencyMs        int64             `json:"latency_ms"`
+	FinishReason     string            `json:"finish_reason,omitempty"`
+	Temperature      float64           `json:"temperature,omitempty"`
+	MaxTokens        int               `json:"max_tokens,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"` // the request's metadata
+	Redactions       int               `json:"redactions,omitempty"`
+}
+
+// NewSample builds a sample from a request and the response that served it.
+// Images are left out; the first choice is the completion.
+func NewSample(req models.ChatRequest, response *models.ChatResponse, tenant, provider string, latency time.Duration) Sample {
+	sample := Sample{
+		Time:      time.Now(),
+		RequestID: req.RequestID,
+		Tenant:    tenant,
+		Provider:  provider,
+		Model:     response.Model,
+		Messages:  make([]Message, 0, len(req.Messages)+1),
+		Metadata: Metadata{
+			PromptTokens:     response.Usage.PromptTokens,
+			CompletionTokens: response.Usage.CompletionTokens,
+			LatencyMs:        latency.Milliseconds(),
+			Temperature:      req.Temperature,
+			MaxTokens:        req.MaxTokens,
+			Labels:           req.Metadata,
+		},
+	}
+	if sample.Model == "" {
+		sample.Model = req.Model
+	}
+	for _, message := range req.Messages {
+		sample.Messages = append(sample.Messages, newMessage(message))
+	}
+	if len(response.Choices) > 0 {
+		sample.Messages = append(sample.Messages, newMessage(response.Choices[0].Message))
+		sample.Metadata.FinishReason = response.Choices[0].FinishReason
+	}
+	return sample
+}
+
+// newMessage converts a message, keeping its text.
+func newMessage(message models.Message) Message {
+	return Message{
+		Role:       message.Role,
+		Content:    message.Content.Text(),
+		Name:       message.Name,
+		ToolCalls:  message.ToolCalls,
+		ToolCallID: message.ToolCallID,
+	}
+}
+
+// scrub replaces personal data in the sample's text, tool call arguments and
+// labels, counting the replacements.
+func (s Sample) scrub(scrubber *Scrubber) Sample {
+	count := func(text string) string {
+		scrubbed, n := scrubber.Scrub(text)
+		s.Metadata.Redactions += n
+		return scrubbed
+	}
+
+	messages := make([]Message, len(s.Messages))
+	for i, message := range s.Messages {
+		message.Content = count(message.Content)
+		if len(message.ToolCalls) > 0 {
+			calls := make([]models.ToolCall, len(message.ToolCalls))
+			for j, call := range message.ToolCalls {
+				call.Function.Arguments = count(call.Function.Arguments)
+				calls[j] = call
+			}
+			message.ToolCalls = calls
+		}
+		messages[i] = message
+	}
+	s.Messages = messages
+
+	if len(s.Metadata.Labels) > 0 {
+		labels := make(map[string]string, len(s.Metadata.Labels))
+		for key, value := range s.Metadata.Labels {
+			labels[key] = count(value)
+		}
+		s.Metadata.Labels = labels
+	}
+	return s
+}
+
+// Stats counts the samples handled since startup.
+type Stats struct {
+	Written int64 `json:"written"`
+	Dropped int64 `json:"dropped"` // because the buffer was full
+	Failed  int64 `json:"failed"`  // in batches the sink did not accept
+}
+
+// Pipeline scrubs sampled completions and writes them to the sink in
+// batches, off the request path.
+type Pipeline struct {
+	config   Config
+	scrubber *Scrubber
+	sink     Sink
+	logger   *zap.Logger
+	samples  chan Sample
+
+	mutex sync.Mutex // guards stats
+	stats Stats
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewPipeline validates the configuration and creates the sink.
+func NewPipeline(config Config, logger *zap.Logger) (*Pipeline, error) {
+	config, err := config.WithDefaults()
+	if err != nil {
+		return nil, err
+	}
+	scrubber, err := NewScrubber(config.Scrub)
+	if err != nil {
+		return nil, err
+	}
+	sink, err := newSink(config.Sink)
+	if err != nil {
+		return nil, err
+	}
+	return &Pipeline{
+		config:   config,
+		scrubber: scrubber,
+		sink:     sink,
+		logger:   logger,
+		samples:  make(chan Sample, config.BufferSize),
+	}, nil
+}
+
+// Sampled reports whether an eligible completion is sampled.
+func (p *Pipeline) Sampled() bool {
+	return rand.Float64() < p.config.SampleRate
+}
+
+// Add queues a sample for writing. It never blocks; when the buffer is full
+// the sample is dropped and false returned.
+func (p *Pipeline) Add(sample Sample) bool {
+	select {
+	case p.samples <- sample:
+		return true
+	default:
+		p.count(func(stats *Stats) { stats.Dropped++ })
+		return false
+	}
+}
+
+// Stats returns the samples handled since startup.
+func (p *Pipeline) Stats() Stats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.stats
+}
+
+// Start starts writing queued samples.
+func (p *Pipeline) Start() {
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(p.config.FlushInterval)
+		defer ticker.Stop()
+
+		var batch []Sample
+		for {
+			select {
+			case <-p.stop:
+				// Write what was queued before shutdown
+				for {
+					select {
+					case sample := <-p.samples:
+						batch = append(batch, sample)
+						if len(batch) >= p.config.MaxBatch {
+							p.write(batch)
+							batch = nil
+						}
+					default:
+						p.write(batch)
+						return
+					}
+				}
+			case sample := <-p.samples:
+				batch = append(batch, sample)
+				if len(batch) >= p.config.MaxBatch {
+					p.write(batch)
+					batch = nil
+				}
+			case <-ticker.C:
+				p.write(batch)
+				batch = nil
+			}
+		}
+	}()
+}
+
+// Stop writes the queued samples and closes the sink.
+func (p *Pipeline) Stop() error {
+	if p.stop != nil {
+		close(p.stop)
+		<-p.done
+	}
+	return p.sink.Close()
+}
+
+// write scrubs a batch and writes it to the sink.
+func (p *Pipeline) write(batch []Sample) {
+	if len(batch) == 0 {
+		return
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, sample := range batch {
+		if err := encoder.Encode(sample.scrub(p.scrubber)); err != nil {
+			p.logger.Warn("Failed to encode dataset sample", zap.Error(err))
+		}
+	}
+
+	if err := p.sink.Write(context.Background(), buf.Bytes(), time.Now()); err != nil {
+		p.logger.Warn("Failed to write dataset samples",
+			zap.Int("samples", len(batch)), zap.Error(err))
+		p.count(func(stats *Stats) { stats.Failed += int64(len(batch)) })
+		return
+	}
+	p.count(func(stats *Stats) { stats.Written += int64(len(batch)) })
+}
+
+// count updates the stats.
+func (p *Pipeline) count(update func(stats *Stats)) {
+	p.mutex.Lock()
+	update(&p.stats)
+	p.mutex.Unlock()
+}
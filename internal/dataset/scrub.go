@@ -0,0 +1,98 @@
+package dataset
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PatternConfig is an additional pattern scrubbed from samples.
+type PatternConfig struct {
+	Name    string `mapstructure:"name"`    // placeholder, e.g. "employee_id" becomes [EMPLOYEE_ID]
+	Pattern string `mapstructure:"pattern"` // regular expression
+}
+
+// scrubPattern replaces the matches of a pattern with a placeholder. A match
+// is only replaced when valid accepts it.
+type scrubPattern struct {
+	placeholder string
+	re          *regexp.Regexp
+	valid       func(match string) bool
+}
+
+// builtinPatterns are always scrubbed, in this order: card numbers before
+// phone numbers, whose digits they would otherwise match.
+var builtinPatterns = []scrubPattern{
+	{placeholder: "[EMAIL]", re: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{placeholder: "[SECRET]", re: regexp.MustCompile(`\b(?:sk|pk|rk)-[A-Za-z0-9_-]{16,}|\bAKIA[0-9A-Z]{16}\b|\b(?:ghp|gho|ghs)_[A-Za-z0-9]{36}\b|\bBearer\s+[A-Za-z0-9._~+/-]{16,}=*`)},
+	{placeholder: "[CARD]", re: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), valid: luhn},
+	{placeholder: "[IBAN]", re: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`)},
+	{placeholder: "[SSN]", re: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	{placeholder: "[PHONE]", re: regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?\(?\b\d{3}\)?[\s.-]?\d{3}[\s.-]?\d{4}\b`)},
+	{placeholder: "[IP]", re: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`)},
+}
+
+// Scrubber replaces personal data and secrets in text with placeholders.
+type Scrubber struct {
+	patterns []scrubPattern
+}
+
+// NewScrubber creates a scrubber for the built-in patterns and the given
+// additional ones.
+func NewScrubber(extra []PatternConfig) (*Scrubber, error) {
+	patterns := append([]scrubPattern(nil), builtinPatterns...)
+	for _, pattern := range extra {
+		if pattern.Name == "" {
+			return nil, fmt.Errorf("scrub pattern needs a name")
+		}
+		re, err := regexp.Compile(pattern.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scrub pattern %q: %w", pattern.Name, err)
+		}
+		patterns = append(patterns, scrubPattern{
+			placeholder: "[" + strings.ToUpper(pattern.Name) + "]",
+			re:          re,
+		})
+	}
+	return &Scrubber{patterns: patterns}, nil
+}
+
+// Scrub returns text with every match replaced by its placeholder and the
+// number of matches replaced.
+func (s *Scrubber) Scrub(text string) (string, int) {
+	replaced := 0
+	for _, pattern := range s.patterns {
+		text = pattern.re.ReplaceAllStringFunc(text, func(match string) string {
+			if pattern.valid != nil && !pattern.valid(match) {
+				return match
+			}
+			replaced++
+			return pattern.placeholder
+		})
+	}
+	return text, replaced
+}
+
+// luhn reports whether the digits of a candidate card number pass the Luhn
+// check, which tells card numbers from other long digit runs.
+func luhn(number string) bool {
+	sum, digits := 0, 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+		double = !double
+	}
+	return digits >= 13 && sum%10 == 0
+}
@@ -0,0 +1,185 @@
+package dataset
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// Sink types.
+const (
+	SinkFile = "file"
+	SinkS3   = "s3"
+)
+
+// defaultS3Timeout bounds an upload when the sink sets no timeout.
+const defaultS3Timeout = 30 * time.Second
+
+// SinkConfig configures where sampled batches are written, as JSON lines.
+type SinkConfig struct {
+	Type string `mapstructure:"type"` // file or s3
+
+	// File: batches are appended to one file per UTC day in the directory
+	Directory string `mapstructure:"directory"`
+
+	// S3: each batch is uploaded as an object under the prefix, with
+	// credentials from the AWS default chain
+	Bucket   string        `mapstructure:"bucket"`
+	Prefix   string        `mapstructure:"prefix"`
+	Region   string        `mapstructure:"region"`   // defaults to AWS_REGION
+	Endpoint string        `mapstructure:"endpoint"` // for S3-compatible stores; default https://s3.<region>.amazonaws.com
+	Profile  string        `mapstructure:"profile"`  // shared credentials profile, defaults to AWS_PROFILE
+	Timeout  time.Duration `mapstructure:"timeout"`  // per upload
+}
+
+// Sink writes batches of samples.
+type Sink interface {
+	// Write stores a batch of JSON lines written at the given time.
+	Write(ctx context.Context, batch []byte, at time.Time) error
+	Close() error
+}
+
+// s3KeyPattern restricts S3 prefixes to characters that need no encoding, so
+// object paths are signed as sent.
+var s3KeyPattern = regexp.MustCompile(`^[A-Za-z0-9/_.-]*$`)
+
+// newSink creates a sink from its configuration.
+func newSink(config SinkConfig) (Sink, error) {
+	switch config.Type {
+	case SinkFile:
+		if config.Directory == "" {
+			return nil, fmt.Errorf("file dataset sink needs a directory")
+		}
+		if err := os.MkdirAll(config.Directory, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create dataset directory: %w", err)
+		}
+		return &fileSink{directory: config.Directory}, nil
+	case SinkS3:
+		return newS3Sink(config)
+	default:
+		return nil, fmt.Errorf("unknown dataset sink type %q", config.Type)
+	}
+}
+
+// fileSink appends batches to daily JSON-lines files.
+type fileSink struct {
+	directory string
+}
+
+// Write appends the batch to the file of the batch's day.
+func (s *fileSink) Write(ctx context.Context, batch []byte, at time.Time) error {
+	path := filepath.Join(s.directory, "dataset-"+at.UTC().Format("2006-01-02")+".jsonl")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(batch); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Close implements Sink; files are closed after every batch.
+func (s *fileSink) Close() error {
+	return nil
+}
+
+// s3Sink uploads each batch as an object.
+type s3Sink struct {
+	endpoint string
+	bucket   string
+	prefix   string
+	client   *http.Client
+}
+
+// newS3Sink validates the S3 configuration and sets up SigV4 signing.
+func newS3Sink(config SinkConfig) (*s3Sink, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("s3 dataset sink needs a bucket")
+	}
+	if !s3KeyPattern.MatchString(config.Prefix) {
+		return nil, fmt.Errorf("s3 dataset prefix may only contain letters, digits and / _ . -")
+	}
+	region := config.Region
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		if region == "" {
+			return nil, fmt.Errorf("s3 dataset sink needs a region (region or AWS_REGION)")
+		}
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultS3Timeout
+	}
+
+	transport, err := providers.NewCredentialsTransport(providers.CredentialsConfig{
+		Type:    providers.CredentialsAWS,
+		Region:  region,
+		Service: "s3",
+		Profile: config.Profile,
+	}, http.DefaultTransport)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := strings.Trim(config.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &s3Sink{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		bucket:   config.Bucket,
+		prefix:   prefix,
+		client:   &http.Client{Timeout: timeout, Transport: transport},
+	}, nil
+}
+
+// Write uploads the batch as <prefix>/YYYY/MM/DD/<time>-<random>.jsonl. The
+// random suffix keeps the objects of replicas flushing at once apart.
+func (s *s3Sink) Write(ctx context.Context, batch []byte, at time.Time) error {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	at = at.UTC()
+	key := s.prefix + at.Format("2006/01/02/20060102T150405.000Z") + "-" + hex.EncodeToString(suffix) + ".jsonl"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.endpoint+"/"+s.bucket+"/"+key, bytes.NewReader(batch))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("s3 upload of %s returned %d: %s", key, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// Close implements Sink.
+func (s *s3Sink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
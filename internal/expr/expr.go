@@ -0,0 +1,843 @@
+// Package expr evaluates small boolean expressions over named values, used to
+// express routing logic in configuration. The syntax follows CEL:
+//
+//	tenant in ["acme", "globex"] && prompt_length > 20000
+//	model.startsWith("gpt-4") || headers["x-priority"] == "high"
+//
+// Values are numbers, strings, booleans, lists, maps and null. Operators are
+// || && ! == != < <= > >= in + - * / and parentheses. Strings have the methods
+// contains, startsWith, endsWith and matches (a regular expression); the
+// functions size, lower and upper take one argument. A missing map key is null.
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Program is a compiled expression.
+type Program struct {
+	source    string
+	root      node
+	variables []string
+}
+
+// Compile parses an expression.
+func Compile(source string) (*Program, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, variables: make(map[string]bool)}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at offset %d", p.peek().text, p.peek().offset)
+	}
+
+	variables := make([]string, 0, len(p.variables))
+	for name := range p.variables {
+		variables = append(variables, name)
+	}
+	sort.Strings(variables)
+	return &Program{source: source, root: root, variables: variables}, nil
+}
+
+// String returns the source of the expression.
+func (p *Program) String() string {
+	return p.source
+}
+
+// Variables returns the names of the variables the expression refers to.
+func (p *Program) Variables() []string {
+	return p.variables
+}
+
+// Eval evaluates the expression with the given variables.
+func (p *Program) Eval(vars map[string]interface{}) (interface{}, error) {
+	return p.root.eval(vars)
+}
+
+// EvalBool evaluates an expression that must produce a boolean.
+func (p *Program) EvalBool(vars map[string]interface{}) (bool, error) {
+	value, err := p.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression produced %s, not a boolean", typeName(value))
+	}
+	return result, nil
+}
+
+// Tokens
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+// operators lists the operators and punctuation, longest first.
+var operators = []string{"||", "&&", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "(", ")", "[", "]", ",", "."}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := rune(source[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(source) && (source[i] >= '0' && source[i] <= '9' || source[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: source[start:i], offset: start})
+		case c == '"' || c == '\'':
+			start := i
+			var text strings.Builder
+			i++
+			for {
+				if i >= len(source) {
+					return nil, fmt.Errorf("unterminated string at offset %d", start)
+				}
+				if source[i] == byte(c) {
+					i++
+					break
+				}
+				if source[i] == '\\' && i+1 < len(source) {
+					i++
+					switch source[i] {
+					case 'n':
+						text.WriteByte('\n')
+					case 't':
+						text.WriteByte('\t')
+					default:
+						text.WriteByte(source[i])
+					}
+					i++
+					continue
+				}
+				text.WriteByte(source[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text.String(), offset: start})
+		case isIdentStart(source[i]):
+			start := i
+			for i < len(source) && (isIdentStart(source[i]) || source[i] >= '0' && source[i] <= '9') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[start:i], offset: start})
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op, offset: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, text: "end of expression", offset: len(source)}), nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// Parser
+
+type parser struct {
+	tokens    []token
+	pos       int
+	variables map[string]bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the given operator or keyword.
+func (p *parser) accept(text string) bool {
+	t := p.peek()
+	if (t.kind == tokenOperator || t.kind == tokenIdent) && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		t := p.peek()
+		return fmt.Errorf("expected %q but found %s at offset %d", text, t.text, t.offset)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{or: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.accept("!") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">", "in"} {
+		if p.accept(op) {
+			right, err := p.parseSum()
+			if err != nil {
+				return nil, err
+			}
+			return &binaryNode{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseSum() (node, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek().text
+		if p.peek().kind != tokenOperator || (op != "+" && op != "-") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseProduct() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek().text
+		if p.peek().kind != tokenOperator || (op != "*" && op != "/") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.accept("-") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{op: "-", left: &literalNode{value: 0.0}, right: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	operand, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			name := p.next()
+			if name.kind != tokenIdent {
+				return nil, fmt.Errorf("expected a name after '.' at offset %d", name.offset)
+			}
+			if p.accept("(") {
+				args, err := p.parseArgs()
+				if err != nil {
+					return nil, err
+				}
+				if _, known := methods[name.text]; !known {
+					return nil, fmt.Errorf("unknown method %s at offset %d", name.text, name.offset)
+				}
+				operand = &methodNode{name: name.text, receiver: operand, args: args}
+			} else {
+				operand = &indexNode{operand: operand, index: &literalNode{value: name.text}}
+			}
+		case p.accept("["):
+			index, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			operand = &indexNode{operand: operand, index: index}
+		default:
+			return operand, nil
+		}
+	}
+}
+
+// parseArgs parses call arguments after the opening parenthesis.
+func (p *parser) parseArgs() ([]node, error) {
+	var args []node
+	if p.accept(")") {
+		return args, nil
+	}
+	for {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.accept(")") {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s at offset %d", t.text, t.offset)
+		}
+		return &literalNode{value: value}, nil
+	case tokenString:
+		return &literalNode{value: t.text}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+		if p.accept("(") {
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			if _, known := functions[t.text]; !known {
+				return nil, fmt.Errorf("unknown function %s at offset %d", t.text, t.offset)
+			}
+			return &callNode{name: t.text, args: args}, nil
+		}
+		p.variables[t.text] = true
+		return &variableNode{name: t.text}, nil
+	case tokenOperator:
+		switch t.text {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+		case "[":
+			var items []node
+			if p.accept("]") {
+				return &listNode{items: items}, nil
+			}
+			for {
+				item, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+				if p.accept("]") {
+					return &listNode{items: items}, nil
+				}
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("unexpected %s at offset %d", t.text, t.offset)
+}
+
+// Evaluation
+
+type node interface {
+	eval(vars map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct{ value interface{} }
+
+func (n *literalNode) eval(map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type variableNode struct{ name string }
+
+func (n *variableNode) eval(vars map[string]interface{}) (interface{}, error) {
+	value, exists := vars[n.name]
+	if !exists {
+		return nil, fmt.Errorf("unknown variable %s", n.name)
+	}
+	return normalize(value), nil
+}
+
+type listNode struct{ items []node }
+
+func (n *listNode) eval(vars map[string]interface{}) (interface{}, error) {
+	list := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		value, err := item.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = value
+	}
+	return list, nil
+}
+
+type logicalNode struct {
+	or          bool
+	left, right node
+}
+
+func (n *logicalNode) eval(vars map[string]interface{}) (interface{}, error) {
+	left, err := evalBool(n.left, vars)
+	if err != nil {
+		return nil, err
+	}
+	// Short-circuit
+	if left == n.or {
+		return left, nil
+	}
+	return evalBool(n.right, vars)
+}
+
+type notNode struct{ operand node }
+
+func (n *notNode) eval(vars map[string]interface{}) (interface{}, error) {
+	value, err := evalBool(n.operand, vars)
+	if err != nil {
+		return nil, err
+	}
+	return !value, nil
+}
+
+func evalBool(n node, vars map[string]interface{}) (bool, error) {
+	value, err := n.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected a boolean, got %s", typeName(value))
+	}
+	return result, nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n *binaryNode) eval(vars map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		switch container := right.(type) {
+		case []interface{}:
+			for _, item := range container {
+				if equal(left, item) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]interface{}:
+			key, ok := left.(string)
+			if !ok {
+				return nil, fmt.Errorf("map keys are strings, got %s", typeName(left))
+			}
+			_, exists := container[key]
+			return exists, nil
+		case string:
+			substring, ok := left.(string)
+			if !ok {
+				return nil, fmt.Errorf("cannot look for %s in a string", typeName(left))
+			}
+			return strings.Contains(container, substring), nil
+		default:
+			return nil, fmt.Errorf("'in' needs a list, map or string, got %s", typeName(right))
+		}
+	}
+
+	if n.op == "+" {
+		if l, ok := left.(string); ok {
+			if r, ok := right.(string); ok {
+				return l + r, nil
+			}
+		}
+	}
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			switch n.op {
+			case "<":
+				return l < r, nil
+			case "<=":
+				return l <= r, nil
+			case ">":
+				return l > r, nil
+			case ">=":
+				return l >= r, nil
+			}
+		}
+	}
+
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("cannot apply %s to %s and %s", n.op, typeName(left), typeName(right))
+	}
+	switch n.op {
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	case ">=":
+		return l >= r, nil
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return l / r, nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", n.op)
+}
+
+type indexNode struct {
+	operand, index node
+}
+
+func (n *indexNode) eval(vars map[string]interface{}) (interface{}, error) {
+	operand, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch container := operand.(type) {
+	case map[string]interface{}:
+		key, ok := index.(string)
+		if !ok {
+			return nil, fmt.Errorf("map keys are strings, got %s", typeName(index))
+		}
+		return normalize(container[key]), nil
+	case []interface{}:
+		position, ok := index.(float64)
+		if !ok || position != float64(int(position)) {
+			return nil, fmt.Errorf("list index must be an integer")
+		}
+		if position < 0 || int(position) >= len(container) {
+			return nil, nil
+		}
+		return container[int(position)], nil
+	case nil:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("cannot index %s", typeName(operand))
+	}
+}
+
+type callNode struct {
+	name string
+	args []node
+}
+
+// functions maps function names to their implementation.
+var functions = map[string]func(args []interface{}) (interface{}, error){
+	"size": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("size takes one argument")
+		}
+		switch value := args[0].(type) {
+		case string:
+			return float64(len([]rune(value))), nil
+		case []interface{}:
+			return float64(len(value)), nil
+		case map[string]interface{}:
+			return float64(len(value)), nil
+		case nil:
+			return 0.0, nil
+		}
+		return nil, fmt.Errorf("size of %s", typeName(args[0]))
+	},
+	"lower": stringFunction("lower", strings.ToLower),
+	"upper": stringFunction("upper", strings.ToUpper),
+}
+
+func stringFunction(name string, apply func(string) string) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s takes one argument", name)
+		}
+		value, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s needs a string, got %s", name, typeName(args[0]))
+		}
+		return apply(value), nil
+	}
+}
+
+func (n *callNode) eval(vars map[string]interface{}) (interface{}, error) {
+	args, err := evalArgs(n.args, vars)
+	if err != nil {
+		return nil, err
+	}
+	return functions[n.name](args)
+}
+
+type methodNode struct {
+	name     string
+	receiver node
+	args     []node
+}
+
+// methods maps string method names to their implementation.
+var methods = map[string]func(receiver, arg string) (bool, error){
+	"contains":   func(receiver, arg string) (bool, error) { return strings.Contains(receiver, arg), nil },
+	"startsWith": func(receiver, arg string) (bool, error) { return strings.HasPrefix(receiver, arg), nil },
+	"endsWith":   func(receiver, arg string) (bool, error) { return strings.HasSuffix(receiver, arg), nil },
+	"matches": func(receiver, arg string) (bool, error) {
+		pattern, err := compilePattern(arg)
+		if err != nil {
+			return false, err
+		}
+		return pattern.MatchString(receiver), nil
+	},
+}
+
+// patterns caches compiled regular expressions by source.
+var patterns sync.Map
+
+func compilePattern(source string) (*regexp.Regexp, error) {
+	if cached, found := patterns.Load(source); found {
+		return cached.(*regexp.Regexp), nil
+	}
+	pattern, err := regexp.Compile(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", source, err)
+	}
+	patterns.Store(source, pattern)
+	return pattern, nil
+}
+
+func (n *methodNode) eval(vars map[string]interface{}) (interface{}, error) {
+	receiver, err := n.receiver.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	args, err := evalArgs(n.args, vars)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%s takes one argument", n.name)
+	}
+	if receiver == nil {
+		return false, nil
+	}
+	value, ok := receiver.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s needs a string receiver, got %s", n.name, typeName(receiver))
+	}
+	arg, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("%s needs a string argument, got %s", n.name, typeName(args[0]))
+	}
+	return methods[n.name](value, arg)
+}
+
+func evalArgs(nodes []node, vars map[string]interface{}) ([]interface{}, error) {
+	args := make([]interface{}, len(nodes))
+	for i, arg := range nodes {
+		value, err := arg.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	return args, nil
+}
+
+// normalize converts Go values to the expression types: numbers to float64,
+// and string slices and maps to lists and maps of interface values.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case []string:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = item
+		}
+		return list
+	case map[string]string:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = item
+		}
+		return m
+	default:
+		return value
+	}
+}
+
+func equal(a, b interface{}) bool {
+	switch a := a.(type) {
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		return false
+	}
+	if _, ok := b.([]interface{}); ok {
+		return false
+	}
+	if _, ok := b.(map[string]interface{}); ok {
+		return false
+	}
+	return a == b
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,153 @@
+package expr
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	vars := map[string]interface{}{
+		"tenant":        "acme",
+		"model":         "gpt-4o-mini",
+		"prompt_length": 25000,
+		"temperature":   0.2,
+		"stream":        true,
+		"tags":          []string{"batch", "eu"},
+		"headers":       map[string]string{"x-priority": "high"},
+		"metadata":      map[string]interface{}{"team": "search", "tier": 2.0},
+	}
+
+	tests := []struct {
+		source string
+		want   interface{}
+	}{
+		{`tenant in ["acme", "globex"] && prompt_length > 20000`, true},
+		{`model.startsWith("gpt-4") || headers["x-priority"] == "high"`, true},
+		{`model.endsWith("-mini") && model.contains("4o")`, true},
+		{`model.matches("^gpt-4o(-mini)?$")`, true},
+		{`!stream`, false},
+		{`1 + 2 * 3`, 7.0},
+		{`(1 + 2) * 3`, 9.0},
+		{`10 / 4 - 1`, 1.5},
+		{`-temperature < 0`, true},
+		{`"gpt" + "-4" == "gpt-4"`, true},
+		{`"abc" < "abd"`, true},
+		{`"eu" in tags`, true},
+		{`"x-priority" in headers`, true},
+		{`"4o" in model`, true},
+		{`tags[0] == "batch"`, true},
+		{`tags[5] == null`, true},
+		{`metadata.team == "search" && metadata.tier >= 2`, true},
+		{`headers["missing"] == null`, true},
+		{`headers.missing.deeper == null`, true},
+		{`size(tags) == 2 && size("héllo") == 5 && size(null) == 0`, true},
+		{`lower("ACME") == tenant && upper(tenant) == "ACME"`, true},
+		{`[1, "a"] == [1, "a"]`, true},
+		{`[1, 2] != [1, 2, 3]`, true},
+		{`'single' == "single"`, true},
+		{`"line\nbreak".contains("\n")`, true},
+		{`headers["missing"].startsWith("a")`, false},
+		{`false && undefined_variable`, false},
+		{`true || undefined_variable`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			program, err := Compile(tt.source)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			got, err := program.Eval(vars)
+			if err != nil {
+				t.Fatalf("Eval() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Eval() = %v (%T), want %v", got, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := map[string]string{
+		`tenant ==`:          "unexpected end of expression",
+		`(tenant == "acme"`:  `expected ")"`,
+		`"unterminated`:      "unterminated string",
+		`tenant # 1`:         "unexpected character",
+		`unknown(tenant)`:    "unknown function",
+		`model.reverse("a")`: "unknown method",
+		`tenant == "a" "b"`:  "unexpected b",
+		`1.2.3 > 0`:          "invalid number",
+		`tags[0`:             `expected "]"`,
+		`model.`:             "expected a name",
+	}
+	for source, want := range tests {
+		t.Run(source, func(t *testing.T) {
+			_, err := Compile(source)
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Fatalf("Compile() error = %v, want it to mention %q", err, want)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	vars := map[string]interface{}{"tenant": "acme", "count": 3, "tags": []string{"a"}}
+
+	tests := map[string]string{
+		`missing == 1`:              "unknown variable missing",
+		`count / 0 > 1`:             "division by zero",
+		`tenant > 1`:                "cannot apply >",
+		`count && true`:             "expected a boolean",
+		`1 in count`:                "'in' needs a list, map or string",
+		`tags["a"] == null`:         "list index must be an integer",
+		`count.startsWith("a")`:     "needs a string receiver",
+		`tenant.matches("(")`:       "invalid pattern",
+		`tenant.contains("a", "b")`: "takes one argument",
+		`size(count) > 0`:           "size of number",
+		`lower(count) == "3"`:       "lower needs a string",
+	}
+	for source, want := range tests {
+		t.Run(source, func(t *testing.T) {
+			program, err := Compile(source)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if _, err := program.Eval(vars); err == nil || !strings.Contains(err.Error(), want) {
+				t.Fatalf("Eval() error = %v, want it to mention %q", err, want)
+			}
+		})
+	}
+}
+
+func TestEvalBool(t *testing.T) {
+	program, err := Compile(`count + 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := program.EvalBool(map[string]interface{}{"count": 1}); err == nil || !strings.Contains(err.Error(), "not a boolean") {
+		t.Fatalf("EvalBool() of a number error = %v", err)
+	}
+
+	program, err = Compile(`count > 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := program.EvalBool(map[string]interface{}{"count": int64(2)}); err != nil || !ok {
+		t.Fatalf("EvalBool() = %v, %v, want true", ok, err)
+	}
+}
+
+func TestVariables(t *testing.T) {
+	program, err := Compile(`tenant in allowed && size(model) > 3 && lower(tenant) != "x" && headers.team == "a"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"allowed", "headers", "model", "tenant"}
+	if got := program.Variables(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Variables() = %v, want %v", got, want)
+	}
+	if program.String() != `tenant in allowed && size(model) > 3 && lower(tenant) != "x" && headers.team == "a"` {
+		t.Fatalf("String() = %q", program.String())
+	}
+}
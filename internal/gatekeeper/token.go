@@ -0,0 +1,171 @@
+package gatekeeper
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidToken is returned for tokens that are malformed or carry a bad signature.
+	ErrInvalidToken = errors.New("invalid gatekeeper token")
+
+	// ErrTokenExpired is returned for tokens past their expiry.
+	ErrTokenExpired = errors.New("gatekeeper token expired")
+)
+
+// Config holds configuration for gatekeeper (route-only) mode.
+type Config struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	SigningKey string        `mapstructure:"signing_key"` // HMAC key shared with token verifiers
+	TokenTTL   time.Duration `mapstructure:"token_ttl"`
+	Issuer     string        `mapstructure:"issuer"`
+	Redemption string        `mapstructure:"redemption"` // "proxy" (default) or "key"
+	PublicURL  string        `mapstructure:"public_url"` // externally reachable base URL, used for proxy URLs
+}
+
+// Claims are the routing decision details carried by a signed token.
+type Claims struct {
+	ID            string    `json:"jti"`
+	Issuer        string    `json:"iss,omitempty"`
+	Provider      string    `json:"provider"`
+	Model         string    `json:"model"`
+	CredentialRef string    `json:"credential_ref"`
+	Tenant        string    `json:"tenant,omitempty"` // that requested the route; only it may redeem the voucher
+	RequestID     string    `json:"request_id,omitempty"`
+	User          string    `json:"user,omitempty"`
+	IssuedAt      time.Time `json:"iat"`
+	ExpiresAt     time.Time `json:"exp"`
+}
+
+// Signer issues and verifies HMAC-SHA256 signed gatekeeper tokens.
+// Tokens have the form base64url(claims JSON) "." base64url(signature).
+type Signer struct {
+	key    []byte
+	ttl    time.Duration
+	issuer string
+}
+
+// MinSigningKeyLength is the shortest signing key accepted, in bytes.
+const MinSigningKeyLength = 32
+
+// NewSigner creates a new token signer. With gatekeeper mode enabled the
+// signing key is required, as anyone who knows it can sign tokens. While it
+// is disabled a random key is generated, as no tokens are issued.
+func NewSigner(config Config) (*Signer, error) {
+	key := []byte(config.SigningKey)
+	if config.Enabled {
+		if err := validateSigningKey(config.SigningKey); err != nil {
+			return nil, err
+		}
+	} else if len(key) == 0 {
+		key = make([]byte, MinSigningKeyLength)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate signing key: %w", err)
+		}
+	}
+
+	ttl := config.TokenTTL
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+
+	return &Signer{
+		key:    key,
+		ttl:    ttl,
+		issuer: config.Issuer,
+	}, nil
+}
+
+// validateSigningKey rejects a signing key that is missing, still an
+// unexpanded ${NAME} reference, or too short to resist guessing.
+func validateSigningKey(key string) error {
+	switch {
+	case key == "":
+		return fmt.Errorf("gatekeeper.signing_key is required when gatekeeper mode is enabled")
+	case strings.Contains(key, "${"):
+		return fmt.Errorf("gatekeeper.signing_key contains an unexpanded environment reference")
+	case len(key) < MinSigningKeyLength:
+		return fmt.Errorf("gatekeeper.signing_key must be at least %d bytes, got %d", MinSigningKeyLength, len(key))
+	}
+	return nil
+}
+
+// Issue fills in the token ID, issuer and validity window and signs the claims.
+func (s *Signer) Issue(claims Claims) (string, Claims, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", Claims{}, fmt.Errorf("failed to generate token ID: %w", err)
+	}
+
+	now := time.Now().UTC()
+	claims.ID = hex.EncodeToString(id)
+	claims.Issuer = s.issuer
+	claims.IssuedAt = now
+	claims.ExpiresAt = now.Add(s.ttl)
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", Claims{}, fmt.Errorf("failed to encode claims: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.sign(encoded), claims, nil
+}
+
+// Verify checks a token's signature and expiry and returns its claims.
+func (s *Signer) Verify(token string) (Claims, error) {
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return Claims{}, ErrInvalidToken
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(s.sign(encoded))) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+
+	if time.Now().After(claims.ExpiresAt) {
+		return claims, ErrTokenExpired
+	}
+
+	return claims, nil
+}
+
+// TTL returns the validity period of issued tokens.
+func (s *Signer) TTL() time.Duration {
+	return s.ttl
+}
+
+// sign returns the base64url HMAC-SHA256 signature of the encoded claims.
+func (s *Signer) sign(encoded string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// CredentialRef returns a stable, non-secret reference to a provider credential
+// so callers can pick the matching key from their own secret store.
+func CredentialRef(provider, apiKey string) string {
+	if apiKey == "" {
+		return provider
+	}
+	sum := sha256.Sum256([]byte(apiKey))
+	return fmt.Sprintf("%s:sha256:%s", provider, hex.EncodeToString(sum[:])[:12])
+}
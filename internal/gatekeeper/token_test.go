@@ -0,0 +1,127 @@
+package gatekeeper
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testKey = "0123456789abcdef0123456789abcdef"
+
+func TestNewSignerValidatesKeyWhenEnabled(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{"disabled without key", Config{}, ""},
+		{"enabled with key", Config{Enabled: true, SigningKey: testKey}, ""},
+		{"enabled without key", Config{Enabled: true}, "required"},
+		{"unexpanded reference", Config{Enabled: true, SigningKey: "${SEMAROUTE_GATEKEEPER_SIGNING_KEY}"}, "unexpanded"},
+		{"short key", Config{Enabled: true, SigningKey: "secret"}, "at least 32 bytes"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewSigner(test.config)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("NewSigner() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("NewSigner() error = %v, want one containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestIssueVerifyRoundTrip(t *testing.T) {
+	signer, err := NewSigner(Config{Enabled: true, SigningKey: testKey, Issuer: "semaroute"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, issued, err := signer.Issue(Claims{Provider: "openai", Model: "gpt-4o", User: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issued.ID == "" || issued.Issuer != "semaroute" || !issued.ExpiresAt.After(issued.IssuedAt) {
+		t.Fatalf("Issue() filled in %+v", issued)
+	}
+
+	claims, err := signer.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if claims.ID != issued.ID || claims.Provider != "openai" || claims.Model != "gpt-4o" || claims.User != "alice" {
+		t.Fatalf("Verify() = %+v, want %+v", claims, issued)
+	}
+}
+
+func TestVerifyRejectsTamperedTokens(t *testing.T) {
+	signer, err := NewSigner(Config{Enabled: true, SigningKey: testKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewSigner(Config{Enabled: true, SigningKey: strings.Repeat("x", 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := signer.Issue(Claims{Provider: "openai", Model: "gpt-4o"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, _, err := other.Issue(Claims{Provider: "openai", Model: "gpt-4o"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, signature, _ := strings.Cut(token, ".")
+
+	tests := map[string]string{
+		"no separator":       payload,
+		"other key":          forged,
+		"altered payload":    "e30" + payload[3:] + "." + signature,
+		"altered signature":  payload + "." + strings.Repeat("A", len(signature)),
+		"empty":              "",
+		"signature dropped":  payload + ".",
+		"invalid base64 pay": "!!!." + signature,
+	}
+	for name, tampered := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := signer.Verify(tampered); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("Verify() error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsExpiredTokens(t *testing.T) {
+	signer, err := NewSigner(Config{Enabled: true, SigningKey: testKey, TokenTTL: time.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := signer.Issue(Claims{Provider: "openai"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+
+	if _, err := signer.Verify(token); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("Verify() error = %v, want ErrTokenExpired", err)
+	}
+}
+
+func TestCredentialRefHidesKey(t *testing.T) {
+	ref := CredentialRef("openai", "sk-secret")
+	if strings.Contains(ref, "sk-secret") || !strings.HasPrefix(ref, "openai:sha256:") {
+		t.Fatalf("CredentialRef() = %q", ref)
+	}
+	if ref != CredentialRef("openai", "sk-secret") {
+		t.Fatal("CredentialRef() is not stable")
+	}
+	if CredentialRef("openai", "") != "openai" {
+		t.Fatal("CredentialRef() without key should be the provider")
+	}
+}
@@ -0,0 +1,91 @@
+package gatekeeper
+
+import (
+	"container/heap"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Redemption modes control what a voucher is exchanged for.
+const (
+	// RedeemKey exchanges a voucher for the provider API key. It needs an
+	// API key with the vouchers:key scope.
+	RedeemKey = "key"
+
+	// RedeemProxy exchanges a voucher for a proxy URL; the provider key never
+	// leaves the router.
+	RedeemProxy = "proxy"
+)
+
+// ErrVoucherRedeemed is returned when a voucher is presented a second time.
+var ErrVoucherRedeemed = errors.New("voucher already redeemed")
+
+// Ledger records redeemed vouchers so each one can be used only once.
+// Entries are kept until the voucher would have expired anyway, and pruned in
+// order of expiry.
+//
+// The ledger is held in memory by each instance. Behind a load balancer, a
+// voucher could be redeemed once on every replica. Run gatekeeper mode on a
+// single instance, or route redemptions to one replica, for strict single use.
+type Ledger struct {
+	redeemed map[string]time.Time
+	expiries expiryHeap
+	mutex    sync.Mutex
+}
+
+// NewLedger creates a new voucher ledger.
+func NewLedger() *Ledger {
+	return &Ledger{
+		redeemed: make(map[string]time.Time),
+	}
+}
+
+// Redeem marks the voucher as used, returning ErrVoucherRedeemed if it already was.
+func (l *Ledger) Redeem(claims Claims) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// Only the vouchers that expired since the last call are removed
+	now := time.Now()
+	for len(l.expiries) > 0 && now.After(l.expiries[0].expiresAt) {
+		delete(l.redeemed, heap.Pop(&l.expiries).(redeemedVoucher).id)
+	}
+
+	if _, exists := l.redeemed[claims.ID]; exists {
+		return ErrVoucherRedeemed
+	}
+	l.redeemed[claims.ID] = claims.ExpiresAt
+	heap.Push(&l.expiries, redeemedVoucher{id: claims.ID, expiresAt: claims.ExpiresAt})
+
+	return nil
+}
+
+// Len returns the number of redeemed vouchers held.
+func (l *Ledger) Len() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return len(l.redeemed)
+}
+
+// redeemedVoucher is a redeemed voucher and when it expires.
+type redeemedVoucher struct {
+	id        string
+	expiresAt time.Time
+}
+
+// expiryHeap is a min-heap of redeemed vouchers by expiry.
+type expiryHeap []redeemedVoucher
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h expiryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *expiryHeap) Push(x interface{}) { *h = append(*h, x.(redeemedVoucher)) }
+
+func (h *expiryHeap) Pop() interface{} {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
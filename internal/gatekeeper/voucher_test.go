@@ -0,0 +1,49 @@
+package gatekeeper
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLedgerRedeemsOnce(t *testing.T) {
+	ledger := NewLedger()
+	claims := Claims{ID: "voucher-1", ExpiresAt: time.Now().Add(time.Minute)}
+
+	if err := ledger.Redeem(claims); err != nil {
+		t.Fatalf("first Redeem() error = %v", err)
+	}
+	if err := ledger.Redeem(claims); !errors.Is(err, ErrVoucherRedeemed) {
+		t.Fatalf("second Redeem() error = %v, want ErrVoucherRedeemed", err)
+	}
+	if err := ledger.Redeem(Claims{ID: "voucher-2", ExpiresAt: time.Now().Add(time.Minute)}); err != nil {
+		t.Fatalf("Redeem() of another voucher error = %v", err)
+	}
+}
+
+func TestLedgerPrunesExpiredVouchers(t *testing.T) {
+	ledger := NewLedger()
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		claims := Claims{ID: fmt.Sprintf("expired-%d", i), ExpiresAt: now.Add(-time.Duration(i+1) * time.Second)}
+		if err := ledger.Redeem(claims); err != nil {
+			t.Fatal(err)
+		}
+	}
+	live := Claims{ID: "live", ExpiresAt: now.Add(time.Hour)}
+	if err := ledger.Redeem(live); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next redemption prunes the expired vouchers and keeps the live one
+	if err := ledger.Redeem(Claims{ID: "next", ExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ledger.Len(); got != 2 {
+		t.Fatalf("Len() = %d after pruning, want 2", got)
+	}
+	if err := ledger.Redeem(live); !errors.Is(err, ErrVoucherRedeemed) {
+		t.Fatalf("Redeem() of the live voucher error = %v, want ErrVoucherRedeemed", err)
+	}
+}
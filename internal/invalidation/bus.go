@@ -0,0 +1,86 @@
+// Package invalidation propagates cache purges and configuration reloads
+// between semaroute replicas that each keep local in-memory state.
+package invalidation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Event types published on the bus.
+const (
+	// CachePurge removes Key from the cache, or every entry when Key is empty.
+	CachePurge = "cache_purge"
+
+	// PricingReload reloads the pricing catalog from its file.
+	PricingReload = "pricing_reload"
+)
+
+// Config holds configuration for the invalidation bus.
+type Config struct {
+	Type       string      `mapstructure:"type"`        // none or redis
+	InstanceID string      `mapstructure:"instance_id"` // defaults to the hostname and a random suffix
+	Redis      RedisConfig `mapstructure:"redis"`
+}
+
+// Event is an invalidation broadcast to all replicas.
+type Event struct {
+	Type   string    `json:"type"`
+	Key    string    `json:"key,omitempty"`
+	Origin string    `json:"origin"`
+	Time   time.Time `json:"time"`
+}
+
+// Handler applies an event received from another replica.
+type Handler func(event Event)
+
+// Bus publishes events to, and receives events from, other replicas.
+// Events published by this instance are not delivered back to it.
+type Bus interface {
+	// Publish broadcasts an event. Origin and Time are filled in.
+	Publish(ctx context.Context, event Event) error
+
+	// Subscribe starts delivering events from other replicas to handler
+	// until the bus is closed.
+	Subscribe(handler Handler)
+
+	// Close stops the subscription and releases connections.
+	Close() error
+}
+
+// NewBus creates the bus for the configured type. The "none" bus drops every
+// event, which is correct for a single instance.
+func NewBus(config Config) (Bus, error) {
+	instanceID := config.InstanceID
+	if instanceID == "" {
+		instanceID = defaultInstanceID()
+	}
+
+	switch config.Type {
+	case "", "none":
+		return noopBus{}, nil
+	case "redis":
+		return newRedisBus(config.Redis, instanceID)
+	default:
+		return nil, fmt.Errorf("unknown invalidation bus type: %s", config.Type)
+	}
+}
+
+// defaultInstanceID identifies this process on the bus.
+func defaultInstanceID() string {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return host + "-" + hex.EncodeToString(suffix)
+}
+
+// noopBus is used when no bus is configured.
+type noopBus struct{}
+
+func (noopBus) Publish(ctx context.Context, event Event) error { return nil }
+func (noopBus) Subscribe(handler Handler)                      {}
+func (noopBus) Close() error                                   { return nil }
@@ -0,0 +1,163 @@
+package invalidation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/redis"
+)
+
+const (
+	// defaultRedisChannel is the pub/sub channel used when none is configured.
+	defaultRedisChannel = "semaroute:invalidation"
+
+	// redisMaxBackoff caps the delay between subscription reconnects.
+	redisMaxBackoff = 30 * time.Second
+)
+
+// RedisConfig holds the Redis connection used for pub/sub.
+type RedisConfig struct {
+	Address  string `mapstructure:"address"` // host:port
+	Password string `mapstructure:"password"`
+	Channel  string `mapstructure:"channel"`
+}
+
+// connection returns the settings for the Redis client.
+func (c RedisConfig) connection() redis.Config {
+	return redis.Config{Address: c.Address, Password: c.Password}
+}
+
+// redisBus publishes and subscribes over Redis pub/sub. The subscription uses
+// a dedicated connection, as a subscribed connection cannot issue other commands.
+type redisBus struct {
+	config     RedisConfig
+	instanceID string
+
+	client *redis.Client
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	subMutex  sync.Mutex
+	subConn   *redis.Conn
+}
+
+// newRedisBus creates a Redis bus. Connections are opened lazily, so the
+// gateway starts even when Redis is briefly unavailable.
+func newRedisBus(config RedisConfig, instanceID string) (*redisBus, error) {
+	if config.Address == "" {
+		return nil, errors.New("invalidation.redis.address is required")
+	}
+	if config.Channel == "" {
+		config.Channel = defaultRedisChannel
+	}
+	client, err := redis.NewClient(config.connection())
+	if err != nil {
+		return nil, err
+	}
+	return &redisBus{
+		config:     config,
+		instanceID: instanceID,
+		client:     client,
+		closed:     make(chan struct{}),
+	}, nil
+}
+
+// Publish implements Bus.
+func (b *redisBus) Publish(ctx context.Context, event Event) error {
+	event.Origin = b.instanceID
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	// Retry once on a fresh connection, in case a pooled one was dropped
+	for attempt := 0; attempt < 2; attempt++ {
+		if _, err = b.client.Do(ctx, "PUBLISH", b.config.Channel, string(payload)); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to publish invalidation: %w", err)
+}
+
+// Subscribe implements Bus. The subscription reconnects with exponential
+// backoff until the bus is closed.
+func (b *redisBus) Subscribe(handler Handler) {
+	go func() {
+		backoff := time.Second
+		for {
+			if b.subscribe(handler) {
+				backoff = time.Second
+			}
+			select {
+			case <-b.closed:
+				return
+			case <-time.After(backoff):
+			}
+			if backoff < redisMaxBackoff {
+				backoff *= 2
+			}
+		}
+	}()
+}
+
+// subscribe runs one subscription connection until it fails or the bus is
+// closed. It reports whether the subscription was established.
+func (b *redisBus) subscribe(handler Handler) bool {
+	conn, err := redis.Dial(context.Background(), b.config.connection())
+	if err != nil {
+		return false
+	}
+	b.subMutex.Lock()
+	select {
+	case <-b.closed:
+		b.subMutex.Unlock()
+		conn.Close()
+		return false
+	default:
+	}
+	b.subConn = conn
+	b.subMutex.Unlock()
+	defer conn.Close()
+
+	if err := conn.Send("SUBSCRIBE", b.config.Channel); err != nil {
+		return false
+	}
+	conn.ClearDeadline()
+	for {
+		reply, err := conn.Receive()
+		if err != nil {
+			return true
+		}
+		parts, ok := reply.([]interface{})
+		if !ok || len(parts) != 3 || parts[0] != "message" {
+			continue
+		}
+		payload, _ := parts[2].(string)
+
+		var event Event
+		if err := json.Unmarshal([]byte(payload), &event); err != nil || event.Origin == b.instanceID {
+			continue
+		}
+		handler(event)
+	}
+}
+
+// Close implements Bus.
+func (b *redisBus) Close() error {
+	b.closeOnce.Do(func() { close(b.closed) })
+
+	b.subMutex.Lock()
+	if b.subConn != nil {
+		b.subConn.Close()
+	}
+	b.subMutex.Unlock()
+
+	return b.client.Close()
+}
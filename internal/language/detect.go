@@ -0,0 +1,167 @@
+package language
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minLetters is the fewest letters a text needs for its language to be
+// detected when not configured.
+const minLetters = 20
+
+// names are the languages Detect recognizes, by ISO 639-1 code.
+var names = map[string]string{
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"de": "German",
+	"it": "Italian",
+	"pt": "Portuguese",
+	"nl": "Dutch",
+	"ru": "Russian",
+	"uk": "Ukrainian",
+	"el": "Greek",
+	"ar": "Arabic",
+	"he": "Hebrew",
+	"hi": "Hindi",
+	"th": "Thai",
+	"zh": "Chinese",
+	"ja": "Japanese",
+	"ko": "Korean",
+}
+
+// stopwords are frequent words of the languages written in Latin script,
+// which tell them apart.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "of", "to", "in", "that", "it", "with", "for", "this", "you", "not", "be", "was", "have"},
+	"es": {"el", "la", "los", "las", "de", "que", "y", "es", "en", "por", "con", "para", "una", "del", "se", "no", "está"},
+	"fr": {"le", "la", "les", "des", "et", "est", "que", "une", "du", "pour", "dans", "pas", "sur", "avec", "vous", "ce", "il"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "mit", "den", "von", "sie", "auf", "ich", "es", "sind"},
+	"it": {"il", "di", "che", "e", "la", "per", "non", "sono", "una", "del", "con", "gli", "è", "della", "questo", "anche"},
+	"pt": {"o", "a", "de", "que", "e", "do", "da", "em", "um", "uma", "para", "não", "com", "os", "é", "dos", "você"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "niet", "op", "te", "zijn", "met", "voor", "ik", "je", "ook"},
+}
+
+// stopwordLanguages maps each stopword to the languages it is frequent in.
+var stopwordLanguages = func() map[string][]string {
+	index := make(map[string][]string)
+	for code, words := range stopwords {
+		for _, word := range words {
+			index[word] = append(index[word], code)
+		}
+	}
+	return index
+}()
+
+// Supported reports whether Detect recognizes the language with an ISO 639-1
+// code.
+func Supported(code string) bool {
+	_, ok := names[code]
+	return ok
+}
+
+// Name returns the English name of a supported language, or its code.
+func Name(code string) string {
+	if name, ok := names[code]; ok {
+		return name
+	}
+	return code
+}
+
+// Detect returns the ISO 639-1 code of the language text is written in, or
+// false when it is too short or ambiguous to tell. Languages with their own
+// script are told apart by script; those written in Latin script by their
+// most frequent words.
+func Detect(text string, min int) (string, bool) {
+	if min <= 0 {
+		min = minLetters
+	}
+
+	scripts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			scripts["latin"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			scripts["cyrillic"]++
+			if strings.ContainsRune("іїєґІЇЄҐ", r) {
+				scripts["ukrainian"]++
+			}
+		case unicode.Is(unicode.Greek, r):
+			scripts["el"]++
+		case unicode.Is(unicode.Arabic, r):
+			scripts["ar"]++
+		case unicode.Is(unicode.Hebrew, r):
+			scripts["he"]++
+		case unicode.Is(unicode.Devanagari, r):
+			scripts["hi"]++
+		case unicode.Is(unicode.Thai, r):
+			scripts["th"]++
+		case unicode.Is(unicode.Hangul, r):
+			scripts["ko"]++
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			scripts["kana"]++
+		case unicode.Is(unicode.Han, r):
+			scripts["han"]++
+		}
+	}
+	if letters < min {
+		return "", false
+	}
+
+	// Japanese mixes kana into Han; Chinese has none. CJK text has fewer
+	// letters per word, so it counts for more.
+	cjk := scripts["kana"] + scripts["han"]
+	switch {
+	case cjk*2 > letters:
+		if scripts["kana"]*10 >= cjk {
+			return "ja", true
+		}
+		return "zh", true
+	case scripts["cyrillic"]*2 > letters:
+		if scripts["ukrainian"] > 0 {
+			return "uk", true
+		}
+		return "ru", true
+	case scripts["latin"]*2 > letters:
+		return detectLatin(text)
+	}
+	for _, code := range []string{"el", "ar", "he", "hi", "th", "ko"} {
+		if scripts[code]*2 > letters {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+// detectLatin tells the languages written in Latin script apart by counting
+// their stopwords. It fails on a tie or without stopwords.
+func detectLatin(text string) (string, bool) {
+	scores := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		for _, code := range stopwordLanguages[word] {
+			scores[code]++
+		}
+	}
+
+	best, bestScore, tied := "", 0, false
+	for code, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, tied = code, score, false
+		case score == bestScore:
+			tied = true
+		}
+	}
+	if bestScore < 2 || tied {
+		return "", false
+	}
+	return best, true
+}
@@ -0,0 +1,133 @@
+// Package language checks that completions are written in the language a
+// tenant or request requires, and retries those that are not.
+package language
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/observability"
+)
+
+// defaultInstruction asks the model for an answer in the required language;
+// %s is the language's name.
+const defaultInstruction = "Respond only in %s, whatever the language of the conversation."
+
+// Enforcement outcomes, as recorded in metrics.
+const (
+	OutcomeMismatch    = "mismatch"    // a response in another language
+	OutcomeCorrected   = "corrected"   // a retry answered in the required language
+	OutcomeUncorrected = "uncorrected" // no retry did; the last response is returned
+)
+
+// Config holds configuration for response language enforcement.
+type Config struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// Retries on the same provider with the instruction added, default 1
+	MaxRetries int `mapstructure:"max_retries"`
+
+	// Try the next best provider, with the instruction, when the retries
+	// did not help
+	Alternate bool `mapstructure:"alternate"`
+
+	// Letters below which a response is too short to check, default 20
+	MinLetters int `mapstructure:"min_letters"`
+
+	// System message added to retries; %s is the language's name
+	Instruction string `mapstructure:"instruction"`
+}
+
+// CompleteFunc executes a single chat completion.
+type CompleteFunc func(ctx context.Context, req models.ChatRequest) (*models.ChatResponse, error)
+
+// AlternateFunc executes a chat completion on another provider than the one
+// that answered, returning the provider's name.
+type AlternateFunc func(ctx context.Context, req models.ChatRequest) (string, *models.ChatResponse, error)
+
+// Guard checks the language of completions and retries wrong-language ones.
+type Guard struct {
+	config  Config
+	metrics *observability.Metrics
+}
+
+// NewGuard creates a language guard.
+func NewGuard(config Config, metrics *observability.Metrics) *Guard {
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = 1
+	}
+	if config.MinLetters <= 0 {
+		config.MinLetters = minLetters
+	}
+	if config.Instruction == "" {
+		config.Instruction = defaultInstruction
+	}
+	return &Guard{config: config, metrics: metrics}
+}
+
+// Enforce returns resp and providerName unchanged unless resp is detectably
+// written in another language than target. It then retries the request with
+// an explicit instruction on the same provider and, if allowed, on an
+// alternate one, returning the first answer in the target language, or the
+// last answer with its provider when none is. Structured output is not
+// checked, as its keys need not be in the target language.
+func (g *Guard) Enforce(ctx context.Context, providerName string, req models.ChatRequest, resp *models.ChatResponse, target string, complete CompleteFunc, alternate AlternateFunc) (*models.ChatResponse, string) {
+	if !g.config.Enabled || target == "" || !Supported(target) || req.ResponseFormat != nil || g.matches(resp, target) {
+		return resp, providerName
+	}
+	g.metrics.RecordLanguageEnforcement(providerName, req.Model, OutcomeMismatch)
+
+	instructed := g.instructedRequest(req, target)
+	for i := 0; i < g.config.MaxRetries; i++ {
+		next, err := complete(ctx, instructed)
+		if err != nil {
+			break
+		}
+		resp = next
+		if g.matches(resp, target) {
+			g.metrics.RecordLanguageEnforcement(providerName, req.Model, OutcomeCorrected)
+			return resp, providerName
+		}
+		g.metrics.RecordLanguageEnforcement(providerName, req.Model, OutcomeMismatch)
+	}
+
+	if g.config.Alternate && alternate != nil {
+		if name, next, err := alternate(ctx, instructed); err == nil {
+			resp, providerName = next, name
+			if g.matches(resp, target) {
+				g.metrics.RecordLanguageEnforcement(providerName, req.Model, OutcomeCorrected)
+				return resp, providerName
+			}
+			g.metrics.RecordLanguageEnforcement(providerName, req.Model, OutcomeMismatch)
+		}
+	}
+
+	g.metrics.RecordLanguageEnforcement(providerName, req.Model, OutcomeUncorrected)
+	return resp, providerName
+}
+
+// matches reports whether resp is in the target language, or too short or
+// ambiguous to tell.
+func (g *Guard) matches(resp *models.ChatResponse, target string) bool {
+	if resp == nil || len(resp.Choices) == 0 {
+		return true
+	}
+	detected, ok := Detect(resp.Choices[0].Message.Content.Text(), g.config.MinLetters)
+	return !ok || detected == target
+}
+
+// instructedRequest adds the instruction to answer in the target language
+// after the conversation.
+func (g *Guard) instructedRequest(req models.ChatRequest, target string) models.ChatRequest {
+	messages := make([]models.Message, 0, len(req.Messages)+1)
+	messages = append(messages, req.Messages...)
+	messages = append(messages, models.Message{
+		Role:    "system",
+		Content: models.TextContent(fmt.Sprintf(g.config.Instruction, Name(target))),
+	})
+
+	next := req
+	next.Messages = messages
+	return next
+}
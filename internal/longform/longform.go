@@ -0,0 +1,235 @@
+package longform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+// Defaults used when the configuration leaves a setting empty.
+const (
+	defaultMaxSections      = 8
+	defaultSectionMaxTokens = 2048
+	defaultPlanMaxTokens    = 512
+)
+
+// Progress event types.
+const (
+	EventPlan    = "plan"
+	EventSection = "section"
+	EventDone    = "done"
+)
+
+// Config holds configuration for long-output generation.
+type Config struct {
+	Enabled          bool `mapstructure:"enabled"`
+	MaxSections      int  `mapstructure:"max_sections"`       // upper bound on planned sections
+	SectionMaxTokens int  `mapstructure:"section_max_tokens"` // max_tokens for each section request
+	PlanMaxTokens    int  `mapstructure:"plan_max_tokens"`    // max_tokens for the outline request
+}
+
+// CompleteFunc routes and executes a single chat completion, returning the
+// response. Each call may be served by a different provider.
+type CompleteFunc func(ctx context.Context, req models.ChatRequest) (*models.ChatResponse, error)
+
+// Request is a long-output generation request.
+type Request struct {
+	Chat     models.ChatRequest // the original conversation describing the document
+	Sections int                // desired number of sections, 0 to let the model decide
+}
+
+// Section is one generated part of the document.
+type Section struct {
+	Index    int          `json:"index"`
+	Title    string       `json:"title"`
+	Content  string       `json:"content"`
+	Provider string       `json:"provider"`
+	Model    string       `json:"model"`
+	Usage    models.Usage `json:"usage"`
+}
+
+// Result is the assembled document.
+type Result struct {
+	Outline  []string     `json:"outline"`
+	Sections []Section    `json:"sections"`
+	Content  string       `json:"content"`
+	Usage    models.Usage `json:"usage"`
+}
+
+// Event reports progress of a generation.
+type Event struct {
+	Type    string   `json:"type"`
+	Outline []string `json:"outline,omitempty"`
+	Section *Section `json:"section,omitempty"`
+	Total   int      `json:"total,omitempty"`
+	Result  *Result  `json:"result,omitempty"`
+}
+
+// Orchestrator plans a document as a list of sections, generates them one by
+// one so each stays within the output limit, and assembles the result.
+type Orchestrator struct {
+	config Config
+}
+
+// NewOrchestrator creates a new orchestrator.
+func NewOrchestrator(config Config) *Orchestrator {
+	if config.MaxSections <= 0 {
+		config.MaxSections = defaultMaxSections
+	}
+	if config.SectionMaxTokens <= 0 {
+		config.SectionMaxTokens = defaultSectionMaxTokens
+	}
+	if config.PlanMaxTokens <= 0 {
+		config.PlanMaxTokens = defaultPlanMaxTokens
+	}
+
+	return &Orchestrator{config: config}
+}
+
+// Generate plans and writes the document. progress, if not nil, is called
+// after planning and after every section.
+func (o *Orchestrator) Generate(ctx context.Context, req Request, complete CompleteFunc, progress func(Event)) (*Result, error) {
+	if progress == nil {
+		progress = func(Event) {}
+	}
+
+	sections := req.Sections
+	if sections <= 0 || sections > o.config.MaxSections {
+		sections = o.config.MaxSections
+	}
+
+	outline, usage, err := o.plan(ctx, req.Chat, sections, complete)
+	if err != nil {
+		return nil, fmt.Errorf("planning failed: %w", err)
+	}
+
+	result := &Result{Outline: outline, Usage: usage}
+	progress(Event{Type: EventPlan, Outline: outline, Total: len(outline)})
+
+	var content strings.Builder
+	for i, title := range outline {
+		resp, err := complete(ctx, o.sectionRequest(req.Chat, outline, i, content.String()))
+		if err != nil {
+			return nil, fmt.Errorf("section %d (%s) failed: %w", i+1, title, err)
+		}
+
+		section := Section{
+			Index:    i,
+			Title:    title,
+			Content:  strings.TrimSpace(firstContent(resp)),
+			Provider: resp.Provider,
+			Model:    resp.Model,
+			Usage:    resp.Usage,
+		}
+		result.Sections = append(result.Sections, section)
+		addUsage(&result.Usage, resp.Usage)
+
+		if content.Len() > 0 {
+			content.WriteString("\n\n")
+		}
+		content.WriteString(section.Content)
+
+		progress(Event{Type: EventSection, Section: &section, Total: len(outline)})
+	}
+
+	result.Content = content.String()
+	progress(Event{Type: EventDone, Result: result, Total: len(outline)})
+	return result, nil
+}
+
+// plan asks the model for a section outline, one title per line.
+func (o *Orchestrator) plan(ctx context.Context, chat models.ChatRequest, sections int, complete CompleteFunc) ([]string, models.Usage, error) {
+	req := withMessages(chat, models.Message{
+		Role: "user",
+		Content: models.TextContent(fmt.Sprintf("Before writing, plan the document requested above as at most %d sections. "+
+			"Reply with the section titles only, one per line, without numbering or any other text.", sections)),
+	})
+	req.MaxTokens = o.config.PlanMaxTokens
+
+	resp, err := complete(ctx, req)
+	if err != nil {
+		return nil, models.Usage{}, err
+	}
+
+	outline := parseOutline(firstContent(resp), sections)
+	if len(outline) == 0 {
+		return nil, resp.Usage, fmt.Errorf("model returned an empty outline")
+	}
+	return outline, resp.Usage, nil
+}
+
+// sectionRequest builds the request for one section. The outline and the end
+// of the text written so far are included to keep the sections consistent.
+func (o *Orchestrator) sectionRequest(chat models.ChatRequest, outline []string, index int, written string) models.ChatRequest {
+	var prompt strings.Builder
+	prompt.WriteString("You are writing the document requested above section by section. The outline is:\n")
+	for i, title := range outline {
+		fmt.Fprintf(&prompt, "%d. %s\n", i+1, title)
+	}
+	if written != "" {
+		fmt.Fprintf(&prompt, "\nThe previous section ended with:\n%s\n", tail(written, 2000))
+	}
+	fmt.Fprintf(&prompt, "\nWrite section %d, \"%s\", in full. Reply with the section text only.", index+1, outline[index])
+
+	req := withMessages(chat, models.Message{Role: "user", Content: models.TextContent(prompt.String())})
+	req.MaxTokens = o.config.SectionMaxTokens
+	req.Stream = false
+	return req
+}
+
+// withMessages returns a copy of chat with messages appended.
+func withMessages(chat models.ChatRequest, messages ...models.Message) models.ChatRequest {
+	next := chat
+	next.Messages = append(append([]models.Message(nil), chat.Messages...), messages...)
+	next.Stream = false
+	return next
+}
+
+// parseOutline extracts up to max section titles, stripping list markers.
+func parseOutline(text string, max int) []string {
+	var outline []string
+	for _, line := range strings.Split(text, "\n") {
+		title := strings.TrimLeftFunc(line, func(r rune) bool {
+			return unicode.IsDigit(r) || unicode.IsSpace(r) || strings.ContainsRune(".)-*#", r)
+		})
+		title = strings.TrimSpace(title)
+		if title == "" {
+			continue
+		}
+		outline = append(outline, title)
+		if len(outline) == max {
+			break
+		}
+	}
+	return outline
+}
+
+// firstContent returns the text of the first choice.
+func firstContent(resp *models.ChatResponse) string {
+	if resp == nil || len(resp.Choices) == 0 {
+		return ""
+	}
+	return resp.Choices[0].Message.Content.Text()
+}
+
+// tail returns at most the last n bytes of s, starting on a rune boundary.
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[len(s)-n:]
+	for len(s) > 0 && !utf8.RuneStart(s[0]) {
+		s = s[1:]
+	}
+	return s
+}
+
+func addUsage(total *models.Usage, usage models.Usage) {
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.TotalTokens += usage.TotalTokens
+}
@@ -0,0 +1,183 @@
+// Package migrate upgrades the files of the persistent stores, such as the
+// usage records and the audit log, from the format an older release wrote to
+// the current one. Each store's schema version is kept next to its file in a
+// .schema file, and its numbered migrations are applied in order.
+package migrate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Config holds configuration for schema migrations.
+type Config struct {
+	// AutoApply migrates the stores at startup. Without it, the server
+	// refuses to start while migrations are pending, and they are applied
+	// with the migrate command.
+	AutoApply bool `mapstructure:"auto_apply"`
+}
+
+// Migration upgrades the files of a store by one schema version.
+type Migration struct {
+	Version     int
+	Description string
+	Apply       func(path string) error // path of the store's file
+}
+
+// Store is a persistent store whose files are migrated.
+type Store struct {
+	Name       string
+	Path       string      // the store's file
+	Migrations []Migration // by increasing version, starting at 1
+}
+
+// Latest returns the schema version the store's migrations lead to.
+func (s Store) Latest() int {
+	if len(s.Migrations) == 0 {
+		return 0
+	}
+	return s.Migrations[len(s.Migrations)-1].Version
+}
+
+// schema is the content of a .schema file.
+type schema struct {
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// schemaPath returns the path of the store's .schema file.
+func (s Store) schemaPath() string {
+	return s.Path + ".schema"
+}
+
+// Version returns the schema version of the store's files. Files written
+// before versioning are at version 0.
+func (s Store) Version() (int, error) {
+	data, err := os.ReadFile(s.schemaPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var current schema
+	if err := json.Unmarshal(data, &current); err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", s.schemaPath(), err)
+	}
+	return current.Version, nil
+}
+
+// Pending returns the migrations not yet applied to the store. A store
+// without files yet is created at the latest version and has none. Files of a
+// newer release are an error, as this one cannot read them safely.
+func (s Store) Pending() ([]Migration, error) {
+	if _, err := os.Stat(s.Path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	version, err := s.Version()
+	if err != nil {
+		return nil, err
+	}
+	if version > s.Latest() {
+		return nil, fmt.Errorf("%s store %s is at schema version %d, written by a newer release; this release reads up to %d",
+			s.Name, s.Path, version, s.Latest())
+	}
+
+	var pending []Migration
+	for _, migration := range s.Migrations {
+		if migration.Version > version {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate applies the pending migrations in order, recording the version
+// after each, and returns those applied. A store without files yet is marked
+// as at the latest version.
+func (s Store) Migrate() ([]Migration, error) {
+	pending, err := s.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range pending {
+		if err := migration.Apply(s.Path); err != nil {
+			return applied, fmt.Errorf("%s store: migration %d (%s): %w", s.Name, migration.Version, migration.Description, err)
+		}
+		if err := s.setVersion(migration.Version); err != nil {
+			return applied, err
+		}
+		applied = append(applied, migration)
+	}
+
+	if _, err := os.Stat(s.schemaPath()); errors.Is(err, os.ErrNotExist) {
+		if err := s.setVersion(s.Latest()); err != nil {
+			return applied, err
+		}
+	}
+	return applied, nil
+}
+
+// setVersion records the store's schema version, replacing the .schema file
+// atomically.
+func (s Store) setVersion(version int) error {
+	data, err := json.Marshal(schema{Version: version, UpdatedAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.schemaPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.schemaPath())
+}
+
+// Check migrates the stores when apply is set. Otherwise it fails when any
+// store has migrations pending, naming them. It returns the migrations
+// applied, by store name.
+func Check(stores []Store, apply bool) (map[string][]Migration, error) {
+	applied := make(map[string][]Migration)
+	for _, store := range stores {
+		if apply {
+			migrations, err := store.Migrate()
+			if len(migrations) > 0 {
+				applied[store.Name] = migrations
+			}
+			if err != nil {
+				return applied, err
+			}
+			continue
+		}
+
+		pending, err := store.Pending()
+		if err != nil {
+			return applied, err
+		}
+		if len(pending) > 0 {
+			return applied, fmt.Errorf("%s store %s has %d pending schema migration(s) up to version %d; run the migrate command or set migrations.auto_apply",
+				store.Name, store.Path, len(pending), store.Latest())
+		}
+		// A new store is marked current, so it has nothing pending later
+		if _, err := store.Migrate(); err != nil {
+			return applied, err
+		}
+	}
+	return applied, nil
+}
+
+// Baseline is the first migration of every store. Files written before
+// schema versioning already have the version 1 format, so it only records
+// the version.
+func Baseline(path string) error {
+	return nil
+}
@@ -0,0 +1,168 @@
+package migrate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// recordingStore returns a store with three migrations that record their
+// versions as they are applied; the one at failAt fails while *fail is set.
+func recordingStore(t *testing.T, failAt int, fail *bool) (Store, *[]int) {
+	t.Helper()
+	var applied []int
+	migration := func(version int) Migration {
+		return Migration{Version: version, Description: "step", Apply: func(path string) error {
+			if version == failAt && *fail {
+				return errors.New("disk full")
+			}
+			applied = append(applied, version)
+			return nil
+		}}
+	}
+	store := Store{
+		Name:       "usage",
+		Path:       filepath.Join(t.TempDir(), "usage.jsonl"),
+		Migrations: []Migration{migration(1), migration(2), migration(3)},
+	}
+	return store, &applied
+}
+
+// writeStoreFile writes the store's file, as a release before schema
+// versioning did.
+func writeStoreFile(t *testing.T, store Store) {
+	t.Helper()
+	if err := os.WriteFile(store.Path, []byte("{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func versions(migrations []Migration) []int {
+	var versions []int
+	for _, migration := range migrations {
+		versions = append(versions, migration.Version)
+	}
+	return versions
+}
+
+func TestMigrateNewStoreStartsAtLatest(t *testing.T) {
+	fail := false
+	store, applied := recordingStore(t, 0, &fail)
+
+	if pending, err := store.Pending(); err != nil || len(pending) != 0 {
+		t.Fatalf("Pending() = %v, %v for a store without files", pending, err)
+	}
+	if _, err := store.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(*applied) != 0 {
+		t.Fatalf("migrations %v were applied to a store without files", *applied)
+	}
+	if version, err := store.Version(); err != nil || version != 3 {
+		t.Fatalf("Version() = %d, %v, want the latest", version, err)
+	}
+}
+
+func TestMigrateUnversionedStore(t *testing.T) {
+	fail := false
+	store, applied := recordingStore(t, 0, &fail)
+	writeStoreFile(t, store)
+
+	if version, err := store.Version(); err != nil || version != 0 {
+		t.Fatalf("Version() = %d, %v, want 0 before versioning", version, err)
+	}
+	pending, err := store.Pending()
+	if err != nil || !reflect.DeepEqual(versions(pending), []int{1, 2, 3}) {
+		t.Fatalf("Pending() = %v, %v, want every migration", versions(pending), err)
+	}
+
+	migrated, err := store.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(versions(migrated), []int{1, 2, 3}) || !reflect.DeepEqual(*applied, []int{1, 2, 3}) {
+		t.Fatalf("Migrate() applied %v, ran %v, want them in order", versions(migrated), *applied)
+	}
+	if migrated, err := store.Migrate(); err != nil || len(migrated) != 0 {
+		t.Fatalf("second Migrate() = %v, %v, want nothing to do", versions(migrated), err)
+	}
+}
+
+func TestMigrateResumesAfterFailure(t *testing.T) {
+	fail := true
+	store, applied := recordingStore(t, 2, &fail)
+	writeStoreFile(t, store)
+
+	migrated, err := store.Migrate()
+	if err == nil || !strings.Contains(err.Error(), "migration 2") {
+		t.Fatalf("Migrate() error = %v, want migration 2's failure", err)
+	}
+	if !reflect.DeepEqual(versions(migrated), []int{1}) {
+		t.Fatalf("Migrate() applied %v before failing, want [1]", versions(migrated))
+	}
+	if version, _ := store.Version(); version != 1 {
+		t.Fatalf("Version() = %d after the failure, want the last applied", version)
+	}
+
+	fail = false
+	migrated, err = store.Migrate()
+	if err != nil || !reflect.DeepEqual(versions(migrated), []int{2, 3}) {
+		t.Fatalf("Migrate() = %v, %v, want the rest", versions(migrated), err)
+	}
+	if !reflect.DeepEqual(*applied, []int{1, 2, 3}) {
+		t.Fatalf("migrations ran %v, want each once", *applied)
+	}
+}
+
+func TestPendingRejectsNewerAndInvalidSchemas(t *testing.T) {
+	fail := false
+	store, _ := recordingStore(t, 0, &fail)
+	writeStoreFile(t, store)
+
+	if err := store.setVersion(4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Pending(); err == nil || !strings.Contains(err.Error(), "newer release") {
+		t.Fatalf("Pending() error = %v for a newer schema", err)
+	}
+
+	if err := os.WriteFile(store.schemaPath(), []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Pending(); err == nil {
+		t.Fatal("Pending() accepted an invalid .schema file")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	fail := false
+	existing, applied := recordingStore(t, 0, &fail)
+	writeStoreFile(t, existing)
+	fresh, _ := recordingStore(t, 0, &fail)
+	fresh.Name = "audit"
+
+	// Pending migrations refuse startup without auto_apply
+	if _, err := Check([]Store{fresh, existing}, false); err == nil || !strings.Contains(err.Error(), "pending") {
+		t.Fatalf("Check() error = %v, want the pending migrations named", err)
+	}
+	if len(*applied) != 0 {
+		t.Fatalf("Check() without apply ran migrations %v", *applied)
+	}
+	if version, _ := fresh.Version(); version != 3 {
+		t.Fatalf("new store at version %d after Check(), want it marked current", version)
+	}
+
+	migrated, err := Check([]Store{fresh, existing}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrated) != 1 || !reflect.DeepEqual(versions(migrated["usage"]), []int{1, 2, 3}) {
+		t.Fatalf("Check() applied %v, want the usage store's migrations", migrated)
+	}
+	if _, err := Check([]Store{fresh, existing}, false); err != nil {
+		t.Fatalf("Check() error = %v once migrated", err)
+	}
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 )
 
@@ -17,6 +19,21 @@ type ChatRequest struct {
 	PresencePenalty float64 `json:"presence_penalty,omitempty"`
 	FrequencyPenalty float64 `json:"frequency_penalty,omitempty"`
 	User        string    `json:"user,omitempty"`
+	Tools       []Tool    `json:"tools,omitempty"`
+	ToolChoice  *ToolChoice `json:"tool_choice,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	N           int       `json:"n,omitempty"`
+	Seed        *int      `json:"seed,omitempty"`
+	Logprobs    bool      `json:"logprobs,omitempty"`
+	TopLogprobs int       `json:"top_logprobs,omitempty"`
+	LogitBias   map[string]float64 `json:"logit_bias,omitempty"`
+	Thinking    *Thinking `json:"thinking,omitempty"`
+	// Metadata holds client-supplied key-value pairs for routing rules. It is
+	// not sent to providers.
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	// Routing holds the client's constraints on routing. It is not sent to
+	// providers.
+	Routing     *RoutingHints `json:"routing,omitempty"`
 	RequestID   string    `json:"request_id,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 }
@@ -24,11 +41,158 @@ type ChatRequest struct {
 // Message represents a single message in a conversation.
 type Message struct {
 	Role      string `json:"role"`
-	Content   string `json:"content"`
+	Content   Content `json:"content"`
 	Name      string `json:"name,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+	// ReasoningSignature and RedactedReasoning carry Anthropic's thinking
+	// signature and redacted thinking blocks, which must be sent back
+	// unchanged when the conversation continues.
+	ReasoningSignature string   `json:"reasoning_signature,omitempty"`
+	RedactedReasoning  []string `json:"redacted_reasoning,omitempty"`
 	Timestamp time.Time `json:"timestamp,omitempty"`
 }
 
+// Content is the content of a message: either plain text or a list of typed
+// parts mixing text and images. Plain text is encoded as a JSON string.
+type Content struct {
+	Parts []ContentPart
+}
+
+// ContentPart is one part of a multimodal message. Type is "text" or "image_url".
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL references an image by URL or as a base64 data URL.
+type ImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// TextContent returns content holding a single text part.
+func TextContent(text string) Content {
+	if text == "" {
+		return Content{}
+	}
+	return Content{Parts: []ContentPart{{Type: "text", Text: text}}}
+}
+
+// Text returns the concatenated text parts.
+func (c Content) Text() string {
+	if len(c.Parts) == 1 {
+		return c.Parts[0].Text
+	}
+	var text strings.Builder
+	for _, part := range c.Parts {
+		if part.Type == "text" {
+			text.WriteString(part.Text)
+		}
+	}
+	return text.String()
+}
+
+// IsText reports whether the content has no parts other than text.
+func (c Content) IsText() bool {
+	for _, part := range c.Parts {
+		if part.Type != "text" {
+			return false
+		}
+	}
+	return true
+}
+
+// Images returns the image parts.
+func (c Content) Images() []ImageURL {
+	var images []ImageURL
+	for _, part := range c.Parts {
+		if part.Type == "image_url" && part.ImageURL != nil {
+			images = append(images, *part.ImageURL)
+		}
+	}
+	return images
+}
+
+// MarshalJSON encodes text-only content as a string and anything else as parts.
+func (c Content) MarshalJSON() ([]byte, error) {
+	if c.IsText() {
+		return json.Marshal(c.Text())
+	}
+	return json.Marshal(c.Parts)
+}
+
+// UnmarshalJSON decodes a string, an array of parts, or null.
+func (c *Content) UnmarshalJSON(data []byte) error {
+	var text *string
+	if err := json.Unmarshal(data, &text); err == nil {
+		if text == nil {
+			*c = Content{}
+		} else {
+			*c = TextContent(*text)
+		}
+		return nil
+	}
+
+	var parts []ContentPart
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return err
+	}
+	*c = Content{Parts: parts}
+	return nil
+}
+
+// ResponseFormat constrains the response to JSON. Type is "text",
+// "json_object" or "json_schema"; JSONSchema is set for "json_schema".
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+// JSONSchema is a named JSON Schema the response must conform to.
+type JSONSchema struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Schema      json.RawMessage `json:"schema,omitempty"`
+	Strict      bool            `json:"strict,omitempty"`
+}
+
+// Tool is a function the model may call.
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction describes a callable function and its JSON Schema parameters.
+type ToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ToolChoice controls whether and which tool the model calls. Type is one of
+// "auto", "none", "required" or "function"; Name is set for "function".
+type ToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// ToolCall is a function call requested by the model. Arguments is the
+// JSON-encoded argument object.
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction names the called function and its arguments.
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
 // ChatResponse represents a unified successful response.
 type ChatResponse struct {
 	ID      string   `json:"id"`
@@ -44,7 +208,38 @@ type ChatResponse struct {
 type Choice struct {
 	Index   int     `json:"index"`
 	Message Message `json:"message"`
+	Logprobs *Logprobs `json:"logprobs,omitempty"`
 	FinishReason string `json:"finish_reason"`
+	// StopSequence is the stop sequence that ended generation, when known.
+	StopSequence string `json:"stop_sequence,omitempty"`
+}
+
+// Thinking enables extended thinking, where the model reasons before
+// answering within a budget of BudgetTokens output tokens.
+type Thinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens,omitempty"`
+}
+
+// Logprobs holds the log probabilities of the generated tokens.
+type Logprobs struct {
+	Content []TokenLogprob `json:"content"`
+}
+
+// TokenLogprob is the log probability of one generated token and, when
+// requested, of the most likely alternatives at that position.
+type TokenLogprob struct {
+	Token       string       `json:"token"`
+	Logprob     float64      `json:"logprob"`
+	Bytes       []int        `json:"bytes,omitempty"`
+	TopLogprobs []TopLogprob `json:"top_logprobs,omitempty"`
+}
+
+// TopLogprob is the log probability of an alternative token.
+type TopLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+	Bytes   []int   `json:"bytes,omitempty"`
 }
 
 // Usage represents token usage statistics.
@@ -52,6 +247,14 @@ type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+	PromptTokensDetails *PromptTokensDetails `json:"prompt_tokens_details,omitempty"`
+}
+
+// PromptTokensDetails breaks down prompt tokens served from the provider's
+// prompt cache. Both counts are included in PromptTokens.
+type PromptTokensDetails struct {
+	CachedTokens        int `json:"cached_tokens"`
+	CacheCreationTokens int `json:"cache_creation_tokens,omitempty"`
 }
 
 // StreamResponse represents a streaming response chunk.
@@ -62,6 +265,10 @@ type StreamResponse struct {
 	Created int64    `json:"created"`
 	Provider string  `json:"provider"`
 	RequestID string `json:"request_id,omitempty"`
+
+	// Usage is set on the chunk, usually the last, on which the provider
+	// reported the usage of the whole stream.
+	Usage *Usage `json:"usage,omitempty"`
 }
 
 // StreamChoice represents a streaming choice.
@@ -71,6 +278,98 @@ type StreamChoice struct {
 	FinishReason string `json:"finish_reason,omitempty"`
 }
 
+// EmbeddingRequest represents a unified embeddings request.
+type EmbeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+	User       string   `json:"user,omitempty"`
+	RequestID  string   `json:"request_id,omitempty"`
+}
+
+// EmbeddingResponse represents a unified embeddings response.
+type EmbeddingResponse struct {
+	Model     string      `json:"model"`
+	Data      []Embedding `json:"data"`
+	Usage     Usage       `json:"usage"`
+	Provider  string      `json:"provider"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// Embedding is the vector for one input of an embeddings request.
+type Embedding struct {
+	Index     int       `json:"index"`
+	Embedding []float64 `json:"embedding"`
+}
+
+// ImageRequest represents a unified image generation request.
+type ImageRequest struct {
+	Model          string `json:"model"`
+	Prompt         string `json:"prompt"`
+	N              int    `json:"n,omitempty"`
+	Size           string `json:"size,omitempty"`
+	Quality        string `json:"quality,omitempty"`
+	Style          string `json:"style,omitempty"`
+	ResponseFormat string `json:"response_format,omitempty"` // "url" or "b64_json"
+	User           string `json:"user,omitempty"`
+	RequestID      string `json:"request_id,omitempty"`
+}
+
+// ImageResponse represents a unified image generation response.
+type ImageResponse struct {
+	Created   int64   `json:"created"`
+	Data      []Image `json:"data"`
+	Provider  string  `json:"provider"`
+	RequestID string  `json:"request_id,omitempty"`
+}
+
+// Image is a single generated image, returned either as a URL or base64 data.
+type Image struct {
+	URL           string `json:"url,omitempty"`
+	B64JSON       string `json:"b64_json,omitempty"`
+	RevisedPrompt string `json:"revised_prompt,omitempty"`
+}
+
+// TranscriptionRequest represents a unified speech-to-text request.
+type TranscriptionRequest struct {
+	Model          string  `json:"model"`
+	File           []byte  `json:"-"`
+	FileName       string  `json:"file_name"`
+	Language       string  `json:"language,omitempty"`
+	Prompt         string  `json:"prompt,omitempty"`
+	ResponseFormat string  `json:"response_format,omitempty"` // json, verbose_json, text, srt or vtt
+	Temperature    float64 `json:"temperature,omitempty"`
+	RequestID      string  `json:"request_id,omitempty"`
+}
+
+// TranscriptionResponse represents a unified speech-to-text response. For the
+// text, srt and vtt formats, Text holds the document in that format.
+type TranscriptionResponse struct {
+	Text      string  `json:"text"`
+	Language  string  `json:"language,omitempty"`
+	Duration  float64 `json:"duration,omitempty"`
+	Provider  string  `json:"provider"`
+	RequestID string  `json:"request_id,omitempty"`
+}
+
+// SpeechRequest represents a unified text-to-speech request.
+type SpeechRequest struct {
+	Model          string  `json:"model"`
+	Input          string  `json:"input"`
+	Voice          string  `json:"voice"`
+	ResponseFormat string  `json:"response_format,omitempty"` // mp3, opus, aac, flac, wav or pcm
+	Speed          float64 `json:"speed,omitempty"`
+	RequestID      string  `json:"request_id,omitempty"`
+}
+
+// SpeechResponse represents generated audio.
+type SpeechResponse struct {
+	Audio       []byte `json:"-"`
+	ContentType string `json:"content_type"`
+	Provider    string `json:"provider"`
+	RequestID   string `json:"request_id,omitempty"`
+}
+
 // ProviderError represents a standardized error from any provider.
 type ProviderError struct {
 	StatusCode int    `json:"status_code"`
@@ -98,6 +397,30 @@ type HealthStatus struct {
 	Error     string    `json:"error,omitempty"`
 }
 
+// RoutingHints are a client's constraints on how a request is routed. Zero
+// values are unset.
+type RoutingHints struct {
+	PreferProviders  []string      `json:"prefer_providers,omitempty"`  // used when one can serve the request
+	ExcludeProviders []string      `json:"exclude_providers,omitempty"` // never used
+	MaxCost          float64       `json:"max_cost,omitempty"`          // estimated, in USD
+	MaxLatency       time.Duration `json:"max_latency,omitempty"`       // estimated
+	RequireStreaming bool          `json:"require_streaming,omitempty"`
+	Residency        string        `json:"residency,omitempty"` // residency tag, e.g. eu-only
+	Priority         string        `json:"priority,omitempty"`  // high, normal (default), low or deferred
+	Language         string        `json:"language,omitempty"`  // ISO 639-1 code the response must be in
+}
+
+// Request priorities. Under saturation, high-priority requests are served
+// before normal ones, and normal before low. Deferred requests are low
+// priority and may also wait for a provider's quota to reset instead of
+// failing while it is used up.
+const (
+	PriorityHigh     = "high"
+	PriorityNormal   = "normal"
+	PriorityLow      = "low"
+	PriorityDeferred = "deferred"
+)
+
 // RoutingRequest represents a request for routing decision.
 type RoutingRequest struct {
 	Request     ChatRequest `json:"request"`
@@ -11,7 +11,7 @@ import (
 type LoggerConfig struct {
 	Level      string `mapstructure:"level"`
 	Format     string `mapstructure:"format"` // json or console
-	OutputPath string `mapstructure:"output_path"`
+	OutputPath string `mapstructure:"output_path"` // file path, stdout or stderr
 	ErrorPath  string `mapstructure:"error_path"`
 	Development bool   `mapstructure:"development"`
 }
@@ -50,12 +50,12 @@ func NewLogger(config LoggerConfig) (*zap.Logger, error) {
 		)
 	} else {
 		// Production mode: log to file
-		outputFile, err := os.OpenFile(config.OutputPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		outputFile, err := openLogOutput(config.OutputPath)
 		if err != nil {
 			return nil, err
 		}
 
-		errorFile, err := os.OpenFile(config.ErrorPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		errorFile, err := openLogOutput(config.ErrorPath)
 		if err != nil {
 			outputFile.Close()
 			return nil, err
@@ -91,6 +91,18 @@ func NewLogger(config LoggerConfig) (*zap.Logger, error) {
 	return logger, nil
 }
 
+// openLogOutput opens a log file for appending; stdout and stderr name the
+// process's standard streams, as used by containers and sidecars.
+func openLogOutput(path string) (*os.File, error) {
+	switch path {
+	case "stdout":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	}
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+}
+
 // DefaultLogger creates a logger with sensible defaults.
 func DefaultLogger() *zap.Logger {
 	logger, err := NewLogger(LoggerConfig{
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
 	"go.opentelemetry.io/otel/sdk/metric"
@@ -33,20 +34,82 @@ type Metrics struct {
 	requestsTotal    *prometheus.CounterVec
 	requestsDuration *prometheus.HistogramVec
 	requestsErrors   *prometheus.CounterVec
+	requestsInFlight prometheus.Gauge
 
 	// Provider metrics
 	providerHealth  *prometheus.GaugeVec
 	providerLatency *prometheus.HistogramVec
 	providerErrors  *prometheus.CounterVec
+	providerPhases  *prometheus.HistogramVec
+	providerWarmups *prometheus.HistogramVec
+
+	// Truncation metrics
+	truncations     *prometheus.CounterVec
+	continuations   *prometheus.CounterVec
+	streamStalls    *prometheus.CounterVec
+	weightFactors   *prometheus.GaugeVec
+	operatorWeights *prometheus.GaugeVec
+
+	// Circuit breaker metrics
+	breakerStates      *prometheus.GaugeVec
+	breakerTransitions *prometheus.CounterVec
 
 	// Routing metrics
-	routingDecisions *prometheus.CounterVec
-	routingLatency   *prometheus.HistogramVec
+	routingDecisions  *prometheus.CounterVec
+	routingLatency    *prometheus.HistogramVec
+	contentCategories *prometheus.CounterVec
+	routingOverhead   *prometheus.HistogramVec
+
+	// Tool execution metrics
+	toolCallDuration *prometheus.HistogramVec
 
 	// Cache metrics (for future use)
 	cacheHits   *prometheus.CounterVec
 	cacheMisses *prometheus.CounterVec
 	cacheSize   *prometheus.GaugeVec
+	cacheMemory *prometheus.GaugeVec
+
+	// Rate limit metrics
+	rateLimitDecisions     *prometheus.CounterVec
+	rateLimitCheckDuration *prometheus.HistogramVec
+	rateLimitApproxError   *prometheus.GaugeVec
+	rateLimitOvershoot     *prometheus.CounterVec
+
+	// Priority tier metrics
+	priorityAdmissions *prometheus.CounterVec
+	priorityQueueDepth *prometheus.GaugeVec
+	priorityQueueWait  *prometheus.HistogramVec
+
+	// Fallback and spend metrics
+	fallbacks  *prometheus.CounterVec
+	spend      *prometheus.CounterVec
+	hedges     *prometheus.CounterVec
+	hedgeWaste *prometheus.CounterVec
+	aborted    *prometheus.CounterVec
+	spillovers *prometheus.CounterVec
+
+	// Response language enforcement metrics
+	languageEnforcement *prometheus.CounterVec
+
+	// Model list metrics
+	modelListFailures *prometheus.CounterVec
+
+	// Shadow traffic metrics
+	shadowRequests   *prometheus.CounterVec
+	shadowLatency    *prometheus.HistogramVec
+	shadowSpend      *prometheus.CounterVec
+	shadowSimilarity *prometheus.HistogramVec
+
+	// In-process consumers of provider events
+	observers []Observer
+}
+
+// Observer receives provider events as they are recorded, for consumers that
+// evaluate them in process, such as alert rules. Observers must not block.
+type Observer interface {
+	ObserveProviderRequest(providerName string, failed bool)
+	ObserveFallback(providerName string)
+	ObserveSpend(providerName string, cost float64)
 }
 
 // NewMetrics creates a new metrics instance.
@@ -108,6 +171,13 @@ func (m *Metrics) initMetrics() error {
 		[]string{"method", "endpoint", "error_type"},
 	)
 
+	m.requestsInFlight = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "semaroute_requests_in_flight",
+			Help: "Number of requests currently being handled",
+		},
+	)
+
 	// Provider metrics
 	m.providerHealth = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -134,6 +204,24 @@ func (m *Metrics) initMetrics() error {
 		[]string{"provider_name", "error_type"},
 	)
 
+	m.providerPhases = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "semaroute_provider_request_phase_seconds",
+			Help:    "Duration of each phase of outbound provider requests: dns, connect, tls, first_byte and body",
+			Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120},
+		},
+		[]string{"provider_name", "phase"},
+	)
+
+	m.providerWarmups = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "semaroute_provider_prewarm_seconds",
+			Help:    "Duration of provider pre-warms after recoveries, by outcome (success or failure)",
+			Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"provider_name", "outcome"},
+	)
+
 	// Routing metrics
 	m.routingDecisions = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -152,6 +240,90 @@ func (m *Metrics) initMetrics() error {
 		[]string{"policy_name"},
 	)
 
+	m.contentCategories = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "semaroute_content_categories_total",
+			Help: "Requests routed by content category: code, reasoning, creative, extraction or general",
+		},
+		[]string{"category", "provider_name", "model"},
+	)
+
+	m.routingOverhead = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "semaroute_routing_overhead_seconds",
+			Help:    "Latency added by the router: request duration minus provider and client streaming time",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		},
+		[]string{"endpoint"},
+	)
+
+	// Truncation metrics
+	m.truncations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "semaroute_truncations_total",
+			Help: "Total number of responses cut off with finish_reason=length",
+		},
+		[]string{"provider_name", "model"},
+	)
+
+	m.streamStalls = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "semaroute_stream_stalls_total",
+			Help: "Streams ended because the provider sent no chunk within the stall timeout, by phase (first_chunk or mid_stream)",
+		},
+		[]string{"provider_name", "model", "phase"},
+	)
+
+	m.weightFactors = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "semaroute_routing_weight_factor",
+			Help: "Share of its configured routing weight a provider keeps after error budget reweighting",
+		},
+		[]string{"provider_name"},
+	)
+
+	m.operatorWeights = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "semaroute_provider_operator_weight",
+			Help: "Routing weight set for a provider by an operator at runtime; 0 drains it",
+		},
+		[]string{"provider_name"},
+	)
+
+	m.breakerStates = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "semaroute_circuit_breaker_state",
+			Help: "State of a provider's circuit breaker: 0 closed, 1 half-open, 2 open",
+		},
+		[]string{"provider_name"},
+	)
+
+	m.breakerTransitions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "semaroute_circuit_breaker_transitions_total",
+			Help: "Circuit breaker state changes, by the state entered",
+		},
+		[]string{"provider_name", "state"},
+	)
+
+	m.continuations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "semaroute_continuations_total",
+			Help: "Total number of automatic continuation requests for truncated responses",
+		},
+		[]string{"provider_name", "model"},
+	)
+
+	// Tool execution metrics
+	m.toolCallDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "semaroute_tool_call_duration_seconds",
+			Help:    "Server-side tool call latency in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"tool", "status"},
+	)
+
 	// Cache metrics
 	m.cacheHits = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -177,6 +349,176 @@ func (m *Metrics) initMetrics() error {
 		[]string{"cache_type"},
 	)
 
+	m.cacheMemory = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "semaroute_cache_memory_bytes",
+			Help: "Estimated memory held by cached values, after compression",
+		},
+		[]string{"cache_type"},
+	)
+
+	// Rate limit metrics
+	m.rateLimitDecisions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "semaroute_ratelimit_decisions_total",
+			Help: "Rate limit decisions by limit, mode and result (allowed, denied, fallback)",
+		},
+		[]string{"limit", "mode", "result"},
+	)
+
+	m.rateLimitCheckDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "semaroute_ratelimit_check_duration_seconds",
+			Help:    "Time spent deciding a rate limit check in seconds",
+			Buckets: []float64{0.00001, 0.0001, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05},
+		},
+		[]string{"mode"},
+	)
+
+	m.rateLimitApproxError = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "semaroute_ratelimit_approximation_error",
+			Help: "Worst fraction by which the fleet exceeded a local limit in the current window, at the last reconciliation",
+		},
+		[]string{"limit"},
+	)
+
+	m.rateLimitOvershoot = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "semaroute_ratelimit_overshoot_total",
+			Help: "Requests admitted by local limits beyond the fleet-wide limit",
+		},
+		[]string{"limit"},
+	)
+
+	// Priority tier metrics
+	m.priorityAdmissions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "semaroute_priority_requests_total",
+			Help: "Requests by priority tier and admission result (admitted, queued, shed or expired)",
+		},
+		[]string{"tier", "result"},
+	)
+
+	m.priorityQueueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "semaroute_priority_queue_depth",
+			Help: "Requests waiting for a slot, by priority tier",
+		},
+		[]string{"tier"},
+	)
+
+	m.priorityQueueWait = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "semaroute_priority_queue_wait_seconds",
+			Help:    "Time requests waited for a slot, by priority tier",
+			Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"tier"},
+	)
+
+	// Fallback and spend metrics
+	m.fallbacks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "semaroute_fallbacks_total",
+			Help: "Requests served by another provider after the chosen provider failed",
+		},
+		[]string{"from_provider", "to_provider"},
+	)
+
+	m.spend = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "semaroute_spend_usd_total",
+			Help: "Estimated spend in USD from the pricing catalog",
+		},
+		[]string{"provider", "model"},
+	)
+
+	m.hedges = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "semaroute_hedged_requests_total",
+			Help: "Requests reissued to a second provider after the hedging delay, by winner (primary, hedge or none)",
+		},
+		[]string{"primary_provider", "hedge_provider", "winner"},
+	)
+
+	m.hedgeWaste = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "semaroute_hedge_wasted_spend_usd_total",
+			Help: "Estimated spend in USD of hedged attempts cancelled because the other attempt won",
+		},
+		[]string{"provider"},
+	)
+
+	m.aborted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "semaroute_aborted_requests_total",
+			Help: "Chat completions cut short by a client disconnect or deadline, by reason",
+		},
+		[]string{"provider", "model", "reason"},
+	)
+
+	m.spillovers = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "semaroute_spillovers_total",
+			Help: "Requests spilled over from a rate-limited provider to the next candidate, by cause",
+		},
+		[]string{"from_provider", "to_provider", "cause"},
+	)
+
+	// Response language enforcement metrics
+	m.languageEnforcement = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "semaroute_language_enforcement_total",
+			Help: "Responses checked against a required language, by outcome: mismatch, corrected or uncorrected",
+		},
+		[]string{"provider", "model", "outcome"},
+	)
+
+	// Model list metrics
+	m.modelListFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "semaroute_model_list_failures_total",
+			Help: "Failed attempts to fetch a provider's model list",
+		},
+		[]string{"provider"},
+	)
+
+	// Shadow traffic metrics
+	m.shadowRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "semaroute_shadow_requests_total",
+			Help: "Requests mirrored to shadow providers, by outcome (match, mismatch, error or dropped)",
+		},
+		[]string{"shadow_provider", "outcome"},
+	)
+
+	m.shadowLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "semaroute_shadow_latency_seconds",
+			Help:    "Latency of successful mirrored requests to shadow providers",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"shadow_provider"},
+	)
+
+	m.shadowSpend = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "semaroute_shadow_spend_usd_total",
+			Help: "Estimated spend in USD of mirrored requests from the pricing catalog",
+		},
+		[]string{"shadow_provider"},
+	)
+
+	m.shadowSimilarity = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "semaroute_shadow_similarity",
+			Help:    "Embedding cosine similarity of shadow responses to the primary responses",
+			Buckets: []float64{.5, .6, .7, .8, .85, .9, .95, .98, .99, 1},
+		},
+		[]string{"shadow_provider"},
+	)
+
 	// Register all metrics
 	metrics := []prometheus.Collector{
 		m.requestsTotal,
@@ -185,11 +527,45 @@ func (m *Metrics) initMetrics() error {
 		m.providerHealth,
 		m.providerLatency,
 		m.providerErrors,
+		m.providerPhases,
+		m.providerWarmups,
 		m.routingDecisions,
 		m.routingLatency,
+		m.contentCategories,
+		m.routingOverhead,
+		m.truncations,
+		m.continuations,
+		m.streamStalls,
+		m.weightFactors,
+		m.operatorWeights,
+		m.breakerStates,
+		m.breakerTransitions,
+		m.toolCallDuration,
 		m.cacheHits,
 		m.cacheMisses,
 		m.cacheSize,
+		m.cacheMemory,
+		m.rateLimitDecisions,
+		m.rateLimitCheckDuration,
+		m.rateLimitApproxError,
+		m.rateLimitOvershoot,
+		m.priorityAdmissions,
+		m.priorityQueueDepth,
+		m.priorityQueueWait,
+		m.fallbacks,
+		m.spend,
+		m.hedges,
+		m.hedgeWaste,
+		m.aborted,
+		m.spillovers,
+		m.languageEnforcement,
+		m.modelListFailures,
+		m.shadowRequests,
+		m.shadowLatency,
+		m.shadowSpend,
+		m.shadowSimilarity,
+		m.requestsInFlight,
+		collectors.NewGoCollector(),
 	}
 
 	for _, metric := range metrics {
@@ -209,6 +585,71 @@ func (m *Metrics) RecordRequest(method, endpoint string, statusCode int, duratio
 	m.requestsDuration.WithLabelValues(method, endpoint).Observe(duration.Seconds())
 }
 
+// RecordInFlightRequests records the number of requests currently being handled.
+func (m *Metrics) RecordInFlightRequests(count int64) {
+	m.requestsInFlight.Set(float64(count))
+}
+
+// RegisterProviderConcurrency exports the in-flight requests, queue depth and
+// saturation (in-flight / limit) of a concurrency-limited provider.
+func (m *Metrics) RegisterProviderConcurrency(providerName string, limit int, inFlight, queued func() int) error {
+	labels := prometheus.Labels{"provider_name": providerName}
+
+	gauges := []prometheus.Collector{
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "semaroute_provider_in_flight",
+			Help:        "Requests currently in flight to the provider",
+			ConstLabels: labels,
+		}, func() float64 { return float64(inFlight()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "semaroute_provider_queue_depth",
+			Help:        "Requests waiting for a provider concurrency slot",
+			ConstLabels: labels,
+		}, func() float64 { return float64(queued()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "semaroute_provider_saturation",
+			Help:        "Fraction of the provider concurrency limit in use",
+			ConstLabels: labels,
+		}, func() float64 { return float64(inFlight()) / float64(limit) }),
+	}
+
+	for _, gauge := range gauges {
+		if err := m.registry.Register(gauge); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RegisterProviderBandwidth exports the response bytes received from a
+// provider, as received and after decoding, so compression savings can be
+// compared across providers.
+func (m *Metrics) RegisterProviderBandwidth(providerName string, wireBytes, decodedBytes func() int64) error {
+	labels := prometheus.Labels{"provider_name": providerName}
+
+	counters := []prometheus.Collector{
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name:        "semaroute_provider_response_wire_bytes_total",
+			Help:        "Response body bytes received from the provider, before decoding",
+			ConstLabels: labels,
+		}, func() float64 { return float64(wireBytes()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name:        "semaroute_provider_response_decoded_bytes_total",
+			Help:        "Response body bytes received from the provider, after decoding",
+			ConstLabels: labels,
+		}, func() float64 { return float64(decodedBytes()) }),
+	}
+
+	for _, counter := range counters {
+		if err := m.registry.Register(counter); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // RecordRequestError records metrics for a request error.
 func (m *Metrics) RecordRequestError(method, endpoint, errorType string) {
 	m.requestsErrors.WithLabelValues(method, endpoint, errorType).Inc()
@@ -226,11 +667,76 @@ func (m *Metrics) RecordProviderHealth(providerName string, healthy bool) {
 // RecordProviderLatency records the response latency of a provider.
 func (m *Metrics) RecordProviderLatency(providerName, model string, duration time.Duration) {
 	m.providerLatency.WithLabelValues(providerName, model).Observe(duration.Seconds())
+	for _, observer := range m.observers {
+		observer.ObserveProviderRequest(providerName, false)
+	}
+}
+
+// RecordProviderPhase records the duration of one phase of an outbound
+// request to a provider.
+func (m *Metrics) RecordProviderPhase(providerName, phase string, duration time.Duration) {
+	m.providerPhases.WithLabelValues(providerName, phase).Observe(duration.Seconds())
 }
 
 // RecordProviderError records an error from a provider.
 func (m *Metrics) RecordProviderError(providerName, errorType string) {
 	m.providerErrors.WithLabelValues(providerName, errorType).Inc()
+	for _, observer := range m.observers {
+		observer.ObserveProviderRequest(providerName, true)
+	}
+}
+
+// RecordFallback records a request served by another provider after the
+// chosen provider failed.
+func (m *Metrics) RecordFallback(fromProvider, toProvider string) {
+	m.fallbacks.WithLabelValues(fromProvider, toProvider).Inc()
+	for _, observer := range m.observers {
+		observer.ObserveFallback(fromProvider)
+	}
+}
+
+// RecordSpend records the estimated cost of a served request.
+func (m *Metrics) RecordSpend(providerName, model string, cost float64) {
+	m.spend.WithLabelValues(providerName, model).Add(cost)
+	for _, observer := range m.observers {
+		observer.ObserveSpend(providerName, cost)
+	}
+}
+
+// RecordHedge records a hedged request and which attempt served it.
+func (m *Metrics) RecordHedge(primaryProvider, hedgeProvider, winner string) {
+	m.hedges.WithLabelValues(primaryProvider, hedgeProvider, winner).Inc()
+}
+
+// RecordHedgeWaste records the estimated spend of a cancelled hedged attempt.
+func (m *Metrics) RecordHedgeWaste(providerName string, cost float64) {
+	m.hedgeWaste.WithLabelValues(providerName).Add(cost)
+}
+
+// RecordAbortedRequest records a chat completion cut short before it completed.
+func (m *Metrics) RecordAbortedRequest(providerName, model, reason string) {
+	m.aborted.WithLabelValues(providerName, model, reason).Inc()
+}
+
+// RecordSpillover records a request spilled over from a rate-limited provider.
+func (m *Metrics) RecordSpillover(fromProvider, toProvider, cause string) {
+	m.spillovers.WithLabelValues(fromProvider, toProvider, cause).Inc()
+}
+
+// RecordLanguageEnforcement records the outcome of a response language check.
+func (m *Metrics) RecordLanguageEnforcement(providerName, model, outcome string) {
+	m.languageEnforcement.WithLabelValues(providerName, model, outcome).Inc()
+}
+
+// RecordModelListFailure records a failed attempt to fetch a provider's model list.
+func (m *Metrics) RecordModelListFailure(providerName string) {
+	m.modelListFailures.WithLabelValues(providerName).Inc()
+}
+
+// AddObserver registers an observer of provider events. It must be called
+// before requests are served.
+func (m *Metrics) AddObserver(observer Observer) {
+	m.observers = append(m.observers, observer)
 }
 
 // RecordRoutingDecision records a routing decision made by a policy.
@@ -238,11 +744,99 @@ func (m *Metrics) RecordRoutingDecision(policyName, providerName, model string)
 	m.routingDecisions.WithLabelValues(policyName, providerName, model).Inc()
 }
 
+// RecordContentCategory records the content category a request was routed by.
+func (m *Metrics) RecordContentCategory(category, providerName, model string) {
+	m.contentCategories.WithLabelValues(category, providerName, model).Inc()
+}
+
 // RecordRoutingLatency records the time taken to make a routing decision.
 func (m *Metrics) RecordRoutingLatency(policyName string, duration time.Duration) {
 	m.routingLatency.WithLabelValues(policyName).Observe(duration.Seconds())
 }
 
+// RecordRoutingOverhead records the latency added by the router to a request.
+func (m *Metrics) RecordRoutingOverhead(endpoint string, overhead time.Duration) {
+	m.routingOverhead.WithLabelValues(endpoint).Observe(overhead.Seconds())
+}
+
+// RecordTruncation records a response that was cut off by the token limit.
+func (m *Metrics) RecordTruncation(providerName, model string) {
+	m.truncations.WithLabelValues(providerName, model).Inc()
+}
+
+// RecordStreamStall records a stream ended by the stall watchdog.
+func (m *Metrics) RecordStreamStall(providerName, model, phase string) {
+	m.streamStalls.WithLabelValues(providerName, model, phase).Inc()
+}
+
+// RecordProviderPrewarm records a pre-warm of a recovered provider.
+func (m *Metrics) RecordProviderPrewarm(providerName string, success bool, duration time.Duration) {
+	outcome := "success"
+	if !success {
+		outcome = "failure"
+	}
+	m.providerWarmups.WithLabelValues(providerName, outcome).Observe(duration.Seconds())
+}
+
+// RecordShadowRequest records the outcome of a request mirrored to a shadow
+// provider.
+func (m *Metrics) RecordShadowRequest(shadowProvider, outcome string) {
+	m.shadowRequests.WithLabelValues(shadowProvider, outcome).Inc()
+}
+
+// RecordShadowResponse records the latency, cost and, when scored, the
+// similarity of a successful shadow response.
+func (m *Metrics) RecordShadowResponse(shadowProvider string, latency time.Duration, cost float64, similarity *float64) {
+	m.shadowLatency.WithLabelValues(shadowProvider).Observe(latency.Seconds())
+	if cost > 0 {
+		m.shadowSpend.WithLabelValues(shadowProvider).Add(cost)
+	}
+	if similarity != nil {
+		m.shadowSimilarity.WithLabelValues(shadowProvider).Observe(*similarity)
+	}
+}
+
+// RecordWeightFactor records the share of its routing weight a provider keeps.
+func (m *Metrics) RecordWeightFactor(providerName string, factor float64) {
+	m.weightFactors.WithLabelValues(providerName).Set(factor)
+}
+
+// RecordOperatorWeight records the routing weight an operator set for a
+// provider.
+func (m *Metrics) RecordOperatorWeight(providerName string, weight float64) {
+	m.operatorWeights.WithLabelValues(providerName).Set(weight)
+}
+
+// ClearOperatorWeight removes the operator weight of a provider, once its
+// own weight applies again.
+func (m *Metrics) ClearOperatorWeight(providerName string) {
+	m.operatorWeights.DeleteLabelValues(providerName)
+}
+
+// RecordBreakerState records a provider's circuit breaker entering a state:
+// closed, half_open or open.
+func (m *Metrics) RecordBreakerState(providerName, state string) {
+	value := 0.0
+	switch state {
+	case "half_open":
+		value = 1
+	case "open":
+		value = 2
+	}
+	m.breakerStates.WithLabelValues(providerName).Set(value)
+	m.breakerTransitions.WithLabelValues(providerName, state).Inc()
+}
+
+// RecordContinuation records an automatic continuation request.
+func (m *Metrics) RecordContinuation(providerName, model string) {
+	m.continuations.WithLabelValues(providerName, model).Inc()
+}
+
+// RecordToolCall records the latency and outcome of a server-side tool call.
+func (m *Metrics) RecordToolCall(tool, status string, duration time.Duration) {
+	m.toolCallDuration.WithLabelValues(tool, status).Observe(duration.Seconds())
+}
+
 // RecordCacheHit records a cache hit.
 func (m *Metrics) RecordCacheHit(cacheType string) {
 	m.cacheHits.WithLabelValues(cacheType).Inc()
@@ -258,6 +852,40 @@ func (m *Metrics) RecordCacheSize(cacheType string, size int) {
 	m.cacheSize.WithLabelValues(cacheType).Set(float64(size))
 }
 
+// RecordCacheMemory records the memory held by a cache in bytes.
+func (m *Metrics) RecordCacheMemory(cacheType string, bytes int64) {
+	m.cacheMemory.WithLabelValues(cacheType).Set(float64(bytes))
+}
+
+// RecordRateLimitDecision records a rate limit decision and how long it took.
+func (m *Metrics) RecordRateLimitDecision(limit, mode, result string, duration time.Duration) {
+	m.rateLimitDecisions.WithLabelValues(limit, mode, result).Inc()
+	m.rateLimitCheckDuration.WithLabelValues(mode).Observe(duration.Seconds())
+}
+
+// RecordRateLimitApproximationError records how far local limiting overshot a limit.
+func (m *Metrics) RecordRateLimitApproximationError(limit string, ratio float64) {
+	m.rateLimitApproxError.WithLabelValues(limit).Set(ratio)
+}
+
+// RecordRateLimitOvershoot records requests admitted beyond a fleet-wide limit.
+func (m *Metrics) RecordRateLimitOvershoot(limit string, requests int) {
+	m.rateLimitOvershoot.WithLabelValues(limit).Add(float64(requests))
+}
+
+// RecordPriorityAdmission records the admission of a request of a priority
+// tier and how long it waited for a slot.
+func (m *Metrics) RecordPriorityAdmission(tier, result string, waited time.Duration) {
+	m.priorityAdmissions.WithLabelValues(tier, result).Inc()
+	m.priorityQueueWait.WithLabelValues(tier).Observe(waited.Seconds())
+}
+
+// RecordPriorityQueueDepth records the number of requests of a priority tier
+// waiting for a slot.
+func (m *Metrics) RecordPriorityQueueDepth(tier string, depth int) {
+	m.priorityQueueDepth.WithLabelValues(tier).Set(float64(depth))
+}
+
 // GetRegistry returns the Prometheus registry.
 func (m *Metrics) GetRegistry() *prometheus.Registry {
 	return m.registry
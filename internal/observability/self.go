@@ -0,0 +1,194 @@
+package observability
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// providerTimerKey is the context key for the per-request provider timer.
+type providerTimerKey struct{}
+
+// ProviderTimer accumulates the time a request spends waiting on providers
+// (and, for streams, on the client), so the router's own share of the request
+// latency can be derived.
+type ProviderTimer struct {
+	start time.Time
+	nanos int64
+}
+
+// WithProviderTimer returns a context carrying a new provider timer started now.
+func WithProviderTimer(ctx context.Context) (context.Context, *ProviderTimer) {
+	timer := &ProviderTimer{start: time.Now()}
+	return context.WithValue(ctx, providerTimerKey{}, timer), timer
+}
+
+// ProviderTimerFrom returns the provider timer carried by ctx, or nil.
+func ProviderTimerFrom(ctx context.Context) *ProviderTimer {
+	timer, _ := ctx.Value(providerTimerKey{}).(*ProviderTimer)
+	return timer
+}
+
+// Add records time spent waiting on a provider. It is safe to call on a nil timer.
+func (t *ProviderTimer) Add(d time.Duration) {
+	if t == nil {
+		return
+	}
+	atomic.AddInt64(&t.nanos, int64(d))
+}
+
+// Total returns the accumulated provider time.
+func (t *ProviderTimer) Total() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return time.Duration(atomic.LoadInt64(&t.nanos))
+}
+
+// Overhead returns the time elapsed since the timer started that was not
+// spent waiting on providers.
+func (t *ProviderTimer) Overhead() time.Duration {
+	if t == nil {
+		return 0
+	}
+	overhead := time.Since(t.start) - t.Total()
+	if overhead < 0 {
+		return 0
+	}
+	return overhead
+}
+
+// SelfReport describes the router's own health, independent of its providers.
+type SelfReport struct {
+	Uptime           time.Duration  `json:"uptime"`
+	Goroutines       int            `json:"goroutines"`
+	InFlightRequests int64          `json:"in_flight_requests"`
+	QueueDepths      map[string]int `json:"queue_depths"`
+	HeapAllocBytes   uint64         `json:"heap_alloc_bytes"`
+	NumGC            uint32         `json:"num_gc"`
+	GCPauseP50       time.Duration  `json:"gc_pause_p50"`
+	GCPauseP99       time.Duration  `json:"gc_pause_p99"`
+	GCPauseMax       time.Duration  `json:"gc_pause_max"`
+	OverheadSamples  int            `json:"handler_overhead_samples"`
+	OverheadP50      time.Duration  `json:"handler_overhead_p50"`
+	OverheadP95      time.Duration  `json:"handler_overhead_p95"`
+	OverheadP99      time.Duration  `json:"handler_overhead_p99"`
+}
+
+// SelfMonitor tracks router-internal load: in-flight requests, registered
+// queue depths, and handler latency excluding time spent in providers.
+type SelfMonitor struct {
+	startedAt time.Time
+	inFlight  int64
+
+	overhead []time.Duration // ring buffer of recent samples
+	next     int
+	filled   bool
+
+	queues map[string]func() int
+	mutex  sync.Mutex
+}
+
+// NewSelfMonitor creates a monitor keeping the given number of overhead samples.
+func NewSelfMonitor(sampleSize int) *SelfMonitor {
+	if sampleSize <= 0 {
+		sampleSize = 1024
+	}
+
+	return &SelfMonitor{
+		startedAt: time.Now(),
+		overhead:  make([]time.Duration, sampleSize),
+		queues:    make(map[string]func() int),
+	}
+}
+
+// RegisterQueue adds a named queue whose current depth is reported by Snapshot.
+func (m *SelfMonitor) RegisterQueue(name string, depth func() int) {
+	m.mutex.Lock()
+	m.queues[name] = depth
+	m.mutex.Unlock()
+}
+
+// RequestStarted marks a request as in flight.
+func (m *SelfMonitor) RequestStarted() {
+	atomic.AddInt64(&m.inFlight, 1)
+}
+
+// RequestFinished marks a request as done and records its handler overhead.
+func (m *SelfMonitor) RequestFinished(overhead time.Duration) {
+	atomic.AddInt64(&m.inFlight, -1)
+
+	if overhead < 0 {
+		overhead = 0
+	}
+
+	m.mutex.Lock()
+	m.overhead[m.next] = overhead
+	m.next = (m.next + 1) % len(m.overhead)
+	if m.next == 0 {
+		m.filled = true
+	}
+	m.mutex.Unlock()
+}
+
+// InFlight returns the number of requests currently being handled.
+func (m *SelfMonitor) InFlight() int64 {
+	return atomic.LoadInt64(&m.inFlight)
+}
+
+// Snapshot returns the current self-health report.
+func (m *SelfMonitor) Snapshot() SelfReport {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	report := SelfReport{
+		Uptime:           time.Since(m.startedAt),
+		Goroutines:       runtime.NumGoroutine(),
+		InFlightRequests: m.InFlight(),
+		QueueDepths:      make(map[string]int),
+		HeapAllocBytes:   memStats.HeapAlloc,
+		NumGC:            memStats.NumGC,
+	}
+
+	// PauseNs is a circular buffer of the most recent 256 GC pauses
+	pauses := make([]time.Duration, 0, len(memStats.PauseNs))
+	for i := uint32(0); i < memStats.NumGC && i < uint32(len(memStats.PauseNs)); i++ {
+		pauses = append(pauses, time.Duration(memStats.PauseNs[i]))
+	}
+	report.GCPauseP50 = percentile(pauses, 50)
+	report.GCPauseP99 = percentile(pauses, 99)
+	report.GCPauseMax = percentile(pauses, 100)
+
+	m.mutex.Lock()
+	samples := m.overhead[:m.next]
+	if m.filled {
+		samples = m.overhead
+	}
+	overhead := make([]time.Duration, len(samples))
+	copy(overhead, samples)
+	for name, depth := range m.queues {
+		report.QueueDepths[name] = depth()
+	}
+	m.mutex.Unlock()
+
+	report.OverheadSamples = len(overhead)
+	report.OverheadP50 = percentile(overhead, 50)
+	report.OverheadP95 = percentile(overhead, 95)
+	report.OverheadP99 = percentile(overhead, 99)
+
+	return report
+}
+
+// percentile returns the p-th percentile of values, sorting them in place.
+func percentile(values []time.Duration, p int) time.Duration {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	index := (len(values) - 1) * p / 100
+	return values[index]
+}
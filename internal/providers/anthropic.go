@@ -2,15 +2,31 @@ package providers
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/semantrix/semaroute/internal/adapters"
 	"github.com/semantrix/semaroute/internal/models"
 	"github.com/sethvargo/go-retry"
 )
 
+const (
+	// defaultAnthropicBaseURL is the Anthropic API base URL used when none is configured.
+	defaultAnthropicBaseURL = "https://api.anthropic.com"
+
+	// anthropicAPIVersion is sent in the anthropic-version header.
+	anthropicAPIVersion = "2023-06-01"
+
+	// anthropicMinThinkingBudget is the smallest thinking budget the API accepts.
+	anthropicMinThinkingBudget = 1024
+
+	// anthropicJSONToolName names the tool used to emulate JSON mode when the
+	// requested format has no schema name of its own.
+	anthropicJSONToolName = "json_response"
+)
+
 // AnthropicProvider implements the Provider interface for Anthropic.
 type AnthropicProvider struct {
 	*BaseProvider
@@ -18,34 +34,44 @@ type AnthropicProvider struct {
 }
 
 // NewAnthropicProvider creates a new Anthropic provider instance.
-func NewAnthropicProvider(config ProviderConfig) Provider {
-	client := &http.Client{
-		Timeout: config.Timeout,
+func NewAnthropicProvider(config ProviderConfig) (Provider, error) {
+	if config.BaseURL == "" {
+		config.BaseURL = defaultAnthropicBaseURL
+	}
+
+	base := NewBaseProvider(config)
+	client, err := newHTTPClient(config, base.bandwidth, base.phases)
+	if err != nil {
+		return nil, err
 	}
 
 	return &AnthropicProvider{
-		BaseProvider: NewBaseProvider(config),
+		BaseProvider: base,
 		client:       client,
-	}
+	}, nil
 }
 
 // GetModels returns the list of available Anthropic models.
 func (p *AnthropicProvider) GetModels() ([]string, error) {
 	// For now, return a static list. In production, this would call the Anthropic models endpoint.
-	return []string{
+	return p.withFineTunes([]string{
 		"claude-3-opus-20240229",
 		"claude-3-sonnet-20240229",
 		"claude-3-haiku-20240307",
 		"claude-2.1",
 		"claude-2.0",
 		"claude-instant-1.2",
-	}, nil
+	}), nil
 }
 
 // GetCostEstimate returns an estimated cost for the request.
 func (p *AnthropicProvider) GetCostEstimate(req models.ChatRequest) (float64, error) {
-	// Simplified cost estimation based on model and token count
-	// In production, this would use actual pricing data
+	// Prefer configured pricing from the model catalog
+	if cost, ok := p.catalogCostEstimate(req); ok {
+		return cost, nil
+	}
+
+	// Fall back to simplified cost estimation based on model and token count
 	model := req.Model
 	var costPer1kTokens float64
 
@@ -64,13 +90,8 @@ func (p *AnthropicProvider) GetCostEstimate(req models.ChatRequest) (float64, er
 		costPer1kTokens = 0.005
 	}
 
-	// Estimate tokens (rough approximation)
-	estimatedTokens := len(req.Messages) * 100 // Very rough estimate
-	if req.MaxTokens > 0 {
-		estimatedTokens += req.MaxTokens
-	}
-
-	return float64(estimatedTokens) * costPer1kTokens / 1000, nil
+	inputTokens, outputTokens := p.estimateTokens(req)
+	return float64(inputTokens+outputTokens) * costPer1kTokens / 1000, nil
 }
 
 // GetLatencyEstimate returns an estimated latency for the request.
@@ -79,22 +100,30 @@ func (p *AnthropicProvider) GetLatencyEstimate(req models.ChatRequest) (time.Dur
 	baseLatency := 300 * time.Millisecond
 	perTokenLatency := 15 * time.Millisecond
 
-	estimatedTokens := len(req.Messages) * 100
-	if req.MaxTokens > 0 {
-		estimatedTokens += req.MaxTokens
-	}
-
-	return baseLatency + time.Duration(estimatedTokens)*perTokenLatency, nil
+	// Prompt tokens are processed in parallel, so they add far less latency
+	// than generated tokens
+	inputTokens, outputTokens := p.estimateTokens(req)
+	return baseLatency + time.Duration(inputTokens)*perTokenLatency/20 + time.Duration(outputTokens)*perTokenLatency, nil
 }
 
 // CreateChatCompletion creates a chat completion using Anthropic's API.
 func (p *AnthropicProvider) CreateChatCompletion(ctx context.Context, req models.ChatRequest) (*models.ChatResponse, error) {
+	if err := p.CheckContextWindow(req); err != nil {
+		return nil, err
+	}
+
+	release, err := p.acquireSlot(ctx, req.RequestID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	// Convert to Anthropic format
 	anthropicReq := p.convertToAnthropicRequest(req)
 
 	// Implement retry logic
 	var response *models.ChatResponse
-	err := retry.Do(ctx, retry.WithMaxRetries(uint64(p.config.MaxRetries), retry.NewConstant(p.config.RetryDelay)), func(ctx context.Context) error {
+	err = retry.Do(ctx, retry.WithMaxRetries(uint64(p.config.MaxRetries), retry.NewConstant(p.config.RetryDelay)), func(ctx context.Context) error {
 		var err error
 		response, err = p.makeAnthropicRequest(ctx, anthropicReq)
 		if err != nil {
@@ -109,7 +138,7 @@ func (p *AnthropicProvider) CreateChatCompletion(ctx context.Context, req models
 
 	if err != nil {
 		return nil, &models.ProviderError{
-			StatusCode: 500,
+			StatusCode: statusCodeOf(err, 500),
 			Err:        err,
 			Provider:   p.GetName(),
 			RequestID:  req.RequestID,
@@ -117,14 +146,29 @@ func (p *AnthropicProvider) CreateChatCompletion(ctx context.Context, req models
 		}
 	}
 
+	if name, _, ok := anthropicStructuredOutput(req.ResponseFormat); ok {
+		unwrapStructuredOutput(response, name)
+	}
+
+	response.RequestID = req.RequestID
 	return response, nil
 }
 
-// CreateChatCompletionStream creates a streaming chat completion.
-func (p *AnthropicProvider) CreateChatCompletionStream(ctx context.Context, req models.ChatRequest) (<-chan models.StreamResponse, error) {
-	// For now, return an error indicating streaming is not yet implemented
-	// In production, this would implement Server-Sent Events or similar
-	return nil, fmt.Errorf("streaming not yet implemented for Anthropic provider")
+// SupportsVision reports whether model accepts image content; Claude 2 and
+// Claude Instant do not.
+func (p *AnthropicProvider) SupportsVision(model string) bool {
+	return !strings.Contains(model, "claude-2") && !strings.Contains(model, "claude-instant")
+}
+
+// SupportsTools reports whether model accepts tools; Claude 2 and Claude
+// Instant do not.
+func (p *AnthropicProvider) SupportsTools(model string) bool {
+	return !strings.Contains(model, "claude-2") && !strings.Contains(model, "claude-instant")
+}
+
+// Prewarm opens connections to the Anthropic API ahead of traffic.
+func (p *AnthropicProvider) Prewarm(ctx context.Context, connections int) (int, error) {
+	return prewarmConnections(ctx, p.client, p.config.BaseURL, connections)
 }
 
 // Close performs cleanup for the Anthropic provider.
@@ -138,50 +182,288 @@ func (p *AnthropicProvider) Close() error {
 // convertToAnthropicRequest converts our unified request to Anthropic format.
 func (p *AnthropicProvider) convertToAnthropicRequest(req models.ChatRequest) map[string]interface{} {
 	// Convert messages to Anthropic format
-	messages := make([]map[string]interface{}, len(req.Messages))
-	for i, msg := range req.Messages {
-		// Anthropic uses "user" and "assistant" roles
-		role := msg.Role
-		if role == "system" {
-			role = "user" // Anthropic doesn't have a system role, so we use user
+	messages := make([]map[string]interface{}, 0, len(req.Messages))
+	var system []string
+	for _, msg := range req.Messages {
+		// System prompts go in the top-level system parameter
+		if msg.Role == "system" {
+			system = append(system, msg.Content.Text())
+			continue
 		}
 
-		messages[i] = map[string]interface{}{
-			"role":    role,
-			"content": msg.Content,
+		// Tool results are sent back as tool_result blocks in a user message;
+		// consecutive results share one message since roles must alternate
+		if msg.Role == "tool" {
+			block := map[string]interface{}{
+				"type":        "tool_result",
+				"tool_use_id": msg.ToolCallID,
+				"content":     msg.Content.Text(),
+			}
+			if last := len(messages) - 1; last >= 0 && messages[last]["role"] == "user" {
+				if blocks, ok := messages[last]["content"].([]map[string]interface{}); ok {
+					messages[last]["content"] = append(blocks, block)
+					continue
+				}
+			}
+			messages = append(messages, map[string]interface{}{
+				"role":    "user",
+				"content": []map[string]interface{}{block},
+			})
+			continue
 		}
+
+		content := anthropicContent(msg.Content)
+		if len(msg.ToolCalls) > 0 || msg.ReasoningSignature != "" || len(msg.RedactedReasoning) > 0 {
+			content = anthropicAssistantBlocks(msg)
+		}
+
+		messages = append(messages, map[string]interface{}{
+			"role":    msg.Role,
+			"content": content,
+		})
 	}
 
-	anthropicReq := map[string]interface{}{
-		"model":       req.Model,
-		"messages":    messages,
-		"max_tokens":  req.MaxTokens,
-		"temperature": req.Temperature,
+	// max_tokens is required, so fall back to a default when the client sent none
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = p.defaultMaxTokens(req.Model)
 	}
 
-	if req.TopP > 0 {
-		anthropicReq["top_p"] = req.TopP
+	anthropicReq := map[string]interface{}{
+		"model":    req.Model,
+		"messages": messages,
 	}
-	if req.TopK > 0 {
-		anthropicReq["top_k"] = req.TopK
+	if len(system) > 0 {
+		anthropicReq["system"] = strings.Join(system, "\n\n")
 	}
+
+	if req.Thinking != nil && req.Thinking.Type == "enabled" {
+		// The thinking budget counts against max_tokens, which must exceed it,
+		// and sampling parameters cannot be changed while thinking
+		budget := req.Thinking.BudgetTokens
+		if budget < anthropicMinThinkingBudget {
+			budget = anthropicMinThinkingBudget
+		}
+		if maxTokens <= budget {
+			maxTokens = budget + p.defaultMaxTokens(req.Model)
+		}
+		anthropicReq["thinking"] = map[string]interface{}{
+			"type":          "enabled",
+			"budget_tokens": budget,
+		}
+	} else {
+		anthropicReq["temperature"] = req.Temperature
+		if req.TopP > 0 {
+			anthropicReq["top_p"] = req.TopP
+		}
+		if req.TopK > 0 {
+			anthropicReq["top_k"] = req.TopK
+		}
+	}
+	anthropicReq["max_tokens"] = maxTokens
+
 	if len(req.Stop) > 0 {
 		anthropicReq["stop_sequences"] = req.Stop
 	}
 
+	// A "none" tool choice is expressed by not offering any tools
+	if len(req.Tools) > 0 && (req.ToolChoice == nil || req.ToolChoice.Type != "none") {
+		tools := make([]map[string]interface{}, len(req.Tools))
+		for i, tool := range req.Tools {
+			schema := tool.Function.Parameters
+			if len(schema) == 0 {
+				schema = json.RawMessage(`{"type":"object","properties":{}}`)
+			}
+			tools[i] = map[string]interface{}{
+				"name":         tool.Function.Name,
+				"description":  tool.Function.Description,
+				"input_schema": schema,
+			}
+		}
+		anthropicReq["tools"] = tools
+
+		if req.ToolChoice != nil {
+			switch req.ToolChoice.Type {
+			case "required":
+				anthropicReq["tool_choice"] = map[string]interface{}{"type": "any"}
+			case "function":
+				anthropicReq["tool_choice"] = map[string]interface{}{"type": "tool", "name": req.ToolChoice.Name}
+			default:
+				anthropicReq["tool_choice"] = map[string]interface{}{"type": "auto"}
+			}
+		}
+	}
+
+	// Anthropic has no JSON mode, so structured output is emulated with a tool
+	// whose input schema is the requested schema, and the model must call it
+	if name, schema, ok := anthropicStructuredOutput(req.ResponseFormat); ok {
+		tools, _ := anthropicReq["tools"].([]map[string]interface{})
+		anthropicReq["tools"] = append(tools, map[string]interface{}{
+			"name":         name,
+			"description":  "Respond with the final answer as the input of this tool.",
+			"input_schema": schema,
+		})
+		anthropicReq["tool_choice"] = map[string]interface{}{"type": "tool", "name": name}
+	}
+
+	// Forcing a tool call is not allowed while thinking, so let the model choose
+	if _, thinking := anthropicReq["thinking"]; thinking {
+		if choice, ok := anthropicReq["tool_choice"].(map[string]interface{}); ok && choice["type"] != "auto" {
+			anthropicReq["tool_choice"] = map[string]interface{}{"type": "auto"}
+		}
+	}
+
 	return anthropicReq
 }
 
-// makeAnthropicRequest makes the actual HTTP request to Anthropic.
+// defaultMaxTokens returns the max_tokens sent when a request has none: the
+// configured default_max_tokens, or the model's maximum output length.
+func (p *AnthropicProvider) defaultMaxTokens(model string) int {
+	if p.config.DefaultMaxTokens > 0 {
+		return p.config.DefaultMaxTokens
+	}
+
+	switch {
+	case strings.HasPrefix(model, "claude-3-5"), strings.HasPrefix(model, "claude-3-7"),
+		strings.HasPrefix(model, "claude-sonnet-4"), strings.HasPrefix(model, "claude-opus-4"):
+		return 8192
+	default:
+		return 4096
+	}
+}
+
+// anthropicStructuredOutput returns the tool name and input schema used to
+// emulate a JSON response format, or false for plain text.
+func anthropicStructuredOutput(format *models.ResponseFormat) (string, json.RawMessage, bool) {
+	if format == nil {
+		return "", nil, false
+	}
+
+	switch format.Type {
+	case "json_object":
+		return anthropicJSONToolName, json.RawMessage(`{"type":"object"}`), true
+	case "json_schema":
+		name, schema := anthropicJSONToolName, json.RawMessage(`{"type":"object"}`)
+		if format.JSONSchema != nil {
+			if format.JSONSchema.Name != "" {
+				name = format.JSONSchema.Name
+			}
+			if len(format.JSONSchema.Schema) > 0 {
+				schema = format.JSONSchema.Schema
+			}
+		}
+		return name, schema, true
+	default:
+		return "", nil, false
+	}
+}
+
+// unwrapStructuredOutput turns the call to the structured-output tool back
+// into message content, so the client sees a plain JSON response.
+func unwrapStructuredOutput(response *models.ChatResponse, toolName string) {
+	for i := range response.Choices {
+		message := &response.Choices[i].Message
+
+		remaining := message.ToolCalls[:0]
+		for _, call := range message.ToolCalls {
+			if call.Function.Name == toolName {
+				message.Content = models.TextContent(call.Function.Arguments)
+				continue
+			}
+			remaining = append(remaining, call)
+		}
+		if len(remaining) == 0 {
+			message.ToolCalls = nil
+			if response.Choices[i].FinishReason == "tool_calls" {
+				response.Choices[i].FinishReason = "stop"
+			}
+		} else {
+			message.ToolCalls = remaining
+		}
+	}
+}
+
+// anthropicContent converts message content to Anthropic format: a plain
+// string for text, or text and image blocks for multimodal content. Data URLs
+// become base64 image sources; other URLs are passed by reference.
+func anthropicContent(content models.Content) interface{} {
+	if content.IsText() {
+		return content.Text()
+	}
+
+	blocks := make([]map[string]interface{}, 0, len(content.Parts))
+	for _, part := range content.Parts {
+		switch {
+		case part.Type == "text":
+			blocks = append(blocks, map[string]interface{}{
+				"type": "text",
+				"text": part.Text,
+			})
+		case part.Type == "image_url" && part.ImageURL != nil:
+			blocks = append(blocks, map[string]interface{}{
+				"type":   "image",
+				"source": anthropicImageSource(part.ImageURL.URL),
+			})
+		}
+	}
+	return blocks
+}
+
+// anthropicImageSource converts an image URL to an Anthropic image source.
+func anthropicImageSource(url string) map[string]interface{} {
+	// data:<media type>;base64,<data>
+	if rest, ok := strings.CutPrefix(url, "data:"); ok {
+		if header, data, ok := strings.Cut(rest, ","); ok {
+			if mediaType, ok := strings.CutSuffix(header, ";base64"); ok {
+				return map[string]interface{}{
+					"type":       "base64",
+					"media_type": mediaType,
+					"data":       data,
+				}
+			}
+		}
+	}
+	return map[string]interface{}{
+		"type": "url",
+		"url":  url,
+	}
+}
+
+// anthropicAssistantBlocks converts an assistant message to Anthropic content
+// blocks. Reasoning is only sent back with its signature, since Anthropic
+// rejects thinking blocks it cannot verify, such as another provider's.
+func anthropicAssistantBlocks(msg models.Message) []adapters.AnthropicContentBlock {
+	if msg.ReasoningSignature == "" {
+		msg.ReasoningContent = ""
+	}
+	return adapters.MessageBlocks(msg)
+}
+
+// makeAnthropicRequest makes the HTTP request to the Anthropic messages endpoint.
 func (p *AnthropicProvider) makeAnthropicRequest(ctx context.Context, req map[string]interface{}) (*models.ChatResponse, error) {
-	// This is a placeholder implementation
-	// In production, this would make the actual HTTP request to Anthropic's API
-	return nil, fmt.Errorf("Anthropic API request not yet implemented")
+	endpoint := strings.TrimRight(p.config.BaseURL, "/") + "/v1/messages"
+
+	apiKey := p.SelectAPIKey()
+
+	var anthropicResp adapters.AnthropicMessage
+	header, err := doJSONRequest(ctx, p.client, http.MethodPost, endpoint, p.requestHeaders(map[string]string{
+		"x-api-key":         apiKey,
+		"anthropic-version": anthropicAPIVersion,
+	}), req, &anthropicResp)
+	p.reportKeyResult(apiKey, err)
+	if header != nil {
+		p.updateRateLimit(parseAnthropicRateLimit(header))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response := adapters.FromAnthropic(anthropicResp)
+	response.Provider = p.GetName()
+	return response, nil
 }
 
 // isRetryableError determines if an error should trigger a retry.
 func (p *AnthropicProvider) isRetryableError(err error) bool {
-	// Check for retryable error conditions
-	// In production, this would check for rate limits, timeouts, etc.
-	return false
+	return isRetryableStatus(err)
 }
@@ -0,0 +1,66 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+func TestAnthropicRequestHoistsSystemPrompts(t *testing.T) {
+	provider, err := NewAnthropicProvider(ProviderConfig{Name: "anthropic", APIKey: "sk-ant"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := provider.(*AnthropicProvider)
+
+	tests := []struct {
+		name       string
+		messages   []models.Message
+		wantSystem interface{}
+		wantRoles  []string
+	}{
+		{
+			name:      "no system prompt",
+			messages:  []models.Message{{Role: "user", Content: models.TextContent("Hi")}},
+			wantRoles: []string{"user"},
+		},
+		{
+			name: "leading system prompt",
+			messages: []models.Message{
+				{Role: "system", Content: models.TextContent("Be brief.")},
+				{Role: "user", Content: models.TextContent("Hi")},
+			},
+			wantSystem: "Be brief.",
+			wantRoles:  []string{"user"},
+		},
+		{
+			name: "system prompts anywhere are joined",
+			messages: []models.Message{
+				{Role: "system", Content: models.TextContent("Be brief.")},
+				{Role: "user", Content: models.TextContent("Hi")},
+				{Role: "assistant", Content: models.TextContent("Hello")},
+				{Role: "system", Content: models.TextContent("Answer in French.")},
+				{Role: "user", Content: models.TextContent("Bye")},
+			},
+			wantSystem: "Be brief.\n\nAnswer in French.",
+			wantRoles:  []string{"user", "assistant", "user"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := p.convertToAnthropicRequest(models.ChatRequest{Model: "claude-sonnet-4", Messages: test.messages})
+			if system := req["system"]; system != test.wantSystem {
+				t.Errorf("system = %#v, want %#v", system, test.wantSystem)
+			}
+			messages := req["messages"].([]map[string]interface{})
+			if len(messages) != len(test.wantRoles) {
+				t.Fatalf("messages = %v, want roles %v", messages, test.wantRoles)
+			}
+			for i, message := range messages {
+				if message["role"] != test.wantRoles[i] {
+					t.Errorf("message %d role = %v, want %s", i, message["role"], test.wantRoles[i])
+				}
+			}
+		})
+	}
+}
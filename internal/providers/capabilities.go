@@ -0,0 +1,123 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+// Capability is a feature a request may need beyond a plain chat completion.
+type Capability string
+
+// Capabilities providers declare by implementing the capability interfaces.
+const (
+	CapabilityStreaming  Capability = "streaming"
+	CapabilityEmbeddings Capability = "embeddings"
+	CapabilityVision     Capability = "vision"
+	CapabilityTools      Capability = "tools"
+)
+
+// StreamingProvider is implemented by providers that stream chat completions.
+type StreamingProvider interface {
+	// CreateChatCompletionStream creates a streaming chat completion.
+	CreateChatCompletionStream(ctx context.Context, req models.ChatRequest) (<-chan models.StreamResponse, error)
+}
+
+// EmbeddingsProvider is implemented by providers with an embeddings API.
+type EmbeddingsProvider interface {
+	// CreateEmbedding creates embeddings for the request inputs.
+	CreateEmbedding(ctx context.Context, req models.EmbeddingRequest) (*models.EmbeddingResponse, error)
+}
+
+// VisionProvider is implemented by providers that accept image content for
+// some of their models.
+type VisionProvider interface {
+	// SupportsVision reports whether model accepts image content.
+	SupportsVision(model string) bool
+}
+
+// ToolsProvider is implemented by providers that accept tool definitions for
+// some of their models.
+type ToolsProvider interface {
+	// SupportsTools reports whether model accepts tool definitions.
+	SupportsTools(model string) bool
+}
+
+// Errors returned for requests needing a capability the provider lacks.
+var (
+	ErrStreamingNotSupported  = errors.New("streaming is not supported by this provider")
+	ErrEmbeddingsNotSupported = errors.New("embeddings are not supported by this provider")
+)
+
+// Supports reports whether provider has capability for model.
+func Supports(provider Provider, capability Capability, model string) bool {
+	switch capability {
+	case CapabilityStreaming:
+		_, ok := provider.(StreamingProvider)
+		return ok
+	case CapabilityEmbeddings:
+		_, ok := provider.(EmbeddingsProvider)
+		return ok
+	case CapabilityVision:
+		vision, ok := provider.(VisionProvider)
+		return ok && vision.SupportsVision(model)
+	case CapabilityTools:
+		tools, ok := provider.(ToolsProvider)
+		return ok && tools.SupportsTools(model)
+	}
+	return false
+}
+
+// RequiredCapabilities returns the capabilities a chat request needs:
+// streaming when it streams, vision when a message has image content and
+// tools when it defines tools.
+func RequiredCapabilities(req models.ChatRequest) []Capability {
+	var required []Capability
+	if req.Stream {
+		required = append(required, CapabilityStreaming)
+	}
+	for _, msg := range req.Messages {
+		if len(msg.Content.Images()) > 0 {
+			required = append(required, CapabilityVision)
+			break
+		}
+	}
+	if len(req.Tools) > 0 {
+		required = append(required, CapabilityTools)
+	}
+	return required
+}
+
+// OpenStream opens a streaming chat completion, failing with
+// ErrStreamingNotSupported when the provider does not stream.
+func OpenStream(ctx context.Context, provider Provider, req models.ChatRequest) (<-chan models.StreamResponse, error) {
+	streamer, ok := provider.(StreamingProvider)
+	if !ok {
+		return nil, &models.ProviderError{
+			StatusCode: http.StatusNotImplemented,
+			Err:        ErrStreamingNotSupported,
+			Provider:   provider.GetName(),
+			RequestID:  req.RequestID,
+			Retryable:  false,
+		}
+	}
+	return streamer.CreateChatCompletionStream(ctx, req)
+}
+
+// Embed creates embeddings, failing with ErrEmbeddingsNotSupported when the
+// provider has no embeddings API.
+func Embed(ctx context.Context, provider Provider, req models.EmbeddingRequest) (*models.EmbeddingResponse, error) {
+	embedder, ok := provider.(EmbeddingsProvider)
+	if !ok {
+		return nil, &models.ProviderError{
+			StatusCode: http.StatusNotImplemented,
+			Err:        ErrEmbeddingsNotSupported,
+			Provider:   provider.GetName(),
+			RequestID:  req.RequestID,
+			Retryable:  false,
+		}
+	}
+	return embedder.CreateEmbedding(ctx, req)
+}
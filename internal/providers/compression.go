@@ -0,0 +1,220 @@
+package providers
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ContentDecoder returns a reader decoding a response body compressed with
+// one content encoding.
+type ContentDecoder func(body io.Reader) (io.ReadCloser, error)
+
+var (
+	contentDecodersMutex sync.RWMutex
+	contentDecoders      = make(map[string]ContentDecoder)
+)
+
+func init() {
+	RegisterContentDecoder("gzip", func(body io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(body)
+	})
+	// HTTP's deflate is zlib-wrapped deflate data
+	RegisterContentDecoder("deflate", func(body io.Reader) (io.ReadCloser, error) {
+		return zlib.NewReader(body)
+	})
+}
+
+// RegisterContentDecoder makes a content encoding usable in a provider's
+// transport.compression list, so builds can add encodings such as br or zstd
+// with their own decoder. It is meant to be called from init functions and
+// panics if the encoding is already registered or the decoder is nil.
+func RegisterContentDecoder(encoding string, decoder ContentDecoder) {
+	contentDecodersMutex.Lock()
+	defer contentDecodersMutex.Unlock()
+
+	encoding = strings.ToLower(encoding)
+	if decoder == nil {
+		panic("providers: RegisterContentDecoder decoder is nil for " + encoding)
+	}
+	if _, exists := contentDecoders[encoding]; exists {
+		panic("providers: RegisterContentDecoder called twice for " + encoding)
+	}
+	contentDecoders[encoding] = decoder
+}
+
+// contentDecoder returns the decoder registered for an encoding.
+func contentDecoder(encoding string) (ContentDecoder, bool) {
+	contentDecodersMutex.RLock()
+	defer contentDecodersMutex.RUnlock()
+
+	decoder, exists := contentDecoders[encoding]
+	return decoder, exists
+}
+
+// BandwidthStats counts the response bytes a provider sent, as received and
+// after decoding. Their difference is the bandwidth saved by compression.
+type BandwidthStats struct {
+	Responses           int64 `json:"responses"`
+	CompressedResponses int64 `json:"compressed_responses"`
+	WireBytes           int64 `json:"wire_bytes"`
+	DecodedBytes        int64 `json:"decoded_bytes"`
+}
+
+// BandwidthReporter is implemented by providers that measure the size of
+// their responses.
+type BandwidthReporter interface {
+	// GetBandwidthStats returns the response bytes received so far.
+	GetBandwidthStats() BandwidthStats
+}
+
+// bandwidthCounter accumulates BandwidthStats.
+type bandwidthCounter struct {
+	responses           int64
+	compressedResponses int64
+	wireBytes           int64
+	decodedBytes        int64
+}
+
+// stats returns the counts so far.
+func (c *bandwidthCounter) stats() BandwidthStats {
+	return BandwidthStats{
+		Responses:           atomic.LoadInt64(&c.responses),
+		CompressedResponses: atomic.LoadInt64(&c.compressedResponses),
+		WireBytes:           atomic.LoadInt64(&c.wireBytes),
+		DecodedBytes:        atomic.LoadInt64(&c.decodedBytes),
+	}
+}
+
+// defaultCompression is offered when a provider configures no encodings,
+// matching what net/http would negotiate on its own.
+var defaultCompression = []string{"gzip"}
+
+// validateCompression checks that every configured encoding has a decoder.
+// identity asks for uncompressed responses.
+func validateCompression(encodings []string) error {
+	for _, encoding := range encodings {
+		if strings.EqualFold(encoding, "identity") {
+			continue
+		}
+		if _, exists := contentDecoder(strings.ToLower(encoding)); !exists {
+			return fmt.Errorf("unsupported compression %q", encoding)
+		}
+	}
+	return nil
+}
+
+// compressionTransport offers the configured encodings in Accept-Encoding,
+// decodes compressed responses before hooks and providers see them, and
+// counts response bytes on the wire and after decoding.
+type compressionTransport struct {
+	base           http.RoundTripper
+	acceptEncoding string
+	counter        *bandwidthCounter
+}
+
+// newCompressionTransport wraps base, which must have net/http's own
+// compression handling disabled. With no encodings, gzip is offered.
+func newCompressionTransport(encodings []string, counter *bandwidthCounter, base http.RoundTripper) (http.RoundTripper, error) {
+	if len(encodings) == 0 {
+		encodings = defaultCompression
+	}
+	if err := validateCompression(encodings); err != nil {
+		return nil, err
+	}
+	return &compressionTransport{
+		base:           base,
+		acceptEncoding: strings.ToLower(strings.Join(encodings, ", ")),
+		counter:        counter,
+	}, nil
+}
+
+// RoundTrip negotiates the encoding and wraps the response body to decode
+// and count it.
+func (t *compressionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept-Encoding", t.acceptEncoding)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt64(&t.counter.responses, 1)
+
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" {
+		resp.Body = &countingBody{ReadCloser: resp.Body, wire: &t.counter.wireBytes, decoded: &t.counter.decodedBytes}
+		return resp, nil
+	}
+
+	decoder, exists := contentDecoder(encoding)
+	if !exists {
+		// Only possible when the request set Accept-Encoding itself
+		return resp, nil
+	}
+	atomic.AddInt64(&t.counter.compressedResponses, 1)
+
+	wire := &countingBody{ReadCloser: resp.Body, wire: &t.counter.wireBytes}
+	resp.Body = &decodingBody{wire: wire, decoder: decoder, decoded: &t.counter.decodedBytes}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// countingBody counts the bytes read from a body into wire and, if set,
+// decoded.
+type countingBody struct {
+	io.ReadCloser
+	wire    *int64
+	decoded *int64
+}
+
+// Read reads from the body, counting the bytes.
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	atomic.AddInt64(b.wire, int64(n))
+	if b.decoded != nil {
+		atomic.AddInt64(b.decoded, int64(n))
+	}
+	return n, err
+}
+
+// decodingBody decodes a compressed body, creating the decoder on the first
+// read so a body closed unread costs nothing.
+type decodingBody struct {
+	wire    *countingBody
+	decoder ContentDecoder
+	reader  io.ReadCloser
+	err     error
+	decoded *int64
+}
+
+// Read reads decoded bytes, counting them.
+func (b *decodingBody) Read(p []byte) (int, error) {
+	if b.reader == nil && b.err == nil {
+		b.reader, b.err = b.decoder(b.wire)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	n, err := b.reader.Read(p)
+	atomic.AddInt64(b.decoded, int64(n))
+	return n, err
+}
+
+// Close closes the decoder and the underlying body.
+func (b *decodingBody) Close() error {
+	if b.reader != nil {
+		b.reader.Close()
+	}
+	return b.wire.Close()
+}
@@ -0,0 +1,131 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+// ConcurrencyStats describes a provider's in-flight request limit and usage.
+type ConcurrencyStats struct {
+	Limit    int `json:"limit"` // 0 means unlimited
+	InFlight int `json:"in_flight"`
+	Queued   int `json:"queued"`
+}
+
+// ConcurrencyReporter is implemented by providers that track concurrent
+// requests. In-flight requests are counted whether or not there is a limit.
+type ConcurrencyReporter interface {
+	// GetConcurrencyStats returns the current in-flight and queued request counts.
+	GetConcurrencyStats() ConcurrencyStats
+}
+
+// concurrencyLimiter counts the in-flight requests to a provider and, when it
+// has a limit, bounds them. Requests beyond the limit wait for a free slot
+// until their context is done or the queue timeout passes.
+type concurrencyLimiter struct {
+	slots        chan struct{} // nil when unlimited
+	queueTimeout time.Duration
+	inFlight     int64
+	queued       int64
+}
+
+// newConcurrencyLimiter creates a limiter. A limit that is not positive only
+// counts requests.
+func newConcurrencyLimiter(limit int, queueTimeout time.Duration) *concurrencyLimiter {
+	if limit <= 0 {
+		return &concurrencyLimiter{}
+	}
+
+	return &concurrencyLimiter{
+		slots:        make(chan struct{}, limit),
+		queueTimeout: queueTimeout,
+	}
+}
+
+// acquire waits for a free slot. It is a no-op on a nil limiter.
+func (l *concurrencyLimiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	if l.slots == nil {
+		atomic.AddInt64(&l.inFlight, 1)
+		return nil
+	}
+
+	// Fast path when a slot is free
+	select {
+	case l.slots <- struct{}{}:
+		atomic.AddInt64(&l.inFlight, 1)
+		return nil
+	default:
+	}
+
+	if l.queueTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.queueTimeout)
+		defer cancel()
+	}
+
+	atomic.AddInt64(&l.queued, 1)
+	defer atomic.AddInt64(&l.queued, -1)
+
+	select {
+	case l.slots <- struct{}{}:
+		atomic.AddInt64(&l.inFlight, 1)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a slot taken by acquire. It is a no-op on a nil limiter.
+func (l *concurrencyLimiter) release() {
+	if l == nil {
+		return
+	}
+
+	atomic.AddInt64(&l.inFlight, -1)
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// stats returns the limiter's current usage.
+func (l *concurrencyLimiter) stats() ConcurrencyStats {
+	if l == nil {
+		return ConcurrencyStats{}
+	}
+
+	return ConcurrencyStats{
+		Limit:    cap(l.slots),
+		InFlight: int(atomic.LoadInt64(&l.inFlight)),
+		Queued:   int(atomic.LoadInt64(&l.queued)),
+	}
+}
+
+// GetConcurrencyStats returns the provider's in-flight and queued request counts.
+func (p *BaseProvider) GetConcurrencyStats() ConcurrencyStats {
+	return p.limiter.stats()
+}
+
+// acquireSlot waits for a free concurrency slot for a request and counts the
+// request against the provider's quotas. The returned function must be called
+// to release the slot once the request completes.
+func (p *BaseProvider) acquireSlot(ctx context.Context, requestID string) (func(), error) {
+	if err := p.limiter.acquire(ctx); err != nil {
+		return nil, &models.ProviderError{
+			StatusCode: 503,
+			Err:        fmt.Errorf("no free concurrency slot (limit %d): %w", p.config.MaxConcurrent, err),
+			Provider:   p.GetName(),
+			RequestID:  requestID,
+			Retryable:  true,
+		}
+	}
+	p.quotas.consume(time.Now())
+
+	return p.limiter.release, nil
+}
@@ -0,0 +1,159 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Cloud credential types for providers reached through a cloud platform
+// rather than with a static API key.
+const (
+	CredentialsAWS   = "aws"
+	CredentialsGCP   = "gcp"
+	CredentialsAzure = "azure"
+)
+
+const (
+	// credentialsRefreshMargin refreshes cloud credentials this long before they expire.
+	credentialsRefreshMargin = 5 * time.Minute
+
+	// credentialsTimeout bounds each call to a token or metadata endpoint.
+	credentialsTimeout = 10 * time.Second
+)
+
+// CredentialsConfig selects cloud-native credentials for a provider. When set,
+// they replace the provider's API key header on every outbound request.
+type CredentialsConfig struct {
+	Type string `mapstructure:"type"` // aws, gcp or azure; empty uses api_key
+
+	// AWS SigV4 signing; credentials come from the default chain
+	Region  string `mapstructure:"region"`  // defaults to AWS_REGION
+	Service string `mapstructure:"service"` // signing name, default "bedrock"
+	Profile string `mapstructure:"profile"` // shared credentials profile, defaults to AWS_PROFILE
+
+	// GCP application default credentials
+	Scopes []string `mapstructure:"scopes"` // default cloud-platform
+
+	// Azure AAD tokens; ClientID selects a user-assigned managed identity
+	Resource string `mapstructure:"resource"` // default https://cognitiveservices.azure.com
+	ClientID string `mapstructure:"client_id"`
+}
+
+// cloudToken is a credential with its expiry. A zero Expiry never expires.
+type cloudToken struct {
+	Value  string
+	Expiry time.Time
+}
+
+// valid reports whether the token can still be used without refreshing.
+func (t cloudToken) valid() bool {
+	if t.Value == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(credentialsRefreshMargin).Before(t.Expiry)
+}
+
+// tokenSource fetches a fresh bearer token.
+type tokenSource interface {
+	token(ctx context.Context) (cloudToken, error)
+}
+
+// cachedTokenSource returns the last token until it is close to expiry.
+type cachedTokenSource struct {
+	source tokenSource
+	mutex  sync.Mutex
+	cached cloudToken
+}
+
+// token returns the cached token, refreshing it when needed.
+func (c *cachedTokenSource) token(ctx context.Context) (cloudToken, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.cached.valid() {
+		return c.cached, nil
+	}
+	token, err := c.source.token(ctx)
+	if err != nil {
+		return cloudToken{}, err
+	}
+	c.cached = token
+	return token, nil
+}
+
+// invalidate discards the cached token, so the next request fetches a new one.
+func (c *cachedTokenSource) invalidate() {
+	c.mutex.Lock()
+	c.cached = cloudToken{}
+	c.mutex.Unlock()
+}
+
+// bearerTransport sets an OAuth bearer token on each request. A 401 response
+// discards the token so a revoked or rotated one is replaced on the next request.
+type bearerTransport struct {
+	base   http.RoundTripper
+	tokens *cachedTokenSource
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.tokens.token(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain credentials: %w", err)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token.Value)
+
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		t.tokens.invalidate()
+	}
+	return resp, err
+}
+
+// NewCredentialsTransport wraps base so requests carry the configured cloud
+// credentials. Token endpoints are called through base, so they honour the
+// provider's proxy and TLS settings; instance metadata endpoints are not.
+func NewCredentialsTransport(config CredentialsConfig, base http.RoundTripper) (http.RoundTripper, error) {
+	client := &http.Client{Timeout: credentialsTimeout, Transport: base}
+
+	switch config.Type {
+	case "":
+		return base, nil
+	case CredentialsAWS:
+		return newAWSTransport(config, base, client)
+	case CredentialsGCP:
+		source, err := newGCPTokenSource(config, client)
+		if err != nil {
+			return nil, err
+		}
+		return &bearerTransport{base: base, tokens: &cachedTokenSource{source: source}}, nil
+	case CredentialsAzure:
+		source, err := newAzureTokenSource(config, client)
+		if err != nil {
+			return nil, err
+		}
+		return &bearerTransport{base: base, tokens: &cachedTokenSource{source: source}}, nil
+	default:
+		return nil, fmt.Errorf("unknown credentials type %q", config.Type)
+	}
+}
+
+// newMetadataClient returns a client for link-local instance metadata
+// endpoints, which must never be reached through an egress proxy.
+func newMetadataClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	return &http.Client{Timeout: credentialsTimeout, Transport: transport}
+}
+
+// fetchToken sends a token request and decodes the JSON response into out.
+func fetchToken(client *http.Client, req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	_, err := doRequest(client, req, out)
+	return err
+}
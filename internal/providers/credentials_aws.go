@@ -0,0 +1,515 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultAWSService is the SigV4 signing name used when none is configured.
+	defaultAWSService = "bedrock"
+
+	// awsIMDSEndpoint is the EC2 instance metadata service.
+	awsIMDSEndpoint = "http://169.254.169.254"
+
+	// awsECSEndpoint is the ECS task metadata endpoint for relative credential URIs.
+	awsECSEndpoint = "http://169.254.170.2"
+
+	awsTimeFormat = "20060102T150405Z"
+	awsDateFormat = "20060102"
+)
+
+// awsCredentials is an access key pair with an optional session token.
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiry          time.Time
+}
+
+// valid reports whether the credentials can still be used without refreshing.
+func (c awsCredentials) valid() bool {
+	return cloudToken{Value: c.AccessKeyID, Expiry: c.Expiry}.valid()
+}
+
+// awsCredentialSource fetches AWS credentials.
+type awsCredentialSource func(ctx context.Context) (awsCredentials, error)
+
+// awsTransport signs each request with AWS Signature Version 4.
+type awsTransport struct {
+	base    http.RoundTripper
+	region  string
+	service string
+	source  awsCredentialSource
+
+	mutex  sync.Mutex
+	cached awsCredentials
+}
+
+// newAWSTransport resolves the region and the credential chain for SigV4 signing.
+func newAWSTransport(config CredentialsConfig, base http.RoundTripper, client *http.Client) (*awsTransport, error) {
+	region := firstNonEmpty(config.Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))
+	if region == "" {
+		return nil, errors.New("aws credentials need a region (credentials.region or AWS_REGION)")
+	}
+	service := config.Service
+	if service == "" {
+		service = defaultAWSService
+	}
+
+	return &awsTransport{
+		base:    base,
+		region:  region,
+		service: service,
+		source:  awsDefaultChain(config.Profile, region, client),
+	}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *awsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	creds, err := t.credentials(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain credentials: %w", err)
+	}
+
+	req = req.Clone(req.Context())
+	payload, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	signAWSRequest(req, payload, creds, t.region, t.service, time.Now())
+
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusForbidden {
+		t.invalidate()
+	}
+	return resp, err
+}
+
+// credentials returns the cached credentials, refreshing them when needed.
+func (t *awsTransport) credentials(ctx context.Context) (awsCredentials, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.cached.valid() {
+		return t.cached, nil
+	}
+	creds, err := t.source(ctx)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	t.cached = creds
+	return creds, nil
+}
+
+// invalidate discards the cached credentials.
+func (t *awsTransport) invalidate() {
+	t.mutex.Lock()
+	t.cached = awsCredentials{}
+	t.mutex.Unlock()
+}
+
+// readBody returns the request body and replaces it with a fresh reader, so
+// the payload can be hashed and still sent.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	payload, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(payload))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(payload)), nil
+	}
+	return payload, nil
+}
+
+// signAWSRequest adds the SigV4 Authorization header. Host, Content-Type and
+// all X-Amz-* headers are signed; any existing Authorization header, such as
+// an empty bearer key, is replaced.
+func signAWSRequest(req *http.Request, payload []byte, creds awsCredentials, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format(awsTimeFormat)
+	date := now.Format(awsDateFormat)
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Del("Authorization")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.Join(values, ",")
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsCanonicalPath(req.URL, service),
+		awsCanonicalQuery(req.URL),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// awsCanonicalPath encodes each segment of the already escaped path once
+// more, as SigV4 requires for every service except S3. S3 object keys are
+// encoded once, so S3 requests must send their path in the same encoding.
+func awsCanonicalPath(u *url.URL, service string) string {
+	path := u.EscapedPath()
+	if service == "s3" {
+		path = u.Path
+	}
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = awsEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// awsCanonicalQuery returns the query string sorted by key and value.
+func awsCanonicalQuery(u *url.URL) string {
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, awsEscape(key)+"="+awsEscape(value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// awsEscape percent-encodes everything except RFC 3986 unreserved characters.
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsDefaultChain returns the standard AWS credential chain: environment
+// variables, web identity (EKS service accounts), the shared credentials
+// file, the ECS container endpoint and finally EC2 instance metadata. The
+// chain is resolved on each refresh, so rotated credentials are picked up.
+func awsDefaultChain(profile, region string, client *http.Client) awsCredentialSource {
+	metadata := newMetadataClient()
+
+	return func(ctx context.Context) (awsCredentials, error) {
+		if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+			return awsCredentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, nil
+		}
+
+		if tokenFile, role := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"), os.Getenv("AWS_ROLE_ARN"); tokenFile != "" && role != "" {
+			return awsWebIdentityCredentials(ctx, client, region, tokenFile, role)
+		}
+
+		if creds, found, err := awsSharedCredentials(profile); err != nil || found {
+			return creds, err
+		}
+
+		if os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "" || os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "" {
+			return awsContainerCredentials(ctx, metadata)
+		}
+
+		creds, err := awsInstanceCredentials(ctx, metadata)
+		if err != nil {
+			return awsCredentials{}, fmt.Errorf("no AWS credentials found in the environment, shared credentials file or instance metadata: %w", err)
+		}
+		return creds, nil
+	}
+}
+
+// awsSharedCredentials reads static keys for the profile from the shared
+// credentials file. found is false when the file or profile does not exist.
+func awsSharedCredentials(profile string) (awsCredentials, bool, error) {
+	if profile == "" {
+		profile = firstNonEmpty(os.Getenv("AWS_PROFILE"), "default")
+	}
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return awsCredentials{}, false, nil
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return awsCredentials{}, false, nil
+	}
+	if err != nil {
+		return awsCredentials{}, false, fmt.Errorf("failed to read AWS shared credentials: %w", err)
+	}
+	defer file.Close()
+
+	var creds awsCredentials
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if section != profile {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "aws_access_key_id":
+			creds.AccessKeyID = strings.TrimSpace(value)
+		case "aws_secret_access_key":
+			creds.SecretAccessKey = strings.TrimSpace(value)
+		case "aws_session_token":
+			creds.SessionToken = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return awsCredentials{}, false, fmt.Errorf("failed to read AWS shared credentials: %w", err)
+	}
+
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return awsCredentials{}, false, nil
+	}
+	return creds, true, nil
+}
+
+// awsTemporaryCredentials is the JSON shape returned by the ECS and EC2
+// metadata credential endpoints.
+type awsTemporaryCredentials struct {
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	Token           string    `json:"Token"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+func (c awsTemporaryCredentials) credentials() awsCredentials {
+	return awsCredentials{
+		AccessKeyID:     c.AccessKeyID,
+		SecretAccessKey: c.SecretAccessKey,
+		SessionToken:    c.Token,
+		Expiry:          c.Expiration,
+	}
+}
+
+// awsContainerCredentials fetches task role credentials from the ECS endpoint.
+func awsContainerCredentials(ctx context.Context, client *http.Client) (awsCredentials, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if relative := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); relative != "" {
+		endpoint = awsECSEndpoint + relative
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	authorization := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	if path := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); path != "" {
+		token, err := os.ReadFile(path)
+		if err != nil {
+			return awsCredentials{}, fmt.Errorf("failed to read container authorization token: %w", err)
+		}
+		authorization = strings.TrimSpace(string(token))
+	}
+	if authorization != "" {
+		httpReq.Header.Set("Authorization", authorization)
+	}
+
+	var resp awsTemporaryCredentials
+	if err := fetchToken(client, httpReq, &resp); err != nil {
+		return awsCredentials{}, fmt.Errorf("container credentials request failed: %w", err)
+	}
+	return resp.credentials(), nil
+}
+
+// awsInstanceCredentials fetches instance profile credentials using IMDSv2.
+func awsInstanceCredentials(ctx context.Context, client *http.Client) (awsCredentials, error) {
+	tokenReq, err := http.NewRequestWithContext(ctx, http.MethodPut, awsIMDSEndpoint+"/latest/api/token", nil)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	tokenReq.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	token, _, err := doRawRequest(client, tokenReq)
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("instance metadata token request failed: %w", err)
+	}
+
+	metadataGet := func(path string) ([]byte, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, awsIMDSEndpoint+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("X-aws-ec2-metadata-token", string(token))
+		body, _, err := doRawRequest(client, httpReq)
+		return body, err
+	}
+
+	const credentialsPath = "/latest/meta-data/iam/security-credentials/"
+	roles, err := metadataGet(credentialsPath)
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("instance profile lookup failed: %w", err)
+	}
+	role := strings.TrimSpace(strings.SplitN(string(roles), "\n", 2)[0])
+	if role == "" {
+		return awsCredentials{}, errors.New("no instance profile attached")
+	}
+
+	body, err := metadataGet(credentialsPath + role)
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("instance profile credentials request failed: %w", err)
+	}
+	var resp awsTemporaryCredentials
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return awsCredentials{}, fmt.Errorf("failed to decode instance profile credentials: %w", err)
+	}
+	return resp.credentials(), nil
+}
+
+// awsWebIdentityResponse is the STS AssumeRoleWithWebIdentity response.
+type awsWebIdentityResponse struct {
+	Credentials struct {
+		AccessKeyID     string    `xml:"AccessKeyId"`
+		SecretAccessKey string    `xml:"SecretAccessKey"`
+		SessionToken    string    `xml:"SessionToken"`
+		Expiration      time.Time `xml:"Expiration"`
+	} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+}
+
+// awsWebIdentityCredentials exchanges a projected service account token for
+// role credentials. The call is unsigned; the token is the proof of identity.
+func awsWebIdentityCredentials(ctx context.Context, client *http.Client, region, tokenFile, role string) (awsCredentials, error) {
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("failed to read web identity token: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set("Action", "AssumeRoleWithWebIdentity")
+	form.Set("Version", "2011-06-15")
+	form.Set("RoleArn", role)
+	form.Set("RoleSessionName", firstNonEmpty(os.Getenv("AWS_ROLE_SESSION_NAME"), fmt.Sprintf("semaroute-%d", time.Now().Unix())))
+	form.Set("WebIdentityToken", strings.TrimSpace(string(token)))
+
+	endpoint := fmt.Sprintf("https://sts.%s.amazonaws.com/", region)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	body, _, err := doRawRequest(client, httpReq)
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("web identity exchange failed: %w", err)
+	}
+	var resp awsWebIdentityResponse
+	if err := xml.Unmarshal(body, &resp); err != nil {
+		return awsCredentials{}, fmt.Errorf("failed to decode web identity credentials: %w", err)
+	}
+	return awsCredentials{
+		AccessKeyID:     resp.Credentials.AccessKeyID,
+		SecretAccessKey: resp.Credentials.SecretAccessKey,
+		SessionToken:    resp.Credentials.SessionToken,
+		Expiry:          resp.Credentials.Expiration,
+	}, nil
+}
+
+// firstNonEmpty returns the first non-empty value.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,142 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultAzureResource is the AAD resource for Azure OpenAI and other Cognitive Services.
+	defaultAzureResource = "https://cognitiveservices.azure.com"
+
+	// defaultAzureAuthorityHost is the public cloud AAD endpoint.
+	defaultAzureAuthorityHost = "https://login.microsoftonline.com"
+
+	// azureIMDSTokenURL is the managed identity endpoint of Azure VMs and AKS nodes.
+	azureIMDSTokenURL = "http://169.254.169.254/metadata/identity/oauth2/token"
+)
+
+// azureTokenResponse is the response body of the AAD and managed identity token
+// endpoints. Managed identity endpoints return the numbers as strings.
+type azureTokenResponse struct {
+	AccessToken string      `json:"access_token"`
+	ExpiresIn   json.Number `json:"expires_in"`
+	ExpiresOn   json.Number `json:"expires_on"`
+}
+
+// expiry returns when the token expires.
+func (r azureTokenResponse) expiry() time.Time {
+	if on, err := r.ExpiresOn.Int64(); err == nil && on > 0 {
+		return time.Unix(on, 0)
+	}
+	in, _ := r.ExpiresIn.Int64()
+	return expiresIn(in)
+}
+
+// azureTokenSource fetches AAD access tokens.
+type azureTokenSource struct {
+	client   *http.Client
+	resource string
+	clientID string
+
+	// Service principal or workload identity, from the AZURE_* environment
+	tenantID     string
+	clientSecret string
+	tokenFile    string
+	authority    string
+
+	// App Service and Container Apps managed identity
+	identityEndpoint string
+	identityHeader   string
+}
+
+// newAzureTokenSource picks the first available credential: a service
+// principal secret or a federated workload identity token from AZURE_*
+// environment variables, the App Service managed identity endpoint, and
+// otherwise the instance metadata service managed identity.
+func newAzureTokenSource(config CredentialsConfig, client *http.Client) (*azureTokenSource, error) {
+	resource := config.Resource
+	if resource == "" {
+		resource = defaultAzureResource
+	}
+	source := &azureTokenSource{
+		client:    client,
+		resource:  strings.TrimSuffix(resource, "/"),
+		clientID:  firstNonEmpty(config.ClientID, os.Getenv("AZURE_CLIENT_ID")),
+		tenantID:  os.Getenv("AZURE_TENANT_ID"),
+		authority: strings.TrimSuffix(firstNonEmpty(os.Getenv("AZURE_AUTHORITY_HOST"), defaultAzureAuthorityHost), "/"),
+	}
+
+	if source.tenantID != "" && source.clientID != "" {
+		source.clientSecret = os.Getenv("AZURE_CLIENT_SECRET")
+		source.tokenFile = os.Getenv("AZURE_FEDERATED_TOKEN_FILE")
+		if source.clientSecret != "" || source.tokenFile != "" {
+			return source, nil
+		}
+	}
+
+	source.tenantID = ""
+	source.client = newMetadataClient()
+	source.identityEndpoint = os.Getenv("IDENTITY_ENDPOINT")
+	source.identityHeader = os.Getenv("IDENTITY_HEADER")
+	return source, nil
+}
+
+// token implements tokenSource.
+func (s *azureTokenSource) token(ctx context.Context) (cloudToken, error) {
+	var httpReq *http.Request
+	var err error
+
+	switch {
+	case s.tenantID != "":
+		form := url.Values{
+			"grant_type": {"client_credentials"},
+			"client_id":  {s.clientID},
+			"scope":      {s.resource + "/.default"},
+		}
+		if s.clientSecret != "" {
+			form.Set("client_secret", s.clientSecret)
+		} else {
+			assertion, readErr := os.ReadFile(s.tokenFile)
+			if readErr != nil {
+				return cloudToken{}, fmt.Errorf("failed to read federated token: %w", readErr)
+			}
+			form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+			form.Set("client_assertion", strings.TrimSpace(string(assertion)))
+		}
+		httpReq, err = newFormRequest(ctx, s.authority+"/"+s.tenantID+"/oauth2/v2.0/token", form)
+	case s.identityEndpoint != "":
+		query := url.Values{"api-version": {"2019-08-01"}, "resource": {s.resource}}
+		if s.clientID != "" {
+			query.Set("client_id", s.clientID)
+		}
+		httpReq, err = http.NewRequestWithContext(ctx, http.MethodGet, s.identityEndpoint+"?"+query.Encode(), nil)
+		if err == nil {
+			httpReq.Header.Set("X-IDENTITY-HEADER", s.identityHeader)
+		}
+	default:
+		query := url.Values{"api-version": {"2018-02-01"}, "resource": {s.resource}}
+		if s.clientID != "" {
+			query.Set("client_id", s.clientID)
+		}
+		httpReq, err = http.NewRequestWithContext(ctx, http.MethodGet, azureIMDSTokenURL+"?"+query.Encode(), nil)
+		if err == nil {
+			httpReq.Header.Set("Metadata", "true")
+		}
+	}
+	if err != nil {
+		return cloudToken{}, err
+	}
+
+	var resp azureTokenResponse
+	if err := fetchToken(s.client, httpReq, &resp); err != nil {
+		return cloudToken{}, fmt.Errorf("Azure token request failed: %w", err)
+	}
+	return cloudToken{Value: resp.AccessToken, Expiry: resp.expiry()}, nil
+}
@@ -0,0 +1,223 @@
+package providers
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// gcpCloudPlatformScope is the default OAuth scope, covering Vertex AI.
+	gcpCloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+	// gcpTokenURL is the OAuth token endpoint for user and service account credentials.
+	gcpTokenURL = "https://oauth2.googleapis.com/token"
+
+	// gcpMetadataTokenURL is the token endpoint of the GCE metadata server.
+	gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// gcpCredentialsFile is the subset of an application default credentials file that is used.
+type gcpCredentialsFile struct {
+	Type string `json:"type"`
+
+	// service_account
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+
+	// authorized_user, as written by gcloud auth application-default login
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// gcpTokenResponse is the response body of the OAuth and metadata token endpoints.
+type gcpTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// gcpTokenSource fetches OAuth access tokens using application default credentials.
+type gcpTokenSource struct {
+	client *http.Client
+	scopes []string
+	file   *gcpCredentialsFile
+	key    *rsa.PrivateKey
+}
+
+// newGCPTokenSource resolves application default credentials: the file named
+// by GOOGLE_APPLICATION_CREDENTIALS, then the gcloud well-known file, then the
+// metadata server of the GCE, GKE or Cloud Run instance.
+func newGCPTokenSource(config CredentialsConfig, client *http.Client) (*gcpTokenSource, error) {
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{gcpCloudPlatformScope}
+	}
+	source := &gcpTokenSource{client: client, scopes: scopes}
+
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		if configDir, err := os.UserConfigDir(); err == nil {
+			wellKnown := filepath.Join(configDir, "gcloud", "application_default_credentials.json")
+			if _, err := os.Stat(wellKnown); err == nil {
+				path = wellKnown
+			}
+		}
+	}
+	if path == "" {
+		source.client = newMetadataClient()
+		return source, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GCP credentials: %w", err)
+	}
+	var file gcpCredentialsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse GCP credentials %s: %w", path, err)
+	}
+
+	switch file.Type {
+	case "service_account":
+		key, err := parseRSAPrivateKey(file.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid service account key in %s: %w", path, err)
+		}
+		source.key = key
+	case "authorized_user":
+		if file.RefreshToken == "" {
+			return nil, fmt.Errorf("GCP credentials %s have no refresh_token", path)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported GCP credentials type %q in %s", file.Type, path)
+	}
+	if file.TokenURI == "" {
+		file.TokenURI = gcpTokenURL
+	}
+	source.file = &file
+
+	return source, nil
+}
+
+// token implements tokenSource.
+func (s *gcpTokenSource) token(ctx context.Context) (cloudToken, error) {
+	var httpReq *http.Request
+	var err error
+
+	switch {
+	case s.file == nil:
+		query := url.Values{}
+		query.Set("scopes", strings.Join(s.scopes, ","))
+		httpReq, err = http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataTokenURL+"?"+query.Encode(), nil)
+		if err == nil {
+			httpReq.Header.Set("Metadata-Flavor", "Google")
+		}
+	case s.key != nil:
+		assertion, signErr := s.signAssertion(time.Now())
+		if signErr != nil {
+			return cloudToken{}, signErr
+		}
+		httpReq, err = newFormRequest(ctx, s.file.TokenURI, url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		})
+	default:
+		httpReq, err = newFormRequest(ctx, s.file.TokenURI, url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {s.file.ClientID},
+			"client_secret": {s.file.ClientSecret},
+			"refresh_token": {s.file.RefreshToken},
+		})
+	}
+	if err != nil {
+		return cloudToken{}, err
+	}
+
+	var resp gcpTokenResponse
+	if err := fetchToken(s.client, httpReq, &resp); err != nil {
+		return cloudToken{}, fmt.Errorf("GCP token request failed: %w", err)
+	}
+	return cloudToken{Value: resp.AccessToken, Expiry: expiresIn(resp.ExpiresIn)}, nil
+}
+
+// signAssertion builds the RS256-signed JWT a service account exchanges for an access token.
+func (s *gcpTokenSource) signAssertion(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": s.file.PrivateKeyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   s.file.ClientEmail,
+		"scope": strings.Join(s.scopes, " "),
+		"aud":   s.file.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign service account assertion: %w", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parseRSAPrivateKey parses a PEM-encoded PKCS#8 or PKCS#1 RSA private key.
+func parseRSAPrivateKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not RSA")
+	}
+	return key, nil
+}
+
+// newFormRequest builds a form-encoded POST request.
+func newFormRequest(ctx context.Context, endpoint string, form url.Values) (*http.Request, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return httpReq, nil
+}
+
+// expiresIn converts an expires_in value in seconds to an expiry time,
+// assuming an hour when the endpoint does not say.
+func expiresIn(seconds int64) time.Time {
+	if seconds <= 0 {
+		seconds = 3600
+	}
+	return time.Now().Add(time.Duration(seconds) * time.Second)
+}
@@ -0,0 +1,310 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultRaceDelay is how long a connection attempt gets before the next
+	// address is tried alongside it, as recommended by RFC 8305.
+	defaultRaceDelay = 250 * time.Millisecond
+
+	// defaultLookupTimeout bounds a DNS lookup.
+	defaultLookupTimeout = 5 * time.Second
+)
+
+// IP version preferences of DNSConfig.IPVersion.
+const (
+	ipVersionAny        = ""
+	ipVersionIPv4       = "ipv4"
+	ipVersionIPv6       = "ipv6"
+	ipVersionPreferIPv4 = "prefer_ipv4"
+	ipVersionPreferIPv6 = "prefer_ipv6"
+)
+
+// DNSConfig controls how a provider's host names are resolved and connected
+// to. Zero values keep net/http's behaviour.
+type DNSConfig struct {
+	Servers       []string      `mapstructure:"servers"`        // resolvers as host:port, tried in turn; empty uses the system resolver
+	TTL           time.Duration `mapstructure:"ttl"`            // reuse lookups this long; 0 looks up every connection
+	StaleTTL      time.Duration `mapstructure:"stale_ttl"`      // serve expired lookups this much longer while lookups fail
+	IPVersion     string        `mapstructure:"ip_version"`     // ipv4, ipv6, prefer_ipv4 or prefer_ipv6; empty alternates
+	RaceDelay     time.Duration `mapstructure:"race_delay"`     // head start of each connection attempt, 0 = 250ms, negative dials one address at a time
+	LookupTimeout time.Duration `mapstructure:"lookup_timeout"` // 0 = 5s
+}
+
+// enabled reports whether any DNS setting is configured.
+func (c DNSConfig) enabled() bool {
+	return len(c.Servers) > 0 || c.TTL != 0 || c.StaleTTL != 0 || c.IPVersion != "" || c.RaceDelay != 0 || c.LookupTimeout != 0
+}
+
+// validate checks the settings.
+func (c DNSConfig) validate() error {
+	switch c.IPVersion {
+	case ipVersionAny, ipVersionIPv4, ipVersionIPv6, ipVersionPreferIPv4, ipVersionPreferIPv6:
+	default:
+		return fmt.Errorf("dns ip_version must be ipv4, ipv6, prefer_ipv4 or prefer_ipv6, got %q", c.IPVersion)
+	}
+	for _, server := range c.Servers {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			return fmt.Errorf("dns server %q must be host:port: %w", server, err)
+		}
+	}
+	if c.TTL < 0 || c.StaleTTL < 0 || c.LookupTimeout < 0 {
+		return fmt.Errorf("dns ttl, stale_ttl and lookup_timeout must not be negative")
+	}
+	return nil
+}
+
+// dnsEntry is a cached lookup.
+type dnsEntry struct {
+	addrs      []net.IP
+	resolvedAt time.Time
+}
+
+// dnsDialer resolves host names with the configured resolvers and cache, and
+// races connections to the resolved addresses.
+type dnsDialer struct {
+	config   DNSConfig
+	dialer   *net.Dialer
+	resolver *net.Resolver
+	next     uint32 // next resolver to ask
+
+	mutex sync.Mutex
+	cache map[string]dnsEntry
+}
+
+// newDNSDialer creates a dialer connecting through dialer.
+func newDNSDialer(config DNSConfig, dialer *net.Dialer) (*dnsDialer, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if config.RaceDelay == 0 {
+		config.RaceDelay = defaultRaceDelay
+	}
+	if config.LookupTimeout == 0 {
+		config.LookupTimeout = defaultLookupTimeout
+	}
+
+	d := &dnsDialer{
+		config:   config,
+		dialer:   dialer,
+		resolver: net.DefaultResolver,
+		cache:    make(map[string]dnsEntry),
+	}
+	if len(config.Servers) > 0 {
+		d.resolver = &net.Resolver{PreferGo: true, Dial: d.dialResolver}
+	}
+	return d, nil
+}
+
+// dialResolver connects to the configured resolvers in turn, so retries of a
+// query go to the next one.
+func (d *dnsDialer) dialResolver(ctx context.Context, network, _ string) (net.Conn, error) {
+	server := d.config.Servers[int(atomic.AddUint32(&d.next, 1)-1)%len(d.config.Servers)]
+	return d.dialer.DialContext(ctx, network, server)
+}
+
+// DialContext resolves the host of address and connects to one of its
+// addresses. Resolution failures are reported as DNS errors, so they are not
+// mistaken for the provider being down.
+func (d *dnsDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return d.dialer.DialContext(ctx, network, address)
+	}
+
+	addrs, err := d.lookup(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("dns resolution of %s failed: %w", host, err)
+	}
+	return d.race(ctx, network, addrs, port)
+}
+
+// lookup returns the addresses of host in dialing order, from the cache while
+// it is fresh. When a lookup fails, an expired entry is still served within
+// the stale TTL.
+func (d *dnsDialer) lookup(ctx context.Context, host string) ([]net.IP, error) {
+	now := time.Now()
+	d.mutex.Lock()
+	entry, cached := d.cache[host]
+	d.mutex.Unlock()
+	if cached && now.Sub(entry.resolvedAt) < d.config.TTL {
+		return entry.addrs, nil
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, d.config.LookupTimeout)
+	defer cancel()
+	addrs, err := d.resolver.LookupIP(lookupCtx, d.lookupNetwork(), host)
+	if err == nil {
+		addrs = orderAddrs(addrs, d.config.IPVersion)
+		if len(addrs) == 0 {
+			err = fmt.Errorf("no %s addresses for %s", d.config.IPVersion, host)
+		}
+	}
+	if err != nil {
+		if cached && now.Sub(entry.resolvedAt) < d.config.TTL+d.config.StaleTTL {
+			return entry.addrs, nil
+		}
+		return nil, err
+	}
+
+	if d.config.TTL > 0 {
+		d.mutex.Lock()
+		d.cache[host] = dnsEntry{addrs: addrs, resolvedAt: now}
+		d.mutex.Unlock()
+	}
+	return addrs, nil
+}
+
+// lookupNetwork returns the address families to look up.
+func (d *dnsDialer) lookupNetwork() string {
+	switch d.config.IPVersion {
+	case ipVersionIPv4:
+		return "ip4"
+	case ipVersionIPv6:
+		return "ip6"
+	}
+	return "ip"
+}
+
+// orderAddrs orders addresses for dialing: the preferred family first, or the
+// families alternating, starting with the first address's, as RFC 8305 asks.
+func orderAddrs(addrs []net.IP, ipVersion string) []net.IP {
+	var v4, v6 []net.IP
+	for _, addr := range addrs {
+		if addr.To4() != nil {
+			v4 = append(v4, addr)
+		} else {
+			v6 = append(v6, addr)
+		}
+	}
+
+	switch ipVersion {
+	case ipVersionIPv4:
+		return v4
+	case ipVersionIPv6:
+		return v6
+	case ipVersionPreferIPv4:
+		return append(v4, v6...)
+	case ipVersionPreferIPv6:
+		return append(v6, v4...)
+	}
+
+	first, second := v4, v6
+	if len(addrs) > 0 && addrs[0].To4() == nil {
+		first, second = v6, v4
+	}
+	ordered := make([]net.IP, 0, len(addrs))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			ordered = append(ordered, first[i])
+		}
+		if i < len(second) {
+			ordered = append(ordered, second[i])
+		}
+	}
+	return ordered
+}
+
+// dialResult is the outcome of one connection attempt.
+type dialResult struct {
+	conn net.Conn
+	err  error
+}
+
+// race connects to the addresses in order, starting the next attempt when the
+// previous one fails or has had its race delay, and returns the first
+// connection made. The other attempts are cancelled and their connections
+// closed.
+func (d *dnsDialer) race(ctx context.Context, network string, addrs []net.IP, port string) (net.Conn, error) {
+	if d.config.RaceDelay < 0 {
+		var firstErr error
+		for _, addr := range addrs {
+			conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		return nil, firstErr
+	}
+
+	raceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialResult, len(addrs))
+	dial := func(addr net.IP) {
+		conn, err := d.dialer.DialContext(raceCtx, network, net.JoinHostPort(addr.String(), port))
+		results <- dialResult{conn: conn, err: err}
+	}
+
+	timer := time.NewTimer(d.config.RaceDelay)
+	defer timer.Stop()
+
+	started, pending := 0, 0
+	startNext := func() {
+		if started < len(addrs) {
+			go dial(addrs[started])
+			started++
+			pending++
+			resetTimer(timer, d.config.RaceDelay)
+		}
+	}
+
+	var firstErr error
+	startNext()
+	for pending > 0 {
+		select {
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				cancel()
+				go closeLosers(results, pending)
+				return result.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = result.err
+			}
+			// A failed attempt hands over to the next address at once
+			startNext()
+		case <-timer.C:
+			startNext()
+		case <-ctx.Done():
+			go closeLosers(results, pending)
+			return nil, ctx.Err()
+		}
+	}
+	return nil, firstErr
+}
+
+// resetTimer restarts a timer, discarding an expiry not yet received.
+func resetTimer(timer *time.Timer, delay time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(delay)
+}
+
+// closeLosers closes the connections of attempts still in flight when the
+// race was decided.
+func closeLosers(results <-chan dialResult, pending int) {
+	for ; pending > 0; pending-- {
+		if result := <-results; result.conn != nil {
+			result.conn.Close()
+		}
+	}
+}
@@ -0,0 +1,474 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// Hook intercepts the HTTP calls a provider makes, so outbound payloads and
+// headers can be changed and raw responses captured without modifying the
+// provider. Hooks see the request before cloud credentials are applied, so
+// signed requests cover the hooked payload.
+type Hook interface {
+	// Name identifies the hook in errors and logs.
+	Name() string
+
+	// BeforeRequest may modify the request headers and body. An error
+	// aborts the call.
+	BeforeRequest(ctx context.Context, req *HookRequest) error
+
+	// AfterResponse sees every response, including error statuses, and may
+	// replace its body. An error fails the call.
+	AfterResponse(ctx context.Context, req *HookRequest, resp *HookResponse) error
+
+	// OnError is called when the call fails without a response, or when
+	// another hook fails it.
+	OnError(ctx context.Context, req *HookRequest, err error)
+}
+
+// HookRequest is an outbound provider call.
+type HookRequest struct {
+	Provider string
+	Method   string
+	URL      *url.URL
+	Header   http.Header
+	Body     []byte
+}
+
+// HookResponse is a provider response. Body is nil for streamed responses,
+// which are passed through without buffering.
+type HookResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Stream     bool
+	Latency    time.Duration
+}
+
+// HookFuncs adapts functions to the Hook interface. Any function may be nil.
+type HookFuncs struct {
+	Label  string
+	Before func(ctx context.Context, req *HookRequest) error
+	After  func(ctx context.Context, req *HookRequest, resp *HookResponse) error
+	Error  func(ctx context.Context, req *HookRequest, err error)
+}
+
+// Name returns the hook label.
+func (h HookFuncs) Name() string {
+	return h.Label
+}
+
+// BeforeRequest calls Before, if set.
+func (h HookFuncs) BeforeRequest(ctx context.Context, req *HookRequest) error {
+	if h.Before == nil {
+		return nil
+	}
+	return h.Before(ctx, req)
+}
+
+// AfterResponse calls After, if set.
+func (h HookFuncs) AfterResponse(ctx context.Context, req *HookRequest, resp *HookResponse) error {
+	if h.After == nil {
+		return nil
+	}
+	return h.After(ctx, req, resp)
+}
+
+// OnError calls Error, if set.
+func (h HookFuncs) OnError(ctx context.Context, req *HookRequest, err error) {
+	if h.Error != nil {
+		h.Error(ctx, req, err)
+	}
+}
+
+// HookConfig configures a hook on a provider.
+type HookConfig struct {
+	Type   string                 `mapstructure:"type"`
+	Config map[string]interface{} `mapstructure:"config"`
+}
+
+// HookFactory creates a hook from the "config" section of its configuration.
+type HookFactory func(config map[string]interface{}) (Hook, error)
+
+var (
+	hookFactoriesMutex sync.RWMutex
+	hookFactories      = make(map[string]HookFactory)
+)
+
+func init() {
+	RegisterHook("headers", newHeadersHook)
+	RegisterHook("body_fields", newBodyFieldsHook)
+	RegisterHook("capture", newCaptureHook)
+}
+
+// RegisterHook makes a hook type selectable by name in configuration. It is
+// meant to be called from init functions and panics if the name is already
+// registered or the factory is nil.
+func RegisterHook(name string, factory HookFactory) {
+	hookFactoriesMutex.Lock()
+	defer hookFactoriesMutex.Unlock()
+
+	if factory == nil {
+		panic("providers: RegisterHook factory is nil for " + name)
+	}
+	if _, exists := hookFactories[name]; exists {
+		panic("providers: RegisterHook called twice for " + name)
+	}
+	hookFactories[name] = factory
+}
+
+// NewHook creates the hook registered under the configured type.
+func NewHook(config HookConfig) (Hook, error) {
+	hookFactoriesMutex.RLock()
+	factory, exists := hookFactories[config.Type]
+	hookFactoriesMutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("unknown provider hook %q (registered: %v)", config.Type, RegisteredHooks())
+	}
+
+	hook, err := factory(config.Config)
+	if err != nil {
+		return nil, fmt.Errorf("provider hook %s: %w", config.Type, err)
+	}
+	return hook, nil
+}
+
+// RegisteredHooks returns the registered hook types in sorted order.
+func RegisteredHooks() []string {
+	hookFactoriesMutex.RLock()
+	defer hookFactoriesMutex.RUnlock()
+
+	names := make([]string, 0, len(hookFactories))
+	for name := range hookFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// decodeHookConfig decodes a hook config map into target, rejecting unknown keys.
+func decodeHookConfig(config map[string]interface{}, target interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		ErrorUnused: true,
+		Result:      target,
+	})
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(config); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	return nil
+}
+
+// hookTransport runs hooks around each round trip. BeforeRequest hooks run in
+// order and AfterResponse hooks in reverse order, so the first hook is the
+// outermost layer.
+type hookTransport struct {
+	base     http.RoundTripper
+	provider string
+	hooks    []Hook
+}
+
+// newHookTransport wraps base with the configured hooks, or returns base when there are none.
+func newHookTransport(provider string, configs []HookConfig, base http.RoundTripper) (http.RoundTripper, error) {
+	if len(configs) == 0 {
+		return base, nil
+	}
+
+	hooks := make([]Hook, 0, len(configs))
+	for _, config := range configs {
+		hook, err := NewHook(config)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return &hookTransport{base: base, provider: provider, hooks: hooks}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *hookTransport) RoundTrip(httpReq *http.Request) (*http.Response, error) {
+	ctx := httpReq.Context()
+	httpReq = httpReq.Clone(ctx)
+
+	body, err := readBody(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	req := &HookRequest{
+		Provider: t.provider,
+		Method:   httpReq.Method,
+		URL:      httpReq.URL,
+		Header:   httpReq.Header,
+		Body:     body,
+	}
+
+	for _, hook := range t.hooks {
+		if err := hook.BeforeRequest(ctx, req); err != nil {
+			err = fmt.Errorf("hook %s: %w", hook.Name(), err)
+			t.onError(ctx, req, err)
+			return nil, err
+		}
+	}
+	setBody(httpReq, req.Body)
+
+	start := time.Now()
+	httpResp, err := t.base.RoundTrip(httpReq)
+	if err != nil {
+		t.onError(ctx, req, err)
+		return nil, err
+	}
+
+	resp := &HookResponse{
+		StatusCode: httpResp.StatusCode,
+		Header:     httpResp.Header,
+		Stream:     strings.HasPrefix(httpResp.Header.Get("Content-Type"), "text/event-stream"),
+		Latency:    time.Since(start),
+	}
+	if !resp.Stream {
+		resp.Body, err = io.ReadAll(httpResp.Body)
+		httpResp.Body.Close()
+		if err != nil {
+			t.onError(ctx, req, err)
+			return nil, err
+		}
+	}
+
+	for i := len(t.hooks) - 1; i >= 0; i-- {
+		if err := t.hooks[i].AfterResponse(ctx, req, resp); err != nil {
+			if resp.Stream {
+				httpResp.Body.Close()
+			}
+			err = fmt.Errorf("hook %s: %w", t.hooks[i].Name(), err)
+			t.onError(ctx, req, err)
+			return nil, err
+		}
+	}
+
+	if !resp.Stream {
+		httpResp.Body = io.NopCloser(bytes.NewReader(resp.Body))
+		httpResp.ContentLength = int64(len(resp.Body))
+		httpResp.Header.Del("Content-Length")
+	}
+	return httpResp, nil
+}
+
+// onError notifies every hook of a failed call.
+func (t *hookTransport) onError(ctx context.Context, req *HookRequest, err error) {
+	for _, hook := range t.hooks {
+		hook.OnError(ctx, req, err)
+	}
+}
+
+// setBody replaces a request body, keeping it replayable for retries and redirects.
+func setBody(req *http.Request, body []byte) {
+	if body == nil {
+		req.Body = nil
+		req.GetBody = nil
+		req.ContentLength = 0
+		return
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Del("Content-Length")
+}
+
+// headersHook sets and removes outbound request headers.
+type headersHook struct {
+	set    map[string]string
+	remove []string
+}
+
+func newHeadersHook(config map[string]interface{}) (Hook, error) {
+	var cfg struct {
+		Set    map[string]string `mapstructure:"set"`
+		Remove []string          `mapstructure:"remove"`
+	}
+	if err := decodeHookConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+	return &headersHook{set: cfg.Set, remove: cfg.Remove}, nil
+}
+
+func (h *headersHook) Name() string { return "headers" }
+
+// BeforeRequest applies the header changes. Unlike the provider's static
+// headers, these also override authentication headers set by the provider.
+func (h *headersHook) BeforeRequest(ctx context.Context, req *HookRequest) error {
+	for _, name := range h.remove {
+		req.Header.Del(name)
+	}
+	for name, value := range h.set {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+	return nil
+}
+
+func (h *headersHook) AfterResponse(ctx context.Context, req *HookRequest, resp *HookResponse) error {
+	return nil
+}
+
+func (h *headersHook) OnError(ctx context.Context, req *HookRequest, err error) {}
+
+// bodyFieldsHook sets or removes top-level fields of JSON request bodies,
+// such as a provider-specific option the gateway does not model.
+type bodyFieldsHook struct {
+	set    map[string]interface{}
+	remove []string
+}
+
+func newBodyFieldsHook(config map[string]interface{}) (Hook, error) {
+	var cfg struct {
+		Set    map[string]interface{} `mapstructure:"set"`
+		Remove []string               `mapstructure:"remove"`
+	}
+	if err := decodeHookConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+	return &bodyFieldsHook{set: cfg.Set, remove: cfg.Remove}, nil
+}
+
+func (h *bodyFieldsHook) Name() string { return "body_fields" }
+
+// BeforeRequest rewrites JSON object bodies; other bodies are left alone.
+func (h *bodyFieldsHook) BeforeRequest(ctx context.Context, req *HookRequest) error {
+	if len(req.Body) == 0 || !strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(req.Body, &payload); err != nil {
+		return nil
+	}
+	for _, field := range h.remove {
+		delete(payload, field)
+	}
+	for field, value := range h.set {
+		payload[field] = value
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	return nil
+}
+
+func (h *bodyFieldsHook) AfterResponse(ctx context.Context, req *HookRequest, resp *HookResponse) error {
+	return nil
+}
+
+func (h *bodyFieldsHook) OnError(ctx context.Context, req *HookRequest, err error) {}
+
+// captureHook appends raw provider exchanges to a JSON-lines file. Request
+// headers are not recorded, so credentials never reach the file.
+type captureHook struct {
+	maxBody int
+	mutex   sync.Mutex
+	file    *os.File
+}
+
+// captureRecord is one captured exchange.
+type captureRecord struct {
+	Time       time.Time   `json:"time"`
+	Provider   string      `json:"provider"`
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Request    string      `json:"request,omitempty"`
+	StatusCode int         `json:"status_code,omitempty"`
+	Header     http.Header `json:"response_header,omitempty"`
+	Response   string      `json:"response,omitempty"`
+	LatencyMs  float64     `json:"latency_ms,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+func newCaptureHook(config map[string]interface{}) (Hook, error) {
+	cfg := struct {
+		Path    string `mapstructure:"path"`
+		MaxBody int    `mapstructure:"max_body"` // bytes of each body kept, 0 keeps all
+	}{MaxBody: 64 * 1024}
+	if err := decodeHookConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+
+	file, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture file: %w", err)
+	}
+	return &captureHook{maxBody: cfg.MaxBody, file: file}, nil
+}
+
+func (h *captureHook) Name() string { return "capture" }
+
+func (h *captureHook) BeforeRequest(ctx context.Context, req *HookRequest) error {
+	return nil
+}
+
+// AfterResponse records the exchange. Streamed responses are recorded without a body.
+func (h *captureHook) AfterResponse(ctx context.Context, req *HookRequest, resp *HookResponse) error {
+	h.write(captureRecord{
+		Time:       time.Now(),
+		Provider:   req.Provider,
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		Request:    h.truncate(req.Body),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Response:   h.truncate(resp.Body),
+		LatencyMs:  float64(resp.Latency) / float64(time.Millisecond),
+	})
+	return nil
+}
+
+func (h *captureHook) OnError(ctx context.Context, req *HookRequest, err error) {
+	h.write(captureRecord{
+		Time:     time.Now(),
+		Provider: req.Provider,
+		Method:   req.Method,
+		URL:      req.URL.String(),
+		Request:  h.truncate(req.Body),
+		Error:    err.Error(),
+	})
+}
+
+// truncate returns the body as a string, cut to the configured size.
+func (h *captureHook) truncate(body []byte) string {
+	if h.maxBody > 0 && len(body) > h.maxBody {
+		return string(body[:h.maxBody]) + "...[truncated " + strconv.Itoa(len(body)-h.maxBody) + " bytes]"
+	}
+	return string(body)
+}
+
+// write appends a record; capture failures never fail the call.
+func (h *captureHook) write(record captureRecord) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.file.Write(append(line, '\n'))
+}
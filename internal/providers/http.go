@@ -0,0 +1,161 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+)
+
+// maxErrorBodySize limits how much of an error response body is kept.
+const maxErrorBodySize = 4096
+
+// HTTPStatusError is returned when a provider responds with a non-2xx status.
+type HTTPStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error implements the error interface.
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("provider returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// doJSONRequest sends a JSON request and decodes a JSON response into out.
+// It returns the response headers so callers can inspect provider metadata.
+func doJSONRequest(ctx context.Context, client *http.Client, method, url string, headers map[string]string, body, out interface{}) (http.Header, error) {
+	httpReq, err := newJSONRequest(ctx, method, url, headers, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return doRequest(client, httpReq, out)
+}
+
+// newJSONRequest builds a request with a JSON body. Headers are applied last,
+// so callers can override Accept.
+func newJSONRequest(ctx context.Context, method, url string, headers map[string]string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	for key, value := range headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	return httpReq, nil
+}
+
+// doRequest sends a prepared request and decodes a JSON response into out.
+func doRequest(client *http.Client, httpReq *http.Request, out interface{}) (http.Header, error) {
+	body, header, err := doRawRequest(client, httpReq)
+	if err != nil {
+		return header, err
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(body, out); err != nil {
+			return header, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+
+	return header, nil
+}
+
+// doRawRequest sends a prepared request and returns the response body as is,
+// for endpoints that do not answer with JSON.
+func doRawRequest(client *http.Client, httpReq *http.Request) ([]byte, http.Header, error) {
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return nil, resp.Header, &HTTPStatusError{
+			StatusCode: resp.StatusCode,
+			Body:       string(errBody),
+		}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.Header, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return body, resp.Header, nil
+}
+
+// doMultipartRequest sends a multipart/form-data POST with the given fields and
+// a single file, and returns the raw response body.
+func doMultipartRequest(ctx context.Context, client *http.Client, url string, headers, fields map[string]string, fileField, fileName string, file []byte) ([]byte, http.Header, error) {
+	var payload bytes.Buffer
+	writer := multipart.NewWriter(&payload)
+
+	for name, value := range fields {
+		if value == "" {
+			continue
+		}
+		if err := writer.WriteField(name, value); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	part, err := writer.CreateFormFile(fileField, fileName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	if _, err := part.Write(file); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
+	for key, value := range headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	return doRawRequest(client, httpReq)
+}
+
+// isRetryableStatus returns true for errors caused by rate limiting or server failures.
+func isRetryableStatus(err error) bool {
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+}
+
+// statusCodeOf returns the HTTP status carried by err, or fallback if there is none.
+func statusCodeOf(err error, fallback int) int {
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode
+	}
+	return fallback
+}
@@ -0,0 +1,178 @@
+package providers
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Key selection strategies for providers configured with several API keys.
+const (
+	KeySelectionRoundRobin = "round_robin"
+	KeySelectionWeighted   = "weighted"
+)
+
+// defaultKeyQuarantine is how long a failing key is skipped when not configured.
+const defaultKeyQuarantine = time.Minute
+
+// APIKeyConfig is one of several API keys for a provider.
+type APIKeyConfig struct {
+	Key    string `mapstructure:"key"`
+	Weight int    `mapstructure:"weight"` // relative share for weighted selection, default 1
+}
+
+// poolKey is an API key and its selection state.
+type poolKey struct {
+	value            string
+	weight           int
+	currentWeight    int
+	quarantinedUntil time.Time
+}
+
+// keyPool selects among a provider's API keys, skipping keys that recently
+// failed authentication or were rate limited.
+type keyPool struct {
+	keys       []*poolKey
+	weighted   bool
+	quarantine time.Duration
+	mutex      sync.Mutex
+}
+
+// newKeyPool builds a key pool from the single api_key and the api_keys list.
+func newKeyPool(config ProviderConfig) *keyPool {
+	pool := &keyPool{
+		weighted:   config.KeySelection == KeySelectionWeighted,
+		quarantine: config.KeyQuarantine,
+	}
+	if pool.quarantine <= 0 {
+		pool.quarantine = defaultKeyQuarantine
+	}
+
+	if config.APIKey != "" {
+		pool.keys = append(pool.keys, &poolKey{value: config.APIKey, weight: 1})
+	}
+	for _, key := range config.APIKeys {
+		if key.Key == "" {
+			continue
+		}
+		weight := key.Weight
+		if weight <= 0 || !pool.weighted {
+			weight = 1
+		}
+		pool.keys = append(pool.keys, &poolKey{value: key.Key, weight: weight})
+	}
+
+	return pool
+}
+
+// selectKey returns the next key using smooth weighted round robin over the
+// keys that are not quarantined. If every key is quarantined, the one whose
+// quarantine ends first is returned rather than failing outright.
+func (p *keyPool) selectKey() string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if len(p.keys) == 0 {
+		return ""
+	}
+
+	now := time.Now()
+	var best *poolKey
+	total := 0
+	for _, key := range p.keys {
+		if now.Before(key.quarantinedUntil) {
+			continue
+		}
+		key.currentWeight += key.weight
+		total += key.weight
+		if best == nil || key.currentWeight > best.currentWeight {
+			best = key
+		}
+	}
+
+	if best == nil {
+		best = p.keys[0]
+		for _, key := range p.keys[1:] {
+			if key.quarantinedUntil.Before(best.quarantinedUntil) {
+				best = key
+			}
+		}
+		return best.value
+	}
+
+	best.currentWeight -= total
+	return best.value
+}
+
+// report quarantines a key whose request failed with an authentication or
+// rate-limit error. Other errors do not affect the key.
+func (p *keyPool) report(value string, err error) {
+	if err == nil || len(p.keys) < 2 {
+		return
+	}
+
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		return
+	}
+	switch statusErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+	default:
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for _, key := range p.keys {
+		if key.value == value {
+			key.quarantinedUntil = time.Now().Add(p.quarantine)
+			key.currentWeight = 0
+		}
+	}
+}
+
+// KeyStatus describes an API key in a provider's key pool without revealing it.
+type KeyStatus struct {
+	Suffix           string    `json:"suffix"`
+	Weight           int       `json:"weight"`
+	QuarantinedUntil time.Time `json:"quarantined_until,omitempty"`
+}
+
+// status returns the state of every key in the pool.
+func (p *keyPool) status() []KeyStatus {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	statuses := make([]KeyStatus, len(p.keys))
+	for i, key := range p.keys {
+		suffix := key.value
+		if len(suffix) > 4 {
+			suffix = suffix[len(suffix)-4:]
+		}
+		statuses[i] = KeyStatus{
+			Suffix: "..." + suffix,
+			Weight: key.weight,
+		}
+		if time.Now().Before(key.quarantinedUntil) {
+			statuses[i].QuarantinedUntil = key.quarantinedUntil
+		}
+	}
+	return statuses
+}
+
+// SelectAPIKey returns the API key to use for the next request.
+func (p *BaseProvider) SelectAPIKey() string {
+	return p.keys.selectKey()
+}
+
+// GetKeyStatus returns the state of the provider's API keys.
+func (p *BaseProvider) GetKeyStatus() []KeyStatus {
+	return p.keys.status()
+}
+
+// reportKeyResult quarantines a key after an authentication or rate-limit failure.
+func (p *BaseProvider) reportKeyResult(key string, err error) {
+	p.keys.report(key, err)
+}
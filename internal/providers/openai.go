@@ -2,8 +2,10 @@ package providers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,40 +13,110 @@ import (
 	"github.com/sethvargo/go-retry"
 )
 
+// defaultOpenAIBaseURL is the OpenAI API base URL used when none is configured.
+const defaultOpenAIBaseURL = "https://api.openai.com/v1"
+
 // OpenAIProvider implements the Provider interface for OpenAI.
 type OpenAIProvider struct {
 	*BaseProvider
 	client *http.Client
 }
 
+// openAIChatResponse is the response body of the chat completions endpoint.
+type openAIChatResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Created int64  `json:"created"`
+	Choices []struct {
+		Index   int `json:"index"`
+		Message struct {
+			Role      string            `json:"role"`
+			Content   string            `json:"content"`
+			ToolCalls []models.ToolCall `json:"tool_calls"`
+		} `json:"message"`
+		Logprobs     *models.Logprobs `json:"logprobs"`
+		FinishReason string           `json:"finish_reason"`
+	} `json:"choices"`
+	Usage models.Usage `json:"usage"`
+}
+
+// openAIEmbeddingResponse is the response body of the embeddings endpoint.
+type openAIEmbeddingResponse struct {
+	Model string `json:"model"`
+	Data  []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+	Usage struct {
+		PromptTokens int `json:"prompt_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// openAIImageResponse is the response body of the image generations endpoint.
+type openAIImageResponse struct {
+	Created int64 `json:"created"`
+	Data    []struct {
+		URL           string `json:"url"`
+		B64JSON       string `json:"b64_json"`
+		RevisedPrompt string `json:"revised_prompt"`
+	} `json:"data"`
+}
+
+// openAITranscriptionResponse is the JSON response body of the transcriptions endpoint.
+type openAITranscriptionResponse struct {
+	Text     string  `json:"text"`
+	Language string  `json:"language"`
+	Duration float64 `json:"duration"`
+}
+
 // NewOpenAIProvider creates a new OpenAI provider instance.
-func NewOpenAIProvider(config ProviderConfig) Provider {
-	client := &http.Client{
-		Timeout: config.Timeout,
+func NewOpenAIProvider(config ProviderConfig) (Provider, error) {
+	if config.BaseURL == "" {
+		config.BaseURL = defaultOpenAIBaseURL
+	}
+
+	base := NewBaseProvider(config)
+	client, err := newHTTPClient(config, base.bandwidth, base.phases)
+	if err != nil {
+		return nil, err
 	}
 
 	return &OpenAIProvider{
-		BaseProvider: NewBaseProvider(config),
+		BaseProvider: base,
 		client:       client,
-	}
+	}, nil
 }
 
 // GetModels returns the list of available OpenAI models.
 func (p *OpenAIProvider) GetModels() ([]string, error) {
 	// For now, return a static list. In production, this would call the OpenAI models endpoint.
-	return []string{
+	return p.withFineTunes([]string{
 		"gpt-4",
 		"gpt-4-turbo-preview",
 		"gpt-4-32k",
 		"gpt-3.5-turbo",
 		"gpt-3.5-turbo-16k",
-	}, nil
+		"text-embedding-3-small",
+		"text-embedding-3-large",
+		"text-embedding-ada-002",
+		"dall-e-2",
+		"dall-e-3",
+		"gpt-image-1",
+		"whisper-1",
+		"tts-1",
+		"tts-1-hd",
+	}), nil
 }
 
 // GetCostEstimate returns an estimated cost for the request.
 func (p *OpenAIProvider) GetCostEstimate(req models.ChatRequest) (float64, error) {
-	// Simplified cost estimation based on model and token count
-	// In production, this would use actual pricing data
+	// Prefer configured pricing from the model catalog
+	if cost, ok := p.catalogCostEstimate(req); ok {
+		return cost, nil
+	}
+
+	// Fall back to simplified cost estimation based on model and token count
 	model := req.Model
 	var costPer1kTokens float64
 
@@ -57,13 +129,8 @@ func (p *OpenAIProvider) GetCostEstimate(req models.ChatRequest) (float64, error
 		costPer1kTokens = 0.01
 	}
 
-	// Estimate tokens (rough approximation)
-	estimatedTokens := len(req.Messages) * 100 // Very rough estimate
-	if req.MaxTokens > 0 {
-		estimatedTokens += req.MaxTokens
-	}
-
-	return float64(estimatedTokens) * costPer1kTokens / 1000, nil
+	inputTokens, outputTokens := p.estimateTokens(req)
+	return float64(inputTokens+outputTokens) * costPer1kTokens / 1000, nil
 }
 
 // GetLatencyEstimate returns an estimated latency for the request.
@@ -72,22 +139,30 @@ func (p *OpenAIProvider) GetLatencyEstimate(req models.ChatRequest) (time.Durati
 	baseLatency := 200 * time.Millisecond
 	perTokenLatency := 10 * time.Millisecond
 
-	estimatedTokens := len(req.Messages) * 100
-	if req.MaxTokens > 0 {
-		estimatedTokens += req.MaxTokens
-	}
-
-	return baseLatency + time.Duration(estimatedTokens)*perTokenLatency, nil
+	// Prompt tokens are processed in parallel, so they add far less latency
+	// than generated tokens
+	inputTokens, outputTokens := p.estimateTokens(req)
+	return baseLatency + time.Duration(inputTokens)*perTokenLatency/20 + time.Duration(outputTokens)*perTokenLatency, nil
 }
 
 // CreateChatCompletion creates a chat completion using OpenAI's API.
 func (p *OpenAIProvider) CreateChatCompletion(ctx context.Context, req models.ChatRequest) (*models.ChatResponse, error) {
+	if err := p.CheckContextWindow(req); err != nil {
+		return nil, err
+	}
+
+	release, err := p.acquireSlot(ctx, req.RequestID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	// Convert to OpenAI format
 	openAIReq := p.convertToOpenAIRequest(req)
 
 	// Implement retry logic
 	var response *models.ChatResponse
-	err := retry.Do(ctx, retry.WithMaxRetries(uint64(p.config.MaxRetries), retry.NewConstant(p.config.RetryDelay)), func(ctx context.Context) error {
+	err = retry.Do(ctx, retry.WithMaxRetries(uint64(p.config.MaxRetries), retry.NewConstant(p.config.RetryDelay)), func(ctx context.Context) error {
 		var err error
 		response, err = p.makeOpenAIRequest(ctx, openAIReq)
 		if err != nil {
@@ -102,7 +177,189 @@ func (p *OpenAIProvider) CreateChatCompletion(ctx context.Context, req models.Ch
 
 	if err != nil {
 		return nil, &models.ProviderError{
-			StatusCode: 500,
+			StatusCode: statusCodeOf(err, 500),
+			Err:        err,
+			Provider:   p.GetName(),
+			RequestID:  req.RequestID,
+			Retryable:  p.isRetryableError(err),
+		}
+	}
+
+	response.RequestID = req.RequestID
+	return response, nil
+}
+
+// SupportsVision reports whether model accepts image content; GPT-3.5
+// models do not.
+func (p *OpenAIProvider) SupportsVision(model string) bool {
+	return !strings.Contains(model, "gpt-3.5")
+}
+
+// SupportsTools reports that every OpenAI chat model accepts tools.
+func (p *OpenAIProvider) SupportsTools(model string) bool {
+	return true
+}
+
+// CreateEmbedding creates embeddings using OpenAI's API.
+func (p *OpenAIProvider) CreateEmbedding(ctx context.Context, req models.EmbeddingRequest) (*models.EmbeddingResponse, error) {
+	release, err := p.acquireSlot(ctx, req.RequestID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	openAIReq := map[string]interface{}{
+		"model": req.Model,
+		"input": req.Input,
+	}
+	if req.Dimensions > 0 {
+		openAIReq["dimensions"] = req.Dimensions
+	}
+	if req.User != "" {
+		openAIReq["user"] = req.User
+	}
+
+	var response *models.EmbeddingResponse
+	err = retry.Do(ctx, retry.WithMaxRetries(uint64(p.config.MaxRetries), retry.NewConstant(p.config.RetryDelay)), func(ctx context.Context) error {
+		var err error
+		response, err = p.makeOpenAIEmbeddingRequest(ctx, openAIReq)
+		if err != nil {
+			if p.isRetryableError(err) {
+				return retry.RetryableError(err)
+			}
+			return err
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, &models.ProviderError{
+			StatusCode: statusCodeOf(err, 500),
+			Err:        err,
+			Provider:   p.GetName(),
+			RequestID:  req.RequestID,
+			Retryable:  p.isRetryableError(err),
+		}
+	}
+
+	response.RequestID = req.RequestID
+	return response, nil
+}
+
+// CreateImage generates images using OpenAI's DALL·E and gpt-image models.
+func (p *OpenAIProvider) CreateImage(ctx context.Context, req models.ImageRequest) (*models.ImageResponse, error) {
+	release, err := p.acquireSlot(ctx, req.RequestID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	openAIReq := map[string]interface{}{
+		"model":  req.Model,
+		"prompt": req.Prompt,
+	}
+	if req.N > 0 {
+		openAIReq["n"] = req.N
+	}
+	if req.Size != "" {
+		openAIReq["size"] = req.Size
+	}
+	if req.Quality != "" {
+		openAIReq["quality"] = req.Quality
+	}
+	if req.Style != "" {
+		openAIReq["style"] = req.Style
+	}
+	// gpt-image models always return base64 data and reject response_format
+	if req.ResponseFormat != "" && !strings.HasPrefix(req.Model, "gpt-image") {
+		openAIReq["response_format"] = req.ResponseFormat
+	}
+	if req.User != "" {
+		openAIReq["user"] = req.User
+	}
+
+	var response *models.ImageResponse
+	err = retry.Do(ctx, retry.WithMaxRetries(uint64(p.config.MaxRetries), retry.NewConstant(p.config.RetryDelay)), func(ctx context.Context) error {
+		var err error
+		response, err = p.makeOpenAIImageRequest(ctx, openAIReq)
+		if err != nil {
+			if p.isRetryableError(err) {
+				return retry.RetryableError(err)
+			}
+			return err
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, &models.ProviderError{
+			StatusCode: statusCodeOf(err, 500),
+			Err:        err,
+			Provider:   p.GetName(),
+			RequestID:  req.RequestID,
+			Retryable:  p.isRetryableError(err),
+		}
+	}
+
+	response.RequestID = req.RequestID
+	return response, nil
+}
+
+// Transcribe transcribes audio using OpenAI's Whisper API.
+func (p *OpenAIProvider) Transcribe(ctx context.Context, req models.TranscriptionRequest) (*models.TranscriptionResponse, error) {
+	release, err := p.acquireSlot(ctx, req.RequestID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	fields := map[string]string{
+		"model":           req.Model,
+		"language":        req.Language,
+		"prompt":          req.Prompt,
+		"response_format": req.ResponseFormat,
+	}
+	if req.Temperature > 0 {
+		fields["temperature"] = strconv.FormatFloat(req.Temperature, 'f', -1, 64)
+	}
+	endpoint := strings.TrimRight(p.config.BaseURL, "/") + "/audio/transcriptions"
+
+	var response *models.TranscriptionResponse
+	err = retry.Do(ctx, retry.WithMaxRetries(uint64(p.config.MaxRetries), retry.NewConstant(p.config.RetryDelay)), func(ctx context.Context) error {
+		apiKey := p.SelectAPIKey()
+		body, header, err := doMultipartRequest(ctx, p.client, endpoint, p.requestHeaders(map[string]string{
+			"Authorization": "Bearer " + apiKey,
+		}), fields, "file", req.FileName, req.File)
+		p.reportKeyResult(apiKey, err)
+		if header != nil {
+			p.updateRateLimit(parseOpenAIRateLimit(header))
+		}
+		if err != nil {
+			if p.isRetryableError(err) {
+				return retry.RetryableError(err)
+			}
+			return err
+		}
+
+		response = &models.TranscriptionResponse{Provider: p.GetName()}
+		switch req.ResponseFormat {
+		case "text", "srt", "vtt":
+			response.Text = string(body)
+		default:
+			var openAIResp openAITranscriptionResponse
+			if err := json.Unmarshal(body, &openAIResp); err != nil {
+				return fmt.Errorf("failed to decode response: %w", err)
+			}
+			response.Text = openAIResp.Text
+			response.Language = openAIResp.Language
+			response.Duration = openAIResp.Duration
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, &models.ProviderError{
+			StatusCode: statusCodeOf(err, 500),
 			Err:        err,
 			Provider:   p.GetName(),
 			RequestID:  req.RequestID,
@@ -110,14 +367,79 @@ func (p *OpenAIProvider) CreateChatCompletion(ctx context.Context, req models.Ch
 		}
 	}
 
+	response.RequestID = req.RequestID
 	return response, nil
 }
 
-// CreateChatCompletionStream creates a streaming chat completion.
-func (p *OpenAIProvider) CreateChatCompletionStream(ctx context.Context, req models.ChatRequest) (<-chan models.StreamResponse, error) {
-	// For now, return an error indicating streaming is not yet implemented
-	// In production, this would implement Server-Sent Events or similar
-	return nil, fmt.Errorf("streaming not yet implemented for OpenAI provider")
+// Speak synthesizes speech using OpenAI's TTS API.
+func (p *OpenAIProvider) Speak(ctx context.Context, req models.SpeechRequest) (*models.SpeechResponse, error) {
+	release, err := p.acquireSlot(ctx, req.RequestID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	openAIReq := map[string]interface{}{
+		"model": req.Model,
+		"input": req.Input,
+		"voice": req.Voice,
+	}
+	if req.ResponseFormat != "" {
+		openAIReq["response_format"] = req.ResponseFormat
+	}
+	if req.Speed > 0 {
+		openAIReq["speed"] = req.Speed
+	}
+	endpoint := strings.TrimRight(p.config.BaseURL, "/") + "/audio/speech"
+
+	var response *models.SpeechResponse
+	err = retry.Do(ctx, retry.WithMaxRetries(uint64(p.config.MaxRetries), retry.NewConstant(p.config.RetryDelay)), func(ctx context.Context) error {
+		apiKey := p.SelectAPIKey()
+		httpReq, err := newJSONRequest(ctx, http.MethodPost, endpoint, p.requestHeaders(map[string]string{
+			"Authorization": "Bearer " + apiKey,
+			"Accept":        "*/*",
+		}), openAIReq)
+		if err != nil {
+			return err
+		}
+
+		audio, header, err := doRawRequest(p.client, httpReq)
+		p.reportKeyResult(apiKey, err)
+		if header != nil {
+			p.updateRateLimit(parseOpenAIRateLimit(header))
+		}
+		if err != nil {
+			if p.isRetryableError(err) {
+				return retry.RetryableError(err)
+			}
+			return err
+		}
+
+		response = &models.SpeechResponse{
+			Audio:       audio,
+			ContentType: header.Get("Content-Type"),
+			Provider:    p.GetName(),
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, &models.ProviderError{
+			StatusCode: statusCodeOf(err, 500),
+			Err:        err,
+			Provider:   p.GetName(),
+			RequestID:  req.RequestID,
+			Retryable:  p.isRetryableError(err),
+		}
+	}
+
+	response.RequestID = req.RequestID
+	return response, nil
+}
+
+// Prewarm opens connections to the OpenAI API ahead of traffic.
+func (p *OpenAIProvider) Prewarm(ctx context.Context, connections int) (int, error) {
+	return prewarmConnections(ctx, p.client, p.config.BaseURL, connections)
 }
 
 // Close performs cleanup for the OpenAI provider.
@@ -140,6 +462,15 @@ func (p *OpenAIProvider) convertToOpenAIRequest(req models.ChatRequest) map[stri
 		if msg.Name != "" {
 			messages[i]["name"] = msg.Name
 		}
+		if len(msg.ToolCalls) > 0 {
+			messages[i]["tool_calls"] = msg.ToolCalls
+			if len(msg.Content.Parts) == 0 {
+				messages[i]["content"] = nil
+			}
+		}
+		if msg.ToolCallID != "" {
+			messages[i]["tool_call_id"] = msg.ToolCallID
+		}
 	}
 
 	openAIReq := map[string]interface{}{
@@ -170,20 +501,164 @@ func (p *OpenAIProvider) convertToOpenAIRequest(req models.ChatRequest) map[stri
 	if req.User != "" {
 		openAIReq["user"] = req.User
 	}
+	if len(req.Tools) > 0 {
+		openAIReq["tools"] = req.Tools
+	}
+	if req.ToolChoice != nil {
+		openAIReq["tool_choice"] = openAIToolChoice(*req.ToolChoice)
+	}
+	if req.ResponseFormat != nil {
+		openAIReq["response_format"] = req.ResponseFormat
+	}
+	if req.N > 1 {
+		openAIReq["n"] = req.N
+	}
+	if req.Seed != nil {
+		openAIReq["seed"] = *req.Seed
+	}
+	if req.Logprobs {
+		openAIReq["logprobs"] = true
+		if req.TopLogprobs > 0 {
+			openAIReq["top_logprobs"] = req.TopLogprobs
+		}
+	}
+	if len(req.LogitBias) > 0 {
+		openAIReq["logit_bias"] = req.LogitBias
+	}
 
 	return openAIReq
 }
 
-// makeOpenAIRequest makes the actual HTTP request to OpenAI.
+// openAIToolChoice converts a tool choice to OpenAI's string or object form.
+func openAIToolChoice(choice models.ToolChoice) interface{} {
+	if choice.Type == "function" {
+		return map[string]interface{}{
+			"type":     "function",
+			"function": map[string]string{"name": choice.Name},
+		}
+	}
+	return choice.Type
+}
+
+// makeOpenAIRequest makes the HTTP request to the OpenAI chat completions endpoint.
 func (p *OpenAIProvider) makeOpenAIRequest(ctx context.Context, req map[string]interface{}) (*models.ChatResponse, error) {
-	// This is a placeholder implementation
-	// In production, this would make the actual HTTP request to OpenAI's API
-	return nil, fmt.Errorf("OpenAI API request not yet implemented")
+	endpoint := strings.TrimRight(p.config.BaseURL, "/") + "/chat/completions"
+
+	apiKey := p.SelectAPIKey()
+
+	var openAIResp openAIChatResponse
+	header, err := doJSONRequest(ctx, p.client, http.MethodPost, endpoint, p.requestHeaders(map[string]string{
+		"Authorization": "Bearer " + apiKey,
+	}), req, &openAIResp)
+	p.reportKeyResult(apiKey, err)
+	if header != nil {
+		p.updateRateLimit(parseOpenAIRateLimit(header))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return p.convertFromOpenAIResponse(openAIResp), nil
+}
+
+// makeOpenAIEmbeddingRequest makes the HTTP request to the OpenAI embeddings endpoint.
+func (p *OpenAIProvider) makeOpenAIEmbeddingRequest(ctx context.Context, req map[string]interface{}) (*models.EmbeddingResponse, error) {
+	endpoint := strings.TrimRight(p.config.BaseURL, "/") + "/embeddings"
+
+	apiKey := p.SelectAPIKey()
+
+	var openAIResp openAIEmbeddingResponse
+	header, err := doJSONRequest(ctx, p.client, http.MethodPost, endpoint, p.requestHeaders(map[string]string{
+		"Authorization": "Bearer " + apiKey,
+	}), req, &openAIResp)
+	p.reportKeyResult(apiKey, err)
+	if header != nil {
+		p.updateRateLimit(parseOpenAIRateLimit(header))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]models.Embedding, len(openAIResp.Data))
+	for i, item := range openAIResp.Data {
+		data[i] = models.Embedding{
+			Index:     item.Index,
+			Embedding: item.Embedding,
+		}
+	}
+
+	return &models.EmbeddingResponse{
+		Model: openAIResp.Model,
+		Data:  data,
+		Usage: models.Usage{
+			PromptTokens: openAIResp.Usage.PromptTokens,
+			TotalTokens:  openAIResp.Usage.TotalTokens,
+		},
+		Provider: p.GetName(),
+	}, nil
+}
+
+// makeOpenAIImageRequest makes the HTTP request to the OpenAI image generations endpoint.
+func (p *OpenAIProvider) makeOpenAIImageRequest(ctx context.Context, req map[string]interface{}) (*models.ImageResponse, error) {
+	endpoint := strings.TrimRight(p.config.BaseURL, "/") + "/images/generations"
+
+	apiKey := p.SelectAPIKey()
+
+	var openAIResp openAIImageResponse
+	header, err := doJSONRequest(ctx, p.client, http.MethodPost, endpoint, p.requestHeaders(map[string]string{
+		"Authorization": "Bearer " + apiKey,
+	}), req, &openAIResp)
+	p.reportKeyResult(apiKey, err)
+	if header != nil {
+		p.updateRateLimit(parseOpenAIRateLimit(header))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	images := make([]models.Image, len(openAIResp.Data))
+	for i, item := range openAIResp.Data {
+		images[i] = models.Image{
+			URL:           item.URL,
+			B64JSON:       item.B64JSON,
+			RevisedPrompt: item.RevisedPrompt,
+		}
+	}
+
+	return &models.ImageResponse{
+		Created:  openAIResp.Created,
+		Data:     images,
+		Provider: p.GetName(),
+	}, nil
+}
+
+// convertFromOpenAIResponse converts an OpenAI response to our unified format.
+func (p *OpenAIProvider) convertFromOpenAIResponse(resp openAIChatResponse) *models.ChatResponse {
+	choices := make([]models.Choice, len(resp.Choices))
+	for i, choice := range resp.Choices {
+		choices[i] = models.Choice{
+			Index: choice.Index,
+			Message: models.Message{
+				Role:      choice.Message.Role,
+				Content:   models.TextContent(choice.Message.Content),
+				ToolCalls: choice.Message.ToolCalls,
+			},
+			Logprobs:     choice.Logprobs,
+			FinishReason: choice.FinishReason,
+		}
+	}
+
+	return &models.ChatResponse{
+		ID:       resp.ID,
+		Model:    resp.Model,
+		Choices:  choices,
+		Usage:    resp.Usage,
+		Created:  resp.Created,
+		Provider: p.GetName(),
+	}
 }
 
 // isRetryableError determines if an error should trigger a retry.
 func (p *OpenAIProvider) isRetryableError(err error) bool {
-	// Check for retryable error conditions
-	// In production, this would check for rate limits, timeouts, etc.
-	return false
+	return isRetryableStatus(err)
 }
@@ -0,0 +1,173 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/sethvargo/go-retry"
+)
+
+// maxSSELineSize bounds a line of the event stream; a chunk is sent on one
+// line.
+const maxSSELineSize = 1 << 20
+
+// openAIStreamChunk is a chunk of the chat completions event stream.
+type openAIStreamChunk struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Created int64  `json:"created"`
+	Choices []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Role      string            `json:"role"`
+			Content   string            `json:"content"`
+			ToolCalls []models.ToolCall `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *models.Usage `json:"usage"`
+}
+
+// CreateChatCompletionStream streams a chat completion from OpenAI's API
+// with server-sent events. Opening the stream is retried like a completion;
+// once it is open, the chunks are sent on the channel until the [DONE]
+// event, and the channel is closed when the stream ends, fails or ctx is
+// done.
+func (p *OpenAIProvider) CreateChatCompletionStream(ctx context.Context, req models.ChatRequest) (<-chan models.StreamResponse, error) {
+	if err := p.CheckContextWindow(req); err != nil {
+		return nil, err
+	}
+
+	release, err := p.acquireSlot(ctx, req.RequestID)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Stream = true
+	openAIReq := p.convertToOpenAIRequest(req)
+
+	var body io.ReadCloser
+	err = retry.Do(ctx, retry.WithMaxRetries(uint64(p.config.MaxRetries), retry.NewConstant(p.config.RetryDelay)), func(ctx context.Context) error {
+		var err error
+		body, err = p.openOpenAIStream(ctx, openAIReq)
+		if err != nil {
+			if p.isRetryableError(err) {
+				return retry.RetryableError(err)
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		release()
+		return nil, &models.ProviderError{
+			StatusCode: statusCodeOf(err, 500),
+			Err:        err,
+			Provider:   p.GetName(),
+			RequestID:  req.RequestID,
+			Retryable:  p.isRetryableError(err),
+		}
+	}
+
+	stream := make(chan models.StreamResponse)
+	go func() {
+		defer release()
+		defer body.Close()
+		defer close(stream)
+		p.readOpenAIStream(ctx, body, req.RequestID, stream)
+	}()
+	return stream, nil
+}
+
+// openOpenAIStream sends a streaming request to the chat completions
+// endpoint and returns the event stream.
+func (p *OpenAIProvider) openOpenAIStream(ctx context.Context, req map[string]interface{}) (io.ReadCloser, error) {
+	endpoint := strings.TrimRight(p.config.BaseURL, "/") + "/chat/completions"
+
+	apiKey := p.SelectAPIKey()
+	httpReq, err := newJSONRequest(ctx, http.MethodPost, endpoint, p.requestHeaders(map[string]string{
+		"Authorization": "Bearer " + apiKey,
+		"Accept":        "text/event-stream",
+	}), req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		p.reportKeyResult(apiKey, err)
+		return nil, err
+	}
+	p.updateRateLimit(parseOpenAIRateLimit(resp.Header))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		resp.Body.Close()
+		err := &HTTPStatusError{StatusCode: resp.StatusCode, Body: string(errBody)}
+		p.reportKeyResult(apiKey, err)
+		return nil, err
+	}
+	p.reportKeyResult(apiKey, nil)
+	return resp.Body, nil
+}
+
+// readOpenAIStream sends the chunks of an event stream until the [DONE]
+// event. Comments, other fields and chunks that do not decode are skipped.
+func (p *OpenAIProvider) readOpenAIStream(ctx context.Context, body io.Reader, requestID string, stream chan<- models.StreamResponse) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSELineSize)
+	for scanner.Scan() {
+		data, ok := bytes.CutPrefix(scanner.Bytes(), []byte("data:"))
+		if !ok {
+			continue
+		}
+		data = bytes.TrimSpace(data)
+		if string(data) == "[DONE]" {
+			return
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			continue
+		}
+		select {
+		case stream <- p.convertFromOpenAIChunk(chunk, requestID):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// convertFromOpenAIChunk converts an OpenAI stream chunk to our unified
+// format.
+func (p *OpenAIProvider) convertFromOpenAIChunk(chunk openAIStreamChunk, requestID string) models.StreamResponse {
+	choices := make([]models.StreamChoice, len(chunk.Choices))
+	for i, choice := range chunk.Choices {
+		choices[i] = models.StreamChoice{
+			Index: choice.Index,
+			Delta: models.Message{
+				Role:      choice.Delta.Role,
+				Content:   models.TextContent(choice.Delta.Content),
+				ToolCalls: choice.Delta.ToolCalls,
+			},
+		}
+		if choice.FinishReason != nil {
+			choices[i].FinishReason = *choice.FinishReason
+		}
+	}
+
+	return models.StreamResponse{
+		ID:        chunk.ID,
+		Model:     chunk.Model,
+		Choices:   choices,
+		Created:   chunk.Created,
+		Provider:  p.GetName(),
+		RequestID: requestID,
+		Usage:     chunk.Usage,
+	}
+}
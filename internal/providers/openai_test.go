@@ -0,0 +1,167 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+// sseRequest is the request an SSE test server received.
+type sseRequest struct {
+	mutex  sync.Mutex
+	header http.Header
+	body   []byte
+}
+
+// newSSEProvider returns an OpenAI provider pointed at an SSE server that
+// writes events, one per flush.
+func newSSEProvider(t *testing.T, status int, events []string) (*OpenAIProvider, *sseRequest) {
+	t.Helper()
+	received := &sseRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received.mutex.Lock()
+		received.header, received.body = r.Header.Clone(), body
+		received.mutex.Unlock()
+
+		if status != http.StatusOK {
+			http.Error(w, `{"error":{"message":"bad model"}}`, status)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			fmt.Fprint(w, event)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := NewOpenAIProvider(ProviderConfig{Name: "openai", APIKey: "sk-test", BaseURL: server.URL, Timeout: 5 * time.Second, RetryDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider.(*OpenAIProvider), received
+}
+
+// collect reads a stream to its end.
+func collect(t *testing.T, stream <-chan models.StreamResponse) []models.StreamResponse {
+	t.Helper()
+	var chunks []models.StreamResponse
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case chunk, ok := <-stream:
+			if !ok {
+				return chunks
+			}
+			chunks = append(chunks, chunk)
+		case <-timeout:
+			t.Fatal("stream did not end")
+		}
+	}
+}
+
+func TestOpenAIStreamsChatCompletion(t *testing.T) {
+	provider, received := newSSEProvider(t, http.StatusOK, []string{
+		": keep-alive\n\n",
+		`data: {"id":"chatcmpl-1","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{"role":"assistant","content":""},"finish_reason":null}]}` + "\n\n",
+		`data: {"id":"chatcmpl-1","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{"content":"Hel"},"finish_reason":null}]}` + "\n\n",
+		`data:{"id":"chatcmpl-1","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":null}]}` + "\n\n",
+		`data: {"id":"chatcmpl-1","model":"gpt-4o","created":1,"choices":[{"index":0,"delta":{},"finish_reason":"stop"}],"usage":{"prompt_tokens":9,"completion_tokens":2,"total_tokens":11}}` + "\n\n",
+		"data: [DONE]\n\n",
+		`data: {"id":"after-done","choices":[{"index":0,"delta":{"content":"ignored"}}]}` + "\n\n",
+	})
+
+	if !Supports(provider, CapabilityStreaming, "gpt-4o") {
+		t.Fatal("OpenAI provider does not declare streaming")
+	}
+	stream, err := OpenStream(context.Background(), provider, models.ChatRequest{
+		Model:     "gpt-4o",
+		Messages:  []models.Message{{Role: "user", Content: models.TextContent("Hi")}},
+		RequestID: "req-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := collect(t, stream)
+
+	if len(chunks) != 4 {
+		t.Fatalf("%d chunks, want 4: %+v", len(chunks), chunks)
+	}
+	var text string
+	for _, chunk := range chunks {
+		if chunk.ID != "chatcmpl-1" || chunk.Provider != "openai" || chunk.RequestID != "req-1" {
+			t.Fatalf("chunk = %+v", chunk)
+		}
+		text += chunk.Choices[0].Delta.Content.Text()
+	}
+	if text != "Hello" {
+		t.Errorf("streamed text = %q", text)
+	}
+	if chunks[0].Choices[0].Delta.Role != "assistant" || chunks[3].Choices[0].FinishReason != "stop" {
+		t.Errorf("role %q, finish reason %q", chunks[0].Choices[0].Delta.Role, chunks[3].Choices[0].FinishReason)
+	}
+	if chunks[2].Usage != nil || chunks[3].Usage == nil || chunks[3].Usage.CompletionTokens != 2 {
+		t.Errorf("usage = %+v, %+v", chunks[2].Usage, chunks[3].Usage)
+	}
+
+	received.mutex.Lock()
+	defer received.mutex.Unlock()
+	var body map[string]interface{}
+	if err := json.Unmarshal(received.body, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["stream"] != true {
+		t.Errorf("request stream = %v, want true", body["stream"])
+	}
+	if received.header.Get("Accept") != "text/event-stream" || received.header.Get("Authorization") != "Bearer sk-test" {
+		t.Errorf("request headers = %v", received.header)
+	}
+}
+
+func TestOpenAIStreamEndsWithoutDone(t *testing.T) {
+	provider, _ := newSSEProvider(t, http.StatusOK, []string{
+		`data: {"id":"chatcmpl-1","choices":[{"index":0,"delta":{"content":"Hel"}}]}` + "\n\n",
+		"data: {not json}\n\n",
+	})
+	stream, err := provider.CreateChatCompletionStream(context.Background(), models.ChatRequest{Model: "gpt-4o"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunks := collect(t, stream); len(chunks) != 1 {
+		t.Fatalf("%d chunks, want the one that decoded", len(chunks))
+	}
+}
+
+func TestOpenAIStreamReportsErrorStatus(t *testing.T) {
+	provider, _ := newSSEProvider(t, http.StatusBadRequest, nil)
+	_, err := provider.CreateChatCompletionStream(context.Background(), models.ChatRequest{Model: "gpt-4o"})
+
+	providerErr, ok := err.(*models.ProviderError)
+	if !ok || providerErr.StatusCode != http.StatusBadRequest || providerErr.Retryable {
+		t.Fatalf("error = %#v, want a non-retryable 400", err)
+	}
+}
+
+func TestOpenAIStreamStopsWhenCancelled(t *testing.T) {
+	provider, _ := newSSEProvider(t, http.StatusOK, []string{
+		`data: {"id":"chatcmpl-1","choices":[{"index":0,"delta":{"content":"a"}}]}` + "\n\n",
+		`data: {"id":"chatcmpl-1","choices":[{"index":0,"delta":{"content":"b"}}]}` + "\n\n",
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := provider.CreateChatCompletionStream(ctx, models.ChatRequest{Model: "gpt-4o"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-stream
+	cancel()
+	collect(t, stream)
+}
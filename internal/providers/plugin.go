@@ -0,0 +1,330 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	v1 "github.com/semantrix/semaroute/pkg/api/v1"
+	"github.com/semantrix/semaroute/pkg/plugin"
+)
+
+// PluginsConfig holds configuration for out-of-process provider plugins.
+type PluginsConfig struct {
+	Directory        string        `mapstructure:"directory"`
+	HandshakeTimeout time.Duration `mapstructure:"handshake_timeout"`
+}
+
+// PluginProvider implements the Provider interface by delegating to a plugin process.
+type PluginProvider struct {
+	*BaseProvider
+	client *plugin.Client
+}
+
+// NewPluginProvider wraps a started plugin client, configuring it with the given provider configuration.
+func NewPluginProvider(client *plugin.Client, config ProviderConfig) (Provider, error) {
+	config.Name = client.Name()
+
+	err := client.Configure(plugin.Config{
+		APIKey:  config.APIKey,
+		BaseURL: config.BaseURL,
+		Timeout: config.Timeout,
+		Headers: config.Headers,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure plugin %s: %w", config.Name, err)
+	}
+
+	return &PluginProvider{
+		BaseProvider: NewBaseProvider(config),
+		client:       client,
+	}, nil
+}
+
+// GetModels returns the models served by the plugin.
+func (p *PluginProvider) GetModels() ([]string, error) {
+	served, err := p.client.Models()
+	if err != nil {
+		return nil, err
+	}
+	return p.withFineTunes(served), nil
+}
+
+// GetCostEstimate returns the catalog price for the request, or the plugin's own estimate.
+func (p *PluginProvider) GetCostEstimate(req models.ChatRequest) (float64, error) {
+	if cost, ok := p.catalogCostEstimate(req); ok {
+		return cost, nil
+	}
+	return p.client.CostEstimate(ToPluginRequest(req))
+}
+
+// GetLatencyEstimate returns the plugin's latency estimate for the request.
+func (p *PluginProvider) GetLatencyEstimate(req models.ChatRequest) (time.Duration, error) {
+	return p.client.LatencyEstimate(ToPluginRequest(req))
+}
+
+// CreateChatCompletion creates a chat completion through the plugin.
+func (p *PluginProvider) CreateChatCompletion(ctx context.Context, req models.ChatRequest) (*models.ChatResponse, error) {
+	if err := p.CheckContextWindow(req); err != nil {
+		return nil, err
+	}
+
+	release, err := p.acquireSlot(ctx, req.RequestID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if p.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.Timeout)
+		defer cancel()
+	}
+
+	resp, err := p.client.ChatCompletion(ctx, ToPluginRequest(req))
+	if err != nil {
+		return nil, &models.ProviderError{
+			StatusCode: 502,
+			Err:        err,
+			Provider:   p.GetName(),
+			RequestID:  req.RequestID,
+			Retryable:  ctx.Err() == nil,
+		}
+	}
+
+	return fromPluginResponse(resp, p.GetName()), nil
+}
+
+// SupportsVision reports that plugins receive image content, for the plugin
+// to accept or reject.
+func (p *PluginProvider) SupportsVision(model string) bool {
+	return true
+}
+
+// SupportsTools reports that plugins receive tool definitions, for the plugin
+// to accept or reject.
+func (p *PluginProvider) SupportsTools(model string) bool {
+	return true
+}
+
+// Close terminates the plugin process.
+func (p *PluginProvider) Close() error {
+	if err := p.client.Kill(); err != nil {
+		return err
+	}
+	return p.BaseProvider.Close()
+}
+
+// ToPluginRequest converts our unified request to the public plugin API format.
+func ToPluginRequest(req models.ChatRequest) v1.ChatCompletionRequest {
+	messages := make([]v1.Message, len(req.Messages))
+	for i, msg := range req.Messages {
+		messages[i] = v1.Message{
+			Role:       msg.Role,
+			Content:    toPluginContent(msg.Content),
+			Name:       msg.Name,
+			ToolCalls:  toPluginToolCalls(msg.ToolCalls),
+			ToolCallID: msg.ToolCallID,
+			Timestamp:  msg.Timestamp,
+		}
+	}
+
+	return v1.ChatCompletionRequest{
+		Model:            req.Model,
+		Messages:         messages,
+		Stream:           req.Stream,
+		MaxTokens:        req.MaxTokens,
+		Temperature:      req.Temperature,
+		TopP:             req.TopP,
+		TopK:             req.TopK,
+		Stop:             req.Stop,
+		PresencePenalty:  req.PresencePenalty,
+		FrequencyPenalty: req.FrequencyPenalty,
+		User:             req.User,
+		ResponseFormat:   toPluginResponseFormat(req.ResponseFormat),
+		N:                req.N,
+		Seed:             req.Seed,
+		Logprobs:         req.Logprobs,
+		TopLogprobs:      req.TopLogprobs,
+		LogitBias:        req.LogitBias,
+		Thinking:         toPluginThinking(req.Thinking),
+		Tools:            toPluginTools(req.Tools),
+		ToolChoice:       toPluginToolChoice(req.ToolChoice),
+		RequestID:        req.RequestID,
+	}
+}
+
+// toPluginTools converts tool definitions to the public plugin API format.
+func toPluginTools(tools []models.Tool) []v1.Tool {
+	if len(tools) == 0 {
+		return nil
+	}
+	pluginTools := make([]v1.Tool, len(tools))
+	for i, tool := range tools {
+		pluginTools[i] = v1.Tool{
+			Type: tool.Type,
+			Function: v1.ToolFunction{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				Parameters:  tool.Function.Parameters,
+			},
+		}
+	}
+	return pluginTools
+}
+
+// toPluginToolChoice converts a tool choice to the public plugin API format.
+func toPluginToolChoice(choice *models.ToolChoice) *v1.ToolChoice {
+	if choice == nil {
+		return nil
+	}
+	if choice.Type == "function" {
+		return &v1.ToolChoice{Type: "function", Function: &v1.ToolChoiceFunction{Name: choice.Name}}
+	}
+	return &v1.ToolChoice{Type: choice.Type}
+}
+
+// toPluginToolCalls converts the tool calls of a message to the public plugin API format.
+func toPluginToolCalls(calls []models.ToolCall) []v1.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	pluginCalls := make([]v1.ToolCall, len(calls))
+	for i, call := range calls {
+		pluginCalls[i] = v1.ToolCall{
+			ID:   call.ID,
+			Type: call.Type,
+			Function: v1.ToolCallFunction{
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			},
+		}
+	}
+	return pluginCalls
+}
+
+// fromPluginToolCalls converts plugin tool calls to our unified format.
+func fromPluginToolCalls(calls []v1.ToolCall) []models.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	converted := make([]models.ToolCall, len(calls))
+	for i, call := range calls {
+		converted[i] = models.ToolCall{
+			ID:   call.ID,
+			Type: call.Type,
+			Function: models.ToolCallFunction{
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			},
+		}
+	}
+	return converted
+}
+
+// toPluginResponseFormat converts a response format to the public plugin API format.
+func toPluginResponseFormat(format *models.ResponseFormat) *v1.ResponseFormat {
+	if format == nil {
+		return nil
+	}
+	pluginFormat := &v1.ResponseFormat{Type: format.Type}
+	if format.JSONSchema != nil {
+		pluginFormat.JSONSchema = &v1.JSONSchema{
+			Name:        format.JSONSchema.Name,
+			Description: format.JSONSchema.Description,
+			Schema:      format.JSONSchema.Schema,
+			Strict:      format.JSONSchema.Strict,
+		}
+	}
+	return pluginFormat
+}
+
+// toPluginThinking converts extended-thinking settings to the public plugin API format.
+func toPluginThinking(thinking *models.Thinking) *v1.Thinking {
+	if thinking == nil {
+		return nil
+	}
+	return &v1.Thinking{Type: thinking.Type, BudgetTokens: thinking.BudgetTokens}
+}
+
+// fromPluginLogprobs converts plugin log probabilities to our unified format.
+func fromPluginLogprobs(logprobs *v1.Logprobs) *models.Logprobs {
+	if logprobs == nil {
+		return nil
+	}
+	converted := &models.Logprobs{Content: make([]models.TokenLogprob, len(logprobs.Content))}
+	for i, token := range logprobs.Content {
+		converted.Content[i] = models.TokenLogprob{
+			Token:   token.Token,
+			Logprob: token.Logprob,
+			Bytes:   token.Bytes,
+		}
+		for _, top := range token.TopLogprobs {
+			converted.Content[i].TopLogprobs = append(converted.Content[i].TopLogprobs, models.TopLogprob{
+				Token:   top.Token,
+				Logprob: top.Logprob,
+				Bytes:   top.Bytes,
+			})
+		}
+	}
+	return converted
+}
+
+// toPluginContent converts message content to the public plugin API format.
+func toPluginContent(content models.Content) v1.Content {
+	parts := make([]v1.ContentPart, len(content.Parts))
+	for i, part := range content.Parts {
+		parts[i] = v1.ContentPart{Type: part.Type, Text: part.Text}
+		if part.ImageURL != nil {
+			parts[i].ImageURL = &v1.ImageURL{URL: part.ImageURL.URL, Detail: part.ImageURL.Detail}
+		}
+	}
+	return v1.Content{Parts: parts}
+}
+
+// fromPluginContent converts plugin message content to our unified format.
+func fromPluginContent(content v1.Content) models.Content {
+	parts := make([]models.ContentPart, len(content.Parts))
+	for i, part := range content.Parts {
+		parts[i] = models.ContentPart{Type: part.Type, Text: part.Text}
+		if part.ImageURL != nil {
+			parts[i].ImageURL = &models.ImageURL{URL: part.ImageURL.URL, Detail: part.ImageURL.Detail}
+		}
+	}
+	return models.Content{Parts: parts}
+}
+
+// fromPluginResponse converts a plugin response to our unified format.
+func fromPluginResponse(resp *v1.ChatCompletionResponse, providerName string) *models.ChatResponse {
+	choices := make([]models.Choice, len(resp.Choices))
+	for i, choice := range resp.Choices {
+		choices[i] = models.Choice{
+			Index: choice.Index,
+			Message: models.Message{
+				Role:       choice.Message.Role,
+				Content:    fromPluginContent(choice.Message.Content),
+				Name:       choice.Message.Name,
+				ToolCalls:  fromPluginToolCalls(choice.Message.ToolCalls),
+				ToolCallID: choice.Message.ToolCallID,
+				Timestamp:  choice.Message.Timestamp,
+			},
+			Logprobs:     fromPluginLogprobs(choice.Logprobs),
+			FinishReason: choice.FinishReason,
+		}
+	}
+
+	return &models.ChatResponse{
+		ID:      resp.ID,
+		Model:   resp.Model,
+		Choices: choices,
+		Usage: models.Usage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+			TotalTokens:      resp.Usage.TotalTokens,
+		},
+		Created:   resp.Created,
+		Provider:  providerName,
+		RequestID: resp.RequestID,
+	}
+}
@@ -0,0 +1,57 @@
+package providers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/semantrix/semaroute/internal/models"
+	v1 "github.com/semantrix/semaroute/pkg/api/v1"
+)
+
+func TestToPluginRequestCarriesTools(t *testing.T) {
+	call := models.ToolCall{ID: "call_1", Type: "function", Function: models.ToolCallFunction{Name: "get_weather", Arguments: `{"city":"Paris"}`}}
+	req := models.ChatRequest{
+		Model: "custom-model",
+		Messages: []models.Message{
+			{Role: "user", Content: models.TextContent("Weather in Paris?")},
+			{Role: "assistant", ToolCalls: []models.ToolCall{call}},
+			{Role: "tool", ToolCallID: "call_1", Content: models.TextContent(`{"temp":18}`)},
+		},
+		Tools: []models.Tool{{Type: "function", Function: models.ToolFunction{
+			Name:       "get_weather",
+			Parameters: json.RawMessage(`{"type":"object"}`),
+		}}},
+		ToolChoice: &models.ToolChoice{Type: "function", Name: "get_weather"},
+	}
+
+	converted := ToPluginRequest(req)
+	if len(converted.Tools) != 1 || converted.Tools[0].Function.Name != "get_weather" || string(converted.Tools[0].Function.Parameters) != `{"type":"object"}` {
+		t.Fatalf("tools = %+v", converted.Tools)
+	}
+	if converted.ToolChoice == nil || converted.ToolChoice.Function == nil || converted.ToolChoice.Function.Name != "get_weather" {
+		t.Fatalf("tool_choice = %+v, want the named function", converted.ToolChoice)
+	}
+	if calls := converted.Messages[1].ToolCalls; len(calls) != 1 || calls[0].ID != "call_1" || calls[0].Function.Arguments != `{"city":"Paris"}` {
+		t.Fatalf("assistant tool calls = %+v", calls)
+	}
+	if converted.Messages[2].ToolCallID != "call_1" {
+		t.Fatalf("tool_call_id = %q", converted.Messages[2].ToolCallID)
+	}
+
+	if choice := ToPluginRequest(models.ChatRequest{ToolChoice: &models.ToolChoice{Type: "auto"}}).ToolChoice; choice == nil || choice.Type != "auto" || choice.Function != nil {
+		t.Fatalf("tool_choice = %+v, want auto", choice)
+	}
+}
+
+func TestFromPluginResponseCarriesToolCalls(t *testing.T) {
+	call := v1.ToolCall{ID: "call_1", Type: "function", Function: v1.ToolCallFunction{Name: "get_weather", Arguments: `{"city":"Paris"}`}}
+	resp := fromPluginResponse(&v1.ChatCompletionResponse{
+		Choices: []v1.Choice{{Message: v1.Message{Role: "assistant", ToolCalls: []v1.ToolCall{call}}, FinishReason: "tool_calls"}},
+	}, "custom")
+
+	want := []models.ToolCall{{ID: "call_1", Type: "function", Function: models.ToolCallFunction{Name: "get_weather", Arguments: `{"city":"Paris"}`}}}
+	if got := resp.Choices[0].Message.ToolCalls; !reflect.DeepEqual(got, want) {
+		t.Fatalf("tool calls = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,60 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Prewarmer is implemented by providers that can open connections ahead of
+// traffic, so the first requests after a recovery do not pay for DNS, TCP and
+// TLS setup.
+type Prewarmer interface {
+	// Prewarm opens up to connections connections to the provider's API and
+	// leaves them idle in the connection pool. It returns the number opened.
+	Prewarm(ctx context.Context, connections int) (int, error)
+}
+
+// prewarmConnections sends concurrent HEAD requests to url over client. Any
+// HTTP response means the connection was established, whatever its status, and
+// the drained connection is returned to the idle pool. HTTP/2 providers may
+// serve all requests over a single connection.
+func prewarmConnections(ctx context.Context, client *http.Client, url string, connections int) (int, error) {
+	var (
+		wg     sync.WaitGroup
+		mutex  sync.Mutex
+		opened int
+		errs   []error
+	)
+	for i := 0; i < connections; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+			if err == nil {
+				var resp *http.Response
+				resp, err = client.Do(req)
+				if err == nil {
+					io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+				}
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			opened++
+		}()
+	}
+	wg.Wait()
+
+	if opened == 0 && len(errs) > 0 {
+		return 0, fmt.Errorf("failed to open connections to %s: %w", url, errs[0])
+	}
+	return opened, nil
+}
@@ -2,12 +2,17 @@ package providers
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/semantrix/semaroute/internal/catalog"
 	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/tokenizer"
 )
 
 // Provider defines the interface that all LLM providers must implement.
+// Streaming, embeddings, vision and tools are optional capabilities, declared
+// by implementing the interfaces in capabilities.go.
 type Provider interface {
 	// GetName returns the unique name identifier for this provider.
 	GetName() string
@@ -33,13 +38,28 @@ type Provider interface {
 	// CreateChatCompletion creates a synchronous chat completion.
 	CreateChatCompletion(ctx context.Context, req models.ChatRequest) (*models.ChatResponse, error)
 
-	// CreateChatCompletionStream creates a streaming chat completion.
-	CreateChatCompletionStream(ctx context.Context, req models.ChatRequest) (<-chan models.StreamResponse, error)
-
 	// Close performs any necessary cleanup when the provider is no longer needed.
 	Close() error
 }
 
+// ImageProvider is implemented by providers that can generate images.
+type ImageProvider interface {
+	// CreateImage generates images from a text prompt.
+	CreateImage(ctx context.Context, req models.ImageRequest) (*models.ImageResponse, error)
+}
+
+// Transcriber is implemented by providers that can transcribe audio.
+type Transcriber interface {
+	// Transcribe converts speech in an uploaded audio file to text.
+	Transcribe(ctx context.Context, req models.TranscriptionRequest) (*models.TranscriptionResponse, error)
+}
+
+// Speaker is implemented by providers that can synthesize speech.
+type Speaker interface {
+	// Speak converts text to audio.
+	Speak(ctx context.Context, req models.SpeechRequest) (*models.SpeechResponse, error)
+}
+
 // ProviderConfig holds common configuration for all providers.
 type ProviderConfig struct {
 	Name                string        `mapstructure:"name"`
@@ -51,13 +71,64 @@ type ProviderConfig struct {
 	HealthCheckURL      string        `mapstructure:"health_check_url"`
 	HealthCheckInterval time.Duration `mapstructure:"health_check_interval"`
 	Enabled             bool          `mapstructure:"enabled"`
+
+	// Region the provider's endpoint is hosted in, e.g. eu-west, for
+	// data-residency routing; requests with a residency tag never go to
+	// providers without one
+	Region string `mapstructure:"region"`
+
+	// Multiple API keys, used in addition to APIKey. Keys failing with 401/403/429
+	// are skipped for KeyQuarantine.
+	APIKeys       []APIKeyConfig `mapstructure:"api_keys"`
+	KeySelection  string         `mapstructure:"key_selection"` // "round_robin" or "weighted"
+	KeyQuarantine time.Duration  `mapstructure:"key_quarantine"`
+
+	// Static headers added to every outbound request, e.g. OpenAI-Organization
+	Headers map[string]string `mapstructure:"headers"`
+
+	// Egress settings; proxy_url accepts http, https, socks5 and socks5h URLs
+	ProxyURL string    `mapstructure:"proxy_url"`
+	TLS      TLSConfig `mapstructure:"tls"`
+
+	// Connection pool, timeout and HTTP/2 settings of the provider's transport
+	Transport TransportConfig `mapstructure:"transport"`
+
+	// Hooks run around every HTTP call to the provider, first hook outermost
+	Hooks []HookConfig `mapstructure:"hooks"`
+
+	// Cloud-native credentials (AWS SigV4, GCP ADC, Azure AAD), used instead of api_key
+	Credentials CredentialsConfig `mapstructure:"credentials"`
+
+	// Concurrency limiting; requests beyond MaxConcurrent wait up to QueueTimeout for a slot
+	MaxConcurrent int           `mapstructure:"max_concurrent"`
+	QueueTimeout  time.Duration `mapstructure:"queue_timeout"`
+
+	// Vendor quotas resetting each minute, hour or day; deferred-priority
+	// requests wait for the reset of a provider that used one up
+	Quotas []QuotaConfig `mapstructure:"quotas"`
+
+	// watsonx.ai specific settings
+	ProjectID  string `mapstructure:"project_id"`
+	IAMURL     string `mapstructure:"iam_url"`
+	APIVersion string `mapstructure:"api_version"`
+
+	// Anthropic specific settings; max_tokens is required by the messages API,
+	// so requests without one are sent with this value (0 picks a per-model default)
+	DefaultMaxTokens int `mapstructure:"default_max_tokens"`
 }
 
 // BaseProvider provides common functionality for all providers.
 type BaseProvider struct {
-	config ProviderConfig
-	health models.HealthStatus
-	models []string
+	config     ProviderConfig
+	health     models.HealthStatus
+	models     []string
+	catalog    *catalog.Catalog
+	rateLimits rateLimitTracker
+	limiter    *concurrencyLimiter
+	quotas     *quotaCalendar
+	keys       *keyPool
+	bandwidth  *bandwidthCounter
+	phases     *phaseRecorder
 }
 
 // NewBaseProvider creates a new base provider with the given configuration.
@@ -68,6 +139,11 @@ func NewBaseProvider(config ProviderConfig) *BaseProvider {
 			Healthy:   true,
 			LastCheck: time.Now(),
 		},
+		limiter:   newConcurrencyLimiter(config.MaxConcurrent, config.QueueTimeout),
+		quotas:    newQuotaCalendar(config.Quotas),
+		keys:      newKeyPool(config),
+		bandwidth: &bandwidthCounter{},
+		phases:    &phaseRecorder{},
 	}
 }
 
@@ -99,6 +175,143 @@ func (p *BaseProvider) GetConfig() ProviderConfig {
 	return p.config
 }
 
+// Region returns the region the provider is hosted in, or "".
+func (p *BaseProvider) Region() string {
+	return p.config.Region
+}
+
+// SetCatalog sets the model catalog consulted for cost estimates and
+// fine-tuned models.
+func (p *BaseProvider) SetCatalog(c *catalog.Catalog) {
+	p.catalog = c
+}
+
+// withFineTunes adds the fine-tuned models registered for the provider in the
+// catalog to the models it serves.
+func (p *BaseProvider) withFineTunes(served []string) []string {
+	if p.catalog == nil {
+		return served
+	}
+	known := make(map[string]bool, len(served))
+	for _, model := range served {
+		known[model] = true
+	}
+	for _, model := range p.catalog.FineTunes(p.GetName()) {
+		if !known[model] {
+			served = append(served, model)
+		}
+	}
+	return served
+}
+
+// GetBandwidthStats returns the response bytes received from the provider.
+func (p *BaseProvider) GetBandwidthStats() BandwidthStats {
+	return p.bandwidth.stats()
+}
+
+// ObservePhases reports the phase durations of the provider's outbound
+// requests to observer.
+func (p *BaseProvider) ObservePhases(observer PhaseObserver) {
+	p.phases.setObserver(observer)
+}
+
+// GetRateLimitState returns the rate-limit state last reported by the provider, if any.
+func (p *BaseProvider) GetRateLimitState() (RateLimitState, bool) {
+	return p.rateLimits.GetRateLimitState()
+}
+
+// updateRateLimit stores rate-limit state parsed from a provider response.
+func (p *BaseProvider) updateRateLimit(state RateLimitState, ok bool) {
+	p.rateLimits.update(state, ok)
+}
+
+// requestHeaders returns the configured static headers merged with the
+// request-specific ones, which take precedence.
+func (p *BaseProvider) requestHeaders(headers map[string]string) map[string]string {
+	merged := make(map[string]string, len(p.config.Headers)+len(headers))
+	for key, value := range p.config.Headers {
+		merged[key] = value
+	}
+	for key, value := range headers {
+		merged[key] = value
+	}
+	return merged
+}
+
+// estimateTokens returns the prompt and completion token counts for a request.
+// The completion count is the requested max_tokens, as the actual length is
+// not known until the provider responds.
+func (p *BaseProvider) estimateTokens(req models.ChatRequest) (int, int) {
+	return tokenizer.CountMessages(p.GetName(), req.Model, req.Messages), req.MaxTokens
+}
+
+// contextWindow returns the context window size for a model from the catalog,
+// falling back to the built-in sizes. It returns 0 if the size is unknown.
+func (p *BaseProvider) contextWindow(model string) int {
+	if p.catalog != nil {
+		if entry, found := p.catalog.Lookup(p.GetName(), model); found && entry.ContextWindow > 0 {
+			return entry.ContextWindow
+		}
+	}
+	return tokenizer.ContextWindow(model)
+}
+
+// ContextChecker is implemented by providers that know the context windows of
+// their models, so requests too long for a model can be routed elsewhere.
+type ContextChecker interface {
+	// CheckContextWindow returns an error if the request's prompt plus
+	// max_tokens do not fit the model's context window.
+	CheckContextWindow(req models.ChatRequest) error
+}
+
+// Regional is implemented by providers that know the region they are hosted
+// in.
+type Regional interface {
+	// Region returns the provider's region, or "" when not configured.
+	Region() string
+}
+
+// RegionOf returns the region a provider is hosted in, or "".
+func RegionOf(provider Provider) string {
+	if regional, ok := provider.(Regional); ok {
+		return regional.Region()
+	}
+	return ""
+}
+
+// CheckContextWindow rejects requests whose prompt plus max_tokens do not fit
+// the model's context window, before they are sent to the provider.
+func (p *BaseProvider) CheckContextWindow(req models.ChatRequest) error {
+	window := p.contextWindow(req.Model)
+	if window == 0 {
+		return nil
+	}
+
+	inputTokens, outputTokens := p.estimateTokens(req)
+	if inputTokens+outputTokens > window {
+		return &models.ProviderError{
+			StatusCode: 400,
+			Err: fmt.Errorf("request needs %d tokens (%d prompt + %d max_tokens) but %s has a context window of %d",
+				inputTokens+outputTokens, inputTokens, outputTokens, req.Model, window),
+			Provider:  p.GetName(),
+			RequestID: req.RequestID,
+			Retryable: false,
+		}
+	}
+
+	return nil
+}
+
+// catalogCostEstimate returns the cost estimate from the model catalog, if the model is listed there.
+func (p *BaseProvider) catalogCostEstimate(req models.ChatRequest) (float64, bool) {
+	if p.catalog == nil {
+		return 0, false
+	}
+
+	inputTokens, outputTokens := p.estimateTokens(req)
+	return p.catalog.EstimateCost(p.GetName(), req.Model, inputTokens, outputTokens)
+}
+
 // Close performs cleanup for the base provider.
 func (p *BaseProvider) Close() error {
 	// Base implementation does nothing
@@ -0,0 +1,197 @@
+package providers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Quota periods. A quota resets at the start of each minute, hour or day.
+const (
+	QuotaPerMinute = "minute"
+	QuotaPerHour   = "hour"
+	QuotaPerDay    = "day"
+)
+
+// QuotaConfig is a vendor request quota that resets on a calendar boundary,
+// such as 10000 requests per day reset at midnight Pacific time.
+type QuotaConfig struct {
+	Period   string `mapstructure:"period"`   // minute, hour or day
+	Requests int    `mapstructure:"requests"` // requests allowed per period
+	Timezone string `mapstructure:"timezone"` // IANA name the period boundaries are in; default UTC
+}
+
+// QuotaStatus is the usage of a provider quota in its current period.
+type QuotaStatus struct {
+	Period   string    `json:"period"`
+	Requests int       `json:"requests"`
+	Used     int       `json:"used"`
+	ResetsAt time.Time `json:"resets_at"`
+}
+
+// QuotaReporter is implemented by providers that track their quotas.
+type QuotaReporter interface {
+	// GetQuotaStatus returns the usage of each configured quota.
+	GetQuotaStatus() []QuotaStatus
+}
+
+// ValidateQuotas checks provider quota configurations.
+func ValidateQuotas(configs []QuotaConfig) error {
+	for _, config := range configs {
+		switch config.Period {
+		case QuotaPerMinute, QuotaPerHour, QuotaPerDay:
+		default:
+			return fmt.Errorf("quota period %q must be minute, hour or day", config.Period)
+		}
+		if config.Requests <= 0 {
+			return fmt.Errorf("%s quota must allow a positive number of requests", config.Period)
+		}
+		if _, err := time.LoadLocation(config.Timezone); err != nil {
+			return fmt.Errorf("invalid %s quota timezone: %w", config.Period, err)
+		}
+	}
+	return nil
+}
+
+// quota counts the requests sent in the current period of a QuotaConfig.
+type quota struct {
+	config   QuotaConfig
+	location *time.Location
+	start    time.Time // of the current period
+	used     int
+}
+
+// periodStart returns the start of the period containing t.
+func (q *quota) periodStart(t time.Time) time.Time {
+	t = t.In(q.location)
+	switch q.config.Period {
+	case QuotaPerMinute:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, q.location)
+	case QuotaPerHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, q.location)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, q.location)
+	}
+}
+
+// periodEnd returns when the period starting at start resets.
+func (q *quota) periodEnd(start time.Time) time.Time {
+	switch q.config.Period {
+	case QuotaPerMinute:
+		return start.Add(time.Minute)
+	case QuotaPerHour:
+		return start.Add(time.Hour)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// roll starts a new period when the current one has reset by now.
+func (q *quota) roll(now time.Time) {
+	if start := q.periodStart(now); !start.Equal(q.start) {
+		q.start = start
+		q.used = 0
+	}
+}
+
+// quotaCalendar tracks a provider's quotas and when each resets.
+type quotaCalendar struct {
+	quotas []*quota
+	mutex  sync.Mutex
+}
+
+// newQuotaCalendar creates a calendar for validated quota configurations.
+func newQuotaCalendar(configs []QuotaConfig) *quotaCalendar {
+	calendar := &quotaCalendar{}
+	for _, config := range configs {
+		location, err := time.LoadLocation(config.Timezone)
+		if err != nil {
+			location = time.UTC
+		}
+		calendar.quotas = append(calendar.quotas, &quota{config: config, location: location})
+	}
+	return calendar
+}
+
+// consume counts a request sent at now against every quota.
+func (c *quotaCalendar) consume(now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, q := range c.quotas {
+		q.roll(now)
+		q.used++
+	}
+}
+
+// exhaustedUntil returns when the last of the quotas used up at now resets,
+// or false if every quota has requests left.
+func (c *quotaCalendar) exhaustedUntil(now time.Time) (time.Time, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var until time.Time
+	for _, q := range c.quotas {
+		q.roll(now)
+		if q.used < q.config.Requests {
+			continue
+		}
+		if reset := q.periodEnd(q.start); reset.After(until) {
+			until = reset
+		}
+	}
+	return until, !until.IsZero()
+}
+
+// status returns the usage of each quota at now.
+func (c *quotaCalendar) status(now time.Time) []QuotaStatus {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	statuses := make([]QuotaStatus, 0, len(c.quotas))
+	for _, q := range c.quotas {
+		q.roll(now)
+		statuses = append(statuses, QuotaStatus{
+			Period:   q.config.Period,
+			Requests: q.config.Requests,
+			Used:     q.used,
+			ResetsAt: q.periodEnd(q.start),
+		})
+	}
+	return statuses
+}
+
+// GetQuotaStatus returns the usage of each configured quota.
+func (p *BaseProvider) GetQuotaStatus() []QuotaStatus {
+	return p.quotas.status(time.Now())
+}
+
+// QuotaExhaustedUntil returns when provider may serve requests again after
+// using up a configured quota or, as its last response headers reported, its
+// request or token allowance. It returns false if the provider has headroom.
+func QuotaExhaustedUntil(provider Provider) (time.Time, bool) {
+	now := time.Now()
+	var until time.Time
+	if reporter, ok := provider.(interface {
+		quotaExhaustedUntil(now time.Time) (time.Time, bool)
+	}); ok {
+		until, _ = reporter.quotaExhaustedUntil(now)
+	}
+	if reporter, ok := provider.(RateLimitReporter); ok {
+		if state, known := reporter.GetRateLimitState(); known {
+			if state.RemainingRequests == 0 && state.ResetRequests.After(now) && state.ResetRequests.After(until) {
+				until = state.ResetRequests
+			}
+			if state.RemainingTokens == 0 && state.ResetTokens.After(now) && state.ResetTokens.After(until) {
+				until = state.ResetTokens
+			}
+		}
+	}
+	return until, !until.IsZero()
+}
+
+// quotaExhaustedUntil returns when the provider's configured quotas used up
+// at now reset, or false if none is used up.
+func (p *BaseProvider) quotaExhaustedUntil(now time.Time) (time.Time, bool) {
+	return p.quotas.exhaustedUntil(now)
+}
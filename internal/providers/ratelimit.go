@@ -0,0 +1,142 @@
+package providers
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitState is the most recent rate-limit status reported by a provider
+// in its response headers. Limits and remaining counts are -1 when the
+// provider did not report them.
+type RateLimitState struct {
+	LimitRequests     int       `json:"limit_requests"`
+	RemainingRequests int       `json:"remaining_requests"`
+	ResetRequests     time.Time `json:"reset_requests,omitempty"`
+	LimitTokens       int       `json:"limit_tokens"`
+	RemainingTokens   int       `json:"remaining_tokens"`
+	ResetTokens       time.Time `json:"reset_tokens,omitempty"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// RateLimitReporter is implemented by providers that track rate-limit state.
+type RateLimitReporter interface {
+	// GetRateLimitState returns the last observed rate-limit state, if any.
+	GetRateLimitState() (RateLimitState, bool)
+}
+
+// NearLimit returns true if the remaining requests or tokens have dropped
+// below the given fraction of the limit and the window has not reset yet.
+func (s RateLimitState) NearLimit(threshold float64) bool {
+	now := time.Now()
+	return nearLimit(s.RemainingRequests, s.LimitRequests, s.ResetRequests, threshold, now) ||
+		nearLimit(s.RemainingTokens, s.LimitTokens, s.ResetTokens, threshold, now)
+}
+
+func nearLimit(remaining, limit int, reset time.Time, threshold float64, now time.Time) bool {
+	if remaining < 0 || limit <= 0 {
+		return false
+	}
+	if !reset.IsZero() && now.After(reset) {
+		return false
+	}
+	return float64(remaining) < float64(limit)*threshold
+}
+
+// IsNearRateLimit returns true if the provider reports being close to its rate limit.
+func IsNearRateLimit(provider Provider, threshold float64) bool {
+	reporter, ok := provider.(RateLimitReporter)
+	if !ok {
+		return false
+	}
+	state, ok := reporter.GetRateLimitState()
+	return ok && state.NearLimit(threshold)
+}
+
+// rateLimitTracker stores the rate-limit state parsed from provider responses.
+type rateLimitTracker struct {
+	state RateLimitState
+	known bool
+	mutex sync.RWMutex
+}
+
+// update stores a newly observed state.
+func (t *rateLimitTracker) update(state RateLimitState, ok bool) {
+	if !ok {
+		return
+	}
+
+	t.mutex.Lock()
+	t.state = state
+	t.known = true
+	t.mutex.Unlock()
+}
+
+// GetRateLimitState returns the last observed rate-limit state, if any.
+func (t *rateLimitTracker) GetRateLimitState() (RateLimitState, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	return t.state, t.known
+}
+
+// parseOpenAIRateLimit parses the x-ratelimit-* headers sent by OpenAI.
+// Reset values are durations such as "1s" or "6m0s".
+func parseOpenAIRateLimit(header http.Header) (RateLimitState, bool) {
+	now := time.Now()
+	state := RateLimitState{
+		LimitRequests:     headerInt(header, "x-ratelimit-limit-requests"),
+		RemainingRequests: headerInt(header, "x-ratelimit-remaining-requests"),
+		ResetRequests:     headerDuration(header, "x-ratelimit-reset-requests", now),
+		LimitTokens:       headerInt(header, "x-ratelimit-limit-tokens"),
+		RemainingTokens:   headerInt(header, "x-ratelimit-remaining-tokens"),
+		ResetTokens:       headerDuration(header, "x-ratelimit-reset-tokens", now),
+		UpdatedAt:         now,
+	}
+
+	return state, state.RemainingRequests >= 0 || state.RemainingTokens >= 0
+}
+
+// parseAnthropicRateLimit parses the anthropic-ratelimit-* headers sent by Anthropic.
+// Reset values are RFC 3339 timestamps.
+func parseAnthropicRateLimit(header http.Header) (RateLimitState, bool) {
+	state := RateLimitState{
+		LimitRequests:     headerInt(header, "anthropic-ratelimit-requests-limit"),
+		RemainingRequests: headerInt(header, "anthropic-ratelimit-requests-remaining"),
+		ResetRequests:     headerTime(header, "anthropic-ratelimit-requests-reset"),
+		LimitTokens:       headerInt(header, "anthropic-ratelimit-tokens-limit"),
+		RemainingTokens:   headerInt(header, "anthropic-ratelimit-tokens-remaining"),
+		ResetTokens:       headerTime(header, "anthropic-ratelimit-tokens-reset"),
+		UpdatedAt:         time.Now(),
+	}
+
+	return state, state.RemainingRequests >= 0 || state.RemainingTokens >= 0
+}
+
+// headerInt returns an integer header value, or -1 if it is missing or invalid.
+func headerInt(header http.Header, key string) int {
+	value, err := strconv.Atoi(header.Get(key))
+	if err != nil {
+		return -1
+	}
+	return value
+}
+
+// headerDuration returns now plus a duration header value, or the zero time.
+func headerDuration(header http.Header, key string, now time.Time) time.Time {
+	d, err := time.ParseDuration(header.Get(key))
+	if err != nil {
+		return time.Time{}
+	}
+	return now.Add(d)
+}
+
+// headerTime returns an RFC 3339 header value, or the zero time.
+func headerTime(header http.Header, key string) time.Time {
+	t, err := time.Parse(time.RFC3339, header.Get(key))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,97 @@
+package providers
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// ProviderSet is a concurrency-safe set of named providers. Reads work on an
+// immutable snapshot, so a request that takes one snapshot sees the same
+// membership throughout routing and execution while providers are added or
+// removed concurrently. Writes copy the map and publish it atomically.
+type ProviderSet struct {
+	snapshot atomic.Value // map[string]Provider, never mutated once stored
+	mutex    sync.Mutex   // serializes writers
+}
+
+// NewProviderSet creates a set holding the given providers.
+func NewProviderSet(initial map[string]Provider) *ProviderSet {
+	members := make(map[string]Provider, len(initial))
+	for name, provider := range initial {
+		members[name] = provider
+	}
+
+	set := &ProviderSet{}
+	set.snapshot.Store(members)
+	return set
+}
+
+// Snapshot returns the current membership. The returned map is shared and
+// must not be modified.
+func (s *ProviderSet) Snapshot() map[string]Provider {
+	return s.snapshot.Load().(map[string]Provider)
+}
+
+// Get returns the named provider.
+func (s *ProviderSet) Get(name string) (Provider, bool) {
+	provider, ok := s.Snapshot()[name]
+	return provider, ok
+}
+
+// Len returns the number of providers.
+func (s *ProviderSet) Len() int {
+	return len(s.Snapshot())
+}
+
+// Names returns the provider names in sorted order.
+func (s *ProviderSet) Names() []string {
+	members := s.Snapshot()
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Put adds or replaces a provider and returns the one it replaced, if any.
+func (s *ProviderSet) Put(name string, provider Provider) (Provider, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current := s.Snapshot()
+	previous, existed := current[name]
+
+	next := make(map[string]Provider, len(current)+1)
+	for n, p := range current {
+		next[n] = p
+	}
+	next[name] = provider
+	s.snapshot.Store(next)
+
+	return previous, existed
+}
+
+// Remove removes a provider and returns it. Requests holding an older
+// snapshot may still use it, so callers should not close it immediately.
+func (s *ProviderSet) Remove(name string) (Provider, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current := s.Snapshot()
+	removed, existed := current[name]
+	if !existed {
+		return nil, false
+	}
+
+	next := make(map[string]Provider, len(current))
+	for n, p := range current {
+		if n != name {
+			next[n] = p
+		}
+	}
+	s.snapshot.Store(next)
+
+	return removed, true
+}
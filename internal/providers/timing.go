@@ -0,0 +1,219 @@
+package providers
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Phases of an outbound request, as reported to a PhaseObserver. dns,
+// connect and tls are only observed for requests that open a new connection.
+const (
+	PhaseDNS       = "dns"        // host name lookup
+	PhaseConnect   = "connect"    // TCP connect, from the first attempt to the connection used
+	PhaseTLS       = "tls"        // TLS handshake
+	PhaseFirstByte = "first_byte" // from the request being written to the first response byte
+	PhaseBody      = "body"       // from the first response byte to the end of the body
+)
+
+// PhaseObserver receives the duration of each phase of a provider's outbound
+// requests.
+type PhaseObserver func(phase string, duration time.Duration)
+
+// PhaseReporter is implemented by providers that time the phases of their
+// outbound requests.
+type PhaseReporter interface {
+	// ObservePhases sets the observer phase durations are reported to.
+	ObservePhases(observer PhaseObserver)
+}
+
+// ErrReadIdleTimeout is returned when a provider sends no response data for
+// longer than its read idle timeout.
+var ErrReadIdleTimeout = errors.New("provider sent no data within the read idle timeout")
+
+// phaseRecorder holds the observer of a provider's request phases, which is
+// set after the provider's HTTP client is built.
+type phaseRecorder struct {
+	observer atomic.Value // PhaseObserver
+}
+
+// setObserver sets the observer.
+func (r *phaseRecorder) setObserver(observer PhaseObserver) {
+	r.observer.Store(observer)
+}
+
+// observe reports a phase to the observer, if any.
+func (r *phaseRecorder) observe(phase string, duration time.Duration) {
+	if observer, ok := r.observer.Load().(PhaseObserver); ok && observer != nil {
+		observer(phase, duration)
+	}
+}
+
+// timingTransport times the phases of each request and enforces the read
+// idle timeout on response bodies.
+type timingTransport struct {
+	base        http.RoundTripper
+	recorder    *phaseRecorder
+	readTimeout time.Duration
+}
+
+// newTimingTransport wraps base. A zero readTimeout leaves response bodies
+// without an idle limit.
+func newTimingTransport(recorder *phaseRecorder, readTimeout time.Duration, base http.RoundTripper) http.RoundTripper {
+	return &timingTransport{base: base, recorder: recorder, readTimeout: readTimeout}
+}
+
+// RoundTrip traces the request and wraps the response body to time it.
+func (t *timingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	timer := &phaseTimer{recorder: t.recorder}
+	req = req.WithContext(httptrace.WithClientTrace(ctx, timer.trace()))
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = newTimedBody(resp.Body, timer, t.readTimeout, cancel)
+	return resp, nil
+}
+
+// phaseTimer collects the timestamps of one request.
+type phaseTimer struct {
+	recorder *phaseRecorder
+
+	mutex        sync.Mutex
+	dnsStart     time.Time
+	connectStart time.Time
+	connected    bool
+	tlsStart     time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+}
+
+// trace returns the hooks recording the request's phases.
+func (t *phaseTimer) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mutex.Lock()
+			t.dnsStart = time.Now()
+			t.mutex.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.since(&t.dnsStart, PhaseDNS)
+		},
+		ConnectStart: func(string, string) {
+			// Raced attempts share the start of the first one
+			t.mutex.Lock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+			t.mutex.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.mutex.Lock()
+			first := err == nil && !t.connected
+			t.connected = t.connected || err == nil
+			t.mutex.Unlock()
+			if first {
+				t.since(&t.connectStart, PhaseConnect)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mutex.Lock()
+			t.tlsStart = time.Now()
+			t.mutex.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.since(&t.tlsStart, PhaseTLS)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mutex.Lock()
+			t.wroteRequest = time.Now()
+			t.mutex.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mutex.Lock()
+			t.firstByte = time.Now()
+			t.mutex.Unlock()
+			t.since(&t.wroteRequest, PhaseFirstByte)
+		},
+	}
+}
+
+// since reports the time elapsed from *start as phase, if start was recorded.
+func (t *phaseTimer) since(start *time.Time, phase string) {
+	t.mutex.Lock()
+	begin := *start
+	t.mutex.Unlock()
+	if !begin.IsZero() {
+		t.recorder.observe(phase, time.Since(begin))
+	}
+}
+
+// timedBody reports the body phase when the body is fully read or closed,
+// and cancels the request when no data arrives within the read timeout.
+type timedBody struct {
+	io.ReadCloser
+	timer  *phaseTimer
+	cancel context.CancelFunc
+
+	readTimeout time.Duration
+	idle        *time.Timer
+	timedOut    int32
+
+	done sync.Once
+}
+
+// newTimedBody wraps body. cancel aborts the request.
+func newTimedBody(body io.ReadCloser, timer *phaseTimer, readTimeout time.Duration, cancel context.CancelFunc) *timedBody {
+	b := &timedBody{ReadCloser: body, timer: timer, cancel: cancel, readTimeout: readTimeout}
+	if readTimeout > 0 {
+		b.idle = time.AfterFunc(readTimeout, func() {
+			atomic.StoreInt32(&b.timedOut, 1)
+			cancel()
+		})
+	}
+	return b
+}
+
+// Read reads from the body, restarting the idle timer on data.
+func (b *timedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if atomic.LoadInt32(&b.timedOut) == 1 {
+		b.finish()
+		return n, fmt.Errorf("%w (%s)", ErrReadIdleTimeout, b.readTimeout)
+	}
+	if n > 0 && b.idle != nil {
+		b.idle.Reset(b.readTimeout)
+	}
+	if err != nil {
+		b.finish()
+	}
+	return n, err
+}
+
+// Close closes the body and ends the request.
+func (b *timedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish()
+	return err
+}
+
+// finish reports the body phase once and releases the request.
+func (b *timedBody) finish() {
+	b.done.Do(func() {
+		if b.idle != nil {
+			b.idle.Stop()
+		}
+		b.timer.since(&b.timer.firstByte, PhaseBody)
+		b.cancel()
+	})
+}
@@ -0,0 +1,188 @@
+package providers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	// defaultMaxIdleConnsPerHost replaces net/http's default of 2, which makes
+	// concurrent requests to one provider open and tear down connections.
+	defaultMaxIdleConnsPerHost = 32
+
+	// defaultDialTimeout and defaultKeepAlive match net/http's default dialer.
+	defaultDialTimeout = 30 * time.Second
+	defaultKeepAlive   = 30 * time.Second
+)
+
+// TLSConfig holds custom TLS settings for a provider's outbound connections.
+type TLSConfig struct {
+	CABundle           string `mapstructure:"ca_bundle"` // PEM file with additional trusted CAs
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"`
+	ServerName         string `mapstructure:"server_name"`
+}
+
+// TransportConfig tunes the connection pool and protocol of a provider's
+// HTTP transport. Zero values keep the defaults.
+type TransportConfig struct {
+	MaxIdleConns          int           `mapstructure:"max_idle_conns"`          // across all hosts, 0 = 100
+	MaxIdleConnsPerHost   int           `mapstructure:"max_idle_conns_per_host"` // 0 = 32
+	MaxConnsPerHost       int           `mapstructure:"max_conns_per_host"`      // 0 = unlimited
+	IdleConnTimeout       time.Duration `mapstructure:"idle_conn_timeout"`       // 0 = 90s
+	DialTimeout           time.Duration `mapstructure:"dial_timeout"`            // 0 = 30s
+	TLSHandshakeTimeout   time.Duration `mapstructure:"tls_handshake_timeout"`   // 0 = 10s
+	ResponseHeaderTimeout time.Duration `mapstructure:"response_header_timeout"` // 0 = no limit beyond timeout
+	ReadIdleTimeout       time.Duration `mapstructure:"read_idle_timeout"`       // max gap between response body reads, e.g. stream chunks; 0 = no limit
+	KeepAlive             time.Duration `mapstructure:"keep_alive"`              // TCP keep-alive period, 0 = 30s, negative disables
+	DisableKeepAlives     bool          `mapstructure:"disable_keep_alives"`     // close connections after each request
+	HTTP2                 *bool         `mapstructure:"http2"`                   // unset or true negotiates HTTP/2, false forces HTTP/1.1
+
+	// Compression lists the response encodings offered in Accept-Encoding, in
+	// order of preference: gzip, deflate, identity, or any registered with
+	// RegisterContentDecoder. Empty offers gzip.
+	Compression []string `mapstructure:"compression"`
+
+	// DNS controls resolution of the provider's host and racing of
+	// connections to its addresses.
+	DNS DNSConfig `mapstructure:"dns"`
+}
+
+// newHTTPClient builds the HTTP client for a provider, applying its egress
+// proxy and TLS settings, its response compression, its cloud credentials and
+// its hooks. Each provider gets its own transport so proxies, trust settings
+// and credentials do not leak between providers. Response sizes are counted
+// into bandwidth and request phases reported to phases.
+func newHTTPClient(config ProviderConfig, bandwidth *bandwidthCounter, phases *phaseRecorder) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if err := applyTransportConfig(transport, config.Transport); err != nil {
+		return nil, err
+	}
+
+	if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy_url: %w", err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := buildTLSConfig(config.TLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	// Compression is negotiated by compressionTransport, which measures it
+	transport.DisableCompression = true
+	roundTripper := newTimingTransport(phases, config.Transport.ReadIdleTimeout, transport)
+	roundTripper, err = newCompressionTransport(config.Transport.Compression, bandwidth, roundTripper)
+	if err != nil {
+		return nil, err
+	}
+
+	roundTripper, err = NewCredentialsTransport(config.Credentials, roundTripper)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials: %w", err)
+	}
+	roundTripper, err = newHookTransport(config.Name, config.Hooks, roundTripper)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Timeout:   config.Timeout,
+		Transport: roundTripper,
+	}, nil
+}
+
+// applyTransportConfig applies the connection pool, timeout, DNS and protocol
+// settings.
+func applyTransportConfig(transport *http.Transport, config TransportConfig) error {
+	dialer := &net.Dialer{Timeout: defaultDialTimeout, KeepAlive: defaultKeepAlive}
+	if config.DialTimeout > 0 {
+		dialer.Timeout = config.DialTimeout
+	}
+	if config.KeepAlive != 0 {
+		dialer.KeepAlive = config.KeepAlive
+	}
+	transport.DialContext = dialer.DialContext
+	if config.DNS.enabled() {
+		dnsDialer, err := newDNSDialer(config.DNS, dialer)
+		if err != nil {
+			return err
+		}
+		transport.DialContext = dnsDialer.DialContext
+	}
+
+	transport.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	if config.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	}
+	if config.MaxIdleConns > 0 {
+		transport.MaxIdleConns = config.MaxIdleConns
+	}
+	if config.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = config.MaxConnsPerHost
+	}
+	if config.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = config.IdleConnTimeout
+	}
+	if config.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = config.TLSHandshakeTimeout
+	}
+	if config.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = config.ResponseHeaderTimeout
+	}
+	transport.DisableKeepAlives = config.DisableKeepAlives
+
+	if config.HTTP2 != nil && !*config.HTTP2 {
+		// A non-nil, empty TLSNextProto disables the bundled HTTP/2 support
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	return nil
+}
+
+// buildTLSConfig returns the TLS configuration for the settings, or nil to use the defaults.
+func buildTLSConfig(config TLSConfig) (*tls.Config, error) {
+	if config.CABundle == "" && !config.InsecureSkipVerify && config.ServerName == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+		ServerName:         config.ServerName,
+	}
+
+	if config.CABundle != "" {
+		pem, err := os.ReadFile(config.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", config.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
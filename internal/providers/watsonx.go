@@ -0,0 +1,348 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/sethvargo/go-retry"
+)
+
+const (
+	// defaultWatsonxIAMURL is the IBM Cloud IAM token endpoint.
+	defaultWatsonxIAMURL = "https://iam.cloud.ibm.com/identity/token"
+
+	// defaultWatsonxAPIVersion is the watsonx.ai API version date sent with each request.
+	defaultWatsonxAPIVersion = "2024-05-31"
+
+	// watsonxTokenRefreshMargin refreshes IAM tokens this long before they expire.
+	watsonxTokenRefreshMargin = 60 * time.Second
+)
+
+// WatsonxProvider implements the Provider interface for IBM watsonx.ai.
+type WatsonxProvider struct {
+	*BaseProvider
+	client *http.Client
+
+	tokenMutex  sync.Mutex
+	accessToken string
+	tokenExpiry time.Time
+}
+
+// watsonxChatResponse is the response body of the text chat endpoint.
+type watsonxChatResponse struct {
+	ID      string `json:"id"`
+	ModelID string `json:"model_id"`
+	Created int64  `json:"created"`
+	Choices []struct {
+		Index   int `json:"index"`
+		Message struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// watsonxTokenResponse is the response body of the IAM token endpoint.
+type watsonxTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Expiration  int64  `json:"expiration"`
+}
+
+// NewWatsonxProvider creates a new watsonx.ai provider instance.
+func NewWatsonxProvider(config ProviderConfig) (Provider, error) {
+	if config.IAMURL == "" {
+		config.IAMURL = defaultWatsonxIAMURL
+	}
+	if config.APIVersion == "" {
+		config.APIVersion = defaultWatsonxAPIVersion
+	}
+
+	base := NewBaseProvider(config)
+	client, err := newHTTPClient(config, base.bandwidth, base.phases)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WatsonxProvider{
+		BaseProvider: base,
+		client:       client,
+	}, nil
+}
+
+// GetModels returns the list of available watsonx.ai models.
+func (p *WatsonxProvider) GetModels() ([]string, error) {
+	// For now, return a static list. In production, this would call the foundation model specs endpoint.
+	return p.withFineTunes([]string{
+		"ibm/granite-3-8b-instruct",
+		"ibm/granite-3-2b-instruct",
+		"ibm/granite-13b-chat-v2",
+		"meta-llama/llama-3-1-70b-instruct",
+		"meta-llama/llama-3-1-8b-instruct",
+		"mistralai/mixtral-8x7b-instruct-v01",
+	}), nil
+}
+
+// GetCostEstimate returns an estimated cost for the request.
+func (p *WatsonxProvider) GetCostEstimate(req models.ChatRequest) (float64, error) {
+	// Prefer configured pricing from the model catalog
+	if cost, ok := p.catalogCostEstimate(req); ok {
+		return cost, nil
+	}
+
+	// Fall back to simplified cost estimation based on model and token count
+	model := req.Model
+	var costPer1kTokens float64
+
+	switch {
+	case strings.Contains(model, "70b"), strings.Contains(model, "mixtral"):
+		costPer1kTokens = 0.0018
+	case strings.Contains(model, "granite"):
+		costPer1kTokens = 0.0002
+	default:
+		costPer1kTokens = 0.0006
+	}
+
+	inputTokens, outputTokens := p.estimateTokens(req)
+	return float64(inputTokens+outputTokens) * costPer1kTokens / 1000, nil
+}
+
+// GetLatencyEstimate returns an estimated latency for the request.
+func (p *WatsonxProvider) GetLatencyEstimate(req models.ChatRequest) (time.Duration, error) {
+	// Base latency + per-token latency
+	baseLatency := 350 * time.Millisecond
+	perTokenLatency := 15 * time.Millisecond
+
+	// Prompt tokens are processed in parallel, so they add far less latency
+	// than generated tokens
+	inputTokens, outputTokens := p.estimateTokens(req)
+	return baseLatency + time.Duration(inputTokens)*perTokenLatency/20 + time.Duration(outputTokens)*perTokenLatency, nil
+}
+
+// CreateChatCompletion creates a chat completion using the watsonx.ai text chat API.
+func (p *WatsonxProvider) CreateChatCompletion(ctx context.Context, req models.ChatRequest) (*models.ChatResponse, error) {
+	if err := p.CheckContextWindow(req); err != nil {
+		return nil, err
+	}
+
+	release, err := p.acquireSlot(ctx, req.RequestID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if p.config.ProjectID == "" {
+		return nil, &models.ProviderError{
+			StatusCode: 400,
+			Err:        fmt.Errorf("watsonx provider requires project_id"),
+			Provider:   p.GetName(),
+			RequestID:  req.RequestID,
+		}
+	}
+
+	// Convert to watsonx format
+	watsonxReq := p.convertToWatsonxRequest(req)
+
+	// Implement retry logic
+	var response *models.ChatResponse
+	err = retry.Do(ctx, retry.WithMaxRetries(uint64(p.config.MaxRetries), retry.NewConstant(p.config.RetryDelay)), func(ctx context.Context) error {
+		var err error
+		response, err = p.makeWatsonxRequest(ctx, watsonxReq)
+		if err != nil {
+			// Check if error is retryable
+			if p.isRetryableError(err) {
+				return retry.RetryableError(err)
+			}
+			return err
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, &models.ProviderError{
+			StatusCode: statusCodeOf(err, 500),
+			Err:        err,
+			Provider:   p.GetName(),
+			RequestID:  req.RequestID,
+			Retryable:  p.isRetryableError(err),
+		}
+	}
+
+	response.RequestID = req.RequestID
+	return response, nil
+}
+
+// Prewarm opens connections to the watsonx API ahead of traffic.
+func (p *WatsonxProvider) Prewarm(ctx context.Context, connections int) (int, error) {
+	return prewarmConnections(ctx, p.client, p.config.BaseURL, connections)
+}
+
+// Close performs cleanup for the watsonx provider.
+func (p *WatsonxProvider) Close() error {
+	if p.client != nil {
+		p.client.CloseIdleConnections()
+	}
+	return p.BaseProvider.Close()
+}
+
+// convertToWatsonxRequest converts our unified request to watsonx format.
+func (p *WatsonxProvider) convertToWatsonxRequest(req models.ChatRequest) map[string]interface{} {
+	messages := make([]map[string]interface{}, len(req.Messages))
+	for i, msg := range req.Messages {
+		messages[i] = map[string]interface{}{
+			"role":    msg.Role,
+			"content": msg.Content,
+		}
+		if msg.Name != "" {
+			messages[i]["name"] = msg.Name
+		}
+	}
+
+	watsonxReq := map[string]interface{}{
+		"model_id":    req.Model,
+		"project_id":  p.config.ProjectID,
+		"messages":    messages,
+		"temperature": req.Temperature,
+	}
+
+	if req.MaxTokens > 0 {
+		watsonxReq["max_tokens"] = req.MaxTokens
+	}
+	if req.TopP > 0 {
+		watsonxReq["top_p"] = req.TopP
+	}
+	if len(req.Stop) > 0 {
+		watsonxReq["stop"] = req.Stop
+	}
+	if req.PresencePenalty != 0 {
+		watsonxReq["presence_penalty"] = req.PresencePenalty
+	}
+	if req.FrequencyPenalty != 0 {
+		watsonxReq["frequency_penalty"] = req.FrequencyPenalty
+	}
+	if req.ResponseFormat != nil {
+		watsonxReq["response_format"] = req.ResponseFormat
+	}
+
+	return watsonxReq
+}
+
+// makeWatsonxRequest makes the HTTP request to the watsonx.ai text chat endpoint.
+func (p *WatsonxProvider) makeWatsonxRequest(ctx context.Context, req map[string]interface{}) (*models.ChatResponse, error) {
+	token, err := p.getAccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/ml/v1/text/chat?version=%s",
+		strings.TrimRight(p.config.BaseURL, "/"), url.QueryEscape(p.config.APIVersion))
+
+	var watsonxResp watsonxChatResponse
+	_, err = doJSONRequest(ctx, p.client, http.MethodPost, endpoint, p.requestHeaders(map[string]string{
+		"Authorization": "Bearer " + token,
+	}), req, &watsonxResp)
+	if err != nil {
+		if statusCodeOf(err, 0) == http.StatusUnauthorized {
+			// Force a token refresh on the next attempt
+			p.invalidateAccessToken()
+		}
+		return nil, err
+	}
+
+	return p.convertFromWatsonxResponse(watsonxResp), nil
+}
+
+// convertFromWatsonxResponse converts a watsonx response to our unified format.
+func (p *WatsonxProvider) convertFromWatsonxResponse(resp watsonxChatResponse) *models.ChatResponse {
+	choices := make([]models.Choice, len(resp.Choices))
+	for i, choice := range resp.Choices {
+		choices[i] = models.Choice{
+			Index: choice.Index,
+			Message: models.Message{
+				Role:    choice.Message.Role,
+				Content: models.TextContent(choice.Message.Content),
+			},
+			FinishReason: choice.FinishReason,
+		}
+	}
+
+	created := resp.Created
+	if created == 0 {
+		created = time.Now().Unix()
+	}
+
+	return &models.ChatResponse{
+		ID:      resp.ID,
+		Model:   resp.ModelID,
+		Choices: choices,
+		Usage: models.Usage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+			TotalTokens:      resp.Usage.TotalTokens,
+		},
+		Created:  created,
+		Provider: p.GetName(),
+	}
+}
+
+// getAccessToken returns a cached IAM access token, exchanging the API key for a new one when needed.
+func (p *WatsonxProvider) getAccessToken(ctx context.Context) (string, error) {
+	p.tokenMutex.Lock()
+	defer p.tokenMutex.Unlock()
+
+	if p.accessToken != "" && time.Now().Add(watsonxTokenRefreshMargin).Before(p.tokenExpiry) {
+		return p.accessToken, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ibm:params:oauth:grant-type:apikey")
+	form.Set("apikey", p.config.APIKey)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.IAMURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create IAM token request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+
+	var tokenResp watsonxTokenResponse
+	if _, err := doRequest(p.client, httpReq, &tokenResp); err != nil {
+		return "", fmt.Errorf("IAM token exchange failed: %w", err)
+	}
+
+	p.accessToken = tokenResp.AccessToken
+	switch {
+	case tokenResp.Expiration > 0:
+		p.tokenExpiry = time.Unix(tokenResp.Expiration, 0)
+	case tokenResp.ExpiresIn > 0:
+		p.tokenExpiry = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	default:
+		p.tokenExpiry = time.Now().Add(time.Hour)
+	}
+
+	return p.accessToken, nil
+}
+
+// invalidateAccessToken discards the cached IAM token.
+func (p *WatsonxProvider) invalidateAccessToken() {
+	p.tokenMutex.Lock()
+	p.accessToken = ""
+	p.tokenMutex.Unlock()
+}
+
+// isRetryableError determines if an error should trigger a retry.
+func (p *WatsonxProvider) isRetryableError(err error) bool {
+	return isRetryableStatus(err)
+}
@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// localBucket is an in-process token bucket. It also counts the requests it
+// admitted since the last reconciliation, and can be held empty until the end
+// of a window once reconciliation finds the fleet over the limit.
+type localBucket struct {
+	capacity float64
+	rate     float64 // tokens per second
+
+	mutex     sync.Mutex
+	tokens    float64
+	updatedAt time.Time
+
+	pending   int       // admitted since the last reconciliation
+	heldUntil time.Time // deny until this time, set by reconciliation
+
+	// Overshoot already reported for the current window
+	reportedWindow    time.Time
+	reportedOvershoot int
+}
+
+// newLocalBucket creates a full bucket.
+func newLocalBucket(capacity, rate float64) *localBucket {
+	return &localBucket{
+		capacity:  capacity,
+		rate:      rate,
+		tokens:    capacity,
+		updatedAt: time.Now(),
+	}
+}
+
+// take consumes a token if one is available. Otherwise it returns how long
+// until the next token.
+func (b *localBucket) take(now time.Time) (bool, time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if now.Before(b.heldUntil) {
+		return false, b.heldUntil.Sub(now)
+	}
+
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+elapsed*b.rate)
+		b.updatedAt = now
+	}
+
+	if b.tokens < 1 {
+		wait := (1 - b.tokens) / b.rate
+		return false, time.Duration(wait * float64(time.Second))
+	}
+	b.tokens--
+	b.pending++
+	return true, 0
+}
+
+// drain returns and resets the count of requests admitted since the last call.
+func (b *localBucket) drain() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	pending := b.pending
+	b.pending = 0
+	return pending
+}
+
+// restore adds back admitted requests that could not be reconciled.
+func (b *localBucket) restore(n int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.pending += n
+}
+
+// reportOvershoot records the overshoot seen for a window and returns how
+// much of it has not been reported before.
+func (b *localBucket) reportOvershoot(window time.Time, overshoot int) int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !b.reportedWindow.Equal(window) {
+		b.reportedWindow = window
+		b.reportedOvershoot = 0
+	}
+	if overshoot <= b.reportedOvershoot {
+		return 0
+	}
+	delta := overshoot - b.reportedOvershoot
+	b.reportedOvershoot = overshoot
+	return delta
+}
+
+// hold denies all requests until the given time.
+func (b *localBucket) hold(until time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if until.After(b.heldUntil) {
+		b.heldUntil = until
+	}
+}
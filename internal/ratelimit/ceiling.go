@@ -0,0 +1,205 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/observability"
+)
+
+// What the ceiling does with requests over it.
+const (
+	OverflowReject = "reject" // answer 429 at once
+	OverflowQueue  = "queue"  // wait up to the queue timeout for room
+)
+
+// Names of the ceiling's limits, as reported in decisions and metrics.
+const (
+	ceilingRequests = "router_requests"
+	ceilingStreams  = "router_streams"
+	ceilingMode     = "ceiling"
+)
+
+// defaultQueueTimeout is how long a queued request waits for room when not
+// configured.
+const defaultQueueTimeout = 5 * time.Second
+
+// CeilingConfig holds configuration for the router-wide inbound ceiling. It
+// caps the traffic of all tenants and keys together, so that no client can
+// overload the routing tier or its upstream providers for everyone else.
+type CeilingConfig struct {
+	Enabled           bool    `mapstructure:"enabled"`
+	RequestsPerSecond float64 `mapstructure:"requests_per_second"` // 0 leaves the request rate uncapped
+	Burst             int     `mapstructure:"burst"`               // requests admitted at once above the rate; defaults to requests_per_second
+	MaxStreams        int     `mapstructure:"max_streams"`         // concurrent streamed responses; 0 leaves them uncapped
+
+	Overflow     string        `mapstructure:"overflow"`      // reject (default) or queue
+	QueueTimeout time.Duration `mapstructure:"queue_timeout"` // longest a queued request waits; default 5s
+	MaxQueued    int           `mapstructure:"max_queued"`    // requests queued at once beyond which overflow is rejected; 0 is unbounded
+}
+
+// Ceiling admits requests and streams under the router-wide ceiling.
+type Ceiling struct {
+	config  CeilingConfig
+	bucket  *localBucket  // nil when the request rate is uncapped
+	streams chan struct{} // one slot per open stream; nil when uncapped
+	metrics *observability.Metrics
+
+	mutex  sync.Mutex
+	queued int
+}
+
+// NewCeiling validates the ceiling's configuration and creates it.
+func NewCeiling(config CeilingConfig, metrics *observability.Metrics) (*Ceiling, error) {
+	if config.RequestsPerSecond < 0 || config.Burst < 0 || config.MaxStreams < 0 || config.MaxQueued < 0 {
+		return nil, fmt.Errorf("router ceiling limits must not be negative")
+	}
+	switch config.Overflow {
+	case "":
+		config.Overflow = OverflowReject
+	case OverflowReject, OverflowQueue:
+	default:
+		return nil, fmt.Errorf("router ceiling has unknown overflow %q", config.Overflow)
+	}
+	if config.QueueTimeout <= 0 {
+		config.QueueTimeout = defaultQueueTimeout
+	}
+
+	c := &Ceiling{config: config, metrics: metrics}
+	if config.RequestsPerSecond > 0 {
+		burst := float64(config.Burst)
+		if burst < 1 {
+			burst = config.RequestsPerSecond
+		}
+		if burst < 1 {
+			burst = 1
+		}
+		c.bucket = newLocalBucket(burst, config.RequestsPerSecond)
+	}
+	if config.MaxStreams > 0 {
+		c.streams = make(chan struct{}, config.MaxStreams)
+	}
+	return c, nil
+}
+
+// Admit checks a request against the request rate. Over it, the request is
+// denied or, when overflow is queued, waits for a token until the queue
+// timeout or the request's end.
+func (c *Ceiling) Admit(ctx context.Context) Decision {
+	if c.bucket == nil {
+		return Decision{Allowed: true}
+	}
+
+	start := time.Now()
+	allowed, retryAfter := c.bucket.take(start)
+	if allowed {
+		c.record(ceilingRequests, "allowed", start)
+		return Decision{Allowed: true}
+	}
+	if !c.enqueue() {
+		c.record(ceilingRequests, "denied", start)
+		return Decision{Limit: ceilingRequests, RetryAfter: retryAfter}
+	}
+	defer c.dequeue()
+
+	deadline := start.Add(c.config.QueueTimeout)
+	for {
+		wait := retryAfter
+		if remaining := time.Until(deadline); remaining < wait {
+			wait = remaining
+		}
+		if wait <= 0 {
+			break
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			c.record(ceilingRequests, "denied", start)
+			return Decision{Limit: ceilingRequests, RetryAfter: retryAfter}
+		case <-timer.C:
+		}
+
+		if allowed, retryAfter = c.bucket.take(time.Now()); allowed {
+			c.record(ceilingRequests, "queued", start)
+			return Decision{Allowed: true}
+		}
+	}
+	c.record(ceilingRequests, "denied", start)
+	return Decision{Limit: ceilingRequests, RetryAfter: retryAfter}
+}
+
+// AdmitStream takes a stream slot. When none is free, the stream is denied
+// or, when overflow is queued, waits for one until the queue timeout or the
+// request's end. release must be called once the stream ends; it is a no-op
+// for denied streams.
+func (c *Ceiling) AdmitStream(ctx context.Context) (release func(), decision Decision) {
+	if c.streams == nil {
+		return func() {}, Decision{Allowed: true}
+	}
+
+	start := time.Now()
+	release = func() { <-c.streams }
+	select {
+	case c.streams <- struct{}{}:
+		c.record(ceilingStreams, "allowed", start)
+		return release, Decision{Allowed: true}
+	default:
+	}
+
+	// Streams last for seconds, so a second is as good a hint as any
+	denied := Decision{Limit: ceilingStreams, RetryAfter: time.Second}
+	if !c.enqueue() {
+		c.record(ceilingStreams, "denied", start)
+		return func() {}, denied
+	}
+	defer c.dequeue()
+
+	timer := time.NewTimer(c.config.QueueTimeout)
+	defer timer.Stop()
+	select {
+	case c.streams <- struct{}{}:
+		c.record(ceilingStreams, "queued", start)
+		return release, Decision{Allowed: true}
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	c.record(ceilingStreams, "denied", start)
+	return func() {}, denied
+}
+
+// enqueue reserves a place in the queue, reporting false when overflow is
+// rejected or the queue is full.
+func (c *Ceiling) enqueue() bool {
+	if c.config.Overflow != OverflowQueue {
+		return false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.config.MaxQueued > 0 && c.queued >= c.config.MaxQueued {
+		return false
+	}
+	c.queued++
+	return true
+}
+
+// dequeue gives up a place in the queue.
+func (c *Ceiling) dequeue() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.queued--
+}
+
+// record records a ceiling decision. The result label is "allowed",
+// "queued" for admitted after waiting, or "denied".
+func (c *Ceiling) record(limit, result string, start time.Time) {
+	if c.metrics != nil {
+		c.metrics.RecordRateLimitDecision(limit, ceilingMode, result, time.Since(start))
+	}
+}
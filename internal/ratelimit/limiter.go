@@ -0,0 +1,211 @@
+// Package ratelimit enforces per-tenant request rate limits, either locally
+// on each instance or strictly across instances through Redis.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/observability"
+	"github.com/semantrix/semaroute/internal/redis"
+)
+
+// Accuracy modes for a limit.
+const (
+	// ModeLocal decides on each instance without a network round trip. With
+	// Redis configured, instances reconcile their counts every sync interval,
+	// so the fleet can overshoot the limit by at most one interval's traffic.
+	ModeLocal = "local"
+
+	// ModeStrict decides every request with an atomic token bucket in Redis.
+	ModeStrict = "strict"
+)
+
+// defaultSyncInterval is how often local limits reconcile with Redis when not configured.
+const defaultSyncInterval = time.Second
+
+// Config holds configuration for request rate limiting.
+type Config struct {
+	Enabled      bool           `mapstructure:"enabled"`
+	Redis        redis.Config   `mapstructure:"redis"`         // required for strict limits and local reconciliation
+	SyncInterval time.Duration  `mapstructure:"sync_interval"` // how often local limits reconcile
+	Limits       []LimitConfig  `mapstructure:"limits"`
+	Ceiling      CeilingConfig  `mapstructure:"ceiling"`  // router-wide, independent of enabled and limits
+	Priority     PriorityConfig `mapstructure:"priority"` // router-wide, independent of enabled and limits
+}
+
+// LimitConfig is one rate limit, applied to each matching tenant separately.
+type LimitConfig struct {
+	Name     string        `mapstructure:"name"`
+	Tenants  []string      `mapstructure:"tenants"`  // tenants the limit applies to; empty applies to all
+	Requests int           `mapstructure:"requests"` // requests allowed per window
+	Window   time.Duration `mapstructure:"window"`
+	Burst    int           `mapstructure:"burst"` // bucket size, defaults to requests
+	Mode     string        `mapstructure:"mode"`  // local (default) or strict
+}
+
+// Decision is the outcome of a rate limit check.
+type Decision struct {
+	Allowed    bool
+	Limit      string        // name of the limit that denied the request
+	RetryAfter time.Duration // when a denied request may be retried
+}
+
+// limit is a configured limit with its per-tenant state.
+type limit struct {
+	config  LimitConfig
+	tenants map[string]bool
+	rate    float64 // tokens per second
+
+	mutex   sync.Mutex
+	buckets map[string]*localBucket
+}
+
+// applies reports whether the limit covers the tenant.
+func (l *limit) applies(tenant string) bool {
+	return len(l.tenants) == 0 || l.tenants[tenant]
+}
+
+// bucket returns the local bucket for a tenant, creating it on first use.
+func (l *limit) bucket(tenant string) *localBucket {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	bucket, exists := l.buckets[tenant]
+	if !exists {
+		bucket = newLocalBucket(float64(l.config.Burst), l.rate)
+		l.buckets[tenant] = bucket
+	}
+	return bucket
+}
+
+// Limiter checks requests against the configured limits.
+type Limiter struct {
+	limits  []*limit
+	redis   *redis.Client
+	metrics *observability.Metrics
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewLimiter validates the limits and, when Redis is configured, starts the
+// reconciliation of local limits.
+func NewLimiter(config Config, metrics *observability.Metrics) (*Limiter, error) {
+	l := &Limiter{metrics: metrics}
+
+	if config.Redis.Address != "" {
+		client, err := redis.NewClient(config.Redis)
+		if err != nil {
+			return nil, err
+		}
+		l.redis = client
+	}
+
+	names := make(map[string]bool)
+	for _, limitConfig := range config.Limits {
+		if limitConfig.Name == "" {
+			return nil, fmt.Errorf("rate limit needs a name")
+		}
+		if names[limitConfig.Name] {
+			return nil, fmt.Errorf("duplicate rate limit %q", limitConfig.Name)
+		}
+		names[limitConfig.Name] = true
+
+		if limitConfig.Requests <= 0 || limitConfig.Window <= 0 {
+			return nil, fmt.Errorf("rate limit %q needs positive requests and window", limitConfig.Name)
+		}
+		if limitConfig.Burst <= 0 {
+			limitConfig.Burst = limitConfig.Requests
+		}
+		switch limitConfig.Mode {
+		case "":
+			limitConfig.Mode = ModeLocal
+		case ModeLocal:
+		case ModeStrict:
+			if l.redis == nil {
+				return nil, fmt.Errorf("rate limit %q is strict but no redis address is configured", limitConfig.Name)
+			}
+		default:
+			return nil, fmt.Errorf("rate limit %q has unknown mode %q", limitConfig.Name, limitConfig.Mode)
+		}
+
+		tenants := make(map[string]bool, len(limitConfig.Tenants))
+		for _, tenant := range limitConfig.Tenants {
+			tenants[tenant] = true
+		}
+		l.limits = append(l.limits, &limit{
+			config:  limitConfig,
+			tenants: tenants,
+			rate:    float64(limitConfig.Requests) / limitConfig.Window.Seconds(),
+			buckets: make(map[string]*localBucket),
+		})
+	}
+
+	if l.redis != nil {
+		interval := config.SyncInterval
+		if interval <= 0 {
+			interval = defaultSyncInterval
+		}
+		l.stop = make(chan struct{})
+		l.done = make(chan struct{})
+		go l.syncLoop(interval)
+	}
+
+	return l, nil
+}
+
+// Allow checks a request from the tenant against every limit that covers it.
+// Checking stops at the first limit that denies the request.
+func (l *Limiter) Allow(ctx context.Context, tenant string) Decision {
+	for _, lim := range l.limits {
+		if !lim.applies(tenant) {
+			continue
+		}
+
+		start := time.Now()
+		allowed, retryAfter, result := l.check(ctx, lim, tenant)
+		if l.metrics != nil {
+			l.metrics.RecordRateLimitDecision(lim.config.Name, lim.config.Mode, result, time.Since(start))
+		}
+		if !allowed {
+			return Decision{Limit: lim.config.Name, RetryAfter: retryAfter}
+		}
+	}
+	return Decision{Allowed: true}
+}
+
+// check applies one limit. The result label is "allowed" or "denied", or
+// "fallback" when a strict limit was decided locally because Redis failed.
+func (l *Limiter) check(ctx context.Context, lim *limit, tenant string) (bool, time.Duration, string) {
+	if lim.config.Mode == ModeStrict {
+		allowed, retryAfter, err := l.takeStrict(ctx, lim, tenant)
+		if err == nil {
+			return allowed, retryAfter, result(allowed)
+		}
+		allowed, retryAfter = lim.bucket(tenant).take(time.Now())
+		return allowed, retryAfter, "fallback"
+	}
+
+	allowed, retryAfter := lim.bucket(tenant).take(time.Now())
+	return allowed, retryAfter, result(allowed)
+}
+
+// result returns the metric label for a decision.
+func result(allowed bool) string {
+	if allowed {
+		return "allowed"
+	}
+	return "denied"
+}
+
+// Close stops reconciliation and closes the Redis connections.
+func (l *Limiter) Close() error {
+	if l.redis == nil {
+		return nil
+	}
+	close(l.stop)
+	<-l.done
+	return l.redis.Close()
+}
@@ -0,0 +1,207 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/observability"
+)
+
+// Priority tiers, highest first. Deferred requests are in the low tier.
+var priorityTiers = []string{models.PriorityHigh, models.PriorityNormal, models.PriorityLow}
+
+// TierConfig holds what a priority tier's requests do under saturation.
+type TierConfig struct {
+	MaxQueued    int           `mapstructure:"max_queued"`    // requests of the tier waiting at once; 0 sheds them at once
+	QueueTimeout time.Duration `mapstructure:"queue_timeout"` // longest a request of the tier waits; default 5s
+}
+
+// PriorityConfig holds configuration for priority tiers. Up to MaxInFlight
+// requests are served at once; beyond that the router is saturated, and
+// requests wait in their tier's queue or are shed. A freed slot goes to the
+// longest waiting request of the highest tier.
+type PriorityConfig struct {
+	Enabled     bool                  `mapstructure:"enabled"`
+	MaxInFlight int                   `mapstructure:"max_in_flight"`
+	Tiers       map[string]TierConfig `mapstructure:"tiers"` // by tier: high, normal or low
+}
+
+// priorityWaiter is a request waiting for a slot.
+type priorityWaiter struct {
+	granted chan struct{} // closed when the slot is handed over
+}
+
+// PriorityGate admits requests by priority tier under the in-flight limit.
+type PriorityGate struct {
+	config  PriorityConfig
+	metrics *observability.Metrics
+
+	mutex    sync.Mutex
+	inFlight int
+	queues   map[string][]*priorityWaiter
+}
+
+// NewPriorityGate validates the configuration of the priority tiers and
+// creates the gate.
+func NewPriorityGate(config PriorityConfig, metrics *observability.Metrics) (*PriorityGate, error) {
+	if config.MaxInFlight <= 0 {
+		return nil, fmt.Errorf("priority tiers need a positive max_in_flight")
+	}
+	tiers := make(map[string]TierConfig, len(priorityTiers))
+	for tier, tierConfig := range config.Tiers {
+		if !isPriorityTier(tier) {
+			return nil, fmt.Errorf("unknown priority tier %q", tier)
+		}
+		if tierConfig.MaxQueued < 0 {
+			return nil, fmt.Errorf("priority tier %s: max_queued must not be negative", tier)
+		}
+		if tierConfig.QueueTimeout <= 0 {
+			tierConfig.QueueTimeout = defaultQueueTimeout
+		}
+		tiers[tier] = tierConfig
+	}
+	for _, tier := range priorityTiers {
+		if _, exists := tiers[tier]; !exists {
+			tiers[tier] = TierConfig{QueueTimeout: defaultQueueTimeout}
+		}
+	}
+	config.Tiers = tiers
+
+	return &PriorityGate{config: config, metrics: metrics, queues: make(map[string][]*priorityWaiter)}, nil
+}
+
+// isPriorityTier reports whether tier is a priority tier.
+func isPriorityTier(tier string) bool {
+	for _, known := range priorityTiers {
+		if tier == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Tier returns the tier of a request priority. Deferred requests are low;
+// others without a known tier are normal.
+func Tier(priority string) string {
+	if priority == models.PriorityDeferred {
+		return models.PriorityLow
+	}
+	if isPriorityTier(priority) {
+		return priority
+	}
+	return models.PriorityNormal
+}
+
+// Admit takes a slot for a request of the tier. When none is free, the
+// request waits in the tier's queue until a slot is handed to it, the tier's
+// queue timeout or the request's end; when the queue is full it is shed at
+// once. release must be called once the request is served; it is a no-op for
+// denied requests.
+func (g *PriorityGate) Admit(ctx context.Context, tier string) (release func(), decision Decision) {
+	tier = Tier(tier)
+	tierConfig := g.config.Tiers[tier]
+	denied := Decision{Limit: "priority_" + tier, RetryAfter: time.Second}
+	start := time.Now()
+
+	g.mutex.Lock()
+	if g.inFlight < g.config.MaxInFlight {
+		g.inFlight++
+		g.mutex.Unlock()
+		g.record(tier, "admitted", 0)
+		return g.release, Decision{Allowed: true}
+	}
+	if len(g.queues[tier]) >= tierConfig.MaxQueued {
+		g.mutex.Unlock()
+		g.record(tier, "shed", 0)
+		return func() {}, denied
+	}
+	waiter := &priorityWaiter{granted: make(chan struct{})}
+	g.queues[tier] = append(g.queues[tier], waiter)
+	g.recordDepth(tier)
+	g.mutex.Unlock()
+
+	timer := time.NewTimer(tierConfig.QueueTimeout)
+	defer timer.Stop()
+	select {
+	case <-waiter.granted:
+		g.record(tier, "queued", time.Since(start))
+		return g.release, Decision{Allowed: true}
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	g.mutex.Lock()
+	if !g.remove(tier, waiter) {
+		// The slot was handed over as the wait ended; pass it on
+		g.mutex.Unlock()
+		g.release()
+	} else {
+		g.mutex.Unlock()
+	}
+	g.record(tier, "expired", time.Since(start))
+	return func() {}, denied
+}
+
+// release hands the slot of a served request to the longest waiting request
+// of the highest tier, or frees it.
+func (g *PriorityGate) release() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for _, tier := range priorityTiers {
+		if queue := g.queues[tier]; len(queue) > 0 {
+			g.queues[tier] = queue[1:]
+			g.recordDepth(tier)
+			close(queue[0].granted)
+			return
+		}
+	}
+	g.inFlight--
+}
+
+// remove takes a waiter off its tier's queue, reporting false when it was
+// no longer there because a slot was handed to it. The caller must hold the
+// mutex.
+func (g *PriorityGate) remove(tier string, waiter *priorityWaiter) bool {
+	queue := g.queues[tier]
+	for i, queued := range queue {
+		if queued == waiter {
+			g.queues[tier] = append(queue[:i:i], queue[i+1:]...)
+			g.recordDepth(tier)
+			return true
+		}
+	}
+	return false
+}
+
+// Depths returns the number of requests waiting in each tier's queue.
+func (g *PriorityGate) Depths() map[string]int {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	depths := make(map[string]int, len(priorityTiers))
+	for _, tier := range priorityTiers {
+		depths[tier] = len(g.queues[tier])
+	}
+	return depths
+}
+
+// record records the outcome of a request of the tier: "admitted" at once,
+// "queued" for admitted after waiting, "shed" with its queue full, or
+// "expired" for given up waiting.
+func (g *PriorityGate) record(tier, result string, waited time.Duration) {
+	if g.metrics != nil {
+		g.metrics.RecordPriorityAdmission(tier, result, waited)
+	}
+}
+
+// recordDepth records the depth of a tier's queue. The caller must hold the
+// mutex.
+func (g *PriorityGate) recordDepth(tier string) {
+	if g.metrics != nil {
+		g.metrics.RecordPriorityQueueDepth(tier, len(g.queues[tier]))
+	}
+}
@@ -0,0 +1,235 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+func newTestGate(t *testing.T, maxInFlight int, tiers map[string]TierConfig) *PriorityGate {
+	t.Helper()
+	gate, err := NewPriorityGate(PriorityConfig{Enabled: true, MaxInFlight: maxInFlight, Tiers: tiers}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gate
+}
+
+// waitForDepths waits until the tiers' queues are as deep as want.
+func waitForDepths(t *testing.T, gate *PriorityGate, want map[string]int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		depths := gate.Depths()
+		matched := true
+		for tier, depth := range want {
+			if depths[tier] != depth {
+				matched = false
+			}
+		}
+		if matched {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("queue depths = %v, want %v", depths, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTier(t *testing.T) {
+	tests := []struct {
+		priority string
+		want     string
+	}{
+		{models.PriorityHigh, models.PriorityHigh},
+		{models.PriorityNormal, models.PriorityNormal},
+		{models.PriorityLow, models.PriorityLow},
+		{models.PriorityDeferred, models.PriorityLow},
+		{"", models.PriorityNormal},
+		{"urgent", models.PriorityNormal},
+	}
+	for _, test := range tests {
+		if got := Tier(test.priority); got != test.want {
+			t.Errorf("Tier(%q) = %q, want %q", test.priority, got, test.want)
+		}
+	}
+}
+
+func TestNewPriorityGateValidates(t *testing.T) {
+	tests := []struct {
+		name   string
+		config PriorityConfig
+	}{
+		{"no in-flight limit", PriorityConfig{}},
+		{"unknown tier", PriorityConfig{MaxInFlight: 1, Tiers: map[string]TierConfig{"urgent": {}}}},
+		{"negative max_queued", PriorityConfig{MaxInFlight: 1, Tiers: map[string]TierConfig{models.PriorityLow: {MaxQueued: -1}}}},
+	}
+	for _, test := range tests {
+		if _, err := NewPriorityGate(test.config, nil); err == nil {
+			t.Errorf("%s: NewPriorityGate() error = nil", test.name)
+		}
+	}
+}
+
+func TestPriorityGateShedsWithoutQueue(t *testing.T) {
+	for _, tier := range priorityTiers {
+		t.Run(tier, func(t *testing.T) {
+			// Tiers without max_queued shed at once when the gate is full
+			gate := newTestGate(t, 1, nil)
+			release, decision := gate.Admit(context.Background(), tier)
+			if !decision.Allowed {
+				t.Fatalf("first request denied: %+v", decision)
+			}
+
+			start := time.Now()
+			shedRelease, decision := gate.Admit(context.Background(), tier)
+			if decision.Allowed || decision.Limit != "priority_"+tier || decision.RetryAfter != time.Second {
+				t.Fatalf("Admit() on a full gate = %+v, want shed by priority_%s", decision, tier)
+			}
+			if waited := time.Since(start); waited > time.Second {
+				t.Fatalf("shed request waited %s", waited)
+			}
+			// Releasing a shed request frees nothing
+			shedRelease()
+			if _, decision := gate.Admit(context.Background(), tier); decision.Allowed {
+				t.Fatal("releasing a shed request freed a slot")
+			}
+
+			release()
+			next, decision := gate.Admit(context.Background(), tier)
+			if !decision.Allowed {
+				t.Fatalf("Admit() after release = %+v", decision)
+			}
+			next()
+		})
+	}
+}
+
+func TestPriorityGateHandsSlotsToHighestTier(t *testing.T) {
+	queued := TierConfig{MaxQueued: 2, QueueTimeout: time.Minute}
+	gate := newTestGate(t, 1, map[string]TierConfig{
+		models.PriorityHigh:   queued,
+		models.PriorityNormal: queued,
+		models.PriorityLow:    queued,
+	})
+	release, _ := gate.Admit(context.Background(), models.PriorityNormal)
+
+	// Lower tiers queue first, so the order of service is by tier and then
+	// by arrival
+	admitted := make(chan string, 5)
+	var wg sync.WaitGroup
+	enqueue := func(name, tier string, depths map[string]int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, decision := gate.Admit(context.Background(), tier)
+			if !decision.Allowed {
+				admitted <- name + " denied"
+				return
+			}
+			admitted <- name
+			release()
+		}()
+		waitForDepths(t, gate, depths)
+	}
+	enqueue("low-1", models.PriorityLow, map[string]int{models.PriorityLow: 1})
+	enqueue("deferred", models.PriorityDeferred, map[string]int{models.PriorityLow: 2})
+	enqueue("normal", models.PriorityNormal, map[string]int{models.PriorityNormal: 1})
+	enqueue("high-1", models.PriorityHigh, map[string]int{models.PriorityHigh: 1})
+	enqueue("high-2", models.PriorityHigh, map[string]int{models.PriorityHigh: 2})
+
+	release()
+	wg.Wait()
+	close(admitted)
+
+	var order []string
+	for name := range admitted {
+		order = append(order, name)
+	}
+	want := []string{"high-1", "high-2", "normal", "low-1", "deferred"}
+	if len(order) != len(want) {
+		t.Fatalf("admitted %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("admitted %v, want %v", order, want)
+		}
+	}
+	if depths := gate.Depths(); depths[models.PriorityHigh]+depths[models.PriorityNormal]+depths[models.PriorityLow] != 0 {
+		t.Fatalf("queue depths after serving = %v", depths)
+	}
+}
+
+func TestPriorityGateExpiryRacingGrant(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		cancel   bool
+		requests int
+	}{
+		{"queue timeout", time.Millisecond, false, 200},
+		{"request end", time.Minute, true, 200},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			const maxInFlight = 2
+			gate := newTestGate(t, maxInFlight, map[string]TierConfig{
+				models.PriorityNormal: {MaxQueued: test.requests, QueueTimeout: test.timeout},
+			})
+
+			// Slots are released just as waiters give up, so grants race
+			// expiries; each slot must end up either served or passed on
+			var inFlight, peak int32
+			var wg sync.WaitGroup
+			for i := 0; i < test.requests; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ctx, cancel := context.WithCancel(context.Background())
+					defer cancel()
+					if test.cancel {
+						time.AfterFunc(time.Millisecond, cancel)
+					}
+					release, decision := gate.Admit(ctx, models.PriorityNormal)
+					if !decision.Allowed {
+						return
+					}
+					current := atomic.AddInt32(&inFlight, 1)
+					for {
+						seen := atomic.LoadInt32(&peak)
+						if current <= seen || atomic.CompareAndSwapInt32(&peak, seen, current) {
+							break
+						}
+					}
+					time.Sleep(time.Millisecond)
+					atomic.AddInt32(&inFlight, -1)
+					release()
+				}()
+			}
+			wg.Wait()
+
+			if peak > maxInFlight {
+				t.Fatalf("%d requests in flight at once, want at most %d", peak, maxInFlight)
+			}
+			if depths := gate.Depths(); depths[models.PriorityNormal] != 0 {
+				t.Fatalf("queue depths after the race = %v", depths)
+			}
+			// No slot leaked: the gate admits up to its limit again
+			for i := 0; i < maxInFlight; i++ {
+				release, decision := gate.Admit(context.Background(), models.PriorityHigh)
+				if !decision.Allowed {
+					t.Fatalf("slot %d leaked: %+v", i, decision)
+				}
+				defer release()
+			}
+			if _, decision := gate.Admit(context.Background(), models.PriorityHigh); decision.Allowed {
+				t.Fatal("the gate admits more requests than max_in_flight after the race")
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// keyPrefix namespaces rate limit keys in Redis.
+const keyPrefix = "semaroute:ratelimit:"
+
+// tokenBucketScript atomically refills and takes one token from a bucket
+// stored as a hash. It uses the Redis clock so instances with skewed clocks
+// share one notion of time. ARGV: capacity, refill rate in tokens per
+// millisecond. Returns {allowed, retry after in milliseconds}.
+const tokenBucketScript = `
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacity / rate) + 1000)
+return {allowed, retry}
+`
+
+// takeStrict takes a token from the tenant's bucket in Redis.
+func (l *Limiter) takeStrict(ctx context.Context, lim *limit, tenant string) (bool, time.Duration, error) {
+	key := keyPrefix + lim.config.Name + ":" + tenant
+	ratePerMs := lim.rate / 1000
+
+	reply, err := l.redis.Do(ctx, "EVAL", tokenBucketScript, "1", key,
+		strconv.Itoa(lim.config.Burst), strconv.FormatFloat(ratePerMs, 'g', -1, 64))
+	if err != nil {
+		return false, 0, err
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return false, 0, fmt.Errorf("unexpected token bucket reply %v", reply)
+	}
+	allowed, _ := values[0].(int64)
+	retryMs, _ := values[1].(int64)
+	return allowed == 1, time.Duration(retryMs) * time.Millisecond, nil
+}
+
+// syncLoop reconciles local limits until the limiter is closed.
+func (l *Limiter) syncLoop(interval time.Duration) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.reconcile(context.Background())
+		}
+	}
+}
+
+// reconcile adds the requests each local bucket admitted to a shared counter
+// for the current window. Buckets whose counter has reached the limit are held
+// empty until the window ends. The amount by which the fleet admitted more
+// than the limit is the approximation error of local limiting.
+func (l *Limiter) reconcile(ctx context.Context) {
+	now := time.Now()
+
+	for _, lim := range l.limits {
+		if lim.config.Mode != ModeLocal {
+			continue
+		}
+
+		lim.mutex.Lock()
+		buckets := make(map[string]*localBucket, len(lim.buckets))
+		for tenant, bucket := range lim.buckets {
+			buckets[tenant] = bucket
+		}
+		lim.mutex.Unlock()
+
+		windowStart := now.Truncate(lim.config.Window)
+		windowEnd := windowStart.Add(lim.config.Window)
+		worstError := 0.0
+		reconciled := false
+
+		for tenant, bucket := range buckets {
+			key := fmt.Sprintf("%s%s:%s:%d", keyPrefix, lim.config.Name, tenant, windowStart.Unix())
+			admitted := bucket.drain()
+			total, err := l.addCount(ctx, key, admitted, 2*lim.config.Window)
+			if err != nil {
+				bucket.restore(admitted)
+				continue
+			}
+			reconciled = true
+
+			if total >= lim.config.Requests {
+				bucket.hold(windowEnd)
+			}
+
+			overshoot := total - lim.config.Requests
+			if overshoot <= 0 {
+				continue
+			}
+			if newOvershoot := bucket.reportOvershoot(windowStart, overshoot); newOvershoot > 0 && l.metrics != nil {
+				l.metrics.RecordRateLimitOvershoot(lim.config.Name, newOvershoot)
+			}
+			if ratio := float64(overshoot) / float64(lim.config.Requests); ratio > worstError {
+				worstError = ratio
+			}
+		}
+
+		if reconciled && l.metrics != nil {
+			l.metrics.RecordRateLimitApproximationError(lim.config.Name, worstError)
+		}
+	}
+}
+
+// addCount adds n to a window counter and returns the new total. With n of
+// zero it only reads the counter, to learn what other instances admitted.
+func (l *Limiter) addCount(ctx context.Context, key string, n int, ttl time.Duration) (int, error) {
+	if n == 0 {
+		reply, err := l.redis.Do(ctx, "GET", key)
+		if err != nil || reply == nil {
+			return 0, err
+		}
+		return strconv.Atoi(reply.(string))
+	}
+
+	reply, err := l.redis.Do(ctx, "INCRBY", key, strconv.Itoa(n))
+	if err != nil {
+		return 0, err
+	}
+	// The count is already added; a failed expiry only delays cleanup
+	l.redis.Do(ctx, "PEXPIRE", key, strconv.FormatInt(ttl.Milliseconds(), 10))
+	total, _ := reply.(int64)
+	return int(total), nil
+}
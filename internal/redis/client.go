@@ -0,0 +1,223 @@
+// Package redis is a minimal Redis client speaking the subset of RESP that
+// semaroute needs for pub/sub and scripted rate limiting.
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultTimeout bounds connecting and each command.
+	defaultTimeout = 5 * time.Second
+
+	// maxIdleConns is how many connections a Client keeps open between commands.
+	maxIdleConns = 8
+)
+
+// Config holds a Redis connection configuration.
+type Config struct {
+	Address  string        `mapstructure:"address"` // host:port
+	Password string        `mapstructure:"password"`
+	Timeout  time.Duration `mapstructure:"timeout"` // per command, default 5s
+}
+
+// Error is an error reply from the server. The connection remains usable.
+type Error string
+
+// Error implements the error interface.
+func (e Error) Error() string {
+	return string(e)
+}
+
+// Conn is a single Redis connection.
+type Conn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+}
+
+// Dial connects and authenticates.
+func Dial(ctx context.Context, config Config) (*Conn, error) {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", config.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	c := &Conn{conn: conn, reader: bufio.NewReader(conn), timeout: timeout}
+
+	if config.Password != "" {
+		if _, err := c.Do("AUTH", config.Password); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("redis authentication failed: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// Do sends a command and reads its reply.
+func (c *Conn) Do(args ...string) (interface{}, error) {
+	if err := c.Send(args...); err != nil {
+		return nil, err
+	}
+	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	return c.Receive()
+}
+
+// Send writes a command as a RESP array of bulk strings.
+func (c *Conn) Send(args ...string) error {
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"+arg+"\r\n"...)
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+// Receive reads one reply. Bulk and simple strings become strings, integers
+// int64 and arrays []interface{}; a null reply is nil and error replies are
+// returned as Error.
+func (c *Conn) Receive() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, fmt.Errorf("malformed redis reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, Error(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = c.Receive(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected redis reply %q", line)
+	}
+}
+
+// ClearDeadline removes the read deadline, so Receive can block indefinitely.
+func (c *Conn) ClearDeadline() {
+	c.conn.SetReadDeadline(time.Time{})
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// Client runs commands over a small pool of connections. Connections are
+// opened on demand, so a client can be created while Redis is unavailable.
+type Client struct {
+	config Config
+	mutex  sync.Mutex
+	idle   []*Conn
+	closed bool
+}
+
+// NewClient creates a client for the configured server.
+func NewClient(config Config) (*Client, error) {
+	if config.Address == "" {
+		return nil, errors.New("redis address is required")
+	}
+	return &Client{config: config}, nil
+}
+
+// Do runs a command on a pooled connection. A connection that fails for any
+// reason other than an error reply is discarded.
+func (c *Client) Do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.Do(args...)
+	var replyErr Error
+	if err != nil && !errors.As(err, &replyErr) {
+		conn.Close()
+		return nil, err
+	}
+	c.put(conn)
+	return reply, err
+}
+
+// get returns an idle connection or dials a new one.
+func (c *Client) get(ctx context.Context) (*Conn, error) {
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return nil, errors.New("redis client closed")
+	}
+	if n := len(c.idle); n > 0 {
+		conn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mutex.Unlock()
+		return conn, nil
+	}
+	c.mutex.Unlock()
+
+	return Dial(ctx, c.config)
+}
+
+// put returns a connection to the pool.
+func (c *Client) put(conn *Conn) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed || len(c.idle) >= maxIdleConns {
+		conn.Close()
+		return
+	}
+	c.idle = append(c.idle, conn)
+}
+
+// Close closes all idle connections.
+func (c *Client) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.closed = true
+	for _, conn := range c.idle {
+		conn.Close()
+	}
+	c.idle = nil
+	return nil
+}
@@ -10,9 +10,10 @@ import (
 	"go.uber.org/zap"
 )
 
-// HealthChecker monitors the health of all providers.
+// HealthChecker monitors the health of all providers in a provider set.
+// Providers added to or removed from the set are picked up on the next check.
 type HealthChecker struct {
-	providers     map[string]providers.Provider
+	providers     *providers.ProviderSet
 	checkInterval time.Duration
 	timeout       time.Duration
 	stopChan      chan struct{}
@@ -20,6 +21,9 @@ type HealthChecker struct {
 	logger        *zap.Logger
 	metrics       map[string]*ProviderMetrics
 	metricsMutex  sync.RWMutex
+
+	prewarm          PrewarmConfig
+	prewarmObservers []func(PrewarmResult)
 }
 
 // ProviderMetrics tracks health metrics for a provider.
@@ -33,10 +37,10 @@ type ProviderMetrics struct {
 	Uptime           float64       `json:"uptime"`
 }
 
-// NewHealthChecker creates a new health checker instance.
-func NewHealthChecker(checkInterval, timeout time.Duration, logger *zap.Logger) *HealthChecker {
+// NewHealthChecker creates a new health checker for the providers in providerSet.
+func NewHealthChecker(providerSet *providers.ProviderSet, checkInterval, timeout time.Duration, logger *zap.Logger) *HealthChecker {
 	return &HealthChecker{
-		providers:     make(map[string]providers.Provider),
+		providers:     providerSet,
 		checkInterval: checkInterval,
 		timeout:       timeout,
 		stopChan:      make(chan struct{}),
@@ -45,25 +49,6 @@ func NewHealthChecker(checkInterval, timeout time.Duration, logger *zap.Logger)
 	}
 }
 
-// AddProvider adds a provider to be monitored.
-func (hc *HealthChecker) AddProvider(name string, provider providers.Provider) {
-	hc.metricsMutex.Lock()
-	defer hc.metricsMutex.Unlock()
-
-	hc.providers[name] = provider
-	hc.metrics[name] = &ProviderMetrics{
-		LastCheck: time.Now(),
-	}
-}
-
-// RemoveProvider removes a provider from monitoring.
-func (hc *HealthChecker) RemoveProvider(name string) {
-	delete(hc.providers, name)
-	hc.metricsMutex.Lock()
-	delete(hc.metrics, name)
-	hc.metricsMutex.Unlock()
-}
-
 // Start begins the health checking process.
 func (hc *HealthChecker) Start() {
 	hc.wg.Add(1)
@@ -102,14 +87,18 @@ func (hc *HealthChecker) run() {
 func (hc *HealthChecker) checkAllProviders() {
 	var wg sync.WaitGroup
 
-	hc.metricsMutex.RLock()
-	providersCopy := make(map[string]providers.Provider)
-	for name, provider := range hc.providers {
-		providersCopy[name] = provider
+	snapshot := hc.providers.Snapshot()
+
+	// Drop metrics of providers that have been removed
+	hc.metricsMutex.Lock()
+	for name := range hc.metrics {
+		if _, exists := snapshot[name]; !exists {
+			delete(hc.metrics, name)
+		}
 	}
-	hc.metricsMutex.RUnlock()
+	hc.metricsMutex.Unlock()
 
-	for name, provider := range providersCopy {
+	for name, provider := range snapshot {
 		wg.Add(1)
 		go func(providerName string, p providers.Provider) {
 			defer wg.Done()
@@ -128,6 +117,13 @@ func (hc *HealthChecker) checkProvider(name string, provider providers.Provider)
 	_, err := provider.GetModels()
 	latency := time.Since(start)
 
+	// A provider recovering from an outage only takes traffic once pre-warmed
+	if err == nil && hc.prewarm.Enabled && !provider.IsHealthy() {
+		if warmErr := hc.warmUp(name, provider); warmErr != nil {
+			err = fmt.Errorf("pre-warm failed: %w", warmErr)
+		}
+	}
+
 	hc.metricsMutex.Lock()
 	metrics := hc.metrics[name]
 	if metrics == nil {
@@ -181,7 +177,7 @@ func (hc *HealthChecker) checkProvider(name string, provider providers.Provider)
 
 // GetProviderHealth returns the current health status of a provider.
 func (hc *HealthChecker) GetProviderHealth(name string) (models.HealthStatus, error) {
-	provider, exists := hc.providers[name]
+	provider, exists := hc.providers.Get(name)
 	if !exists {
 		return models.HealthStatus{}, fmt.Errorf("provider %s not found", name)
 	}
@@ -193,7 +189,7 @@ func (hc *HealthChecker) GetProviderHealth(name string) (models.HealthStatus, er
 func (hc *HealthChecker) GetAllProviderHealth() map[string]models.HealthStatus {
 	result := make(map[string]models.HealthStatus)
 
-	for name, provider := range hc.providers {
+	for name, provider := range hc.providers.Snapshot() {
 		result[name] = provider.GetHealth()
 	}
 
@@ -0,0 +1,128 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// PrewarmConfig configures the pre-warming of providers that recover from an
+// outage. A recovered provider stays unhealthy until its connections are open
+// and its canary completions succeed, so production traffic does not hit cold
+// connections or a provider that only answers health checks.
+type PrewarmConfig struct {
+	Enabled      bool              `mapstructure:"enabled"`
+	Connections  int               `mapstructure:"connections"`   // opened ahead of traffic
+	Canaries     int               `mapstructure:"canaries"`      // completions that must succeed, 0 disables
+	CanaryModels map[string]string `mapstructure:"canary_models"` // per provider; default is its first model
+	Timeout      time.Duration     `mapstructure:"timeout"`       // for the whole pre-warm
+}
+
+// PrewarmResult is the outcome of pre-warming a recovered provider.
+type PrewarmResult struct {
+	Provider    string
+	Connections int // opened
+	Canaries    int // succeeded
+	Duration    time.Duration
+	Err         error
+}
+
+// SetPrewarm enables pre-warming of recovered providers.
+func (hc *HealthChecker) SetPrewarm(config PrewarmConfig) {
+	if config.Connections <= 0 {
+		config.Connections = 4
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	hc.prewarm = config
+}
+
+// OnPrewarm registers a function called with the outcome of every pre-warm.
+func (hc *HealthChecker) OnPrewarm(observer func(PrewarmResult)) {
+	hc.prewarmObservers = append(hc.prewarmObservers, observer)
+}
+
+// warmUp opens connections to a recovered provider and runs its canary
+// completions, returning an error if the provider is not ready for traffic.
+func (hc *HealthChecker) warmUp(name string, provider providers.Provider) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hc.prewarm.Timeout)
+	defer cancel()
+
+	result := PrewarmResult{Provider: name}
+	start := time.Now()
+	result.Err = hc.runWarmUp(ctx, provider, &result)
+	result.Duration = time.Since(start)
+
+	if result.Err != nil {
+		hc.logger.Warn("Provider pre-warm failed, keeping it unhealthy",
+			zap.String("provider", name),
+			zap.Int("connections", result.Connections),
+			zap.Int("canaries", result.Canaries),
+			zap.Duration("duration", result.Duration),
+			zap.Error(result.Err))
+	} else {
+		hc.logger.Info("Provider recovered and pre-warmed",
+			zap.String("provider", name),
+			zap.Int("connections", result.Connections),
+			zap.Int("canaries", result.Canaries),
+			zap.Duration("duration", result.Duration))
+	}
+	for _, observer := range hc.prewarmObservers {
+		observer(result)
+	}
+	return result.Err
+}
+
+// runWarmUp does the work of warmUp, filling in result as it goes.
+func (hc *HealthChecker) runWarmUp(ctx context.Context, provider providers.Provider, result *PrewarmResult) error {
+	if prewarmer, ok := provider.(providers.Prewarmer); ok {
+		opened, err := prewarmer.Prewarm(ctx, hc.prewarm.Connections)
+		result.Connections = opened
+		if err != nil {
+			return err
+		}
+	}
+	if hc.prewarm.Canaries == 0 {
+		return nil
+	}
+
+	model, err := hc.canaryModel(result.Provider, provider)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < hc.prewarm.Canaries; i++ {
+		_, err := provider.CreateChatCompletion(ctx, models.ChatRequest{
+			Model:     model,
+			Messages:  []models.Message{{Role: "user", Content: models.TextContent("ping")}},
+			MaxTokens: 1,
+			RequestID: fmt.Sprintf("prewarm-%s-%d", result.Provider, i+1),
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("canary completion %d with %s failed: %w", i+1, model, err)
+		}
+		result.Canaries++
+	}
+	return nil
+}
+
+// canaryModel returns the model canary completions are sent to.
+func (hc *HealthChecker) canaryModel(name string, provider providers.Provider) (string, error) {
+	if model := hc.prewarm.CanaryModels[name]; model != "" {
+		return model, nil
+	}
+	served, err := provider.GetModels()
+	if err != nil {
+		return "", err
+	}
+	if len(served) == 0 {
+		return "", fmt.Errorf("no model for canary completions")
+	}
+	return served[0], nil
+}
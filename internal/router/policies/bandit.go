@@ -0,0 +1,332 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// Bandit algorithms.
+const (
+	BanditEpsilonGreedy = "epsilon_greedy"
+	BanditThompson      = "thompson"
+)
+
+// BanditConfig configures the bandit policy.
+type BanditConfig struct {
+	Algorithm string  `mapstructure:"algorithm"` // epsilon_greedy or thompson
+	Epsilon   float64 `mapstructure:"epsilon"`   // share of epsilon_greedy requests sent to a random arm
+
+	// Decay discounts earlier observations on every update of an arm, so
+	// the policy keeps adapting when providers change; 1 never forgets
+	Decay float64 `mapstructure:"decay"`
+
+	// Reward of an observation, in [0, 1]: the weights are normalized, and
+	// a failed request earns nothing
+	SuccessWeight float64       `mapstructure:"success_weight"`
+	LatencyWeight float64       `mapstructure:"latency_weight"`
+	CostWeight    float64       `mapstructure:"cost_weight"`
+	LatencyTarget time.Duration `mapstructure:"latency_target"` // latency that earns no latency reward
+	CostTarget    float64       `mapstructure:"cost_target"`    // estimated cost in USD that earns no cost reward
+}
+
+// DefaultBanditConfig returns Thompson sampling that forgets slowly and
+// rewards success most.
+func DefaultBanditConfig() BanditConfig {
+	return BanditConfig{
+		Algorithm:     BanditThompson,
+		Epsilon:       0.1,
+		Decay:         0.99,
+		SuccessWeight: 0.6,
+		LatencyWeight: 0.3,
+		CostWeight:    0.1,
+		LatencyTarget: 10 * time.Second,
+		CostTarget:    0.05,
+	}
+}
+
+// validate checks the configuration and normalizes the reward weights.
+func (c BanditConfig) validate() (BanditConfig, error) {
+	if c.Algorithm != BanditEpsilonGreedy && c.Algorithm != BanditThompson {
+		return c, fmt.Errorf("algorithm must be %s or %s, got %q", BanditEpsilonGreedy, BanditThompson, c.Algorithm)
+	}
+	if c.Epsilon < 0 || c.Epsilon > 1 {
+		return c, fmt.Errorf("epsilon must be in [0, 1]")
+	}
+	if c.Decay <= 0 || c.Decay > 1 {
+		return c, fmt.Errorf("decay must be in (0, 1]")
+	}
+	if c.SuccessWeight < 0 || c.LatencyWeight < 0 || c.CostWeight < 0 {
+		return c, fmt.Errorf("reward weights must not be negative")
+	}
+	total := c.SuccessWeight + c.LatencyWeight + c.CostWeight
+	if total <= 0 {
+		return c, fmt.Errorf("reward weights must sum to a positive number")
+	}
+	if c.LatencyTarget <= 0 || c.CostTarget <= 0 {
+		return c, fmt.Errorf("latency_target and cost_target must be positive")
+	}
+	c.SuccessWeight /= total
+	c.LatencyWeight /= total
+	c.CostWeight /= total
+	return c, nil
+}
+
+// BanditArm reports what the bandit policy has learned about one provider
+// and model.
+type BanditArm struct {
+	Provider string  `json:"provider"`
+	Model    string  `json:"model"`
+	Pulls    float64 `json:"pulls"`       // decayed number of observations
+	Reward   float64 `json:"mean_reward"` // decayed mean reward, in [0, 1]
+}
+
+// banditArm holds the decayed observations of one provider and model.
+type banditArm struct {
+	pulls  float64
+	reward float64 // decayed sum of rewards
+}
+
+// mean returns the arm's mean reward, or 0 without observations.
+func (a *banditArm) mean() float64 {
+	if a == nil || a.pulls == 0 {
+		return 0
+	}
+	return a.reward / a.pulls
+}
+
+// BanditPolicy treats every provider serving the requested model as an arm
+// and learns each arm's reward from the outcomes reported through
+// UpdateMetrics: success, latency and estimated cost. It shifts traffic to
+// the best arm while still exploring, by epsilon-greedy choice or Thompson
+// sampling. Arms without observations are tried first.
+type BanditPolicy struct {
+	*BasePolicy
+	config BanditConfig
+
+	mutex  sync.Mutex
+	arms   map[string]*banditArm // keyed by provider and model
+	random *rand.Rand
+}
+
+// NewBanditPolicy creates a bandit policy.
+func NewBanditPolicy(config BanditConfig) (*BanditPolicy, error) {
+	config, err := config.validate()
+	if err != nil {
+		return nil, err
+	}
+	return &BanditPolicy{
+		BasePolicy: NewBasePolicy(
+			"bandit",
+			"Learns each provider's reward from success, latency and cost and shifts traffic to the best while exploring",
+		),
+		config: config,
+		arms:   make(map[string]*banditArm),
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// DecideRoute picks an arm among the healthy providers serving the model.
+// Deterministic requests take the arm with the best mean reward without
+// exploring.
+func (p *BanditPolicy) DecideRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) (RoutingDecision, error) {
+	if err := p.ValidateRequest(req); err != nil {
+		return RoutingDecision{}, fmt.Errorf("invalid request: %w", err)
+	}
+
+	healthyProviders := p.getHealthyProviders(availableProviders)
+	if len(healthyProviders) == 0 {
+		return RoutingDecision{}, fmt.Errorf("no healthy providers available")
+	}
+
+	// Avoid providers that are about to throttle
+	healthyProviders = p.excludeRateLimited(ctx, healthyProviders)
+
+	var candidates []string
+	for name, provider := range healthyProviders {
+		if p.providerSupportsModel(provider, req.Model) {
+			candidates = append(candidates, name)
+		}
+	}
+	if len(candidates) == 0 {
+		return RoutingDecision{}, fmt.Errorf("no available providers for model %s", req.Model)
+	}
+	sort.Strings(candidates)
+
+	chosen, reason := p.choose(ctx, req, candidates)
+	decision := RoutingDecision{
+		ProviderName: chosen,
+		Model:        req.Model,
+		Reason:       reason,
+		Confidence:   1.0 / float64(len(candidates)),
+	}
+	if cost, err := healthyProviders[chosen].GetCostEstimate(req); err == nil {
+		decision.EstimatedCost = cost
+	}
+	if latency, err := healthyProviders[chosen].GetLatencyEstimate(req); err == nil {
+		decision.EstimatedLatency = latency
+	}
+
+	p.mutex.Lock()
+	if mean := p.arms[chosen+"/"+req.Model].mean(); mean > 0 {
+		decision.Confidence = mean
+	}
+	p.mutex.Unlock()
+	return decision, nil
+}
+
+// choose picks one of the sorted candidates and says why.
+func (p *BanditPolicy) choose(ctx context.Context, req models.ChatRequest, candidates []string) (string, string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !Deterministic(ctx) {
+		for _, name := range candidates {
+			if p.arms[name+"/"+req.Model] == nil {
+				return name, "Bandit: exploring an arm without observations"
+			}
+		}
+	}
+
+	switch {
+	case Deterministic(ctx):
+		// Greedy below
+	case p.config.Algorithm == BanditEpsilonGreedy && p.random.Float64() < p.config.Epsilon:
+		return candidates[p.random.Intn(len(candidates))], fmt.Sprintf("Bandit: exploring (epsilon %.2f)", p.config.Epsilon)
+	case p.config.Algorithm == BanditThompson:
+		best, bestSample := "", -1.0
+		for _, name := range candidates {
+			arm := p.arms[name+"/"+req.Model]
+			sample := betaSample(p.random, 1+arm.reward, 1+arm.pulls-arm.reward)
+			if sample > bestSample {
+				best, bestSample = name, sample
+			}
+		}
+		return best, fmt.Sprintf("Bandit: Thompson sample %.3f (mean reward %.3f)", bestSample, p.arms[best+"/"+req.Model].mean())
+	}
+
+	best, bestMean := candidates[0], -1.0
+	for _, name := range candidates {
+		if mean := p.arms[name+"/"+req.Model].mean(); mean > bestMean {
+			best, bestMean = name, mean
+		}
+	}
+	return best, fmt.Sprintf("Bandit: best mean reward %.3f", bestMean)
+}
+
+// ExplainRoute ranks the arms serving the model by mean reward, with the
+// mean as the score; higher is better.
+func (p *BanditPolicy) ExplainRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) []CandidateExplanation {
+	explanations := explainCandidates(ctx, req, availableProviders)
+
+	p.mutex.Lock()
+	for i := range explanations {
+		if explanations[i].Excluded != "" {
+			continue
+		}
+		mean := p.arms[explanations[i].Provider+"/"+req.Model].mean()
+		explanations[i].Score = &mean
+	}
+	p.mutex.Unlock()
+
+	sort.SliceStable(explanations, func(i, j int) bool {
+		a, b := explanations[i], explanations[j]
+		if (a.Score == nil) != (b.Score == nil) {
+			return a.Score != nil
+		}
+		if a.Score != nil && *a.Score != *b.Score {
+			return *a.Score > *b.Score
+		}
+		return a.Provider < b.Provider
+	})
+	for i := range explanations {
+		if explanations[i].Score != nil {
+			explanations[i].Rank = i + 1
+		}
+	}
+	return explanations
+}
+
+// UpdateMetrics rewards the arm a decision went to.
+func (p *BanditPolicy) UpdateMetrics(decision RoutingDecision, success bool, latency time.Duration) {
+	p.BasePolicy.UpdateMetrics(decision, success, latency)
+
+	reward := 0.0
+	if success {
+		reward = p.config.SuccessWeight +
+			p.config.LatencyWeight*(1-math.Min(float64(latency)/float64(p.config.LatencyTarget), 1)) +
+			p.config.CostWeight*(1-math.Min(decision.EstimatedCost/p.config.CostTarget, 1))
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	key := decision.ProviderName + "/" + decision.Model
+	arm := p.arms[key]
+	if arm == nil {
+		arm = &banditArm{}
+		p.arms[key] = arm
+	}
+	arm.pulls = arm.pulls*p.config.Decay + 1
+	arm.reward = arm.reward*p.config.Decay + reward
+}
+
+// Arms returns what the policy has learned, best mean reward first.
+func (p *BanditPolicy) Arms() []BanditArm {
+	p.mutex.Lock()
+	arms := make([]BanditArm, 0, len(p.arms))
+	for key, arm := range p.arms {
+		// Model names may contain slashes, provider names do not
+		provider, model, _ := strings.Cut(key, "/")
+		arms = append(arms, BanditArm{Provider: provider, Model: model, Pulls: arm.pulls, Reward: arm.mean()})
+	}
+	p.mutex.Unlock()
+
+	sort.Slice(arms, func(i, j int) bool {
+		if arms[i].Reward != arms[j].Reward {
+			return arms[i].Reward > arms[j].Reward
+		}
+		return arms[i].Provider+"/"+arms[i].Model < arms[j].Provider+"/"+arms[j].Model
+	})
+	return arms
+}
+
+// betaSample draws from Beta(a, b) as the ratio of two gamma draws.
+func betaSample(random *rand.Rand, a, b float64) float64 {
+	x := gammaSample(random, a)
+	y := gammaSample(random, b)
+	if x+y == 0 {
+		return 0.5
+	}
+	return x / (x + y)
+}
+
+// gammaSample draws from Gamma(shape, 1) with Marsaglia and Tsang's method.
+// Shapes below 1 are boosted by one and scaled back.
+func gammaSample(random *rand.Rand, shape float64) float64 {
+	if shape < 1 {
+		return gammaSample(random, shape+1) * math.Pow(random.Float64(), 1/shape)
+	}
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := random.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := random.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// Circuit breaker states.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// CircuitBreakerConfig configures the per-provider circuit breakers. A
+// breaker opens after consecutive failures or when the error rate in the
+// window crosses its threshold; an open provider gets no traffic until the
+// open duration has passed, then takes single probe requests while half-open
+// until enough succeed to close it again.
+type CircuitBreakerConfig struct {
+	Enabled             bool          `mapstructure:"enabled"`
+	ConsecutiveFailures int           `mapstructure:"consecutive_failures"` // that open the breaker, 0 disables
+	ErrorRate           float64       `mapstructure:"error_rate"`           // in the window that opens the breaker, 0 disables
+	MinRequests         int           `mapstructure:"min_requests"`         // in the window before the error rate counts
+	Window              time.Duration `mapstructure:"window"`               // over which the error rate is measured
+	OpenDuration        time.Duration `mapstructure:"open_duration"`        // before an open breaker lets a probe through
+	HalfOpenProbes      int           `mapstructure:"half_open_probes"`     // successes that close a half-open breaker
+}
+
+// withDefaults fills in unset fields and validates the configuration.
+func (c CircuitBreakerConfig) withDefaults() (CircuitBreakerConfig, error) {
+	if c.ConsecutiveFailures < 0 {
+		return c, fmt.Errorf("consecutive_failures must not be negative")
+	}
+	if c.ErrorRate < 0 || c.ErrorRate > 1 {
+		return c, fmt.Errorf("error_rate must be between 0 and 1")
+	}
+	if c.ConsecutiveFailures == 0 && c.ErrorRate == 0 {
+		return c, fmt.Errorf("consecutive_failures or error_rate must be set")
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = 20
+	}
+	if c.Window <= 0 {
+		c.Window = time.Minute
+	}
+	if c.OpenDuration <= 0 {
+		c.OpenDuration = 30 * time.Second
+	}
+	if c.HalfOpenProbes <= 0 {
+		c.HalfOpenProbes = 1
+	}
+	return c, nil
+}
+
+// BreakerTransition is a change of a provider's circuit breaker state.
+type BreakerTransition struct {
+	Time     time.Time `json:"time"`
+	Provider string    `json:"provider"`
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	Reason   string    `json:"reason"`
+}
+
+// BreakerStatus is the state of a provider's circuit breaker.
+type BreakerStatus struct {
+	State               string    `json:"state"`
+	Since               time.Time `json:"since"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Requests            int       `json:"requests"` // in the error rate window
+	Errors              int       `json:"errors"`
+	ProbeInFlight       bool      `json:"probe_in_flight,omitempty"`
+}
+
+// providerBreaker is the breaker state of one provider.
+type providerBreaker struct {
+	state       string
+	since       time.Time
+	consecutive int
+	buckets     []budgetBucket
+	probeSent   time.Time // zero when no probe is in flight
+	probesOK    int
+}
+
+// CircuitBreaker is policy middleware that keeps providers with an open
+// breaker out of routing and routes probes to half-open ones. It learns from
+// the outcome of provider requests as an observer of the metrics, so every
+// request path counts, not only those reporting to the routing policy.
+type CircuitBreaker struct {
+	config CircuitBreakerConfig
+	now    func() time.Time
+
+	mutex        sync.Mutex
+	breakers     map[string]*providerBreaker
+	onTransition []func(BreakerTransition)
+}
+
+// NewCircuitBreaker creates circuit breaker middleware.
+func NewCircuitBreaker(config CircuitBreakerConfig) (*CircuitBreaker, error) {
+	config, err := config.withDefaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid circuit breaker configuration: %w", err)
+	}
+	return &CircuitBreaker{
+		config:   config,
+		now:      time.Now,
+		breakers: make(map[string]*providerBreaker),
+	}, nil
+}
+
+// Name returns the middleware name.
+func (b *CircuitBreaker) Name() string {
+	return "circuit_breaker"
+}
+
+// OnTransition registers a function called with every state change. It must
+// be called before requests are served.
+func (b *CircuitBreaker) OnTransition(listener func(BreakerTransition)) {
+	b.onTransition = append(b.onTransition, listener)
+}
+
+// BeforeDecide removes providers whose breaker is open. A half-open provider
+// without a probe in flight that serves the model gets the request to itself
+// as a probe; one with a probe in flight is left out until the probe returns.
+func (b *CircuitBreaker) BeforeDecide(ctx context.Context, req models.ChatRequest, candidates map[string]providers.Provider) (map[string]providers.Provider, error) {
+	now := b.now()
+	var transitions []BreakerTransition
+
+	b.mutex.Lock()
+	allowed := make(map[string]providers.Provider, len(candidates))
+	var excluded []string
+	probe := ""
+	for name, provider := range candidates {
+		breaker := b.breakers[name]
+		if breaker == nil {
+			allowed[name] = provider
+			continue
+		}
+		if breaker.state == BreakerOpen && now.Sub(breaker.since) >= b.config.OpenDuration {
+			transitions = append(transitions, b.transition(name, breaker, BreakerHalfOpen, now, "open duration elapsed"))
+		}
+
+		switch breaker.state {
+		case BreakerClosed:
+			allowed[name] = provider
+		case BreakerHalfOpen:
+			// A probe whose outcome was never reported is given up after the
+			// open duration, so the breaker cannot stay half-open for good
+			if !breaker.probeSent.IsZero() && now.Sub(breaker.probeSent) < b.config.OpenDuration {
+				excluded = append(excluded, name)
+				continue
+			}
+			allowed[name] = provider
+			if probe == "" && servesModel(provider, req.Model) {
+				probe = name
+			}
+		default:
+			excluded = append(excluded, name)
+		}
+	}
+	b.mutex.Unlock()
+	b.notify(transitions)
+
+	if probe != "" {
+		return map[string]providers.Provider{probe: candidates[probe]}, nil
+	}
+	if len(allowed) == 0 && len(excluded) > 0 {
+		sort.Strings(excluded)
+		return nil, fmt.Errorf("circuit breakers open for %v", excluded)
+	}
+	return allowed, nil
+}
+
+// AfterDecide marks a probe in flight when the decision routes to a
+// half-open provider, unless the decision is a dry run.
+func (b *CircuitBreaker) AfterDecide(ctx context.Context, req models.ChatRequest, decision RoutingDecision) (RoutingDecision, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	breaker := b.breakers[decision.ProviderName]
+	if breaker != nil && breaker.state == BreakerHalfOpen {
+		if !DryRun(ctx) {
+			breaker.probeSent = b.now()
+		}
+		decision.Reason = fmt.Sprintf("Circuit breaker probe: %s", decision.Reason)
+	}
+	return decision, nil
+}
+
+// ObserveProviderRequest records the outcome of a provider request and opens
+// or closes the provider's breaker accordingly.
+func (b *CircuitBreaker) ObserveProviderRequest(providerName string, failed bool) {
+	now := b.now()
+
+	b.mutex.Lock()
+	breaker := b.breakers[providerName]
+	if breaker == nil {
+		breaker = &providerBreaker{state: BreakerClosed, since: now}
+		b.breakers[providerName] = breaker
+	}
+
+	var transition *BreakerTransition
+	switch breaker.state {
+	case BreakerClosed:
+		b.count(breaker, failed, now)
+		if reason := b.tripReason(breaker, now); reason != "" {
+			t := b.transition(providerName, breaker, BreakerOpen, now, reason)
+			transition = &t
+		}
+	case BreakerHalfOpen:
+		breaker.probeSent = time.Time{}
+		if failed {
+			t := b.transition(providerName, breaker, BreakerOpen, now, "probe failed")
+			transition = &t
+			break
+		}
+		breaker.probesOK++
+		if breaker.probesOK >= b.config.HalfOpenProbes {
+			t := b.transition(providerName, breaker, BreakerClosed, now,
+				fmt.Sprintf("%d half-open probes succeeded", breaker.probesOK))
+			transition = &t
+		}
+	}
+	b.mutex.Unlock()
+
+	if transition != nil {
+		b.notify([]BreakerTransition{*transition})
+	}
+}
+
+// ObserveFallback is part of the metrics observer interface; fallbacks are
+// already counted as failed requests.
+func (b *CircuitBreaker) ObserveFallback(providerName string) {}
+
+// ObserveSpend is part of the metrics observer interface and ignored.
+func (b *CircuitBreaker) ObserveSpend(providerName string, cost float64) {}
+
+// Status returns the state of the breaker of every provider that has served
+// a request.
+func (b *CircuitBreaker) Status() map[string]BreakerStatus {
+	now := b.now()
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	status := make(map[string]BreakerStatus, len(b.breakers))
+	for name, breaker := range b.breakers {
+		requests, errors := b.window(breaker, now)
+		status[name] = BreakerStatus{
+			State:               breaker.state,
+			Since:               breaker.since,
+			ConsecutiveFailures: breaker.consecutive,
+			Requests:            requests,
+			Errors:              errors,
+			ProbeInFlight:       !breaker.probeSent.IsZero(),
+		}
+	}
+	return status
+}
+
+// count adds an outcome to a closed breaker's counters. The caller must hold
+// the lock.
+func (b *CircuitBreaker) count(breaker *providerBreaker, failed bool, now time.Time) {
+	if failed {
+		breaker.consecutive++
+	} else {
+		breaker.consecutive = 0
+	}
+
+	start := now.Truncate(b.config.Window / 10)
+	if len(breaker.buckets) == 0 || breaker.buckets[len(breaker.buckets)-1].start.Before(start) {
+		breaker.buckets = append(breaker.buckets, budgetBucket{start: start})
+	}
+	last := &breaker.buckets[len(breaker.buckets)-1]
+	last.requests++
+	if failed {
+		last.errors++
+	}
+}
+
+// window returns a breaker's outcomes within the error rate window, dropping
+// older buckets. The caller must hold the lock.
+func (b *CircuitBreaker) window(breaker *providerBreaker, now time.Time) (requests, errors int) {
+	cutoff := now.Add(-b.config.Window)
+	kept := breaker.buckets[:0]
+	for _, bucket := range breaker.buckets {
+		if bucket.start.Before(cutoff) {
+			continue
+		}
+		kept = append(kept, bucket)
+		requests += bucket.requests
+		errors += bucket.errors
+	}
+	breaker.buckets = kept
+	return requests, errors
+}
+
+// tripReason returns why a closed breaker must open, or "" if it stays
+// closed. The caller must hold the lock.
+func (b *CircuitBreaker) tripReason(breaker *providerBreaker, now time.Time) string {
+	if b.config.ConsecutiveFailures > 0 && breaker.consecutive >= b.config.ConsecutiveFailures {
+		return fmt.Sprintf("%d consecutive failures", breaker.consecutive)
+	}
+	if b.config.ErrorRate == 0 {
+		return ""
+	}
+	requests, errors := b.window(breaker, now)
+	if requests < b.config.MinRequests {
+		return ""
+	}
+	if rate := float64(errors) / float64(requests); rate >= b.config.ErrorRate {
+		return fmt.Sprintf("error rate %.0f%% over %d requests", rate*100, requests)
+	}
+	return ""
+}
+
+// transition moves a breaker to a new state and resets the counters of the
+// state entered. The caller must hold the lock and notify the returned
+// transition after releasing it.
+func (b *CircuitBreaker) transition(name string, breaker *providerBreaker, state string, now time.Time, reason string) BreakerTransition {
+	transition := BreakerTransition{
+		Time:     now,
+		Provider: name,
+		From:     breaker.state,
+		To:       state,
+		Reason:   reason,
+	}
+	breaker.state = state
+	breaker.since = now
+	breaker.probeSent = time.Time{}
+	breaker.probesOK = 0
+	if state == BreakerClosed {
+		breaker.consecutive = 0
+		breaker.buckets = nil
+	}
+	return transition
+}
+
+// notify calls the transition listeners.
+func (b *CircuitBreaker) notify(transitions []BreakerTransition) {
+	for _, transition := range transitions {
+		for _, listener := range b.onTransition {
+			listener(transition)
+		}
+	}
+}
+
+// servesModel reports whether a provider lists the model.
+func servesModel(provider providers.Provider, model string) bool {
+	served, err := provider.GetModels()
+	if err != nil {
+		return false
+	}
+	for _, m := range served {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
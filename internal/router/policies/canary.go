@@ -0,0 +1,272 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// CanaryState is the stage of a canary rollout.
+type CanaryState string
+
+// Canary states. A rolled back canary receives no traffic until the policy is
+// recreated; a promoted canary keeps being watched and can still roll back.
+const (
+	CanaryRamping    CanaryState = "ramping"
+	CanaryPromoted   CanaryState = "promoted"
+	CanaryRolledBack CanaryState = "rolled_back"
+)
+
+// CanaryThresholds decide when a canary ramps up and when it rolls back.
+type CanaryThresholds struct {
+	InitialFraction float64       // share of traffic the canary starts with
+	Step            float64       // share added at each ramp
+	MaxFraction     float64       // share at which the canary is promoted
+	StepInterval    time.Duration // minimum time between ramps
+	Window          time.Duration // outcomes older than this are forgotten
+	MinRequests     int           // canary requests in the window before judging
+	MaxErrorRate    float64       // rolls back above this error rate
+	MaxLatency      time.Duration // rolls back above this p95 latency; 0 disables
+}
+
+// CanaryStatus reports the progress of a canary rollout.
+type CanaryStatus struct {
+	Provider   string        `json:"provider"`
+	Model      string        `json:"model,omitempty"`
+	State      CanaryState   `json:"state"`
+	Fraction   float64       `json:"fraction"`
+	Requests   int           `json:"requests"` // canary requests in the window
+	ErrorRate  float64       `json:"error_rate"`
+	LatencyP95 time.Duration `json:"latency_p95"`
+	ChangedAt  time.Time     `json:"changed_at"`
+	Reason     string        `json:"reason,omitempty"` // why the state or fraction last changed
+}
+
+// canaryOutcome is the result of one canary request.
+type canaryOutcome struct {
+	at      time.Time
+	success bool
+	latency time.Duration
+}
+
+// CanaryPolicy sends a fraction of traffic to a canary provider, or a canary
+// model on it, and the rest to a baseline policy. The fraction grows by Step
+// every StepInterval while the canary's error rate and p95 latency over the
+// sliding window stay within their thresholds, and drops to zero as soon as
+// either is exceeded.
+type CanaryPolicy struct {
+	*BasePolicy
+	provider   string
+	model      string
+	thresholds CanaryThresholds
+	baseline   RoutingPolicy
+
+	mutex     sync.Mutex
+	state     CanaryState
+	fraction  float64
+	changedAt time.Time
+	reason    string
+	outcomes  []canaryOutcome
+}
+
+// NewCanaryPolicy creates a canary policy for a provider and, optionally, a
+// model that replaces the requested one on canary requests.
+func NewCanaryPolicy(provider, model string, thresholds CanaryThresholds, baseline RoutingPolicy) *CanaryPolicy {
+	return &CanaryPolicy{
+		BasePolicy: NewBasePolicy(
+			"canary",
+			"Sends a ramping fraction of traffic to a canary provider and rolls back on errors or latency",
+		),
+		provider:   provider,
+		model:      model,
+		thresholds: thresholds,
+		baseline:   baseline,
+		state:      CanaryRamping,
+		fraction:   thresholds.InitialFraction,
+		changedAt:  time.Now(),
+		reason:     "rollout started",
+	}
+}
+
+// DecideRoute sends the request to the canary with the current fraction, or
+// to the baseline policy.
+func (p *CanaryPolicy) DecideRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) (RoutingDecision, error) {
+	if err := p.ValidateRequest(req); err != nil {
+		return RoutingDecision{}, fmt.Errorf("invalid request: %w", err)
+	}
+
+	p.mutex.Lock()
+	fraction := p.fraction
+	p.mutex.Unlock()
+
+	if fraction > 0 && Draw(ctx, req) < fraction {
+		if decision, ok := p.decideCanary(req, availableProviders, fraction); ok {
+			return decision, nil
+		}
+	}
+
+	// A canary provider only receives traffic through the canary
+	baselineProviders := availableProviders
+	if p.model == "" {
+		if _, exists := availableProviders[p.provider]; exists && len(availableProviders) > 1 {
+			baselineProviders = make(map[string]providers.Provider, len(availableProviders)-1)
+			for name, provider := range availableProviders {
+				if name != p.provider {
+					baselineProviders[name] = provider
+				}
+			}
+		}
+	}
+
+	decision, err := p.baseline.DecideRoute(ctx, req, baselineProviders)
+	if err != nil {
+		return RoutingDecision{}, err
+	}
+	decision.Reason = fmt.Sprintf("Baseline (canary at %.0f%%): %s", fraction*100, decision.Reason)
+	return decision, nil
+}
+
+// decideCanary routes a request to the canary, if it can serve it.
+func (p *CanaryPolicy) decideCanary(req models.ChatRequest, availableProviders map[string]providers.Provider, fraction float64) (RoutingDecision, bool) {
+	provider, exists := availableProviders[p.provider]
+	if !exists || !provider.IsHealthy() {
+		return RoutingDecision{}, false
+	}
+	model := req.Model
+	if p.model != "" {
+		model = p.model
+	}
+	if !p.providerSupportsModel(provider, model) {
+		return RoutingDecision{}, false
+	}
+	return RoutingDecision{
+		ProviderName: p.provider,
+		Model:        model,
+		Reason:       fmt.Sprintf("Canary (%.0f%% of traffic)", fraction*100),
+		Confidence:   fraction,
+	}, true
+}
+
+// UpdateMetrics records canary outcomes, ramping or rolling back the canary,
+// and passes the rest on to the baseline policy.
+func (p *CanaryPolicy) UpdateMetrics(decision RoutingDecision, success bool, latency time.Duration) {
+	p.BasePolicy.UpdateMetrics(decision, success, latency)
+	if !p.isCanary(decision) {
+		p.baseline.UpdateMetrics(decision, success, latency)
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	p.outcomes = append(p.outcomes, canaryOutcome{at: now, success: success, latency: latency})
+	p.evaluate(now)
+}
+
+// isCanary reports whether a decision sent the request to the canary.
+func (p *CanaryPolicy) isCanary(decision RoutingDecision) bool {
+	return decision.ProviderName == p.provider && (p.model == "" || decision.Model == p.model)
+}
+
+// evaluate rolls the canary back if the window breaches a threshold, and
+// otherwise ramps it once the step interval has passed. The caller must hold
+// the mutex.
+func (p *CanaryPolicy) evaluate(now time.Time) {
+	p.prune(now)
+	if p.state == CanaryRolledBack || len(p.outcomes) < p.thresholds.MinRequests {
+		return
+	}
+
+	errorRate, p95 := p.windowStats()
+	switch {
+	case errorRate > p.thresholds.MaxErrorRate:
+		p.rollBack(now, fmt.Sprintf("error rate %.1f%% above %.1f%%", errorRate*100, p.thresholds.MaxErrorRate*100))
+		return
+	case p.thresholds.MaxLatency > 0 && p95 > p.thresholds.MaxLatency:
+		p.rollBack(now, fmt.Sprintf("p95 latency %s above %s", p95.Round(time.Millisecond), p.thresholds.MaxLatency))
+		return
+	}
+
+	if p.state != CanaryRamping || now.Sub(p.changedAt) < p.thresholds.StepInterval {
+		return
+	}
+	p.fraction += p.thresholds.Step
+	p.changedAt = now
+	if p.fraction >= p.thresholds.MaxFraction {
+		p.fraction = p.thresholds.MaxFraction
+		p.state = CanaryPromoted
+		p.reason = "promoted after staying within thresholds"
+	} else {
+		p.reason = fmt.Sprintf("ramped to %.0f%% after staying within thresholds", p.fraction*100)
+	}
+	// The next step is judged on traffic at the new fraction
+	p.outcomes = nil
+}
+
+// rollBack stops sending traffic to the canary. The caller must hold the
+// mutex.
+func (p *CanaryPolicy) rollBack(now time.Time, reason string) {
+	p.state = CanaryRolledBack
+	p.fraction = 0
+	p.changedAt = now
+	p.reason = reason
+}
+
+// prune forgets outcomes older than the window. The caller must hold the
+// mutex.
+func (p *CanaryPolicy) prune(now time.Time) {
+	cutoff := now.Add(-p.thresholds.Window)
+	start := sort.Search(len(p.outcomes), func(i int) bool {
+		return !p.outcomes[i].at.Before(cutoff)
+	})
+	p.outcomes = p.outcomes[start:]
+}
+
+// windowStats returns the error rate and the p95 latency of successful
+// requests in the window. The caller must hold the mutex.
+func (p *CanaryPolicy) windowStats() (float64, time.Duration) {
+	if len(p.outcomes) == 0 {
+		return 0, 0
+	}
+	failures := 0
+	var latencies []time.Duration
+	for _, outcome := range p.outcomes {
+		if !outcome.success {
+			failures++
+			continue
+		}
+		latencies = append(latencies, outcome.latency)
+	}
+	errorRate := float64(failures) / float64(len(p.outcomes))
+	if len(latencies) == 0 {
+		return errorRate, 0
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return errorRate, latencies[int(0.95*float64(len(latencies)-1))]
+}
+
+// Status returns the progress of the rollout.
+func (p *CanaryPolicy) Status() CanaryStatus {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.prune(time.Now())
+	errorRate, p95 := p.windowStats()
+	return CanaryStatus{
+		Provider:   p.provider,
+		Model:      p.model,
+		State:      p.state,
+		Fraction:   p.fraction,
+		Requests:   len(p.outcomes),
+		ErrorRate:  errorRate,
+		LatencyP95: p95,
+		ChangedAt:  p.changedAt,
+		Reason:     p.reason,
+	}
+}
@@ -0,0 +1,58 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// CapabilityError is returned when no provider has a capability the request
+// needs, such as streaming, vision or tools.
+type CapabilityError struct {
+	Capability providers.Capability
+	Model      string
+}
+
+func (e *CapabilityError) Error() string {
+	return fmt.Sprintf("no provider supports %s for model %s", e.Capability, e.Model)
+}
+
+// CapabilityFilter is policy middleware that removes the providers lacking a
+// capability the request needs before the policy decides, so a streaming,
+// image or tool request is routed to a provider that can serve it instead of
+// failing once sent.
+type CapabilityFilter struct{}
+
+// NewCapabilityFilter creates the capability middleware.
+func NewCapabilityFilter() *CapabilityFilter {
+	return &CapabilityFilter{}
+}
+
+// Name returns the middleware name.
+func (f *CapabilityFilter) Name() string {
+	return "capabilities"
+}
+
+// BeforeDecide removes the candidates lacking a required capability. It fails
+// with a CapabilityError naming the first capability no candidate has.
+func (f *CapabilityFilter) BeforeDecide(ctx context.Context, req models.ChatRequest, candidates map[string]providers.Provider) (map[string]providers.Provider, error) {
+	if len(candidates) == 0 {
+		return candidates, nil
+	}
+	for _, capability := range providers.RequiredCapabilities(req) {
+		candidates = filterCandidates(candidates, func(name string, provider providers.Provider) bool {
+			return providers.Supports(provider, capability, req.Model)
+		})
+		if len(candidates) == 0 {
+			return nil, &CapabilityError{Capability: capability, Model: req.Model}
+		}
+	}
+	return candidates, nil
+}
+
+// AfterDecide accepts the decision.
+func (f *CapabilityFilter) AfterDecide(ctx context.Context, req models.ChatRequest, decision RoutingDecision) (RoutingDecision, error) {
+	return decision, nil
+}
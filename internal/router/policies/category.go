@@ -0,0 +1,222 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// Content categories. CategoryGeneral takes requests that fit no other.
+const (
+	CategoryCode       = "code"
+	CategoryReasoning  = "reasoning"
+	CategoryCreative   = "creative"
+	CategoryExtraction = "extraction"
+	CategoryGeneral    = "general"
+)
+
+// categoryOrder breaks ties between categories scoring the same.
+var categoryOrder = []string{CategoryCode, CategoryExtraction, CategoryReasoning, CategoryCreative}
+
+// IsCategory reports whether name is a content category.
+func IsCategory(name string) bool {
+	if name == CategoryGeneral {
+		return true
+	}
+	for _, category := range categoryOrder {
+		if name == category {
+			return true
+		}
+	}
+	return false
+}
+
+// CategoryPool is the models a content category is routed to, in order of
+// preference.
+type CategoryPool struct {
+	Category string   `mapstructure:"category"`
+	Models   []string `mapstructure:"models"`
+}
+
+// CategoryPolicy routes requests for a virtual model, "auto" by default, to
+// a model pool chosen by what the prompt asks for: code, reasoning, creative
+// writing, data extraction or anything else. The first model of the pool the
+// fallback policy can route is used. Requests for other models are routed by
+// the fallback policy unchanged.
+type CategoryPolicy struct {
+	*BasePolicy
+	model    string
+	pools    map[string][]string // by category; always has CategoryGeneral
+	fallback RoutingPolicy
+}
+
+// NewCategoryPolicy creates a category policy for requests of model. pools
+// must include one for CategoryGeneral.
+func NewCategoryPolicy(model string, pools []CategoryPool, fallback RoutingPolicy) *CategoryPolicy {
+	byCategory := make(map[string][]string, len(pools))
+	for _, pool := range pools {
+		byCategory[pool.Category] = pool.Models
+	}
+	return &CategoryPolicy{
+		BasePolicy: NewBasePolicy(
+			"category",
+			"Routes requests for the auto model to model pools by content category: code, reasoning, creative, extraction or general",
+		),
+		model:    model,
+		pools:    byCategory,
+		fallback: fallback,
+	}
+}
+
+// DecideRoute classifies a categorized request and lets the fallback route it
+// with the first model of the category's pool it can serve.
+func (p *CategoryPolicy) DecideRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) (RoutingDecision, error) {
+	if err := p.ValidateRequest(req); err != nil {
+		return RoutingDecision{}, fmt.Errorf("invalid request: %w", err)
+	}
+	if !strings.EqualFold(req.Model, p.model) {
+		return p.fallback.DecideRoute(ctx, req, availableProviders)
+	}
+
+	category, pool := p.classify(req)
+	var lastErr error
+	for _, model := range pool {
+		req.Model = model
+		decision, err := p.fallback.DecideRoute(ctx, req, availableProviders)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if decision.Model == "" {
+			decision.Model = model
+		}
+		decision.Category = category
+		decision.Reason = fmt.Sprintf("Category %s → %s: %s", category, model, decision.Reason)
+		return decision, nil
+	}
+	return RoutingDecision{}, fmt.Errorf("no model of the %s pool could be routed: %w", category, lastErr)
+}
+
+// DecideStage replaces the model of categorized requests with the first model
+// of the category's pool a healthy candidate serves, or else the pool's first,
+// and defers them, with every other request, to the next pipeline stage.
+func (p *CategoryPolicy) DecideStage(ctx context.Context, req models.ChatRequest, candidates map[string]providers.Provider) (StageResult, error) {
+	if err := p.ValidateRequest(req); err != nil {
+		return StageResult{}, fmt.Errorf("invalid request: %w", err)
+	}
+	if !strings.EqualFold(req.Model, p.model) {
+		return StageResult{}, nil
+	}
+
+	category, pool := p.classify(req)
+	model := pool[0]
+	for _, m := range pool {
+		if anyServes(candidates, m) {
+			model = m
+			break
+		}
+	}
+	return StageResult{
+		Model:    model,
+		Category: category,
+		Reason:   fmt.Sprintf("Category %s → %s", category, model),
+	}, nil
+}
+
+// classify returns the request's category and the pool it is routed to.
+// Categories without a pool of their own use the general pool.
+func (p *CategoryPolicy) classify(req models.ChatRequest) (string, []string) {
+	category := ContentCategory(req)
+	if pool, exists := p.pools[category]; exists {
+		return category, pool
+	}
+	return category, p.pools[CategoryGeneral]
+}
+
+// anyServes reports whether a healthy candidate serves the model.
+func anyServes(candidates map[string]providers.Provider, model string) bool {
+	for _, provider := range candidates {
+		if provider.IsHealthy() && servesModel(provider, model) {
+			return true
+		}
+	}
+	return false
+}
+
+// categoryMarkers are phrases typical of the prompts of each category.
+var categoryMarkers = map[string][]string{
+	CategoryCode: {
+		"function", "compile", "stack trace", "exception", "refactor", "debug",
+		"unit test", "regex", "sql", "python", "javascript", "typescript", "golang",
+		"rust", "java", "api endpoint", "bug", "code", "script", "syntax",
+	},
+	CategoryReasoning: {
+		"step by step", "prove", "derive", "calculate", "solve", "how many",
+		"probability", "logic", "puzzle", "explain why", "trade-off", "tradeoff",
+		"compare", "evaluate", "theorem", "equation", "estimate",
+	},
+	CategoryCreative: {
+		"story", "poem", "haiku", "lyrics", "song", "fiction", "novel",
+		"character", "brainstorm", "slogan", "tagline", "imagine", "creative",
+		"screenplay", "rewrite in the style", "metaphor",
+	},
+	CategoryExtraction: {
+		"extract", "parse", "list all", "pull out", "fields", "as json",
+		"into json", "to json", "table of", "entities", "key-value", "from the following",
+		"from this document", "from the text", "classify", "label each",
+	},
+}
+
+// codePattern matches code in a prompt: fences, common declarations and
+// statement syntax.
+var codePattern = regexp.MustCompile("(?m)```|\\bfunc \\w+\\(|\\bdef \\w+\\(|\\bclass \\w+|#include|\\bimport \\w+|=>|\\);\\s*$|\\bSELECT\\b.+\\bFROM\\b")
+
+// mathPattern matches arithmetic or math notation.
+var mathPattern = regexp.MustCompile(`[∑∫√≤≥≠π]|\d+\s*[-+*/^]\s*\d+`)
+
+// ContentCategory classifies a request by its last user message: code,
+// reasoning, creative or extraction, by the category phrases and structure
+// it has most of, or general when it shows no category.
+func ContentCategory(req models.ChatRequest) string {
+	prompt := lastUserMessage(req)
+	lower := strings.ToLower(prompt)
+
+	scores := make(map[string]int, len(categoryMarkers))
+	for category, markers := range categoryMarkers {
+		for _, marker := range markers {
+			if strings.Contains(lower, marker) {
+				scores[category]++
+			}
+		}
+	}
+
+	// Structure weighs more than a single phrase
+	if codePattern.MatchString(prompt) {
+		scores[CategoryCode] += 3
+	}
+	if mathPattern.MatchString(prompt) {
+		scores[CategoryReasoning] += 2
+	}
+	if req.ResponseFormat != nil {
+		scores[CategoryExtraction] += 2
+	}
+
+	best, bestScore := CategoryGeneral, 0
+	for _, category := range categoryOrder {
+		if scores[category] > bestScore {
+			best, bestScore = category, scores[category]
+		}
+	}
+	return best
+}
+
+// UpdateMetrics records the outcome and passes it on to the fallback policy.
+func (p *CategoryPolicy) UpdateMetrics(decision RoutingDecision, success bool, latency time.Duration) {
+	p.BasePolicy.UpdateMetrics(decision, success, latency)
+	p.fallback.UpdateMetrics(decision, success, latency)
+}
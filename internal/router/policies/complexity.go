@@ -0,0 +1,226 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// ComplexityTier sends prompts scoring up to MaxScore to a model.
+type ComplexityTier struct {
+	Name     string  `mapstructure:"name"`
+	MaxScore float64 `mapstructure:"max_score"` // 0 to 1; the last tier takes everything above
+	Model    string  `mapstructure:"model"`
+}
+
+// ComplexityPolicy routes requests for a virtual model, "auto" by default, to
+// a model tier chosen by how difficult the prompt looks: simple prompts go to
+// cheap models and complex ones to frontier models. Difficulty is estimated
+// with heuristics or, when a classifier model is configured, by asking a
+// small model. Requests for other models, and tiered requests once their
+// model is chosen, are routed by the fallback policy.
+type ComplexityPolicy struct {
+	*BasePolicy
+	model    string
+	tiers    []ComplexityTier
+	fallback RoutingPolicy
+
+	classifierProvider string // empty scores with heuristics only
+	classifierModel    string
+	timeout            time.Duration
+}
+
+// NewComplexityPolicy creates a complexity policy for requests of model,
+// choosing among tiers ordered by increasing max_score.
+func NewComplexityPolicy(model string, tiers []ComplexityTier, fallback RoutingPolicy) *ComplexityPolicy {
+	return &ComplexityPolicy{
+		BasePolicy: NewBasePolicy(
+			"complexity",
+			"Routes requests for the auto model to cheap or frontier models by estimated prompt difficulty",
+		),
+		model:    model,
+		tiers:    tiers,
+		fallback: fallback,
+	}
+}
+
+// WithClassifier scores prompts by asking a small model through a provider,
+// falling back to the heuristics when it fails or takes longer than timeout.
+func (p *ComplexityPolicy) WithClassifier(provider, model string, timeout time.Duration) *ComplexityPolicy {
+	p.classifierProvider = provider
+	p.classifierModel = model
+	p.timeout = timeout
+	return p
+}
+
+// DecideRoute picks the tier of a tiered request and lets the fallback route
+// it with the tier's model.
+func (p *ComplexityPolicy) DecideRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) (RoutingDecision, error) {
+	if err := p.ValidateRequest(req); err != nil {
+		return RoutingDecision{}, fmt.Errorf("invalid request: %w", err)
+	}
+	if !strings.EqualFold(req.Model, p.model) {
+		return p.fallback.DecideRoute(ctx, req, availableProviders)
+	}
+
+	tier, reason := p.classify(ctx, req, availableProviders)
+	req.Model = tier.Model
+	decision, err := p.fallback.DecideRoute(ctx, req, availableProviders)
+	if err != nil {
+		return RoutingDecision{}, err
+	}
+	if decision.Model == "" {
+		decision.Model = tier.Model
+	}
+	decision.Reason = fmt.Sprintf("%s: %s", reason, decision.Reason)
+	return decision, nil
+}
+
+// DecideStage replaces the model of tiered requests with the tier's model and
+// defers them, with every other request, to the next pipeline stage.
+func (p *ComplexityPolicy) DecideStage(ctx context.Context, req models.ChatRequest, candidates map[string]providers.Provider) (StageResult, error) {
+	if err := p.ValidateRequest(req); err != nil {
+		return StageResult{}, fmt.Errorf("invalid request: %w", err)
+	}
+	if !strings.EqualFold(req.Model, p.model) {
+		return StageResult{}, nil
+	}
+
+	tier, reason := p.classify(ctx, req, candidates)
+	return StageResult{Model: tier.Model, Reason: reason}, nil
+}
+
+// classify scores the prompt and returns its tier, with the reason recorded
+// in the routing decision.
+func (p *ComplexityPolicy) classify(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) (ComplexityTier, string) {
+	score, method := 0.0, "heuristic"
+	if p.classifierProvider != "" {
+		var err error
+		score, err = p.classifierScore(ctx, req, availableProviders)
+		if err == nil {
+			method = "classifier " + p.classifierModel
+		} else {
+			score = complexityScore(req)
+			method = fmt.Sprintf("heuristic, classifier failed: %v", err)
+		}
+	} else {
+		score = complexityScore(req)
+	}
+
+	tier := p.tiers[len(p.tiers)-1]
+	for _, t := range p.tiers[:len(p.tiers)-1] {
+		if score <= t.MaxScore {
+			tier = t
+			break
+		}
+	}
+	return tier, fmt.Sprintf("Complexity %.2f (%s) → tier %s (%s)", score, method, tier.Name, tier.Model)
+}
+
+// classifierPrompt asks the classifier model for a difficulty rating.
+const classifierPrompt = "Rate how difficult it is to answer the following request well, " +
+	"from 1 (trivial lookup or small talk) to 10 (expert multi-step reasoning, " +
+	"complex code or analysis). Reply with the number only.\n\nRequest:\n"
+
+// classifierInputLimit caps the prompt characters sent to the classifier.
+const classifierInputLimit = 4000
+
+// ratingPattern finds the rating in the classifier's answer.
+var ratingPattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// classifierScore asks the classifier model to rate the prompt, mapping its
+// 1-10 rating to a 0-1 score.
+func (p *ComplexityPolicy) classifierScore(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) (float64, error) {
+	provider, exists := availableProviders[p.classifierProvider]
+	if !exists || !provider.IsHealthy() {
+		return 0, fmt.Errorf("classifier provider %s not available", p.classifierProvider)
+	}
+
+	prompt := lastUserMessage(req)
+	if len(prompt) > classifierInputLimit {
+		prompt = prompt[:classifierInputLimit]
+	}
+	classifyCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	response, err := provider.CreateChatCompletion(classifyCtx, models.ChatRequest{
+		Model:     p.classifierModel,
+		Messages:  []models.Message{{Role: "user", Content: models.TextContent(classifierPrompt + prompt)}},
+		MaxTokens: 4,
+		RequestID: req.RequestID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(response.Choices) == 0 {
+		return 0, fmt.Errorf("classifier returned no answer")
+	}
+
+	answer := response.Choices[0].Message.Content.Text()
+	rating, err := strconv.ParseFloat(ratingPattern.FindString(answer), 64)
+	if err != nil || rating < 1 || rating > 10 {
+		return 0, fmt.Errorf("classifier answered %q", answer)
+	}
+	return (rating - 1) / 9, nil
+}
+
+// reasoningMarkers are phrases typical of prompts that need multi-step
+// reasoning or expert knowledge.
+var reasoningMarkers = []string{
+	"step by step", "prove", "derive", "analyze", "analyse", "compare", "trade-off",
+	"tradeoff", "design", "architect", "optimize", "optimise", "refactor", "debug",
+	"explain why", "evaluate", "critique", "algorithm", "complexity", "theorem",
+	"implement", "migrate", "strategy",
+}
+
+// complexityScore estimates the difficulty of a prompt from 0 (trivial) to 1
+// (complex) from its length, structure, code, math, reasoning phrases, tools
+// and conversation length.
+func complexityScore(req models.ChatRequest) float64 {
+	prompt := lastUserMessage(req)
+	lower := strings.ToLower(prompt)
+
+	// Length saturates around 2000 words
+	words := len(strings.Fields(prompt))
+	score := 0.35 * math.Min(1, math.Log1p(float64(words))/math.Log1p(2000))
+
+	markers := 0
+	for _, marker := range reasoningMarkers {
+		if strings.Contains(lower, marker) {
+			markers++
+		}
+	}
+	score += 0.25 * math.Min(1, float64(markers)/3)
+
+	if strings.Contains(prompt, "```") || strings.Contains(prompt, "func ") || strings.Contains(prompt, "def ") {
+		score += 0.15
+	}
+	if strings.ContainsAny(prompt, "∑∫√≤≥") || strings.Contains(lower, "equation") || strings.Contains(lower, "integral") {
+		score += 0.1
+	}
+
+	// Several questions or enumerated requirements
+	if strings.Count(prompt, "?") > 2 || strings.Count(prompt, "\n- ")+strings.Count(prompt, "\n1.") > 2 {
+		score += 0.05
+	}
+	if len(req.Tools) > 0 || req.ResponseFormat != nil {
+		score += 0.05
+	}
+	if len(req.Messages) > 6 {
+		score += 0.05
+	}
+	return math.Min(1, score)
+}
+
+// UpdateMetrics records the outcome and passes it on to the fallback policy.
+func (p *ComplexityPolicy) UpdateMetrics(decision RoutingDecision, success bool, latency time.Duration) {
+	p.BasePolicy.UpdateMetrics(decision, success, latency)
+	p.fallback.UpdateMetrics(decision, success, latency)
+}
@@ -3,20 +3,56 @@ package policies
 import (
 	"context"
 	"fmt"
+	"math"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/semantrix/semaroute/internal/models"
 	"github.com/semantrix/semaroute/internal/providers"
 )
 
-// CostBasedPolicy implements cost-optimized routing.
+// maxObservedErrorRate caps the error rate used in scoring, so a failing
+// provider's score stays finite.
+const maxObservedErrorRate = 0.9
+
+// CostBasedPolicy implements cost-optimized routing. Once a provider has
+// recent request outcomes, their EWMA latency replaces its latency estimate
+// and their EWMA error rate raises its score, so a degrading provider is
+// deprioritized before health checks mark it down.
 type CostBasedPolicy struct {
 	*BasePolicy
 	maxLatencyThreshold time.Duration
 	costWeight          float64
 	latencyWeight       float64
 	healthWeight        float64
+
+	ewmaAlpha  float64       // weight of each new outcome
+	minSamples int           // outcomes needed before they are trusted
+	maxAge     time.Duration // outcomes are forgotten after this long without a new one
+
+	mutex      sync.Mutex
+	latencies  map[string]*outcomeEWMA // seconds, keyed by provider and model
+	errorRates map[string]*outcomeEWMA // keyed by provider
+}
+
+// outcomeEWMA is an exponentially weighted moving average of request outcomes.
+type outcomeEWMA struct {
+	value   float64
+	samples int
+	updated time.Time
+}
+
+// add folds x into the average. An average not updated within maxAge starts
+// over, as it no longer describes the provider.
+func (e *outcomeEWMA) add(x, alpha float64, maxAge time.Duration, now time.Time) {
+	if e.samples == 0 || now.Sub(e.updated) > maxAge {
+		e.value, e.samples = x, 0
+	} else {
+		e.value = alpha*x + (1-alpha)*e.value
+	}
+	e.samples++
+	e.updated = now
 }
 
 // NewCostBasedPolicy creates a new cost-based routing policy.
@@ -30,9 +66,24 @@ func NewCostBasedPolicy() *CostBasedPolicy {
 		costWeight:          0.6,
 		latencyWeight:       0.3,
 		healthWeight:        0.1,
+		ewmaAlpha:           0.2,
+		minSamples:          3,
+		maxAge:              5 * time.Minute,
+		latencies:           make(map[string]*outcomeEWMA),
+		errorRates:          make(map[string]*outcomeEWMA),
 	}
 }
 
+// providerScore is a provider's composite cost-based score; lower is better.
+type providerScore struct {
+	name      string
+	score     float64
+	cost      float64
+	latency   time.Duration
+	errorRate float64
+	reason    string
+}
+
 // DecideRoute selects the best provider based on cost, latency, and health.
 func (p *CostBasedPolicy) DecideRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) (RoutingDecision, error) {
 	if err := p.ValidateRequest(req); err != nil {
@@ -45,97 +96,238 @@ func (p *CostBasedPolicy) DecideRoute(ctx context.Context, req models.ChatReques
 		return RoutingDecision{}, fmt.Errorf("no healthy providers available")
 	}
 
-	// Score each provider
-	type providerScore struct {
-		name  string
-		score float64
-		cost  float64
-		latency time.Duration
-		reason string
+	scores, _ := p.scoreProviders(ctx, req, healthyProviders)
+	if len(scores) == 0 {
+		return RoutingDecision{}, fmt.Errorf("no suitable providers found for model %s", req.Model)
+	}
+
+	// Select the best provider
+	best := scores[0]
+
+	// Calculate confidence based on score difference from next best
+	confidence := 1.0
+	if len(scores) > 1 {
+		scoreDiff := scores[1].score - best.score
+		if scoreDiff > 0 {
+			confidence = 0.8 + (0.2 * (scoreDiff / best.score))
+			if confidence > 1.0 {
+				confidence = 1.0
+			}
+		}
+	}
+
+	decision := RoutingDecision{
+		ProviderName:      best.name,
+		Model:            req.Model,
+		Reason:           best.reason,
+		EstimatedCost:    best.cost,
+		EstimatedLatency: best.latency,
+		Confidence:       confidence,
+		Fallback:         false,
 	}
 
+	// Record the decision; its outcome is reported through UpdateMetrics
+	if !DryRun(ctx) {
+		p.BasePolicy.UpdateMetrics(decision, true, 0)
+	}
+
+	return decision, nil
+}
+
+// ExplainRoute ranks the providers by score, with the reason each of the
+// others is left out.
+func (p *CostBasedPolicy) ExplainRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) []CandidateExplanation {
+	healthyProviders := p.getHealthyProviders(availableProviders)
+	scores, excluded := p.scoreProviders(ctx, req, healthyProviders)
+
+	explanations := make([]CandidateExplanation, 0, len(availableProviders))
+	for i, scored := range scores {
+		score := scored.score
+		explanations = append(explanations, CandidateExplanation{
+			Provider:         scored.name,
+			Rank:             i + 1,
+			Score:            &score,
+			EstimatedCost:    scored.cost,
+			EstimatedLatency: scored.latency,
+			ErrorRate:        scored.errorRate,
+		})
+	}
+	explanations = append(explanations, excludedBy(availableProviders, healthyProviders, "unhealthy")...)
+	return append(explanations, excluded...)
+}
+
+// scoreProviders scores the healthy providers that can serve the request,
+// best first, and explains why the others cannot.
+func (p *CostBasedPolicy) scoreProviders(ctx context.Context, req models.ChatRequest, healthyProviders map[string]providers.Provider) ([]providerScore, []CandidateExplanation) {
+	// Avoid providers that are about to throttle
+	candidates := p.excludeRateLimited(ctx, healthyProviders)
+	excluded := excludedBy(healthyProviders, candidates, "near its rate limit")
+
 	var scores []providerScore
 
-	for name, provider := range healthyProviders {
+	for name, provider := range candidates {
 		// Check if provider supports the requested model
 		if !p.providerSupportsModel(provider, req.Model) {
+			excluded = append(excluded, CandidateExplanation{Provider: name, Excluded: fmt.Sprintf("does not serve model %s", req.Model)})
+			continue
+		}
+		operatorWeight, weighted := OperatorWeight(ctx, name)
+		if weighted && operatorWeight == 0 {
+			excluded = append(excluded, CandidateExplanation{Provider: name, Excluded: "drained by an operator weight of 0"})
 			continue
 		}
 
 		// Get cost estimate
 		cost, err := provider.GetCostEstimate(req)
 		if err != nil {
-			continue // Skip this provider if we can't get cost estimate
+			// Skip this provider if we can't get cost estimate
+			excluded = append(excluded, CandidateExplanation{Provider: name, Excluded: fmt.Sprintf("no cost estimate: %v", err)})
+			continue
 		}
 
-		// Get latency estimate
+		// Get latency estimate, or the observed latency once there is one
 		latency, err := provider.GetLatencyEstimate(req)
 		if err != nil {
 			latency = p.maxLatencyThreshold // Use max threshold as fallback
 		}
+		observedLatency, errorRate := p.observed(ctx, name, req.Model)
+		if observedLatency > 0 {
+			latency = observedLatency
+		}
 
 		// Check if latency is within acceptable bounds
 		if latency > p.maxLatencyThreshold {
-			continue // Skip providers that are too slow
+			// Skip providers that are too slow
+			excluded = append(excluded, CandidateExplanation{
+				Provider:         name,
+				EstimatedCost:    cost,
+				EstimatedLatency: latency,
+				ErrorRate:        errorRate,
+				Excluded:         fmt.Sprintf("latency estimate %v exceeds %v", latency, p.maxLatencyThreshold),
+			})
+			continue
 		}
 
 		// Calculate composite score
 		// Lower scores are better (like golf scoring)
-		costScore := cost * p.costWeight
-		latencyScore := float64(latency.Milliseconds()) / 1000.0 * p.latencyWeight
-		healthScore := 0.0 // Healthy providers get 0 penalty
+		// Failed requests are sent again elsewhere, so a provider failing a
+		// share of requests costs proportionally more per served request
+		attempts := 1 / (1 - math.Min(errorRate, maxObservedErrorRate))
+		costScore := cost * p.costWeight * attempts
+		latencyScore := float64(latency.Milliseconds()) / 1000.0 * p.latencyWeight * attempts
+		healthScore := errorRate * p.healthWeight // Providers without errors get 0 penalty
 		
 		totalScore := costScore + latencyScore + healthScore
 
-		reason := fmt.Sprintf("Cost: $%.4f, Latency: %v, Health: Good", cost, latency)
+		health := "Good"
+		if errorRate > 0 {
+			health = fmt.Sprintf("%.1f%% errors", errorRate*100)
+		}
+		reason := fmt.Sprintf("Cost: $%.4f, Latency: %v, Health: %s", cost, latency, health)
+		if weighted {
+			// Operators boost a provider by lowering its score
+			totalScore /= operatorWeight
+			reason += fmt.Sprintf(", Weight: %g", operatorWeight)
+		}
 
 		scores = append(scores, providerScore{
-			name:    name,
-			score:   totalScore,
-			cost:    cost,
-			latency: latency,
-			reason:  reason,
+			name:      name,
+			score:     totalScore,
+			cost:      cost,
+			latency:   latency,
+			errorRate: errorRate,
+			reason:    reason,
 		})
 	}
 
-	if len(scores) == 0 {
-		return RoutingDecision{}, fmt.Errorf("no suitable providers found for model %s", req.Model)
-	}
-
 	// Sort by score (ascending - lower is better)
 	sort.Slice(scores, func(i, j int) bool {
-		return scores[i].score < scores[j].score
+		if scores[i].score != scores[j].score {
+			return scores[i].score < scores[j].score
+		}
+		return scores[i].name < scores[j].name
 	})
 
-	// Select the best provider
-	best := scores[0]
+	return scores, excluded
+}
 
-	// Calculate confidence based on score difference from next best
-	confidence := 1.0
-	if len(scores) > 1 {
-		scoreDiff := scores[1].score - best.score
-		if scoreDiff > 0 {
-			confidence = 0.8 + (0.2 * (scoreDiff / best.score))
-			if confidence > 1.0 {
-				confidence = 1.0
-			}
-		}
+// observed returns the EWMA latency of a provider for a model and its EWMA
+// error rate, each 0 until there are enough recent outcomes. Deterministic
+// requests ignore them, as outcomes differ between runs.
+func (p *CostBasedPolicy) observed(ctx context.Context, providerName, model string) (time.Duration, float64) {
+	if Deterministic(ctx) {
+		return 0, 0
 	}
+	now := time.Now()
 
-	decision := RoutingDecision{
-		ProviderName:      best.name,
-		Model:            req.Model,
-		Reason:           best.reason,
-		EstimatedCost:    best.cost,
-		EstimatedLatency: best.latency,
-		Confidence:       confidence,
-		Fallback:         false,
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var latency time.Duration
+	if e := p.latencies[providerName+"/"+model]; p.trusted(e, now) {
+		latency = time.Duration(e.value * float64(time.Second))
+	}
+	errorRate := 0.0
+	if e := p.errorRates[providerName]; p.trusted(e, now) {
+		errorRate = e.value
 	}
+	return latency, errorRate
+}
 
-	// Update metrics
-	p.UpdateMetrics(decision, true, 0) // We don't have actual latency yet
+// trusted reports whether an average has enough recent outcomes to score by.
+func (p *CostBasedPolicy) trusted(e *outcomeEWMA, now time.Time) bool {
+	return e != nil && e.samples >= p.minSamples && now.Sub(e.updated) <= p.maxAge
+}
 
-	return decision, nil
+// UpdateMetrics feeds the outcome of a request into the provider's error
+// rate and, for a successful request, into its latency for the model.
+func (p *CostBasedPolicy) UpdateMetrics(decision RoutingDecision, success bool, latency time.Duration) {
+	p.BasePolicy.UpdateMetrics(decision, success, latency)
+	now := time.Now()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	failed := 1.0
+	if success {
+		failed = 0
+	}
+	errorRate := p.errorRates[decision.ProviderName]
+	if errorRate == nil {
+		errorRate = &outcomeEWMA{}
+		p.errorRates[decision.ProviderName] = errorRate
+	}
+	errorRate.add(failed, p.ewmaAlpha, p.maxAge, now)
+
+	if !success || latency <= 0 {
+		return
+	}
+	key := decision.ProviderName + "/" + decision.Model
+	observed := p.latencies[key]
+	if observed == nil {
+		observed = &outcomeEWMA{}
+		p.latencies[key] = observed
+	}
+	observed.add(latency.Seconds(), p.ewmaAlpha, p.maxAge, now)
+}
+
+// SetFeedback configures how request outcomes feed into scoring: the weight
+// of each new outcome, the outcomes needed before they are used, and how long
+// they are kept without a new one.
+func (p *CostBasedPolicy) SetFeedback(alpha float64, minSamples int, maxAge time.Duration) error {
+	if alpha <= 0 || alpha > 1 {
+		return fmt.Errorf("ewma_alpha must be in (0, 1]")
+	}
+	if minSamples <= 0 {
+		return fmt.Errorf("min_samples must be positive")
+	}
+	if maxAge <= 0 {
+		return fmt.Errorf("max_age must be positive")
+	}
+	p.ewmaAlpha = alpha
+	p.minSamples = minSamples
+	p.maxAge = maxAge
+	return nil
 }
 
 // SetWeights allows customization of the scoring weights.
@@ -0,0 +1,39 @@
+package policies
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"math/rand"
+
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+// Deterministic reports whether the request carried by ctx is routed in
+// deterministic mode. Identical requests then route identically as long as
+// provider health is the same: policies ignore load, rate-limit headroom and
+// observed latency, and random choices are replaced by a hash of the request,
+// so incidents can be reproduced and certification runs repeated.
+func Deterministic(ctx context.Context) bool {
+	return RequestInfoFrom(ctx).Deterministic
+}
+
+// Draw returns a number in [0, 1) for a random routing choice: random, or in
+// deterministic mode derived from the request's model and messages.
+func Draw(ctx context.Context, req models.ChatRequest) float64 {
+	if !Deterministic(ctx) {
+		return rand.Float64()
+	}
+	return requestHash(req)
+}
+
+// requestHash maps the model and messages of a request to [0, 1). Request
+// IDs, timestamps and other per-request metadata do not count.
+func requestHash(req models.ChatRequest) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(req.Model))
+	h.Write([]byte{0})
+	messages, _ := json.Marshal(req.Messages)
+	h.Write(messages)
+	return float64(h.Sum64()>>11) / float64(1<<53)
+}
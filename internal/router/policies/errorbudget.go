@@ -0,0 +1,312 @@
+package policies
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxWeightChanges is the number of weight changes kept for reporting.
+const maxWeightChanges = 100
+
+// ErrorBudgetConfig configures the reweighting of providers by how fast they
+// burn their error budget. The budget is the error rate the SLO allows; a
+// burn rate of 1 spends it exactly, higher rates spend it faster.
+type ErrorBudgetConfig struct {
+	SLO          float64            `mapstructure:"slo"`           // target success rate, e.g. 0.99
+	SLOs         map[string]float64 `mapstructure:"slos"`          // per-provider targets overriding slo
+	Window       time.Duration      `mapstructure:"window"`        // over which the burn rate is measured
+	MinRequests  int                `mapstructure:"min_requests"`  // in the window before a provider is reweighted
+	MinFactor    float64            `mapstructure:"min_factor"`    // lowest share of its weight a provider keeps
+	RecoveryStep float64            `mapstructure:"recovery_step"` // share of its weight regained per interval
+	Interval     time.Duration      `mapstructure:"interval"`      // between evaluations
+}
+
+// withDefaults fills in unset fields and validates the configuration.
+func (c ErrorBudgetConfig) withDefaults() (ErrorBudgetConfig, error) {
+	if c.SLO == 0 {
+		c.SLO = 0.99
+	}
+	if c.Window <= 0 {
+		c.Window = 5 * time.Minute
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = 20
+	}
+	if c.MinFactor == 0 {
+		c.MinFactor = 0.05
+	}
+	if c.RecoveryStep == 0 {
+		c.RecoveryStep = 0.1
+	}
+	if c.Interval <= 0 {
+		c.Interval = 30 * time.Second
+	}
+
+	for name, slo := range c.SLOs {
+		if slo <= 0 || slo >= 1 {
+			return c, fmt.Errorf("slo of %s must be between 0 and 1", name)
+		}
+	}
+	if c.SLO <= 0 || c.SLO >= 1 {
+		return c, fmt.Errorf("slo must be between 0 and 1")
+	}
+	if c.MinFactor < 0 || c.MinFactor > 1 {
+		return c, fmt.Errorf("min_factor must be between 0 and 1")
+	}
+	if c.RecoveryStep <= 0 || c.RecoveryStep > 1 {
+		return c, fmt.Errorf("recovery_step must be between 0 and 1")
+	}
+	return c, nil
+}
+
+// WeightChange is a change of a provider's weight factor.
+type WeightChange struct {
+	Time     time.Time `json:"time"`
+	Provider string    `json:"provider"`
+	From     float64   `json:"from"`
+	To       float64   `json:"to"`
+	BurnRate float64   `json:"burn_rate"`
+	Reason   string    `json:"reason"`
+}
+
+// ProviderWeight is a provider's routing weight and the error budget burn
+// that adjusts it.
+type ProviderWeight struct {
+	Configured float64  `json:"configured"`
+	Override   *float64 `json:"override,omitempty"` // set by an operator, in place of Configured
+	Factor     float64  `json:"factor"`             // share of the configured weight in effect
+	Effective  float64  `json:"effective"`
+	SLO        float64  `json:"slo,omitempty"`
+	BurnRate   float64  `json:"burn_rate"`
+	Requests   int      `json:"requests"` // in the burn rate window
+	Errors     int      `json:"errors"`
+}
+
+// WeightReport describes the routing weights in effect.
+type WeightReport struct {
+	Providers map[string]ProviderWeight `json:"providers"`
+	Changes   []WeightChange            `json:"changes"` // most recent last
+}
+
+// WeightReporter is implemented by policies with routing weights.
+type WeightReporter interface {
+	// WeightReport returns the weights in effect and their recent changes.
+	WeightReport() WeightReport
+
+	// OnWeightChange registers a function called with every weight change.
+	OnWeightChange(func(WeightChange))
+}
+
+// budgetBucket counts outcomes in one slice of the burn rate window.
+type budgetBucket struct {
+	start    time.Time
+	requests int
+	errors   int
+}
+
+// errorBudget tracks the burn rate of each provider and derives the factor
+// applied to its weight: cut in proportion to the burn rate as soon as it
+// exceeds 1, and restored step by step as the provider recovers.
+type errorBudget struct {
+	config ErrorBudgetConfig
+
+	mutex       sync.Mutex
+	buckets     map[string][]budgetBucket
+	factors     map[string]float64
+	burnRates   map[string]float64
+	changes     []WeightChange
+	lastEval    time.Time
+	onChange    []func(WeightChange)
+	bucketWidth time.Duration
+}
+
+// newErrorBudget creates an error budget from a validated configuration.
+func newErrorBudget(config ErrorBudgetConfig) *errorBudget {
+	return &errorBudget{
+		config:      config,
+		buckets:     make(map[string][]budgetBucket),
+		factors:     make(map[string]float64),
+		burnRates:   make(map[string]float64),
+		bucketWidth: config.Window / 10,
+	}
+}
+
+// observe records the outcome of a request to a provider.
+func (b *errorBudget) observe(provider string, success bool, now time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	buckets := b.buckets[provider]
+	start := now.Truncate(b.bucketWidth)
+	if len(buckets) == 0 || buckets[len(buckets)-1].start.Before(start) {
+		buckets = append(buckets, budgetBucket{start: start})
+	}
+	last := &buckets[len(buckets)-1]
+	last.requests++
+	if !success {
+		last.errors++
+	}
+	b.buckets[provider] = buckets
+}
+
+// factor returns the share of its weight a provider keeps.
+func (b *errorBudget) factor(provider string) float64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if factor, exists := b.factors[provider]; exists {
+		return factor
+	}
+	return 1
+}
+
+// slo returns a provider's target success rate.
+func (b *errorBudget) slo(provider string) float64 {
+	if slo, exists := b.config.SLOs[provider]; exists {
+		return slo
+	}
+	return b.config.SLO
+}
+
+// window returns a provider's outcomes within the burn rate window, dropping
+// older buckets. The caller must hold the lock.
+func (b *errorBudget) window(provider string, now time.Time) (requests, errors int) {
+	cutoff := now.Add(-b.config.Window)
+	buckets := b.buckets[provider]
+	kept := buckets[:0]
+	for _, bucket := range buckets {
+		if bucket.start.Before(cutoff) {
+			continue
+		}
+		kept = append(kept, bucket)
+		requests += bucket.requests
+		errors += bucket.errors
+	}
+	b.buckets[provider] = kept
+	return requests, errors
+}
+
+// evaluate updates the weight factors once per interval. Providers burning
+// their budget faster than it accrues drop to 1/burn rate of their weight at
+// once; providers below that target regain recovery_step per interval.
+func (b *errorBudget) evaluate(now time.Time) {
+	b.mutex.Lock()
+	if now.Sub(b.lastEval) < b.config.Interval {
+		b.mutex.Unlock()
+		return
+	}
+	b.lastEval = now
+
+	var changes []WeightChange
+	for provider := range b.buckets {
+		requests, errors := b.window(provider, now)
+		current, exists := b.factors[provider]
+		if !exists {
+			current = 1
+		}
+
+		burn := 0.0
+		if requests > 0 {
+			burn = (float64(errors) / float64(requests)) / (1 - b.slo(provider))
+		}
+		b.burnRates[provider] = burn
+
+		// Too few requests to judge only allow recovery
+		target := 1.0
+		if requests >= b.config.MinRequests && burn > 1 {
+			target = math.Max(b.config.MinFactor, 1/burn)
+		}
+		next := current
+		reason := ""
+		switch {
+		case target < current:
+			next = target
+			reason = fmt.Sprintf("burning error budget at %.1fx", burn)
+		case target > current:
+			next = math.Min(target, current+b.config.RecoveryStep)
+			reason = fmt.Sprintf("recovering, burn rate %.1fx", burn)
+		}
+		next = math.Round(next*1000) / 1000
+		if next == current {
+			continue
+		}
+
+		if next == 1 {
+			delete(b.factors, provider)
+		} else {
+			b.factors[provider] = next
+		}
+		changes = append(changes, WeightChange{
+			Time:     now,
+			Provider: provider,
+			From:     current,
+			To:       next,
+			BurnRate: burn,
+			Reason:   reason,
+		})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Provider < changes[j].Provider })
+
+	b.changes = append(b.changes, changes...)
+	if len(b.changes) > maxWeightChanges {
+		b.changes = append([]WeightChange(nil), b.changes[len(b.changes)-maxWeightChanges:]...)
+	}
+	listeners := b.onChange
+	b.mutex.Unlock()
+
+	for _, change := range changes {
+		for _, listener := range listeners {
+			listener(change)
+		}
+	}
+}
+
+// report fills in the budget state of the given configured weights.
+func (b *errorBudget) report(weights map[string]float64, now time.Time) WeightReport {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	report := WeightReport{
+		Providers: make(map[string]ProviderWeight, len(weights)),
+		Changes:   append([]WeightChange{}, b.changes...),
+	}
+	for provider, configured := range weights {
+		factor, exists := b.factors[provider]
+		if !exists {
+			factor = 1
+		}
+		requests, errors := b.window(provider, now)
+		report.Providers[provider] = ProviderWeight{
+			Configured: configured,
+			Factor:     factor,
+			Effective:  configured * factor,
+			SLO:        b.slo(provider),
+			BurnRate:   b.burnRates[provider],
+			Requests:   requests,
+			Errors:     errors,
+		}
+	}
+	return report
+}
+
+// providers returns the providers with recorded outcomes.
+func (b *errorBudget) providers() []string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	names := make([]string, 0, len(b.buckets))
+	for name := range b.buckets {
+		names = append(names, name)
+	}
+	return names
+}
+
+// addListener registers a function called with every weight change.
+func (b *errorBudget) addListener(listener func(WeightChange)) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.onChange = append(b.onChange, listener)
+}
@@ -0,0 +1,107 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// CandidateExplanation describes how a routing policy weighed one provider
+// for a request.
+type CandidateExplanation struct {
+	Provider         string        `json:"provider"`
+	Rank             int           `json:"rank,omitempty"`  // position among the eligible providers, from 1; 0 when excluded or the policy does not rank
+	Score            *float64      `json:"score,omitempty"` // set by policies that score providers
+	EstimatedCost    float64       `json:"estimated_cost,omitempty"`
+	EstimatedLatency time.Duration `json:"estimated_latency,omitempty"`
+	ErrorRate        float64       `json:"error_rate,omitempty"` // observed recently, for policies that track it
+	Excluded         string        `json:"excluded,omitempty"`   // why the provider could not be chosen
+}
+
+// Explainer is implemented by policies that can rank the candidates for a
+// request without deciding it. ExplainRoute must not change the policy's
+// state.
+type Explainer interface {
+	ExplainRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) []CandidateExplanation
+}
+
+// DryRun reports whether the request carried by ctx is only explained, not
+// executed. Policies and middleware then leave rotations, probes and other
+// state that tracks routed traffic alone.
+func DryRun(ctx context.Context) bool {
+	return RequestInfoFrom(ctx).DryRun
+}
+
+// Explain returns how policy weighs each of the available providers for req:
+// ranked providers first, then unranked eligible ones, then the excluded
+// ones. Providers of policies that are not Explainers are only checked for
+// health, model support and rate-limit headroom.
+func Explain(ctx context.Context, policy RoutingPolicy, req models.ChatRequest, availableProviders map[string]providers.Provider) []CandidateExplanation {
+	var explanations []CandidateExplanation
+	if explainer, ok := policy.(Explainer); ok {
+		explanations = explainer.ExplainRoute(ctx, req, availableProviders)
+	} else {
+		explanations = explainCandidates(ctx, req, availableProviders)
+	}
+
+	sort.SliceStable(explanations, func(i, j int) bool {
+		a, b := explanations[i], explanations[j]
+		if (a.Excluded == "") != (b.Excluded == "") {
+			return a.Excluded == ""
+		}
+		if (a.Rank > 0) != (b.Rank > 0) {
+			return a.Rank > 0
+		}
+		if a.Rank != b.Rank {
+			return a.Rank < b.Rank
+		}
+		return a.Provider < b.Provider
+	})
+	return explanations
+}
+
+// explainCandidates checks the providers the way most policies filter them
+// before choosing, with the estimates every provider reports.
+func explainCandidates(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) []CandidateExplanation {
+	base := &BasePolicy{}
+	healthy := base.getHealthyProviders(availableProviders)
+	headroom := base.excludeRateLimited(ctx, healthy)
+
+	explanations := make([]CandidateExplanation, 0, len(availableProviders))
+	for name, provider := range availableProviders {
+		explanation := CandidateExplanation{Provider: name}
+		switch {
+		case healthy[name] == nil:
+			explanation.Excluded = "unhealthy"
+		case headroom[name] == nil:
+			explanation.Excluded = "near its rate limit"
+		case !base.providerSupportsModel(provider, req.Model):
+			explanation.Excluded = fmt.Sprintf("does not serve model %s", req.Model)
+		default:
+			if cost, err := provider.GetCostEstimate(req); err == nil {
+				explanation.EstimatedCost = cost
+			}
+			if latency, err := provider.GetLatencyEstimate(req); err == nil {
+				explanation.EstimatedLatency = latency
+			}
+		}
+		explanations = append(explanations, explanation)
+	}
+	return explanations
+}
+
+// excludedBy marks the providers in candidates missing from kept as excluded
+// for reason.
+func excludedBy(candidates, kept map[string]providers.Provider, reason string) []CandidateExplanation {
+	var excluded []CandidateExplanation
+	for name := range candidates {
+		if _, ok := kept[name]; !ok {
+			excluded = append(excluded, CandidateExplanation{Provider: name, Excluded: reason})
+		}
+	}
+	return excluded
+}
@@ -40,8 +40,12 @@ func (p *FailoverPolicy) DecideRoute(ctx context.Context, req models.ChatRequest
 		return RoutingDecision{}, fmt.Errorf("invalid request: %w", err)
 	}
 
+	// Avoid providers that are about to throttle
+	availableProviders = p.excludeRateLimited(ctx, p.getHealthyProviders(availableProviders))
+
 	// Check if primary provider is available and healthy
-	if p.shouldUsePrimary() {
+	// Deterministic requests return to a healthy primary without hold-off
+	if Deterministic(ctx) || p.shouldUsePrimary() {
 		if provider, exists := availableProviders[p.primaryProvider]; exists && provider.IsHealthy() {
 			if p.providerSupportsModel(provider, req.Model) {
 				decision := RoutingDecision{
@@ -0,0 +1,196 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// ConstraintError is returned when no provider satisfies a routing hint of
+// the request.
+type ConstraintError struct {
+	Constraint string // the hint's field, e.g. max_cost
+	Reason     string
+}
+
+func (e *ConstraintError) Error() string {
+	return fmt.Sprintf("routing constraint %s cannot be satisfied: %s", e.Constraint, e.Reason)
+}
+
+// RequestHints returns the routing hints of a request: those in its body,
+// with the header hints carried by ctx filling in unset fields.
+func RequestHints(ctx context.Context, req models.ChatRequest) models.RoutingHints {
+	var hints models.RoutingHints
+	if header := RequestInfoFrom(ctx).Hints; header != nil {
+		hints = *header
+	}
+	body := req.Routing
+	if body == nil {
+		return hints
+	}
+	if len(body.PreferProviders) > 0 {
+		hints.PreferProviders = body.PreferProviders
+	}
+	if len(body.ExcludeProviders) > 0 {
+		hints.ExcludeProviders = body.ExcludeProviders
+	}
+	if body.MaxCost > 0 {
+		hints.MaxCost = body.MaxCost
+	}
+	if body.MaxLatency > 0 {
+		hints.MaxLatency = body.MaxLatency
+	}
+	if body.RequireStreaming {
+		hints.RequireStreaming = true
+	}
+	if body.Residency != "" {
+		hints.Residency = body.Residency
+	}
+	if body.Priority != "" {
+		hints.Priority = body.Priority
+	}
+	if body.Language != "" {
+		hints.Language = body.Language
+	}
+	return hints
+}
+
+// HintFilter is policy middleware that applies the routing hints of each
+// request around any policy. Excluded providers, providers that cannot
+// stream when streaming is required and providers whose estimates exceed
+// max_cost or max_latency are removed before the policy decides, and
+// preferred providers are the only candidates when one of them is healthy and
+// serves the model. A
+// decision whose own estimates exceed the limits is rejected.
+type HintFilter struct{}
+
+// NewHintFilter creates the routing hint middleware.
+func NewHintFilter() *HintFilter {
+	return &HintFilter{}
+}
+
+// Name returns the middleware name.
+func (f *HintFilter) Name() string {
+	return "routing_hints"
+}
+
+// BeforeDecide removes the candidates that violate the request's hints. It
+// fails with a ConstraintError naming the first hint no candidate satisfies.
+func (f *HintFilter) BeforeDecide(ctx context.Context, req models.ChatRequest, candidates map[string]providers.Provider) (map[string]providers.Provider, error) {
+	hints := RequestHints(ctx, req)
+	if len(candidates) == 0 {
+		return candidates, nil
+	}
+
+	if len(hints.ExcludeProviders) > 0 {
+		excluded := make(map[string]bool, len(hints.ExcludeProviders))
+		for _, name := range hints.ExcludeProviders {
+			excluded[name] = true
+		}
+		candidates = filterCandidates(candidates, func(name string, provider providers.Provider) bool {
+			return !excluded[name]
+		})
+		if len(candidates) == 0 {
+			return nil, &ConstraintError{Constraint: "exclude_providers", Reason: "every provider is excluded"}
+		}
+	}
+
+	if hints.RequireStreaming {
+		candidates = filterCandidates(candidates, func(name string, provider providers.Provider) bool {
+			return providers.Supports(provider, providers.CapabilityStreaming, req.Model)
+		})
+		if len(candidates) == 0 {
+			return nil, &ConstraintError{Constraint: "require_streaming", Reason: "no remaining provider streams completions"}
+		}
+	}
+
+	if hints.MaxCost > 0 {
+		cheapest := -1.0
+		candidates = filterCandidates(candidates, func(name string, provider providers.Provider) bool {
+			cost, err := provider.GetCostEstimate(req)
+			if err != nil {
+				return false
+			}
+			if cheapest < 0 || cost < cheapest {
+				cheapest = cost
+			}
+			return cost <= hints.MaxCost
+		})
+		if len(candidates) == 0 {
+			reason := fmt.Sprintf("no provider estimates %s within $%.6f", req.Model, hints.MaxCost)
+			if cheapest >= 0 {
+				reason += fmt.Sprintf(", the cheapest estimate is $%.6f", cheapest)
+			}
+			return nil, &ConstraintError{Constraint: "max_cost", Reason: reason}
+		}
+	}
+
+	if hints.MaxLatency > 0 {
+		fastest := time.Duration(-1)
+		candidates = filterCandidates(candidates, func(name string, provider providers.Provider) bool {
+			latency, err := provider.GetLatencyEstimate(req)
+			if err != nil {
+				return false
+			}
+			if fastest < 0 || latency < fastest {
+				fastest = latency
+			}
+			return latency <= hints.MaxLatency
+		})
+		if len(candidates) == 0 {
+			reason := fmt.Sprintf("no provider estimates %s within %s", req.Model, hints.MaxLatency)
+			if fastest >= 0 {
+				reason += fmt.Sprintf(", the fastest estimate is %s", fastest)
+			}
+			return nil, &ConstraintError{Constraint: "max_latency", Reason: reason}
+		}
+	}
+
+	if len(hints.PreferProviders) > 0 {
+		preferred := make(map[string]providers.Provider)
+		for _, name := range hints.PreferProviders {
+			if provider, exists := candidates[name]; exists && provider.IsHealthy() && servesModel(provider, req.Model) {
+				preferred[name] = provider
+			}
+		}
+		if len(preferred) > 0 {
+			candidates = preferred
+		}
+	}
+	return candidates, nil
+}
+
+// AfterDecide rejects a decision whose estimates exceed the request's limits,
+// as policies may route to another model than the one requested.
+func (f *HintFilter) AfterDecide(ctx context.Context, req models.ChatRequest, decision RoutingDecision) (RoutingDecision, error) {
+	hints := RequestHints(ctx, req)
+	if hints.MaxCost > 0 && decision.EstimatedCost > hints.MaxCost {
+		return RoutingDecision{}, &ConstraintError{
+			Constraint: "max_cost",
+			Reason: fmt.Sprintf("%s/%s is estimated at $%.6f, above $%.6f",
+				decision.ProviderName, decision.Model, decision.EstimatedCost, hints.MaxCost),
+		}
+	}
+	if hints.MaxLatency > 0 && decision.EstimatedLatency > hints.MaxLatency {
+		return RoutingDecision{}, &ConstraintError{
+			Constraint: "max_latency",
+			Reason: fmt.Sprintf("%s/%s is estimated at %s, above %s",
+				decision.ProviderName, decision.Model, decision.EstimatedLatency, hints.MaxLatency),
+		}
+	}
+	return decision, nil
+}
+
+// filterCandidates returns the candidates keep accepts.
+func filterCandidates(candidates map[string]providers.Provider, keep func(string, providers.Provider) bool) map[string]providers.Provider {
+	kept := make(map[string]providers.Provider, len(candidates))
+	for name, provider := range candidates {
+		if keep(name, provider) {
+			kept[name] = provider
+		}
+	}
+	return kept
+}
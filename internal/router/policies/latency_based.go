@@ -0,0 +1,183 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// LatencyBasedPolicy routes to the provider with the lowest observed latency
+// for the requested model. Latency comes from recent requests reported through
+// UpdateMetrics. Until a provider has enough recent requests for a model, the
+// latency of its last health check is used instead, and failing that the
+// provider's own estimate.
+type LatencyBasedPolicy struct {
+	*BasePolicy
+	percentile float64
+	window     int
+	maxAge     time.Duration
+	minSamples int
+
+	mutex   sync.Mutex
+	samples map[string]*latencySamples // keyed by provider and model
+}
+
+// latencySample is the latency of one request.
+type latencySample struct {
+	latency time.Duration
+	at      time.Time
+}
+
+// latencySamples is a ring of the most recent request latencies.
+type latencySamples struct {
+	ring []latencySample
+	next int
+}
+
+// add records a sample, overwriting the oldest once the ring is full.
+func (s *latencySamples) add(sample latencySample, size int) {
+	if len(s.ring) < size {
+		s.ring = append(s.ring, sample)
+		return
+	}
+	s.ring[s.next] = sample
+	s.next = (s.next + 1) % size
+}
+
+// NewLatencyBasedPolicy creates a latency-based policy ranking providers by
+// their p95 latency over the last 100 requests of the past 5 minutes.
+func NewLatencyBasedPolicy() *LatencyBasedPolicy {
+	return &LatencyBasedPolicy{
+		BasePolicy: NewBasePolicy(
+			"latency_based",
+			"Routes requests to the provider with the lowest observed latency for the requested model",
+		),
+		percentile: 0.95,
+		window:     100,
+		maxAge:     5 * time.Minute,
+		minSamples: 5,
+		samples:    make(map[string]*latencySamples),
+	}
+}
+
+// DecideRoute selects the healthy provider with the lowest latency for the model.
+func (p *LatencyBasedPolicy) DecideRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) (RoutingDecision, error) {
+	if err := p.ValidateRequest(req); err != nil {
+		return RoutingDecision{}, fmt.Errorf("invalid request: %w", err)
+	}
+
+	healthyProviders := p.getHealthyProviders(availableProviders)
+	if len(healthyProviders) == 0 {
+		return RoutingDecision{}, fmt.Errorf("no healthy providers available")
+	}
+
+	// Avoid providers that are about to throttle
+	healthyProviders = p.excludeRateLimited(ctx, healthyProviders)
+
+	type candidate struct {
+		name     string
+		latency  time.Duration
+		source   string
+		measured bool // ranked by request history
+	}
+	var candidates []candidate
+	for name, provider := range healthyProviders {
+		if !p.providerSupportsModel(provider, req.Model) {
+			continue
+		}
+		latency, source, measured := p.latency(ctx, name, provider, req)
+		candidates = append(candidates, candidate{name: name, latency: latency, source: source, measured: measured})
+	}
+	if len(candidates) == 0 {
+		return RoutingDecision{}, fmt.Errorf("no available providers for model %s", req.Model)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].latency != candidates[j].latency {
+			return candidates[i].latency < candidates[j].latency
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	best := candidates[0]
+
+	confidence := 1.0
+	if !best.measured {
+		confidence = 0.5
+	}
+	return RoutingDecision{
+		ProviderName:     best.name,
+		Model:            req.Model,
+		Reason:           fmt.Sprintf("Lowest latency (%s from %s)", best.latency.Round(time.Millisecond), best.source),
+		EstimatedLatency: best.latency,
+		Confidence:       confidence,
+	}, nil
+}
+
+// latency returns the latency used to rank a provider, where it came from and
+// whether it was measured on recent requests. Deterministic requests are
+// ranked by health check latency, as recent requests differ between runs.
+func (p *LatencyBasedPolicy) latency(ctx context.Context, name string, provider providers.Provider, req models.ChatRequest) (time.Duration, string, bool) {
+	if observed, ok := p.observed(name, req.Model); ok && !Deterministic(ctx) {
+		label := "p95 of recent requests"
+		if p.percentile == 0.5 {
+			label = "p50 of recent requests"
+		}
+		return observed, label, true
+	}
+	if health := provider.GetHealth(); health.Latency > 0 {
+		return health.Latency, "health check", false
+	}
+	if estimate, err := provider.GetLatencyEstimate(req); err == nil {
+		return estimate, "provider estimate", false
+	}
+	return time.Duration(1<<63 - 1), "no measurements", false
+}
+
+// observed returns the configured percentile of recent request latencies, if
+// there are at least minSamples within maxAge.
+func (p *LatencyBasedPolicy) observed(providerName, model string) (time.Duration, bool) {
+	cutoff := time.Now().Add(-p.maxAge)
+
+	p.mutex.Lock()
+	samples, exists := p.samples[providerName+"/"+model]
+	var recent []time.Duration
+	if exists {
+		for _, sample := range samples.ring {
+			if sample.at.After(cutoff) {
+				recent = append(recent, sample.latency)
+			}
+		}
+	}
+	p.mutex.Unlock()
+
+	if len(recent) < p.minSamples {
+		return 0, false
+	}
+	sort.Slice(recent, func(i, j int) bool { return recent[i] < recent[j] })
+	index := int(p.percentile*float64(len(recent)-1) + 0.5)
+	return recent[index], true
+}
+
+// UpdateMetrics records the latency of a successful request. Failed requests
+// are left to the health checker, which takes failing providers out of rotation.
+func (p *LatencyBasedPolicy) UpdateMetrics(decision RoutingDecision, success bool, latency time.Duration) {
+	if !success || latency <= 0 {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	key := decision.ProviderName + "/" + decision.Model
+	samples, exists := p.samples[key]
+	if !exists {
+		samples = &latencySamples{}
+		p.samples[key] = samples
+	}
+	samples.add(latencySample{latency: latency, at: time.Now()}, p.window)
+}
@@ -0,0 +1,89 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// LeastLoadedPolicy routes to the healthy provider with the fewest concurrent
+// requests for the requested model, so no provider saturates while others
+// idle. Load counts in-flight requests and requests queued for a concurrency
+// slot. Ties are broken at random, which spreads light traffic evenly.
+type LeastLoadedPolicy struct {
+	*BasePolicy
+}
+
+// NewLeastLoadedPolicy creates a least-loaded routing policy.
+func NewLeastLoadedPolicy() *LeastLoadedPolicy {
+	return &LeastLoadedPolicy{
+		BasePolicy: NewBasePolicy(
+			"least_loaded",
+			"Routes requests to the healthy provider with the fewest in-flight requests",
+		),
+	}
+}
+
+// DecideRoute selects the provider with the fewest in-flight requests.
+func (p *LeastLoadedPolicy) DecideRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) (RoutingDecision, error) {
+	if err := p.ValidateRequest(req); err != nil {
+		return RoutingDecision{}, fmt.Errorf("invalid request: %w", err)
+	}
+
+	healthyProviders := p.getHealthyProviders(availableProviders)
+	if len(healthyProviders) == 0 {
+		return RoutingDecision{}, fmt.Errorf("no healthy providers available")
+	}
+
+	// Avoid providers that are about to throttle
+	healthyProviders = p.excludeRateLimited(ctx, healthyProviders)
+
+	var least []string
+	leastLoad := -1
+	for name, provider := range healthyProviders {
+		if !p.providerSupportsModel(provider, req.Model) {
+			continue
+		}
+		load := 0
+		if !Deterministic(ctx) {
+			load = providerLoad(provider)
+		}
+		switch {
+		case leastLoad < 0 || load < leastLoad:
+			least, leastLoad = []string{name}, load
+		case load == leastLoad:
+			least = append(least, name)
+		}
+	}
+	if len(least) == 0 {
+		return RoutingDecision{}, fmt.Errorf("no available providers for model %s", req.Model)
+	}
+
+	// Sorted so a given draw always maps to the same provider
+	sort.Strings(least)
+	chosen := least[int(Draw(ctx, req)*float64(len(least)))]
+	reason := fmt.Sprintf("Least loaded (%d in flight)", leastLoad)
+	if Deterministic(ctx) {
+		reason = fmt.Sprintf("Deterministic choice among %d providers", len(least))
+	}
+	return RoutingDecision{
+		ProviderName: chosen,
+		Model:        req.Model,
+		Reason:       reason,
+		Confidence:   1.0 / float64(len(least)),
+	}, nil
+}
+
+// providerLoad returns a provider's in-flight and queued requests. Providers
+// that do not report them count as idle.
+func providerLoad(provider providers.Provider) int {
+	reporter, ok := provider.(providers.ConcurrencyReporter)
+	if !ok {
+		return 0
+	}
+	stats := reporter.GetConcurrencyStats()
+	return stats.InFlight + stats.Queued
+}
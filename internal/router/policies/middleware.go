@@ -0,0 +1,222 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// Middleware intercepts routing around any policy, so cross-cutting rules
+// (allowlists, residency, budgets, experiments) are written once instead of
+// inside every policy.
+type Middleware interface {
+	// Name identifies the middleware in errors and logs.
+	Name() string
+
+	// BeforeDecide runs before the policy and returns the providers the
+	// policy may choose from. It must not modify candidates; return a new
+	// map to narrow it. An error rejects the request.
+	BeforeDecide(ctx context.Context, req models.ChatRequest, candidates map[string]providers.Provider) (map[string]providers.Provider, error)
+
+	// AfterDecide runs after the policy and may adjust or reject the decision.
+	AfterDecide(ctx context.Context, req models.ChatRequest, decision RoutingDecision) (RoutingDecision, error)
+}
+
+// MiddlewareFuncs adapts a pair of functions to the Middleware interface.
+// Either function may be nil.
+type MiddlewareFuncs struct {
+	Label  string
+	Before func(ctx context.Context, req models.ChatRequest, candidates map[string]providers.Provider) (map[string]providers.Provider, error)
+	After  func(ctx context.Context, req models.ChatRequest, decision RoutingDecision) (RoutingDecision, error)
+}
+
+// Name returns the middleware label.
+func (m MiddlewareFuncs) Name() string {
+	return m.Label
+}
+
+// BeforeDecide calls Before, if set.
+func (m MiddlewareFuncs) BeforeDecide(ctx context.Context, req models.ChatRequest, candidates map[string]providers.Provider) (map[string]providers.Provider, error) {
+	if m.Before == nil {
+		return candidates, nil
+	}
+	return m.Before(ctx, req, candidates)
+}
+
+// AfterDecide calls After, if set.
+func (m MiddlewareFuncs) AfterDecide(ctx context.Context, req models.ChatRequest, decision RoutingDecision) (RoutingDecision, error) {
+	if m.After == nil {
+		return decision, nil
+	}
+	return m.After(ctx, req, decision)
+}
+
+// ChainedPolicy wraps a policy with middleware. BeforeDecide hooks run in
+// order, AfterDecide hooks in reverse order, so the first middleware is the
+// outermost layer.
+type ChainedPolicy struct {
+	RoutingPolicy
+	middleware []Middleware
+}
+
+// Chain wraps policy with the given middleware. Chaining an already chained
+// policy appends to its middleware rather than nesting.
+func Chain(policy RoutingPolicy, middleware ...Middleware) RoutingPolicy {
+	if chained, ok := policy.(*ChainedPolicy); ok {
+		combined := append(append([]Middleware(nil), chained.middleware...), middleware...)
+		return &ChainedPolicy{RoutingPolicy: chained.RoutingPolicy, middleware: combined}
+	}
+	return &ChainedPolicy{RoutingPolicy: policy, middleware: middleware}
+}
+
+// DecideRoute runs the middleware around the wrapped policy.
+func (p *ChainedPolicy) DecideRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) (RoutingDecision, error) {
+	candidates := availableProviders
+	for _, m := range p.middleware {
+		var err error
+		candidates, err = m.BeforeDecide(ctx, req, candidates)
+		if err != nil {
+			return RoutingDecision{}, fmt.Errorf("%s: %w", m.Name(), err)
+		}
+		if len(candidates) == 0 {
+			return RoutingDecision{}, fmt.Errorf("%s: no providers left for request", m.Name())
+		}
+	}
+
+	decision, err := p.RoutingPolicy.DecideRoute(ctx, req, candidates)
+	if err != nil {
+		return RoutingDecision{}, err
+	}
+
+	for i := len(p.middleware) - 1; i >= 0; i-- {
+		decision, err = p.middleware[i].AfterDecide(ctx, req, decision)
+		if err != nil {
+			return RoutingDecision{}, fmt.Errorf("%s: %w", p.middleware[i].Name(), err)
+		}
+	}
+
+	return decision, nil
+}
+
+// ExplainRoute runs the BeforeDecide hooks and explains the wrapped policy
+// over the providers they leave. Providers a hook removes are excluded in its
+// name; when a hook rejects the request, every remaining provider is.
+func (p *ChainedPolicy) ExplainRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) []CandidateExplanation {
+	var excluded []CandidateExplanation
+	candidates := availableProviders
+	for _, m := range p.middleware {
+		kept, err := m.BeforeDecide(ctx, req, candidates)
+		reason := "excluded by " + m.Name()
+		if err != nil {
+			kept, reason = nil, fmt.Sprintf("%s: %v", m.Name(), err)
+		}
+		excluded = append(excluded, excludedBy(candidates, kept, reason)...)
+		if len(kept) == 0 {
+			return excluded
+		}
+		candidates = kept
+	}
+	return append(Explain(ctx, p.RoutingPolicy, req, candidates), excluded...)
+}
+
+// Unwrap returns the wrapped policy.
+func (p *ChainedPolicy) Unwrap() RoutingPolicy {
+	return p.RoutingPolicy
+}
+
+// Middleware returns the names of the middleware in the chain.
+func (p *ChainedPolicy) Middleware() []string {
+	names := make([]string, len(p.middleware))
+	for i, m := range p.middleware {
+		names[i] = m.Name()
+	}
+	return names
+}
+
+// MiddlewareConfig configures a built-in middleware.
+type MiddlewareConfig struct {
+	Type   string                 `mapstructure:"type"`
+	Config map[string]interface{} `mapstructure:"config"`
+}
+
+// NewMiddleware creates a built-in middleware from configuration.
+func NewMiddleware(config MiddlewareConfig) (Middleware, error) {
+	switch config.Type {
+	case "provider_filter":
+		return NewProviderFilter(stringList(config.Config["allow"]), stringList(config.Config["deny"])), nil
+	case "schedule":
+		var scheduleConfig ScheduleConfig
+		if err := DecodeConfig(config.Config, &scheduleConfig); err != nil {
+			return nil, fmt.Errorf("invalid schedule middleware config: %w", err)
+		}
+		return NewSchedule(scheduleConfig)
+	default:
+		return nil, fmt.Errorf("unknown policy middleware: %s", config.Type)
+	}
+}
+
+// ProviderFilter restricts routing to allowed providers and never routes to
+// denied ones. An empty allow list allows every provider.
+type ProviderFilter struct {
+	allow map[string]bool
+	deny  map[string]bool
+}
+
+// NewProviderFilter creates a provider allow/deny filter.
+func NewProviderFilter(allow, deny []string) *ProviderFilter {
+	filter := &ProviderFilter{
+		allow: make(map[string]bool),
+		deny:  make(map[string]bool),
+	}
+	for _, name := range allow {
+		filter.allow[name] = true
+	}
+	for _, name := range deny {
+		filter.deny[name] = true
+	}
+	return filter
+}
+
+// Name returns the middleware name.
+func (f *ProviderFilter) Name() string {
+	return "provider_filter"
+}
+
+// BeforeDecide removes providers that are not allowed.
+func (f *ProviderFilter) BeforeDecide(ctx context.Context, req models.ChatRequest, candidates map[string]providers.Provider) (map[string]providers.Provider, error) {
+	filtered := make(map[string]providers.Provider)
+	for name, provider := range candidates {
+		if f.deny[name] || (len(f.allow) > 0 && !f.allow[name]) {
+			continue
+		}
+		filtered[name] = provider
+	}
+	return filtered, nil
+}
+
+// AfterDecide leaves the decision unchanged.
+func (f *ProviderFilter) AfterDecide(ctx context.Context, req models.ChatRequest, decision RoutingDecision) (RoutingDecision, error) {
+	return decision, nil
+}
+
+// stringList converts a decoded config value to a list of strings.
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	case string:
+		return []string{v}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,102 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// ProviderWeights are provider weights set by operators at runtime, to drain
+// a provider or boost its share of traffic without a restart. The weighted
+// policy uses them instead of its configured weights; the cost-based policy,
+// which has no weights of its own, divides its scores by them, so 2 makes a
+// provider look half as expensive. A weight of 0 drains the provider: neither
+// policy routes new requests to it.
+type ProviderWeights struct {
+	mutex   sync.RWMutex
+	weights map[string]float64
+}
+
+// NewProviderWeights creates an empty set of operator weights.
+func NewProviderWeights() *ProviderWeights {
+	return &ProviderWeights{weights: make(map[string]float64)}
+}
+
+// Set sets a provider's weight and returns the weight it replaces, if any.
+func (w *ProviderWeights) Set(provider string, weight float64) (float64, bool, error) {
+	if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+		return 0, false, fmt.Errorf("weight must be a finite number of at least 0, got %v", weight)
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	previous, existed := w.weights[provider]
+	w.weights[provider] = weight
+	return previous, existed, nil
+}
+
+// Clear removes a provider's weight, restoring the policy's own, and returns
+// the weight removed, if any.
+func (w *ProviderWeights) Clear(provider string) (float64, bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	previous, existed := w.weights[provider]
+	delete(w.weights, provider)
+	return previous, existed
+}
+
+// Get returns a provider's weight, if one is set.
+func (w *ProviderWeights) Get(provider string) (float64, bool) {
+	if w == nil {
+		return 0, false
+	}
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	weight, exists := w.weights[provider]
+	return weight, exists
+}
+
+// All returns a copy of the weights set.
+func (w *ProviderWeights) All() map[string]float64 {
+	weights := make(map[string]float64)
+	if w == nil {
+		return weights
+	}
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	for provider, weight := range w.weights {
+		weights[provider] = weight
+	}
+	return weights
+}
+
+// OperatorWeight returns the operator weight of a provider for the request
+// carried by ctx, if one is set.
+func OperatorWeight(ctx context.Context, provider string) (float64, bool) {
+	return RequestInfoFrom(ctx).Weights.Get(provider)
+}
+
+// WithOverrides returns the report with the operator weights in place of the
+// configured ones, scaled by the factors in effect.
+func (r WeightReport) WithOverrides(weights map[string]float64) WeightReport {
+	if len(weights) == 0 {
+		return r
+	}
+	providers := make(map[string]ProviderWeight, len(r.Providers)+len(weights))
+	for name, weight := range r.Providers {
+		providers[name] = weight
+	}
+	for name, override := range weights {
+		weight, exists := providers[name]
+		if !exists {
+			weight = ProviderWeight{Factor: 1}
+		}
+		override := override
+		weight.Override = &override
+		weight.Effective = override * weight.Factor
+		providers[name] = weight
+	}
+	r.Providers = providers
+	return r
+}
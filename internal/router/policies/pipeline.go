@@ -0,0 +1,177 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// StageResult is the outcome of a pipeline stage. A stage either decides the
+// request or defers it to the next stage, optionally narrowing the candidate
+// providers or replacing the requested model first.
+type StageResult struct {
+	Decision   *RoutingDecision              // set when the stage decided; nil defers
+	Candidates map[string]providers.Provider // narrowed candidates; nil keeps them
+	Model      string                        // replaces the requested model for later stages
+	Category   string                        // content category the stage classified the request as
+	Reason     string                        // why the stage deferred, shown in the final reason
+}
+
+// Stage is implemented by policies that can defer requests they have no
+// opinion on, such as rules matching nothing, instead of routing them with
+// their own fallback. In a pipeline, such policies are asked with DecideStage;
+// other policies always decide.
+type Stage interface {
+	// DecideStage decides the request or defers it to the next stage. It must
+	// not modify candidates; return a new map to narrow them.
+	DecideStage(ctx context.Context, req models.ChatRequest, candidates map[string]providers.Provider) (StageResult, error)
+}
+
+// PipelineStage is one stage of a pipeline: a policy, or a middleware that
+// narrows the candidates.
+type PipelineStage struct {
+	Name       string
+	Policy     RoutingPolicy
+	Middleware Middleware
+}
+
+// PipelinePolicy routes requests through ordered stages, for example rules,
+// then semantic matching, then cost-based selection. Each stage decides the
+// request, narrows the candidate providers, or defers to the next stage. The
+// last stage is a policy that always decides.
+type PipelinePolicy struct {
+	*BasePolicy
+	stages []PipelineStage
+}
+
+// NewPipelinePolicy creates a pipeline of the given stages. Middleware stages
+// narrow the candidates with BeforeDecide, and adjust the final decision with
+// AfterDecide in reverse order.
+func NewPipelinePolicy(stages []PipelineStage) *PipelinePolicy {
+	return &PipelinePolicy{
+		BasePolicy: NewBasePolicy(
+			"pipeline",
+			"Routes requests through ordered stages that decide, narrow the candidates or defer to the next stage",
+		),
+		stages: stages,
+	}
+}
+
+// DecideRoute runs the stages in order until one decides.
+func (p *PipelinePolicy) DecideRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) (RoutingDecision, error) {
+	if err := p.ValidateRequest(req); err != nil {
+		return RoutingDecision{}, fmt.Errorf("invalid request: %w", err)
+	}
+
+	candidates := availableProviders
+	var notes []string
+	var passed []Middleware
+	var category string
+	for i, stage := range p.stages {
+		var decision RoutingDecision
+		switch {
+		case stage.Middleware != nil:
+			narrowed, err := stage.Middleware.BeforeDecide(ctx, req, candidates)
+			if err != nil {
+				return RoutingDecision{}, fmt.Errorf("stage %s: %w", stage.Name, err)
+			}
+			if len(narrowed) == 0 {
+				return RoutingDecision{}, fmt.Errorf("stage %s: no providers left for request", stage.Name)
+			}
+			candidates = narrowed
+			passed = append(passed, stage.Middleware)
+			continue
+
+		case i < len(p.stages)-1 && isStage(stage.Policy):
+			result, err := stage.Policy.(Stage).DecideStage(ctx, req, candidates)
+			if err != nil {
+				return RoutingDecision{}, fmt.Errorf("stage %s: %w", stage.Name, err)
+			}
+			if result.Decision == nil {
+				if result.Candidates != nil {
+					if len(result.Candidates) == 0 {
+						return RoutingDecision{}, fmt.Errorf("stage %s: no providers left for request", stage.Name)
+					}
+					candidates = result.Candidates
+				}
+				if result.Model != "" {
+					req.Model = result.Model
+				}
+				if result.Category != "" {
+					category = result.Category
+				}
+				if result.Reason != "" {
+					notes = append(notes, fmt.Sprintf("%s: %s", stage.Name, result.Reason))
+				}
+				continue
+			}
+			decision = *result.Decision
+
+		default:
+			var err error
+			decision, err = stage.Policy.DecideRoute(ctx, req, candidates)
+			if err != nil {
+				return RoutingDecision{}, fmt.Errorf("stage %s: %w", stage.Name, err)
+			}
+		}
+
+		for j := len(passed) - 1; j >= 0; j-- {
+			var err error
+			decision, err = passed[j].AfterDecide(ctx, req, decision)
+			if err != nil {
+				return RoutingDecision{}, fmt.Errorf("stage %s: %w", passed[j].Name(), err)
+			}
+		}
+		if decision.Category == "" {
+			decision.Category = category
+		}
+		decision.Reason = strings.Join(append(notes, fmt.Sprintf("%s: %s", stage.Name, decision.Reason)), " → ")
+		return decision, nil
+	}
+
+	// Unreachable with a validated pipeline, whose last stage is a policy
+	return RoutingDecision{}, fmt.Errorf("no pipeline stage decided the request")
+}
+
+// isStage reports whether a policy can defer requests.
+func isStage(policy RoutingPolicy) bool {
+	_, ok := policy.(Stage)
+	return ok
+}
+
+// Stages returns the names of the stages in order.
+func (p *PipelinePolicy) Stages() []string {
+	names := make([]string, len(p.stages))
+	for i, stage := range p.stages {
+		names[i] = stage.Name
+	}
+	return names
+}
+
+// Close closes the stages that hold resources, such as plugin policies.
+func (p *PipelinePolicy) Close() error {
+	var firstErr error
+	for _, stage := range p.stages {
+		if closer, ok := stage.Policy.(io.Closer); ok {
+			if err := closer.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// UpdateMetrics records the outcome and passes it on to every policy stage.
+func (p *PipelinePolicy) UpdateMetrics(decision RoutingDecision, success bool, latency time.Duration) {
+	p.BasePolicy.UpdateMetrics(decision, success, latency)
+	for _, stage := range p.stages {
+		if stage.Policy != nil {
+			stage.Policy.UpdateMetrics(decision, success, latency)
+		}
+	}
+}
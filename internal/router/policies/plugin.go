@@ -0,0 +1,163 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	goplugin "plugin"
+	"strings"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/pkg/plugin"
+)
+
+// PluginPolicy routes requests with an external routing policy plugin,
+// launched by the router or running as a service. Requests the plugin
+// defers, or fails to route in time, are routed by the fallback policy, so a
+// slow or crashed plugin degrades routing instead of failing requests.
+type PluginPolicy struct {
+	*BasePolicy
+	client   *plugin.PolicyClient
+	timeout  time.Duration
+	fallback RoutingPolicy
+}
+
+// NewPluginPolicy creates a policy routing with a started plugin client.
+// Each routing call times out after timeout.
+func NewPluginPolicy(client *plugin.PolicyClient, timeout time.Duration, fallback RoutingPolicy) *PluginPolicy {
+	return &PluginPolicy{
+		BasePolicy: NewBasePolicy(
+			"plugin:"+client.Name(),
+			"Routes requests with an external routing policy plugin",
+		),
+		client:   client,
+		timeout:  timeout,
+		fallback: fallback,
+	}
+}
+
+// DecideRoute asks the plugin to route the request, and falls back when it
+// defers, fails or picks a provider that cannot serve the request.
+func (p *PluginPolicy) DecideRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) (RoutingDecision, error) {
+	if err := p.ValidateRequest(req); err != nil {
+		return RoutingDecision{}, fmt.Errorf("invalid request: %w", err)
+	}
+
+	decision, err := p.route(ctx, req, availableProviders)
+	if err == nil {
+		return decision, nil
+	}
+
+	decision, fallbackErr := p.fallback.DecideRoute(ctx, req, availableProviders)
+	if fallbackErr != nil {
+		return RoutingDecision{}, fallbackErr
+	}
+	decision.Reason = fmt.Sprintf("Fallback (%v): %s", err, decision.Reason)
+	return decision, nil
+}
+
+// route asks the plugin for a decision and checks it.
+func (p *PluginPolicy) route(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) (RoutingDecision, error) {
+	routeCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	answer, err := p.client.Route(routeCtx, p.routeArgs(ctx, req, availableProviders))
+	if err != nil {
+		return RoutingDecision{}, fmt.Errorf("plugin failed: %w", err)
+	}
+	if answer.Defer {
+		if answer.Reason != "" {
+			return RoutingDecision{}, fmt.Errorf("plugin deferred: %s", answer.Reason)
+		}
+		return RoutingDecision{}, fmt.Errorf("plugin deferred")
+	}
+
+	model := req.Model
+	if answer.Model != "" {
+		model = answer.Model
+	}
+	provider, exists := availableProviders[answer.Provider]
+	if !exists || !provider.IsHealthy() {
+		return RoutingDecision{}, fmt.Errorf("plugin chose unavailable provider %q", answer.Provider)
+	}
+	if !p.providerSupportsModel(provider, model) {
+		return RoutingDecision{}, fmt.Errorf("plugin chose %s, which does not serve %s", answer.Provider, model)
+	}
+
+	reason := answer.Reason
+	if reason == "" {
+		reason = "Chosen by plugin"
+	}
+	confidence := answer.Confidence
+	if confidence == 0 {
+		confidence = 1.0
+	}
+	return RoutingDecision{
+		ProviderName: answer.Provider,
+		Model:        model,
+		Reason:       reason,
+		Confidence:   confidence,
+	}, nil
+}
+
+// routeArgs describes the request and its candidate providers to the plugin.
+func (p *PluginPolicy) routeArgs(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) plugin.RouteArgs {
+	info := RequestInfoFrom(ctx)
+	headers := make(map[string]string, len(info.Headers))
+	for name, values := range info.Headers {
+		if len(values) > 0 {
+			headers[strings.ToLower(name)] = values[0]
+		}
+	}
+
+	candidates := make([]plugin.Candidate, 0, len(availableProviders))
+	for name, provider := range availableProviders {
+		candidate := plugin.Candidate{Name: name, Healthy: provider.IsHealthy()}
+		candidate.Models, _ = provider.GetModels()
+		if cost, err := provider.GetCostEstimate(req); err == nil {
+			candidate.CostEstimate = cost
+		}
+		if latency, err := provider.GetLatencyEstimate(req); err == nil {
+			candidate.LatencyEstimate = latency
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	return plugin.RouteArgs{
+		Request:    providers.ToPluginRequest(req),
+		Tenant:     info.Tenant,
+		Headers:    headers,
+		Candidates: candidates,
+	}
+}
+
+// UpdateMetrics reports the outcome to the plugin and the fallback policy.
+func (p *PluginPolicy) UpdateMetrics(decision RoutingDecision, success bool, latency time.Duration) {
+	p.BasePolicy.UpdateMetrics(decision, success, latency)
+	p.client.Observe(plugin.RouteOutcome{
+		Provider: decision.ProviderName,
+		Model:    decision.Model,
+		Success:  success,
+		Latency:  latency,
+	})
+	p.fallback.UpdateMetrics(decision, success, latency)
+}
+
+// Close disconnects from the plugin, stopping it if the router launched it.
+func (p *PluginPolicy) Close() error {
+	return p.client.Kill()
+}
+
+// LoadLibraries opens Go plugin libraries (built with -buildmode=plugin)
+// whose init functions register policy types with Register, so
+// routing_policy can select them by name. Libraries must be built with the
+// same Go version and module versions as semaroute.
+func LoadLibraries(paths []string) error {
+	for _, path := range paths {
+		if _, err := goplugin.Open(path); err != nil {
+			return fmt.Errorf("failed to load policy library %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -3,11 +3,16 @@ package policies
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 	"github.com/semantrix/semaroute/internal/models"
 	"github.com/semantrix/semaroute/internal/providers"
 )
 
+// rateLimitThreshold is the fraction of a provider's rate limit below which
+// policies avoid it, so requests go elsewhere before the provider throttles.
+const rateLimitThreshold = 0.05
+
 // RoutingDecision represents the result of a routing policy decision.
 type RoutingDecision struct {
 	ProviderName string    `json:"provider_name"`
@@ -17,11 +22,14 @@ type RoutingDecision struct {
 	EstimatedLatency time.Duration `json:"estimated_latency,omitempty"`
 	Confidence   float64   `json:"confidence"`
 	Fallback     bool      `json:"fallback"`
+	Spillover    string    `json:"spillover,omitempty"` // provider the request spilled over from while rate limited
+	Category     string    `json:"category,omitempty"`  // content category the request was routed by
 }
 
 // RoutingPolicy defines the interface for intelligent routing strategies.
 type RoutingPolicy interface {
 	// DecideRoute selects the best provider/model based on the request, cost, health, and latency.
+	// availableProviders is a read-only snapshot of a providers.ProviderSet and must not be modified.
 	DecideRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) (RoutingDecision, error)
 	
 	// GetName returns the name of this routing policy.
@@ -42,6 +50,7 @@ type BasePolicy struct {
 	name        string
 	description string
 	metrics     map[string]interface{}
+	metricsMutex sync.Mutex
 }
 
 // NewBasePolicy creates a new base policy.
@@ -77,6 +86,8 @@ func (p *BasePolicy) ValidateRequest(req models.ChatRequest) error {
 // UpdateMetrics provides a basic metrics update implementation.
 func (p *BasePolicy) UpdateMetrics(decision RoutingDecision, success bool, latency time.Duration) {
 	// In production, this would update Prometheus metrics, etc.
+	p.metricsMutex.Lock()
+	defer p.metricsMutex.Unlock()
 	p.metrics["last_decision"] = decision
 	p.metrics["last_success"] = success
 	p.metrics["last_latency"] = latency
@@ -84,7 +95,14 @@ func (p *BasePolicy) UpdateMetrics(decision RoutingDecision, success bool, laten
 
 // GetMetrics returns the current metrics for this policy.
 func (p *BasePolicy) GetMetrics() map[string]interface{} {
-	return p.metrics
+	p.metricsMutex.Lock()
+	defer p.metricsMutex.Unlock()
+
+	metrics := make(map[string]interface{}, len(p.metrics))
+	for key, value := range p.metrics {
+		metrics[key] = value
+	}
+	return metrics
 }
 
 // Helper function to check if a provider supports the requested model.
@@ -112,3 +130,22 @@ func (p *BasePolicy) getHealthyProviders(availableProviders map[string]providers
 	}
 	return healthy
 }
+
+// Helper function to drop providers that are about to hit their rate limit.
+// If every provider is near its limit, all of them are returned unchanged, as
+// they are in deterministic mode.
+func (p *BasePolicy) excludeRateLimited(ctx context.Context, availableProviders map[string]providers.Provider) map[string]providers.Provider {
+	if Deterministic(ctx) {
+		return availableProviders
+	}
+	available := make(map[string]providers.Provider)
+	for name, provider := range availableProviders {
+		if !providers.IsNearRateLimit(provider, rateLimitThreshold) {
+			available[name] = provider
+		}
+	}
+	if len(available) == 0 {
+		return availableProviders
+	}
+	return available
+}
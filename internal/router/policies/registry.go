@@ -0,0 +1,674 @@
+package policies
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/semantrix/semaroute/pkg/plugin"
+)
+
+// Factory creates a routing policy from the "config" section of its
+// routing_policy configuration. Factories should decode it with DecodeConfig
+// so unknown or mistyped keys are rejected.
+type Factory func(config map[string]interface{}) (RoutingPolicy, error)
+
+var (
+	factoriesMutex sync.RWMutex
+	factories      = make(map[string]Factory)
+)
+
+func init() {
+	Register("bandit", newBanditFromConfig)
+	Register("canary", newCanaryFromConfig)
+	Register("category", newCategoryFromConfig)
+	Register("complexity", newComplexityFromConfig)
+	Register("cost_based", newCostBasedFromConfig)
+	Register("failover", newFailoverFromConfig)
+	Register("latency_based", newLatencyBasedFromConfig)
+	Register("least_loaded", newLeastLoadedFromConfig)
+	Register("pipeline", newPipelineFromConfig)
+	Register("plugin", newPluginFromConfig)
+	Register("round_robin", newRoundRobinFromConfig)
+	Register("rules", newRulesFromConfig)
+	Register("semantic", newSemanticFromConfig)
+	Register("sticky", newStickyFromConfig)
+	Register("weighted", newWeightedFromConfig)
+}
+
+// Register makes a policy type selectable by name in configuration. It is
+// meant to be called from init functions and panics if the name is already
+// registered or the factory is nil.
+func Register(name string, factory Factory) {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+
+	if factory == nil {
+		panic("policies: Register factory is nil for " + name)
+	}
+	if _, exists := factories[name]; exists {
+		panic("policies: Register called twice for " + name)
+	}
+	factories[name] = factory
+}
+
+// New creates the policy registered under name.
+func New(name string, config map[string]interface{}) (RoutingPolicy, error) {
+	factoriesMutex.RLock()
+	factory, exists := factories[name]
+	factoriesMutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("unknown routing policy %q (registered: %v)", name, Registered())
+	}
+
+	policy, err := factory(config)
+	if err != nil {
+		return nil, fmt.Errorf("routing policy %s: %w", name, err)
+	}
+	return policy, nil
+}
+
+// Registered returns the registered policy names in sorted order.
+func Registered() []string {
+	factoriesMutex.RLock()
+	defer factoriesMutex.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isRegistered reports whether a policy type is registered under name.
+func isRegistered(name string) bool {
+	factoriesMutex.RLock()
+	defer factoriesMutex.RUnlock()
+
+	_, exists := factories[name]
+	return exists
+}
+
+// DecodeConfig decodes a policy config map into target, a pointer to a struct
+// with mapstructure tags. Fields missing from config keep their current
+// values, so target can be pre-filled with defaults. Durations may be given
+// as strings such as "30s". Keys that match no field are an error.
+func DecodeConfig(config map[string]interface{}, target interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:  mapstructure.StringToTimeDurationHookFunc(),
+		ErrorUnused: true,
+		Result:      target,
+	})
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(config); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	return nil
+}
+
+func newBanditFromConfig(config map[string]interface{}) (RoutingPolicy, error) {
+	cfg := DefaultBanditConfig()
+	if err := DecodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+	policy, err := NewBanditPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// CanaryConfig configures the canary policy.
+type CanaryConfig struct {
+	Provider        string         `mapstructure:"provider"` // provider under test
+	Model           string         `mapstructure:"model"`    // model under test; empty keeps the requested one
+	InitialFraction float64        `mapstructure:"initial_fraction"`
+	Step            float64        `mapstructure:"step"`
+	MaxFraction     float64        `mapstructure:"max_fraction"`
+	StepInterval    time.Duration  `mapstructure:"step_interval"`
+	Window          time.Duration  `mapstructure:"window"`
+	MinRequests     int            `mapstructure:"min_requests"`
+	MaxErrorRate    float64        `mapstructure:"max_error_rate"`
+	MaxLatency      time.Duration  `mapstructure:"max_latency"` // p95; 0 disables the latency check
+	Baseline        FallbackConfig `mapstructure:"baseline"`    // routes traffic not sent to the canary
+}
+
+func newCanaryFromConfig(config map[string]interface{}) (RoutingPolicy, error) {
+	cfg := CanaryConfig{
+		InitialFraction: 0.05,
+		Step:            0.05,
+		MaxFraction:     1.0,
+		StepInterval:    5 * time.Minute,
+		Window:          10 * time.Minute,
+		MinRequests:     20,
+		MaxErrorRate:    0.05,
+	}
+	cfg.Baseline.Type = defaultFallbackPolicy
+	if err := DecodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.Provider == "" {
+		return nil, fmt.Errorf("provider is required")
+	}
+	if cfg.MaxFraction <= 0 || cfg.MaxFraction > 1 {
+		return nil, fmt.Errorf("max_fraction must be in (0, 1]")
+	}
+	if cfg.InitialFraction <= 0 || cfg.InitialFraction > cfg.MaxFraction {
+		return nil, fmt.Errorf("initial_fraction must be in (0, max_fraction]")
+	}
+	if cfg.Step <= 0 {
+		return nil, fmt.Errorf("step must be positive")
+	}
+	if cfg.StepInterval <= 0 || cfg.Window <= 0 {
+		return nil, fmt.Errorf("step_interval and window must be positive")
+	}
+	if cfg.MinRequests <= 0 {
+		return nil, fmt.Errorf("min_requests must be positive")
+	}
+	if cfg.MaxErrorRate < 0 || cfg.MaxErrorRate > 1 {
+		return nil, fmt.Errorf("max_error_rate must be in [0, 1]")
+	}
+	if cfg.MaxLatency < 0 {
+		return nil, fmt.Errorf("max_latency must not be negative")
+	}
+
+	baseline, err := cfg.Baseline.build("canary")
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCanaryPolicy(cfg.Provider, cfg.Model, CanaryThresholds{
+		InitialFraction: cfg.InitialFraction,
+		Step:            cfg.Step,
+		MaxFraction:     cfg.MaxFraction,
+		StepInterval:    cfg.StepInterval,
+		Window:          cfg.Window,
+		MinRequests:     cfg.MinRequests,
+		MaxErrorRate:    cfg.MaxErrorRate,
+		MaxLatency:      cfg.MaxLatency,
+	}, baseline), nil
+}
+
+// CostBasedConfig configures the cost_based policy.
+type CostBasedConfig struct {
+	CostWeight          float64       `mapstructure:"cost_weight"`
+	LatencyWeight       float64       `mapstructure:"latency_weight"`
+	HealthWeight        float64       `mapstructure:"health_weight"`
+	MaxLatencyThreshold time.Duration `mapstructure:"max_latency_threshold"`
+
+	// Feedback of request outcomes into the score
+	EWMAAlpha  float64       `mapstructure:"ewma_alpha"`  // weight of each new outcome
+	MinSamples int           `mapstructure:"min_samples"` // outcomes needed before they are used
+	MaxAge     time.Duration `mapstructure:"max_age"`     // outcomes are forgotten after this long without a new one
+}
+
+func newCostBasedFromConfig(config map[string]interface{}) (RoutingPolicy, error) {
+	policy := NewCostBasedPolicy()
+
+	cfg := CostBasedConfig{
+		MaxLatencyThreshold: policy.maxLatencyThreshold,
+		EWMAAlpha:           policy.ewmaAlpha,
+		MinSamples:          policy.minSamples,
+		MaxAge:              policy.maxAge,
+	}
+	cfg.CostWeight, cfg.LatencyWeight, cfg.HealthWeight = policy.GetWeights()
+	if err := DecodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	if err := policy.SetWeights(cfg.CostWeight, cfg.LatencyWeight, cfg.HealthWeight); err != nil {
+		return nil, err
+	}
+	if err := policy.SetFeedback(cfg.EWMAAlpha, cfg.MinSamples, cfg.MaxAge); err != nil {
+		return nil, err
+	}
+	policy.SetMaxLatencyThreshold(cfg.MaxLatencyThreshold)
+	return policy, nil
+}
+
+// FailoverConfig configures the failover policy.
+type FailoverConfig struct {
+	PrimaryProvider string        `mapstructure:"primary_provider"`
+	BackupProviders []string      `mapstructure:"backup_providers"`
+	FailoverDelay   time.Duration `mapstructure:"failover_delay"`
+}
+
+func newFailoverFromConfig(config map[string]interface{}) (RoutingPolicy, error) {
+	cfg := FailoverConfig{FailoverDelay: 30 * time.Second}
+	if err := DecodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.PrimaryProvider == "" {
+		return nil, fmt.Errorf("primary_provider is required")
+	}
+
+	policy := NewFailoverPolicy(cfg.PrimaryProvider, cfg.BackupProviders)
+	policy.SetFailoverDelay(cfg.FailoverDelay)
+	return policy, nil
+}
+
+// LatencyBasedConfig configures the latency-based policy.
+type LatencyBasedConfig struct {
+	Percentile string        `mapstructure:"percentile"`  // p50 or p95
+	Window     int           `mapstructure:"window"`      // recent requests kept per provider and model
+	MaxAge     time.Duration `mapstructure:"max_age"`     // requests older than this are ignored
+	MinSamples int           `mapstructure:"min_samples"` // requests needed before history is trusted
+}
+
+func newLatencyBasedFromConfig(config map[string]interface{}) (RoutingPolicy, error) {
+	policy := NewLatencyBasedPolicy()
+
+	cfg := LatencyBasedConfig{
+		Percentile: "p95",
+		Window:     policy.window,
+		MaxAge:     policy.maxAge,
+		MinSamples: policy.minSamples,
+	}
+	if err := DecodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	switch cfg.Percentile {
+	case "p50":
+		policy.percentile = 0.5
+	case "p95":
+		policy.percentile = 0.95
+	default:
+		return nil, fmt.Errorf("percentile must be p50 or p95, got %q", cfg.Percentile)
+	}
+	if cfg.Window <= 0 || cfg.MinSamples <= 0 || cfg.MinSamples > cfg.Window {
+		return nil, fmt.Errorf("window and min_samples must be positive with min_samples <= window")
+	}
+	if cfg.MaxAge <= 0 {
+		return nil, fmt.Errorf("max_age must be positive")
+	}
+	policy.window = cfg.Window
+	policy.maxAge = cfg.MaxAge
+	policy.minSamples = cfg.MinSamples
+	return policy, nil
+}
+
+func newRoundRobinFromConfig(config map[string]interface{}) (RoutingPolicy, error) {
+	// Round robin has no options, but unknown keys are still rejected
+	var cfg struct{}
+	if err := DecodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+	return NewRoundRobinPolicy(), nil
+}
+
+func newLeastLoadedFromConfig(config map[string]interface{}) (RoutingPolicy, error) {
+	// Least loaded has no options, but unknown keys are still rejected
+	var cfg struct{}
+	if err := DecodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+	return NewLeastLoadedPolicy(), nil
+}
+
+// defaultFallbackPolicy routes requests that policies with a fallback leave
+// undecided, when no fallback is configured.
+const defaultFallbackPolicy = "cost_based"
+
+// FallbackConfig selects the policy that routes requests a policy leaves
+// undecided, such as prompts matching no semantic route.
+type FallbackConfig struct {
+	Type   string                 `mapstructure:"type"`
+	Config map[string]interface{} `mapstructure:"config"`
+}
+
+// build creates the fallback policy of a policy of type owner, which it must
+// not be itself.
+func (c FallbackConfig) build(owner string) (RoutingPolicy, error) {
+	if c.Type == owner {
+		return nil, fmt.Errorf("fallback cannot be another %s policy", owner)
+	}
+	fallback, err := New(c.Type, c.Config)
+	if err != nil {
+		return nil, fmt.Errorf("fallback: %w", err)
+	}
+	return fallback, nil
+}
+
+// PipelineStageConfig configures a pipeline stage: a routing policy, or a
+// policy middleware such as provider_filter that narrows the candidates.
+type PipelineStageConfig struct {
+	Name   string                 `mapstructure:"name"` // shown in reasons and errors; defaults to the type
+	Type   string                 `mapstructure:"type"`
+	Config map[string]interface{} `mapstructure:"config"`
+}
+
+// PipelineConfig configures the pipeline policy.
+type PipelineConfig struct {
+	Stages []PipelineStageConfig `mapstructure:"stages"` // run in order; the last must be a policy
+}
+
+func newPipelineFromConfig(config map[string]interface{}) (RoutingPolicy, error) {
+	var cfg PipelineConfig
+	if err := DecodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+	if len(cfg.Stages) == 0 {
+		return nil, fmt.Errorf("at least one stage is required")
+	}
+
+	stages := make([]PipelineStage, len(cfg.Stages))
+	for i, stageConfig := range cfg.Stages {
+		if stageConfig.Type == "" {
+			return nil, fmt.Errorf("stage %d has no type", i)
+		}
+		stage := PipelineStage{Name: stageConfig.Name}
+		if stage.Name == "" {
+			stage.Name = stageConfig.Type
+		}
+
+		if isRegistered(stageConfig.Type) {
+			policy, err := New(stageConfig.Type, stageConfig.Config)
+			if err != nil {
+				return nil, fmt.Errorf("stage %s: %w", stage.Name, err)
+			}
+			stage.Policy = policy
+		} else {
+			middleware, err := NewMiddleware(MiddlewareConfig{Type: stageConfig.Type, Config: stageConfig.Config})
+			if err != nil {
+				return nil, fmt.Errorf("stage %s: unknown routing policy or middleware %q", stage.Name, stageConfig.Type)
+			}
+			stage.Middleware = middleware
+		}
+		stages[i] = stage
+	}
+	if stages[len(stages)-1].Policy == nil {
+		return nil, fmt.Errorf("the last stage must be a routing policy")
+	}
+	return NewPipelinePolicy(stages), nil
+}
+
+// PluginConfig configures the plugin policy. Exactly one of Path and Address
+// is required.
+type PluginConfig struct {
+	Path             string                 `mapstructure:"path"`              // plugin binary the router launches
+	Address          string                 `mapstructure:"address"`           // host:port of a plugin running as a service
+	HandshakeTimeout time.Duration          `mapstructure:"handshake_timeout"` // for starting or connecting to the plugin
+	Timeout          time.Duration          `mapstructure:"timeout"`           // per routing call, then the fallback routes
+	Options          map[string]interface{} `mapstructure:"options"`           // passed to the plugin's Configure
+	Fallback         FallbackConfig         `mapstructure:"fallback"`          // routes requests the plugin defers or fails
+}
+
+func newPluginFromConfig(config map[string]interface{}) (RoutingPolicy, error) {
+	cfg := PluginConfig{
+		HandshakeTimeout: 5 * time.Second,
+		Timeout:          250 * time.Millisecond,
+	}
+	cfg.Fallback.Type = defaultFallbackPolicy
+	if err := DecodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+	if (cfg.Path == "") == (cfg.Address == "") {
+		return nil, fmt.Errorf("exactly one of path and address is required")
+	}
+	if cfg.Timeout <= 0 {
+		return nil, fmt.Errorf("timeout must be positive")
+	}
+
+	fallback, err := cfg.Fallback.build("plugin")
+	if err != nil {
+		return nil, err
+	}
+
+	var client *plugin.PolicyClient
+	if cfg.Path != "" {
+		client, err = plugin.StartPolicy(cfg.Path, cfg.HandshakeTimeout)
+	} else {
+		client, err = plugin.DialPolicy(cfg.Address, cfg.HandshakeTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Configure(cfg.Options); err != nil {
+		client.Kill()
+		return nil, fmt.Errorf("failed to configure plugin %s: %w", client.Name(), err)
+	}
+	return NewPluginPolicy(client, cfg.Timeout, fallback), nil
+}
+
+// RulesConfig configures the rules policy.
+type RulesConfig struct {
+	Rules    []Rule         `mapstructure:"rules"` // evaluated in order
+	Fallback FallbackConfig `mapstructure:"fallback"`
+}
+
+func newRulesFromConfig(config map[string]interface{}) (RoutingPolicy, error) {
+	cfg := RulesConfig{Fallback: FallbackConfig{Type: defaultFallbackPolicy}}
+	if err := DecodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Rules) == 0 {
+		return nil, fmt.Errorf("at least one rule is required")
+	}
+	for i, rule := range cfg.Rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i)
+		}
+		if rule.When == "" {
+			return nil, fmt.Errorf("rule %s has no condition", rule.Name)
+		}
+		if rule.Model == "" && rule.Provider == "" {
+			return nil, fmt.Errorf("rule %s needs a model or a provider", rule.Name)
+		}
+	}
+
+	fallback, err := cfg.Fallback.build("rules")
+	if err != nil {
+		return nil, err
+	}
+	return NewRulesPolicy(cfg.Rules, fallback)
+}
+
+// ComplexityConfig configures the complexity policy.
+type ComplexityConfig struct {
+	Model              string           `mapstructure:"model"`               // virtual model that is tiered
+	Tiers              []ComplexityTier `mapstructure:"tiers"`               // by increasing max_score
+	ClassifierProvider string           `mapstructure:"classifier_provider"` // empty scores with heuristics
+	ClassifierModel    string           `mapstructure:"classifier_model"`
+	Timeout            time.Duration    `mapstructure:"timeout"`  // per classifier request
+	Fallback           FallbackConfig   `mapstructure:"fallback"` // routes requests with their model
+}
+
+func newComplexityFromConfig(config map[string]interface{}) (RoutingPolicy, error) {
+	cfg := ComplexityConfig{
+		Model:   "auto",
+		Timeout: 1 * time.Second,
+	}
+	cfg.Fallback.Type = defaultFallbackPolicy
+	if err := DecodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Tiers) == 0 {
+		return nil, fmt.Errorf("at least one tier is required")
+	}
+	previous := -1.0
+	for i, tier := range cfg.Tiers {
+		if tier.Name == "" {
+			return nil, fmt.Errorf("tier %d has no name", i)
+		}
+		if tier.Model == "" {
+			return nil, fmt.Errorf("tier %s has no model", tier.Name)
+		}
+		if i < len(cfg.Tiers)-1 && (tier.MaxScore <= previous || tier.MaxScore > 1) {
+			return nil, fmt.Errorf("max_score of tier %s must increase and be at most 1", tier.Name)
+		}
+		previous = tier.MaxScore
+	}
+	if cfg.ClassifierProvider != "" && cfg.ClassifierModel == "" {
+		return nil, fmt.Errorf("classifier_model is required with classifier_provider")
+	}
+
+	fallback, err := cfg.Fallback.build("complexity")
+	if err != nil {
+		return nil, err
+	}
+	policy := NewComplexityPolicy(cfg.Model, cfg.Tiers, fallback)
+	if cfg.ClassifierProvider != "" {
+		policy.WithClassifier(cfg.ClassifierProvider, cfg.ClassifierModel, cfg.Timeout)
+	}
+	return policy, nil
+}
+
+// CategoryConfig configures the category policy.
+type CategoryConfig struct {
+	Model    string         `mapstructure:"model"`    // virtual model that is categorized
+	Pools    []CategoryPool `mapstructure:"pools"`    // one per category; general is required
+	Fallback FallbackConfig `mapstructure:"fallback"` // routes requests with their (pool) model
+}
+
+func newCategoryFromConfig(config map[string]interface{}) (RoutingPolicy, error) {
+	cfg := CategoryConfig{Model: "auto"}
+	cfg.Fallback.Type = defaultFallbackPolicy
+	if err := DecodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(cfg.Pools))
+	for i, pool := range cfg.Pools {
+		if !IsCategory(pool.Category) {
+			return nil, fmt.Errorf("pool %d has unknown category %q", i, pool.Category)
+		}
+		if seen[pool.Category] {
+			return nil, fmt.Errorf("duplicate pool for category %s", pool.Category)
+		}
+		seen[pool.Category] = true
+		if len(pool.Models) == 0 {
+			return nil, fmt.Errorf("pool %s has no models", pool.Category)
+		}
+	}
+	if !seen[CategoryGeneral] {
+		return nil, fmt.Errorf("a pool for category %s is required", CategoryGeneral)
+	}
+
+	fallback, err := cfg.Fallback.build("category")
+	if err != nil {
+		return nil, err
+	}
+	return NewCategoryPolicy(cfg.Model, cfg.Pools, fallback), nil
+}
+
+// SemanticConfig configures the semantic policy.
+type SemanticConfig struct {
+	EmbeddingProvider string          `mapstructure:"embedding_provider"` // provider that embeds prompts
+	EmbeddingModel    string          `mapstructure:"embedding_model"`
+	Threshold         float64         `mapstructure:"threshold"` // minimum cosine similarity to match a route
+	Timeout           time.Duration   `mapstructure:"timeout"`   // per embedding request
+	Routes            []SemanticRoute `mapstructure:"routes"`
+	Fallback          FallbackConfig  `mapstructure:"fallback"` // routes prompts matching no route
+}
+
+func newSemanticFromConfig(config map[string]interface{}) (RoutingPolicy, error) {
+	cfg := SemanticConfig{
+		EmbeddingModel: "text-embedding-3-small",
+		Threshold:      0.75,
+		Timeout:        2 * time.Second,
+	}
+	cfg.Fallback.Type = defaultFallbackPolicy
+	if err := DecodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.EmbeddingProvider == "" {
+		return nil, fmt.Errorf("embedding_provider is required")
+	}
+	if cfg.Threshold <= 0 || cfg.Threshold > 1 {
+		return nil, fmt.Errorf("threshold must be in (0, 1]")
+	}
+	if len(cfg.Routes) == 0 {
+		return nil, fmt.Errorf("at least one route is required")
+	}
+	for i, route := range cfg.Routes {
+		if route.Name == "" {
+			return nil, fmt.Errorf("route %d has no name", i)
+		}
+		if len(route.Examples) == 0 {
+			return nil, fmt.Errorf("route %s has no examples", route.Name)
+		}
+		if route.Model == "" && route.Provider == "" {
+			return nil, fmt.Errorf("route %s needs a model or a provider", route.Name)
+		}
+		if route.Threshold < 0 || route.Threshold > 1 {
+			return nil, fmt.Errorf("threshold of route %s must be in (0, 1]", route.Name)
+		}
+	}
+
+	fallback, err := cfg.Fallback.build("semantic")
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSemanticPolicy(cfg.EmbeddingProvider, cfg.EmbeddingModel, cfg.Threshold, cfg.Timeout, cfg.Routes, fallback), nil
+}
+
+// StickyConfig configures the sticky policy.
+type StickyConfig struct {
+	Key      string         `mapstructure:"key"`      // user, tenant, metadata:<field> or header:<name>
+	Fallback FallbackConfig `mapstructure:"fallback"` // routes requests without an affinity key
+}
+
+func newStickyFromConfig(config map[string]interface{}) (RoutingPolicy, error) {
+	cfg := StickyConfig{Key: "user"}
+	cfg.Fallback.Type = defaultFallbackPolicy
+	if err := DecodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+	if !validAffinityKey(cfg.Key) {
+		return nil, fmt.Errorf("key must be user, tenant, metadata:<field> or header:<name>, got %q", cfg.Key)
+	}
+
+	fallback, err := cfg.Fallback.build("sticky")
+	if err != nil {
+		return nil, err
+	}
+	return NewStickyPolicy(cfg.Key, fallback), nil
+}
+
+// WeightedConfig configures the weighted policy.
+type WeightedConfig struct {
+	Weights       map[string]float64 `mapstructure:"weights"`        // relative weight per provider
+	DefaultWeight float64            `mapstructure:"default_weight"` // for providers not in weights
+
+	// ErrorBudget reweights providers by their error budget burn rate; nil
+	// keeps the weights fixed
+	ErrorBudget *ErrorBudgetConfig `mapstructure:"error_budget"`
+}
+
+func newWeightedFromConfig(config map[string]interface{}) (RoutingPolicy, error) {
+	var cfg WeightedConfig
+	if err := DecodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.DefaultWeight < 0 {
+		return nil, fmt.Errorf("default_weight must not be negative")
+	}
+	positive := cfg.DefaultWeight > 0
+	for name, weight := range cfg.Weights {
+		if weight < 0 {
+			return nil, fmt.Errorf("weight of %s must not be negative", name)
+		}
+		positive = positive || weight > 0
+	}
+	if !positive {
+		return nil, fmt.Errorf("at least one provider needs a positive weight")
+	}
+	policy := NewWeightedPolicy(cfg.Weights, cfg.DefaultWeight)
+	if cfg.ErrorBudget != nil {
+		return policy.WithErrorBudget(*cfg.ErrorBudget)
+	}
+	return policy, nil
+}
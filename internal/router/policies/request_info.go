@@ -0,0 +1,48 @@
+package policies
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+// RequestInfo describes the HTTP request a routing decision is made for,
+// beyond the chat request itself.
+type RequestInfo struct {
+	Tenant  string
+	Headers http.Header
+
+	// Hints are the routing constraints sent in request headers. Those in
+	// the request body take precedence.
+	Hints *models.RoutingHints
+
+	// Deterministic asks for the same decision for identical requests given
+	// the same provider health, see Deterministic.
+	Deterministic bool
+
+	// DryRun marks a decision that is only explained, see DryRun.
+	DryRun bool
+
+	// Residency is the residency tag of the request's tenant or API key,
+	// which the request's own routing hints cannot lift.
+	Residency []string
+
+	// Weights are the provider weights set by operators, see
+	// ProviderWeights.
+	Weights *ProviderWeights
+}
+
+// requestInfoKey carries the RequestInfo of a request.
+type requestInfoKey struct{}
+
+// WithRequestInfo returns a context carrying info for policies to inspect.
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFrom returns the RequestInfo carried by ctx, or an empty one.
+func RequestInfoFrom(ctx context.Context) RequestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info
+}
@@ -0,0 +1,151 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// ResidencyConfig maps residency tags, such as eu-only, to the provider
+// regions requests with the tag may be served from.
+type ResidencyConfig struct {
+	Zones map[string][]string `mapstructure:"zones"`
+}
+
+// ResidencyError is returned when a request's residency cannot be kept:
+// its tag is unknown, no candidate is hosted in its regions, or a policy
+// decided on a provider outside them.
+type ResidencyError struct {
+	Residency string
+	Regions   []string // allowed by the tag; nil for an unknown tag
+	Reason    string
+}
+
+func (e *ResidencyError) Error() string {
+	return fmt.Sprintf("residency %s cannot be kept: %s", e.Residency, e.Reason)
+}
+
+// Residency is policy middleware enforcing data residency. A request tagged
+// by its tenant, API key or routing hints is only routed to providers hosted
+// in the regions of every tag it carries, and fails closed with a
+// ResidencyError otherwise. Providers without a region never serve tagged
+// requests.
+type Residency struct {
+	zones  map[string]map[string]bool
+	lookup func(name string) (providers.Provider, bool)
+}
+
+// NewResidency creates residency middleware. lookup finds a provider by
+// name, to check the provider a decision went to.
+func NewResidency(config ResidencyConfig, lookup func(name string) (providers.Provider, bool)) (*Residency, error) {
+	zones := make(map[string]map[string]bool, len(config.Zones))
+	for tag, regions := range config.Zones {
+		if len(regions) == 0 {
+			return nil, fmt.Errorf("residency %s allows no region", tag)
+		}
+		zones[tag] = make(map[string]bool, len(regions))
+		for _, region := range regions {
+			zones[tag][region] = true
+		}
+	}
+	return &Residency{zones: zones, lookup: lookup}, nil
+}
+
+// Name returns the middleware name.
+func (r *Residency) Name() string {
+	return "residency"
+}
+
+// tags returns the residency tags a request carries.
+func (r *Residency) tags(ctx context.Context, req models.ChatRequest) []string {
+	tags := append([]string(nil), RequestInfoFrom(ctx).Residency...)
+	if hint := RequestHints(ctx, req).Residency; hint != "" {
+		tags = append(tags, hint)
+	}
+	return tags
+}
+
+// Allows reports whether a provider may serve the request. It is for paths
+// that pick a provider outside the routing policy, such as stream failover.
+func (r *Residency) Allows(ctx context.Context, req models.ChatRequest, provider providers.Provider) bool {
+	region := providers.RegionOf(provider)
+	for _, tag := range r.tags(ctx, req) {
+		if !r.zones[tag][region] {
+			return false
+		}
+	}
+	return true
+}
+
+// BeforeDecide leaves out the candidates hosted outside the request's
+// residency regions.
+func (r *Residency) BeforeDecide(ctx context.Context, req models.ChatRequest, candidates map[string]providers.Provider) (map[string]providers.Provider, error) {
+	for _, tag := range r.tags(ctx, req) {
+		zone, known := r.zones[tag]
+		if !known {
+			return nil, &ResidencyError{Residency: tag, Reason: "unknown residency tag"}
+		}
+
+		var outside []string
+		kept := filterCandidates(candidates, func(name string, provider providers.Provider) bool {
+			region := providers.RegionOf(provider)
+			if zone[region] {
+				return true
+			}
+			if region == "" {
+				region = "no region"
+			}
+			outside = append(outside, fmt.Sprintf("%s (%s)", name, region))
+			return false
+		})
+		if len(kept) == 0 && len(candidates) > 0 {
+			sort.Strings(outside)
+			return nil, &ResidencyError{
+				Residency: tag,
+				Regions:   r.regions(tag),
+				Reason: fmt.Sprintf("no available provider is hosted in %s; outside: %s",
+					strings.Join(r.regions(tag), ", "), strings.Join(outside, ", ")),
+			}
+		}
+		candidates = kept
+	}
+	return candidates, nil
+}
+
+// AfterDecide rejects a decision for a provider outside the request's
+// residency regions, so a policy choosing outside its candidates fails closed.
+func (r *Residency) AfterDecide(ctx context.Context, req models.ChatRequest, decision RoutingDecision) (RoutingDecision, error) {
+	tags := r.tags(ctx, req)
+	if len(tags) == 0 {
+		return decision, nil
+	}
+	provider, found := r.lookup(decision.ProviderName)
+	region := ""
+	if found {
+		region = providers.RegionOf(provider)
+	}
+	for _, tag := range tags {
+		if !r.zones[tag][region] {
+			return RoutingDecision{}, &ResidencyError{
+				Residency: tag,
+				Regions:   r.regions(tag),
+				Reason:    fmt.Sprintf("the decision for provider %s is outside %s", decision.ProviderName, strings.Join(r.regions(tag), ", ")),
+			}
+		}
+	}
+	return decision, nil
+}
+
+// regions returns the sorted regions of a residency tag.
+func (r *Residency) regions(tag string) []string {
+	regions := make([]string, 0, len(r.zones[tag]))
+	for region := range r.zones[tag] {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return regions
+}
@@ -0,0 +1,81 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// RoundRobinPolicy cycles through the healthy providers supporting the
+// requested model, keeping a separate position per model so each model's
+// traffic is spread evenly across its providers.
+type RoundRobinPolicy struct {
+	*BasePolicy
+
+	mutex     sync.Mutex
+	positions map[string]uint64 // next position per model
+}
+
+// NewRoundRobinPolicy creates a round-robin routing policy.
+func NewRoundRobinPolicy() *RoundRobinPolicy {
+	return &RoundRobinPolicy{
+		BasePolicy: NewBasePolicy(
+			"round_robin",
+			"Cycles through healthy providers supporting the requested model",
+		),
+		positions: make(map[string]uint64),
+	}
+}
+
+// DecideRoute selects the next provider in turn for the requested model.
+func (p *RoundRobinPolicy) DecideRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) (RoutingDecision, error) {
+	if err := p.ValidateRequest(req); err != nil {
+		return RoutingDecision{}, fmt.Errorf("invalid request: %w", err)
+	}
+
+	healthyProviders := p.getHealthyProviders(availableProviders)
+	if len(healthyProviders) == 0 {
+		return RoutingDecision{}, fmt.Errorf("no healthy providers available")
+	}
+
+	// Avoid providers that are about to throttle
+	healthyProviders = p.excludeRateLimited(ctx, healthyProviders)
+
+	// Sorted so the rotation order is stable between requests
+	var candidates []string
+	for name, provider := range healthyProviders {
+		if p.providerSupportsModel(provider, req.Model) {
+			candidates = append(candidates, name)
+		}
+	}
+	if len(candidates) == 0 {
+		return RoutingDecision{}, fmt.Errorf("no available providers for model %s", req.Model)
+	}
+	sort.Strings(candidates)
+
+	// Deterministic requests take a position from their hash and leave the
+	// rotation alone; dry runs read the rotation without advancing it
+	var position uint64
+	if Deterministic(ctx) {
+		position = uint64(requestHash(req) * float64(len(candidates)))
+	} else {
+		p.mutex.Lock()
+		position = p.positions[req.Model]
+		if !DryRun(ctx) {
+			p.positions[req.Model] = position + 1
+		}
+		p.mutex.Unlock()
+	}
+
+	chosen := candidates[position%uint64(len(candidates))]
+	return RoutingDecision{
+		ProviderName: chosen,
+		Model:        req.Model,
+		Reason:       fmt.Sprintf("Round robin across %d providers", len(candidates)),
+		Confidence:   1.0 / float64(len(candidates)),
+	}, nil
+}
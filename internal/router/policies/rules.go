@@ -0,0 +1,254 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/expr"
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// Rule routes requests for which its expression holds to a model, a
+// provider, or both.
+type Rule struct {
+	Name     string `mapstructure:"name"`
+	When     string `mapstructure:"when"`     // expression over the request variables
+	Model    string `mapstructure:"model"`    // model to use; empty keeps the requested one
+	Provider string `mapstructure:"provider"` // provider to use; empty lets the fallback choose
+}
+
+// ruleVariables lists the variables rule expressions may use.
+var ruleVariables = map[string]bool{
+	"model":             true,
+	"user":              true,
+	"tenant":            true,
+	"stream":            true,
+	"max_tokens":        true,
+	"temperature":       true,
+	"message_count":     true,
+	"prompt_length":     true,
+	"last_user_message": true,
+	"system_prompt":     true,
+	"tools":             true,
+	"has_images":        true,
+	"metadata":          true,
+	"headers":           true,
+}
+
+// compiledRule is a rule with its parsed expression.
+type compiledRule struct {
+	Rule
+	program *expr.Program
+}
+
+// RulesPolicy evaluates ordered rules over the request and routes with the
+// first one that matches and can serve it. Requests matching no rule are
+// routed by the fallback policy.
+type RulesPolicy struct {
+	*BasePolicy
+	rules    []compiledRule
+	fallback RoutingPolicy
+}
+
+// NewRulesPolicy compiles the rules and creates a rules policy.
+func NewRulesPolicy(rules []Rule, fallback RoutingPolicy) (*RulesPolicy, error) {
+	compiled := make([]compiledRule, len(rules))
+	for i, rule := range rules {
+		program, err := expr.Compile(rule.When)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+		for _, name := range program.Variables() {
+			if !ruleVariables[name] {
+				return nil, fmt.Errorf("rule %s: unknown variable %s", rule.Name, name)
+			}
+		}
+		compiled[i] = compiledRule{Rule: rule, program: program}
+	}
+
+	return &RulesPolicy{
+		BasePolicy: NewBasePolicy(
+			"rules",
+			"Routes requests with the first configured rule whose expression matches",
+		),
+		rules:    compiled,
+		fallback: fallback,
+	}, nil
+}
+
+// DecideRoute routes with the first matching rule, or the fallback policy.
+func (p *RulesPolicy) DecideRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) (RoutingDecision, error) {
+	if err := p.ValidateRequest(req); err != nil {
+		return RoutingDecision{}, fmt.Errorf("invalid request: %w", err)
+	}
+
+	vars := requestVariables(ctx, req)
+	var skipped []string
+	for _, rule := range p.rules {
+		matched, err := rule.program.EvalBool(vars)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s: %v", rule.Name, err))
+			continue
+		}
+		if !matched {
+			continue
+		}
+
+		decision, err := p.apply(ctx, rule.Rule, req, availableProviders)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s: %v", rule.Name, err))
+			continue
+		}
+		return decision, nil
+	}
+
+	decision, err := p.fallback.DecideRoute(ctx, req, availableProviders)
+	if err != nil {
+		return RoutingDecision{}, err
+	}
+	why := "no rule matched"
+	if len(skipped) > 0 {
+		why += "; skipped " + strings.Join(skipped, "; ")
+	}
+	decision.Reason = fmt.Sprintf("Fallback (%s): %s", why, decision.Reason)
+	return decision, nil
+}
+
+// apply routes a request with a matching rule. It fails if the rule's
+// provider or model cannot serve the request.
+func (p *RulesPolicy) apply(ctx context.Context, rule Rule, req models.ChatRequest, availableProviders map[string]providers.Provider) (RoutingDecision, error) {
+	if rule.Model != "" {
+		req.Model = rule.Model
+	}
+	reason := fmt.Sprintf("Rule %q matched", rule.Name)
+
+	if rule.Provider != "" {
+		healthyProviders := p.excludeRateLimited(ctx, p.getHealthyProviders(availableProviders))
+		provider, exists := healthyProviders[rule.Provider]
+		if !exists {
+			return RoutingDecision{}, fmt.Errorf("provider %s unavailable", rule.Provider)
+		}
+		if !p.providerSupportsModel(provider, req.Model) {
+			return RoutingDecision{}, fmt.Errorf("provider %s does not serve %s", rule.Provider, req.Model)
+		}
+		return RoutingDecision{
+			ProviderName: rule.Provider,
+			Model:        req.Model,
+			Reason:       reason,
+			Confidence:   1.0,
+		}, nil
+	}
+
+	decision, err := p.fallback.DecideRoute(ctx, req, availableProviders)
+	if err != nil {
+		return RoutingDecision{}, err
+	}
+	decision.Model = req.Model
+	decision.Reason = reason + ": " + decision.Reason
+	return decision, nil
+}
+
+// DecideStage decides requests matching a rule with a provider, and defers
+// the rest to the next pipeline stage: unchanged if no rule matched, or with
+// the model of a matching rule that names no provider.
+func (p *RulesPolicy) DecideStage(ctx context.Context, req models.ChatRequest, candidates map[string]providers.Provider) (StageResult, error) {
+	if err := p.ValidateRequest(req); err != nil {
+		return StageResult{}, fmt.Errorf("invalid request: %w", err)
+	}
+
+	vars := requestVariables(ctx, req)
+	var skipped []string
+	for _, rule := range p.rules {
+		matched, err := rule.program.EvalBool(vars)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s: %v", rule.Name, err))
+			continue
+		}
+		if !matched {
+			continue
+		}
+
+		if rule.Provider == "" {
+			return StageResult{Model: rule.Model, Reason: fmt.Sprintf("Rule %q matched", rule.Name)}, nil
+		}
+		decision, err := p.apply(ctx, rule.Rule, req, candidates)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s: %v", rule.Name, err))
+			continue
+		}
+		return StageResult{Decision: &decision}, nil
+	}
+
+	why := "no rule matched"
+	if len(skipped) > 0 {
+		why += "; skipped " + strings.Join(skipped, "; ")
+	}
+	return StageResult{Reason: why}, nil
+}
+
+// UpdateMetrics records the outcome and passes it on to the fallback policy.
+func (p *RulesPolicy) UpdateMetrics(decision RoutingDecision, success bool, latency time.Duration) {
+	p.BasePolicy.UpdateMetrics(decision, success, latency)
+	p.fallback.UpdateMetrics(decision, success, latency)
+}
+
+// requestVariables returns the values rule expressions are evaluated over.
+// Header names are lowercase; a header with several values gives the first.
+func requestVariables(ctx context.Context, req models.ChatRequest) map[string]interface{} {
+	info := RequestInfoFrom(ctx)
+
+	promptLength := 0
+	lastUserMessage, systemPrompt := "", ""
+	hasImages := false
+	for _, msg := range req.Messages {
+		text := msg.Content.Text()
+		promptLength += len([]rune(text))
+		switch msg.Role {
+		case "user":
+			lastUserMessage = text
+		case "system":
+			if systemPrompt != "" {
+				systemPrompt += "\n\n"
+			}
+			systemPrompt += text
+		}
+		hasImages = hasImages || len(msg.Content.Images()) > 0
+	}
+
+	tools := make([]interface{}, len(req.Tools))
+	for i, tool := range req.Tools {
+		tools[i] = tool.Function.Name
+	}
+
+	metadata := make(map[string]interface{}, len(req.Metadata))
+	for key, value := range req.Metadata {
+		metadata[key] = value
+	}
+
+	headers := make(map[string]interface{}, len(info.Headers))
+	for name, values := range info.Headers {
+		if len(values) > 0 {
+			headers[strings.ToLower(name)] = values[0]
+		}
+	}
+
+	return map[string]interface{}{
+		"model":             req.Model,
+		"user":              req.User,
+		"tenant":            info.Tenant,
+		"stream":            req.Stream,
+		"max_tokens":        float64(req.MaxTokens),
+		"temperature":       req.Temperature,
+		"message_count":     float64(len(req.Messages)),
+		"prompt_length":     float64(promptLength),
+		"last_user_message": lastUserMessage,
+		"system_prompt":     systemPrompt,
+		"tools":             tools,
+		"has_images":        hasImages,
+		"metadata":          metadata,
+		"headers":           headers,
+	}
+}
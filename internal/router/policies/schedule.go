@@ -0,0 +1,264 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/expr"
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// ScheduleWindow applies routing rules to matching requests during the times
+// its cron expression matches, or outside them.
+type ScheduleWindow struct {
+	Name string `mapstructure:"name"`
+
+	// Cron is minute, hour, day of month, month and day of week, each a
+	// list of values, ranges and */step; days of week run from 0 (Sunday)
+	Cron    string `mapstructure:"cron"`
+	Outside bool   `mapstructure:"outside"` // active while the cron expression does not match
+
+	// Requests the window applies to: requested models (empty for all) and
+	// an optional expression over the rule variables
+	Models []string `mapstructure:"models"`
+	When   string   `mapstructure:"when"`
+
+	// What the window does: route to a preferred provider when one is healthy
+	// and serves the model, never route to denied providers, or reject
+	PreferProviders []string `mapstructure:"prefer_providers"`
+	DenyProviders   []string `mapstructure:"deny_providers"`
+	Reject          bool     `mapstructure:"reject"`
+}
+
+// ScheduleConfig configures the schedule middleware.
+type ScheduleConfig struct {
+	Timezone string           `mapstructure:"timezone"` // IANA name the windows are evaluated in; default UTC
+	Windows  []ScheduleWindow `mapstructure:"windows"`
+}
+
+// ScheduleError is returned for requests a schedule window rejects.
+type ScheduleError struct {
+	Window string
+	Model  string
+}
+
+func (e *ScheduleError) Error() string {
+	return fmt.Sprintf("model %s is not available at this time (schedule window %s)", e.Model, e.Window)
+}
+
+// compiledWindow is a schedule window with its parsed cron and expression.
+type compiledWindow struct {
+	ScheduleWindow
+	cron    cronSchedule
+	program *expr.Program // nil without a when expression
+	models  map[string]bool
+}
+
+// Schedule is policy middleware applying time-window routing rules, e.g.
+// sending bulk traffic to cheaper providers overnight or rejecting expensive
+// models outside business hours. Windows are evaluated at decision time, in
+// order, and every active window matching a request applies.
+type Schedule struct {
+	location *time.Location
+	windows  []compiledWindow
+	now      func() time.Time
+}
+
+// NewSchedule compiles the windows and creates schedule middleware.
+func NewSchedule(config ScheduleConfig) (*Schedule, error) {
+	location := time.UTC
+	if config.Timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(config.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid schedule timezone: %w", err)
+		}
+	}
+
+	windows := make([]compiledWindow, len(config.Windows))
+	for i, window := range config.Windows {
+		if window.Name == "" {
+			window.Name = fmt.Sprintf("window-%d", i+1)
+		}
+		cron, err := parseCron(window.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("schedule window %s: %w", window.Name, err)
+		}
+		compiled := compiledWindow{ScheduleWindow: window, cron: cron}
+		if window.When != "" {
+			compiled.program, err = expr.Compile(window.When)
+			if err != nil {
+				return nil, fmt.Errorf("schedule window %s: %w", window.Name, err)
+			}
+			for _, name := range compiled.program.Variables() {
+				if !ruleVariables[name] {
+					return nil, fmt.Errorf("schedule window %s: unknown variable %s", window.Name, name)
+				}
+			}
+		}
+		if len(window.Models) > 0 {
+			compiled.models = make(map[string]bool, len(window.Models))
+			for _, model := range window.Models {
+				compiled.models[model] = true
+			}
+		}
+		windows[i] = compiled
+	}
+
+	return &Schedule{location: location, windows: windows, now: time.Now}, nil
+}
+
+// Name returns the middleware name.
+func (s *Schedule) Name() string {
+	return "schedule"
+}
+
+// BeforeDecide applies the windows active now that match the request. It
+// fails with a ScheduleError when one of them rejects the request.
+func (s *Schedule) BeforeDecide(ctx context.Context, req models.ChatRequest, candidates map[string]providers.Provider) (map[string]providers.Provider, error) {
+	now := s.now().In(s.location)
+	var vars map[string]interface{}
+	for _, window := range s.windows {
+		if window.cron.matches(now) == window.Outside {
+			continue
+		}
+		if window.models != nil && !window.models[req.Model] {
+			continue
+		}
+		if window.program != nil {
+			if vars == nil {
+				vars = requestVariables(ctx, req)
+			}
+			// A window whose expression fails on the request does not apply
+			if matched, err := window.program.EvalBool(vars); err != nil || !matched {
+				continue
+			}
+		}
+
+		if window.Reject {
+			return nil, &ScheduleError{Window: window.Name, Model: req.Model}
+		}
+		if len(window.DenyProviders) > 0 {
+			denied := make(map[string]bool, len(window.DenyProviders))
+			for _, name := range window.DenyProviders {
+				denied[name] = true
+			}
+			candidates = filterCandidates(candidates, func(name string, provider providers.Provider) bool {
+				return !denied[name]
+			})
+		}
+		if len(window.PreferProviders) > 0 {
+			preferred := make(map[string]providers.Provider)
+			for _, name := range window.PreferProviders {
+				if provider, exists := candidates[name]; exists && provider.IsHealthy() && servesModel(provider, req.Model) {
+					preferred[name] = provider
+				}
+			}
+			if len(preferred) > 0 {
+				candidates = preferred
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// AfterDecide leaves the decision unchanged.
+func (s *Schedule) AfterDecide(ctx context.Context, req models.ChatRequest, decision RoutingDecision) (RoutingDecision, error) {
+	return decision, nil
+}
+
+// cronSchedule is a parsed five-field cron expression, one bit per value.
+type cronSchedule struct {
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+	anyDayOfMonth, anyDayOfWeek                bool
+}
+
+// matches reports whether t, to the minute, is in the schedule. As in cron,
+// a day matches either day field when both are restricted.
+func (c cronSchedule) matches(t time.Time) bool {
+	if c.minute&(1<<uint(t.Minute())) == 0 || c.hour&(1<<uint(t.Hour())) == 0 || c.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	dayOfMonth := c.dayOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := c.dayOfWeek&(1<<uint(t.Weekday())) != 0
+	if c.anyDayOfMonth || c.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+// parseCron parses a five-field cron expression.
+func parseCron(spec string) (cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return cronSchedule{}, fmt.Errorf("cron expression %q must have 5 fields: minute hour day-of-month month day-of-week", spec)
+	}
+
+	var c cronSchedule
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return cronSchedule{}, fmt.Errorf("cron minute: %w", err)
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return cronSchedule{}, fmt.Errorf("cron hour: %w", err)
+	}
+	if c.dayOfMonth, err = parseCronField(fields[2], 1, 31); err != nil {
+		return cronSchedule{}, fmt.Errorf("cron day of month: %w", err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return cronSchedule{}, fmt.Errorf("cron month: %w", err)
+	}
+	if c.dayOfWeek, err = parseCronField(fields[4], 0, 7); err != nil {
+		return cronSchedule{}, fmt.Errorf("cron day of week: %w", err)
+	}
+	// 7 is Sunday as well as 0
+	if c.dayOfWeek&(1<<7) != 0 {
+		c.dayOfWeek |= 1
+	}
+	c.anyDayOfMonth = fields[2] == "*"
+	c.anyDayOfWeek = fields[4] == "*"
+	return c, nil
+}
+
+// parseCronField parses a comma-separated list of *, values and ranges in
+// [min, max], each with an optional /step, into a bit set.
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangePart = part[:i]
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+		}
+
+		low, high := min, max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("invalid value in %q", part)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("invalid range in %q", part)
+				}
+			} else if step > 1 {
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for value := low; value <= high; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
@@ -0,0 +1,152 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+func TestParseCronMatches(t *testing.T) {
+	// 2024-05-06 is a Monday
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		spec string
+		time time.Time
+		want bool
+	}{
+		{"* * * * *", at(5, 6, 3, 17), true},
+		{"30 9 * * *", at(5, 6, 9, 30), true},
+		{"30 9 * * *", at(5, 6, 9, 31), false},
+		{"*/15 * * * *", at(5, 6, 9, 45), true},
+		{"*/15 * * * *", at(5, 6, 9, 40), false},
+		{"5/20 * * * *", at(5, 6, 9, 45), true},
+		{"5/20 * * * *", at(5, 6, 9, 40), false},
+		{"* 9-17 * * 1-5", at(5, 6, 17, 59), true},
+		{"* 9-17 * * 1-5", at(5, 6, 18, 0), false},
+		{"* 9-17 * * 1-5", at(5, 11, 12, 0), false}, // Saturday
+		{"* 0-6,22-23 * * *", at(5, 6, 23, 0), true},
+		{"* 0-6,22-23 * * *", at(5, 6, 12, 0), false},
+		{"* * * * 0", at(5, 12, 12, 0), true}, // Sunday
+		{"* * * * 7", at(5, 12, 12, 0), true}, // 7 is Sunday too
+		{"* * * * 5-7", at(5, 12, 12, 0), true},
+		{"* * 1 1,7 *", at(7, 1, 0, 0), true},
+		{"* * 1 1,7 *", at(5, 1, 0, 0), false},
+		// Either day field matches when both are restricted
+		{"* * 15 * 1", at(5, 6, 12, 0), true},
+		{"* * 15 * 1", at(5, 15, 12, 0), true},
+		{"* * 15 * 1", at(5, 14, 12, 0), false},
+		// Only the restricted one when the other is *
+		{"* * 15 * *", at(5, 6, 12, 0), false},
+		{"* * * * 1", at(5, 15, 12, 0), false},
+	}
+	for _, tt := range tests {
+		cron, err := parseCron(tt.spec)
+		if err != nil {
+			t.Fatalf("parseCron(%q) error = %v", tt.spec, err)
+		}
+		if got := cron.matches(tt.time); got != tt.want {
+			t.Errorf("parseCron(%q).matches(%s) = %v, want %v", tt.spec, tt.time.Format("Mon 2006-01-02 15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	tests := map[string]string{
+		"* * * *":      "must have 5 fields",
+		"* * * * * *":  "must have 5 fields",
+		"60 * * * *":   "cron minute",
+		"* 24 * * *":   "cron hour",
+		"* * 0 * *":    "cron day of month",
+		"* * * 13 *":   "cron month",
+		"* * * * 8":    "cron day of week",
+		"*/0 * * * *":  "invalid step",
+		"*/x * * * *":  "invalid step",
+		"a * * * *":    "invalid value",
+		"1-b * * * *":  "invalid range",
+		"10-5 * * * *": "outside 0-59",
+		"1,,2 * * * *": "invalid value",
+	}
+	for spec, want := range tests {
+		if _, err := parseCron(spec); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("parseCron(%q) error = %v, want it to mention %q", spec, err, want)
+		}
+	}
+}
+
+func TestScheduleAppliesActiveWindows(t *testing.T) {
+	schedule, err := NewSchedule(ScheduleConfig{
+		Timezone: "Europe/Paris",
+		Windows: []ScheduleWindow{
+			{Name: "business-hours", Cron: "* 9-17 * * 1-5", Outside: true, Models: []string{"gpt-4"}, Reject: true},
+			{Name: "nightly-batch", Cron: "* 0-5 * * *", When: `tenant == "acme"`, DenyProviders: []string{"openai"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	candidates := map[string]providers.Provider{"openai": nil, "anthropic": nil}
+	ctx := WithRequestInfo(context.Background(), RequestInfo{Tenant: "acme"})
+
+	tests := []struct {
+		name       string
+		now        time.Time
+		ctx        context.Context
+		model      string
+		wantReject bool
+		wantCount  int
+	}{
+		// 08:00 UTC is 10:00 in Paris in May, inside business hours
+		{"inside business hours", time.Date(2024, 5, 6, 8, 0, 0, 0, time.UTC), ctx, "gpt-4", false, 2},
+		{"outside business hours", time.Date(2024, 5, 6, 18, 0, 0, 0, time.UTC), ctx, "gpt-4", true, 0},
+		{"other model outside business hours", time.Date(2024, 5, 6, 18, 0, 0, 0, time.UTC), ctx, "gpt-4o", false, 2},
+		{"nightly window for acme", time.Date(2024, 5, 6, 1, 0, 0, 0, time.UTC), ctx, "gpt-4o", false, 1},
+		{"nightly window for another tenant", time.Date(2024, 5, 6, 1, 0, 0, 0, time.UTC), context.Background(), "gpt-4o", false, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule.now = func() time.Time { return tt.now }
+			kept, err := schedule.BeforeDecide(tt.ctx, models.ChatRequest{Model: tt.model}, candidates)
+
+			var rejected *ScheduleError
+			if gotReject := errors.As(err, &rejected); gotReject != tt.wantReject {
+				t.Fatalf("BeforeDecide() error = %v, want rejected %v", err, tt.wantReject)
+			}
+			if tt.wantReject {
+				if rejected.Window != "business-hours" {
+					t.Fatalf("rejected by window %q", rejected.Window)
+				}
+				return
+			}
+			if len(kept) != tt.wantCount {
+				t.Fatalf("BeforeDecide() kept %d candidates, want %d", len(kept), tt.wantCount)
+			}
+			if tt.wantCount == 1 {
+				if _, denied := kept["openai"]; denied {
+					t.Fatal("denied provider kept")
+				}
+			}
+		})
+	}
+}
+
+func TestNewScheduleRejectsInvalidWindows(t *testing.T) {
+	tests := map[string]ScheduleConfig{
+		"timezone":   {Timezone: "Mars/Olympus", Windows: nil},
+		"cron":       {Windows: []ScheduleWindow{{Cron: "* * *"}}},
+		"expression": {Windows: []ScheduleWindow{{Cron: "* * * * *", When: "tenant =="}}},
+		"variable":   {Windows: []ScheduleWindow{{Cron: "* * * * *", When: `planet == "mars"`}}},
+	}
+	for name, config := range tests {
+		if _, err := NewSchedule(config); err == nil {
+			t.Errorf("NewSchedule() accepted an invalid %s", name)
+		}
+	}
+}
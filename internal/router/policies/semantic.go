@@ -0,0 +1,316 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// maxCachedPrompts bounds the prompt embeddings kept by the semantic policy.
+const maxCachedPrompts = 1000
+
+// SemanticRoute sends prompts similar to its examples to a model, a provider,
+// or both.
+type SemanticRoute struct {
+	Name      string   `mapstructure:"name"`
+	Examples  []string `mapstructure:"examples"`  // prompts typical of the route
+	Model     string   `mapstructure:"model"`     // model to use; empty keeps the requested one
+	Provider  string   `mapstructure:"provider"`  // preferred provider; empty lets the fallback choose
+	Threshold float64  `mapstructure:"threshold"` // overrides the policy threshold when set
+}
+
+// SemanticPolicy routes by what a prompt is about. The last user message is
+// embedded and compared with the example prompts of each route; the most
+// similar route above its threshold picks the model and provider. Prompts that
+// match no route, or that cannot be embedded, are routed by the fallback policy.
+type SemanticPolicy struct {
+	*BasePolicy
+	embeddingProvider string
+	embeddingModel    string
+	threshold         float64
+	timeout           time.Duration
+	routes            []SemanticRoute
+	fallback          RoutingPolicy
+
+	mutex    sync.Mutex
+	examples [][][]float64        // per route, per example; nil until embedded
+	prompts  map[string][]float64 // recent prompt embeddings
+}
+
+// NewSemanticPolicy creates a semantic policy embedding prompts with the given
+// provider and model. Embedding requests time out after timeout.
+func NewSemanticPolicy(embeddingProvider, embeddingModel string, threshold float64, timeout time.Duration, routes []SemanticRoute, fallback RoutingPolicy) *SemanticPolicy {
+	return &SemanticPolicy{
+		BasePolicy: NewBasePolicy(
+			"semantic",
+			"Routes requests by matching the prompt against example prompts of configured routes",
+		),
+		embeddingProvider: embeddingProvider,
+		embeddingModel:    embeddingModel,
+		threshold:         threshold,
+		timeout:           timeout,
+		routes:            routes,
+		fallback:          fallback,
+		prompts:           make(map[string][]float64),
+	}
+}
+
+// DecideRoute matches the prompt against the routes and routes to the best
+// match, or defers to the fallback policy.
+func (p *SemanticPolicy) DecideRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) (RoutingDecision, error) {
+	if err := p.ValidateRequest(req); err != nil {
+		return RoutingDecision{}, fmt.Errorf("invalid request: %w", err)
+	}
+
+	prompt := lastUserMessage(req)
+	if prompt == "" {
+		return p.decideFallback(ctx, req, availableProviders, "no user message to match")
+	}
+
+	route, similarity, err := p.match(ctx, prompt, availableProviders)
+	if err != nil {
+		return p.decideFallback(ctx, req, availableProviders, fmt.Sprintf("semantic matching failed: %v", err))
+	}
+	if route == nil {
+		return p.decideFallback(ctx, req, availableProviders, fmt.Sprintf("no route matched (best similarity %.2f)", similarity))
+	}
+
+	routedReq := req
+	if route.Model != "" {
+		routedReq.Model = route.Model
+	}
+	reason := fmt.Sprintf("Semantic route %q (similarity %.2f)", route.Name, similarity)
+
+	// Use the route's provider when it can serve the request
+	if route.Provider != "" {
+		healthyProviders := p.excludeRateLimited(ctx, p.getHealthyProviders(availableProviders))
+		if provider, exists := healthyProviders[route.Provider]; exists && p.providerSupportsModel(provider, routedReq.Model) {
+			return RoutingDecision{
+				ProviderName: route.Provider,
+				Model:        routedReq.Model,
+				Reason:       reason,
+				Confidence:   similarity,
+			}, nil
+		}
+		reason += fmt.Sprintf(", provider %s unavailable", route.Provider)
+	}
+
+	decision, err := p.fallback.DecideRoute(ctx, routedReq, availableProviders)
+	if err != nil {
+		return RoutingDecision{}, fmt.Errorf("%s: %w", reason, err)
+	}
+	decision.Model = routedReq.Model
+	decision.Reason = reason + ": " + decision.Reason
+	return decision, nil
+}
+
+// decideFallback routes the request unchanged with the fallback policy.
+func (p *SemanticPolicy) decideFallback(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider, why string) (RoutingDecision, error) {
+	decision, err := p.fallback.DecideRoute(ctx, req, availableProviders)
+	if err != nil {
+		return RoutingDecision{}, err
+	}
+	decision.Reason = fmt.Sprintf("Fallback (%s): %s", why, decision.Reason)
+	return decision, nil
+}
+
+// DecideStage decides prompts matching a route whose provider can serve them,
+// and defers the rest to the next pipeline stage, with the model of the
+// matched route if any.
+func (p *SemanticPolicy) DecideStage(ctx context.Context, req models.ChatRequest, candidates map[string]providers.Provider) (StageResult, error) {
+	if err := p.ValidateRequest(req); err != nil {
+		return StageResult{}, fmt.Errorf("invalid request: %w", err)
+	}
+
+	prompt := lastUserMessage(req)
+	if prompt == "" {
+		return StageResult{Reason: "no user message to match"}, nil
+	}
+	route, similarity, err := p.match(ctx, prompt, candidates)
+	if err != nil {
+		return StageResult{Reason: fmt.Sprintf("semantic matching failed: %v", err)}, nil
+	}
+	if route == nil {
+		return StageResult{Reason: fmt.Sprintf("no route matched (best similarity %.2f)", similarity)}, nil
+	}
+
+	model := req.Model
+	if route.Model != "" {
+		model = route.Model
+	}
+	reason := fmt.Sprintf("Semantic route %q (similarity %.2f)", route.Name, similarity)
+	if route.Provider != "" {
+		healthyProviders := p.excludeRateLimited(ctx, p.getHealthyProviders(candidates))
+		if provider, exists := healthyProviders[route.Provider]; exists && p.providerSupportsModel(provider, model) {
+			return StageResult{Decision: &RoutingDecision{
+				ProviderName: route.Provider,
+				Model:        model,
+				Reason:       reason,
+				Confidence:   similarity,
+			}}, nil
+		}
+		reason += fmt.Sprintf(", provider %s unavailable", route.Provider)
+	}
+	return StageResult{Model: route.Model, Reason: reason}, nil
+}
+
+// match returns the route most similar to the prompt and the similarity, or a
+// nil route if none reaches its threshold.
+func (p *SemanticPolicy) match(ctx context.Context, prompt string, availableProviders map[string]providers.Provider) (*SemanticRoute, float64, error) {
+	provider, exists := availableProviders[p.embeddingProvider]
+	if !exists {
+		return nil, 0, fmt.Errorf("embedding provider %s not available", p.embeddingProvider)
+	}
+
+	examples, err := p.exampleEmbeddings(ctx, provider)
+	if err != nil {
+		return nil, 0, err
+	}
+	query, err := p.promptEmbedding(ctx, provider, prompt)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var best *SemanticRoute
+	bestSimilarity := -1.0
+	for i := range p.routes {
+		for _, example := range examples[i] {
+			similarity := cosineSimilarity(query, example)
+			if similarity > bestSimilarity {
+				best, bestSimilarity = &p.routes[i], similarity
+			}
+		}
+	}
+
+	threshold := p.threshold
+	if best != nil && best.Threshold > 0 {
+		threshold = best.Threshold
+	}
+	if best == nil || bestSimilarity < threshold {
+		return nil, bestSimilarity, nil
+	}
+	return best, bestSimilarity, nil
+}
+
+// exampleEmbeddings embeds the example prompts of all routes on first use. A
+// failure is retried on the next request.
+func (p *SemanticPolicy) exampleEmbeddings(ctx context.Context, provider providers.Provider) ([][][]float64, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.examples != nil {
+		return p.examples, nil
+	}
+
+	var inputs []string
+	for _, route := range p.routes {
+		inputs = append(inputs, route.Examples...)
+	}
+	vectors, err := p.embed(ctx, provider, inputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed route examples: %w", err)
+	}
+
+	examples := make([][][]float64, len(p.routes))
+	next := 0
+	for i, route := range p.routes {
+		examples[i] = vectors[next : next+len(route.Examples)]
+		next += len(route.Examples)
+	}
+	p.examples = examples
+	return examples, nil
+}
+
+// promptEmbedding embeds a prompt, reusing the embedding of a recent identical
+// prompt.
+func (p *SemanticPolicy) promptEmbedding(ctx context.Context, provider providers.Provider, prompt string) ([]float64, error) {
+	p.mutex.Lock()
+	cached, found := p.prompts[prompt]
+	p.mutex.Unlock()
+	if found {
+		return cached, nil
+	}
+
+	vectors, err := p.embed(ctx, provider, []string{prompt})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed prompt: %w", err)
+	}
+
+	p.mutex.Lock()
+	if len(p.prompts) >= maxCachedPrompts {
+		p.prompts = make(map[string][]float64)
+	}
+	p.prompts[prompt] = vectors[0]
+	p.mutex.Unlock()
+	return vectors[0], nil
+}
+
+// embed returns one embedding per input, in input order.
+func (p *SemanticPolicy) embed(ctx context.Context, provider providers.Provider, inputs []string) ([][]float64, error) {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	response, err := providers.Embed(ctx, provider, models.EmbeddingRequest{
+		Model: p.embeddingModel,
+		Input: inputs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float64, len(inputs))
+	for _, embedding := range response.Data {
+		if embedding.Index >= 0 && embedding.Index < len(vectors) {
+			vectors[embedding.Index] = embedding.Embedding
+		}
+	}
+	for i, vector := range vectors {
+		if len(vector) == 0 {
+			return nil, fmt.Errorf("no embedding returned for input %d", i)
+		}
+	}
+	return vectors, nil
+}
+
+// UpdateMetrics records the outcome and passes it on to the fallback policy,
+// which made the provider choice for most requests.
+func (p *SemanticPolicy) UpdateMetrics(decision RoutingDecision, success bool, latency time.Duration) {
+	p.BasePolicy.UpdateMetrics(decision, success, latency)
+	p.fallback.UpdateMetrics(decision, success, latency)
+}
+
+// lastUserMessage returns the text of the last user message.
+func lastUserMessage(req models.ChatRequest) string {
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == "user" {
+			return req.Messages[i].Content.Text()
+		}
+	}
+	return ""
+}
+
+// cosineSimilarity returns the cosine of the angle between two vectors, or 0
+// if their lengths differ or either is zero.
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
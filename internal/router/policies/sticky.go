@@ -0,0 +1,158 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// StickyPolicy keeps requests with the same affinity key, such as a user or
+// conversation ID, on the same provider. Providers are ranked per key by
+// rendezvous hashing, so when a key's provider is unavailable its requests go
+// to the key's next provider and return once it recovers, while other keys do
+// not move. Requests without a key are routed by the fallback policy.
+type StickyPolicy struct {
+	*BasePolicy
+	key      string
+	fallback RoutingPolicy
+}
+
+// NewStickyPolicy creates a sticky policy taking the affinity key from key:
+// "user", "tenant", "metadata:<field>" or "header:<name>".
+func NewStickyPolicy(key string, fallback RoutingPolicy) *StickyPolicy {
+	return &StickyPolicy{
+		BasePolicy: NewBasePolicy(
+			"sticky",
+			"Keeps requests with the same user or conversation ID on the same provider",
+		),
+		key:      key,
+		fallback: fallback,
+	}
+}
+
+// validAffinityKey reports whether key names a supported affinity key source.
+func validAffinityKey(key string) bool {
+	switch {
+	case key == "user", key == "tenant":
+		return true
+	case strings.HasPrefix(key, "metadata:"):
+		return len(key) > len("metadata:")
+	case strings.HasPrefix(key, "header:"):
+		return len(key) > len("header:")
+	}
+	return false
+}
+
+// DecideRoute routes the request to the highest-ranked available provider for
+// its affinity key.
+func (p *StickyPolicy) DecideRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) (RoutingDecision, error) {
+	if err := p.ValidateRequest(req); err != nil {
+		return RoutingDecision{}, fmt.Errorf("invalid request: %w", err)
+	}
+
+	affinity := p.affinityKey(ctx, req)
+	if affinity == "" {
+		decision, err := p.fallback.DecideRoute(ctx, req, availableProviders)
+		if err != nil {
+			return RoutingDecision{}, err
+		}
+		decision.Reason = "Fallback (no affinity key): " + decision.Reason
+		return decision, nil
+	}
+
+	healthyProviders := p.getHealthyProviders(availableProviders)
+	if len(healthyProviders) == 0 {
+		return RoutingDecision{}, fmt.Errorf("no healthy providers available")
+	}
+
+	// Rate-limited providers are skipped like unhealthy ones; the key returns
+	// to them once they recover
+	healthyProviders = p.excludeRateLimited(ctx, healthyProviders)
+
+	var chosen string
+	var bestScore uint64
+	for name, provider := range healthyProviders {
+		if !p.providerSupportsModel(provider, req.Model) {
+			continue
+		}
+		score := rendezvousScore(affinity, name)
+		if chosen == "" || score > bestScore || (score == bestScore && name < chosen) {
+			chosen, bestScore = name, score
+		}
+	}
+	if chosen == "" {
+		return RoutingDecision{}, fmt.Errorf("no available providers for model %s", req.Model)
+	}
+
+	reason := "Sticky affinity"
+	if home := p.homeProvider(affinity, req.Model, availableProviders); home != chosen {
+		reason = fmt.Sprintf("Sticky affinity, failed over from %s", home)
+	}
+	return RoutingDecision{
+		ProviderName: chosen,
+		Model:        req.Model,
+		Reason:       reason,
+		Confidence:   1.0,
+	}, nil
+}
+
+// homeProvider returns the provider the key maps to when every provider
+// serving the model is available.
+func (p *StickyPolicy) homeProvider(affinity, model string, availableProviders map[string]providers.Provider) string {
+	var home string
+	var bestScore uint64
+	for name, provider := range availableProviders {
+		if !p.providerSupportsModel(provider, model) {
+			continue
+		}
+		score := rendezvousScore(affinity, name)
+		if home == "" || score > bestScore || (score == bestScore && name < home) {
+			home, bestScore = name, score
+		}
+	}
+	return home
+}
+
+// affinityKey returns the request's affinity key, or "" if it has none.
+func (p *StickyPolicy) affinityKey(ctx context.Context, req models.ChatRequest) string {
+	switch {
+	case p.key == "user":
+		return req.User
+	case p.key == "tenant":
+		return RequestInfoFrom(ctx).Tenant
+	case strings.HasPrefix(p.key, "metadata:"):
+		return req.Metadata[strings.TrimPrefix(p.key, "metadata:")]
+	case strings.HasPrefix(p.key, "header:"):
+		return RequestInfoFrom(ctx).Headers.Get(strings.TrimPrefix(p.key, "header:"))
+	}
+	return ""
+}
+
+// UpdateMetrics records the outcome and passes it on to the fallback policy.
+func (p *StickyPolicy) UpdateMetrics(decision RoutingDecision, success bool, latency time.Duration) {
+	p.BasePolicy.UpdateMetrics(decision, success, latency)
+	p.fallback.UpdateMetrics(decision, success, latency)
+}
+
+// rendezvousScore ranks a provider for an affinity key; the highest score
+// wins. FNV-1a is finished with the splitmix64 mixer, as it spreads poorly on
+// its own when inputs differ only in their last bytes.
+func rendezvousScore(key, provider string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(provider))
+
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,71 @@
+package policies
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// SwappablePolicy routes with a policy that can be replaced while requests
+// are served. Each call uses the policy current at the time; a request that
+// straddles a swap may decide with the old policy and report its outcome to
+// the new one.
+type SwappablePolicy struct {
+	current atomic.Pointer[swappedPolicy]
+}
+
+// swappedPolicy holds the current policy, as atomic.Pointer needs a concrete
+// type.
+type swappedPolicy struct {
+	policy RoutingPolicy
+}
+
+// NewSwappablePolicy creates a swappable policy routing with policy.
+func NewSwappablePolicy(policy RoutingPolicy) *SwappablePolicy {
+	p := &SwappablePolicy{}
+	p.current.Store(&swappedPolicy{policy: policy})
+	return p
+}
+
+// Current returns the policy in use.
+func (p *SwappablePolicy) Current() RoutingPolicy {
+	return p.current.Load().policy
+}
+
+// Swap replaces the policy in use and returns the previous one.
+func (p *SwappablePolicy) Swap(policy RoutingPolicy) RoutingPolicy {
+	return p.current.Swap(&swappedPolicy{policy: policy}).policy
+}
+
+// DecideRoute decides with the current policy.
+func (p *SwappablePolicy) DecideRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) (RoutingDecision, error) {
+	return p.Current().DecideRoute(ctx, req, availableProviders)
+}
+
+// ExplainRoute explains the route with the current policy.
+func (p *SwappablePolicy) ExplainRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) []CandidateExplanation {
+	return Explain(ctx, p.Current(), req, availableProviders)
+}
+
+// GetName returns the name of the current policy.
+func (p *SwappablePolicy) GetName() string {
+	return p.Current().GetName()
+}
+
+// GetDescription returns the description of the current policy.
+func (p *SwappablePolicy) GetDescription() string {
+	return p.Current().GetDescription()
+}
+
+// ValidateRequest validates the request with the current policy.
+func (p *SwappablePolicy) ValidateRequest(req models.ChatRequest) error {
+	return p.Current().ValidateRequest(req)
+}
+
+// UpdateMetrics reports the outcome to the current policy.
+func (p *SwappablePolicy) UpdateMetrics(decision RoutingDecision, success bool, latency time.Duration) {
+	p.Current().UpdateMetrics(decision, success, latency)
+}
@@ -0,0 +1,175 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// WeightedPolicy distributes requests among the healthy providers supporting
+// the requested model in proportion to their configured weights. Weights are
+// relative: 80 and 20 split traffic the same way as 4 and 1. When a provider
+// is unavailable its share is spread over the others. With an error budget,
+// providers burning it too fast are reweighted automatically.
+type WeightedPolicy struct {
+	*BasePolicy
+	weights       map[string]float64
+	defaultWeight float64
+	budget        *errorBudget // nil without error budget reweighting
+}
+
+// NewWeightedPolicy creates a weighted policy. Providers without a weight get
+// defaultWeight; with a default of 0 they receive no traffic.
+func NewWeightedPolicy(weights map[string]float64, defaultWeight float64) *WeightedPolicy {
+	return &WeightedPolicy{
+		BasePolicy: NewBasePolicy(
+			"weighted",
+			"Distributes requests among healthy providers in proportion to configured weights",
+		),
+		weights:       weights,
+		defaultWeight: defaultWeight,
+	}
+}
+
+// WithErrorBudget enables reweighting providers by their error budget burn
+// rate: a provider's weight is cut in proportion to how fast it burns its
+// budget, and restored gradually as it recovers.
+func (p *WeightedPolicy) WithErrorBudget(config ErrorBudgetConfig) (*WeightedPolicy, error) {
+	config, err := config.withDefaults()
+	if err != nil {
+		return nil, fmt.Errorf("error_budget: %w", err)
+	}
+	p.budget = newErrorBudget(config)
+	return p, nil
+}
+
+// DecideRoute picks a provider at random, weighted by the configured weights.
+func (p *WeightedPolicy) DecideRoute(ctx context.Context, req models.ChatRequest, availableProviders map[string]providers.Provider) (RoutingDecision, error) {
+	if err := p.ValidateRequest(req); err != nil {
+		return RoutingDecision{}, fmt.Errorf("invalid request: %w", err)
+	}
+	if p.budget != nil {
+		p.budget.evaluate(time.Now())
+	}
+
+	healthyProviders := p.getHealthyProviders(availableProviders)
+	if len(healthyProviders) == 0 {
+		return RoutingDecision{}, fmt.Errorf("no healthy providers available")
+	}
+
+	// Avoid providers that are about to throttle
+	healthyProviders = p.excludeRateLimited(ctx, healthyProviders)
+
+	type candidate struct {
+		name   string
+		weight float64
+	}
+	var candidates []candidate
+	total := 0.0
+	for name, provider := range healthyProviders {
+		weight := p.weight(ctx, name)
+		if Deterministic(ctx) {
+			// Error budget factors change with traffic
+			weight = p.configuredWeight(ctx, name)
+		}
+		if weight <= 0 || !p.providerSupportsModel(provider, req.Model) {
+			continue
+		}
+		candidates = append(candidates, candidate{name: name, weight: weight})
+		total += weight
+	}
+	if len(candidates) == 0 {
+		return RoutingDecision{}, fmt.Errorf("no weighted providers available for model %s", req.Model)
+	}
+
+	// Sorted so a given draw always maps to the same provider
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].name < candidates[j].name })
+
+	chosen := candidates[len(candidates)-1]
+	point := Draw(ctx, req) * total
+	for _, c := range candidates {
+		if point < c.weight {
+			chosen = c
+			break
+		}
+		point -= c.weight
+	}
+
+	share := chosen.weight / total
+	return RoutingDecision{
+		ProviderName: chosen.name,
+		Model:        req.Model,
+		Reason:       fmt.Sprintf("Weighted selection (%.0f%% share)", share*100),
+		Confidence:   share,
+	}, nil
+}
+
+// weight returns a provider's weight in effect.
+func (p *WeightedPolicy) weight(ctx context.Context, name string) float64 {
+	weight := p.configuredWeight(ctx, name)
+	if p.budget != nil {
+		weight *= p.budget.factor(name)
+	}
+	return weight
+}
+
+// configuredWeight returns a provider's configured weight, or the weight an
+// operator set in its place.
+func (p *WeightedPolicy) configuredWeight(ctx context.Context, name string) float64 {
+	if weight, set := OperatorWeight(ctx, name); set {
+		return weight
+	}
+	if weight, exists := p.weights[name]; exists {
+		return weight
+	}
+	return p.defaultWeight
+}
+
+// UpdateMetrics records the outcome against the provider's error budget.
+func (p *WeightedPolicy) UpdateMetrics(decision RoutingDecision, success bool, latency time.Duration) {
+	p.BasePolicy.UpdateMetrics(decision, success, latency)
+	if p.budget != nil {
+		p.budget.observe(decision.ProviderName, success, time.Now())
+	}
+}
+
+// WeightReport returns the configured weights and, with an error budget, the
+// factors applied to them and their recent changes.
+func (p *WeightedPolicy) WeightReport() WeightReport {
+	weights := p.GetWeights()
+	if p.budget == nil {
+		report := WeightReport{Providers: make(map[string]ProviderWeight, len(weights)), Changes: []WeightChange{}}
+		for name, weight := range weights {
+			report.Providers[name] = ProviderWeight{Configured: weight, Factor: 1, Effective: weight}
+		}
+		return report
+	}
+
+	for _, name := range p.budget.providers() {
+		if _, exists := weights[name]; !exists {
+			weights[name] = p.defaultWeight
+		}
+	}
+	return p.budget.report(weights, time.Now())
+}
+
+// OnWeightChange registers a function called with every weight change made
+// by the error budget.
+func (p *WeightedPolicy) OnWeightChange(listener func(WeightChange)) {
+	if p.budget != nil {
+		p.budget.addListener(listener)
+	}
+}
+
+// GetWeights returns a copy of the configured weights.
+func (p *WeightedPolicy) GetWeights() map[string]float64 {
+	weights := make(map[string]float64, len(p.weights))
+	for name, weight := range p.weights {
+		weights[name] = weight
+	}
+	return weights
+}
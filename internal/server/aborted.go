@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/tokenizer"
+	"github.com/semantrix/semaroute/internal/usage"
+)
+
+// abortReason returns why a request whose context ended was cut short.
+func abortReason(ctx context.Context) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return usage.AbortDeadline
+	}
+	return usage.AbortClientDisconnect
+}
+
+// streamedTokens returns the prompt and completion tokens of a chat
+// completion that delivered output. The usage reported by the provider is
+// preferred; without it, the tokens are estimated from the prompt and the
+// delivered text.
+func streamedTokens(providerName string, req models.ChatRequest, delivered *streamOutput) (int, int) {
+	if delivered != nil && delivered.usage != nil {
+		return delivered.usage.PromptTokens, delivered.usage.CompletionTokens
+	}
+	promptTokens := tokenizer.CountMessages(providerName, req.Model, req.Messages)
+	completionTokens := 0
+	if delivered != nil && delivered.text.Len() > 0 {
+		completionTokens = tokenizer.CountText(providerName, req.Model, delivered.text.String())
+	}
+	return promptTokens, completionTokens
+}
+
+// recordStreamed accounts for a chat completion streamed to the end.
+func (s *Server) recordStreamed(tenant, providerName string, req models.ChatRequest, delivered *streamOutput) {
+	promptTokens, completionTokens := streamedTokens(providerName, req, delivered)
+	cost, _ := s.modelCatalog.EstimateCost(providerName, req.Model, promptTokens, completionTokens)
+	s.recordUsage(usage.Record{
+		Tenant:           tenant,
+		Provider:         providerName,
+		Model:            req.Model,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		Cost:             cost,
+	})
+}
+
+// recordAborted accounts for a chat completion cut short by reason before it
+// completed, with what it delivered before the abort, if anything. Providers
+// rarely report usage for aborted requests, so unless the last chunk carried
+// it, it is estimated from the prompt and the delivered text. Its cost is
+// counted per the aborted_cost policy.
+func (s *Server) recordAborted(tenant, providerName string, req models.ChatRequest, delivered *streamOutput, reason string) {
+	promptTokens, completionTokens := streamedTokens(providerName, req, delivered)
+
+	consumed, _ := s.modelCatalog.EstimateCost(providerName, req.Model, promptTokens, completionTokens)
+	full := consumed
+	if req.MaxTokens > completionTokens {
+		full, _ = s.modelCatalog.EstimateCost(providerName, req.Model, promptTokens, req.MaxTokens)
+	}
+	policy, _ := s.config.Usage.AbortedCostPolicy()
+
+	s.metrics.RecordAbortedRequest(providerName, req.Model, reason)
+	s.logger.Info("Request aborted",
+		zap.String("request_id", req.RequestID),
+		zap.String("provider", providerName),
+		zap.String("reason", reason),
+		zap.Int("prompt_tokens", promptTokens),
+		zap.Int("completion_tokens", completionTokens))
+	s.recordUsage(usage.Record{
+		Tenant:           tenant,
+		Provider:         providerName,
+		Model:            req.Model,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		Cost:             usage.AbortedCost(policy, consumed, full),
+		Aborted:          reason,
+	})
+}
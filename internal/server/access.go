@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/internal/router/policies"
+	"github.com/semantrix/semaroute/internal/tenants"
+)
+
+// accessList is the access list of a request's tenant or API key.
+type accessList struct {
+	owner  string // "tenant" or "API key", for error messages
+	access tenants.Access
+}
+
+// accessListsFrom returns the access lists of the request's tenant and API
+// key, if any.
+func accessListsFrom(ctx context.Context) []accessList {
+	tenant, _ := ctx.Value(tenantContextKey{}).(requestTenant)
+	return tenant.Access
+}
+
+// allowedBy reports whether every access list allows the provider and model.
+func allowedBy(lists []accessList, provider, model string) bool {
+	for _, list := range lists {
+		if !list.access.AllowsProvider(provider) || !list.access.AllowsModel(model) {
+			return false
+		}
+	}
+	return true
+}
+
+// newAccessListFilter returns policy middleware that enforces the access
+// lists of the request's tenant and API key. Providers they deny are left out
+// of the candidates; a decided model they deny fails the decision, so models
+// chosen by the policy for virtual or aliased models are checked too.
+func newAccessListFilter() policies.Middleware {
+	return policies.MiddlewareFuncs{
+		Label: "access_lists",
+		Before: func(ctx context.Context, req models.ChatRequest, candidates map[string]providers.Provider) (map[string]providers.Provider, error) {
+			lists := accessListsFrom(ctx)
+			if len(lists) == 0 {
+				return candidates, nil
+			}
+
+			allowed := make(map[string]providers.Provider, len(candidates))
+			for name, provider := range candidates {
+				if providerAllowed(lists, name) {
+					allowed[name] = provider
+				}
+			}
+			if len(allowed) == 0 && len(candidates) > 0 {
+				return nil, &modelAccessError{
+					model:  req.Model,
+					tenant: policies.RequestInfoFrom(ctx).Tenant,
+					reason: "no provider it may use is available",
+				}
+			}
+			return allowed, nil
+		},
+		After: func(ctx context.Context, req models.ChatRequest, decision policies.RoutingDecision) (policies.RoutingDecision, error) {
+			model := routedRequest(req, decision).Model
+			for _, list := range accessListsFrom(ctx) {
+				if !list.access.AllowsModel(model) {
+					return policies.RoutingDecision{}, &modelAccessError{
+						model:  model,
+						tenant: policies.RequestInfoFrom(ctx).Tenant,
+						reason: "denied by the " + list.owner + "'s access list",
+					}
+				}
+			}
+			return decision, nil
+		},
+	}
+}
+
+// providerAllowed reports whether every access list allows the provider.
+func providerAllowed(lists []accessList, provider string) bool {
+	for _, list := range lists {
+		if !list.access.AllowsProvider(provider) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/semantrix/semaroute/internal/cache"
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/internal/router/policies"
+	"github.com/semantrix/semaroute/internal/tenants"
+)
+
+// tenantOf runs a request with the API key through the tenant middleware and
+// returns the tenant it identified.
+func tenantOf(t *testing.T, s *Server, apiKey string) requestTenant {
+	t.Helper()
+	var tenant requestTenant
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	r.Header.Set("Authorization", "Bearer "+apiKey)
+	w := httptest.NewRecorder()
+	s.tenantMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = tenantFrom(r)
+	})).ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("tenant middleware status = %d: %s", w.Code, w.Body)
+	}
+	return tenant
+}
+
+func TestTenantMiddlewareCollectsAccessLists(t *testing.T) {
+	registry, err := tenants.NewRegistry(tenants.Config{RequireAPIKey: true, Tenants: []tenants.TenantConfig{
+		{
+			ID:      "acme",
+			Access:  tenants.Access{DenyProviders: []string{"anthropic"}},
+			APIKeys: []string{"sk-acme"},
+			Keys:    []tenants.KeyConfig{{Key: "sk-acme-mini", Access: tenants.Access{AllowModels: []string{"gpt-4o-mini"}}}},
+		},
+		{ID: "globex", APIKeys: []string{"sk-globex"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{tenants: registry, logger: zap.NewNop()}
+
+	if lists := tenantOf(t, s, "sk-acme").Access; len(lists) != 1 || lists[0].owner != "tenant" {
+		t.Fatalf("access lists of a plain key = %+v, want the tenant's", lists)
+	}
+	lists := tenantOf(t, s, "sk-acme-mini").Access
+	if len(lists) != 2 || lists[0].owner != "API key" || lists[1].owner != "tenant" {
+		t.Fatalf("access lists of a restricted key = %+v, want the key's and the tenant's", lists)
+	}
+	if allowedBy(lists, "openai", "gpt-4o") || allowedBy(lists, "anthropic", "gpt-4o-mini") || !allowedBy(lists, "openai", "gpt-4o-mini") {
+		t.Fatal("both access lists should apply to a restricted key")
+	}
+	if lists := tenantOf(t, s, "sk-globex").Access; len(lists) != 0 {
+		t.Fatalf("access lists of an unrestricted tenant = %+v", lists)
+	}
+}
+
+func TestAccessListFilter(t *testing.T) {
+	filter := newAccessListFilter()
+	candidates := map[string]providers.Provider{"openai": nil, "anthropic": nil, "azure": nil}
+	restricted := func(lists ...accessList) context.Context {
+		ctx := context.WithValue(context.Background(), tenantContextKey{}, requestTenant{ID: "acme", Access: lists})
+		return policies.WithRequestInfo(ctx, policies.RequestInfo{Tenant: "acme"})
+	}
+	tenantList := accessList{owner: "tenant", access: tenants.Access{DenyProviders: []string{"azure"}}}
+	keyList := accessList{owner: "API key", access: tenants.Access{AllowProviders: []string{"openai"}, AllowModels: []string{"gpt-4o*"}}}
+	req := models.ChatRequest{Model: "gpt-4o"}
+
+	t.Run("unrestricted", func(t *testing.T) {
+		allowed, err := filter.BeforeDecide(context.Background(), req, candidates)
+		if err != nil || len(allowed) != len(candidates) {
+			t.Fatalf("BeforeDecide() = %v, %v, want every candidate", allowed, err)
+		}
+	})
+
+	t.Run("denied providers are left out", func(t *testing.T) {
+		allowed, err := filter.BeforeDecide(restricted(tenantList), req, candidates)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, found := allowed["azure"]; found || len(allowed) != 2 {
+			t.Fatalf("BeforeDecide() = %v, want openai and anthropic", allowed)
+		}
+		allowed, err = filter.BeforeDecide(restricted(keyList, tenantList), req, candidates)
+		if _, found := allowed["openai"]; err != nil || !found || len(allowed) != 1 {
+			t.Fatalf("BeforeDecide() = %v, %v, want just openai", allowed, err)
+		}
+	})
+
+	t.Run("no allowed provider", func(t *testing.T) {
+		_, err := filter.BeforeDecide(restricted(keyList), req, map[string]providers.Provider{"anthropic": nil})
+		var denied *modelAccessError
+		if !errors.As(err, &denied) || denied.tenant != "acme" {
+			t.Fatalf("BeforeDecide() error = %v, want a modelAccessError", err)
+		}
+	})
+
+	t.Run("decided model", func(t *testing.T) {
+		ctx := restricted(keyList, tenantList)
+		if _, err := filter.AfterDecide(ctx, req, policies.RoutingDecision{ProviderName: "openai"}); err != nil {
+			t.Fatalf("AfterDecide() for an allowed model error = %v", err)
+		}
+		// A policy choosing a model the key may not use fails the decision
+		_, err := filter.AfterDecide(ctx, req, policies.RoutingDecision{ProviderName: "openai", Model: "o3"})
+		var denied *modelAccessError
+		if !errors.As(err, &denied) || denied.model != "o3" || denied.reason != "denied by the API key's access list" {
+			t.Fatalf("AfterDecide() error = %v, want the API key's denial of o3", err)
+		}
+	})
+}
+
+func TestCachedResponseHonoursAccessLists(t *testing.T) {
+	memory, err := cache.NewMemoryCache(cache.CacheConfig{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{cache: memory}
+	cached := []byte(`{"id": "chatcmpl-1", "model": "claude-3-5-sonnet", "provider": "anthropic", "choices": []}`)
+	if err := memory.Set(context.Background(), "key", cached, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	caller := func(lists ...accessList) context.Context {
+		return context.WithValue(context.Background(), tenantContextKey{}, requestTenant{ID: "acme", Access: lists})
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantHit bool
+	}{
+		{"unrestricted", context.Background(), true},
+		{"allowed", caller(accessList{owner: "tenant", access: tenants.Access{AllowProviders: []string{"anthropic"}}}), true},
+		{"provider denied", caller(accessList{owner: "tenant", access: tenants.Access{DenyProviders: []string{"anthropic"}}}), false},
+		{"model denied by the key", caller(
+			accessList{owner: "API key", access: tenants.Access{AllowModels: []string{"gpt-4o*"}}},
+			accessList{owner: "tenant"},
+		), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, hit := s.cachedResponse(test.ctx, "key")
+			if hit != test.wantHit {
+				t.Fatalf("cachedResponse() hit = %v, want %v", hit, test.wantHit)
+			}
+			if hit && response.ID != "chatcmpl-1" {
+				t.Fatalf("cachedResponse() = %+v", response)
+			}
+		})
+	}
+	if _, hit := s.cachedResponse(context.Background(), "other"); hit {
+		t.Fatal("cachedResponse() hit an empty key")
+	}
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/internal/router/policies"
+)
+
+// AliasTarget is a concrete model a model alias resolves to, optionally
+// pinned to one provider.
+type AliasTarget struct {
+	Provider string  `mapstructure:"provider"` // empty lets the routing policy choose
+	Model    string  `mapstructure:"model"`
+	Weight   float64 `mapstructure:"weight"` // share of the alias's traffic tried here first; 0 only as a fallback
+}
+
+// String formats the target as provider/model.
+func (t AliasTarget) String() string {
+	if t.Provider == "" {
+		return t.Model
+	}
+	return t.Provider + "/" + t.Model
+}
+
+// validateAliases checks that every alias has targets naming a model and a
+// configured provider.
+func validateAliases(aliases map[string][]AliasTarget, configured map[string]providers.Provider) error {
+	for alias, targets := range aliases {
+		if len(targets) == 0 {
+			return fmt.Errorf("model alias %s has no targets", alias)
+		}
+		for _, target := range targets {
+			if target.Model == "" {
+				return fmt.Errorf("model alias %s: target without a model", alias)
+			}
+			if target.Weight < 0 {
+				return fmt.Errorf("model alias %s: target %s has a negative weight", alias, target)
+			}
+			if target.Provider != "" {
+				if _, ok := configured[target.Provider]; !ok {
+					return fmt.Errorf("model alias %s: unknown provider %s", alias, target.Provider)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// aliasTargets returns the targets of model if it is an alias. Alias names
+// are matched case-insensitively.
+func (s *Server) aliasTargets(model string) (string, []AliasTarget) {
+	alias := strings.ToLower(model)
+	targets, ok := s.config.ModelAliases[alias]
+	if !ok {
+		return "", nil
+	}
+	return alias, targets
+}
+
+// decideRoute makes the routing decision for a request among the providers
+// whose context window holds it. A request for a model alias is routed to the
+// alias's first target the routing policy can serve, after the targets are
+// blended by weight. A model no provider can serve is routed to an equivalent
+// model, if one is configured. A decision for a provider out of rate-limit budget spills
+// over to the next candidate when spillover is enabled.
+// It returns the alias the request named, if any.
+func (s *Server) decideRoute(ctx context.Context, req models.ChatRequest, available map[string]providers.Provider) (policies.RoutingDecision, string, error) {
+	alias, targets := s.aliasTargets(req.Model)
+	if targets == nil {
+		candidates, err := s.excludeSmallContexts(req, available)
+		if err != nil {
+			return policies.RoutingDecision{}, "", err
+		}
+		decision, err := s.decideWithEquivalents(ctx, req, candidates)
+		if err != nil {
+			return decision, "", err
+		}
+		return s.spillExhausted(ctx, req, decision, candidates), "", nil
+	}
+
+	var lastErr error
+	for _, target := range blendAliasTargets(ctx, req, targets) {
+		decision, err := s.routeTarget(ctx, req, available, target)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		decision = s.spillExhausted(ctx, req, decision, available)
+		decision.Reason = fmt.Sprintf("Alias %s → %s: %s", alias, target, decision.Reason)
+		return decision, alias, nil
+	}
+	return policies.RoutingDecision{}, alias, fmt.Errorf("no target of model alias %s is available: %w", alias, lastErr)
+}
+
+// routeTarget routes a request to a target model, with the candidates
+// narrowed to the target's provider when it is pinned, and to the providers
+// whose context window for the model holds the request.
+func (s *Server) routeTarget(ctx context.Context, req models.ChatRequest, available map[string]providers.Provider, target AliasTarget) (policies.RoutingDecision, error) {
+	candidates := available
+	if target.Provider != "" {
+		provider, ok := available[target.Provider]
+		if !ok || !provider.IsHealthy() {
+			return policies.RoutingDecision{}, fmt.Errorf("provider %s is unavailable", target.Provider)
+		}
+		candidates = map[string]providers.Provider{target.Provider: provider}
+	}
+
+	req.Model = target.Model
+	candidates, err := s.excludeSmallContexts(req, candidates)
+	if err != nil {
+		return policies.RoutingDecision{}, err
+	}
+	decision, err := s.routingPolicy.DecideRoute(ctx, req, candidates)
+	if err != nil {
+		return policies.RoutingDecision{}, err
+	}
+	if decision.Model == "" {
+		decision.Model = target.Model
+	}
+	return decision, nil
+}
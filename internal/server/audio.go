@@ -0,0 +1,241 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/observability"
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/pkg/api/v1"
+	"go.uber.org/zap"
+)
+
+// maxAudioUploadSize matches the upload limit of the OpenAI audio API.
+const maxAudioUploadSize = 25 << 20
+
+// handleTranscription transcribes an uploaded audio file (multipart/form-data
+// with a "file" part), routed among the providers that can transcribe audio.
+func (s *Server) handleTranscription(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAudioUploadSize+(1<<20))
+	if err := r.ParseMultipartForm(maxAudioUploadSize); err != nil {
+		http.Error(w, "Invalid multipart body", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	audio, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusBadRequest)
+		return
+	}
+
+	req := models.TranscriptionRequest{
+		Model:          r.FormValue("model"),
+		File:           audio,
+		FileName:       header.Filename,
+		Language:       r.FormValue("language"),
+		Prompt:         r.FormValue("prompt"),
+		ResponseFormat: r.FormValue("response_format"),
+		RequestID:      r.FormValue("request_id"),
+	}
+	if temperature := r.FormValue("temperature"); temperature != "" {
+		req.Temperature, err = strconv.ParseFloat(temperature, 64)
+		if err != nil {
+			http.Error(w, "Invalid temperature", http.StatusBadRequest)
+			return
+		}
+	}
+
+	candidates := s.capableProviders(req.Model, func(p providers.Provider) bool {
+		_, ok := p.(providers.Transcriber)
+		return ok
+	})
+	if len(candidates) == 0 {
+		http.Error(w, "No provider supports audio transcription", http.StatusNotImplemented)
+		return
+	}
+
+	routingReq := models.ChatRequest{
+		Model:     req.Model,
+		Messages:  []models.Message{{Role: "user", Content: models.TextContent(req.Prompt)}},
+		RequestID: req.RequestID,
+		CreatedAt: time.Now(),
+	}
+
+	routingStart := time.Now()
+	decision, err := s.routingPolicy.DecideRoute(ctx, routingReq, candidates)
+	if err != nil {
+		s.logger.Error("Routing decision failed", zap.Error(err))
+		writeRoutingError(w, routingReq.RequestID, err)
+		return
+	}
+	s.metrics.RecordRoutingDecision(s.routingPolicy.GetName(), decision.ProviderName, decision.Model)
+	s.metrics.RecordRoutingLatency(s.routingPolicy.GetName(), time.Since(routingStart))
+
+	provider, ok := candidates[decision.ProviderName].(providers.Transcriber)
+	if !ok {
+		s.logger.Error("Selected provider not found", zap.String("provider", decision.ProviderName))
+		http.Error(w, "Provider not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	start := time.Now()
+	response, err := provider.Transcribe(ctx, req)
+	duration := time.Since(start)
+	observability.ProviderTimerFrom(ctx).Add(duration)
+
+	if err != nil {
+		s.writeAudioError(w, decision.ProviderName, req.RequestID, "transcription_failed", err)
+		return
+	}
+
+	s.metrics.RecordProviderLatency(decision.ProviderName, decision.Model, duration)
+	s.metrics.RecordProviderHealth(decision.ProviderName, true)
+
+	setOverheadHeader(w, r)
+	switch req.ResponseFormat {
+	case "text", "srt", "vtt":
+		contentType := "text/plain; charset=utf-8"
+		if req.ResponseFormat == "vtt" {
+			contentType = "text/vtt"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, response.Text)
+	default:
+		apiResponse := v1.TranscriptionResponse{
+			Text:      response.Text,
+			Language:  response.Language,
+			Duration:  response.Duration,
+			Provider:  decision.ProviderName,
+			RequestID: response.RequestID,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(apiResponse)
+	}
+}
+
+// handleSpeech synthesizes speech from text, routed among the providers that
+// can generate audio. The audio is returned as the response body.
+func (s *Server) handleSpeech(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var apiReq v1.SpeechRequest
+	if err := json.NewDecoder(r.Body).Decode(&apiReq); err != nil {
+		s.logger.Error("Failed to decode request", zap.Error(err))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if apiReq.Input == "" || apiReq.Voice == "" {
+		http.Error(w, "input and voice are required", http.StatusBadRequest)
+		return
+	}
+
+	req := models.SpeechRequest{
+		Model:          apiReq.Model,
+		Input:          apiReq.Input,
+		Voice:          apiReq.Voice,
+		ResponseFormat: apiReq.ResponseFormat,
+		Speed:          apiReq.Speed,
+		RequestID:      apiReq.RequestID,
+	}
+
+	candidates := s.capableProviders(req.Model, func(p providers.Provider) bool {
+		_, ok := p.(providers.Speaker)
+		return ok
+	})
+	if len(candidates) == 0 {
+		http.Error(w, "No provider supports speech synthesis", http.StatusNotImplemented)
+		return
+	}
+
+	routingReq := models.ChatRequest{
+		Model:     req.Model,
+		Messages:  []models.Message{{Role: "user", Content: models.TextContent(req.Input)}},
+		RequestID: req.RequestID,
+		CreatedAt: time.Now(),
+	}
+
+	routingStart := time.Now()
+	decision, err := s.routingPolicy.DecideRoute(ctx, routingReq, candidates)
+	if err != nil {
+		s.logger.Error("Routing decision failed", zap.Error(err))
+		writeRoutingError(w, routingReq.RequestID, err)
+		return
+	}
+	s.metrics.RecordRoutingDecision(s.routingPolicy.GetName(), decision.ProviderName, decision.Model)
+	s.metrics.RecordRoutingLatency(s.routingPolicy.GetName(), time.Since(routingStart))
+
+	provider, ok := candidates[decision.ProviderName].(providers.Speaker)
+	if !ok {
+		s.logger.Error("Selected provider not found", zap.String("provider", decision.ProviderName))
+		http.Error(w, "Provider not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	start := time.Now()
+	response, err := provider.Speak(ctx, req)
+	duration := time.Since(start)
+	observability.ProviderTimerFrom(ctx).Add(duration)
+
+	if err != nil {
+		s.writeAudioError(w, decision.ProviderName, req.RequestID, "speech_failed", err)
+		return
+	}
+
+	s.metrics.RecordProviderLatency(decision.ProviderName, decision.Model, duration)
+	s.metrics.RecordProviderHealth(decision.ProviderName, true)
+
+	contentType := response.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	setOverheadHeader(w, r)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(response.Audio)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(response.Audio)
+}
+
+// writeAudioError logs a failed audio request and writes the error response.
+func (s *Server) writeAudioError(w http.ResponseWriter, providerName, requestID, errorType string, err error) {
+	s.logger.Error("Provider audio request failed",
+		zap.String("provider", providerName),
+		zap.Error(err))
+	s.metrics.RecordProviderError(providerName, errorType)
+
+	errorResponse := v1.ErrorResponse{
+		Error: v1.ErrorDetails{
+			Type:       "provider_error",
+			Message:    err.Error(),
+			StatusCode: http.StatusBadGateway,
+			Provider:   providerName,
+		},
+		RequestID: requestID,
+	}
+	var providerErr *models.ProviderError
+	if errors.As(err, &providerErr) {
+		errorResponse.Error.Retryable = providerErr.Retryable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadGateway)
+	json.NewEncoder(w).Encode(errorResponse)
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/semantrix/semaroute/internal/audit"
+	"go.uber.org/zap"
+)
+
+// Bounds of the audit query endpoint.
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// adminActorKey carries the tenant of the API key an admin request was made
+// with.
+type adminActorKey struct{}
+
+// adminActor returns who made an admin request: the tenant of its API key, or
+// "anonymous" when the admin API is open and no key was sent.
+func adminActor(r *http.Request) string {
+	if actor, ok := r.Context().Value(adminActorKey{}).(string); ok {
+		return actor
+	}
+	return "anonymous"
+}
+
+// recordAudit records an admin action with the values it changed. Failing to
+// record does not undo the action, but is logged as an error.
+func (s *Server) recordAudit(r *http.Request, action, target string, before, after interface{}) {
+	if s.auditLog == nil {
+		return
+	}
+
+	entry := audit.Entry{
+		Actor:      adminActor(r),
+		Action:     action,
+		Target:     target,
+		RemoteAddr: r.RemoteAddr,
+		RequestID:  middleware.GetReqID(r.Context()),
+		Before:     auditValue(before),
+		After:      auditValue(after),
+	}
+	if _, err := s.auditLog.Record(entry); err != nil {
+		s.logger.Error("Failed to record admin action",
+			zap.String("action", action),
+			zap.String("actor", entry.Actor),
+			zap.String("target", target),
+			zap.Error(err))
+	}
+}
+
+// auditValue encodes a value recorded in the audit log. Nil records nothing.
+func auditValue(value interface{}) json.RawMessage {
+	if value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// handleGetAudit returns recorded admin actions, newest first, filtered by
+// the actor, action, target, since and until query parameters.
+func (s *Server) handleGetAudit(w http.ResponseWriter, r *http.Request) {
+	if s.auditLog == nil {
+		http.Error(w, "Audit log is disabled", http.StatusNotFound)
+		return
+	}
+
+	params := r.URL.Query()
+	query := audit.Query{
+		Actor:  params.Get("actor"),
+		Action: params.Get("action"),
+		Target: params.Get("target"),
+		Limit:  defaultAuditLimit,
+	}
+	for name, bound := range map[string]*time.Time{"since": &query.Since, "until": &query.Until} {
+		if value := params.Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, name+" must be an RFC 3339 time", http.StatusBadRequest)
+				return
+			}
+			*bound = parsed
+		}
+	}
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(maxAuditLimit), http.StatusBadRequest)
+			return
+		}
+		query.Limit = limit
+	}
+
+	entries := s.auditLog.Query(query)
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": entries,
+	})
+}
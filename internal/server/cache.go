@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/invalidation"
+	"github.com/semantrix/semaroute/internal/usage"
+	"github.com/semantrix/semaroute/pkg/api/v1"
+	"go.uber.org/zap"
+)
+
+// warmCache preloads the response cache with the responses requested most
+// often within the warm-up window, so a new instance does not start cold.
+func (s *Server) warmCache(ctx context.Context) {
+	if s.usageStore == nil {
+		s.logger.Warn("Cache warming needs the usage store, which is disabled")
+		return
+	}
+
+	config := s.config.Cache.Warm
+	entries := s.usageStore.TopResponses(time.Now().Add(-config.Window), config.MinHits, config.MaxEntries)
+	warmed := 0
+	for _, entry := range entries {
+		if err := s.cache.Set(ctx, entry.CacheKey, []byte(entry.Response), 0); err != nil {
+			s.logger.Warn("Failed to warm cache entry", zap.Error(err))
+			continue
+		}
+		warmed++
+	}
+
+	s.logger.Info("Warmed response cache", zap.Int("entries", warmed))
+}
+
+// cachedResponse returns the response cached under key, unless it came from
+// a provider or model the access lists of the request's tenant or API key
+// deny; such a request is routed like a miss instead.
+func (s *Server) cachedResponse(ctx context.Context, key string) (v1.ChatCompletionResponse, bool) {
+	var response v1.ChatCompletionResponse
+	cached, hit, _ := s.cache.Get(ctx, key)
+	if !hit {
+		return response, false
+	}
+	data, ok := cached.([]byte)
+	if !ok || json.Unmarshal(data, &response) != nil {
+		return response, false
+	}
+	return response, allowedBy(accessListsFrom(ctx), response.Provider, response.Model)
+}
+
+// recordUsage records the spend of a served chat completion and adds it to
+// the usage store, if enabled.
+func (s *Server) recordUsage(record usage.Record) {
+	if record.Cost > 0 {
+		s.metrics.RecordSpend(record.Provider, record.Model, record.Cost)
+	}
+	if s.usageStore == nil {
+		return
+	}
+	record.Time = time.Now()
+	if err := s.usageStore.Add(record); err != nil {
+		s.logger.Warn("Failed to record usage", zap.Error(err))
+	}
+}
+
+// purgeCache removes one cache entry, or every entry when key is empty.
+func (s *Server) purgeCache(ctx context.Context, key string) error {
+	if key == "" {
+		return s.cache.Clear(ctx)
+	}
+	return s.cache.Delete(ctx, key)
+}
+
+// publishInvalidation tells the other replicas about a local purge or reload.
+// A failure is logged; the local change has already been applied.
+func (s *Server) publishInvalidation(ctx context.Context, event invalidation.Event) {
+	if err := s.invalidation.Publish(ctx, event); err != nil {
+		s.logger.Warn("Failed to publish invalidation", zap.String("type", event.Type), zap.Error(err))
+	}
+}
+
+// applyInvalidation applies a purge or reload published by another replica.
+func (s *Server) applyInvalidation(event invalidation.Event) {
+	ctx := context.Background()
+	var err error
+
+	switch event.Type {
+	case invalidation.CachePurge:
+		err = s.purgeCache(ctx, event.Key)
+	case invalidation.PricingReload:
+		err = s.modelCatalog.Reload()
+	default:
+		s.logger.Debug("Ignoring unknown invalidation", zap.String("type", event.Type))
+		return
+	}
+
+	if err != nil {
+		s.logger.Error("Failed to apply invalidation",
+			zap.String("type", event.Type),
+			zap.String("origin", event.Origin),
+			zap.Error(err))
+		return
+	}
+	s.logger.Info("Applied invalidation",
+		zap.String("type", event.Type),
+		zap.String("origin", event.Origin),
+		zap.Duration("delay", time.Since(event.Time)))
+}
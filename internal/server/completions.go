@@ -0,0 +1,217 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/observability"
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/pkg/api/v1"
+	"go.uber.org/zap"
+)
+
+// handleCompletion serves the legacy prompt-style completions API. Each prompt
+// is sent as a single user message through the regular chat routing, and the
+// result is returned in the legacy "text_completion" shape.
+func (s *Server) handleCompletion(w http.ResponseWriter, r *http.Request) {
+	var apiReq v1.CompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&apiReq); err != nil {
+		s.logger.Error("Failed to decode request", zap.Error(err))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(apiReq.Prompt) == 0 {
+		http.Error(w, "prompt is required", http.StatusBadRequest)
+		return
+	}
+
+	if apiReq.Stream {
+		if len(apiReq.Prompt) > 1 {
+			http.Error(w, "Streaming supports a single prompt", http.StatusBadRequest)
+			return
+		}
+		s.handleCompletionStream(w, r, apiReq)
+		return
+	}
+
+	apiResponse := v1.CompletionResponse{
+		Object:    "text_completion",
+		Created:   time.Now().Unix(),
+		Model:     apiReq.Model,
+		Usage:     &v1.Usage{},
+		RequestID: apiReq.RequestID,
+	}
+
+	for i, prompt := range apiReq.Prompt {
+		response, err := s.routeAndComplete(r.Context(), completionChatRequest(apiReq, prompt))
+		if err != nil {
+			s.logger.Error("Provider request failed", zap.Error(err))
+			errorResponse := v1.ErrorResponse{
+				Error: v1.ErrorDetails{
+					Type:       "provider_error",
+					Message:    err.Error(),
+					StatusCode: http.StatusServiceUnavailable,
+					Retryable:  true,
+				},
+				RequestID: apiReq.RequestID,
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(errorResponse)
+			return
+		}
+
+		if apiResponse.ID == "" {
+			apiResponse.ID = response.ID
+			apiResponse.Model = response.Model
+			apiResponse.Provider = response.Provider
+		}
+
+		choice := v1.CompletionChoice{Index: i}
+		if len(response.Choices) > 0 {
+			choice.Text = response.Choices[0].Message.Content.Text()
+			choice.FinishReason = response.Choices[0].FinishReason
+		}
+		if apiReq.Echo {
+			choice.Text = prompt + choice.Text
+		}
+		apiResponse.Choices = append(apiResponse.Choices, choice)
+
+		apiResponse.Usage.PromptTokens += response.Usage.PromptTokens
+		apiResponse.Usage.CompletionTokens += response.Usage.CompletionTokens
+		apiResponse.Usage.TotalTokens += response.Usage.TotalTokens
+	}
+
+	setOverheadHeader(w, r)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(apiResponse)
+}
+
+// handleCompletionStream streams a legacy completion as Server-Sent Events.
+func (s *Server) handleCompletionStream(w http.ResponseWriter, r *http.Request, apiReq v1.CompletionRequest) {
+	release, ok := s.admitStream(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	ctx := r.Context()
+	req := completionChatRequest(apiReq, apiReq.Prompt[0])
+	req.Stream = true
+
+	available := s.providers.Snapshot()
+	decision, err := s.routingPolicy.DecideRoute(ctx, req, available)
+	if err != nil {
+		s.logger.Error("Routing decision failed", zap.Error(err))
+		writeRoutingError(w, req.RequestID, err)
+		return
+	}
+	s.metrics.RecordRoutingDecision(s.routingPolicy.GetName(), decision.ProviderName, decision.Model)
+	if decision.Category != "" {
+		s.metrics.RecordContentCategory(decision.Category, decision.ProviderName, decision.Model)
+	}
+
+	provider, exists := available[decision.ProviderName]
+	if !exists {
+		http.Error(w, "Provider not available", http.StatusServiceUnavailable)
+		return
+	}
+	req = routedRequest(req, decision)
+
+	timer := observability.ProviderTimerFrom(ctx)
+	start := time.Now()
+	stream, err := providers.OpenStream(ctx, provider, req)
+	timer.Add(time.Since(start))
+	if err != nil {
+		s.logger.Error("Provider stream request failed",
+			zap.String("provider", decision.ProviderName),
+			zap.Error(err))
+		s.metrics.RecordProviderError(decision.ProviderName, "stream_failed")
+		http.Error(w, "Provider stream failed", http.StatusBadGateway)
+		return
+	}
+
+	setOverheadHeader(w, r)
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", contentTypeSSE)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	writeEvent := func(payload interface{}) {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	streamStart := time.Now()
+	defer func() { timer.Add(time.Since(streamStart)) }()
+
+	if apiReq.Echo {
+		writeEvent(v1.CompletionResponse{
+			Object:  "text_completion",
+			Created: time.Now().Unix(),
+			Model:   apiReq.Model,
+			Choices: []v1.CompletionChoice{{Text: apiReq.Prompt[0]}},
+		})
+	}
+
+	for {
+		select {
+		case chunk, ok := <-stream:
+			if !ok {
+				fmt.Fprint(w, "data: [DONE]\n\n")
+				if flusher != nil {
+					flusher.Flush()
+				}
+				s.metrics.RecordProviderLatency(decision.ProviderName, decision.Model, time.Since(start))
+				return
+			}
+
+			legacy := v1.CompletionResponse{
+				ID:       chunk.ID,
+				Object:   "text_completion",
+				Created:  chunk.Created,
+				Model:    chunk.Model,
+				Provider: decision.ProviderName,
+			}
+			for _, choice := range chunk.Choices {
+				legacy.Choices = append(legacy.Choices, v1.CompletionChoice{
+					Text:         choice.Delta.Content.Text(),
+					Index:        choice.Index,
+					FinishReason: choice.FinishReason,
+				})
+			}
+			writeEvent(legacy)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// completionChatRequest adapts a legacy completion prompt into a chat request.
+func completionChatRequest(apiReq v1.CompletionRequest, prompt string) models.ChatRequest {
+	return models.ChatRequest{
+		Model:            apiReq.Model,
+		Messages:         []models.Message{{Role: "user", Content: models.TextContent(prompt)}},
+		MaxTokens:        apiReq.MaxTokens,
+		Temperature:      apiReq.Temperature,
+		TopP:             apiReq.TopP,
+		Stop:             apiReq.Stop,
+		PresencePenalty:  apiReq.PresencePenalty,
+		FrequencyPenalty: apiReq.FrequencyPenalty,
+		User:             apiReq.User,
+		RequestID:        apiReq.RequestID,
+		CreatedAt:        time.Now(),
+	}
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/internal/router/policies"
+	v1 "github.com/semantrix/semaroute/pkg/api/v1"
+)
+
+// contextTooLongError is returned when no candidate provider's context window
+// can hold a request.
+type contextTooLongError struct {
+	model   string
+	reasons []string // one per excluded provider
+}
+
+func (e *contextTooLongError) Error() string {
+	return fmt.Sprintf("request does not fit the context window of %s on any provider: %s",
+		e.model, strings.Join(e.reasons, "; "))
+}
+
+// excludeSmallContexts drops the candidates whose context window for the
+// requested model cannot hold the request, so the routing policy does not pick
+// a provider that would reject it. Providers that do not know the model's
+// context window are kept. It fails when every candidate is excluded.
+func (s *Server) excludeSmallContexts(req models.ChatRequest, candidates map[string]providers.Provider) (map[string]providers.Provider, error) {
+	fitting := make(map[string]providers.Provider, len(candidates))
+	var reasons []string
+	for name, provider := range candidates {
+		checker, ok := provider.(providers.ContextChecker)
+		if !ok {
+			fitting[name] = provider
+			continue
+		}
+		if err := checker.CheckContextWindow(req); err != nil {
+			reasons = append(reasons, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		fitting[name] = provider
+	}
+
+	if len(reasons) > 0 {
+		sort.Strings(reasons)
+		s.logger.Debug("Excluded providers with a too small context window",
+			zap.String("model", req.Model),
+			zap.Strings("reasons", reasons))
+	}
+	if len(fitting) == 0 && len(candidates) > 0 {
+		return nil, &contextTooLongError{model: req.Model, reasons: reasons}
+	}
+	return fitting, nil
+}
+
+// writeRoutingError writes the error response for a failed routing decision:
+// 400 for requests too long for any provider, 403 for models restricted to
+// other tenants, 422 for routing hints no provider satisfies, 503 otherwise.
+func writeRoutingError(w http.ResponseWriter, requestID string, err error) {
+	details := routingErrorDetails(err)
+	if details.StatusCode == http.StatusServiceUnavailable {
+		http.Error(w, "Routing failed", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(details.StatusCode)
+	json.NewEncoder(w).Encode(v1.ErrorResponse{
+		Error:     details,
+		RequestID: requestID,
+	})
+}
+
+// routingErrorDetails describes a failed routing decision.
+func routingErrorDetails(err error) v1.ErrorDetails {
+	var denied *modelAccessError
+	if errors.As(err, &denied) {
+		return v1.ErrorDetails{
+			Type:       "model_access_denied",
+			Message:    denied.Error(),
+			StatusCode: http.StatusForbidden,
+			Details:    map[string]interface{}{"model": denied.model},
+		}
+	}
+
+	var unsatisfiable *policies.ConstraintError
+	if errors.As(err, &unsatisfiable) {
+		return v1.ErrorDetails{
+			Type:       "routing_constraint_unsatisfiable",
+			Message:    unsatisfiable.Error(),
+			StatusCode: http.StatusUnprocessableEntity,
+			Details:    map[string]interface{}{"constraint": unsatisfiable.Constraint},
+		}
+	}
+
+	var scheduled *policies.ScheduleError
+	if errors.As(err, &scheduled) {
+		return v1.ErrorDetails{
+			Type:       "schedule_restricted",
+			Message:    scheduled.Error(),
+			StatusCode: http.StatusForbidden,
+			Details:    map[string]interface{}{"window": scheduled.Window},
+		}
+	}
+
+	var outside *policies.ResidencyError
+	if errors.As(err, &outside) {
+		return v1.ErrorDetails{
+			Type:       "residency_unsatisfiable",
+			Message:    outside.Error(),
+			StatusCode: http.StatusUnprocessableEntity,
+			Details:    map[string]interface{}{"residency": outside.Residency, "regions": outside.Regions},
+		}
+	}
+
+	var unsupported *policies.CapabilityError
+	if errors.As(err, &unsupported) {
+		return v1.ErrorDetails{
+			Type:       "capability_unsupported",
+			Message:    unsupported.Error(),
+			StatusCode: http.StatusUnprocessableEntity,
+			Details:    map[string]interface{}{"capability": string(unsupported.Capability)},
+		}
+	}
+
+	var tooLong *contextTooLongError
+	if errors.As(err, &tooLong) {
+		return v1.ErrorDetails{
+			Type:       "context_length_exceeded",
+			Message:    tooLong.Error(),
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	return v1.ErrorDetails{
+		Type:       "routing_failed",
+		Message:    err.Error(),
+		StatusCode: http.StatusServiceUnavailable,
+		Retryable:  true,
+	}
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/dataset"
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+// sampleDataset queues a share of the completions of consenting tenants for
+// the fine-tuning dataset. Requests without a configured tenant are never
+// sampled.
+func (s *Server) sampleDataset(r *http.Request, req models.ChatRequest, response *models.ChatResponse, providerName string, latency time.Duration) {
+	if s.dataset == nil {
+		return
+	}
+	tenantID := tenantFrom(r).ID
+	if tenant, found := s.tenants.Get(tenantID); !found || !tenant.DatasetConsent {
+		return
+	}
+	if !s.dataset.Sampled() {
+		return
+	}
+	s.dataset.Add(dataset.NewSample(req, response, tenantID, providerName, latency))
+}
+
+// handleGetDataset returns how many samples were written, dropped and failed.
+func (s *Server) handleGetDataset(w http.ResponseWriter, r *http.Request) {
+	if s.dataset == nil {
+		http.Error(w, "Dataset sampling is disabled", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(s.dataset.Stats())
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/semantrix/semaroute/internal/models"
+	v1 "github.com/semantrix/semaroute/pkg/api/v1"
+)
+
+// defaultsHeader lists the generation parameters filled in from defaults, as
+// name=source pairs.
+const defaultsHeader = "X-Semaroute-Defaults"
+
+// Sources of a generation parameter, in order of precedence.
+const (
+	sourceRequest = "request"
+	sourceTenant  = "tenant"
+	sourceGlobal  = "global"
+)
+
+// applyGenerationDefaults fills in the generation parameters a request omits
+// from its tenant's defaults, then the global ones. As elsewhere, a zero
+// temperature or max_tokens counts as omitted. It returns the request and the
+// source of every parameter that has a value.
+func (s *Server) applyGenerationDefaults(req models.ChatRequest, tenantID string) (models.ChatRequest, []v1.ParameterSource) {
+	tenant, global := s.tenants.Defaults(tenantID)
+	var sources []v1.ParameterSource
+
+	source := sourceOf(req.Temperature != 0, tenant.Temperature != 0, global.Temperature != 0)
+	switch source {
+	case sourceTenant:
+		req.Temperature = tenant.Temperature
+	case sourceGlobal:
+		req.Temperature = global.Temperature
+	}
+	if source != "" {
+		sources = append(sources, v1.ParameterSource{Name: "temperature", Value: req.Temperature, Source: source})
+	}
+
+	source = sourceOf(req.MaxTokens != 0, tenant.MaxTokens != 0, global.MaxTokens != 0)
+	switch source {
+	case sourceTenant:
+		req.MaxTokens = tenant.MaxTokens
+	case sourceGlobal:
+		req.MaxTokens = global.MaxTokens
+	}
+	if source != "" {
+		sources = append(sources, v1.ParameterSource{Name: "max_tokens", Value: req.MaxTokens, Source: source})
+	}
+
+	source = sourceOf(len(req.Stop) > 0, len(tenant.Stop) > 0, len(global.Stop) > 0)
+	switch source {
+	case sourceTenant:
+		req.Stop = tenant.Stop
+	case sourceGlobal:
+		req.Stop = global.Stop
+	}
+	if source != "" {
+		sources = append(sources, v1.ParameterSource{Name: "stop", Value: req.Stop, Source: source})
+	}
+
+	// A request's own system message replaces the preamble
+	preamble := ""
+	source = sourceOf(hasSystemMessage(req.Messages), tenant.SystemPreamble != "", global.SystemPreamble != "")
+	switch source {
+	case sourceTenant:
+		preamble = tenant.SystemPreamble
+	case sourceGlobal:
+		preamble = global.SystemPreamble
+	}
+	if preamble != "" {
+		system := models.Message{Role: "system", Content: models.TextContent(preamble)}
+		req.Messages = append([]models.Message{system}, req.Messages...)
+	}
+	if source != "" {
+		sources = append(sources, v1.ParameterSource{Name: "system_preamble", Value: preamble, Source: source})
+	}
+
+	return req, sources
+}
+
+// sourceOf returns the source of a parameter from whether the request, the
+// tenant and the global defaults set it, or "" if none does.
+func sourceOf(request, tenant, global bool) string {
+	switch {
+	case request:
+		return sourceRequest
+	case tenant:
+		return sourceTenant
+	case global:
+		return sourceGlobal
+	}
+	return ""
+}
+
+// hasSystemMessage reports whether a conversation has a system message.
+func hasSystemMessage(messages []models.Message) bool {
+	for _, message := range messages {
+		if message.Role == "system" {
+			return true
+		}
+	}
+	return false
+}
+
+// setDefaultsHeader reports the parameters filled in from defaults.
+func setDefaultsHeader(w http.ResponseWriter, sources []v1.ParameterSource) {
+	var applied []string
+	for _, source := range sources {
+		if source.Source != sourceRequest {
+			applied = append(applied, source.Name+"="+source.Source)
+		}
+	}
+	if len(applied) > 0 {
+		w.Header().Set(defaultsHeader, strings.Join(applied, ", "))
+	}
+}
+
+// effectiveDefaults returns the defaults a request of the tenant omitting
+// every parameter would get.
+func (s *Server) effectiveDefaults(tenantID string) []v1.ParameterSource {
+	_, sources := s.applyGenerationDefaults(models.ChatRequest{}, tenantID)
+	return sources
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/internal/router/policies"
+)
+
+// defaultDeferralMargin is how long after a quota reset deferred requests are
+// released when not configured.
+const defaultDeferralMargin = time.Second
+
+// DeferralConfig configures how deferred-priority requests wait for provider
+// quotas to reset.
+type DeferralConfig struct {
+	// Longest a request waits for a reset; a request whose providers reset
+	// later is routed right away. 0 disables deferral
+	MaxWait time.Duration `mapstructure:"max_wait"`
+
+	// Waited past a reset, as provider clocks may run behind; default 1s
+	Margin time.Duration `mapstructure:"margin"`
+
+	// Between requests released at the same reset, so the backlog does not
+	// use up the new quota in one burst
+	Spread time.Duration `mapstructure:"spread"`
+
+	// Requests scheduled beyond this are routed right away; 0 = unlimited
+	MaxQueued int `mapstructure:"max_queued"`
+}
+
+// scheduledRequest is a deferred request waiting for a provider's quota reset.
+type scheduledRequest struct {
+	RequestID   string    `json:"request_id"`
+	Tenant      string    `json:"tenant,omitempty"`
+	Model       string    `json:"model"`
+	Provider    string    `json:"provider"` // whose reset the request waits for
+	ResetsAt    time.Time `json:"resets_at"`
+	EnqueuedAt  time.Time `json:"enqueued_at"`
+	ScheduledAt time.Time `json:"scheduled_at"` // when the request is routed
+}
+
+// deferralQueue is the backlog of deferred requests scheduled after quota
+// resets.
+type deferralQueue struct {
+	config  DeferralConfig
+	mutex   sync.Mutex
+	entries map[*scheduledRequest]bool
+}
+
+// newDeferralQueue creates a deferral queue, or returns nil when deferral is
+// disabled.
+func newDeferralQueue(config DeferralConfig) *deferralQueue {
+	if config.MaxWait <= 0 {
+		return nil
+	}
+	if config.Margin <= 0 {
+		config.Margin = defaultDeferralMargin
+	}
+	return &deferralQueue{config: config, entries: make(map[*scheduledRequest]bool)}
+}
+
+// schedule adds a request waiting for provider's reset, after the requests
+// already waiting for the same one. It returns false when the reset is past
+// the maximum wait or the backlog is full.
+func (q *deferralQueue) schedule(req models.ChatRequest, tenant, provider string, reset time.Time) (*scheduledRequest, bool) {
+	now := time.Now()
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.config.MaxQueued > 0 && len(q.entries) >= q.config.MaxQueued {
+		return nil, false
+	}
+	position := 0
+	for entry := range q.entries {
+		if entry.Provider == provider && entry.ResetsAt.Equal(reset) {
+			position++
+		}
+	}
+	scheduledAt := reset.Add(q.config.Margin + time.Duration(position)*q.config.Spread)
+	if scheduledAt.Sub(now) > q.config.MaxWait {
+		return nil, false
+	}
+
+	entry := &scheduledRequest{
+		RequestID:   req.RequestID,
+		Tenant:      tenant,
+		Model:       req.Model,
+		Provider:    provider,
+		ResetsAt:    reset,
+		EnqueuedAt:  now,
+		ScheduledAt: scheduledAt,
+	}
+	q.entries[entry] = true
+	return entry, true
+}
+
+// remove takes a request off the backlog.
+func (q *deferralQueue) remove(entry *scheduledRequest) {
+	q.mutex.Lock()
+	delete(q.entries, entry)
+	q.mutex.Unlock()
+}
+
+// backlog returns the scheduled requests, soonest first.
+func (q *deferralQueue) backlog() []scheduledRequest {
+	if q == nil {
+		return []scheduledRequest{}
+	}
+	q.mutex.Lock()
+	backlog := make([]scheduledRequest, 0, len(q.entries))
+	for entry := range q.entries {
+		backlog = append(backlog, *entry)
+	}
+	q.mutex.Unlock()
+
+	sort.Slice(backlog, func(i, j int) bool {
+		return backlog[i].ScheduledAt.Before(backlog[j].ScheduledAt)
+	})
+	return backlog
+}
+
+// earliestQuotaReset returns the provider serving model whose used-up quota
+// resets first, or false if a provider serving it has quota left or none
+// serves it.
+func earliestQuotaReset(model string, available map[string]providers.Provider) (string, time.Time, bool) {
+	var earliest string
+	var reset time.Time
+	for name, provider := range available {
+		if !provider.IsHealthy() || !servesModel(provider, model) {
+			continue
+		}
+		until, exhausted := providers.QuotaExhaustedUntil(provider)
+		if !exhausted {
+			return "", time.Time{}, false
+		}
+		if earliest == "" || until.Before(reset) {
+			earliest, reset = name, until
+		}
+	}
+	return earliest, reset, earliest != ""
+}
+
+// servesModel reports whether provider lists model among its models.
+func servesModel(provider providers.Provider, model string) bool {
+	served, err := provider.GetModels()
+	if err != nil {
+		return false
+	}
+	for _, m := range served {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
+
+// awaitQuotaReset holds a deferred-priority request while every provider
+// serving its model has used up its quota, until just after the first of
+// them resets. Other requests, and deferred ones the queue cannot take, are
+// routed right away. It returns the context's error if the client leaves
+// while the request waits.
+func (s *Server) awaitQuotaReset(ctx context.Context, req models.ChatRequest, tenant string, available map[string]providers.Provider) error {
+	if s.deferrals == nil || policies.RequestHints(ctx, req).Priority != models.PriorityDeferred {
+		return nil
+	}
+	provider, reset, exhausted := earliestQuotaReset(req.Model, available)
+	if !exhausted {
+		return nil
+	}
+	entry, scheduled := s.deferrals.schedule(req, tenant, provider, reset)
+	if !scheduled {
+		s.logger.Debug("Deferred request not scheduled",
+			zap.String("request_id", req.RequestID),
+			zap.String("provider", provider),
+			zap.Time("resets_at", reset))
+		return nil
+	}
+	defer s.deferrals.remove(entry)
+
+	s.logger.Debug("Deferred request until quota reset",
+		zap.String("request_id", req.RequestID),
+		zap.String("provider", provider),
+		zap.Time("scheduled_at", entry.ScheduledAt))
+	timer := time.NewTimer(time.Until(entry.ScheduledAt))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// providerQuotas is the quota calendar of a provider.
+type providerQuotas struct {
+	Quotas         []providers.QuotaStatus `json:"quotas,omitempty"`
+	ExhaustedUntil *time.Time              `json:"exhausted_until,omitempty"`
+}
+
+// handleGetQuotas returns each provider's quotas and when it has quota again
+// if used up, with the backlog of deferred requests scheduled after resets.
+func (s *Server) handleGetQuotas(w http.ResponseWriter, r *http.Request) {
+	calendars := make(map[string]providerQuotas)
+	for name, provider := range s.providers.Snapshot() {
+		var calendar providerQuotas
+		if reporter, ok := provider.(providers.QuotaReporter); ok {
+			calendar.Quotas = reporter.GetQuotaStatus()
+		}
+		if until, exhausted := providers.QuotaExhaustedUntil(provider); exhausted {
+			calendar.ExhaustedUntil = &until
+		}
+		calendars[name] = calendar
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"providers": calendars,
+		"scheduled": s.deferrals.backlog(),
+	})
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/longform"
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/observability"
+	"github.com/semantrix/semaroute/pkg/api/v1"
+	"go.uber.org/zap"
+)
+
+// handleGenerateDocument generates a document longer than a single response
+// allows by planning sections and generating them one after another. Each
+// section is routed on its own, so sections may come from different providers.
+func (s *Server) handleGenerateDocument(w http.ResponseWriter, r *http.Request) {
+	if !s.config.Longform.Enabled {
+		http.Error(w, "Long-output generation is disabled", http.StatusNotFound)
+		return
+	}
+
+	var apiReq v1.DocumentRequest
+	if err := json.NewDecoder(r.Body).Decode(&apiReq); err != nil {
+		s.logger.Error("Failed to decode request", zap.Error(err))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(apiReq.Messages) == 0 {
+		http.Error(w, "messages are required", http.StatusBadRequest)
+		return
+	}
+
+	req := longform.Request{
+		Chat: models.ChatRequest{
+			Model:     apiReq.Model,
+			Messages:  convertMessages(apiReq.Messages),
+			User:      apiReq.User,
+			RequestID: apiReq.RequestID,
+			CreatedAt: time.Now(),
+		},
+		Sections: apiReq.Sections,
+	}
+
+	if !apiReq.Stream {
+		result, err := s.orchestrator.Generate(r.Context(), req, s.routeAndComplete, nil)
+		if err != nil {
+			s.logger.Error("Document generation failed", zap.Error(err))
+			errorResponse := v1.ErrorResponse{
+				Error: v1.ErrorDetails{
+					Type:       "provider_error",
+					Message:    err.Error(),
+					StatusCode: http.StatusBadGateway,
+				},
+				RequestID: apiReq.RequestID,
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(errorResponse)
+			return
+		}
+
+		setOverheadHeader(w, r)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(convertDocument(result, apiReq.RequestID))
+		return
+	}
+
+	// Stream progress as Server-Sent Events, one event per step
+	setOverheadHeader(w, r)
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", contentTypeSSE)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	writeEvent := func(event v1.DocumentEvent) {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, payload)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	_, err := s.orchestrator.Generate(r.Context(), req, s.routeAndComplete, func(event longform.Event) {
+		apiEvent := v1.DocumentEvent{
+			Type:    event.Type,
+			Outline: event.Outline,
+			Total:   event.Total,
+		}
+		if event.Section != nil {
+			section := convertDocumentSection(*event.Section)
+			apiEvent.Section = &section
+		}
+		if event.Result != nil {
+			apiEvent.Document = convertDocument(event.Result, apiReq.RequestID)
+		}
+		writeEvent(apiEvent)
+	})
+	if err != nil {
+		s.logger.Warn("Document generation failed", zap.Error(err))
+		writeEvent(v1.DocumentEvent{Type: "error", Error: err.Error()})
+	}
+}
+
+// routeAndComplete routes a single chat request and executes it with the chosen provider.
+func (s *Server) routeAndComplete(ctx context.Context, req models.ChatRequest) (*models.ChatResponse, error) {
+	available := s.providers.Snapshot()
+
+	routingStart := time.Now()
+	decision, err := s.routingPolicy.DecideRoute(ctx, req, available)
+	if err != nil {
+		return nil, fmt.Errorf("routing failed: %w", err)
+	}
+	s.metrics.RecordRoutingDecision(s.routingPolicy.GetName(), decision.ProviderName, decision.Model)
+	s.metrics.RecordRoutingLatency(s.routingPolicy.GetName(), time.Since(routingStart))
+
+	provider, exists := available[decision.ProviderName]
+	if !exists {
+		return nil, fmt.Errorf("provider %s not available", decision.ProviderName)
+	}
+	req = routedRequest(req, decision)
+
+	start := time.Now()
+	response, err := provider.CreateChatCompletion(ctx, req)
+	duration := time.Since(start)
+	observability.ProviderTimerFrom(ctx).Add(duration)
+	if err != nil {
+		s.metrics.RecordProviderError(decision.ProviderName, "request_failed")
+		return nil, err
+	}
+
+	s.metrics.RecordProviderLatency(decision.ProviderName, decision.Model, duration)
+	s.metrics.RecordProviderHealth(decision.ProviderName, true)
+
+	response.Provider = decision.ProviderName
+	return response, nil
+}
+
+func convertDocument(result *longform.Result, requestID string) *v1.DocumentResponse {
+	document := &v1.DocumentResponse{
+		Outline:   result.Outline,
+		Sections:  make([]v1.DocumentSection, len(result.Sections)),
+		Content:   result.Content,
+		Usage:     convertUsage(result.Usage),
+		RequestID: requestID,
+	}
+	for i, section := range result.Sections {
+		document.Sections[i] = convertDocumentSection(section)
+	}
+	return document
+}
+
+func convertDocumentSection(section longform.Section) v1.DocumentSection {
+	return v1.DocumentSection{
+		Index:    section.Index,
+		Title:    section.Title,
+		Content:  section.Content,
+		Provider: section.Provider,
+		Model:    section.Model,
+		Usage:    convertUsage(section.Usage),
+	}
+}
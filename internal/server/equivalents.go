@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/internal/router/policies"
+)
+
+// modelEquivalents indexes groups of models that can stand in for each other
+// on providers with different model IDs, e.g. gpt-4o and
+// claude-3-5-sonnet-20240620.
+type modelEquivalents map[string][]string
+
+// newModelEquivalents indexes the configured groups. A model may be in one
+// group only.
+func newModelEquivalents(groups [][]string) (modelEquivalents, error) {
+	index := make(modelEquivalents)
+	for _, group := range groups {
+		if len(group) < 2 {
+			return nil, fmt.Errorf("equivalence group %v needs at least two models", group)
+		}
+		for _, model := range group {
+			if _, exists := index[model]; exists {
+				return nil, fmt.Errorf("model %s is in more than one equivalence group", model)
+			}
+			index[model] = group
+		}
+	}
+	return index, nil
+}
+
+// of returns the models equivalent to model, in the order of its group.
+func (e modelEquivalents) of(model string) []string {
+	var equivalents []string
+	for _, m := range e[model] {
+		if m != model {
+			equivalents = append(equivalents, m)
+		}
+	}
+	return equivalents
+}
+
+// substitutable reports whether a failed routing decision may be retried
+// with an equivalent model. Requests denied the model, rather than finding no
+// provider for it, are not.
+func substitutable(err error) bool {
+	var accessErr *modelAccessError
+	var scheduleErr *policies.ScheduleError
+	return !errors.As(err, &accessErr) && !errors.As(err, &scheduleErr)
+}
+
+// decideWithEquivalents asks the routing policy to route a request among the
+// candidates and, when no candidate can serve its model, routes it to the
+// first equivalent model one can serve instead.
+func (s *Server) decideWithEquivalents(ctx context.Context, req models.ChatRequest, candidates map[string]providers.Provider) (policies.RoutingDecision, error) {
+	decision, err := s.routingPolicy.DecideRoute(ctx, req, candidates)
+	if err == nil || !substitutable(err) {
+		return decision, err
+	}
+
+	for _, model := range s.equivalents.of(req.Model) {
+		equivalent := req
+		equivalent.Model = model
+		fitting, fitErr := s.excludeSmallContexts(equivalent, candidates)
+		if fitErr != nil {
+			continue
+		}
+		next, nextErr := s.routingPolicy.DecideRoute(ctx, equivalent, fitting)
+		if nextErr != nil {
+			continue
+		}
+		if next.Model == "" {
+			next.Model = model
+		}
+		next.Reason = fmt.Sprintf("Equivalent model %s for %s (%v): %s", model, req.Model, err, next.Reason)
+		return next, nil
+	}
+	return decision, err
+}
+
+// equivalentHops returns fallback hops to the healthy providers other than
+// the failed one that serve a model equivalent to the failed model, in the
+// order of its group.
+func (s *Server) equivalentHops(failed policies.RoutingDecision, available map[string]providers.Provider) []FallbackHop {
+	var hops []FallbackHop
+	for _, model := range s.equivalents.of(failed.Model) {
+		for _, name := range sortedProviderNames(available) {
+			provider := available[name]
+			if name != failed.ProviderName && provider.IsHealthy() && servesModel(provider, model) {
+				hops = append(hops, FallbackHop{Provider: name, Model: model})
+			}
+		}
+	}
+	return hops
+}
+
+// sortedProviderNames returns the names of the providers in order.
+func sortedProviderNames(available map[string]providers.Provider) []string {
+	names := make([]string, 0, len(available))
+	for name := range available {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"go.uber.org/zap"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/internal/router/policies"
+	v1 "github.com/semantrix/semaroute/pkg/api/v1"
+)
+
+// handleExplainRoute makes the routing decision for a chat completion request
+// as a dry run, without executing it, and returns it with every provider's
+// rank, score, estimates and the reason it was excluded. A failed decision is
+// reported in the body rather than the status, as the candidates are what
+// explain it.
+func (s *Server) handleExplainRoute(w http.ResponseWriter, r *http.Request) {
+	var apiReq v1.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&apiReq); err != nil {
+		s.logger.Error("Failed to decode request", zap.Error(err))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req := convertChatRequest(apiReq)
+	req, _ = s.applyGenerationDefaults(req, tenantFrom(r).ID)
+
+	info := policies.RequestInfoFrom(r.Context())
+	info.DryRun = true
+	ctx := policies.WithRequestInfo(r.Context(), info)
+	available := s.providers.Snapshot()
+
+	decision, alias, err := s.decideRoute(ctx, req, available)
+	response := v1.RoutingExplanation{
+		RequestID:     req.RequestID,
+		RoutingPolicy: s.routingPolicy.GetName(),
+		Model:         req.Model,
+		Alias:         alias,
+	}
+	if err != nil {
+		details := routingErrorDetails(err)
+		response.Error = &details
+	} else {
+		response.Decision = &v1.RoutingDecision{
+			ProviderName:     decision.ProviderName,
+			Model:            decision.Model,
+			Reason:           decision.Reason,
+			EstimatedCost:    decision.EstimatedCost,
+			EstimatedLatency: decision.EstimatedLatency,
+			Confidence:       decision.Confidence,
+			Fallback:         decision.Fallback,
+			Spillover:        decision.Spillover,
+			Category:         decision.Category,
+		}
+	}
+
+	// An alias is explained for the target the decision went to, or its
+	// first target when no target could be routed
+	candidates := available
+	if _, targets := s.aliasTargets(req.Model); targets != nil {
+		target := explainedTarget(targets, decision, err)
+		req.Model = target.Model
+		if target.Provider != "" {
+			candidates = pinnedCandidates(available, target.Provider)
+		}
+	}
+	response.Model = req.Model
+
+	for _, explanation := range s.explainRoute(ctx, req, available, candidates) {
+		response.Candidates = append(response.Candidates, v1.RoutingCandidate{
+			Provider:         explanation.Provider,
+			Rank:             explanation.Rank,
+			Score:            explanation.Score,
+			EstimatedCost:    explanation.EstimatedCost,
+			EstimatedLatency: explanation.EstimatedLatency,
+			ErrorRate:        explanation.ErrorRate,
+			Excluded:         explanation.Excluded,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// explainRoute explains how the routing policy weighs the candidates for a
+// request, after the providers outside candidates and those whose context
+// window cannot hold the request are excluded.
+func (s *Server) explainRoute(ctx context.Context, req models.ChatRequest, available, candidates map[string]providers.Provider) []policies.CandidateExplanation {
+	var excluded []policies.CandidateExplanation
+	fitting := make(map[string]providers.Provider, len(candidates))
+	for name := range available {
+		provider, ok := candidates[name]
+		if !ok {
+			excluded = append(excluded, policies.CandidateExplanation{Provider: name, Excluded: "not the alias target"})
+			continue
+		}
+		if checker, ok := provider.(providers.ContextChecker); ok {
+			if err := checker.CheckContextWindow(req); err != nil {
+				excluded = append(excluded, policies.CandidateExplanation{Provider: name, Excluded: fmt.Sprintf("context window: %v", err)})
+				continue
+			}
+		}
+		fitting[name] = provider
+	}
+	sort.Slice(excluded, func(i, j int) bool {
+		return excluded[i].Provider < excluded[j].Provider
+	})
+
+	if len(fitting) == 0 {
+		return excluded
+	}
+	return append(policies.Explain(ctx, s.routingPolicy, req, fitting), excluded...)
+}
+
+// explainedTarget returns the alias target a decision went to, or the first
+// target when routing failed.
+func explainedTarget(targets []AliasTarget, decision policies.RoutingDecision, err error) AliasTarget {
+	if err == nil {
+		for _, target := range targets {
+			if target.Model == decision.Model && (target.Provider == "" || target.Provider == decision.ProviderName) {
+				return target
+			}
+		}
+	}
+	return targets[0]
+}
+
+// pinnedCandidates narrows the available providers to the one an alias
+// target is pinned to.
+func pinnedCandidates(available map[string]providers.Provider, name string) map[string]providers.Provider {
+	provider, ok := available[name]
+	if !ok {
+		return nil
+	}
+	return map[string]providers.Provider{name: provider}
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/observability"
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/internal/router/policies"
+	v1 "github.com/semantrix/semaroute/pkg/api/v1"
+)
+
+// FallbackHop is one step of a fallback chain: a model to try, optionally
+// pinned to a provider, with its own retry policy.
+type FallbackHop struct {
+	Provider   string        `mapstructure:"provider"` // empty lets the routing policy choose
+	Model      string        `mapstructure:"model"`
+	Retries    int           `mapstructure:"retries"`     // attempts after the first
+	RetryDelay time.Duration `mapstructure:"retry_delay"` // between attempts
+}
+
+// target returns the model the hop routes to.
+func (h FallbackHop) target() AliasTarget {
+	return AliasTarget{Provider: h.Provider, Model: h.Model}
+}
+
+// validateFallbackChains checks that every hop names a model and a configured
+// provider.
+func validateFallbackChains(chains map[string][]FallbackHop, configured map[string]providers.Provider) error {
+	for model, hops := range chains {
+		for _, hop := range hops {
+			if hop.Model == "" {
+				return fmt.Errorf("fallback chain of %s: hop without a model", model)
+			}
+			if hop.Provider != "" {
+				if _, ok := configured[hop.Provider]; !ok {
+					return fmt.Errorf("fallback chain of %s: unknown provider %s", model, hop.Provider)
+				}
+			}
+			if hop.Retries < 0 {
+				return fmt.Errorf("fallback chain of %s: negative retries", model)
+			}
+		}
+	}
+	return nil
+}
+
+// fallbackHops returns the fallback chain of a request: the chain configured
+// for the alias it named, else for the routed model. Without one, a decision
+// that allows fallback falls back to the other healthy providers serving the
+// same model, then to those serving an equivalent model.
+func (s *Server) fallbackHops(alias string, decision policies.RoutingDecision, available map[string]providers.Provider) []FallbackHop {
+	for _, name := range []string{alias, decision.Model} {
+		if hops, ok := s.config.FallbackChains[strings.ToLower(name)]; ok && name != "" {
+			return hops
+		}
+	}
+	if !decision.Fallback {
+		return nil
+	}
+
+	var hops []FallbackHop
+	for name, provider := range available {
+		if name == decision.ProviderName || !provider.IsHealthy() {
+			continue
+		}
+		served, err := provider.GetModels()
+		if err != nil {
+			continue
+		}
+		for _, m := range served {
+			if m == decision.Model {
+				hops = append(hops, FallbackHop{Provider: name, Model: decision.Model})
+				break
+			}
+		}
+	}
+	sort.Slice(hops, func(i, j int) bool { return hops[i].Provider < hops[j].Provider })
+	return append(hops, s.equivalentHops(decision, available)...)
+}
+
+// runFallbackChain tries the hops in order after the routed provider failed,
+// retrying each as configured. It returns the response, the request and
+// decision of the hop that served it, and which fallback was used.
+func (s *Server) runFallbackChain(ctx context.Context, req models.ChatRequest, failed policies.RoutingDecision, hops []FallbackHop, available map[string]providers.Provider) (*models.ChatResponse, models.ChatRequest, policies.RoutingDecision, *v1.FallbackInfo, error) {
+	lastErr := fmt.Errorf("no fallback configured")
+	ladder := attemptLadderFrom(ctx)
+	for i, hop := range hops {
+		decision, err := s.routeTarget(ctx, req, available, hop.target())
+		if err != nil {
+			lastErr = err
+			ladder.record(v1.ProviderAttempt{
+				Provider:   hop.Provider,
+				Model:      hop.Model,
+				Hop:        i + 1,
+				ErrorClass: "unavailable",
+			}, err, 0)
+			s.logger.Warn("Fallback hop unavailable",
+				zap.Int("hop", i+1),
+				zap.String("target", hop.target().String()),
+				zap.Error(err))
+			continue
+		}
+		provider := available[decision.ProviderName]
+		hopReq := routedRequest(req, decision)
+
+		for attempt := 0; attempt <= hop.Retries; attempt++ {
+			if attempt > 0 {
+				select {
+				case <-time.After(hop.RetryDelay):
+				case <-ctx.Done():
+					return nil, req, failed, nil, ctx.Err()
+				}
+			}
+
+			start := time.Now()
+			var response *models.ChatResponse
+			response, err = provider.CreateChatCompletion(ctx, hopReq)
+			duration := time.Since(start)
+			observability.ProviderTimerFrom(ctx).Add(duration)
+			s.routingPolicy.UpdateMetrics(decision, err == nil, duration)
+			ladder.record(v1.ProviderAttempt{Provider: decision.ProviderName, Model: decision.Model, Hop: i + 1}, err, duration)
+			if err == nil {
+				s.metrics.RecordFallback(failed.ProviderName, decision.ProviderName)
+				s.metrics.RecordProviderLatency(decision.ProviderName, decision.Model, duration)
+				decision.Reason = fmt.Sprintf("Fallback hop %d after %s/%s failed: %s",
+					i+1, failed.ProviderName, failed.Model, decision.Reason)
+				return response, hopReq, decision, &v1.FallbackInfo{
+					Hop:      i + 1,
+					From:     failed.ProviderName + "/" + failed.Model,
+					Attempts: attempt + 1,
+				}, nil
+			}
+
+			lastErr = err
+			s.metrics.RecordProviderError(decision.ProviderName, "request_failed")
+			s.logger.Warn("Fallback attempt failed",
+				zap.Int("hop", i+1),
+				zap.Int("attempt", attempt+1),
+				zap.String("provider", decision.ProviderName),
+				zap.String("model", decision.Model),
+				zap.Error(err))
+		}
+	}
+	return nil, req, failed, nil, lastErr
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/semantrix/semaroute/internal/catalog"
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/internal/router/policies"
+)
+
+// modelAccessError is returned when the catalog restricts the requested
+// model, on every candidate provider, to tenants other than the caller's, or
+// the access lists of the caller's tenant or API key deny it.
+type modelAccessError struct {
+	model  string
+	tenant string
+	reason string // why, when not restricted by the catalog
+}
+
+func (e *modelAccessError) Error() string {
+	message := fmt.Sprintf("tenant %s may not use model %s", e.tenant, e.model)
+	if e.tenant == "" {
+		message = fmt.Sprintf("model %s is restricted to specific tenants", e.model)
+	}
+	if e.reason != "" {
+		message += ": " + e.reason
+	}
+	return message
+}
+
+// newModelAccessFilter returns policy middleware that leaves out providers
+// whose catalog entry for the requested model does not allow the request's
+// tenant, so restricted fine-tunes are only routed for their tenants.
+func newModelAccessFilter(modelCatalog *catalog.Catalog) policies.Middleware {
+	return policies.MiddlewareFuncs{
+		Label: "model_access",
+		Before: func(ctx context.Context, req models.ChatRequest, candidates map[string]providers.Provider) (map[string]providers.Provider, error) {
+			tenant := policies.RequestInfoFrom(ctx).Tenant
+			allowed := make(map[string]providers.Provider, len(candidates))
+			for name, provider := range candidates {
+				if entry, found := modelCatalog.Lookup(name, req.Model); found && !entry.AllowsTenant(tenant) {
+					continue
+				}
+				allowed[name] = provider
+			}
+			if len(allowed) == 0 && len(candidates) > 0 {
+				return nil, &modelAccessError{model: req.Model, tenant: tenant}
+			}
+			return allowed, nil
+		},
+	}
+}
+
+// blendAliasTargets orders the targets of an alias for a request. When any
+// target has a weight, one of the weighted targets is drawn in proportion to
+// its weight and tried first; the others follow in configured order as
+// fallbacks. Targets of unweighted aliases keep their order.
+func blendAliasTargets(ctx context.Context, req models.ChatRequest, targets []AliasTarget) []AliasTarget {
+	total := 0.0
+	for _, target := range targets {
+		total += target.Weight
+	}
+	if total <= 0 {
+		return targets
+	}
+
+	point := policies.Draw(ctx, req) * total
+	chosen := -1
+	for i, target := range targets {
+		if target.Weight <= 0 {
+			continue
+		}
+		chosen = i
+		point -= target.Weight
+		if point < 0 {
+			break
+		}
+	}
+
+	ordered := make([]AliasTarget, 0, len(targets))
+	ordered = append(ordered, targets[chosen])
+	ordered = append(ordered, targets[:chosen]...)
+	return append(ordered, targets[chosen+1:]...)
+}
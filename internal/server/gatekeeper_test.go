@@ -0,0 +1,214 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/semantrix/semaroute/internal/catalog"
+	"github.com/semantrix/semaroute/internal/gatekeeper"
+	"github.com/semantrix/semaroute/internal/observability"
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/internal/tenants"
+	"github.com/semantrix/semaroute/internal/usage"
+	"github.com/semantrix/semaroute/pkg/api/v1"
+)
+
+// newGatekeeperServer returns a server with just what voucher redemption
+// needs, redeeming vouchers in the given mode.
+func newGatekeeperServer(t *testing.T, redemption string) *Server {
+	t.Helper()
+	config := &Config{}
+	config.Gatekeeper = gatekeeper.Config{
+		Enabled:    true,
+		SigningKey: "0123456789abcdef0123456789abcdef",
+		Redemption: redemption,
+		PublicURL:  "https://semaroute.internal",
+	}
+	signer, err := gatekeeper.NewSigner(config.Gatekeeper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := providers.NewOpenAIProvider(providers.ProviderConfig{Name: "openai", APIKey: "sk-provider", BaseURL: "https://api.openai.com/v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Server{
+		config:        config,
+		providers:     providers.NewProviderSet(map[string]providers.Provider{"openai": provider}),
+		tokenSigner:   signer,
+		voucherLedger: gatekeeper.NewLedger(),
+		logger:        zap.NewNop(),
+	}
+}
+
+// redeem posts a voucher to the redeem handler as the given tenant.
+func redeem(s *Server, token string, tenant requestTenant) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(v1.VoucherRedeemRequest{Token: token})
+	r := httptest.NewRequest(http.MethodPost, "/v1/vouchers/redeem", bytes.NewReader(body))
+	r = r.WithContext(context.WithValue(r.Context(), tenantContextKey{}, tenant))
+	w := httptest.NewRecorder()
+	s.handleRedeemVoucher(w, r)
+	return w
+}
+
+func TestRedeemVoucher(t *testing.T) {
+	keyHolder := requestTenant{ID: "acme", Authenticated: true, Scopes: tenants.Scopes{tenants.ScopeChatWrite, tenants.ScopeVoucherKey}}
+	chatOnly := requestTenant{ID: "acme", Authenticated: true, Scopes: tenants.Scopes{tenants.ScopeChatWrite}}
+
+	tests := []struct {
+		name       string
+		redemption string
+		issuedTo   string
+		redeemer   requestTenant
+		wantStatus int
+		wantKey    bool
+	}{
+		{"proxy for the issuing tenant", gatekeeper.RedeemProxy, "acme", chatOnly, http.StatusOK, false},
+		{"proxy for another tenant", gatekeeper.RedeemProxy, "globex", chatOnly, http.StatusForbidden, false},
+		{"key with the scope", gatekeeper.RedeemKey, "acme", keyHolder, http.StatusOK, true},
+		{"key without the scope", gatekeeper.RedeemKey, "acme", chatOnly, http.StatusForbidden, false},
+		{"key without an API key", gatekeeper.RedeemKey, "acme", requestTenant{ID: "acme"}, http.StatusForbidden, false},
+		{"key for another tenant", gatekeeper.RedeemKey, "globex", keyHolder, http.StatusForbidden, false},
+		{"key for anonymous voucher", gatekeeper.RedeemKey, "", requestTenant{}, http.StatusForbidden, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newGatekeeperServer(t, test.redemption)
+			token, _, err := s.tokenSigner.Issue(gatekeeper.Claims{Provider: "openai", Model: "gpt-4o", Tenant: test.issuedTo})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := redeem(s, token, test.redeemer)
+			if w.Code != test.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, test.wantStatus, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var redemption v1.VoucherRedemption
+			if err := json.NewDecoder(w.Body).Decode(&redemption); err != nil {
+				t.Fatal(err)
+			}
+			if gotKey := redemption.APIKey != ""; gotKey != test.wantKey {
+				t.Fatalf("api_key = %q, want key returned: %v", redemption.APIKey, test.wantKey)
+			}
+			if test.wantKey && redemption.APIKey != "sk-provider" {
+				t.Fatalf("api_key = %q, want the provider key", redemption.APIKey)
+			}
+			if !test.wantKey && redemption.ProxyURL != "https://semaroute.internal/v1/vouchers/proxy/chat/completions" {
+				t.Fatalf("proxy_url = %q", redemption.ProxyURL)
+			}
+		})
+	}
+}
+
+func TestRedeemVoucherRejectsReuseAndForgery(t *testing.T) {
+	s := newGatekeeperServer(t, gatekeeper.RedeemKey)
+	tenant := requestTenant{ID: "acme", Authenticated: true, Scopes: tenants.Scopes{tenants.ScopeVoucherKey}}
+	token, _, err := s.tokenSigner.Issue(gatekeeper.Claims{Provider: "openai", Model: "gpt-4o", Tenant: "acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w := redeem(s, token, tenant); w.Code != http.StatusOK {
+		t.Fatalf("first redemption status = %d", w.Code)
+	}
+	if w := redeem(s, token, tenant); w.Code != http.StatusConflict {
+		t.Fatalf("second redemption status = %d, want 409", w.Code)
+	}
+
+	forger, err := gatekeeper.NewSigner(gatekeeper.Config{Enabled: true, SigningKey: "ffffffffffffffffffffffffffffffff"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, _, err := forger.Issue(gatekeeper.Claims{Provider: "openai", Model: "gpt-4o", Tenant: "acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := redeem(s, forged, tenant); w.Code != http.StatusUnauthorized {
+		t.Fatalf("forged voucher status = %d, want 401", w.Code)
+	}
+}
+
+func TestVoucherProxyChargesTheVoucherTenant(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "chatcmpl-1", "model": "gpt-4o", "choices": [{"index": 0, "message": {"role": "assistant", "content": "Hi"}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 1000, "completion_tokens": 500, "total_tokens": 1500}}`))
+	}))
+	defer upstream.Close()
+
+	s := newGatekeeperServer(t, gatekeeper.RedeemProxy)
+	provider, err := providers.NewOpenAIProvider(providers.ProviderConfig{Name: "openai", APIKey: "sk-provider", BaseURL: upstream.URL, RetryDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.providers = providers.NewProviderSet(map[string]providers.Provider{"openai": provider})
+	s.metrics = testMetrics(t)
+	s.modelCatalog, err = catalog.NewCatalog(catalog.Config{Models: []catalog.ModelEntry{
+		{Provider: "openai", Model: "gpt-4o", InputPer1K: 0.005, OutputPer1K: 0.015},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.usageStore, err = usage.NewStore(usage.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, _, err := s.tokenSigner.Issue(gatekeeper.Claims{Provider: "openai", Model: "gpt-4o", Tenant: "acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/v1/vouchers/proxy/chat/completions",
+		strings.NewReader(`{"messages": [{"role": "user", "content": "Hello"}]}`))
+	r.Header.Set("Authorization", "Bearer "+token)
+	r.Header.Set(tenantHeader, "globex")
+	w := httptest.NewRecorder()
+	s.handleVoucherProxy(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	records := s.usageStore.Records(time.Time{})
+	if len(records) != 1 {
+		t.Fatalf("%d usage records, want 1", len(records))
+	}
+	record := records[0]
+	if record.Tenant != "acme" || record.Provider != "openai" || record.Model != "gpt-4o" {
+		t.Fatalf("record = %+v, want it charged to the voucher's tenant", record)
+	}
+	if record.PromptTokens != 1000 || record.CompletionTokens != 500 || math.Abs(record.Cost-0.0125) > 1e-9 {
+		t.Fatalf("record tokens and cost = %d, %d, %v, want 1000, 500, 0.0125", record.PromptTokens, record.CompletionTokens, record.Cost)
+	}
+}
+
+var (
+	sharedMetrics     *observability.Metrics
+	sharedMetricsErr  error
+	sharedMetricsOnce sync.Once
+)
+
+// testMetrics returns metrics shared by the package's tests, as the exporter
+// registers itself with the default Prometheus registry only once.
+func testMetrics(t *testing.T) *observability.Metrics {
+	t.Helper()
+	sharedMetricsOnce.Do(func() {
+		sharedMetrics, sharedMetricsErr = observability.NewMetrics(observability.MetricsConfig{}, zap.NewNop())
+	})
+	if sharedMetricsErr != nil {
+		t.Fatal(sharedMetricsErr)
+	}
+	return sharedMetrics
+}
@@ -1,13 +1,25 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/semantrix/semaroute/internal/gatekeeper"
+	"github.com/semantrix/semaroute/internal/invalidation"
 	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/observability"
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/internal/router/policies"
+	"github.com/semantrix/semaroute/internal/shadow"
+	"github.com/semantrix/semaroute/internal/tenants"
+	"github.com/semantrix/semaroute/internal/tokenizer"
+	"github.com/semantrix/semaroute/internal/usage"
 	"github.com/semantrix/semaroute/pkg/api/v1"
 	"go.uber.org/zap"
 )
@@ -48,7 +60,7 @@ func (s *Server) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
 
 // handleChatCompletion handles chat completion requests.
 func (s *Server) handleChatCompletion(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, ladder := withAttemptLadder(r.Context())
 	
 	// Parse request
 	var apiReq v1.ChatCompletionRequest
@@ -58,155 +70,1105 @@ func (s *Server) handleChatCompletion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Convert to internal model
-	req := models.ChatRequest{
-		Model:            apiReq.Model,
-		Messages:         convertMessages(apiReq.Messages),
-		Stream:           apiReq.Stream,
-		MaxTokens:        apiReq.MaxTokens,
-		Temperature:      apiReq.Temperature,
-		TopP:             apiReq.TopP,
-		TopK:             apiReq.TopK,
-		Stop:             apiReq.Stop,
-		PresencePenalty:  apiReq.PresencePenalty,
-		FrequencyPenalty: apiReq.FrequencyPenalty,
-		User:             apiReq.User,
-		RequestID:        apiReq.RequestID,
-		CreatedAt:        time.Now(),
+	// Convert to internal model
+	req := convertChatRequest(apiReq)
+
+	// Fill in omitted parameters from the tenant's and the global defaults
+	req, parameters := s.applyGenerationDefaults(req, tenantFrom(r).ID)
+	setDefaultsHeader(w, parameters)
+
+	// Serve repeated requests from the response cache
+	cacheKey, cacheable := "", false
+	if s.config.Cache.Responses {
+		cacheKey, cacheable = s.cacheKeys.Key(req, tenantFrom(r).ID)
+	}
+	if cacheable {
+		if apiResponse, hit := s.cachedResponse(ctx, cacheKey); hit {
+			s.metrics.RecordCacheHit("response")
+			s.recordUsage(usage.Record{
+				Tenant:   tenantFrom(r).ID,
+				Provider: apiResponse.Provider,
+				Model:    apiResponse.Model,
+				CacheKey: cacheKey,
+				Hit:      true,
+			})
+			apiResponse.RequestID = req.RequestID
+
+			setOverheadHeader(w, r)
+			w.Header().Set(cacheHeader, "hit")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(apiResponse)
+			return
+		}
+		s.metrics.RecordCacheMiss("response")
+	}
+
+	// Route and execute against one snapshot of the provider set
+	available := s.providers.Snapshot()
+
+	// Deferred requests wait for a used-up quota to reset
+	if err := s.awaitQuotaReset(ctx, req, tenantFrom(r).ID, available); err != nil {
+		return
+	}
+
+	// Make routing decision
+	routingStart := time.Now()
+	decision, alias, err := s.decideRoute(ctx, req, available)
+	if err != nil {
+		s.logger.Error("Routing decision failed", zap.Error(err))
+		writeRoutingError(w, req.RequestID, err)
+		return
+	}
+	routingDuration := time.Since(routingStart)
+
+	// Record routing metrics
+	s.metrics.RecordRoutingDecision(s.routingPolicy.GetName(), decision.ProviderName, decision.Model)
+	if decision.Category != "" {
+		s.metrics.RecordContentCategory(decision.Category, decision.ProviderName, decision.Model)
+	}
+	s.metrics.RecordRoutingLatency(s.routingPolicy.GetName(), routingDuration)
+
+	// Get the selected provider
+	provider, exists := available[decision.ProviderName]
+	if !exists {
+		s.logger.Error("Selected provider not found", zap.String("provider", decision.ProviderName))
+		http.Error(w, "Provider not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	req = routedRequest(req, decision)
+
+	// Streaming requests are written chunk by chunk
+	if req.Stream {
+		s.handleChatCompletionStream(w, r, req, decision.ProviderName, decision.Model, provider, available)
+		return
+	}
+
+	// Execute the request, hedged to a second provider when the first is slow
+	start := time.Now()
+	var fallback *v1.FallbackInfo
+	var hedge *v1.HedgeInfo
+	var response *models.ChatResponse
+	hedgeCost := 0.0
+	if s.hedgeable(ctx, req.Model) {
+		response, req, decision, hedge, hedgeCost, err = s.runHedged(ctx, req, decision, available)
+	} else {
+		response, err = provider.CreateChatCompletion(ctx, req)
+		ladder.record(v1.ProviderAttempt{Provider: decision.ProviderName, Model: decision.Model}, err, time.Since(start))
+	}
+	duration := time.Since(start)
+	observability.ProviderTimerFrom(ctx).Add(duration)
+	// Hedged attempts record their own metrics
+	if hedge == nil {
+		s.routingPolicy.UpdateMetrics(decision, err == nil, duration)
+	}
+
+	if err != nil {
+		// Handle provider errors
+		s.logger.Error("Provider request failed", 
+			zap.String("provider", decision.ProviderName),
+			zap.Error(err))
+		
+		// Record error metrics
+		if hedge == nil {
+			s.metrics.RecordProviderError(decision.ProviderName, "request_failed")
+		}
+		
+		// Spill a rate-limited request over to the next candidate
+		if hedge == nil && isRateLimited(err) {
+			response, req, decision, err = s.runSpillover(ctx, req, decision, available, err)
+		}
+
+		// Walk the model's fallback chain
+		if hops := s.fallbackHops(alias, decision, available); err != nil && len(hops) > 0 {
+			response, req, decision, fallback, err = s.runFallbackChain(ctx, req, decision, hops, available)
+		}
+
+		if err != nil {
+			// A request the client left or whose deadline passed still
+			// consumed its prompt
+			if ctx.Err() != nil {
+				s.recordAborted(tenantFrom(r).ID, decision.ProviderName, req, nil, abortReason(ctx))
+			}
+
+			// All providers failed; the attempts made are reported for triage
+			errorResponse := v1.ErrorResponse{
+				Error: v1.ErrorDetails{
+					Type:        "provider_error",
+					Message:     "All providers failed",
+					StatusCode:  http.StatusServiceUnavailable,
+					Retryable:   true,
+					Details:     ladder.details(),
+				},
+				RequestID: req.RequestID,
+			}
+			
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(errorResponse)
+			return
+		}
+	}
+
+	// Record success metrics; fallback hops, spillovers and hedged attempts
+	// record their own
+	if fallback == nil && hedge == nil && decision.Spillover == "" {
+		s.metrics.RecordProviderLatency(decision.ProviderName, decision.Model, duration)
+	}
+	s.metrics.RecordProviderHealth(decision.ProviderName, true)
+
+	// Continue truncated output with the provider that produced it
+	continueWith := available[decision.ProviderName]
+	completeWith := func(ctx context.Context, req models.ChatRequest) (*models.ChatResponse, error) {
+		continueStart := time.Now()
+		defer func() { observability.ProviderTimerFrom(ctx).Add(time.Since(continueStart)) }()
+		return continueWith.CreateChatCompletion(ctx, req)
+	}
+	response = s.continuer.Continue(ctx, decision.ProviderName, req, response, completeWith)
+
+	// Run the tool calls the server executes and continue the conversation
+	response, err = s.runServerTools(ctx, tenantFrom(r).ID, req, response, completeWith)
+	if err != nil {
+		s.logger.Error("Follow-up after server-side tool calls failed",
+			zap.String("provider", decision.ProviderName),
+			zap.Error(err))
+		s.metrics.RecordProviderError(decision.ProviderName, "request_failed")
+
+		errorResponse := v1.ErrorResponse{
+			Error: v1.ErrorDetails{
+				Type:       "provider_error",
+				Message:    err.Error(),
+				StatusCode: http.StatusBadGateway,
+				Provider:   decision.ProviderName,
+			},
+			RequestID: req.RequestID,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(errorResponse)
+		return
+	}
+
+	// Retry answers in another language than the tenant or request requires
+	response, decision = s.enforceLanguage(ctx, req, tenantFrom(r).ID, response, decision, available)
+
+	// Convert response to API format
+	apiResponse := v1.ChatCompletionResponse{
+		ID:        response.ID,
+		Model:     response.Model,
+		Choices:   convertChoices(response.Choices),
+		Usage:     convertUsage(response.Usage),
+		Created:   response.Created,
+		Provider:  decision.ProviderName,
+		Alias:     alias,
+		Fallback:  fallback,
+		Hedge:     hedge,
+		RequestID: response.RequestID,
+	}
+	if apiResponse.Model == "" {
+		apiResponse.Model = req.Model
+	}
+
+	record := usage.Record{
+		Tenant:           tenantFrom(r).ID,
+		Provider:         decision.ProviderName,
+		Model:            response.Model,
+		PromptTokens:     response.Usage.PromptTokens,
+		CompletionTokens: response.Usage.CompletionTokens,
+		HedgeCost:        hedgeCost,
+	}
+	if cost, found := s.modelCatalog.EstimateCost(decision.ProviderName, response.Model,
+		response.Usage.PromptTokens, response.Usage.CompletionTokens); found {
+		record.Cost = cost
+	}
+	s.captureLogprobs(&record, req, response)
+
+	// Mirror a share of traffic to shadow providers under evaluation; fallback
+	// and hedged responses are skipped as their latency is not comparable
+	if fallback == nil && hedge == nil {
+		s.mirrorShadow(ctx, req, shadow.Sample{
+			Provider: decision.ProviderName,
+			Model:    req.Model,
+			Response: response,
+			Latency:  duration,
+			Cost:     record.Cost,
+		})
+	}
+	s.sampleDataset(r, req, response, decision.ProviderName, duration)
+
+	// Cached as JSON so the cache can measure and compress it
+	if cacheable {
+		if data, err := json.Marshal(apiResponse); err == nil {
+			s.cache.Set(ctx, cacheKey, data, 0)
+			record.CacheKey = cacheKey
+			record.Response = data
+		}
+		w.Header().Set(cacheHeader, "miss")
+	}
+	s.recordUsage(record)
+
+	setOverheadHeader(w, r)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(apiResponse)
+}
+
+// handleChatCompletionStream streams a chat completion using the encoding negotiated from the Accept header.
+// A slow stream may be hedged to one of the available providers; nil disables hedging.
+func (s *Server) handleChatCompletionStream(w http.ResponseWriter, r *http.Request, req models.ChatRequest, providerName, model string, provider providers.Provider, available map[string]providers.Provider) {
+	release, ok := s.admitStream(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	start := time.Now()
+
+	// The stall watchdog aborts the provider stream through streamCtx
+	streamCtx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	timer := observability.ProviderTimerFrom(r.Context())
+	stream, err := providers.OpenStream(streamCtx, provider, req)
+	timer.Add(time.Since(start))
+
+	// A rate-limited stream spills over to the next candidate
+	if err != nil && available != nil && isRateLimited(err) {
+		if name, spilled, spillReq, spillStream, spillErr := s.spillStream(streamCtx, req, providerName, available, err); spillErr == nil {
+			s.metrics.RecordProviderError(providerName, "stream_failed")
+			providerName, provider, req, model, stream, err = name, spilled, spillReq, spillReq.Model, spillStream, nil
+		} else {
+			err = spillErr
+		}
+	}
+	if err != nil {
+		s.logger.Error("Provider stream request failed",
+			zap.String("provider", providerName),
+			zap.Error(err))
+		s.metrics.RecordProviderError(providerName, "stream_failed")
+
+		errorResponse := v1.ErrorResponse{
+			Error: v1.ErrorDetails{
+				Type:       "provider_error",
+				Message:    err.Error(),
+				StatusCode: http.StatusBadGateway,
+				Provider:   providerName,
+				Retryable:  true,
+			},
+			RequestID: req.RequestID,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(errorResponse)
+		return
+	}
+
+	// A stream without a first token within the hedging delay races a
+	// second provider, and the loser is cancelled
+	if available != nil && s.hedgeable(r.Context(), req.Model) {
+		hedgeStart := time.Now()
+		hedged := s.hedgeStream(r, hedgedStream{
+			chunks:   stream,
+			ctx:      streamCtx,
+			cancel:   cancel,
+			provider: provider,
+			name:     providerName,
+			req:      req,
+		}, available)
+		timer.Add(time.Since(hedgeStart))
+		stream, streamCtx, cancel = hedged.chunks, hedged.ctx, hedged.cancel
+		provider, providerName, req, model = hedged.provider, hedged.name, hedged.req, hedged.req.Model
+		defer cancel()
+	}
+
+	// Truncated streams are extended in place by continuation streams; the
+	// provider streams, as its stream opened
+	stream = s.continuer.ContinueStream(streamCtx, providerName, req, stream, provider.(providers.StreamingProvider).CreateChatCompletionStream)
+
+	// Streams that stop sending chunks are ended by the stall watchdog
+	var watchdog *stallWatchdog
+	writeReq := r
+	if s.config.Streaming.StallTimeout > 0 {
+		superviseStart := time.Now()
+		supervised := s.superviseStream(streamCtx, cancel, r, req, providerName, stream)
+		timer.Add(time.Since(superviseStart))
+		if supervised.chunks == nil {
+			errorResponse := v1.ErrorResponse{
+				Error: v1.ErrorDetails{
+					Type:       "provider_error",
+					Message:    fmt.Sprintf("Provider stream stalled: no data for %s", s.config.Streaming.StallTimeout),
+					StatusCode: http.StatusGatewayTimeout,
+					Provider:   supervised.provider,
+					Retryable:  true,
+				},
+				RequestID: req.RequestID,
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusGatewayTimeout)
+			json.NewEncoder(w).Encode(errorResponse)
+			return
+		}
+		stream, watchdog, providerName = supervised.chunks, supervised.watchdog, supervised.provider
+		// A stall cancels the stream's context, ending the write without
+		// the end-of-stream marker
+		writeReq = r.WithContext(supervised.ctx)
+	}
+
+	// Time from here on is spent streaming from the provider to the client
+	setOverheadHeader(w, r)
+
+	streamStart := time.Now()
+	var delivered streamOutput
+	chunks, err := writeStream(w, writeReq, negotiateStreamEncoder(r), stream, &delivered)
+	timer.Add(time.Since(streamStart))
+	stalled := false
+	if watchdog != nil {
+		var midStream bool
+		if stalled, midStream = watchdog.Stalled(); stalled && midStream {
+			s.recordStall(providerName, model, stallMidStream)
+		}
+	}
+	if err != nil {
+		s.logger.Warn("Stream interrupted",
+			zap.String("provider", providerName),
+			zap.Int("chunks", chunks),
+			zap.Error(err))
+		// A stream the client left is accounted for up to what it received;
+		// a stalled one was ended by the router
+		if !stalled {
+			s.recordAborted(tenantFrom(r).ID, providerName, req, &delivered, abortReason(r.Context()))
+		}
+		return
+	}
+
+	s.metrics.RecordProviderLatency(providerName, model, time.Since(start))
+	s.metrics.RecordProviderHealth(providerName, true)
+	s.recordStreamed(tenantFrom(r).ID, providerName, req, &delivered)
+}
+
+// handleRoute runs the routing policy and returns the decision with a signed
+// short-lived token instead of executing the completion (gatekeeper mode).
+func (s *Server) handleRoute(w http.ResponseWriter, r *http.Request) {
+	if !s.config.Gatekeeper.Enabled {
+		http.Error(w, "Gatekeeper mode is disabled", http.StatusNotFound)
+		return
+	}
+
+	var apiReq v1.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&apiReq); err != nil {
+		s.logger.Error("Failed to decode request", zap.Error(err))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req := convertChatRequest(apiReq)
+	available := s.providers.Snapshot()
+
+	// Make routing decision
+	routingStart := time.Now()
+	decision, _, err := s.decideRoute(r.Context(), req, available)
+	if err != nil {
+		s.logger.Error("Routing decision failed", zap.Error(err))
+		writeRoutingError(w, req.RequestID, err)
+		return
+	}
+	s.metrics.RecordRoutingDecision(s.routingPolicy.GetName(), decision.ProviderName, decision.Model)
+	if decision.Category != "" {
+		s.metrics.RecordContentCategory(decision.Category, decision.ProviderName, decision.Model)
+	}
+	s.metrics.RecordRoutingLatency(s.routingPolicy.GetName(), time.Since(routingStart))
+
+	provider, exists := available[decision.ProviderName]
+	if !exists {
+		s.logger.Error("Selected provider not found", zap.String("provider", decision.ProviderName))
+		http.Error(w, "Provider not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var providerConfig providers.ProviderConfig
+	if p, ok := provider.(interface{ GetConfig() providers.ProviderConfig }); ok {
+		providerConfig = p.GetConfig()
+	}
+	apiKey := providerConfig.APIKey
+	if p, ok := provider.(interface{ SelectAPIKey() string }); ok {
+		apiKey = p.SelectAPIKey()
+	}
+
+	token, claims, err := s.tokenSigner.Issue(gatekeeper.Claims{
+		Provider:      decision.ProviderName,
+		Model:         decision.Model,
+		CredentialRef: gatekeeper.CredentialRef(decision.ProviderName, apiKey),
+		RequestID:     req.RequestID,
+		User:          req.User,
+		Tenant:        tenantFrom(r).ID,
+	})
+	if err != nil {
+		s.logger.Error("Failed to issue gatekeeper token", zap.Error(err))
+		http.Error(w, "Failed to issue token", http.StatusInternalServerError)
+		return
+	}
+
+	response := v1.RouteResponse{
+		RequestID:     req.RequestID,
+		Provider:      decision.ProviderName,
+		Model:         decision.Model,
+		BaseURL:       providerConfig.BaseURL,
+		CredentialRef: claims.CredentialRef,
+		Token:         token,
+		ExpiresAt:     claims.ExpiresAt,
+		Decision: v1.RoutingDecision{
+			ProviderName:     decision.ProviderName,
+			Model:            decision.Model,
+			Reason:           decision.Reason,
+			EstimatedCost:    decision.EstimatedCost,
+			EstimatedLatency: decision.EstimatedLatency,
+			Confidence:       decision.Confidence,
+			Fallback:         decision.Fallback,
+			Spillover:        decision.Spillover,
+			Category:         decision.Category,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleRedeemVoucher exchanges a gatekeeper token for the provider key, or for
+// a proxy URL when the redemption mode is "proxy".
+func (s *Server) handleRedeemVoucher(w http.ResponseWriter, r *http.Request) {
+	if !s.config.Gatekeeper.Enabled {
+		http.Error(w, "Gatekeeper mode is disabled", http.StatusNotFound)
+		return
+	}
+
+	var redeemReq v1.VoucherRedeemRequest
+	if err := json.NewDecoder(r.Body).Decode(&redeemReq); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := s.verifyVoucher(w, redeemReq.Token)
+	if !ok {
+		return
+	}
+
+	// Only the tenant that requested the route may redeem its voucher, and
+	// only keys granted vouchers:key may see provider keys
+	tenant := tenantFrom(r)
+	if claims.Tenant != tenant.ID {
+		writeForbidden(w, r, "this voucher was issued to another tenant")
+		return
+	}
+	if s.config.Gatekeeper.Redemption == gatekeeper.RedeemKey &&
+		(!tenant.Authenticated || !tenant.Scopes.Allows(tenants.ScopeVoucherKey)) {
+		writeForbidden(w, r, "redeeming vouchers for provider keys needs an API key with the "+tenants.ScopeVoucherKey+" scope")
+		return
+	}
+
+	provider, exists := s.providers.Get(claims.Provider)
+	if !exists {
+		http.Error(w, "Provider not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	response := v1.VoucherRedemption{
+		Provider:  claims.Provider,
+		Model:     claims.Model,
+		ExpiresAt: claims.ExpiresAt,
+	}
+
+	if s.config.Gatekeeper.Redemption == gatekeeper.RedeemProxy {
+		// The voucher itself authorizes the proxied call and is consumed there
+		baseURL := strings.TrimRight(s.config.Gatekeeper.PublicURL, "/")
+		if baseURL == "" {
+			baseURL = "http://" + r.Host
+		}
+		response.ProxyURL = baseURL + "/v1/vouchers/proxy/chat/completions"
+	} else {
+		if err := s.voucherLedger.Redeem(claims); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		var providerConfig providers.ProviderConfig
+		if p, ok := provider.(interface{ GetConfig() providers.ProviderConfig }); ok {
+			providerConfig = p.GetConfig()
+		}
+		response.APIKey = voucherAPIKey(claims, providerConfig)
+		response.BaseURL = providerConfig.BaseURL
+	}
+
+	s.logger.Info("Voucher redeemed",
+		zap.String("voucher_id", claims.ID),
+		zap.String("provider", claims.Provider),
+		zap.String("tenant", claims.Tenant),
+		zap.String("mode", s.config.Gatekeeper.Redemption))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleVoucherProxy executes a chat completion on the provider and model named
+// by the bearer voucher, without running the routing policy again.
+func (s *Server) handleVoucherProxy(w http.ResponseWriter, r *http.Request) {
+	if !s.config.Gatekeeper.Enabled || s.config.Gatekeeper.Redemption != gatekeeper.RedeemProxy {
+		http.Error(w, "Voucher proxy is disabled", http.StatusNotFound)
+		return
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	claims, ok := s.verifyVoucher(w, token)
+	if !ok {
+		return
+	}
+
+	// The call is charged to the tenant the voucher was issued to, whoever
+	// presents it, and counts against that tenant's rate limits
+	r = r.WithContext(context.WithValue(r.Context(), tenantContextKey{}, requestTenant{ID: claims.Tenant}))
+	if s.rateLimiter != nil {
+		if decision := s.rateLimiter.Allow(r.Context(), claims.Tenant); !decision.Allowed {
+			writeRateLimited(w, r, decision)
+			return
+		}
+	}
+
+	if err := s.voucherLedger.Redeem(claims); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	var apiReq v1.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&apiReq); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req := convertChatRequest(apiReq)
+	req.Model = claims.Model
+	if req.RequestID == "" {
+		req.RequestID = claims.RequestID
+	}
+
+	provider, exists := s.providers.Get(claims.Provider)
+	if !exists {
+		http.Error(w, "Provider not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if req.Stream {
+		s.handleChatCompletionStream(w, r, req, claims.Provider, claims.Model, provider, nil)
+		return
+	}
+
+	start := time.Now()
+	response, err := provider.CreateChatCompletion(r.Context(), req)
+	observability.ProviderTimerFrom(r.Context()).Add(time.Since(start))
+	if err != nil {
+		s.logger.Error("Voucher proxy request failed",
+			zap.String("provider", claims.Provider),
+			zap.Error(err))
+		s.metrics.RecordProviderError(claims.Provider, "request_failed")
+		if r.Context().Err() != nil {
+			s.recordAborted(claims.Tenant, claims.Provider, req, nil, abortReason(r.Context()))
+		}
+
+		errorResponse := v1.ErrorResponse{
+			Error: v1.ErrorDetails{
+				Type:       "provider_error",
+				Message:    err.Error(),
+				StatusCode: http.StatusBadGateway,
+				Provider:   claims.Provider,
+			},
+			RequestID: req.RequestID,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(errorResponse)
+		return
+	}
+	s.metrics.RecordProviderLatency(claims.Provider, claims.Model, time.Since(start))
+
+	apiResponse := v1.ChatCompletionResponse{
+		ID:        response.ID,
+		Model:     response.Model,
+		Choices:   convertChoices(response.Choices),
+		Usage:     convertUsage(response.Usage),
+		Created:   response.Created,
+		Provider:  claims.Provider,
+		RequestID: response.RequestID,
+	}
+	if apiResponse.Model == "" {
+		apiResponse.Model = req.Model
+	}
+
+	record := usage.Record{
+		Tenant:           claims.Tenant,
+		Provider:         claims.Provider,
+		Model:            apiResponse.Model,
+		PromptTokens:     response.Usage.PromptTokens,
+		CompletionTokens: response.Usage.CompletionTokens,
+	}
+	if cost, found := s.modelCatalog.EstimateCost(claims.Provider, apiResponse.Model,
+		response.Usage.PromptTokens, response.Usage.CompletionTokens); found {
+		record.Cost = cost
+	}
+	s.recordUsage(record)
+
+	setOverheadHeader(w, r)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(apiResponse)
+}
+
+// voucherAPIKey returns the provider key the voucher's credential reference was issued for.
+func voucherAPIKey(claims gatekeeper.Claims, config providers.ProviderConfig) string {
+	for _, key := range config.APIKeys {
+		if gatekeeper.CredentialRef(claims.Provider, key.Key) == claims.CredentialRef {
+			return key.Key
+		}
+	}
+	return config.APIKey
+}
+
+// verifyVoucher checks a gatekeeper token, writing a 401 response if it is not valid.
+func (s *Server) verifyVoucher(w http.ResponseWriter, token string) (gatekeeper.Claims, bool) {
+	claims, err := s.tokenSigner.Verify(token)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return gatekeeper.Claims{}, false
+	}
+	return claims, true
+}
+
+// handleGetSelf reports the router's own health, separate from provider health,
+// to tell a slow provider apart from an overloaded router.
+func (s *Server) handleGetSelf(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(s.selfMonitor.Snapshot())
+}
+
+// handleGetAlerts returns the state of each alert rule.
+func (s *Server) handleGetAlerts(w http.ResponseWriter, r *http.Request) {
+	if s.alerts == nil {
+		http.Error(w, "Alerting is disabled", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rules": s.alerts.States(),
+	})
+}
+
+// handleTokenize counts the tokens of a conversation or text with the same
+// counter used for cost estimation and context-window checks.
+func (s *Server) handleTokenize(w http.ResponseWriter, r *http.Request) {
+	var tokenizeReq v1.TokenizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&tokenizeReq); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if tokenizeReq.Model == "" {
+		http.Error(w, "model is required", http.StatusBadRequest)
+		return
+	}
+
+	providerName := tokenizeReq.Provider
+	if providerName == "" {
+		providerName = s.providerForModel(tokenizeReq.Model)
+	}
+
+	counter := tokenizer.CounterFor(providerName, tokenizeReq.Model)
+	tokens := counter.CountText(tokenizeReq.Model, tokenizeReq.Input)
+	if len(tokenizeReq.Messages) > 0 {
+		tokens += counter.CountMessages(tokenizeReq.Model, convertMessages(tokenizeReq.Messages))
+	}
+
+	contextWindow := tokenizer.ContextWindow(tokenizeReq.Model)
+	if entry, found := s.modelCatalog.Lookup(providerName, tokenizeReq.Model); found && entry.ContextWindow > 0 {
+		contextWindow = entry.ContextWindow
+	}
+
+	response := v1.TokenizeResponse{
+		Model:         tokenizeReq.Model,
+		Provider:      providerName,
+		Encoding:      counter.Name(),
+		Tokens:        tokens,
+		ContextWindow: contextWindow,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleEmbeddings handles embeddings requests. The request is routed like a
+// chat completion among the providers serving the model.
+func (s *Server) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var apiReq v1.EmbeddingRequest
+	if err := json.NewDecoder(r.Body).Decode(&apiReq); err != nil {
+		s.logger.Error("Failed to decode request", zap.Error(err))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if apiReq.Model == "" || len(apiReq.Input) == 0 {
+		http.Error(w, "model and input are required", http.StatusBadRequest)
+		return
+	}
+
+	req := models.EmbeddingRequest{
+		Model:      apiReq.Model,
+		Input:      apiReq.Input,
+		Dimensions: apiReq.Dimensions,
+		User:       apiReq.User,
+		RequestID:  apiReq.RequestID,
+	}
+
+	// Route on the inputs as if they were a prompt, so cost and latency estimates apply
+	routingReq := models.ChatRequest{
+		Model:     req.Model,
+		Messages:  make([]models.Message, len(req.Input)),
+		User:      req.User,
+		RequestID: req.RequestID,
+		CreatedAt: time.Now(),
+	}
+	for i, input := range req.Input {
+		routingReq.Messages[i] = models.Message{Role: "user", Content: models.TextContent(input)}
+	}
+
+	available := s.providers.Snapshot()
+	candidates := s.capableProviders(req.Model, func(p providers.Provider) bool {
+		return providers.Supports(p, providers.CapabilityEmbeddings, req.Model)
+	})
+	if len(candidates) == 0 {
+		http.Error(w, "No provider supports embeddings", http.StatusNotImplemented)
+		return
+	}
+
+	routingStart := time.Now()
+	decision, err := s.routingPolicy.DecideRoute(ctx, routingReq, candidates)
+	if err != nil {
+		s.logger.Error("Routing decision failed", zap.Error(err))
+		writeRoutingError(w, routingReq.RequestID, err)
+		return
+	}
+	s.metrics.RecordRoutingDecision(s.routingPolicy.GetName(), decision.ProviderName, decision.Model)
+	s.metrics.RecordRoutingLatency(s.routingPolicy.GetName(), time.Since(routingStart))
+
+	provider, exists := available[decision.ProviderName]
+	if !exists {
+		s.logger.Error("Selected provider not found", zap.String("provider", decision.ProviderName))
+		http.Error(w, "Provider not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	start := time.Now()
+	response, err := providers.Embed(ctx, provider, req)
+	duration := time.Since(start)
+	observability.ProviderTimerFrom(ctx).Add(duration)
+
+	if err != nil {
+		s.logger.Error("Provider embeddings request failed",
+			zap.String("provider", decision.ProviderName),
+			zap.Error(err))
+		s.metrics.RecordProviderError(decision.ProviderName, "embedding_failed")
+
+		errorResponse := v1.ErrorResponse{
+			Error: v1.ErrorDetails{
+				Type:       "provider_error",
+				Message:    err.Error(),
+				StatusCode: http.StatusBadGateway,
+				Provider:   decision.ProviderName,
+			},
+			RequestID: req.RequestID,
+		}
+		var providerErr *models.ProviderError
+		if errors.As(err, &providerErr) {
+			if providerErr.StatusCode == http.StatusNotImplemented {
+				errorResponse.Error.StatusCode = http.StatusNotImplemented
+			}
+			errorResponse.Error.Retryable = providerErr.Retryable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(errorResponse.Error.StatusCode)
+		json.NewEncoder(w).Encode(errorResponse)
+		return
+	}
+
+	s.metrics.RecordProviderLatency(decision.ProviderName, decision.Model, duration)
+	s.metrics.RecordProviderHealth(decision.ProviderName, true)
+
+	apiResponse := v1.EmbeddingResponse{
+		Object:    "list",
+		Model:     response.Model,
+		Data:      make([]v1.EmbeddingData, len(response.Data)),
+		Usage:     convertUsage(response.Usage),
+		Provider:  decision.ProviderName,
+		RequestID: response.RequestID,
+	}
+	for i, embedding := range response.Data {
+		apiResponse.Data[i] = v1.EmbeddingData{
+			Object:    "embedding",
+			Index:     embedding.Index,
+			Embedding: embedding.Embedding,
+		}
+	}
+
+	setOverheadHeader(w, r)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(apiResponse)
+}
+
+// handleImageGeneration handles image generation requests, routed among the
+// providers that can generate images.
+func (s *Server) handleImageGeneration(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var apiReq v1.ImageGenerationRequest
+	if err := json.NewDecoder(r.Body).Decode(&apiReq); err != nil {
+		s.logger.Error("Failed to decode request", zap.Error(err))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if apiReq.Prompt == "" {
+		http.Error(w, "prompt is required", http.StatusBadRequest)
+		return
+	}
+
+	req := models.ImageRequest{
+		Model:          apiReq.Model,
+		Prompt:         apiReq.Prompt,
+		N:              apiReq.N,
+		Size:           apiReq.Size,
+		Quality:        apiReq.Quality,
+		Style:          apiReq.Style,
+		ResponseFormat: apiReq.ResponseFormat,
+		User:           apiReq.User,
+		RequestID:      apiReq.RequestID,
+	}
+
+	candidates := s.capableProviders(req.Model, func(p providers.Provider) bool {
+		_, ok := p.(providers.ImageProvider)
+		return ok
+	})
+	if len(candidates) == 0 {
+		http.Error(w, "No provider supports image generation", http.StatusNotImplemented)
+		return
+	}
+
+	routingReq := models.ChatRequest{
+		Model:     req.Model,
+		Messages:  []models.Message{{Role: "user", Content: models.TextContent(req.Prompt)}},
+		User:      req.User,
+		RequestID: req.RequestID,
+		CreatedAt: time.Now(),
 	}
 
-	// Make routing decision
 	routingStart := time.Now()
-	decision, err := s.routingPolicy.DecideRoute(ctx, req, s.providers)
+	decision, err := s.routingPolicy.DecideRoute(ctx, routingReq, candidates)
 	if err != nil {
 		s.logger.Error("Routing decision failed", zap.Error(err))
-		http.Error(w, "Routing failed", http.StatusServiceUnavailable)
+		writeRoutingError(w, routingReq.RequestID, err)
 		return
 	}
-	routingDuration := time.Since(routingStart)
-
-	// Record routing metrics
 	s.metrics.RecordRoutingDecision(s.routingPolicy.GetName(), decision.ProviderName, decision.Model)
-	s.metrics.RecordRoutingLatency(s.routingPolicy.GetName(), routingDuration)
+	s.metrics.RecordRoutingLatency(s.routingPolicy.GetName(), time.Since(routingStart))
 
-	// Get the selected provider
-	provider, exists := s.providers[decision.ProviderName]
-	if !exists {
+	provider, ok := candidates[decision.ProviderName].(providers.ImageProvider)
+	if !ok {
 		s.logger.Error("Selected provider not found", zap.String("provider", decision.ProviderName))
 		http.Error(w, "Provider not available", http.StatusServiceUnavailable)
 		return
 	}
 
-	// Execute the request
 	start := time.Now()
-	var response *models.ChatResponse
-	
-	if req.Stream {
-		// Handle streaming (not yet implemented)
-		http.Error(w, "Streaming not yet implemented", http.StatusNotImplemented)
-		return
-	} else {
-		response, err = provider.CreateChatCompletion(ctx, req)
-	}
-	
+	response, err := provider.CreateImage(ctx, req)
 	duration := time.Since(start)
+	observability.ProviderTimerFrom(ctx).Add(duration)
 
 	if err != nil {
-		// Handle provider errors
-		s.logger.Error("Provider request failed", 
+		s.logger.Error("Provider image request failed",
 			zap.String("provider", decision.ProviderName),
 			zap.Error(err))
-		
-		// Record error metrics
-		s.metrics.RecordProviderError(decision.ProviderName, "request_failed")
-		
-		// Check if we should try a different provider
-		if decision.Fallback {
-			// Try to find another provider
-			// This is a simplified fallback - in production you'd want more sophisticated logic
-			for name, p := range s.providers {
-				if name != decision.ProviderName && p.IsHealthy() {
-					// Try the fallback provider
-					response, err = p.CreateChatCompletion(ctx, req)
-					if err == nil {
-						decision.ProviderName = name
-						decision.Reason = "Fallback provider used"
-						break
-					}
-				}
-			}
-		}
+		s.metrics.RecordProviderError(decision.ProviderName, "image_failed")
 
-		if err != nil {
-			// All providers failed
-			errorResponse := v1.ErrorResponse{
-				Error: v1.ErrorDetails{
-					Type:        "provider_error",
-					Message:     "All providers failed",
-					StatusCode:  http.StatusServiceUnavailable,
-					Retryable:   true,
-				},
-				RequestID: req.RequestID,
-			}
-			
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusServiceUnavailable)
-			json.NewEncoder(w).Encode(errorResponse)
-			return
+		errorResponse := v1.ErrorResponse{
+			Error: v1.ErrorDetails{
+				Type:       "provider_error",
+				Message:    err.Error(),
+				StatusCode: http.StatusBadGateway,
+				Provider:   decision.ProviderName,
+			},
+			RequestID: req.RequestID,
 		}
+		var providerErr *models.ProviderError
+		if errors.As(err, &providerErr) {
+			errorResponse.Error.Retryable = providerErr.Retryable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(errorResponse)
+		return
 	}
 
-	// Record success metrics
 	s.metrics.RecordProviderLatency(decision.ProviderName, decision.Model, duration)
 	s.metrics.RecordProviderHealth(decision.ProviderName, true)
 
-	// Convert response to API format
-	apiResponse := v1.ChatCompletionResponse{
-		ID:        response.ID,
-		Model:     response.Model,
-		Choices:   convertChoices(response.Choices),
-		Usage:     convertUsage(response.Usage),
+	apiResponse := v1.ImageGenerationResponse{
 		Created:   response.Created,
+		Data:      make([]v1.ImageData, len(response.Data)),
 		Provider:  decision.ProviderName,
 		RequestID: response.RequestID,
 	}
+	for i, image := range response.Data {
+		apiResponse.Data[i] = v1.ImageData{
+			URL:           image.URL,
+			B64JSON:       image.B64JSON,
+			RevisedPrompt: image.RevisedPrompt,
+		}
+	}
 
+	setOverheadHeader(w, r)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(apiResponse)
 }
 
+// capableProviders returns the providers with an optional capability,
+// preferring those that list model among their models.
+func (s *Server) capableProviders(model string, capable func(providers.Provider) bool) map[string]providers.Provider {
+	available := s.providers.Snapshot()
+
+	candidates := make(map[string]providers.Provider)
+	for name, provider := range providersForModel(available, model) {
+		if capable(provider) {
+			candidates[name] = provider
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates
+	}
+
+	for name, provider := range available {
+		if capable(provider) {
+			candidates[name] = provider
+		}
+	}
+	return candidates
+}
+
+// providersForModel returns the providers in available that list model among their models.
+func providersForModel(available map[string]providers.Provider, model string) map[string]providers.Provider {
+	serving := make(map[string]providers.Provider)
+	for name, provider := range available {
+		served, err := provider.GetModels()
+		if err != nil {
+			continue
+		}
+		for _, m := range served {
+			if m == model {
+				serving[name] = provider
+				break
+			}
+		}
+	}
+	return serving
+}
+
+// providerForModel returns the first provider, by name, that serves model.
+func (s *Server) providerForModel(model string) string {
+	available := s.providers.Snapshot()
+	for _, name := range s.providers.Names() {
+		provider, exists := available[name]
+		if !exists {
+			continue
+		}
+		served, err := provider.GetModels()
+		if err != nil {
+			continue
+		}
+		for _, m := range served {
+			if m == model {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// routedRequest returns the request to send for a routing decision. Policies
+// may choose a different model than the one requested, such as a cheaper model
+// for simple prompts.
+func routedRequest(req models.ChatRequest, decision policies.RoutingDecision) models.ChatRequest {
+	if decision.Model != "" {
+		req.Model = decision.Model
+	}
+	return req
+}
+
 // handleGetModels returns available models from all providers.
 func (s *Server) handleGetModels(w http.ResponseWriter, r *http.Request) {
-	var allModels []v1.ModelInfo
-	var allProviders []string
+	filter, err := parseModelFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	for name, provider := range s.providers {
-		models, err := provider.GetModels()
-		if err != nil {
-			s.logger.Warn("Failed to get models from provider", 
-				zap.String("provider", name), 
-				zap.Error(err))
+	allModels := []v1.ModelInfo{}
+	allProviders := []string{}
+	statuses := []v1.ProviderModelsStatus{}
+	tenant := tenantFrom(r).ID
+	accessLists := tenantFrom(r).Access
+
+	for _, result := range s.fetchModelLists(s.providers.Snapshot()) {
+		statuses = append(statuses, result.status)
+		if result.status.Status == modelListFailed && !result.status.Stale {
 			continue
 		}
 
-		allProviders = append(allProviders, name)
-		
-		for _, model := range models {
-			allModels = append(allModels, v1.ModelInfo{
-				ID:       model,
-				Name:     model,
-				Provider: name,
-				Type:     "chat_completion", // This could be more sophisticated
-			})
+		allProviders = append(allProviders, result.status.Provider)
+
+		for _, model := range result.models {
+			info, entry, inCatalog := s.describeModel(result.status.Provider, model)
+			if inCatalog && !entry.AllowsTenant(tenant) || !allowedBy(accessLists, result.status.Provider, model) {
+				continue
+			}
+			if filter.matches(info, entry, inCatalog) {
+				allModels = append(allModels, info)
+			}
 		}
 	}
 
 	response := v1.ModelsResponse{
-		Models:    allModels,
-		Total:     len(allModels),
-		Providers: allProviders,
+		Total:          len(allModels),
+		Providers:      allProviders,
+		ProviderStatus: statuses,
+	}
+
+	// Paginate only when a limit is given
+	page := allModels
+	if filter.offset > len(page) {
+		page = page[:0]
+	} else {
+		page = page[filter.offset:]
 	}
+	if filter.limit > 0 && len(page) > filter.limit {
+		page = page[:filter.limit]
+		response.HasMore = true
+		response.NextOffset = filter.offset + filter.limit
+	}
+	response.Models = page
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -227,6 +1189,7 @@ func (s *Server) handleGetRoutingInfo(w http.ResponseWriter, r *http.Request) {
 			Confidence:   0.0,
 			Fallback:     false,
 		},
+		Parameters: s.effectiveDefaults(tenantFrom(r).ID),
 		Timestamp: time.Now(),
 	}
 
@@ -247,7 +1210,7 @@ func (s *Server) handleGetMetrics(w http.ResponseWriter, r *http.Request) {
 			ErrorRate: 0.0,
 		},
 		Providers: v1.ProviderMetrics{
-			Total:   int64(len(s.providers)),
+			Total:   int64(s.providers.Len()),
 			Healthy: 0,
 			Unhealthy: 0,
 		},
@@ -274,7 +1237,7 @@ func (s *Server) handleGetMetrics(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleGetProviders(w http.ResponseWriter, r *http.Request) {
 	providers := make(map[string]interface{})
 	
-	for name, provider := range s.providers {
+	for name, provider := range s.providers.Snapshot() {
 		health := provider.GetHealth()
 		models, _ := provider.GetModels()
 		
@@ -297,7 +1260,7 @@ func (s *Server) handleGetProviders(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleGetProviderHealth(w http.ResponseWriter, r *http.Request) {
 	providerName := chi.URLParam(r, "name")
 	
-	provider, exists := s.providers[providerName]
+	provider, exists := s.providers.Get(providerName)
 	if !exists {
 		http.Error(w, "Provider not found", http.StatusNotFound)
 		return
@@ -314,6 +1277,20 @@ func (s *Server) handleGetProviderHealth(w http.ResponseWriter, r *http.Request)
 		"error":     health.Error,
 		"models":    models,
 	}
+	if reporter, ok := provider.(providers.BandwidthReporter); ok {
+		response["bandwidth"] = reporter.GetBandwidthStats()
+	}
+	if reporter, ok := provider.(providers.RateLimitReporter); ok {
+		if state, known := reporter.GetRateLimitState(); known {
+			response["rate_limit"] = state
+		}
+	}
+	if p, ok := provider.(interface{ GetKeyStatus() []providers.KeyStatus }); ok {
+		response["api_keys"] = p.GetKeyStatus()
+	}
+	if weight, set := s.weights.Get(providerName); set {
+		response["operator_weight"] = weight
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -326,6 +1303,7 @@ func (s *Server) handleForceHealthCheck(w http.ResponseWriter, r *http.Request)
 	
 	// Force health check
 	s.healthChecker.ForceHealthCheck()
+	s.recordAudit(r, "provider.health_check", providerName, nil, nil)
 	
 	response := map[string]string{
 		"message": fmt.Sprintf("Health check triggered for provider: %s", providerName),
@@ -336,12 +1314,108 @@ func (s *Server) handleForceHealthCheck(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(response)
 }
 
-// handleGetRoutingPolicy returns information about the current routing policy.
+// handleGetRoutingPolicy returns the routing policy in use and its
+// configuration.
 func (s *Server) handleGetRoutingPolicy(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(s.routingPolicyStatus())
+}
+
+// routingPolicyStatus describes the routing policy in use, its middleware and
+// configuration.
+func (s *Server) routingPolicyStatus() map[string]interface{} {
+	s.policyMutex.Lock()
+	config := s.config.RoutingPolicy
+	updated := s.policyUpdated
+	s.policyMutex.Unlock()
+
+	policy := unwrapPolicy(s.routingPolicy)
+	response := map[string]interface{}{
+		"name":        policy.GetName(),
+		"description": policy.GetDescription(),
+		"type":        config.Type,
+		"config":      config.Config,
+	}
+	if !updated.IsZero() {
+		response["updated_at"] = updated
+	}
+	if chained, ok := s.routingPolicy.(*policies.ChainedPolicy); ok {
+		response["middleware"] = chained.Middleware()
+	}
+	if canary, ok := policy.(*policies.CanaryPolicy); ok {
+		response["canary"] = canary.Status()
+	}
+	if bandit, ok := policy.(*policies.BanditPolicy); ok {
+		response["arms"] = bandit.Arms()
+	}
+	if pipeline, ok := policy.(*policies.PipelinePolicy); ok {
+		response["stages"] = pipeline.Stages()
+	}
+	return response
+}
+
+// handleGetRoutingWeights returns the routing weights in effect and their
+// recent changes, for policies with weights.
+func (s *Server) handleGetRoutingWeights(w http.ResponseWriter, r *http.Request) {
+	reporter, ok := unwrapPolicy(s.routingPolicy).(policies.WeightReporter)
+	if !ok {
+		http.Error(w, "The routing policy has no weights", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reporter.WeightReport().WithOverrides(s.weights.All()))
+}
+
+// handleGetCircuitBreakers returns the state of the providers' circuit
+// breakers. Providers that have not served a request yet are not listed.
+func (s *Server) handleGetCircuitBreakers(w http.ResponseWriter, r *http.Request) {
+	if s.breaker == nil {
+		http.Error(w, "Circuit breakers are not enabled", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"providers": s.breaker.Status(),
+	})
+}
+
+// handleUpdateRoutingPolicy replaces the routing policy in use with one of
+// the given type and configuration, without a restart. An invalid policy is
+// rejected with 400 and leaves the policy in use unchanged.
+func (s *Server) handleUpdateRoutingPolicy(w http.ResponseWriter, r *http.Request) {
+	var update RoutingPolicyUpdate
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&update); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	before, err := s.updateRoutingPolicy(update)
+	if err != nil {
+		s.logger.Warn("Rejected routing policy update", zap.String("type", update.Type), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Invalid routing policy: %v", err), http.StatusBadRequest)
+		return
+	}
+	s.recordAudit(r, "routing.policy", update.Type,
+		RoutingPolicyUpdate{Type: before.Type, Config: before.Config}, update)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(s.routingPolicyStatus())
+}
+
+// handleGetPricing returns the current pricing catalog.
+func (s *Server) handleGetPricing(w http.ResponseWriter, r *http.Request) {
 	response := map[string]interface{}{
-		"name":        s.routingPolicy.GetName(),
-		"description": s.routingPolicy.GetDescription(),
-		"type":        s.config.RoutingPolicy.Type,
+		"models":    s.modelCatalog.Entries(),
+		"file":      s.modelCatalog.GetConfig().File,
+		"loaded_at": s.modelCatalog.LoadedAt(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -349,53 +1423,292 @@ func (s *Server) handleGetRoutingPolicy(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(response)
 }
 
-// handleUpdateRoutingPolicy updates the routing policy configuration.
-func (s *Server) handleUpdateRoutingPolicy(w http.ResponseWriter, r *http.Request) {
-	// This would allow dynamic policy updates
-	// For now, return not implemented
-	http.Error(w, "Policy updates not yet implemented", http.StatusNotImplemented)
+// handleReloadPricing reloads the pricing catalog from the inline entries of
+// the config file and the pricing file.
+func (s *Server) handleReloadPricing(w http.ResponseWriter, r *http.Request) {
+	before := s.modelCatalog.Entries()
+	if err := s.modelCatalog.Reload(); err != nil {
+		s.logger.Error("Failed to reload pricing catalog", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Failed to reload pricing: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	entries := s.modelCatalog.Entries()
+	s.logger.Info("Pricing catalog reloaded", zap.Int("models", len(entries)))
+	s.recordAudit(r, "pricing.reload", s.modelCatalog.GetConfig().File, before, entries)
+	s.publishInvalidation(r.Context(), invalidation.Event{Type: invalidation.PricingReload})
+
+	response := map[string]interface{}{
+		"message":   "Pricing catalog reloaded",
+		"models":    len(entries),
+		"loaded_at": s.modelCatalog.LoadedAt(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handlePurgeCache removes the entry named by the key query parameter, or
+// every entry without one, on this and all other replicas.
+func (s *Server) handlePurgeCache(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if err := s.purgeCache(r.Context(), key); err != nil {
+		s.logger.Error("Failed to purge cache", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Failed to purge cache: %v", err), http.StatusInternalServerError)
+		return
+	}
+	s.publishInvalidation(r.Context(), invalidation.Event{Type: invalidation.CachePurge, Key: key})
+	target := key
+	if target == "" {
+		target = "*"
+	}
+	s.recordAudit(r, "cache.purge", target, nil, nil)
+
+	response := map[string]interface{}{
+		"message": "Cache purged",
+	}
+	if key != "" {
+		response["key"] = key
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGetShadowReports returns the comparison reports for all shadow providers.
+func (s *Server) handleGetShadowReports(w http.ResponseWriter, r *http.Request) {
+	reports := make(map[string]interface{})
+	for _, provider := range s.shadowStore.Providers() {
+		reports[provider] = s.shadowStore.Report(provider)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reports)
+}
+
+// handleGetShadowReport returns the comparison report for a single shadow provider.
+func (s *Server) handleGetShadowReport(w http.ResponseWriter, r *http.Request) {
+	providerName := chi.URLParam(r, "provider")
+
+	response := map[string]interface{}{
+		"report": s.shadowStore.Report(providerName),
+	}
+	if r.URL.Query().Get("samples") == "true" {
+		response["samples"] = s.shadowStore.List(providerName)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
 }
 
 // Helper functions for converting between API and internal types
 
+func convertChatRequest(apiReq v1.ChatCompletionRequest) models.ChatRequest {
+	return models.ChatRequest{
+		Model:            apiReq.Model,
+		Messages:         convertMessages(apiReq.Messages),
+		Stream:           apiReq.Stream,
+		MaxTokens:        apiReq.MaxTokens,
+		Temperature:      apiReq.Temperature,
+		TopP:             apiReq.TopP,
+		TopK:             apiReq.TopK,
+		Stop:             apiReq.Stop,
+		PresencePenalty:  apiReq.PresencePenalty,
+		FrequencyPenalty: apiReq.FrequencyPenalty,
+		User:             apiReq.User,
+		Tools:            convertTools(apiReq.Tools),
+		ToolChoice:       convertToolChoice(apiReq.ToolChoice),
+		ResponseFormat:   convertResponseFormat(apiReq.ResponseFormat),
+		N:                apiReq.N,
+		Seed:             apiReq.Seed,
+		Logprobs:         apiReq.Logprobs,
+		TopLogprobs:      apiReq.TopLogprobs,
+		LogitBias:        apiReq.LogitBias,
+		Thinking:         convertThinking(apiReq.Thinking),
+		Metadata:         apiReq.Metadata,
+		Routing:          convertRoutingHints(apiReq.Routing),
+		RequestID:        apiReq.RequestID,
+		CreatedAt:        time.Now(),
+	}
+}
+
 func convertMessages(apiMessages []v1.Message) []models.Message {
 	messages := make([]models.Message, len(apiMessages))
 	for i, msg := range apiMessages {
 		messages[i] = models.Message{
-			Role:      msg.Role,
-			Content:   msg.Content,
-			Name:      msg.Name,
-			Timestamp: msg.Timestamp,
+			Role:       msg.Role,
+			Content:    convertContent(msg.Content),
+			Name:       msg.Name,
+			ToolCallID: msg.ToolCallID,
+			ReasoningContent:   msg.ReasoningContent,
+			ReasoningSignature: msg.ReasoningSignature,
+			RedactedReasoning:  msg.RedactedReasoning,
+			Timestamp:  msg.Timestamp,
+		}
+		for _, call := range msg.ToolCalls {
+			messages[i].ToolCalls = append(messages[i].ToolCalls, models.ToolCall{
+				ID:   call.ID,
+				Type: call.Type,
+				Function: models.ToolCallFunction{
+					Name:      call.Function.Name,
+					Arguments: call.Function.Arguments,
+				},
+			})
 		}
 	}
 	return messages
 }
 
+func convertContent(apiContent v1.Content) models.Content {
+	parts := make([]models.ContentPart, len(apiContent.Parts))
+	for i, part := range apiContent.Parts {
+		parts[i] = models.ContentPart{Type: part.Type, Text: part.Text}
+		if part.ImageURL != nil {
+			parts[i].ImageURL = &models.ImageURL{URL: part.ImageURL.URL, Detail: part.ImageURL.Detail}
+		}
+	}
+	return models.Content{Parts: parts}
+}
+
+func convertContentToAPI(content models.Content) v1.Content {
+	parts := make([]v1.ContentPart, len(content.Parts))
+	for i, part := range content.Parts {
+		parts[i] = v1.ContentPart{Type: part.Type, Text: part.Text}
+		if part.ImageURL != nil {
+			parts[i].ImageURL = &v1.ImageURL{URL: part.ImageURL.URL, Detail: part.ImageURL.Detail}
+		}
+	}
+	return v1.Content{Parts: parts}
+}
+
+func convertResponseFormat(apiFormat *v1.ResponseFormat) *models.ResponseFormat {
+	if apiFormat == nil {
+		return nil
+	}
+	format := &models.ResponseFormat{Type: apiFormat.Type}
+	if apiFormat.JSONSchema != nil {
+		format.JSONSchema = &models.JSONSchema{
+			Name:        apiFormat.JSONSchema.Name,
+			Description: apiFormat.JSONSchema.Description,
+			Schema:      apiFormat.JSONSchema.Schema,
+			Strict:      apiFormat.JSONSchema.Strict,
+		}
+	}
+	return format
+}
+
+func convertTools(apiTools []v1.Tool) []models.Tool {
+	if len(apiTools) == 0 {
+		return nil
+	}
+	tools := make([]models.Tool, len(apiTools))
+	for i, tool := range apiTools {
+		tools[i] = models.Tool{
+			Type: tool.Type,
+			Function: models.ToolFunction{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				Parameters:  tool.Function.Parameters,
+			},
+		}
+	}
+	return tools
+}
+
+func convertToolChoice(apiChoice *v1.ToolChoice) *models.ToolChoice {
+	if apiChoice == nil {
+		return nil
+	}
+	choice := &models.ToolChoice{Type: apiChoice.Type}
+	if apiChoice.Function != nil {
+		choice.Type = "function"
+		choice.Name = apiChoice.Function.Name
+	}
+	return choice
+}
+
 func convertChoices(choices []models.Choice) []v1.Choice {
 	apiChoices := make([]v1.Choice, len(choices))
 	for i, choice := range choices {
 		apiChoices[i] = v1.Choice{
 			Index:        choice.Index,
 			Message:      convertMessage(choice.Message),
+			Logprobs:     convertLogprobs(choice.Logprobs),
 			FinishReason: choice.FinishReason,
+			StopSequence: choice.StopSequence,
 		}
 	}
 	return apiChoices
 }
 
+func convertThinking(apiThinking *v1.Thinking) *models.Thinking {
+	if apiThinking == nil {
+		return nil
+	}
+	return &models.Thinking{Type: apiThinking.Type, BudgetTokens: apiThinking.BudgetTokens}
+}
+
+func convertLogprobs(logprobs *models.Logprobs) *v1.Logprobs {
+	if logprobs == nil {
+		return nil
+	}
+	apiLogprobs := &v1.Logprobs{Content: make([]v1.TokenLogprob, len(logprobs.Content))}
+	for i, token := range logprobs.Content {
+		apiLogprobs.Content[i] = v1.TokenLogprob{
+			Token:   token.Token,
+			Logprob: token.Logprob,
+			Bytes:   token.Bytes,
+		}
+		for _, top := range token.TopLogprobs {
+			apiLogprobs.Content[i].TopLogprobs = append(apiLogprobs.Content[i].TopLogprobs, v1.TopLogprob{
+				Token:   top.Token,
+				Logprob: top.Logprob,
+				Bytes:   top.Bytes,
+			})
+		}
+	}
+	return apiLogprobs
+}
+
 func convertMessage(msg models.Message) v1.Message {
-	return v1.Message{
-		Role:      msg.Role,
-		Content:   msg.Content,
-		Name:      msg.Name,
-		Timestamp: msg.Timestamp,
+	apiMsg := v1.Message{
+		Role:             msg.Role,
+		Content:          convertContentToAPI(msg.Content),
+		Name:             msg.Name,
+		ToolCallID:       msg.ToolCallID,
+		ReasoningContent: msg.ReasoningContent,
+		ReasoningSignature: msg.ReasoningSignature,
+		RedactedReasoning:  msg.RedactedReasoning,
+		Timestamp:        msg.Timestamp,
+	}
+	for _, call := range msg.ToolCalls {
+		apiMsg.ToolCalls = append(apiMsg.ToolCalls, v1.ToolCall{
+			ID:   call.ID,
+			Type: call.Type,
+			Function: v1.ToolCallFunction{
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			},
+		})
 	}
+	return apiMsg
 }
 
 func convertUsage(usage models.Usage) v1.Usage {
-	return v1.Usage{
+	apiUsage := v1.Usage{
 		PromptTokens:     usage.PromptTokens,
 		CompletionTokens: usage.CompletionTokens,
 		TotalTokens:      usage.TotalTokens,
 	}
+	if details := usage.PromptTokensDetails; details != nil {
+		apiUsage.PromptTokensDetails = &v1.PromptTokensDetails{
+			CachedTokens:        details.CachedTokens,
+			CacheCreationTokens: details.CacheCreationTokens,
+		}
+	}
+	return apiUsage
 }
@@ -0,0 +1,278 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/internal/router/policies"
+	"github.com/semantrix/semaroute/internal/tokenizer"
+	v1 "github.com/semantrix/semaroute/pkg/api/v1"
+)
+
+// HedgingConfig configures hedged requests: a request whose provider has not
+// sent a first token within Delay is reissued to the next best provider, and
+// whichever answers first serves it while the other is cancelled.
+type HedgingConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	Delay   time.Duration `mapstructure:"delay"`  // without a first token before the hedge is sent
+	Models  []string      `mapstructure:"models"` // hedged models; empty hedges all
+}
+
+// Hedge winners, as reported and recorded in metrics.
+const (
+	hedgeWinnerPrimary = "primary"
+	hedgeWinnerHedge   = "hedge"
+	hedgeWinnerNone    = "none"
+)
+
+// hedgeable reports whether requests for model are hedged. Deterministic
+// requests are not, as the race would make their provider depend on timing.
+func (s *Server) hedgeable(ctx context.Context, model string) bool {
+	if !s.config.Hedging.Enabled || s.config.Hedging.Delay <= 0 || policies.Deterministic(ctx) {
+		return false
+	}
+	if len(s.config.Hedging.Models) == 0 {
+		return true
+	}
+	for _, m := range s.config.Hedging.Models {
+		if strings.EqualFold(m, model) {
+			return true
+		}
+	}
+	return false
+}
+
+// hedgeRoute asks the routing policy for the best provider other than the
+// primary's to reissue a request to.
+func (s *Server) hedgeRoute(ctx context.Context, req models.ChatRequest, primary policies.RoutingDecision, available map[string]providers.Provider) (policies.RoutingDecision, providers.Provider, error) {
+	return s.routeExcluding(ctx, req, available, map[string]bool{primary.ProviderName: true})
+}
+
+// hedgeAttempt is one side of a hedged request.
+type hedgeAttempt struct {
+	decision policies.RoutingDecision
+	req      models.ChatRequest
+	cancel   context.CancelFunc
+}
+
+// hedgeResult is the outcome of a hedged attempt.
+type hedgeResult struct {
+	attempt  int // index into the attempts
+	response *models.ChatResponse
+	err      error
+	latency  time.Duration
+}
+
+// runHedged sends a chat completion to the routed provider and, if it has not
+// answered within the hedging delay, reissues it to the next best provider.
+// The first success wins and the other attempt is cancelled. It returns the
+// winning response, request and decision, the hedge and the estimated spend
+// of the cancelled attempt. When the hedge was sent it records the routing
+// metrics of both attempts itself; otherwise the caller records them.
+func (s *Server) runHedged(ctx context.Context, req models.ChatRequest, decision policies.RoutingDecision, available map[string]providers.Provider) (*models.ChatResponse, models.ChatRequest, policies.RoutingDecision, *v1.HedgeInfo, float64, error) {
+	results := make(chan hedgeResult, 2)
+	send := func(i int, provider providers.Provider, attemptCtx context.Context, attemptReq models.ChatRequest) {
+		start := time.Now()
+		response, err := provider.CreateChatCompletion(attemptCtx, attemptReq)
+		results <- hedgeResult{attempt: i, response: response, err: err, latency: time.Since(start)}
+	}
+
+	primaryCtx, cancelPrimary := context.WithCancel(ctx)
+	defer cancelPrimary()
+	go send(0, available[decision.ProviderName], primaryCtx, req)
+
+	timer := time.NewTimer(s.config.Hedging.Delay)
+	defer timer.Stop()
+	ladder := attemptLadderFrom(ctx)
+	select {
+	case result := <-results:
+		ladder.record(v1.ProviderAttempt{Provider: decision.ProviderName, Model: decision.Model}, result.err, result.latency)
+		return result.response, req, decision, nil, 0, result.err
+	case <-timer.C:
+	}
+
+	hedgeDecision, hedgeProvider, err := s.hedgeRoute(ctx, req, decision, available)
+	if err != nil {
+		s.logger.Debug("No provider to hedge with", zap.String("request_id", req.RequestID), zap.Error(err))
+		result := <-results
+		ladder.record(v1.ProviderAttempt{Provider: decision.ProviderName, Model: decision.Model}, result.err, result.latency)
+		return result.response, req, decision, nil, 0, result.err
+	}
+	hedgeReq := routedRequest(req, hedgeDecision)
+	hedgeCtx, cancelHedge := context.WithCancel(ctx)
+	defer cancelHedge()
+	go send(1, hedgeProvider, hedgeCtx, hedgeReq)
+
+	attempts := []hedgeAttempt{
+		{decision: decision, req: req, cancel: cancelPrimary},
+		{decision: hedgeDecision, req: hedgeReq, cancel: cancelHedge},
+	}
+	info := &v1.HedgeInfo{
+		Primary: decision.ProviderName + "/" + decision.Model,
+		Hedge:   hedgeDecision.ProviderName + "/" + hedgeDecision.Model,
+		Winner:  hedgeWinnerNone,
+	}
+	s.logger.Info("Hedging request",
+		zap.String("request_id", req.RequestID),
+		zap.String("primary", info.Primary),
+		zap.String("hedge", info.Hedge),
+		zap.Duration("delay", s.config.Hedging.Delay))
+
+	var lastErr error
+	for pending := len(attempts); pending > 0; pending-- {
+		result := <-results
+		attempt := attempts[result.attempt]
+		s.routingPolicy.UpdateMetrics(attempt.decision, result.err == nil, result.latency)
+		ladder.record(v1.ProviderAttempt{
+			Provider: attempt.decision.ProviderName,
+			Model:    attempt.decision.Model,
+			Hedge:    result.attempt == 1,
+		}, result.err, result.latency)
+		if result.err != nil {
+			lastErr = result.err
+			s.metrics.RecordProviderError(attempt.decision.ProviderName, "request_failed")
+			continue
+		}
+		s.metrics.RecordProviderLatency(attempt.decision.ProviderName, attempt.decision.Model, result.latency)
+
+		// The loser is still running: cancel it and account for its spend
+		wasted := 0.0
+		if pending > 1 {
+			loser := attempts[1-result.attempt]
+			loser.cancel()
+			wasted = s.recordHedgeWaste(loser.decision.ProviderName, loser.req)
+		}
+		info.Winner = hedgeWinnerPrimary
+		if result.attempt == 1 {
+			info.Winner = hedgeWinnerHedge
+			attempt.decision.Reason = fmt.Sprintf("Hedge after %s without a response from %s: %s",
+				s.config.Hedging.Delay, decision.ProviderName, attempt.decision.Reason)
+		}
+		s.metrics.RecordHedge(decision.ProviderName, hedgeDecision.ProviderName, info.Winner)
+		return result.response, attempt.req, attempt.decision, info, wasted, nil
+	}
+
+	s.metrics.RecordHedge(decision.ProviderName, hedgeDecision.ProviderName, info.Winner)
+	return nil, req, decision, info, 0, lastErr
+}
+
+// hedgedStream is the stream serving a request after hedging, with the
+// context and cancel function of its provider request.
+type hedgedStream struct {
+	chunks   <-chan models.StreamResponse
+	ctx      context.Context
+	cancel   context.CancelFunc
+	provider providers.Provider
+	name     string
+	req      models.ChatRequest
+}
+
+// hedgeStream waits up to the hedging delay for the first chunk of the
+// primary stream and, if none arrives, opens the request on the next best
+// provider. The stream sending the first chunk wins and the other is
+// cancelled; a stream that ends without a chunk loses. The returned stream
+// starts with its first chunk.
+func (s *Server) hedgeStream(r *http.Request, primary hedgedStream, available map[string]providers.Provider) hedgedStream {
+	timer := time.NewTimer(s.config.Hedging.Delay)
+	defer timer.Stop()
+	select {
+	case first, ok := <-primary.chunks:
+		if ok {
+			primary.chunks = prependChunk(primary.ctx, first, primary.chunks)
+		}
+		return primary
+	case <-timer.C:
+	case <-r.Context().Done():
+		return primary
+	}
+
+	routed := policies.RoutingDecision{ProviderName: primary.name, Model: primary.req.Model}
+	hedgeDecision, provider, err := s.hedgeRoute(r.Context(), primary.req, routed, available)
+	if err != nil {
+		s.logger.Debug("No provider to hedge with", zap.String("request_id", primary.req.RequestID), zap.Error(err))
+		return primary
+	}
+	hedgeReq := routedRequest(primary.req, hedgeDecision)
+	ctx, cancel := context.WithCancel(r.Context())
+	chunks, err := providers.OpenStream(ctx, provider, hedgeReq)
+	if err != nil {
+		cancel()
+		s.metrics.RecordProviderError(hedgeDecision.ProviderName, "stream_failed")
+		s.logger.Warn("Hedge stream failed", zap.String("provider", hedgeDecision.ProviderName), zap.Error(err))
+		return primary
+	}
+	hedge := hedgedStream{chunks: chunks, ctx: ctx, cancel: cancel, provider: provider, name: hedgeDecision.ProviderName, req: hedgeReq}
+	s.logger.Info("Hedging stream",
+		zap.String("request_id", primary.req.RequestID),
+		zap.String("primary", primary.name),
+		zap.String("hedge", hedge.name),
+		zap.Duration("delay", s.config.Hedging.Delay))
+
+	streams := []*hedgedStream{&primary, &hedge}
+	for open := len(streams); open > 0; {
+		select {
+		case first, ok := <-primary.chunks:
+			if !ok {
+				primary.chunks, open = nil, open-1
+				continue
+			}
+			return s.hedgeWinner(streams, 0, first)
+		case first, ok := <-hedge.chunks:
+			if !ok {
+				hedge.chunks, open = nil, open-1
+				continue
+			}
+			return s.hedgeWinner(streams, 1, first)
+		case <-r.Context().Done():
+			open = 0
+		}
+	}
+
+	// Both ended without a chunk, or the client went away
+	hedge.cancel()
+	s.metrics.RecordHedge(primary.name, hedge.name, hedgeWinnerNone)
+	ended := make(chan models.StreamResponse)
+	close(ended)
+	primary.chunks = ended
+	return primary
+}
+
+// hedgeWinner cancels the losing stream, accounts for its spend and returns
+// the winner starting with its first chunk.
+func (s *Server) hedgeWinner(streams []*hedgedStream, winner int, first models.StreamResponse) hedgedStream {
+	won, lost := streams[winner], streams[1-winner]
+	lost.cancel()
+	if lost.chunks != nil {
+		s.recordHedgeWaste(lost.name, lost.req)
+	}
+
+	outcome := hedgeWinnerPrimary
+	if winner == 1 {
+		outcome = hedgeWinnerHedge
+	}
+	s.metrics.RecordHedge(streams[0].name, streams[1].name, outcome)
+	won.chunks = prependChunk(won.ctx, first, won.chunks)
+	return *won
+}
+
+// recordHedgeWaste accounts for the spend of an attempt cancelled because the
+// other side of a hedge won. Its prompt is billed whether or not it produced
+// output, so the prompt's catalog cost is counted as spend; output generated
+// before the cancellation is not known and not counted. It returns the cost.
+func (s *Server) recordHedgeWaste(providerName string, req models.ChatRequest) float64 {
+	promptTokens := tokenizer.CountMessages(providerName, req.Model, req.Messages)
+	cost, found := s.modelCatalog.EstimateCost(providerName, req.Model, promptTokens, 0)
+	if !found || cost == 0 {
+		return 0
+	}
+	s.metrics.RecordSpend(providerName, req.Model, cost)
+	s.metrics.RecordHedgeWaste(providerName, cost)
+	return cost
+}
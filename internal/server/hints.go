@@ -0,0 +1,100 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/language"
+	"github.com/semantrix/semaroute/internal/models"
+	v1 "github.com/semantrix/semaroute/pkg/api/v1"
+)
+
+// Headers carrying routing hints, for clients that cannot add fields to the
+// request body. Hints in the body take precedence.
+const (
+	preferProvidersHeader  = "X-Semaroute-Prefer-Providers"  // comma-separated or repeated
+	excludeProvidersHeader = "X-Semaroute-Exclude-Providers" // comma-separated or repeated
+	maxCostHeader          = "X-Semaroute-Max-Cost"          // USD
+	maxLatencyHeader       = "X-Semaroute-Max-Latency-Ms"
+	requireStreamingHeader = "X-Semaroute-Require-Streaming"
+	residencyHeader        = "X-Semaroute-Residency"
+	priorityHeader         = "X-Semaroute-Priority" // high, normal, low or deferred
+	languageHeader         = "X-Semaroute-Language" // ISO 639-1 code
+)
+
+// headerRoutingHints returns the routing hints in a request's headers, or nil
+// if it has none.
+func headerRoutingHints(header http.Header) (*models.RoutingHints, error) {
+	var hints models.RoutingHints
+	found := false
+
+	if values := header.Values(preferProvidersHeader); len(values) > 0 {
+		hints.PreferProviders, found = splitList(values), true
+	}
+	if values := header.Values(excludeProvidersHeader); len(values) > 0 {
+		hints.ExcludeProviders, found = splitList(values), true
+	}
+	if value := header.Get(maxCostHeader); value != "" {
+		cost, err := strconv.ParseFloat(value, 64)
+		if err != nil || cost <= 0 {
+			return nil, fmt.Errorf("%s must be a positive number", maxCostHeader)
+		}
+		hints.MaxCost, found = cost, true
+	}
+	if value := header.Get(maxLatencyHeader); value != "" {
+		ms, err := strconv.Atoi(value)
+		if err != nil || ms <= 0 {
+			return nil, fmt.Errorf("%s must be a positive integer", maxLatencyHeader)
+		}
+		hints.MaxLatency, found = time.Duration(ms)*time.Millisecond, true
+	}
+	if value := header.Get(requireStreamingHeader); value != "" {
+		required, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false", requireStreamingHeader)
+		}
+		hints.RequireStreaming, found = required, true
+	}
+	if value := header.Get(residencyHeader); value != "" {
+		hints.Residency, found = value, true
+	}
+	if value := header.Get(priorityHeader); value != "" {
+		switch value {
+		case models.PriorityHigh, models.PriorityNormal, models.PriorityLow, models.PriorityDeferred:
+		default:
+			return nil, fmt.Errorf("%s must be %s, %s, %s or %s", priorityHeader,
+				models.PriorityHigh, models.PriorityNormal, models.PriorityLow, models.PriorityDeferred)
+		}
+		hints.Priority, found = value, true
+	}
+	if value := header.Get(languageHeader); value != "" {
+		if !language.Supported(value) {
+			return nil, fmt.Errorf("%s %q is not a supported language", languageHeader, value)
+		}
+		hints.Language, found = value, true
+	}
+
+	if !found {
+		return nil, nil
+	}
+	return &hints, nil
+}
+
+// convertRoutingHints converts the routing hints of an API request.
+func convertRoutingHints(hints *v1.RoutingHints) *models.RoutingHints {
+	if hints == nil {
+		return nil
+	}
+	return &models.RoutingHints{
+		PreferProviders:  hints.PreferProviders,
+		ExcludeProviders: hints.ExcludeProviders,
+		MaxCost:          hints.MaxCost,
+		MaxLatency:       time.Duration(hints.MaxLatencyMs) * time.Millisecond,
+		RequireStreaming: hints.RequireStreaming,
+		Residency:        hints.Residency,
+		Priority:         hints.Priority,
+		Language:         hints.Language,
+	}
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	v1 "github.com/semantrix/semaroute/pkg/api/v1"
+)
+
+// attemptLadderKey is the context key for the provider attempts of a request.
+type attemptLadderKey struct{}
+
+// attemptLadder records the provider attempts made for a chat completion,
+// including hedges and fallback hops, so a request every provider failed
+// can report what was tried.
+type attemptLadder struct {
+	start    time.Time
+	mutex    sync.Mutex
+	attempts []v1.ProviderAttempt
+}
+
+// withAttemptLadder returns a context carrying a new attempt ladder started now.
+func withAttemptLadder(ctx context.Context) (context.Context, *attemptLadder) {
+	ladder := &attemptLadder{start: time.Now()}
+	return context.WithValue(ctx, attemptLadderKey{}, ladder), ladder
+}
+
+// attemptLadderFrom returns the attempt ladder carried by ctx, or nil.
+func attemptLadderFrom(ctx context.Context) *attemptLadder {
+	ladder, _ := ctx.Value(attemptLadderKey{}).(*attemptLadder)
+	return ladder
+}
+
+// record adds an attempt with its outcome. It is safe to call on a nil
+// ladder.
+func (l *attemptLadder) record(attempt v1.ProviderAttempt, err error, elapsed time.Duration) {
+	if l == nil {
+		return
+	}
+	attempt.ElapsedMs = elapsed.Milliseconds()
+	if err != nil {
+		if attempt.ErrorClass == "" {
+			attempt.ErrorClass = errorClass(err)
+		}
+		attempt.Error = err.Error()
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.attempts = append(l.attempts, attempt)
+}
+
+// details returns the ladder for ErrorDetails.Details: the attempts in the
+// order they finished and the time spent since the ladder started.
+func (l *attemptLadder) details() map[string]interface{} {
+	if l == nil {
+		return nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return map[string]interface{}{
+		"attempts":      append([]v1.ProviderAttempt(nil), l.attempts...),
+		"total_time_ms": time.Since(l.start).Milliseconds(),
+	}
+}
+
+// errorClass classifies a failed provider attempt for triage: timeout,
+// canceled, rate_limited, auth, server_error, client_error, connection,
+// unavailable (nothing was sent) or unknown.
+func errorClass(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+
+	var providerErr *models.ProviderError
+	if errors.As(err, &providerErr) {
+		switch status := providerErr.StatusCode; {
+		case status == http.StatusTooManyRequests:
+			return "rate_limited"
+		case status == http.StatusUnauthorized || status == http.StatusForbidden:
+			return "auth"
+		case status >= 500:
+			return "server_error"
+		case status >= 400:
+			return "client_error"
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return "timeout"
+		}
+		return "connection"
+	}
+	return "unknown"
+}
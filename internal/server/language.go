@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/observability"
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/internal/router/policies"
+)
+
+// requiredLanguage returns the language a request's response must be in: the
+// request's language hint, else its tenant's language, else none.
+func (s *Server) requiredLanguage(ctx context.Context, req models.ChatRequest, tenantID string) string {
+	if hint := policies.RequestHints(ctx, req).Language; hint != "" {
+		return hint
+	}
+	if tenant, found := s.tenants.Get(tenantID); found {
+		return tenant.Language
+	}
+	return ""
+}
+
+// enforceLanguage checks that a completion is in the language required for
+// the request and retries it when not, on the same provider and then on the
+// next best one. It returns the response and the decision of the provider
+// that served it.
+func (s *Server) enforceLanguage(ctx context.Context, req models.ChatRequest, tenantID string, response *models.ChatResponse, decision policies.RoutingDecision, available map[string]providers.Provider) (*models.ChatResponse, policies.RoutingDecision) {
+	target := s.requiredLanguage(ctx, req, tenantID)
+	if target == "" {
+		return response, decision
+	}
+
+	provider := available[decision.ProviderName]
+	alternate := decision
+	response, servedBy := s.languageGuard.Enforce(ctx, decision.ProviderName, req, response, target,
+		func(ctx context.Context, req models.ChatRequest) (*models.ChatResponse, error) {
+			start := time.Now()
+			defer func() { observability.ProviderTimerFrom(ctx).Add(time.Since(start)) }()
+			return provider.CreateChatCompletion(ctx, req)
+		},
+		func(ctx context.Context, req models.ChatRequest) (string, *models.ChatResponse, error) {
+			next, nextProvider, err := s.hedgeRoute(ctx, req, decision, available)
+			if err != nil {
+				return "", nil, err
+			}
+			alternate = next
+			start := time.Now()
+			defer func() { observability.ProviderTimerFrom(ctx).Add(time.Since(start)) }()
+			response, err := nextProvider.CreateChatCompletion(ctx, routedRequest(req, next))
+			return next.ProviderName, response, err
+		})
+	if servedBy != decision.ProviderName {
+		return response, alternate
+	}
+	return response, decision
+}
@@ -0,0 +1,349 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/semantrix/semaroute/internal/adapters"
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/pkg/api/v1"
+)
+
+// anthropicRequest is a request body of the Anthropic-compatible messages
+// endpoint.
+type anthropicRequest struct {
+	Model         string               `json:"model"`
+	MaxTokens     int                  `json:"max_tokens"`
+	System        json.RawMessage      `json:"system"` // a string or text blocks
+	Messages      []anthropicMessage   `json:"messages"`
+	StopSequences []string             `json:"stop_sequences"`
+	Temperature   float64              `json:"temperature"`
+	TopP          float64              `json:"top_p"`
+	TopK          int                  `json:"top_k"`
+	Stream        bool                 `json:"stream"`
+	Tools         []anthropicTool      `json:"tools"`
+	ToolChoice    *anthropicToolChoice `json:"tool_choice"`
+	Thinking      *v1.Thinking         `json:"thinking"`
+	Metadata      anthropicRequestMeta `json:"metadata"`
+}
+
+// anthropicMessage is one message of an Anthropic request.
+type anthropicMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"` // a string or content blocks
+}
+
+// anthropicTool describes a tool the model may call.
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// anthropicToolChoice is auto, any, none, or a named tool.
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// anthropicRequestMeta is the request metadata.
+type anthropicRequestMeta struct {
+	UserID string `json:"user_id"`
+}
+
+// anthropicErrorResponse is an error in the Anthropic format.
+type anthropicErrorResponse struct {
+	Type  string `json:"type"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// handleMessages serves Anthropic-format clients. The request is converted to
+// a chat completion and routed like any other, so it may be served by any
+// provider; the response is converted back to an Anthropic message.
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
+	var anthropicReq anthropicRequest
+	if err := json.NewDecoder(r.Body).Decode(&anthropicReq); err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if anthropicReq.Stream {
+		writeAnthropicError(w, http.StatusBadRequest, "streaming is not supported on /v1/messages")
+		return
+	}
+
+	apiReq, err := convertAnthropicRequest(anthropicReq)
+	if err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	body, err := json.Marshal(apiReq)
+	if err != nil {
+		writeAnthropicError(w, http.StatusInternalServerError, "failed to encode request")
+		return
+	}
+
+	// Run the chat completion handler and capture its response
+	chatReq := r.Clone(r.Context())
+	chatReq.Body = io.NopCloser(bytes.NewReader(body))
+	chatReq.ContentLength = int64(len(body))
+	captured := &capturedResponse{header: w.Header(), status: http.StatusOK}
+	s.handleChatCompletion(captured, chatReq)
+
+	if captured.status != http.StatusOK {
+		writeAnthropicError(w, captured.status, capturedErrorMessage(captured.body.Bytes()))
+		return
+	}
+
+	var response models.ChatResponse
+	if err := json.Unmarshal(captured.body.Bytes(), &response); err != nil {
+		writeAnthropicError(w, http.StatusInternalServerError, "failed to decode response")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(adapters.ToAnthropic(&response))
+}
+
+// capturedResponse buffers a response body and status, sharing the headers
+// of the real response.
+type capturedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (c *capturedResponse) Header() http.Header { return c.header }
+
+func (c *capturedResponse) WriteHeader(status int) { c.status = status }
+
+func (c *capturedResponse) Write(data []byte) (int, error) { return c.body.Write(data) }
+
+// capturedErrorMessage extracts the message of an error response, which is
+// either a JSON error or plain text.
+func capturedErrorMessage(body []byte) string {
+	var errorResponse v1.ErrorResponse
+	if json.Unmarshal(body, &errorResponse) == nil && errorResponse.Error.Message != "" {
+		return errorResponse.Error.Message
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// writeAnthropicError writes an error response in the Anthropic format.
+func writeAnthropicError(w http.ResponseWriter, status int, message string) {
+	var errorResponse anthropicErrorResponse
+	errorResponse.Type = "error"
+	errorResponse.Error.Type = anthropicErrorType(status)
+	errorResponse.Error.Message = message
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Del("X-Content-Type-Options")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse)
+}
+
+// anthropicErrorType returns the Anthropic error type for an HTTP status.
+func anthropicErrorType(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case http.StatusServiceUnavailable:
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
+
+// convertAnthropicRequest converts an Anthropic request to a chat completion
+// request. Tool results in a user message become tool messages ahead of the
+// message's remaining content.
+func convertAnthropicRequest(req anthropicRequest) (v1.ChatCompletionRequest, error) {
+	if req.Model == "" {
+		return v1.ChatCompletionRequest{}, fmt.Errorf("model is required")
+	}
+	if req.MaxTokens <= 0 {
+		return v1.ChatCompletionRequest{}, fmt.Errorf("max_tokens must be positive")
+	}
+	if len(req.Messages) == 0 {
+		return v1.ChatCompletionRequest{}, fmt.Errorf("messages must not be empty")
+	}
+
+	apiReq := v1.ChatCompletionRequest{
+		Model:       req.Model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		TopK:        req.TopK,
+		Stop:        req.StopSequences,
+		User:        req.Metadata.UserID,
+		Thinking:    req.Thinking,
+	}
+
+	system, err := anthropicBlocks(req.System)
+	if err != nil {
+		return v1.ChatCompletionRequest{}, fmt.Errorf("invalid system: %w", err)
+	}
+	if text := blocksText(system); text != "" {
+		apiReq.Messages = append(apiReq.Messages, v1.Message{Role: "system", Content: v1.TextContent(text)})
+	}
+
+	for i, msg := range req.Messages {
+		blocks, err := anthropicBlocks(msg.Content)
+		if err != nil {
+			return v1.ChatCompletionRequest{}, fmt.Errorf("invalid content in message %d: %w", i, err)
+		}
+		switch msg.Role {
+		case "user":
+			messages, err := convertAnthropicUserMessage(blocks)
+			if err != nil {
+				return v1.ChatCompletionRequest{}, fmt.Errorf("message %d: %w", i, err)
+			}
+			apiReq.Messages = append(apiReq.Messages, messages...)
+		case "assistant":
+			apiReq.Messages = append(apiReq.Messages, convertAnthropicAssistantMessage(blocks))
+		default:
+			return v1.ChatCompletionRequest{}, fmt.Errorf("message %d has unsupported role %q", i, msg.Role)
+		}
+	}
+
+	for _, tool := range req.Tools {
+		apiReq.Tools = append(apiReq.Tools, v1.Tool{
+			Type: "function",
+			Function: v1.ToolFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		})
+	}
+
+	if choice := req.ToolChoice; choice != nil {
+		switch choice.Type {
+		case "auto", "none":
+			apiReq.ToolChoice = &v1.ToolChoice{Type: choice.Type}
+		case "any":
+			apiReq.ToolChoice = &v1.ToolChoice{Type: "required"}
+		case "tool":
+			apiReq.ToolChoice = &v1.ToolChoice{Type: "function", Function: &v1.ToolChoiceFunction{Name: choice.Name}}
+		default:
+			return v1.ChatCompletionRequest{}, fmt.Errorf("unsupported tool_choice type %q", choice.Type)
+		}
+	}
+	return apiReq, nil
+}
+
+// anthropicBlocks decodes content given as a string or as content blocks.
+func anthropicBlocks(raw json.RawMessage) ([]adapters.AnthropicContentBlock, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return []adapters.AnthropicContentBlock{{Type: "text", Text: text}}, nil
+	}
+	var blocks []adapters.AnthropicContentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// blocksText concatenates the text blocks, separated by blank lines.
+func blocksText(blocks []adapters.AnthropicContentBlock) string {
+	var texts []string
+	for _, block := range blocks {
+		if block.Type == "text" {
+			texts = append(texts, block.Text)
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// convertAnthropicUserMessage converts a user message to tool messages, one per
+// tool result, followed by a user message with any text and images.
+func convertAnthropicUserMessage(blocks []adapters.AnthropicContentBlock) ([]v1.Message, error) {
+	var messages []v1.Message
+	var parts []v1.ContentPart
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			parts = append(parts, v1.ContentPart{Type: "text", Text: block.Text})
+		case "image":
+			if block.Source == nil {
+				return nil, fmt.Errorf("image block has no source")
+			}
+			url := block.Source.URL
+			if block.Source.Type == "base64" {
+				url = "data:" + block.Source.MediaType + ";base64," + block.Source.Data
+			}
+			parts = append(parts, v1.ContentPart{Type: "image_url", ImageURL: &v1.ImageURL{URL: url}})
+		case "tool_result":
+			result, err := anthropicBlocks(block.Content)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tool_result content: %w", err)
+			}
+			messages = append(messages, v1.Message{
+				Role:       "tool",
+				Content:    v1.TextContent(blocksText(result)),
+				ToolCallID: block.ToolUseID,
+			})
+		default:
+			return nil, fmt.Errorf("unsupported content block type %q", block.Type)
+		}
+	}
+	if len(parts) > 0 {
+		messages = append(messages, v1.Message{Role: "user", Content: v1.Content{Parts: parts}})
+	}
+	return messages, nil
+}
+
+// convertAnthropicAssistantMessage converts an assistant message, keeping
+// thinking and its signature so they can be sent back to Anthropic.
+func convertAnthropicAssistantMessage(blocks []adapters.AnthropicContentBlock) v1.Message {
+	message := v1.Message{Role: "assistant"}
+	var text, reasoning strings.Builder
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "thinking":
+			reasoning.WriteString(block.Thinking)
+			message.ReasoningSignature = block.Signature
+		case "redacted_thinking":
+			message.RedactedReasoning = append(message.RedactedReasoning, block.Data)
+		case "tool_use":
+			arguments := string(block.Input)
+			if len(block.Input) == 0 {
+				arguments = "{}"
+			}
+			message.ToolCalls = append(message.ToolCalls, v1.ToolCall{
+				ID:   block.ID,
+				Type: "function",
+				Function: v1.ToolCallFunction{
+					Name:      block.Name,
+					Arguments: arguments,
+				},
+			})
+		}
+	}
+	message.Content = v1.TextContent(text.String())
+	message.ReasoningContent = reasoning.String()
+	return message
+}
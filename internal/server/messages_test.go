@@ -0,0 +1,55 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConvertAnthropicRequestHoistsSystem(t *testing.T) {
+	tests := []struct {
+		name       string
+		system     string
+		wantSystem string
+	}{
+		{"none", ``, ""},
+		{"string", `"Be brief."`, "Be brief."},
+		{"text blocks", `[{"type":"text","text":"Be brief."},{"type":"text","text":"Answer in French."}]`, "Be brief.\n\nAnswer in French."},
+		{"empty string", `""`, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := anthropicRequest{
+				Model:     "claude-sonnet-4",
+				MaxTokens: 100,
+				System:    json.RawMessage(test.system),
+				Messages:  []anthropicMessage{{Role: "user", Content: json.RawMessage(`"Hi"`)}},
+			}
+			apiReq, err := convertAnthropicRequest(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			messages := apiReq.Messages
+			if test.wantSystem != "" {
+				if len(messages) != 2 || messages[0].Role != "system" || messages[0].Content.Text() != test.wantSystem {
+					t.Fatalf("messages = %+v, want the system prompt %q first", messages, test.wantSystem)
+				}
+				messages = messages[1:]
+			}
+			if len(messages) != 1 || messages[0].Role != "user" || messages[0].Content.Text() != "Hi" {
+				t.Fatalf("messages = %+v, want the user message", messages)
+			}
+		})
+	}
+}
+
+func TestConvertAnthropicRequestRejectsInvalidSystem(t *testing.T) {
+	req := anthropicRequest{
+		Model:    "claude-sonnet-4",
+		System:   json.RawMessage(`{"text":"Be brief."}`),
+		Messages: []anthropicMessage{{Role: "user", Content: json.RawMessage(`"Hi"`)}},
+	}
+	if _, err := convertAnthropicRequest(req); err == nil {
+		t.Fatal("convertAnthropicRequest() accepted a system object")
+	}
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	"github.com/semantrix/semaroute/internal/audit"
+	"github.com/semantrix/semaroute/internal/migrate"
+	"github.com/semantrix/semaroute/internal/tenants"
+	"github.com/semantrix/semaroute/internal/usage"
+)
+
+// PersistentStores returns the enabled stores that keep files, with their
+// schema migrations.
+func PersistentStores(config *Config) []migrate.Store {
+	var stores []migrate.Store
+	if config.Usage.Enabled && config.Usage.Path != "" {
+		stores = append(stores, migrate.Store{Name: "usage", Path: config.Usage.Path, Migrations: usage.Migrations})
+	}
+	if config.Audit.Enabled && config.Audit.Path != "" {
+		stores = append(stores, migrate.Store{Name: "audit", Path: config.Audit.Path, Migrations: audit.Migrations})
+	}
+	if config.Tenancy.StateFile != "" {
+		stores = append(stores, migrate.Store{Name: "tenant states", Path: config.Tenancy.StateFile, Migrations: tenants.Migrations})
+	}
+	return stores
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/semantrix/semaroute/internal/audit"
+	"github.com/semantrix/semaroute/internal/tenants"
+	"github.com/semantrix/semaroute/internal/usage"
+)
+
+func TestPersistentStores(t *testing.T) {
+	config := &Config{}
+	if stores := PersistentStores(config); len(stores) != 0 {
+		t.Fatalf("PersistentStores() = %v with no store enabled", stores)
+	}
+
+	config.Usage = usage.Config{Enabled: true, Path: "/var/lib/semaroute/usage.jsonl"}
+	config.Audit = audit.Config{Enabled: true} // in memory only
+	config.Tenancy = tenants.Config{StateFile: "/var/lib/semaroute/tenants.json"}
+	stores := PersistentStores(config)
+	if len(stores) != 2 || stores[0].Name != "usage" || stores[1].Name != "tenant states" {
+		t.Fatalf("PersistentStores() = %v, want the usage and tenant state stores", stores)
+	}
+
+	// Every store's migrations are numbered from 1 without gaps
+	for _, store := range stores {
+		for i, migration := range store.Migrations {
+			if migration.Version != i+1 || migration.Apply == nil {
+				t.Errorf("%s migration %d has version %d", store.Name, i, migration.Version)
+			}
+		}
+	}
+	for i, migration := range audit.Migrations {
+		if migration.Version != i+1 || migration.Apply == nil {
+			t.Errorf("audit migration %d has version %d", i, migration.Version)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/catalog"
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/internal/tokenizer"
+	"github.com/semantrix/semaroute/pkg/api/v1"
+	"go.uber.org/zap"
+)
+
+// Model list statuses reported per provider.
+const (
+	modelListSucceeded = "succeeded"
+	modelListFailed    = "failed"
+)
+
+// modelList is a provider's model list and when it was fetched.
+type modelList struct {
+	models    []string
+	fetchedAt time.Time
+}
+
+// modelListCache keeps the last model list fetched successfully from each
+// provider, served when a later fetch fails.
+type modelListCache struct {
+	mutex sync.Mutex
+	lists map[string]modelList
+}
+
+// newModelListCache creates an empty cache.
+func newModelListCache() *modelListCache {
+	return &modelListCache{lists: make(map[string]modelList)}
+}
+
+// store records a successfully fetched list.
+func (c *modelListCache) store(provider string, list modelList) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.lists[provider] = list
+}
+
+// lastKnownGood returns the last list fetched successfully from a provider.
+func (c *modelListCache) lastKnownGood(provider string) (modelList, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	list, found := c.lists[provider]
+	return list, found
+}
+
+// retain drops the lists of providers that are no longer configured.
+func (c *modelListCache) retain(snapshot map[string]providers.Provider) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for name := range c.lists {
+		if _, exists := snapshot[name]; !exists {
+			delete(c.lists, name)
+		}
+	}
+}
+
+// providerModels is the outcome of fetching one provider's model list.
+type providerModels struct {
+	models []string
+	status v1.ProviderModelsStatus
+}
+
+// fetchModelLists fetches the model lists of all providers concurrently. A
+// provider whose fetch fails is served from its last known good list, marked
+// stale, or with no models if it never succeeded. Results are sorted by
+// provider name and each list by model, so pages are stable.
+func (s *Server) fetchModelLists(snapshot map[string]providers.Provider) []providerModels {
+	s.modelLists.retain(snapshot)
+
+	results := make([]providerModels, 0, len(snapshot))
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for name, provider := range snapshot {
+		wg.Add(1)
+		go func(name string, provider providers.Provider) {
+			defer wg.Done()
+			result := s.fetchModelList(name, provider)
+
+			mutex.Lock()
+			results = append(results, result)
+			mutex.Unlock()
+		}(name, provider)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].status.Provider < results[j].status.Provider })
+	return results
+}
+
+// fetchModelList fetches one provider's model list.
+func (s *Server) fetchModelList(name string, provider providers.Provider) providerModels {
+	models, err := provider.GetModels()
+	if err == nil {
+		models = append([]string(nil), models...)
+		sort.Strings(models)
+		list := modelList{models: models, fetchedAt: time.Now()}
+		s.modelLists.store(name, list)
+		return providerModels{
+			models: models,
+			status: v1.ProviderModelsStatus{
+				Provider:  name,
+				Status:    modelListSucceeded,
+				FetchedAt: list.fetchedAt,
+				Models:    len(models),
+			},
+		}
+	}
+
+	s.logger.Warn("Failed to get models from provider",
+		zap.String("provider", name),
+		zap.Error(err))
+	s.metrics.RecordModelListFailure(name)
+
+	status := v1.ProviderModelsStatus{
+		Provider: name,
+		Status:   modelListFailed,
+		Error:    err.Error(),
+	}
+	list, found := s.modelLists.lastKnownGood(name)
+	if !found {
+		return providerModels{status: status}
+	}
+	status.Stale = true
+	status.FetchedAt = list.fetchedAt
+	status.Models = len(list.models)
+	return providerModels{models: list.models, status: status}
+}
+
+// maxModelsPageSize bounds the limit query parameter of /v1/models.
+const maxModelsPageSize = 1000
+
+// modelFilter holds the search and pagination parameters of /v1/models.
+type modelFilter struct {
+	query        string          // case-insensitive substring of the model ID
+	providers    map[string]bool // empty matches every provider
+	capabilities []string        // all must be listed in the catalog
+	minContext   int
+	maxPrice     float64 // USD per 1K tokens, input and output; 0 for no limit
+	offset       int
+	limit        int // 0 for no pagination
+}
+
+// parseModelFilter reads the filter from query parameters. provider and
+// capability accept comma-separated lists and may be repeated.
+func parseModelFilter(query url.Values) (modelFilter, error) {
+	filter := modelFilter{
+		query:     strings.ToLower(query.Get("q")),
+		providers: make(map[string]bool),
+	}
+	for _, provider := range splitList(query["provider"]) {
+		filter.providers[provider] = true
+	}
+	filter.capabilities = splitList(query["capability"])
+
+	var err error
+	if filter.minContext, err = intParam(query, "min_context", 0, 1<<31-1); err != nil {
+		return modelFilter{}, err
+	}
+	if filter.offset, err = intParam(query, "offset", 0, 1<<31-1); err != nil {
+		return modelFilter{}, err
+	}
+	if filter.limit, err = intParam(query, "limit", 1, maxModelsPageSize); err != nil {
+		return modelFilter{}, err
+	}
+	if value := query.Get("max_price"); value != "" {
+		filter.maxPrice, err = strconv.ParseFloat(value, 64)
+		if err != nil || filter.maxPrice <= 0 {
+			return modelFilter{}, fmt.Errorf("max_price must be a positive number")
+		}
+	}
+	return filter, nil
+}
+
+// splitList flattens repeated and comma-separated query values.
+func splitList(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// intParam parses an optional integer query parameter within [min, max].
+func intParam(query url.Values, name string, min, max int) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < min || parsed > max {
+		return 0, fmt.Errorf("%s must be an integer between %d and %d", name, min, max)
+	}
+	return parsed, nil
+}
+
+// describeModel builds the model info from the catalog. The context window
+// falls back to the built-in size when the catalog does not set one.
+func (s *Server) describeModel(provider, model string) (v1.ModelInfo, catalog.ModelEntry, bool) {
+	info := v1.ModelInfo{
+		ID:       model,
+		Name:     model,
+		Provider: provider,
+		Type:     "chat_completion", // This could be more sophisticated
+	}
+
+	entry, found := s.modelCatalog.Lookup(provider, model)
+	if found {
+		info.ContextSize = entry.ContextWindow
+		info.SupportedFeatures = entry.Capabilities
+		info.InputPer1K = entry.InputPer1K
+		info.OutputPer1K = entry.OutputPer1K
+		info.BaseModel = entry.BaseModel
+		info.Owner = entry.Owner
+		info.TrainingDate = entry.TrainingDate
+	}
+	if info.ContextSize == 0 {
+		info.ContextSize = tokenizer.ContextWindow(model)
+	}
+	return info, entry, found
+}
+
+// matches reports whether a model passes the filter. Filters on capabilities
+// and price exclude models missing from the catalog, and min_context excludes
+// models whose context window is unknown.
+func (f modelFilter) matches(info v1.ModelInfo, entry catalog.ModelEntry, inCatalog bool) bool {
+	if len(f.providers) > 0 && !f.providers[info.Provider] {
+		return false
+	}
+	if f.query != "" && !strings.Contains(strings.ToLower(info.ID), f.query) {
+		return false
+	}
+	if f.minContext > 0 && info.ContextSize < f.minContext {
+		return false
+	}
+	if len(f.capabilities) > 0 || f.maxPrice > 0 {
+		if !inCatalog {
+			return false
+		}
+	}
+	for _, capability := range f.capabilities {
+		if !entry.HasCapability(capability) {
+			return false
+		}
+	}
+	if f.maxPrice > 0 && (entry.InputPer1K > f.maxPrice || entry.OutputPer1K > f.maxPrice) {
+		return false
+	}
+	return true
+}
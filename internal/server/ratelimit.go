@@ -0,0 +1,135 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/ratelimit"
+	"github.com/semantrix/semaroute/internal/router/policies"
+	"github.com/semantrix/semaroute/pkg/api/v1"
+)
+
+// rateLimitMiddleware rejects requests over the tenant's rate limits with 429
+// and a Retry-After header.
+func (s *Server) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.rateLimiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		decision := s.rateLimiter.Allow(r.Context(), tenantFrom(r).ID)
+		if decision.Allowed {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		writeRateLimited(w, r, decision)
+	})
+}
+
+// ceilingMiddleware rejects requests over the router-wide request rate with
+// 429 and a Retry-After header, whoever sends them.
+func (s *Server) ceilingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.ceiling == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		decision := s.ceiling.Admit(r.Context())
+		if decision.Allowed {
+			next.ServeHTTP(w, r)
+			return
+		}
+		writeRateLimited(w, r, decision)
+	})
+}
+
+// priorityMiddleware serves requests by priority tier once the router is
+// saturated: it holds a slot while the request is served, and rejects
+// requests shed or kept waiting too long by their tier with 429 and a
+// Retry-After header.
+func (s *Server) priorityMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.priorities == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		release, decision := s.priorities.Admit(r.Context(), requestPriority(r))
+		if !decision.Allowed {
+			writeRateLimited(w, r, decision)
+			return
+		}
+		defer release()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestPriority returns the priority tier of a request: that of its API key
+// or tenant, normal by default. The priority header can lower it, but not
+// raise it above that.
+func requestPriority(r *http.Request) string {
+	priority := models.PriorityNormal
+	if configured := tenantFrom(r).Priority; configured != "" {
+		priority = configured
+	}
+	if hints := policies.RequestInfoFrom(r.Context()).Hints; hints != nil && hints.Priority != "" {
+		if requested := ratelimit.Tier(hints.Priority); priorityRank(requested) > priorityRank(priority) {
+			priority = requested
+		}
+	}
+	return priority
+}
+
+// priorityRank orders priority tiers, 0 being the highest.
+func priorityRank(tier string) int {
+	switch tier {
+	case models.PriorityHigh:
+		return 0
+	case models.PriorityLow:
+		return 2
+	}
+	return 1
+}
+
+// admitStream takes a router-wide stream slot for a streamed response. When
+// none is free it writes a 429 and returns false; otherwise release must be
+// called once the stream ends.
+func (s *Server) admitStream(w http.ResponseWriter, r *http.Request) (release func(), ok bool) {
+	if s.ceiling == nil {
+		return func() {}, true
+	}
+
+	release, decision := s.ceiling.AdmitStream(r.Context())
+	if !decision.Allowed {
+		writeRateLimited(w, r, decision)
+		return nil, false
+	}
+	return release, true
+}
+
+// writeRateLimited writes the 429 for a denied rate limit decision.
+func writeRateLimited(w http.ResponseWriter, r *http.Request, decision ratelimit.Decision) {
+	retryAfter := int(math.Max(1, math.Ceil(decision.RetryAfter.Seconds())))
+	errorResponse := v1.ErrorResponse{
+		Error: v1.ErrorDetails{
+			Type:       "rate_limit_exceeded",
+			Message:    fmt.Sprintf("rate limit %q exceeded", decision.Limit),
+			StatusCode: http.StatusTooManyRequests,
+			Retryable:  true,
+		},
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(errorResponse)
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/semantrix/semaroute/internal/observability"
+	"github.com/semantrix/semaroute/internal/router/policies"
+)
+
+// RoutingPolicyUpdate is the body of PUT /admin/routing/policy. Policy
+// libraries are loaded at startup only, so a policy type they register must
+// already be loaded.
+type RoutingPolicyUpdate struct {
+	Type   string                 `json:"type"`
+	Config map[string]interface{} `json:"config"`
+}
+
+// observeRoutingPolicy logs and exports the weight changes made by error
+// budget reweighting, for policies with weights.
+func observeRoutingPolicy(policy policies.RoutingPolicy, logger *zap.Logger, metrics *observability.Metrics) {
+	reporter, ok := policy.(policies.WeightReporter)
+	if !ok {
+		return
+	}
+	reporter.OnWeightChange(func(change policies.WeightChange) {
+		logger.Info("Routing weight changed",
+			zap.String("provider", change.Provider),
+			zap.Float64("from", change.From),
+			zap.Float64("to", change.To),
+			zap.Float64("burn_rate", change.BurnRate),
+			zap.String("reason", change.Reason))
+		metrics.RecordWeightFactor(change.Provider, change.To)
+	})
+}
+
+// updateRoutingPolicy creates the policy described by update and swaps it in
+// for the policy in use, keeping the policy middleware. Requests decide with
+// either the old or the new policy, never a partly configured one. The old
+// policy is closed once in-flight requests have had time to finish. It
+// returns the configuration replaced.
+func (s *Server) updateRoutingPolicy(update RoutingPolicyUpdate) (RoutingPolicyConfig, error) {
+	if update.Type == "" {
+		return RoutingPolicyConfig{}, fmt.Errorf("policy type is required")
+	}
+	policy, err := policies.New(update.Type, update.Config)
+	if err != nil {
+		return RoutingPolicyConfig{}, err
+	}
+	observeRoutingPolicy(policy, s.logger, s.metrics)
+
+	s.policyMutex.Lock()
+	before := s.config.RoutingPolicy
+	previous := s.livePolicy.Swap(policy)
+	s.config.RoutingPolicy.Type = update.Type
+	s.config.RoutingPolicy.Config = update.Config
+	s.policyUpdated = time.Now()
+	s.policyMutex.Unlock()
+
+	if closer, ok := previous.(io.Closer); ok {
+		time.AfterFunc(providerDrainPeriod, func() {
+			if err := closer.Close(); err != nil {
+				s.logger.Error("Error closing replaced routing policy", zap.Error(err))
+			}
+		})
+	}
+	s.logger.Info("Routing policy updated",
+		zap.String("from", before.Type),
+		zap.String("to", update.Type),
+		zap.String("policy", policy.GetName()))
+	return before, nil
+}
@@ -3,31 +3,83 @@ package server
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
+	"runtime/debug"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/semantrix/semaroute/internal/alerting"
+	"github.com/semantrix/semaroute/internal/audit"
 	"github.com/semantrix/semaroute/internal/cache"
+	"github.com/semantrix/semaroute/internal/catalog"
+	"github.com/semantrix/semaroute/internal/continuation"
+	"github.com/semantrix/semaroute/internal/dataset"
+	"github.com/semantrix/semaroute/internal/gatekeeper"
+	"github.com/semantrix/semaroute/internal/invalidation"
+	"github.com/semantrix/semaroute/internal/language"
+	"github.com/semantrix/semaroute/internal/longform"
+	"github.com/semantrix/semaroute/internal/migrate"
 	"github.com/semantrix/semaroute/internal/observability"
 	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/internal/ratelimit"
 	"github.com/semantrix/semaroute/internal/router/health"
 	"github.com/semantrix/semaroute/internal/router/policies"
+	"github.com/semantrix/semaroute/internal/shadow"
+	"github.com/semantrix/semaroute/internal/tenants"
+	"github.com/semantrix/semaroute/internal/tools"
+	"github.com/semantrix/semaroute/internal/usage"
+	"github.com/semantrix/semaroute/pkg/plugin"
 	"go.uber.org/zap"
 )
 
+// providerDrainPeriod is how long a removed provider stays open for in-flight requests.
+const providerDrainPeriod = time.Minute
+
 // Server represents the main HTTP server for the semaroute service.
 type Server struct {
 	config        *Config
 	router        *chi.Mux
-	providers     map[string]providers.Provider
+	providers     *providers.ProviderSet
+	modelCatalog  *catalog.Catalog
+	modelLists    *modelListCache
+	equivalents   modelEquivalents
 	routingPolicy policies.RoutingPolicy
+	livePolicy    *policies.SwappablePolicy
+	policyMutex   sync.Mutex // serializes policy updates and guards config.RoutingPolicy
+	policyUpdated time.Time  // zero until the policy is replaced at runtime
 	healthChecker *health.HealthChecker
 	cache         cache.CacheClient
+	cacheKeys     *cache.KeyBuilder
+	invalidation  invalidation.Bus
+	rateLimiter   *ratelimit.Limiter
+	ceiling       *ratelimit.Ceiling      // nil when the router-wide ceiling is disabled
+	priorities    *ratelimit.PriorityGate // nil when priority tiers are disabled
+	tenants       *tenants.Registry
+	toolGuard     *tools.Guard
+	shadowStore   *shadow.Store
+	shadowSlots   chan struct{}
+	usageStore    *usage.Store
+	dataset       *dataset.Pipeline
+	auditLog      *audit.Log
+	tokenSigner   *gatekeeper.Signer
+	voucherLedger *gatekeeper.Ledger
+	selfMonitor   *observability.SelfMonitor
+	alerts        *alerting.Engine
+	breaker       *policies.CircuitBreaker
+	weights       *policies.ProviderWeights
+	residency     *policies.Residency
+	deferrals     *deferralQueue
+	continuer     *continuation.Continuer
+	languageGuard *language.Guard
+	orchestrator  *longform.Orchestrator
 	logger        *zap.Logger
 	metrics       *observability.Metrics
 	tracing       *observability.Tracing
@@ -36,28 +88,103 @@ type Server struct {
 
 // Config holds the server configuration.
 type Config struct {
+	// Configuration preset whose defaults were applied: default, dev, staging,
+	// prod-high-availability or edge
+	Profile string `mapstructure:"profile"`
+
 	Server struct {
 		Port            int           `mapstructure:"port"`
 		ReadTimeout     time.Duration `mapstructure:"read_timeout"`
 		WriteTimeout    time.Duration `mapstructure:"write_timeout"`
 		IdleTimeout     time.Duration `mapstructure:"idle_timeout"`
 		ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+
+		// Soft memory limit of the Go runtime, 0 = none
+		MemoryLimit cache.ByteSize `mapstructure:"memory_limit"`
 	} `mapstructure:"server"`
 
 	Providers map[string]providers.ProviderConfig `mapstructure:"providers"`
 
-	RoutingPolicy struct {
-		Type   string                 `mapstructure:"type"`
-		Config map[string]interface{} `mapstructure:"config"`
-	} `mapstructure:"routing_policy"`
+	Plugins providers.PluginsConfig `mapstructure:"plugins"`
+
+	Pricing catalog.Config `mapstructure:"pricing"`
+
+	// Virtual model names resolved to concrete models before routing
+	ModelAliases map[string][]AliasTarget `mapstructure:"model_aliases"`
+
+	// Groups of models that stand in for each other on providers with
+	// different model IDs, used when no provider serves the requested model
+	// and for fallback and failover
+	ModelEquivalents [][]string `mapstructure:"model_equivalents"`
+
+	// Fallback chains tried when a model's provider fails, keyed by model or alias
+	FallbackChains map[string][]FallbackHop `mapstructure:"fallback_chains"`
+
+	RoutingPolicy RoutingPolicyConfig `mapstructure:"routing_policy"`
+
+	// Middleware applied around the routing policy, outermost first
+	PolicyMiddleware []policies.MiddlewareConfig `mapstructure:"policy_middleware"`
+
+	// Per-provider circuit breakers applied inside the policy middleware
+	CircuitBreaker policies.CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+
+	// Residency tags and the provider regions requests with them may use
+	Residency policies.ResidencyConfig `mapstructure:"residency"`
 
 	HealthCheck struct {
 		Interval time.Duration `mapstructure:"interval"`
 		Timeout  time.Duration `mapstructure:"timeout"`
+
+		// Pre-warming of providers recovering from an outage
+		Prewarm health.PrewarmConfig `mapstructure:"prewarm"`
 	} `mapstructure:"health_check"`
 
 	Cache cache.CacheConfig `mapstructure:"cache"`
 
+	Invalidation invalidation.Config `mapstructure:"invalidation"`
+
+	RateLimit ratelimit.Config `mapstructure:"rate_limit"`
+
+	Tenancy tenants.Config `mapstructure:"tenancy"`
+
+	Tools tools.Config `mapstructure:"tools"`
+
+	Shadow shadow.Config `mapstructure:"shadow"`
+
+	Usage usage.Config `mapstructure:"usage"`
+
+	// Sampling of completions into a fine-tuning dataset
+	Dataset dataset.Config `mapstructure:"dataset"`
+
+	// Audit log of admin actions
+	Audit audit.Config `mapstructure:"audit"`
+
+	// Schema migrations of the usage, audit and tenant state files
+	Migrations migrate.Config `mapstructure:"migrations"`
+
+	Gatekeeper gatekeeper.Config `mapstructure:"gatekeeper"`
+
+	Continuation continuation.Config `mapstructure:"continuation"`
+
+	// Retrying of responses in another language than the tenant or request requires
+	LanguageEnforcement language.Config `mapstructure:"language_enforcement"`
+
+	// Stall watchdog of streamed completions
+	Streaming StreamingConfig `mapstructure:"streaming"`
+
+	// Reissuing of slow requests to a second provider
+	Hedging HedgingConfig `mapstructure:"hedging"`
+
+	// Rerouting of requests from rate-limited providers to the next candidate
+	Spillover SpilloverConfig `mapstructure:"spillover"`
+
+	// Waiting of deferred-priority requests for provider quota resets
+	Deferral DeferralConfig `mapstructure:"deferral"`
+
+	Longform longform.Config `mapstructure:"longform"`
+
+	Alerting alerting.Config `mapstructure:"alerting"`
+
 	Observability struct {
 		Logging observability.LoggerConfig  `mapstructure:"logging"`
 		Metrics observability.MetricsConfig `mapstructure:"metrics"`
@@ -65,6 +192,16 @@ type Config struct {
 	} `mapstructure:"observability"`
 }
 
+// RoutingPolicyConfig selects the routing policy.
+type RoutingPolicyConfig struct {
+	Type   string                 `mapstructure:"type"`
+	Config map[string]interface{} `mapstructure:"config"`
+
+	// Go plugin libraries loaded before the policy is created, which may
+	// register policy types
+	Libraries []string `mapstructure:"libraries"`
+}
+
 // NewServer creates a new server instance.
 func NewServer(config *Config) (*Server, error) {
 	// Initialize logger
@@ -73,6 +210,12 @@ func NewServer(config *Config) (*Server, error) {
 		return nil, fmt.Errorf("failed to create logger: %w", err)
 	}
 
+	// The garbage collector works harder as the heap nears the limit, which
+	// keeps small deployments such as edge sidecars within their budget
+	if config.Server.MemoryLimit > 0 {
+		debug.SetMemoryLimit(int64(config.Server.MemoryLimit))
+	}
+
 	// Initialize metrics
 	metrics, err := observability.NewMetrics(config.Observability.Metrics, logger)
 	if err != nil {
@@ -83,40 +226,307 @@ func NewServer(config *Config) (*Server, error) {
 	tracing := observability.NewTracing(config.Observability.Tracing, logger)
 
 	// Initialize cache
-	cacheClient := cache.NewMemoryCache(config.Cache)
+	cacheClient, err := cache.NewMemoryCache(config.Cache, metrics)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize cache: %w", err)
+	}
+
+	// Initialize cross-instance invalidation bus
+	invalidationBus, err := invalidation.NewBus(config.Invalidation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize invalidation bus: %w", err)
+	}
+
+	// Initialize request rate limiting
+	var rateLimiter *ratelimit.Limiter
+	if config.RateLimit.Enabled {
+		rateLimiter, err = ratelimit.NewLimiter(config.RateLimit, metrics)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize rate limiter: %w", err)
+		}
+	}
+	var ceiling *ratelimit.Ceiling
+	if config.RateLimit.Ceiling.Enabled {
+		ceiling, err = ratelimit.NewCeiling(config.RateLimit.Ceiling, metrics)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize router ceiling: %w", err)
+		}
+	}
+	var priorities *ratelimit.PriorityGate
+	if config.RateLimit.Priority.Enabled {
+		priorities, err = ratelimit.NewPriorityGate(config.RateLimit.Priority, metrics)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize priority tiers: %w", err)
+		}
+	}
+
+	// Bring the files of persistent stores to the current schema before
+	// they are opened
+	applied, err := migrate.Check(PersistentStores(config), config.Migrations.AutoApply)
+	for store, migrations := range applied {
+		for _, migration := range migrations {
+			logger.Info("Applied schema migration",
+				zap.String("store", store),
+				zap.Int("version", migration.Version),
+				zap.String("description", migration.Description))
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate stores: %w", err)
+	}
+
+	// Initialize tenant API keys
+	tenantRegistry, err := tenants.NewRegistry(config.Tenancy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tenants: %w", err)
+	}
+
+	// Initialize usage store
+	if _, err := config.Usage.AbortedCostPolicy(); err != nil {
+		return nil, fmt.Errorf("invalid usage configuration: %w", err)
+	}
+	var usageStore *usage.Store
+	if config.Usage.Enabled {
+		usageStore, err = usage.NewStore(config.Usage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize usage store: %w", err)
+		}
+	}
+
+	// Initialize dataset sampling
+	var datasetPipeline *dataset.Pipeline
+	if config.Dataset.Enabled {
+		datasetPipeline, err = dataset.NewPipeline(config.Dataset, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize dataset sampling: %w", err)
+		}
+	}
+
+	// Initialize audit log
+	var auditLog *audit.Log
+	if config.Audit.Enabled {
+		auditLog, err = audit.NewLog(config.Audit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize audit log: %w", err)
+		}
+	}
+
+	// Initialize tool execution guard
+	toolGuard, err := tools.NewGuard(config.Tools, logger, metrics)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tool configuration: %w", err)
+	}
 
 	// Initialize providers
-	providersMap, err := initializeProviders(config.Providers, logger)
+	providersMap, err := initializeProviders(config.Providers, config.Plugins, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize providers: %w", err)
 	}
+	if err := validateAliases(config.ModelAliases, providersMap); err != nil {
+		return nil, fmt.Errorf("invalid model aliases: %w", err)
+	}
+	if err := validateFallbackChains(config.FallbackChains, providersMap); err != nil {
+		return nil, fmt.Errorf("invalid fallback chains: %w", err)
+	}
+	equivalents, err := newModelEquivalents(config.ModelEquivalents)
+	if err != nil {
+		return nil, fmt.Errorf("invalid model equivalents: %w", err)
+	}
+	config.Shadow, err = validateShadowTargets(config.Shadow, providersMap)
+	if err != nil {
+		return nil, fmt.Errorf("invalid shadow configuration: %w", err)
+	}
+
+	// Initialize model catalog and attach it to providers for cost estimation
+	modelCatalog, err := catalog.NewCatalog(config.Pricing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pricing catalog: %w", err)
+	}
+	for _, provider := range providersMap {
+		if p, ok := provider.(interface{ SetCatalog(*catalog.Catalog) }); ok {
+			p.SetCatalog(modelCatalog)
+		}
+	}
+
+	// Providers are looked up by name from here on, e.g. to check the
+	// region of a routed provider
+	providerSet := providers.NewProviderSet(providersMap)
 
 	// Initialize routing policy
-	routingPolicy, err := initializeRoutingPolicy(config.RoutingPolicy, logger)
+	basePolicy, err := initializeRoutingPolicy(config.RoutingPolicy, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize routing policy: %w", err)
 	}
+	// Swappable inside the middleware, so PUT /admin/routing/policy replaces
+	// the policy and keeps the middleware
+	livePolicy := policies.NewSwappablePolicy(basePolicy)
+	var routingPolicy policies.RoutingPolicy = livePolicy
+	if len(config.PolicyMiddleware) > 0 {
+		middleware := make([]policies.Middleware, 0, len(config.PolicyMiddleware))
+		for _, middlewareConfig := range config.PolicyMiddleware {
+			m, err := policies.NewMiddleware(middlewareConfig)
+			if err != nil {
+				return nil, fmt.Errorf("failed to initialize policy middleware: %w", err)
+			}
+			middleware = append(middleware, m)
+		}
+		routingPolicy = policies.Chain(routingPolicy, middleware...)
+	}
+
+	// Keep tagged requests within their residency, keep models the catalog
+	// restricts to other tenants, models and providers the tenant's or API
+	// key's access lists deny, and providers lacking a capability the
+	// request needs out of routing, and apply the routing hints of each
+	// request whichever policy is in use
+	residency, err := policies.NewResidency(config.Residency, providerSet.Get)
+	if err != nil {
+		return nil, fmt.Errorf("invalid residency configuration: %w", err)
+	}
+	routingPolicy = policies.Chain(routingPolicy, residency, newModelAccessFilter(modelCatalog), newAccessListFilter(), policies.NewCapabilityFilter(), policies.NewHintFilter())
+
+	// Keep providers with an open circuit breaker out of routing. The breaker
+	// learns from every provider request the metrics record.
+	var breaker *policies.CircuitBreaker
+	if config.CircuitBreaker.Enabled {
+		breaker, err = policies.NewCircuitBreaker(config.CircuitBreaker)
+		if err != nil {
+			return nil, err
+		}
+		breaker.OnTransition(func(transition policies.BreakerTransition) {
+			logger.Warn("Circuit breaker state changed",
+				zap.String("provider", transition.Provider),
+				zap.String("from", transition.From),
+				zap.String("to", transition.To),
+				zap.String("reason", transition.Reason))
+			metrics.RecordBreakerState(transition.Provider, transition.To)
+		})
+		metrics.AddObserver(breaker)
+		routingPolicy = policies.Chain(routingPolicy, breaker)
+	}
+
+	// Initialize gatekeeper token signer
+	tokenSigner, err := gatekeeper.NewSigner(config.Gatekeeper)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize gatekeeper: %w", err)
+	}
+	switch config.Gatekeeper.Redemption {
+	case "":
+		config.Gatekeeper.Redemption = gatekeeper.RedeemProxy
+	case gatekeeper.RedeemKey, gatekeeper.RedeemProxy:
+	default:
+		return nil, fmt.Errorf("unknown gatekeeper redemption mode: %s", config.Gatekeeper.Redemption)
+	}
 
 	// Initialize health checker
 	healthChecker := health.NewHealthChecker(
+		providerSet,
 		config.HealthCheck.Interval,
 		config.HealthCheck.Timeout,
 		logger,
 	)
+	if config.HealthCheck.Prewarm.Enabled {
+		for name := range config.HealthCheck.Prewarm.CanaryModels {
+			if _, ok := providersMap[name]; !ok {
+				return nil, fmt.Errorf("canary model configured for unknown provider %s", name)
+			}
+		}
+		healthChecker.SetPrewarm(config.HealthCheck.Prewarm)
+		healthChecker.OnPrewarm(func(result health.PrewarmResult) {
+			metrics.RecordProviderPrewarm(result.Provider, result.Err == nil, result.Duration)
+		})
+	}
+
+	// Initialize router self-monitoring
+	selfMonitor := observability.NewSelfMonitor(0)
+	selfMonitor.RegisterQueue("tool_fanout", toolGuard.QueueDepth)
+
+	// Export concurrency usage of providers with an in-flight limit
+	for name, provider := range providersMap {
+		reporter, ok := provider.(providers.ConcurrencyReporter)
+		if !ok || reporter.GetConcurrencyStats().Limit == 0 {
+			continue
+		}
+		err := metrics.RegisterProviderConcurrency(name, reporter.GetConcurrencyStats().Limit,
+			func() int { return reporter.GetConcurrencyStats().InFlight },
+			func() int { return reporter.GetConcurrencyStats().Queued })
+		if err != nil {
+			return nil, fmt.Errorf("failed to register concurrency metrics for %s: %w", name, err)
+		}
+		selfMonitor.RegisterQueue("provider:"+name, func() int { return reporter.GetConcurrencyStats().Queued })
+	}
+
+	// Export response sizes, which show the bandwidth saved by compression
+	for name, provider := range providersMap {
+		reporter, ok := provider.(providers.BandwidthReporter)
+		if !ok {
+			continue
+		}
+		err := metrics.RegisterProviderBandwidth(name,
+			func() int64 { return reporter.GetBandwidthStats().WireBytes },
+			func() int64 { return reporter.GetBandwidthStats().DecodedBytes })
+		if err != nil {
+			return nil, fmt.Errorf("failed to register bandwidth metrics for %s: %w", name, err)
+		}
+	}
 
-	// Add providers to health checker
+	// Export the phases of outbound requests, to tell slow connects from slow
+	// generation
 	for name, provider := range providersMap {
-		healthChecker.AddProvider(name, provider)
+		if reporter, ok := provider.(providers.PhaseReporter); ok {
+			name := name
+			reporter.ObservePhases(func(phase string, duration time.Duration) {
+				metrics.RecordProviderPhase(name, phase, duration)
+			})
+		}
+	}
+
+	observeRoutingPolicy(basePolicy, logger, metrics)
+
+	// Initialize alert rules over provider events
+	var alertEngine *alerting.Engine
+	if config.Alerting.Enabled {
+		alertEngine, err = alerting.NewEngine(config.Alerting, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize alerting: %w", err)
+		}
+		metrics.AddObserver(alertEngine)
 	}
 
 	// Create server instance
 	server := &Server{
 		config:        config,
 		router:        chi.NewRouter(),
-		providers:     providersMap,
+		providers:     providerSet,
+		modelCatalog:  modelCatalog,
+		modelLists:    newModelListCache(),
 		routingPolicy: routingPolicy,
+		livePolicy:    livePolicy,
 		healthChecker: healthChecker,
 		cache:         cacheClient,
+		cacheKeys:     cache.NewKeyBuilder(config.Cache.Key),
+		invalidation:  invalidationBus,
+		rateLimiter:   rateLimiter,
+		ceiling:       ceiling,
+		priorities:    priorities,
+		tenants:       tenantRegistry,
+		toolGuard:     toolGuard,
+		shadowStore:   shadow.NewStore(config.Shadow),
+		shadowSlots:   make(chan struct{}, config.Shadow.MaxConcurrent),
+		usageStore:    usageStore,
+		dataset:       datasetPipeline,
+		auditLog:      auditLog,
+		tokenSigner:   tokenSigner,
+		voucherLedger: gatekeeper.NewLedger(),
+		selfMonitor:   selfMonitor,
+		alerts:        alertEngine,
+		breaker:       breaker,
+		weights:       policies.NewProviderWeights(),
+		residency:     residency,
+		equivalents:   equivalents,
+		deferrals:     newDeferralQueue(config.Deferral),
+		continuer:     continuation.NewContinuer(config.Continuation, metrics),
+		languageGuard: language.NewGuard(config.LanguageEnforcement, metrics),
+		orchestrator:  longform.NewOrchestrator(config.Longform),
 		logger:        logger,
 		metrics:       metrics,
 		tracing:       tracing,
@@ -149,7 +559,7 @@ func (s *Server) setupRoutes() {
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", overheadHeader},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -159,19 +569,72 @@ func (s *Server) setupRoutes() {
 
 	// API v1 routes
 	s.router.Route("/v1", func(r chi.Router) {
-		r.Post("/chat/completions", s.handleChatCompletion)
-		r.Get("/models", s.handleGetModels)
-		r.Get("/routing/info", s.handleGetRoutingInfo)
-		r.Get("/metrics", s.handleGetMetrics)
+		// Before anything that identifies the client, to shed load cheaply
+		r.Use(s.ceilingMiddleware)
+
+		// The voucher is the bearer token here, not a tenant API key; the
+		// handler rate limits the voucher's tenant
+		r.Post("/vouchers/proxy/chat/completions", s.handleVoucherProxy)
+
+		r.Group(func(r chi.Router) {
+			r.Use(s.tenantMiddleware)
+			r.Use(s.rateLimitMiddleware)
+
+			// Inference needs chat:write
+			r.Group(func(r chi.Router) {
+				r.Use(requireScope(tenants.ScopeChatWrite))
+				r.Use(s.priorityMiddleware)
+				r.Post("/chat/completions", s.handleChatCompletion)
+				r.Post("/messages", s.handleMessages)
+				r.Post("/completions", s.handleCompletion)
+				r.Post("/route", s.handleRoute)
+				r.Post("/vouchers/redeem", s.handleRedeemVoucher)
+				r.Post("/embeddings", s.handleEmbeddings)
+				r.Post("/images/generations", s.handleImageGeneration)
+				r.Post("/audio/transcriptions", s.handleTranscription)
+				r.Post("/audio/speech", s.handleSpeech)
+				r.Post("/documents", s.handleGenerateDocument)
+				r.Post("/tokenize", s.handleTokenize)
+			})
+
+			// Read-only endpoints need models:read
+			r.Group(func(r chi.Router) {
+				r.Use(requireScope(tenants.ScopeModelsRead))
+				r.Get("/models", s.handleGetModels)
+				r.Get("/routing/info", s.handleGetRoutingInfo)
+				r.Post("/routing/explain", s.handleExplainRoute)
+				r.Get("/metrics", s.handleGetMetrics)
+				r.Get("/usage/summary", s.handleUsageSummary)
+				r.Get("/usage/daily", s.handleUsageDaily)
+				r.Get("/usage/top-models", s.handleUsageTopModels)
+			})
+		})
 	})
 
 	// Admin routes
 	s.router.Route("/admin", func(r chi.Router) {
+		r.Use(s.adminAuthMiddleware)
 		r.Get("/providers", s.handleGetProviders)
 		r.Get("/providers/{name}/health", s.handleGetProviderHealth)
 		r.Post("/providers/{name}/health-check", s.handleForceHealthCheck)
+		r.Patch("/providers/{name}/weight", s.handleSetProviderWeight)
 		r.Get("/routing/policy", s.handleGetRoutingPolicy)
 		r.Put("/routing/policy", s.handleUpdateRoutingPolicy)
+		r.Get("/routing/weights", s.handleGetRoutingWeights)
+		r.Get("/routing/topology", s.handleGetRoutingTopology)
+		r.Get("/routing/breakers", s.handleGetCircuitBreakers)
+		r.Get("/quotas", s.handleGetQuotas)
+		r.Get("/pricing", s.handleGetPricing)
+		r.Post("/pricing/reload", s.handleReloadPricing)
+		r.Post("/cache/purge", s.handlePurgeCache)
+		r.Get("/shadow/report", s.handleGetShadowReports)
+		r.Get("/shadow/report/{provider}", s.handleGetShadowReport)
+		r.Get("/self", s.handleGetSelf)
+		r.Get("/alerts", s.handleGetAlerts)
+		r.Get("/dataset", s.handleGetDataset)
+		r.Get("/tenants", s.handleGetTenants)
+		r.Put("/tenants/{id}/state", s.handleSetTenantState)
+		r.Get("/audit", s.handleGetAudit)
 	})
 }
 
@@ -180,16 +643,23 @@ func (s *Server) observabilityMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
-		// Start tracing span
+		// Start tracing span; attributes are only built when tracing is on
 		ctx, span := s.tracing.StartSpan(r.Context(), "http_request")
 		defer span.End()
 
 		// Add request attributes
-		s.tracing.SetAttributes(ctx, map[string]string{
-			"http.method":     r.Method,
-			"http.url":        r.URL.String(),
-			"http.user_agent": r.UserAgent(),
-		})
+		if s.tracing.IsEnabled() {
+			s.tracing.SetAttributes(ctx, map[string]string{
+				"http.method":     r.Method,
+				"http.url":        r.URL.String(),
+				"http.user_agent": r.UserAgent(),
+			})
+		}
+
+		// Track time spent in providers so the router's own overhead can be derived
+		ctx, providerTimer := observability.WithProviderTimer(ctx)
+		s.selfMonitor.RequestStarted()
+		s.metrics.RecordInFlightRequests(s.selfMonitor.InFlight())
 
 		// Create response writer wrapper for status code
 		wrappedWriter := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
@@ -200,15 +670,40 @@ func (s *Server) observabilityMiddleware(next http.Handler) http.Handler {
 		// Record metrics
 		duration := time.Since(start)
 		s.metrics.RecordRequest(r.Method, r.URL.Path, wrappedWriter.statusCode, duration)
+		overhead := duration - providerTimer.Total()
+		s.selfMonitor.RequestFinished(overhead)
+		s.metrics.RecordRoutingOverhead(r.URL.Path, overhead)
+		s.metrics.RecordInFlightRequests(s.selfMonitor.InFlight())
 
 		// Add response attributes
-		s.tracing.SetAttributes(ctx, map[string]string{
-			"http.status_code": fmt.Sprintf("%d", wrappedWriter.statusCode),
-			"http.duration_ms": fmt.Sprintf("%d", duration.Milliseconds()),
-		})
+		if s.tracing.IsEnabled() {
+			s.tracing.SetAttributes(ctx, map[string]string{
+				"http.status_code": fmt.Sprintf("%d", wrappedWriter.statusCode),
+				"http.duration_ms": fmt.Sprintf("%d", duration.Milliseconds()),
+			})
+		}
 	})
 }
 
+// overheadHeader carries the latency added by the router, in milliseconds.
+const overheadHeader = "X-Semaroute-Overhead-Ms"
+
+// tenantHeader identifies the tenant of a request made without a tenant API key.
+const tenantHeader = "X-Semaroute-Tenant"
+
+// deterministicHeader asks for a request to be routed in deterministic mode.
+const deterministicHeader = "X-Semaroute-Deterministic"
+
+// cacheHeader reports whether a response was served from the response cache.
+const cacheHeader = "X-Semaroute-Cache"
+
+// setOverheadHeader reports the router's overhead so far on the response. It
+// must be called before the response header is written.
+func setOverheadHeader(w http.ResponseWriter, r *http.Request) {
+	overhead := observability.ProviderTimerFrom(r.Context()).Overhead()
+	w.Header().Set(overheadHeader, strconv.FormatFloat(float64(overhead)/float64(time.Millisecond), 'f', 3, 64))
+}
+
 // responseWriter wraps http.ResponseWriter to capture status code.
 type responseWriter struct {
 	http.ResponseWriter
@@ -220,6 +715,13 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Flush implements http.Flusher so streaming responses reach the client immediately.
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 // Start starts the server and begins accepting requests.
 func (s *Server) Start() error {
 	// Start health checker
@@ -236,9 +738,28 @@ func (s *Server) Start() error {
 		}()
 	}
 
+	// Start evaluating alert rules
+	if s.alerts != nil {
+		s.alerts.Start()
+	}
+
+	// Start writing dataset samples
+	if s.dataset != nil {
+		s.dataset.Start()
+	}
+
+	// Apply purges and reloads published by other replicas
+	s.invalidation.Subscribe(s.applyInvalidation)
+
+	// Preload the response cache before accepting traffic
+	if s.config.Cache.Responses && s.config.Cache.Warm.Enabled {
+		s.warmCache(context.Background())
+	}
+
 	s.logger.Info("Starting semaroute server",
+		zap.String("profile", s.config.Profile),
 		zap.Int("port", s.config.Server.Port),
-		zap.Int("providers", len(s.providers)))
+		zap.Int("providers", s.providers.Len()))
 
 	// Start server in goroutine
 	go func() {
@@ -267,13 +788,58 @@ func (s *Server) Stop() error {
 		return err
 	}
 
+	// Stop evaluating alert rules
+	if s.alerts != nil {
+		s.alerts.Stop()
+	}
+
+	// Stop receiving invalidations
+	if err := s.invalidation.Close(); err != nil {
+		s.logger.Error("Error closing invalidation bus", zap.Error(err))
+	}
+
+	// Stop rate limit reconciliation
+	if s.rateLimiter != nil {
+		if err := s.rateLimiter.Close(); err != nil {
+			s.logger.Error("Error closing rate limiter", zap.Error(err))
+		}
+	}
+
 	// Close cache
 	if err := s.cache.Close(); err != nil {
 		s.logger.Error("Error closing cache", zap.Error(err))
 	}
 
+	// Close usage store
+	if s.usageStore != nil {
+		if err := s.usageStore.Close(); err != nil {
+			s.logger.Error("Error closing usage store", zap.Error(err))
+		}
+	}
+
+	// Write the remaining dataset samples
+	if s.dataset != nil {
+		if err := s.dataset.Stop(); err != nil {
+			s.logger.Error("Error closing dataset sink", zap.Error(err))
+		}
+	}
+
+	// Close audit log
+	if s.auditLog != nil {
+		if err := s.auditLog.Close(); err != nil {
+			s.logger.Error("Error closing audit log", zap.Error(err))
+		}
+	}
+
+	// Stop routing policy plugins
+	if closer, ok := unwrapPolicy(s.routingPolicy).(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			s.logger.Error("Error closing routing policy", zap.Error(err))
+		}
+	}
+
 	// Close providers
-	for name, provider := range s.providers {
+	for name, provider := range s.providers.Snapshot() {
 		if err := provider.Close(); err != nil {
 			s.logger.Error("Error closing provider", zap.String("provider", name), zap.Error(err))
 		}
@@ -302,54 +868,173 @@ func (s *Server) GetRouter() *chi.Mux {
 	return s.router
 }
 
-// GetProviders returns the providers map for testing purposes.
+// GetProviders returns a snapshot of the providers for testing purposes.
 func (s *Server) GetProviders() map[string]providers.Provider {
-	return s.providers
+	return s.providers.Snapshot()
+}
+
+// UsePolicyMiddleware adds middleware around the routing policy, inside any
+// configured middleware. It must be called before the server starts.
+func (s *Server) UsePolicyMiddleware(middleware ...policies.Middleware) {
+	s.routingPolicy = policies.Chain(s.routingPolicy, middleware...)
+}
+
+// AddProvider adds or replaces a provider at runtime. A replaced provider is
+// closed once requests that may still hold it have had time to finish.
+func (s *Server) AddProvider(name string, provider providers.Provider) {
+	if p, ok := provider.(interface{ SetCatalog(*catalog.Catalog) }); ok {
+		p.SetCatalog(s.modelCatalog)
+	}
+
+	if previous, replaced := s.providers.Put(name, provider); replaced {
+		s.closeProviderLater(name, previous)
+	}
+	s.logger.Info("Provider added", zap.String("provider", name))
+}
+
+// RemoveProvider removes a provider at runtime. It stops receiving new
+// requests immediately and is closed after a drain period.
+func (s *Server) RemoveProvider(name string) bool {
+	provider, removed := s.providers.Remove(name)
+	if !removed {
+		return false
+	}
+
+	s.closeProviderLater(name, provider)
+	s.logger.Info("Provider removed", zap.String("provider", name))
+	return true
+}
+
+// closeProviderLater closes a provider that is no longer in the set, after
+// in-flight requests holding an older snapshot have had time to complete.
+func (s *Server) closeProviderLater(name string, provider providers.Provider) {
+	time.AfterFunc(providerDrainPeriod, func() {
+		if err := provider.Close(); err != nil {
+			s.logger.Error("Error closing provider", zap.String("provider", name), zap.Error(err))
+		}
+	})
 }
 
 // initializeProviders creates and configures all provider instances.
-func initializeProviders(configs map[string]providers.ProviderConfig, logger *zap.Logger) (map[string]providers.Provider, error) {
+func initializeProviders(configs map[string]providers.ProviderConfig, pluginsConfig providers.PluginsConfig, logger *zap.Logger) (map[string]providers.Provider, error) {
 	providersMap := make(map[string]providers.Provider)
+	unknown := make(map[string]bool)
 
 	for name, config := range configs {
 		if !config.Enabled {
 			continue
 		}
 
+		if err := providers.ValidateQuotas(config.Quotas); err != nil {
+			return nil, fmt.Errorf("invalid quotas of provider %s: %w", name, err)
+		}
+
 		var provider providers.Provider
+		var err error
 
 		switch name {
 		case "openai":
-			provider = providers.NewOpenAIProvider(config)
+			provider, err = providers.NewOpenAIProvider(config)
 		case "anthropic":
-			provider = providers.NewAnthropicProvider(config)
+			provider, err = providers.NewAnthropicProvider(config)
+		case "watsonx":
+			provider, err = providers.NewWatsonxProvider(config)
 		default:
-			logger.Warn("Unknown provider type", zap.String("provider", name))
+			// May be served by a plugin
+			unknown[name] = true
 			continue
 		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create provider %s: %w", name, err)
+		}
 
 		providersMap[name] = provider
 		logger.Info("Initialized provider", zap.String("name", name))
 	}
 
+	if pluginsConfig.Directory != "" {
+		initializePluginProviders(pluginsConfig, configs, providersMap, logger)
+	}
+
+	for name := range unknown {
+		if _, loaded := providersMap[name]; !loaded {
+			logger.Warn("Unknown provider type", zap.String("provider", name))
+		}
+	}
+
 	return providersMap, nil
 }
 
-// initializeRoutingPolicy creates and configures the routing policy.
-func initializeRoutingPolicy(config struct {
-	Type   string                 `mapstructure:"type"`
-	Config map[string]interface{} `mapstructure:"config"`
-}, logger *zap.Logger) (policies.RoutingPolicy, error) {
-	switch config.Type {
-	case "cost_based":
-		return policies.NewCostBasedPolicy(), nil
-	case "failover":
-		// Extract failover configuration
-		primary, _ := config.Config["primary_provider"].(string)
-		backups, _ := config.Config["backup_providers"].([]string)
-		return policies.NewFailoverPolicy(primary, backups), nil
-	default:
-		logger.Warn("Unknown routing policy, using cost-based", zap.String("policy", config.Type))
-		return policies.NewCostBasedPolicy(), nil
+// initializePluginProviders launches provider plugins from the plugin directory and adds them to providersMap.
+func initializePluginProviders(pluginsConfig providers.PluginsConfig, configs map[string]providers.ProviderConfig, providersMap map[string]providers.Provider, logger *zap.Logger) {
+	paths, err := plugin.Discover(pluginsConfig.Directory)
+	if err != nil {
+		logger.Warn("Failed to read plugin directory",
+			zap.String("directory", pluginsConfig.Directory),
+			zap.Error(err))
+		return
+	}
+
+	for _, path := range paths {
+		client, err := plugin.Start(path, pluginsConfig.HandshakeTimeout)
+		if err != nil {
+			logger.Error("Failed to start provider plugin", zap.String("path", path), zap.Error(err))
+			continue
+		}
+
+		name := client.Name()
+		config, configured := configs[name]
+		if configured && !config.Enabled {
+			logger.Info("Provider plugin disabled by configuration", zap.String("name", name))
+			client.Kill()
+			continue
+		}
+		if _, exists := providersMap[name]; exists {
+			logger.Error("Provider plugin name conflicts with an existing provider",
+				zap.String("name", name),
+				zap.String("path", path))
+			client.Kill()
+			continue
+		}
+
+		provider, err := providers.NewPluginProvider(client, config)
+		if err != nil {
+			logger.Error("Failed to initialize provider plugin", zap.String("path", path), zap.Error(err))
+			client.Kill()
+			continue
+		}
+
+		providersMap[name] = provider
+		logger.Info("Initialized provider plugin", zap.String("name", name), zap.String("path", path))
+	}
+}
+
+// unwrapPolicy returns the routing policy in use inside its policy
+// middleware.
+func unwrapPolicy(policy policies.RoutingPolicy) policies.RoutingPolicy {
+	if chained, ok := policy.(*policies.ChainedPolicy); ok {
+		policy = chained.Unwrap()
+	}
+	if swappable, ok := policy.(*policies.SwappablePolicy); ok {
+		policy = swappable.Current()
+	}
+	return policy
+}
+
+// initializeRoutingPolicy creates the configured routing policy from the
+// policy registry.
+func initializeRoutingPolicy(config RoutingPolicyConfig, logger *zap.Logger) (policies.RoutingPolicy, error) {
+	if err := policies.LoadLibraries(config.Libraries); err != nil {
+		return nil, err
+	}
+	for _, path := range config.Libraries {
+		logger.Info("Loaded policy library", zap.String("path", path))
+	}
+
+	policy, err := policies.New(config.Type, config.Config)
+	if err != nil {
+		return nil, err
 	}
+	logger.Info("Initialized routing policy", zap.String("policy", config.Type))
+	return policy, nil
 }
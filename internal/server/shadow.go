@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/internal/shadow"
+)
+
+// validateShadowTargets fills in the shadow defaults and checks that shadow
+// targets and the embedding provider are configured providers.
+func validateShadowTargets(config shadow.Config, configured map[string]providers.Provider) (shadow.Config, error) {
+	config, err := config.WithDefaults()
+	if err != nil {
+		return config, err
+	}
+	for _, target := range config.Targets {
+		if _, ok := configured[target.Provider]; !ok {
+			return config, fmt.Errorf("unknown shadow provider %s", target.Provider)
+		}
+	}
+	if config.EmbeddingProvider != "" {
+		if _, ok := configured[config.EmbeddingProvider]; !ok {
+			return config, fmt.Errorf("unknown shadow embedding provider %s", config.EmbeddingProvider)
+		}
+	}
+	return config, nil
+}
+
+// mirrorShadow sends a copy of a served request to the shadow targets whose
+// share it falls in, without delaying the response. Each shadow response is
+// compared with the primary's, recorded in the shadow store and discarded.
+// Mirrored requests beyond max_concurrent are dropped rather than queued.
+func (s *Server) mirrorShadow(ctx context.Context, req models.ChatRequest, primary shadow.Sample) {
+	embed := s.shadowEmbedder(ctx, req)
+	for _, target := range s.config.Shadow.Targets {
+		if target.Provider == primary.Provider || rand.Float64()*100 >= target.Percentage {
+			continue
+		}
+		provider, exists := s.providers.Get(target.Provider)
+		if !exists {
+			continue
+		}
+		// Mirrored requests keep the residency and the access lists of the
+		// original
+		if !s.residency.Allows(ctx, req, provider) || !allowedBy(accessListsFrom(ctx), target.Provider, shadowModel(req, target)) {
+			continue
+		}
+
+		select {
+		case s.shadowSlots <- struct{}{}:
+		default:
+			s.metrics.RecordShadowRequest(target.Provider, "dropped")
+			continue
+		}
+		go func(target shadow.Target, provider providers.Provider) {
+			defer func() { <-s.shadowSlots }()
+			s.runShadow(req, primary, target, provider, embed)
+		}(target, provider)
+	}
+}
+
+// shadowModel returns the model a request is mirrored to the target with.
+func shadowModel(req models.ChatRequest, target shadow.Target) string {
+	if target.Model != "" {
+		return target.Model
+	}
+	return req.Model
+}
+
+// runShadow sends one mirrored request and records its comparison, embedding
+// the responses with embed unless it is nil.
+func (s *Server) runShadow(req models.ChatRequest, primary shadow.Sample, target shadow.Target, provider providers.Provider, embed shadow.EmbedFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Shadow.Timeout)
+	defer cancel()
+
+	req.Model = shadowModel(req, target)
+	req.Stream = false
+
+	start := time.Now()
+	response, err := provider.CreateChatCompletion(ctx, req)
+	sample := shadow.Sample{
+		Provider: target.Provider,
+		Model:    req.Model,
+		Response: response,
+		Latency:  time.Since(start),
+		Err:      err,
+	}
+	if err == nil {
+		sample.Cost = s.responseCost(target.Provider, response)
+	}
+
+	comparison := shadow.Compare(ctx, req.RequestID, primary, sample, embed)
+	s.shadowStore.Add(comparison)
+
+	outcome := "mismatch"
+	switch {
+	case err != nil:
+		outcome = "error"
+		s.logger.Debug("Shadow request failed",
+			zap.String("request_id", req.RequestID),
+			zap.String("provider", target.Provider),
+			zap.String("model", req.Model),
+			zap.Error(err))
+	case comparison.ExactMatch:
+		outcome = "match"
+	}
+	s.metrics.RecordShadowRequest(target.Provider, outcome)
+	if err == nil {
+		s.metrics.RecordShadowResponse(target.Provider, sample.Latency, sample.Cost, comparison.EmbeddingCosine)
+	}
+}
+
+// responseCost returns the catalog cost of a response, or 0 if the model is
+// not priced.
+func (s *Server) responseCost(providerName string, response *models.ChatResponse) float64 {
+	cost, _ := s.modelCatalog.EstimateCost(providerName, response.Model,
+		response.Usage.PromptTokens, response.Usage.CompletionTokens)
+	return cost
+}
+
+// shadowEmbedder returns the function embedding the responses to req for
+// comparison, or nil when no embedding provider is configured. Responses are
+// not embedded by a provider outside the request's residency, or one the
+// access lists of its tenant or API key deny.
+func (s *Server) shadowEmbedder(ctx context.Context, req models.ChatRequest) shadow.EmbedFunc {
+	provider, exists := s.providers.Get(s.config.Shadow.EmbeddingProvider)
+	if s.config.Shadow.EmbeddingProvider == "" || !exists {
+		return nil
+	}
+	if !s.residency.Allows(ctx, req, provider) ||
+		!allowedBy(accessListsFrom(ctx), s.config.Shadow.EmbeddingProvider, s.config.Shadow.EmbeddingModel) {
+		return nil
+	}
+
+	return func(ctx context.Context, texts []string) ([][]float64, error) {
+		response, err := providers.Embed(ctx, provider, models.EmbeddingRequest{
+			Model: s.config.Shadow.EmbeddingModel,
+			Input: texts,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		vectors := make([][]float64, len(texts))
+		for _, embedding := range response.Data {
+			if embedding.Index >= 0 && embedding.Index < len(vectors) {
+				vectors[embedding.Index] = embedding.Embedding
+			}
+		}
+		return vectors, nil
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/internal/router/policies"
+	"github.com/semantrix/semaroute/internal/shadow"
+	"github.com/semantrix/semaroute/internal/tenants"
+)
+
+// newShadowServer returns a server mirroring every request to a shadow
+// anthropic provider, hosted in us-east, whose requests block until the
+// test ends. Its embedding provider is openai, hosted in eu-west.
+func newShadowServer(t *testing.T) *Server {
+	t.Helper()
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+
+	shadowProvider, err := providers.NewOpenAIProvider(providers.ProviderConfig{Name: "anthropic", APIKey: "sk-shadow", BaseURL: upstream.URL, Region: "us-east", RetryDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	embeddingProvider, err := providers.NewOpenAIProvider(providers.ProviderConfig{Name: "openai", APIKey: "sk-embed", BaseURL: upstream.URL, Region: "eu-west", RetryDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	providerSet := providers.NewProviderSet(map[string]providers.Provider{"anthropic": shadowProvider, "openai": embeddingProvider})
+	residency, err := policies.NewResidency(policies.ResidencyConfig{Zones: map[string][]string{"eu-only": {"eu-west"}, "us-only": {"us-east"}}}, providerSet.Get)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{}
+	config.Shadow = shadow.Config{
+		Targets:           []shadow.Target{{Provider: "anthropic", Model: "claude-3-5-sonnet", Percentage: 100}},
+		MaxConcurrent:     1,
+		Timeout:           time.Minute,
+		EmbeddingProvider: "openai",
+		EmbeddingModel:    "text-embedding-3-small",
+	}
+	s := &Server{
+		config:      config,
+		providers:   providerSet,
+		residency:   residency,
+		metrics:     testMetrics(t),
+		shadowStore: shadow.NewStore(config.Shadow),
+		shadowSlots: make(chan struct{}, config.Shadow.MaxConcurrent),
+		logger:      zap.NewNop(),
+	}
+	t.Cleanup(func() {
+		close(release)
+		// Wait for a mirrored request to give its slot back
+		s.shadowSlots <- struct{}{}
+		upstream.Close()
+	})
+	return s
+}
+
+// withAccess returns a request context of tenant acme with the access lists.
+func withAccess(lists ...accessList) context.Context {
+	return context.WithValue(context.Background(), tenantContextKey{}, requestTenant{ID: "acme", Access: lists})
+}
+
+func TestMirrorShadowHonoursAccessLists(t *testing.T) {
+	req := models.ChatRequest{Model: "gpt-4o", Messages: []models.Message{{Role: "user", Content: models.TextContent("Hi")}}}
+	primary := shadow.Sample{Provider: "openai", Model: "gpt-4o", Response: &models.ChatResponse{}}
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		wantMirror bool
+	}{
+		{"unrestricted", context.Background(), true},
+		{"allowed", withAccess(accessList{owner: "tenant", access: tenants.Access{AllowModels: []string{"claude-*"}}}), true},
+		{"provider denied", withAccess(accessList{owner: "tenant", access: tenants.Access{DenyProviders: []string{"anthropic"}}}), false},
+		// The shadow model is checked, not the routed one
+		{"shadow model denied", withAccess(accessList{owner: "API key", access: tenants.Access{AllowModels: []string{"gpt-4o"}}}), false},
+		{"residency", policies.WithRequestInfo(context.Background(), policies.RequestInfo{Residency: []string{"eu-only"}}), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newShadowServer(t)
+			s.mirrorShadow(test.ctx, req, primary)
+			// A mirrored request holds its slot until the blocked shadow
+			// provider answers
+			if mirrored := len(s.shadowSlots) == 1; mirrored != test.wantMirror {
+				t.Fatalf("mirrored = %v, want %v", mirrored, test.wantMirror)
+			}
+		})
+	}
+}
+
+func TestShadowEmbedderHonoursAccessListsAndResidency(t *testing.T) {
+	req := models.ChatRequest{Model: "gpt-4o"}
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		wantEmbed bool
+	}{
+		{"unrestricted", context.Background(), true},
+		{"allowed", withAccess(accessList{owner: "tenant", access: tenants.Access{AllowProviders: []string{"openai"}}}), true},
+		{"provider denied", withAccess(accessList{owner: "tenant", access: tenants.Access{DenyProviders: []string{"openai"}}}), false},
+		{"model denied", withAccess(accessList{owner: "API key", access: tenants.Access{DenyModels: []string{"text-embedding-*"}}}), false},
+		{"inside residency", policies.WithRequestInfo(context.Background(), policies.RequestInfo{Residency: []string{"eu-only"}}), true},
+		{"outside residency", policies.WithRequestInfo(context.Background(), policies.RequestInfo{Residency: []string{"us-only"}}), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newShadowServer(t)
+			if embed := s.shadowEmbedder(test.ctx, req); (embed != nil) != test.wantEmbed {
+				t.Fatalf("shadowEmbedder() returned an embedder: %v, want %v", embed != nil, test.wantEmbed)
+			}
+		})
+	}
+}
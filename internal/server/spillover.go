@@ -0,0 +1,181 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/observability"
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/internal/router/policies"
+	v1 "github.com/semantrix/semaroute/pkg/api/v1"
+)
+
+// defaultMaxSpillovers is how many providers a request spills over to when
+// not configured.
+const defaultMaxSpillovers = 2
+
+// Spillover causes, as recorded in metrics and routing decisions.
+const (
+	spilloverRateLimited     = "rate_limited"     // the provider answered 429
+	spilloverBudgetExhausted = "budget_exhausted" // its tracked rate-limit budget or quota is used up
+)
+
+// SpilloverConfig configures the spilling over of requests from rate-limited
+// providers to the next candidate.
+type SpilloverConfig struct {
+	Enabled       bool `mapstructure:"enabled"`
+	MaxSpillovers int  `mapstructure:"max_spillovers"` // providers tried after the routed one; default 2
+}
+
+// maxSpillovers returns how many providers a request may spill over to, or 0
+// when spillover is disabled.
+func (s *Server) maxSpillovers() int {
+	if !s.config.Spillover.Enabled {
+		return 0
+	}
+	if s.config.Spillover.MaxSpillovers <= 0 {
+		return defaultMaxSpillovers
+	}
+	return s.config.Spillover.MaxSpillovers
+}
+
+// isRateLimited reports whether a provider error is a 429.
+func isRateLimited(err error) bool {
+	var providerErr *models.ProviderError
+	return errors.As(err, &providerErr) && providerErr.StatusCode == http.StatusTooManyRequests
+}
+
+// routeExcluding asks the routing policy for the best healthy provider not in
+// excluded, for the request's model or else an equivalent one.
+func (s *Server) routeExcluding(ctx context.Context, req models.ChatRequest, available map[string]providers.Provider, excluded map[string]bool) (policies.RoutingDecision, providers.Provider, error) {
+	candidates := make(map[string]providers.Provider, len(available))
+	for name, provider := range available {
+		if !excluded[name] && provider.IsHealthy() {
+			candidates[name] = provider
+		}
+	}
+	if len(candidates) == 0 {
+		return policies.RoutingDecision{}, nil, fmt.Errorf("no other healthy provider")
+	}
+
+	candidates, err := s.excludeSmallContexts(req, candidates)
+	if err != nil {
+		return policies.RoutingDecision{}, nil, err
+	}
+	decision, err := s.decideWithEquivalents(ctx, req, candidates)
+	if err != nil {
+		return policies.RoutingDecision{}, nil, err
+	}
+	provider, exists := candidates[decision.ProviderName]
+	if !exists {
+		return policies.RoutingDecision{}, nil, fmt.Errorf("policy chose unavailable provider %s", decision.ProviderName)
+	}
+	return decision, provider, nil
+}
+
+// spilledDecision marks a decision as spilled over from a provider.
+func (s *Server) spilledDecision(ctx context.Context, decision policies.RoutingDecision, from, cause string) policies.RoutingDecision {
+	decision.Spillover = from
+	decision.Reason = fmt.Sprintf("Spillover from %s (%s): %s", from, cause, decision.Reason)
+	if !policies.DryRun(ctx) {
+		s.metrics.RecordSpillover(from, decision.ProviderName, cause)
+	}
+	return decision
+}
+
+// spillExhausted routes a decision for a provider whose tracked rate-limit
+// budget or quota is used up to the next candidate that has some left. The
+// decision is kept when every candidate tried is out of budget too.
+func (s *Server) spillExhausted(ctx context.Context, req models.ChatRequest, decision policies.RoutingDecision, available map[string]providers.Provider) policies.RoutingDecision {
+	excluded := make(map[string]bool)
+	next := decision
+	for i := 0; ; i++ {
+		provider, exists := available[next.ProviderName]
+		if !exists {
+			break
+		}
+		if _, exhausted := providers.QuotaExhaustedUntil(provider); !exhausted {
+			if i == 0 {
+				return decision
+			}
+			return s.spilledDecision(ctx, next, decision.ProviderName, spilloverBudgetExhausted)
+		}
+		if i == s.maxSpillovers() {
+			break
+		}
+
+		excluded[next.ProviderName] = true
+		var err error
+		if next, _, err = s.routeExcluding(ctx, routedRequest(req, decision), available, excluded); err != nil {
+			break
+		}
+	}
+	return decision
+}
+
+// runSpillover sends a request the routed provider rejected with 429 to the
+// next candidates in turn, until one serves it. It returns the response, the
+// request and decision of the provider that served it, or the last error.
+func (s *Server) runSpillover(ctx context.Context, req models.ChatRequest, failed policies.RoutingDecision, available map[string]providers.Provider, err error) (*models.ChatResponse, models.ChatRequest, policies.RoutingDecision, error) {
+	ladder := attemptLadderFrom(ctx)
+	excluded := map[string]bool{failed.ProviderName: true}
+	for i := 0; i < s.maxSpillovers() && isRateLimited(err); i++ {
+		decision, provider, routeErr := s.routeExcluding(ctx, req, available, excluded)
+		if routeErr != nil {
+			break
+		}
+		excluded[decision.ProviderName] = true
+		spillReq := routedRequest(req, decision)
+
+		start := time.Now()
+		var response *models.ChatResponse
+		response, err = provider.CreateChatCompletion(ctx, spillReq)
+		duration := time.Since(start)
+		observability.ProviderTimerFrom(ctx).Add(duration)
+		s.routingPolicy.UpdateMetrics(decision, err == nil, duration)
+		ladder.record(v1.ProviderAttempt{Provider: decision.ProviderName, Model: decision.Model}, err, duration)
+		if err == nil {
+			s.metrics.RecordProviderLatency(decision.ProviderName, decision.Model, duration)
+			return response, spillReq, s.spilledDecision(ctx, decision, failed.ProviderName, spilloverRateLimited), nil
+		}
+
+		s.metrics.RecordProviderError(decision.ProviderName, "request_failed")
+		s.logger.Warn("Spillover attempt failed",
+			zap.String("from", failed.ProviderName),
+			zap.String("provider", decision.ProviderName),
+			zap.Error(err))
+	}
+	return nil, req, failed, err
+}
+
+// spillStream opens a stream the routed provider rejected with 429 on the
+// next candidates in turn. It returns the provider and request of the stream
+// that opened, or the last error.
+func (s *Server) spillStream(ctx context.Context, req models.ChatRequest, failed string, available map[string]providers.Provider, err error) (string, providers.Provider, models.ChatRequest, <-chan models.StreamResponse, error) {
+	excluded := map[string]bool{failed: true}
+	for i := 0; i < s.maxSpillovers() && isRateLimited(err); i++ {
+		decision, provider, routeErr := s.routeExcluding(ctx, req, available, excluded)
+		if routeErr != nil {
+			break
+		}
+		excluded[decision.ProviderName] = true
+		spillReq := routedRequest(req, decision)
+
+		start := time.Now()
+		var stream <-chan models.StreamResponse
+		stream, err = providers.OpenStream(ctx, provider, spillReq)
+		observability.ProviderTimerFrom(ctx).Add(time.Since(start))
+		if err == nil {
+			s.metrics.RecordSpillover(failed, decision.ProviderName, spilloverRateLimited)
+			return decision.ProviderName, provider, spillReq, stream, nil
+		}
+		s.metrics.RecordProviderError(decision.ProviderName, "stream_failed")
+	}
+	return "", nil, req, nil, err
+}
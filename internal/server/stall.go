@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/providers"
+)
+
+// StreamingConfig configures supervision of streamed completions.
+type StreamingConfig struct {
+	// StallTimeout ends a stream when no chunk arrives for this long, from
+	// the request or from the previous chunk; 0 disables the watchdog
+	StallTimeout time.Duration `mapstructure:"stall_timeout"`
+
+	// StallFailover reopens a stream that stalls before its first chunk on
+	// another provider serving the model
+	StallFailover bool `mapstructure:"stall_failover"`
+}
+
+// Stall phases, as recorded in metrics.
+const (
+	stallFirstChunk = "first_chunk"
+	stallMidStream  = "mid_stream"
+)
+
+// stallWatchdog forwards a provider stream and ends it when no chunk arrives
+// within the stall timeout, cancelling the provider request.
+type stallWatchdog struct {
+	out     chan models.StreamResponse
+	stalled int32
+	chunks  int64
+}
+
+// watchStall starts a watchdog over stream. cancel aborts the provider
+// request behind it.
+func watchStall(ctx context.Context, stream <-chan models.StreamResponse, timeout time.Duration, cancel context.CancelFunc) *stallWatchdog {
+	wd := &stallWatchdog{out: make(chan models.StreamResponse)}
+	go func() {
+		defer close(wd.out)
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		for {
+			select {
+			case chunk, ok := <-stream:
+				if !ok {
+					return
+				}
+				select {
+				case wd.out <- chunk:
+				case <-ctx.Done():
+					return
+				}
+				atomic.AddInt64(&wd.chunks, 1)
+				// The client's own pace does not count as a stall
+				resetTimer(timer, timeout)
+			case <-timer.C:
+				atomic.StoreInt32(&wd.stalled, 1)
+				cancel()
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return wd
+}
+
+// Stalled reports whether the watchdog ended the stream, and whether any
+// chunk had been forwarded by then.
+func (wd *stallWatchdog) Stalled() (stalled, midStream bool) {
+	return atomic.LoadInt32(&wd.stalled) == 1, atomic.LoadInt64(&wd.chunks) > 0
+}
+
+// resetTimer restarts a timer, discarding an expiry not yet received.
+func resetTimer(timer *time.Timer, delay time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(delay)
+}
+
+// recordStall records a stalled stream.
+func (s *Server) recordStall(providerName, model, phase string) {
+	s.logger.Warn("Provider stream stalled",
+		zap.String("provider", providerName),
+		zap.String("model", model),
+		zap.String("phase", phase),
+		zap.Duration("stall_timeout", s.config.Streaming.StallTimeout))
+	s.metrics.RecordStreamStall(providerName, model, phase)
+	s.metrics.RecordProviderError(providerName, "stream_stalled")
+}
+
+// supervisedStream is a provider stream under a stall watchdog.
+type supervisedStream struct {
+	chunks   <-chan models.StreamResponse // nil when every attempt stalled
+	ctx      context.Context              // cancelled when the stream stalls
+	watchdog *stallWatchdog
+	provider string
+}
+
+// superviseStream puts a stall watchdog on a provider stream opened with ctx,
+// which cancel aborts, for request r. It waits for the first chunk and, with failover
+// enabled, reopens the stream on the next provider serving the model if the
+// stream stalls before it. The returned stream starts with its first chunk.
+func (s *Server) superviseStream(ctx context.Context, cancel context.CancelFunc, r *http.Request, req models.ChatRequest, providerName string, stream <-chan models.StreamResponse) supervisedStream {
+	timeout := s.config.Streaming.StallTimeout
+	tried := map[string]bool{providerName: true}
+	for {
+		wd := watchStall(ctx, stream, timeout, cancel)
+		supervised := supervisedStream{ctx: ctx, watchdog: wd, provider: providerName}
+		first, ok := <-wd.out
+		if ok {
+			supervised.chunks = prependChunk(ctx, first, wd.out)
+			return supervised
+		}
+		if stalled, _ := wd.Stalled(); !stalled {
+			// The provider ended the stream without a chunk
+			supervised.chunks = wd.out
+			return supervised
+		}
+		s.recordStall(providerName, req.Model, stallFirstChunk)
+		if !s.config.Streaming.StallFailover {
+			return supervised
+		}
+
+		next, provider, model := s.nextStreamProvider(r.Context(), req, tried)
+		if provider == nil {
+			return supervised
+		}
+		tried[next] = true
+		req.Model = model
+
+		ctx, cancel = context.WithCancel(r.Context())
+		reopened, err := provider.CreateChatCompletionStream(ctx, req)
+		if err != nil {
+			cancel()
+			s.logger.Warn("Stall failover stream failed", zap.String("provider", next), zap.Error(err))
+			continue
+		}
+		reopened = s.continuer.ContinueStream(ctx, next, req, reopened, provider.CreateChatCompletionStream)
+		s.metrics.RecordFallback(providerName, next)
+		s.logger.Info("Reopened stalled stream on another provider",
+			zap.String("from", providerName),
+			zap.String("to", next))
+		providerName, stream = next, reopened
+	}
+}
+
+// nextStreamProvider returns a healthy streaming provider within the
+// request's residency and access lists that has not been tried, with the
+// model to stream: the request's model, or else an equivalent one. It returns
+// nil if none serves either.
+func (s *Server) nextStreamProvider(ctx context.Context, req models.ChatRequest, tried map[string]bool) (string, providers.StreamingProvider, string) {
+	available := s.providers.Snapshot()
+	for _, model := range append([]string{req.Model}, s.equivalents.of(req.Model)...) {
+		for name, provider := range available {
+			streamer, streams := provider.(providers.StreamingProvider)
+			if tried[name] || !streams || !provider.IsHealthy() || !s.residency.Allows(ctx, req, provider) {
+				continue
+			}
+			if servesModel(provider, model) && allowedBy(accessListsFrom(ctx), name, model) {
+				return name, streamer, model
+			}
+		}
+	}
+	return "", nil, ""
+}
+
+// prependChunk returns a stream yielding first and then the rest of stream.
+func prependChunk(ctx context.Context, first models.StreamResponse, rest <-chan models.StreamResponse) <-chan models.StreamResponse {
+	out := make(chan models.StreamResponse)
+	go func() {
+		defer close(out)
+		select {
+		case out <- first:
+		case <-ctx.Done():
+			return
+		}
+		for chunk := range rest {
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+const (
+	// contentTypeSSE is the default streaming encoding (OpenAI-compatible).
+	contentTypeSSE = "text/event-stream"
+
+	// contentTypeNDJSON streams one StreamResponse JSON object per line.
+	contentTypeNDJSON = "application/x-ndjson"
+)
+
+// streamEncoder writes stream chunks in a negotiated wire format.
+type streamEncoder interface {
+	// ContentType returns the response content type.
+	ContentType() string
+
+	// WriteChunk writes a single stream chunk.
+	WriteChunk(w http.ResponseWriter, chunk models.StreamResponse) error
+
+	// WriteDone writes the end-of-stream marker, if the format has one.
+	WriteDone(w http.ResponseWriter) error
+}
+
+// negotiateStreamEncoder picks the streaming encoding from the Accept header.
+// Clients asking for application/x-ndjson get ndjson, everyone else gets SSE.
+func negotiateStreamEncoder(r *http.Request) streamEncoder {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if mediaType == contentTypeNDJSON || mediaType == "application/ndjson" {
+			return ndjsonEncoder{}
+		}
+	}
+	return sseEncoder{}
+}
+
+// sseEncoder encodes chunks as Server-Sent Events.
+type sseEncoder struct{}
+
+func (sseEncoder) ContentType() string {
+	return contentTypeSSE
+}
+
+func (sseEncoder) WriteChunk(w http.ResponseWriter, chunk models.StreamResponse) error {
+	payload, err := json.Marshal(chunk)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", payload)
+	return err
+}
+
+func (sseEncoder) WriteDone(w http.ResponseWriter) error {
+	_, err := fmt.Fprint(w, "data: [DONE]\n\n")
+	return err
+}
+
+// ndjsonEncoder encodes each chunk as one line of JSON.
+type ndjsonEncoder struct{}
+
+func (ndjsonEncoder) ContentType() string {
+	return contentTypeNDJSON
+}
+
+func (ndjsonEncoder) WriteChunk(w http.ResponseWriter, chunk models.StreamResponse) error {
+	// json.Encoder terminates each value with a newline
+	return json.NewEncoder(w).Encode(chunk)
+}
+
+func (ndjsonEncoder) WriteDone(w http.ResponseWriter) error {
+	// The end of the response body marks the end of an ndjson stream
+	return nil
+}
+
+// streamOutput is what a stream delivered to the client: the text of its
+// chunks, and the usage last reported by the provider, if any.
+type streamOutput struct {
+	text  strings.Builder
+	usage *models.Usage
+}
+
+// writeStream copies stream chunks to the client until the stream ends or the
+// client goes away. It returns the number of chunks written, and collects
+// their output in delivered unless it is nil.
+func writeStream(w http.ResponseWriter, r *http.Request, encoder streamEncoder, stream <-chan models.StreamResponse, delivered *streamOutput) (int, error) {
+	flusher, _ := w.(http.Flusher)
+
+	w.Header().Set("Content-Type", encoder.ContentType())
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	chunks := 0
+	for {
+		select {
+		case chunk, ok := <-stream:
+			if !ok {
+				err := encoder.WriteDone(w)
+				if flusher != nil {
+					flusher.Flush()
+				}
+				return chunks, err
+			}
+			if err := encoder.WriteChunk(w, chunk); err != nil {
+				return chunks, err
+			}
+			chunks++
+			if delivered != nil {
+				for _, choice := range chunk.Choices {
+					delivered.text.WriteString(choice.Delta.Content.Text())
+				}
+				if chunk.Usage != nil {
+					delivered.usage = chunk.Usage
+				}
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case <-r.Context().Done():
+			return chunks, r.Context().Err()
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"math"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/semantrix/semaroute/internal/catalog"
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/tokenizer"
+	"github.com/semantrix/semaroute/internal/usage"
+)
+
+// newUsageServer returns a server with just what usage accounting needs.
+func newUsageServer(t *testing.T) *Server {
+	t.Helper()
+	modelCatalog, err := catalog.NewCatalog(catalog.Config{Models: []catalog.ModelEntry{
+		{Provider: "openai", Model: "gpt-4o", InputPer1K: 0.005, OutputPer1K: 0.015},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	usageStore, err := usage.NewStore(usage.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Server{
+		config:       &Config{},
+		metrics:      testMetrics(t),
+		modelCatalog: modelCatalog,
+		usageStore:   usageStore,
+		logger:       zap.NewNop(),
+	}
+}
+
+// streamChunks writes the chunks to a recorder and returns what was
+// delivered.
+func streamChunks(t *testing.T, chunks ...models.StreamResponse) *streamOutput {
+	t.Helper()
+	stream := make(chan models.StreamResponse, len(chunks))
+	for _, chunk := range chunks {
+		stream <- chunk
+	}
+	close(stream)
+
+	var delivered streamOutput
+	r := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	if _, err := writeStream(httptest.NewRecorder(), r, sseEncoder{}, stream, &delivered); err != nil {
+		t.Fatal(err)
+	}
+	return &delivered
+}
+
+func textChunk(text string) models.StreamResponse {
+	return models.StreamResponse{Choices: []models.StreamChoice{{Delta: models.Message{Content: models.TextContent(text)}}}}
+}
+
+func onlyRecord(t *testing.T, s *Server) usage.Record {
+	t.Helper()
+	records := s.usageStore.Records(time.Time{})
+	if len(records) != 1 {
+		t.Fatalf("%d usage records, want 1", len(records))
+	}
+	return records[0]
+}
+
+var streamRequest = models.ChatRequest{
+	Model:    "gpt-4o",
+	Messages: []models.Message{{Role: "user", Content: models.TextContent("Tell me a story")}},
+}
+
+func TestRecordStreamedPrefersReportedUsage(t *testing.T) {
+	s := newUsageServer(t)
+	last := textChunk("")
+	last.Usage = &models.Usage{PromptTokens: 1000, CompletionTokens: 500, TotalTokens: 1500}
+	delivered := streamChunks(t, textChunk("Once upon"), textChunk(" a time"), last)
+	if delivered.text.String() != "Once upon a time" {
+		t.Fatalf("delivered text = %q", delivered.text.String())
+	}
+
+	s.recordStreamed("acme", "openai", streamRequest, delivered)
+	record := onlyRecord(t, s)
+	if record.Tenant != "acme" || record.Provider != "openai" || record.Model != "gpt-4o" || record.Aborted != "" {
+		t.Fatalf("record = %+v", record)
+	}
+	if record.PromptTokens != 1000 || record.CompletionTokens != 500 || math.Abs(record.Cost-0.0125) > 1e-9 {
+		t.Fatalf("record tokens and cost = %d, %d, %v, want 1000, 500, 0.0125", record.PromptTokens, record.CompletionTokens, record.Cost)
+	}
+}
+
+func TestRecordStreamedEstimatesMissingUsage(t *testing.T) {
+	s := newUsageServer(t)
+	delivered := streamChunks(t, textChunk("Once upon"), textChunk(" a time"))
+
+	s.recordStreamed("acme", "openai", streamRequest, delivered)
+	record := onlyRecord(t, s)
+	wantPrompt := tokenizer.CountMessages("openai", "gpt-4o", streamRequest.Messages)
+	wantCompletion := tokenizer.CountText("openai", "gpt-4o", "Once upon a time")
+	if record.PromptTokens != wantPrompt || record.CompletionTokens != wantCompletion {
+		t.Fatalf("record tokens = %d, %d, want the estimates %d, %d", record.PromptTokens, record.CompletionTokens, wantPrompt, wantCompletion)
+	}
+	if record.Cost <= 0 {
+		t.Fatalf("record cost = %v, want the estimated cost", record.Cost)
+	}
+}
+
+func TestRecordAbortedPrefersReportedUsage(t *testing.T) {
+	estimatedPrompt := tokenizer.CountMessages("openai", "gpt-4o", streamRequest.Messages)
+	tests := []struct {
+		name           string
+		chunks         []models.StreamResponse
+		wantPrompt     int
+		wantCompletion int
+	}{
+		{"no output", nil, estimatedPrompt, 0},
+		{"estimated", []models.StreamResponse{textChunk("Once upon")}, estimatedPrompt, tokenizer.CountText("openai", "gpt-4o", "Once upon")},
+		{"reported", []models.StreamResponse{textChunk("Once upon"), {Usage: &models.Usage{PromptTokens: 1000, CompletionTokens: 7}}}, 1000, 7},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newUsageServer(t)
+			var delivered *streamOutput
+			if test.chunks != nil {
+				delivered = streamChunks(t, test.chunks...)
+			}
+
+			s.recordAborted("acme", "openai", streamRequest, delivered, usage.AbortClientDisconnect)
+			record := onlyRecord(t, s)
+			if record.PromptTokens != test.wantPrompt || record.CompletionTokens != test.wantCompletion {
+				t.Fatalf("record tokens = %d, %d, want %d, %d", record.PromptTokens, record.CompletionTokens, test.wantPrompt, test.wantCompletion)
+			}
+			if record.Aborted != usage.AbortClientDisconnect {
+				t.Fatalf("record aborted = %q", record.Aborted)
+			}
+		})
+	}
+}
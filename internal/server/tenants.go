@@ -0,0 +1,268 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/semantrix/semaroute/internal/router/policies"
+	"github.com/semantrix/semaroute/internal/tenants"
+	"github.com/semantrix/semaroute/pkg/api/v1"
+	"go.uber.org/zap"
+)
+
+// tenantContextKey carries the tenant of a request.
+type tenantContextKey struct{}
+
+// requestTenant is the tenant a request is made for.
+type requestTenant struct {
+	ID            string
+	Authenticated bool           // identified by an API key rather than the tenant header
+	Scopes        tenants.Scopes // of the API key; nil for the tenant header
+	Access        []accessList   // of the API key and the tenant, when they restrict anything
+	Priority      string         // tier of the API key, or else the tenant; empty when neither sets one
+}
+
+// tenantMiddleware identifies the tenant of a request from its bearer API key,
+// or the x-api-key header Anthropic clients send, falling back to the tenant
+// header unless API keys are required. Once tenant keys are configured, a
+// request presenting a key that is not one of them is refused with 401 rather
+// than falling back. Requests of tenants that are not active are refused with
+// 403.
+func (s *Server) tenantMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant := requestTenant{ID: r.Header.Get(tenantHeader)}
+
+		var residency []string
+		presented := requestAPIKey(r)
+		if key, ok := s.tenants.Authenticate(presented); ok {
+			tenant = requestTenant{ID: key.Tenant.ID, Authenticated: true, Scopes: key.Scopes, Priority: key.Priority}
+			if key.Residency != "" {
+				residency = append(residency, key.Residency)
+			}
+			if !key.Access.IsZero() {
+				tenant.Access = append(tenant.Access, accessList{owner: "API key", access: key.Access})
+			}
+		} else if s.tenants.RequireAPIKey() || (presented != "" && s.tenants.HasKeys()) {
+			writeUnauthorized(w, r, "a valid tenant API key is required")
+			return
+		}
+
+		// Suspended and deleted tenants are refused until reactivated
+		if status, found := s.tenants.Status(tenant.ID); found && status.State != tenants.StateActive {
+			writeTenantInactive(w, r, status)
+			return
+		}
+
+		if configured, found := s.tenants.Get(tenant.ID); found {
+			if configured.Residency != "" {
+				residency = append(residency, configured.Residency)
+			}
+			if !configured.Access.IsZero() {
+				tenant.Access = append(tenant.Access, accessList{owner: "tenant", access: configured.Access})
+			}
+			if tenant.Priority == "" {
+				tenant.Priority = configured.Priority
+			}
+		}
+
+		hints, err := headerRoutingHints(r.Header)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), tenantContextKey{}, tenant)
+		ctx = policies.WithRequestInfo(ctx, policies.RequestInfo{
+			Tenant:        tenant.ID,
+			Headers:       r.Header,
+			Hints:         hints,
+			Deterministic: s.deterministic(r, tenant.ID),
+			Residency:     residency,
+			Weights:       s.weights,
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// deterministic reports whether a request is routed in deterministic mode:
+// for every request of a deterministic tenant, or when asked for with the
+// deterministic header.
+func (s *Server) deterministic(r *http.Request, tenantID string) bool {
+	if tenant, found := s.tenants.Get(tenantID); found && tenant.Deterministic {
+		return true
+	}
+	requested, _ := strconv.ParseBool(r.Header.Get(deterministicHeader))
+	return requested
+}
+
+// requireScope refuses requests made with an API key lacking the scope with
+// 403. Requests identified by the tenant header carry no key and are left to
+// tenancy.require_api_key.
+func requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenant := tenantFrom(r)
+			if tenant.Authenticated && !tenant.Scopes.Allows(scope) {
+				writeForbidden(w, r, "this API key lacks the "+scope+" scope")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// adminAuthMiddleware restricts the admin API to keys with the admin:* scope
+// when tenancy.require_admin_key is set. The tenant of the key, if any, is
+// the actor recorded in the audit log.
+func (s *Server) adminAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := s.tenants.Authenticate(requestAPIKey(r))
+		if s.tenants.RequireAdminKey() {
+			if !ok {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "An admin API key is required", http.StatusUnauthorized)
+				return
+			}
+			if !key.Scopes.Allows(tenants.ScopeAdmin) {
+				http.Error(w, "This API key lacks the "+tenants.ScopeAdmin+" scope", http.StatusForbidden)
+				return
+			}
+		}
+
+		if ok {
+			r = r.WithContext(context.WithValue(r.Context(), adminActorKey{}, key.Tenant.ID))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestAPIKey returns the bearer API key of a request, or the x-api-key
+// header Anthropic clients send.
+func requestAPIKey(r *http.Request) string {
+	key := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if key == "" {
+		key = r.Header.Get("x-api-key")
+	}
+	return key
+}
+
+// tenantFrom returns the tenant of a request. Requests that did not pass
+// through tenantMiddleware are identified by the tenant header.
+func tenantFrom(r *http.Request) requestTenant {
+	if tenant, ok := r.Context().Value(tenantContextKey{}).(requestTenant); ok {
+		return tenant
+	}
+	return requestTenant{ID: r.Header.Get(tenantHeader)}
+}
+
+// writeUnauthorized writes a 401 error response.
+func writeUnauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	errorResponse := v1.ErrorResponse{
+		Error: v1.ErrorDetails{
+			Type:       "authentication_error",
+			Message:    message,
+			StatusCode: http.StatusUnauthorized,
+		},
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(errorResponse)
+}
+
+// writeForbidden writes a 403 error response.
+func writeForbidden(w http.ResponseWriter, r *http.Request, message string) {
+	errorResponse := v1.ErrorResponse{
+		Error: v1.ErrorDetails{
+			Type:       "permission_error",
+			Message:    message,
+			StatusCode: http.StatusForbidden,
+		},
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(errorResponse)
+}
+
+// writeTenantInactive writes the 403 error response refusing a suspended or
+// deleted tenant.
+func writeTenantInactive(w http.ResponseWriter, r *http.Request, status tenants.Status) {
+	errorType, message := "tenant_suspended", status.Message
+	if status.State == tenants.StateDeleted {
+		errorType = "tenant_deleted"
+		if message == "" {
+			message = "This account has been deleted."
+		}
+	}
+
+	errorResponse := v1.ErrorResponse{
+		Error: v1.ErrorDetails{
+			Type:       errorType,
+			Message:    message,
+			StatusCode: http.StatusForbidden,
+		},
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(errorResponse)
+}
+
+// handleGetTenants returns the lifecycle state of every tenant.
+func (s *Server) handleGetTenants(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tenants": s.tenants.Statuses(),
+	})
+}
+
+// handleSetTenantState suspends, deletes or reactivates a tenant. The change
+// applies from the tenant's next request.
+func (s *Server) handleSetTenantState(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		State   tenants.State `json:"state"`
+		Message string        `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !body.State.Valid() {
+		http.Error(w, "state must be active, suspended or deleted", http.StatusBadRequest)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	before, _ := s.tenants.Status(id)
+	status, err := s.tenants.SetState(id, body.State, body.Message)
+	if errors.Is(err, tenants.ErrUnknownTenant) {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to change tenant state", zap.String("tenant", id), zap.Error(err))
+		http.Error(w, "Failed to change tenant state", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Info("Tenant state changed",
+		zap.String("tenant", id),
+		zap.String("state", string(status.State)))
+	s.recordAudit(r, "tenant.state", id, before, status)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/semantrix/semaroute/internal/tenants"
+)
+
+// newAdminAuthServer returns a server with a tenant registry holding an
+// admin key and a chat key.
+func newAdminAuthServer(t *testing.T, requireAdminKey bool) *Server {
+	t.Helper()
+	registry, err := tenants.NewRegistry(tenants.Config{
+		RequireAPIKey:   true,
+		RequireAdminKey: requireAdminKey,
+		Tenants: []tenants.TenantConfig{{
+			ID: "ops",
+			Keys: []tenants.KeyConfig{
+				{Key: "sk-admin", Scopes: []string{tenants.ScopeAdmin}},
+				{Key: "sk-chat", Scopes: []string{tenants.ScopeChatWrite}},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Server{tenants: registry, logger: zap.NewNop()}
+}
+
+func TestAdminAuthMiddleware(t *testing.T) {
+	tests := []struct {
+		name            string
+		requireAdminKey bool
+		header          string
+		value           string
+		wantStatus      int
+		wantActor       string
+	}{
+		{"open without key", false, "", "", http.StatusOK, "anonymous"},
+		{"open with chat key", false, "Authorization", "Bearer sk-chat", http.StatusOK, "ops"},
+		{"required without key", true, "", "", http.StatusUnauthorized, ""},
+		{"required with unknown key", true, "Authorization", "Bearer sk-unknown", http.StatusUnauthorized, ""},
+		{"required with chat key", true, "Authorization", "Bearer sk-chat", http.StatusForbidden, ""},
+		{"required with admin key", true, "Authorization", "Bearer sk-admin", http.StatusOK, "ops"},
+		{"required with admin x-api-key", true, "x-api-key", "sk-admin", http.StatusOK, "ops"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newAdminAuthServer(t, test.requireAdminKey)
+			var actor string
+			handler := s.adminAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				actor = adminActor(r)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/admin/providers", nil)
+			if test.header != "" {
+				r.Header.Set(test.header, test.value)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != test.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, test.wantStatus, w.Body)
+			}
+			if actor != test.wantActor {
+				t.Fatalf("actor = %q, want %q", actor, test.wantActor)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Fatal("401 without a WWW-Authenticate challenge")
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	handler := requireScope(tenants.ScopeModelsRead)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tests := map[string]struct {
+		tenant     requestTenant
+		wantStatus int
+	}{
+		"scoped key":     {requestTenant{ID: "ops", Authenticated: true, Scopes: tenants.Scopes{"models:*"}}, http.StatusOK},
+		"admin key":      {requestTenant{ID: "ops", Authenticated: true, Scopes: tenants.Scopes{tenants.ScopeAdmin}}, http.StatusOK},
+		"key lacking it": {requestTenant{ID: "ops", Authenticated: true, Scopes: tenants.Scopes{tenants.ScopeChatWrite}}, http.StatusForbidden},
+		"tenant header":  {requestTenant{ID: "ops"}, http.StatusOK},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+			r = r.WithContext(context.WithValue(r.Context(), tenantContextKey{}, test.tenant))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != test.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, test.wantStatus)
+			}
+		})
+	}
+}
+
+func TestTenantMiddlewareRejectsUnknownKeys(t *testing.T) {
+	withKeys, err := tenants.NewRegistry(tenants.Config{Tenants: []tenants.TenantConfig{{ID: "acme", APIKeys: []string{"sk-acme"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withoutKeys, err := tenants.NewRegistry(tenants.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		registry   *tenants.Registry
+		header     string
+		value      string
+		wantStatus int
+		wantTenant string
+	}{
+		{"known key", withKeys, "Authorization", "Bearer sk-acme", http.StatusOK, "acme"},
+		{"unknown bearer key", withKeys, "Authorization", "Bearer sk-revoked", http.StatusUnauthorized, ""},
+		{"unknown x-api-key", withKeys, "x-api-key", "sk-revoked", http.StatusUnauthorized, ""},
+		{"no key", withKeys, "", "", http.StatusOK, "globex"},
+		{"no keys configured", withoutKeys, "Authorization", "Bearer sk-anything", http.StatusOK, "globex"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &Server{tenants: test.registry, logger: zap.NewNop()}
+			var tenant requestTenant
+			handler := s.tenantMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tenant = tenantFrom(r)
+			}))
+
+			r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+			r.Header.Set(tenantHeader, "globex")
+			if test.header != "" {
+				r.Header.Set(test.header, test.value)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != test.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, test.wantStatus, w.Body)
+			}
+			if tenant.ID != test.wantTenant {
+				t.Fatalf("tenant = %q, want %q", tenant.ID, test.wantTenant)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/semantrix/semaroute/internal/continuation"
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/tools"
+)
+
+// runServerTools executes the tool calls of a response through the tool
+// guard's fan-out and sends their results back to the model, until it answers without
+// calling tools or max_turns is reached. A turn calling any tool the guard
+// does not run for the tenant is returned as is, for the client to run.
+// Usage adds up across the turns.
+func (s *Server) runServerTools(ctx context.Context, tenantID string, req models.ChatRequest, response *models.ChatResponse, complete continuation.CompleteFunc) (*models.ChatResponse, error) {
+	for turn := 0; turn < s.toolGuard.GetConfig().MaxTurns; turn++ {
+		calls, ok := s.serverToolCalls(tenantID, response)
+		if !ok {
+			return response, nil
+		}
+
+		// The conversation so far is copied so the client's request is
+		// never appended to
+		messages := make([]models.Message, 0, len(req.Messages)+len(calls)+1)
+		messages = append(messages, req.Messages...)
+		messages = append(messages, response.Choices[0].Message)
+		// The calls of a turn run concurrently; results keep the calls' order
+		for _, result := range s.toolGuard.ExecuteAll(ctx, tenantID, calls) {
+			messages = append(messages, toolResultMessage(result))
+		}
+		req.Messages = messages
+
+		next, err := complete(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		next.Usage.PromptTokens += response.Usage.PromptTokens
+		next.Usage.CompletionTokens += response.Usage.CompletionTokens
+		next.Usage.TotalTokens += response.Usage.TotalTokens
+		response = next
+	}
+	return response, nil
+}
+
+// serverToolCalls returns the tool calls of a response when the guard runs
+// every one of them for the tenant.
+func (s *Server) serverToolCalls(tenantID string, response *models.ChatResponse) ([]tools.Call, bool) {
+	if len(response.Choices) == 0 || len(response.Choices[0].Message.ToolCalls) == 0 {
+		return nil, false
+	}
+	toolCalls := response.Choices[0].Message.ToolCalls
+	calls := make([]tools.Call, 0, len(toolCalls))
+	for _, toolCall := range toolCalls {
+		if !s.toolGuard.Handles(tenantID, toolCall.Function.Name) {
+			return nil, false
+		}
+		calls = append(calls, tools.Call{
+			ID:        toolCall.ID,
+			Name:      toolCall.Function.Name,
+			Arguments: json.RawMessage(toolCall.Function.Arguments),
+		})
+	}
+	return calls, true
+}
+
+// toolResultMessage returns the tool message reporting a result to the
+// model. A failed call reports its error, so the model can recover.
+func toolResultMessage(result tools.Result) models.Message {
+	content := string(result.Output)
+	if result.Error != "" {
+		data, _ := json.Marshal(map[string]string{"error": result.Error})
+		content = string(data)
+	}
+	return models.Message{
+		Role:       "tool",
+		Name:       result.Name,
+		Content:    models.TextContent(content),
+		ToolCallID: result.CallID,
+	}
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/tools"
+)
+
+// toolCallResponse returns a response calling the named tools.
+func toolCallResponse(names ...string) *models.ChatResponse {
+	message := models.Message{Role: "assistant"}
+	for i, name := range names {
+		message.ToolCalls = append(message.ToolCalls, models.ToolCall{
+			ID:       name + "-" + string(rune('a'+i)),
+			Type:     "function",
+			Function: models.ToolCallFunction{Name: name, Arguments: `{"query":"weather"}`},
+		})
+	}
+	return &models.ChatResponse{
+		Choices: []models.Choice{{Message: message, FinishReason: "tool_calls"}},
+		Usage:   models.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}
+}
+
+// newToolServer returns a server whose guard runs search and fail for acme.
+func newToolServer(t *testing.T, maxTurns int) *Server {
+	guard, err := tools.NewGuard(tools.Config{
+		Enabled:  true,
+		MaxTurns: maxTurns,
+		Tenants:  map[string]tools.TenantConfig{"acme": {AllowedTools: []string{"search", "fail"}}},
+	}, zap.NewNop(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guard.Register("search", func(ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
+		return json.RawMessage(`{"results":["sunny"]}`), nil
+	})
+	guard.Register("fail", func(ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
+		return nil, errors.New("backend down")
+	})
+	return &Server{toolGuard: guard}
+}
+
+func TestRunServerToolsContinuesConversation(t *testing.T) {
+	s := newToolServer(t, 5)
+	req := models.ChatRequest{Model: "gpt-4o", Messages: []models.Message{{Role: "user", Content: models.TextContent("Weather?")}}}
+
+	var followUps []models.ChatRequest
+	complete := func(ctx context.Context, req models.ChatRequest) (*models.ChatResponse, error) {
+		followUps = append(followUps, req)
+		return &models.ChatResponse{
+			Choices: []models.Choice{{Message: models.Message{Role: "assistant", Content: models.TextContent("Sunny.")}, FinishReason: "stop"}},
+			Usage:   models.Usage{PromptTokens: 20, CompletionTokens: 2, TotalTokens: 22},
+		}, nil
+	}
+
+	response, err := s.runServerTools(context.Background(), "acme", req, toolCallResponse("search"), complete)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Choices[0].Message.Content.Text() != "Sunny." {
+		t.Fatalf("response = %+v", response.Choices[0].Message)
+	}
+	if response.Usage != (models.Usage{PromptTokens: 30, CompletionTokens: 7, TotalTokens: 37}) {
+		t.Fatalf("usage = %+v, want both turns", response.Usage)
+	}
+	if len(followUps) != 1 {
+		t.Fatalf("%d follow-ups, want 1", len(followUps))
+	}
+	messages := followUps[0].Messages
+	if len(messages) != 3 || messages[1].Role != "assistant" || len(messages[1].ToolCalls) != 1 {
+		t.Fatalf("follow-up messages = %+v", messages)
+	}
+	if result := messages[2]; result.Role != "tool" || result.ToolCallID != "search-a" || result.Content.Text() != `{"results":["sunny"]}` {
+		t.Fatalf("tool result = %+v", result)
+	}
+	if len(req.Messages) != 1 {
+		t.Fatalf("client request was appended to: %+v", req.Messages)
+	}
+}
+
+func TestRunServerToolsLeavesClientTools(t *testing.T) {
+	tests := []struct {
+		name     string
+		tenant   string
+		response *models.ChatResponse
+	}{
+		{"tenant not allowed", "globex", toolCallResponse("search")},
+		{"tool without executor", "acme", toolCallResponse("calculator")},
+		{"mixed with a client tool", "acme", toolCallResponse("search", "calculator")},
+		{"no tool calls", "acme", &models.ChatResponse{Choices: []models.Choice{{FinishReason: "stop"}}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newToolServer(t, 5)
+			complete := func(ctx context.Context, req models.ChatRequest) (*models.ChatResponse, error) {
+				t.Fatal("the provider was called again")
+				return nil, nil
+			}
+			response, err := s.runServerTools(context.Background(), test.tenant, models.ChatRequest{}, test.response, complete)
+			if err != nil || response != test.response {
+				t.Fatalf("runServerTools() = %+v, %v, want the response unchanged", response, err)
+			}
+		})
+	}
+}
+
+func TestRunServerToolsReportsErrorsAndStopsAtMaxTurns(t *testing.T) {
+	s := newToolServer(t, 2)
+	calls := 0
+	var last models.ChatRequest
+	complete := func(ctx context.Context, req models.ChatRequest) (*models.ChatResponse, error) {
+		calls++
+		last = req
+		return toolCallResponse("search"), nil
+	}
+
+	response, err := s.runServerTools(context.Background(), "acme", models.ChatRequest{}, toolCallResponse("fail"), complete)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("provider called %d times, want max_turns", calls)
+	}
+	if len(response.Choices[0].Message.ToolCalls) != 1 {
+		t.Fatalf("response = %+v, want the last turn's tool calls", response)
+	}
+	failed := last.Messages[1]
+	if failed.Role != "tool" || !strings.Contains(failed.Content.Text(), `"error":"backend down"`) {
+		t.Fatalf("failed call reported %+v", failed)
+	}
+}
+
+func TestRunServerToolsReturnsFollowUpErrors(t *testing.T) {
+	s := newToolServer(t, 5)
+	complete := func(ctx context.Context, req models.ChatRequest) (*models.ChatResponse, error) {
+		return nil, errors.New("provider unavailable")
+	}
+	if _, err := s.runServerTools(context.Background(), "acme", models.ChatRequest{}, toolCallResponse("search"), complete); err == nil {
+		t.Fatal("runServerTools() error = nil")
+	}
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/providers"
+	"github.com/semantrix/semaroute/internal/router/policies"
+)
+
+// Kinds of topology nodes.
+const (
+	nodeAlias         = "alias"
+	nodeFallbackChain = "fallback_chain"
+	nodePolicy        = "policy"
+	nodeStage         = "stage"
+	nodeProvider      = "provider"
+	nodeEndpoint      = "endpoint"
+)
+
+// topologyNode is an element of the routing setup.
+type topologyNode struct {
+	ID         string                 `json:"id"` // kind:name
+	Kind       string                 `json:"kind"`
+	Label      string                 `json:"label"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// topologyEdge is a path requests take between two nodes.
+type topologyEdge struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Kind   string  `json:"kind"`            // target, fallback, stage, routes or endpoint
+	Label  string  `json:"label,omitempty"` // e.g. the model an alias target routes to
+	Weight float64 `json:"weight,omitempty"`
+}
+
+// routingTopology is the live routing setup as a graph.
+type routingTopology struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	Nodes       []topologyNode `json:"nodes"`
+	Edges       []topologyEdge `json:"edges"`
+}
+
+// configuredProvider is a provider exposing its configuration.
+type configuredProvider interface {
+	GetConfig() providers.ProviderConfig
+}
+
+// nodeID returns the ID of a node.
+func nodeID(kind, name string) string {
+	return kind + ":" + name
+}
+
+// routingTopology builds the graph of aliases and fallback chains, the
+// routing policy and its stages, the providers with their health and weights,
+// and the endpoints they call.
+func (s *Server) routingTopology() routingTopology {
+	topology := routingTopology{GeneratedAt: time.Now()}
+	addNode := func(kind, name string, attributes map[string]interface{}) string {
+		id := nodeID(kind, name)
+		topology.Nodes = append(topology.Nodes, topologyNode{ID: id, Kind: kind, Label: name, Attributes: attributes})
+		return id
+	}
+	addEdge := func(edge topologyEdge) {
+		topology.Edges = append(topology.Edges, edge)
+	}
+
+	// The policy, with its middleware and any pipeline stages
+	policy := unwrapPolicy(s.routingPolicy)
+	policyAttributes := map[string]interface{}{"description": policy.GetDescription()}
+	if chained, ok := s.routingPolicy.(*policies.ChainedPolicy); ok {
+		policyAttributes["middleware"] = chained.Middleware()
+	}
+	policyID := addNode(nodePolicy, policy.GetName(), policyAttributes)
+	routers := []string{policyID}
+	if pipeline, ok := policy.(*policies.PipelinePolicy); ok {
+		routers = nil
+		for i, stage := range pipeline.Stages() {
+			stageID := addNode(nodeStage, stage, map[string]interface{}{"position": i + 1})
+			addEdge(topologyEdge{From: policyID, To: stageID, Kind: "stage", Label: fmt.Sprintf("%d", i+1)})
+			routers = append(routers, stageID)
+		}
+	}
+
+	// Providers, their endpoints, and the policy routing to them
+	var weights map[string]policies.ProviderWeight
+	if reporter, ok := policy.(policies.WeightReporter); ok {
+		weights = reporter.WeightReport().WithOverrides(s.weights.All()).Providers
+	}
+	var breakers map[string]policies.BreakerStatus
+	if s.breaker != nil {
+		breakers = s.breaker.Status()
+	}
+	available := s.providers.Snapshot()
+	for _, name := range sortedProviderNames(available) {
+		provider := available[name]
+		health := provider.GetHealth()
+		attributes := map[string]interface{}{
+			"healthy": health.Healthy,
+			"latency": health.Latency.String(),
+		}
+		if health.Error != "" {
+			attributes["error"] = health.Error
+		}
+		if region := providers.RegionOf(provider); region != "" {
+			attributes["region"] = region
+		}
+		if status, known := breakers[name]; known {
+			attributes["breaker"] = status.State
+		}
+		if override, set := s.weights.Get(name); set {
+			attributes["operator_weight"] = override
+		}
+		providerID := addNode(nodeProvider, name, attributes)
+
+		weight := weights[name].Effective
+		if override, set := s.weights.Get(name); set && weights == nil {
+			// Policies without weights of their own scale by it
+			weight = override
+		}
+		for _, router := range routers {
+			addEdge(topologyEdge{From: router, To: providerID, Kind: "routes", Weight: weight})
+		}
+
+		if p, ok := provider.(configuredProvider); ok {
+			if baseURL := p.GetConfig().BaseURL; baseURL != "" {
+				endpointID := addNode(nodeEndpoint, baseURL, nil)
+				addEdge(topologyEdge{From: providerID, To: endpointID, Kind: "endpoint"})
+			}
+		}
+	}
+
+	// Aliases and fallback chains route to a provider, or through the policy
+	// when they leave the provider to it
+	targetID := func(provider string) string {
+		if provider == "" {
+			return policyID
+		}
+		return nodeID(nodeProvider, provider)
+	}
+	for _, alias := range sortedKeys(s.config.ModelAliases) {
+		aliasID := addNode(nodeAlias, alias, nil)
+		for _, target := range s.config.ModelAliases[alias] {
+			addEdge(topologyEdge{From: aliasID, To: targetID(target.Provider), Kind: "target", Label: target.Model, Weight: target.Weight})
+		}
+	}
+	for _, model := range sortedKeys(s.config.FallbackChains) {
+		chainID := addNode(nodeFallbackChain, model, nil)
+		for i, hop := range s.config.FallbackChains[model] {
+			addEdge(topologyEdge{From: chainID, To: targetID(hop.Provider), Kind: "fallback", Label: fmt.Sprintf("hop %d: %s", i+1, hop.Model)})
+		}
+	}
+	return topology
+}
+
+// sortedKeys returns the keys of a map in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// handleGetRoutingTopology returns the routing topology as JSON, or rendered
+// as Graphviz DOT or a Mermaid flowchart with format=dot or format=mermaid.
+func (s *Server) handleGetRoutingTopology(w http.ResponseWriter, r *http.Request) {
+	topology := s.routingTopology()
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(topology)
+	case "dot":
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, topology.dot())
+	case "mermaid":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, topology.mermaid())
+	default:
+		http.Error(w, fmt.Sprintf("Unknown format %q, expected json, dot or mermaid", format), http.StatusBadRequest)
+	}
+}
+
+// nodeShapes are the Graphviz shapes of node kinds.
+var nodeShapes = map[string]string{
+	nodeAlias:         "note",
+	nodeFallbackChain: "note",
+	nodePolicy:        "diamond",
+	nodeStage:         "box",
+	nodeProvider:      "box",
+	nodeEndpoint:      "ellipse",
+}
+
+// dot renders the topology in the Graphviz DOT language. Unhealthy providers
+// are drawn in red.
+func (t routingTopology) dot() string {
+	var b strings.Builder
+	b.WriteString("digraph routing {\n\trankdir=LR;\n")
+	for _, node := range t.Nodes {
+		attributes := fmt.Sprintf("label=%q, shape=%s", node.Kind+"\n"+node.Label, nodeShapes[node.Kind])
+		if healthy, ok := node.Attributes["healthy"].(bool); ok && !healthy {
+			attributes += ", color=red"
+		}
+		fmt.Fprintf(&b, "\t%q [%s];\n", node.ID, attributes)
+	}
+	for _, edge := range t.Edges {
+		fmt.Fprintf(&b, "\t%q -> %q", edge.From, edge.To)
+		if label := edge.caption(); label != "" {
+			fmt.Fprintf(&b, " [label=%q]", label)
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// mermaid renders the topology as a Mermaid flowchart. Unhealthy providers
+// are marked with a cross.
+func (t routingTopology) mermaid() string {
+	ids := make(map[string]string, len(t.Nodes))
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, node := range t.Nodes {
+		ids[node.ID] = fmt.Sprintf("n%d", i)
+		label := node.Kind + ": " + node.Label
+		if healthy, ok := node.Attributes["healthy"].(bool); ok && !healthy {
+			label += " ✗"
+		}
+		fmt.Fprintf(&b, "    %s[%q]\n", ids[node.ID], label)
+	}
+	for _, edge := range t.Edges {
+		if label := edge.caption(); label != "" {
+			fmt.Fprintf(&b, "    %s -->|%q| %s\n", ids[edge.From], label, ids[edge.To])
+		} else {
+			fmt.Fprintf(&b, "    %s --> %s\n", ids[edge.From], ids[edge.To])
+		}
+	}
+	return b.String()
+}
+
+// caption returns the text drawn on an edge: its label and weight.
+func (e topologyEdge) caption() string {
+	switch {
+	case e.Weight != 0 && e.Label != "":
+		return fmt.Sprintf("%s (%g)", e.Label, e.Weight)
+	case e.Weight != 0:
+		return fmt.Sprintf("%g", e.Weight)
+	}
+	return e.Label
+}
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+	"github.com/semantrix/semaroute/internal/usage"
+	"github.com/semantrix/semaroute/pkg/api/v1"
+)
+
+// Defaults and bounds of the tenant usage endpoints.
+const (
+	defaultUsageDays  = 30
+	defaultTopModels  = 10
+	maxUsageDays      = 366
+	usageTopModelsMax = 100
+)
+
+// usageTenant returns the authenticated tenant of a usage request. Usage is
+// only served to tenants identified by an API key, never by the tenant header.
+func (s *Server) usageTenant(w http.ResponseWriter, r *http.Request) (string, bool) {
+	tenant := tenantFrom(r)
+	if !tenant.Authenticated {
+		writeUnauthorized(w, r, "usage requires a tenant API key")
+		return "", false
+	}
+	if s.usageStore == nil {
+		http.Error(w, "Usage store is disabled", http.StatusNotFound)
+		return "", false
+	}
+	return tenant.ID, true
+}
+
+// usageDays returns the tenant's daily usage for the requested number of days
+// up to today, from the days query parameter.
+func (s *Server) usageDays(w http.ResponseWriter, r *http.Request, tenant string) ([]usage.DailyUsage, bool) {
+	days := defaultUsageDays
+	if value := r.URL.Query().Get("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxUsageDays {
+			http.Error(w, "days must be between 1 and "+strconv.Itoa(maxUsageDays), http.StatusBadRequest)
+			return nil, false
+		}
+		days = parsed
+	}
+
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -(days - 1))
+	return s.usageStore.Daily(tenant, from, to), true
+}
+
+// handleUsageSummary returns the tenant's total usage over a range of days.
+func (s *Server) handleUsageSummary(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := s.usageTenant(w, r)
+	if !ok {
+		return
+	}
+	days, ok := s.usageDays(w, r, tenant)
+	if !ok {
+		return
+	}
+
+	var totals usage.Totals
+	for _, day := range days {
+		totals.Add(day.Totals)
+	}
+
+	writeUsageJSON(w, v1.UsageSummaryResponse{
+		Tenant:      tenant,
+		From:        days[0].Date,
+		To:          days[len(days)-1].Date,
+		UsageTotals: convertUsageTotals(totals),
+	})
+}
+
+// handleUsageDaily returns the tenant's usage per day.
+func (s *Server) handleUsageDaily(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := s.usageTenant(w, r)
+	if !ok {
+		return
+	}
+	days, ok := s.usageDays(w, r, tenant)
+	if !ok {
+		return
+	}
+
+	response := v1.UsageDailyResponse{Tenant: tenant, Days: make([]v1.UsageDay, 0, len(days))}
+	for _, day := range days {
+		response.Days = append(response.Days, v1.UsageDay{
+			Date:        day.Date,
+			UsageTotals: convertUsageTotals(day.Totals),
+		})
+	}
+	writeUsageJSON(w, response)
+}
+
+// handleUsageTopModels returns the tenant's most used models, ranked by
+// requests, tokens or cost.
+func (s *Server) handleUsageTopModels(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := s.usageTenant(w, r)
+	if !ok {
+		return
+	}
+
+	by := r.URL.Query().Get("by")
+	if by == "" {
+		by = "requests"
+	}
+	if by != "requests" && by != "tokens" && by != "cost" {
+		http.Error(w, "by must be requests, tokens or cost", http.StatusBadRequest)
+		return
+	}
+	limit := defaultTopModels
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > usageTopModelsMax {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(usageTopModelsMax), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	days, ok := s.usageDays(w, r, tenant)
+	if !ok {
+		return
+	}
+
+	byModel := make(map[string]*usage.ModelUsage)
+	for _, day := range days {
+		for _, model := range day.Models {
+			key := model.Provider + "/" + model.Model
+			total, exists := byModel[key]
+			if !exists {
+				total = &usage.ModelUsage{Provider: model.Provider, Model: model.Model}
+				byModel[key] = total
+			}
+			total.Add(model.Totals)
+		}
+	}
+
+	models := make([]v1.ModelUsage, 0, len(byModel))
+	for _, model := range byModel {
+		models = append(models, v1.ModelUsage{
+			Provider:    model.Provider,
+			Model:       model.Model,
+			UsageTotals: convertUsageTotals(model.Totals),
+		})
+	}
+	rank := func(model v1.ModelUsage) float64 {
+		switch by {
+		case "tokens":
+			return float64(model.TotalTokens)
+		case "cost":
+			return model.Cost
+		default:
+			return float64(model.Requests)
+		}
+	}
+	sort.Slice(models, func(i, j int) bool {
+		if rank(models[i]) != rank(models[j]) {
+			return rank(models[i]) > rank(models[j])
+		}
+		return models[i].Provider+"/"+models[i].Model < models[j].Provider+"/"+models[j].Model
+	})
+	if len(models) > limit {
+		models = models[:limit]
+	}
+
+	writeUsageJSON(w, v1.UsageTopModelsResponse{
+		Tenant: tenant,
+		From:   days[0].Date,
+		To:     days[len(days)-1].Date,
+		By:     by,
+		Models: models,
+	})
+}
+
+// convertUsageTotals converts usage totals to the API format.
+func convertUsageTotals(totals usage.Totals) v1.UsageTotals {
+	return v1.UsageTotals{
+		Requests:         totals.Requests,
+		CacheHits:        totals.CacheHits,
+		Aborted:          totals.Aborted,
+		PromptTokens:     totals.PromptTokens,
+		CompletionTokens: totals.CompletionTokens,
+		TotalTokens:      totals.PromptTokens + totals.CompletionTokens,
+		Cost:             totals.Cost,
+	}
+}
+
+// writeUsageJSON writes a usage response.
+func writeUsageJSON(w http.ResponseWriter, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, max-age=60")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// captureLogprobs adds the sampled token distributions of a response to its
+// usage record, when the request asked for logprobs and its tenant opted in.
+func (s *Server) captureLogprobs(record *usage.Record, req models.ChatRequest, response *models.ChatResponse) {
+	config := s.config.Usage.Logprobs
+	if !config.Enabled || s.usageStore == nil || !req.Logprobs || len(response.Choices) == 0 {
+		return
+	}
+	if tenant, found := s.tenants.Get(record.Tenant); !found || !tenant.CaptureLogprobs {
+		return
+	}
+	if rand.Float64() >= config.SampleRate {
+		return
+	}
+
+	logprobs := response.Choices[0].Logprobs
+	if logprobs == nil {
+		return
+	}
+	record.Logprobs = usage.CaptureLogprobs(config, logprobs)
+	record.LogprobTokens = len(logprobs.Content)
+}
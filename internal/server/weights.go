@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// ProviderWeightUpdate is the body of PATCH /admin/providers/{name}/weight:
+// a weight to set, or reset to go back to the routing policy's own weight.
+type ProviderWeightUpdate struct {
+	Weight *float64 `json:"weight,omitempty"`
+	Reset  bool     `json:"reset,omitempty"`
+}
+
+// providerWeightStatus is the response of PATCH
+// /admin/providers/{name}/weight.
+type providerWeightStatus struct {
+	Provider  string    `json:"provider"`
+	Weight    *float64  `json:"weight"` // null when the policy's own weight applies
+	Previous  *float64  `json:"previous"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// handleSetProviderWeight sets the routing weight of a provider at runtime,
+// for the weighted and cost-based policies. A weight of 0 drains the
+// provider. The weight applies to this replica until it is reset or the
+// server restarts.
+func (s *Server) handleSetProviderWeight(w http.ResponseWriter, r *http.Request) {
+	providerName := chi.URLParam(r, "name")
+	if _, exists := s.providers.Get(providerName); !exists {
+		http.Error(w, "Provider not found", http.StatusNotFound)
+		return
+	}
+
+	var update ProviderWeightUpdate
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&update); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if (update.Weight == nil) == !update.Reset {
+		http.Error(w, "Exactly one of weight and reset is required", http.StatusBadRequest)
+		return
+	}
+
+	status := providerWeightStatus{Provider: providerName, UpdatedAt: time.Now()}
+	var previous float64
+	var existed bool
+	if update.Reset {
+		previous, existed = s.weights.Clear(providerName)
+		s.metrics.ClearOperatorWeight(providerName)
+	} else {
+		var err error
+		previous, existed, err = s.weights.Set(providerName, *update.Weight)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid weight: %v", err), http.StatusBadRequest)
+			return
+		}
+		s.metrics.RecordOperatorWeight(providerName, *update.Weight)
+		status.Weight = update.Weight
+	}
+	if existed {
+		status.Previous = &previous
+	}
+
+	s.logger.Info("Provider weight updated",
+		zap.String("provider", providerName),
+		zap.Any("from", status.Previous),
+		zap.Any("to", status.Weight))
+	s.recordAudit(r, "provider.weight", providerName, status.Previous, status.Weight)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,103 @@
+package shadow
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+// EmbedFunc returns embedding vectors for the given texts.
+type EmbedFunc func(ctx context.Context, texts []string) ([][]float64, error)
+
+// Sample is one side of a primary/shadow pair.
+type Sample struct {
+	Provider string
+	Model    string
+	Response *models.ChatResponse
+	Latency  time.Duration
+	Cost     float64
+	Err      error
+}
+
+// Comparison holds the similarity metrics between a primary and a shadow response.
+type Comparison struct {
+	RequestID       string        `json:"request_id,omitempty"`
+	Timestamp       time.Time     `json:"timestamp"`
+	PrimaryProvider string        `json:"primary_provider"`
+	PrimaryModel    string        `json:"primary_model"`
+	ShadowProvider  string        `json:"shadow_provider"`
+	ShadowModel     string        `json:"shadow_model"`
+	ShadowError     string        `json:"shadow_error,omitempty"`
+	ExactMatch      bool          `json:"exact_match"`
+	EmbeddingCosine *float64      `json:"embedding_cosine,omitempty"`
+	LengthDelta     int           `json:"length_delta"`
+	LatencyDelta    time.Duration `json:"latency_delta"`
+	CostDelta       float64       `json:"cost_delta"`
+}
+
+// Compare computes the similarity metrics between a primary and a shadow sample.
+// Deltas are shadow minus primary. The embedding cosine is only computed when
+// embed is non-nil and both responses succeeded.
+func Compare(ctx context.Context, requestID string, primary, shadow Sample, embed EmbedFunc) Comparison {
+	comparison := Comparison{
+		RequestID:       requestID,
+		Timestamp:       time.Now(),
+		PrimaryProvider: primary.Provider,
+		PrimaryModel:    primary.Model,
+		ShadowProvider:  shadow.Provider,
+		ShadowModel:     shadow.Model,
+		LatencyDelta:    shadow.Latency - primary.Latency,
+		CostDelta:       shadow.Cost - primary.Cost,
+	}
+
+	if shadow.Err != nil {
+		comparison.ShadowError = shadow.Err.Error()
+		return comparison
+	}
+
+	primaryText := responseText(primary.Response)
+	shadowText := responseText(shadow.Response)
+
+	comparison.ExactMatch = strings.TrimSpace(primaryText) == strings.TrimSpace(shadowText)
+	comparison.LengthDelta = len(shadowText) - len(primaryText)
+
+	if embed != nil && primaryText != "" && shadowText != "" {
+		vectors, err := embed(ctx, []string{primaryText, shadowText})
+		if err == nil && len(vectors) == 2 {
+			cosine := cosineSimilarity(vectors[0], vectors[1])
+			comparison.EmbeddingCosine = &cosine
+		}
+	}
+
+	return comparison
+}
+
+// responseText returns the content of the first choice of a response.
+func responseText(response *models.ChatResponse) string {
+	if response == nil || len(response.Choices) == 0 {
+		return ""
+	}
+	return response.Choices[0].Message.Content.Text()
+}
+
+// cosineSimilarity returns the cosine similarity of two vectors.
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
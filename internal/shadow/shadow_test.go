@@ -0,0 +1,115 @@
+package shadow
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+func response(text string) *models.ChatResponse {
+	return &models.ChatResponse{Choices: []models.Choice{{Message: models.Message{Role: "assistant", Content: models.TextContent(text)}}}}
+}
+
+func TestCompare(t *testing.T) {
+	primary := Sample{Provider: "openai", Model: "gpt-4o", Response: response("Paris"), Latency: 300 * time.Millisecond, Cost: 0.002}
+	embed := func(ctx context.Context, texts []string) ([][]float64, error) {
+		return [][]float64{{1, 0}, {1, 1}}, nil
+	}
+
+	tests := []struct {
+		name      string
+		shadow    Sample
+		embed     EmbedFunc
+		exact     bool
+		length    int
+		cosine    float64 // 0 means no cosine expected
+		shadowErr string
+	}{
+		{
+			name:   "exact match ignores surrounding space",
+			shadow: Sample{Provider: "anthropic", Response: response(" Paris\n"), Latency: 500 * time.Millisecond, Cost: 0.003},
+			exact:  true,
+			length: 2,
+		},
+		{
+			name:   "embedding cosine",
+			shadow: Sample{Provider: "anthropic", Response: response("Paris, France"), Latency: 500 * time.Millisecond, Cost: 0.003},
+			embed:  embed,
+			length: 8,
+			cosine: 1 / math.Sqrt2,
+		},
+		{
+			name:      "shadow error skips similarity",
+			shadow:    Sample{Provider: "anthropic", Err: errors.New("timeout"), Latency: 500 * time.Millisecond, Cost: 0.003},
+			embed:     embed,
+			shadowErr: "timeout",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Compare(context.Background(), "req-1", primary, tt.shadow, tt.embed)
+			if c.ShadowError != tt.shadowErr || c.ExactMatch != tt.exact || c.LengthDelta != tt.length {
+				t.Fatalf("Compare() = %+v", c)
+			}
+			if c.LatencyDelta != 200*time.Millisecond || math.Abs(c.CostDelta-0.001) > 1e-9 {
+				t.Fatalf("deltas = %v, %v, want shadow minus primary", c.LatencyDelta, c.CostDelta)
+			}
+			switch {
+			case tt.cosine == 0 && c.EmbeddingCosine != nil:
+				t.Fatalf("cosine = %v, want none", *c.EmbeddingCosine)
+			case tt.cosine != 0 && (c.EmbeddingCosine == nil || math.Abs(*c.EmbeddingCosine-tt.cosine) > 1e-9):
+				t.Fatalf("cosine = %v, want %v", c.EmbeddingCosine, tt.cosine)
+			}
+		})
+	}
+}
+
+func TestReportExcludesErroredSamples(t *testing.T) {
+	store := NewStore(Config{})
+	store.Add(Comparison{ShadowProvider: "anthropic", ExactMatch: true, LengthDelta: 4, LatencyDelta: 100 * time.Millisecond, CostDelta: 0.01})
+	store.Add(Comparison{ShadowProvider: "anthropic", LengthDelta: -2, LatencyDelta: 300 * time.Millisecond, CostDelta: 0.03})
+	store.Add(Comparison{ShadowProvider: "anthropic", ShadowError: "timeout", LatencyDelta: 60 * time.Second, CostDelta: -0.5})
+
+	report := store.Report("anthropic")
+	if report.Samples != 3 || report.Errors != 1 {
+		t.Fatalf("samples, errors = %d, %d, want 3, 1", report.Samples, report.Errors)
+	}
+	if report.ExactMatchRate != 0.5 || report.MeanLengthDelta != 1 {
+		t.Fatalf("exact match rate, mean length delta = %v, %v, want 0.5, 1", report.ExactMatchRate, report.MeanLengthDelta)
+	}
+	if math.Abs(report.TotalCostDelta-0.04) > 1e-9 || math.Abs(report.MeanCostDelta-0.02) > 1e-9 {
+		t.Fatalf("cost deltas = %v total, %v mean, want 0.04, 0.02", report.TotalCostDelta, report.MeanCostDelta)
+	}
+	if report.MedianLatencyDelta != 300*time.Millisecond || report.P95LatencyDelta != 300*time.Millisecond {
+		t.Fatalf("latency deltas = %v median, %v p95, want the errored sample left out", report.MedianLatencyDelta, report.P95LatencyDelta)
+	}
+}
+
+func TestReportWithOnlyErroredSamples(t *testing.T) {
+	store := NewStore(Config{})
+	store.Add(Comparison{ShadowProvider: "anthropic", ShadowError: "timeout", LatencyDelta: time.Second, CostDelta: 0.1})
+
+	report := store.Report("anthropic")
+	if report.Errors != 1 || report.TotalCostDelta != 0 || report.MedianLatencyDelta != 0 {
+		t.Fatalf("Report() = %+v, want only the error counted", report)
+	}
+	if empty := store.Report("openai"); empty.Samples != 0 {
+		t.Fatalf("Report() of an unknown provider = %+v", empty)
+	}
+}
+
+func TestStoreEvictsOldestSamples(t *testing.T) {
+	store := NewStore(Config{MaxSamples: 2})
+	for _, id := range []string{"a", "b", "c"} {
+		store.Add(Comparison{RequestID: id, ShadowProvider: "anthropic"})
+	}
+
+	samples := store.List("anthropic")
+	if len(samples) != 2 || samples[0].RequestID != "b" || samples[1].RequestID != "c" {
+		t.Fatalf("List() = %+v, want b and c", samples)
+	}
+}
@@ -0,0 +1,177 @@
+package shadow
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Config holds configuration for shadow traffic mirroring and comparison storage.
+type Config struct {
+	MaxSamples int `mapstructure:"max_samples"` // comparisons kept per shadow provider
+
+	// Targets receive a copy of a share of production chat completions; their
+	// responses are compared with the primary's and discarded.
+	Targets       []Target      `mapstructure:"targets"`
+	MaxConcurrent int           `mapstructure:"max_concurrent"` // mirrored requests in flight, beyond which samples are dropped
+	Timeout       time.Duration `mapstructure:"timeout"`        // per mirrored request
+
+	// Embedding model used to score the similarity of responses; empty skips it
+	EmbeddingProvider string `mapstructure:"embedding_provider"`
+	EmbeddingModel    string `mapstructure:"embedding_model"`
+}
+
+// Target is a provider mirrored production traffic is sent to.
+type Target struct {
+	Provider   string  `mapstructure:"provider"`
+	Model      string  `mapstructure:"model"`      // empty keeps the routed model
+	Percentage float64 `mapstructure:"percentage"` // of non-streaming chat completions mirrored
+}
+
+// WithDefaults fills in unset mirroring fields and validates the configuration.
+func (c Config) WithDefaults() (Config, error) {
+	if c.MaxConcurrent <= 0 {
+		c.MaxConcurrent = 10
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 60 * time.Second
+	}
+
+	for _, target := range c.Targets {
+		if target.Provider == "" {
+			return c, fmt.Errorf("shadow target without a provider")
+		}
+		if target.Percentage <= 0 || target.Percentage > 100 {
+			return c, fmt.Errorf("percentage of shadow target %s must be between 0 and 100", target.Provider)
+		}
+	}
+	if c.EmbeddingProvider != "" && c.EmbeddingModel == "" {
+		return c, fmt.Errorf("shadow embedding_provider requires an embedding_model")
+	}
+	return c, nil
+}
+
+// Report aggregates the comparisons recorded for a shadow provider.
+type Report struct {
+	ShadowProvider     string        `json:"shadow_provider"`
+	Samples            int           `json:"samples"`
+	Errors             int           `json:"errors"`
+	ExactMatchRate     float64       `json:"exact_match_rate"`
+	MeanCosine         *float64      `json:"mean_embedding_cosine,omitempty"`
+	MeanLengthDelta    float64       `json:"mean_length_delta"`
+	MedianLatencyDelta time.Duration `json:"median_latency_delta"`
+	P95LatencyDelta    time.Duration `json:"p95_latency_delta"`
+	MeanCostDelta      float64       `json:"mean_cost_delta"`
+	TotalCostDelta     float64       `json:"total_cost_delta"`
+	Since              time.Time     `json:"since"`
+}
+
+// Store keeps the most recent comparisons per shadow provider.
+type Store struct {
+	maxSamples  int
+	comparisons map[string][]Comparison
+	mutex       sync.RWMutex
+}
+
+// NewStore creates a new comparison store.
+func NewStore(config Config) *Store {
+	maxSamples := config.MaxSamples
+	if maxSamples <= 0 {
+		maxSamples = 1000
+	}
+
+	return &Store{
+		maxSamples:  maxSamples,
+		comparisons: make(map[string][]Comparison),
+	}
+}
+
+// Add records a comparison, evicting the oldest one for the provider when full.
+func (s *Store) Add(comparison Comparison) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	samples := append(s.comparisons[comparison.ShadowProvider], comparison)
+	if len(samples) > s.maxSamples {
+		samples = samples[len(samples)-s.maxSamples:]
+	}
+	s.comparisons[comparison.ShadowProvider] = samples
+}
+
+// List returns the recorded comparisons for a shadow provider, oldest first.
+func (s *Store) List(provider string) []Comparison {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]Comparison, len(s.comparisons[provider]))
+	copy(result, s.comparisons[provider])
+	return result
+}
+
+// Providers returns the shadow providers that have recorded comparisons.
+func (s *Store) Providers() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	providers := make([]string, 0, len(s.comparisons))
+	for name := range s.comparisons {
+		providers = append(providers, name)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// Report aggregates the comparisons for a shadow provider.
+func (s *Store) Report(provider string) Report {
+	samples := s.List(provider)
+	report := Report{
+		ShadowProvider: provider,
+		Samples:        len(samples),
+	}
+	if len(samples) == 0 {
+		return report
+	}
+	report.Since = samples[0].Timestamp
+
+	var exact, succeeded, cosineCount int
+	var lengthSum, cosineSum float64
+	latencies := make([]time.Duration, 0, len(samples))
+
+	// Errored samples only count as errors: a failed shadow call has no
+	// meaningful cost or latency to compare
+	for _, c := range samples {
+		if c.ShadowError != "" {
+			report.Errors++
+			continue
+		}
+		succeeded++
+		report.TotalCostDelta += c.CostDelta
+		latencies = append(latencies, c.LatencyDelta)
+		if c.ExactMatch {
+			exact++
+		}
+		lengthSum += float64(c.LengthDelta)
+		if c.EmbeddingCosine != nil {
+			cosineSum += *c.EmbeddingCosine
+			cosineCount++
+		}
+	}
+
+	if cosineCount > 0 {
+		mean := cosineSum / float64(cosineCount)
+		report.MeanCosine = &mean
+	}
+	if succeeded == 0 {
+		return report
+	}
+	report.ExactMatchRate = float64(exact) / float64(succeeded)
+	report.MeanLengthDelta = lengthSum / float64(succeeded)
+	report.MeanCostDelta = report.TotalCostDelta / float64(succeeded)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	report.MedianLatencyDelta = latencies[len(latencies)/2]
+	report.P95LatencyDelta = latencies[(len(latencies)*95)/100]
+
+	return report
+}
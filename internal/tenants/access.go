@@ -0,0 +1,66 @@
+package tenants
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Access restricts the models and providers a tenant or API key may use.
+// Entries are names, or prefixes ending in *, e.g. gpt-4o*. Empty allow lists
+// allow everything; deny lists take precedence over allow lists.
+type Access struct {
+	AllowModels    []string `mapstructure:"allow_models"`
+	DenyModels     []string `mapstructure:"deny_models"`
+	AllowProviders []string `mapstructure:"allow_providers"`
+	DenyProviders  []string `mapstructure:"deny_providers"`
+}
+
+// IsZero reports whether the access lists restrict nothing.
+func (a Access) IsZero() bool {
+	return len(a.AllowModels) == 0 && len(a.DenyModels) == 0 &&
+		len(a.AllowProviders) == 0 && len(a.DenyProviders) == 0
+}
+
+// AllowsModel reports whether the model may be used.
+func (a Access) AllowsModel(model string) bool {
+	return allows(a.AllowModels, a.DenyModels, model)
+}
+
+// AllowsProvider reports whether the provider may be used.
+func (a Access) AllowsProvider(provider string) bool {
+	return allows(a.AllowProviders, a.DenyProviders, provider)
+}
+
+// validate rejects empty entries and wildcards other than a trailing *.
+func (a Access) validate() error {
+	for _, list := range [][]string{a.AllowModels, a.DenyModels, a.AllowProviders, a.DenyProviders} {
+		for _, pattern := range list {
+			if pattern == "" || strings.Contains(strings.TrimSuffix(pattern, "*"), "*") {
+				return fmt.Errorf("invalid access list entry %q", pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// allows applies an allow and a deny list to a name.
+func allows(allow, deny []string, name string) bool {
+	if matchesAny(deny, name) {
+		return false
+	}
+	return len(allow) == 0 || matchesAny(allow, name)
+}
+
+// matchesAny reports whether any pattern matches the name.
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if prefix, wildcard := strings.CutSuffix(pattern, "*"); wildcard {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if pattern == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package tenants
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAccessAllows(t *testing.T) {
+	access := Access{
+		AllowModels:    []string{"gpt-4o*", "claude-sonnet-4"},
+		DenyModels:     []string{"gpt-4o-audio*"},
+		AllowProviders: []string{"openai", "anthropic"},
+		DenyProviders:  []string{"anthropic"},
+	}
+	models := map[string]bool{
+		"gpt-4o":                   true,
+		"gpt-4o-mini":              true,
+		"claude-sonnet-4":          true,
+		"claude-sonnet-4-20250514": false, // exact entries are not prefixes
+		"gpt-4o-audio-preview":     false, // denied despite the allowed prefix
+		"gpt-4":                    false,
+	}
+	for model, want := range models {
+		if got := access.AllowsModel(model); got != want {
+			t.Errorf("AllowsModel(%q) = %v, want %v", model, got, want)
+		}
+	}
+	providers := map[string]bool{"openai": true, "anthropic": false, "azure": false}
+	for provider, want := range providers {
+		if got := access.AllowsProvider(provider); got != want {
+			t.Errorf("AllowsProvider(%q) = %v, want %v", provider, got, want)
+		}
+	}
+
+	if !(Access{}).AllowsModel("anything") || !(Access{}).AllowsProvider("anything") || !(Access{}).IsZero() {
+		t.Error("empty access lists should allow everything")
+	}
+	if !(Access{DenyModels: []string{"gpt-4"}}).AllowsModel("gpt-4o") {
+		t.Error("a deny list alone should allow what it does not match")
+	}
+	if (Access{DenyProviders: []string{"azure"}}).IsZero() {
+		t.Error("IsZero() = true with a deny list")
+	}
+}
+
+func TestNewRegistryValidatesAccessLists(t *testing.T) {
+	tests := map[string]Access{
+		"empty entry":      {AllowModels: []string{""}},
+		"inner wildcard":   {DenyModels: []string{"gpt-*-mini"}},
+		"leading wildcard": {AllowProviders: []string{"*ai"}},
+		"doubled wildcard": {DenyProviders: []string{"open**"}},
+	}
+	for name, access := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewRegistry(Config{Tenants: []TenantConfig{{ID: "acme", Access: access}}})
+			if err == nil || !strings.Contains(err.Error(), "invalid access list entry") {
+				t.Fatalf("NewRegistry() with tenant access %+v error = %v", access, err)
+			}
+			_, err = NewRegistry(Config{RequireAPIKey: true, Tenants: []TenantConfig{{ID: "acme", Keys: []KeyConfig{{Key: "sk-acme", Access: access}}}}})
+			if err == nil || !strings.Contains(err.Error(), "invalid access list entry") {
+				t.Fatalf("NewRegistry() with key access %+v error = %v", access, err)
+			}
+		})
+	}
+}
+
+func TestRegistryCarriesAccessLists(t *testing.T) {
+	tenantAccess := Access{AllowProviders: []string{"openai"}}
+	keyAccess := Access{AllowModels: []string{"gpt-4o-mini"}}
+	registry, err := NewRegistry(Config{RequireAPIKey: true, Tenants: []TenantConfig{{
+		ID:      "acme",
+		Access:  tenantAccess,
+		APIKeys: []string{"sk-plain"},
+		Keys:    []KeyConfig{{Key: "sk-restricted", Access: keyAccess}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tenant, _ := registry.Get("acme")
+	if !tenant.Access.AllowsProvider("openai") || tenant.Access.AllowsProvider("anthropic") {
+		t.Fatalf("tenant access = %+v, want %+v", tenant.Access, tenantAccess)
+	}
+	restricted, _ := registry.Authenticate("sk-restricted")
+	if restricted.Access.AllowsModel("gpt-4o") || !restricted.Access.AllowsModel("gpt-4o-mini") {
+		t.Fatalf("key access = %+v, want %+v", restricted.Access, keyAccess)
+	}
+	plain, _ := registry.Authenticate("sk-plain")
+	if !plain.Access.IsZero() {
+		t.Fatalf("key without access lists has %+v", plain.Access)
+	}
+}
@@ -0,0 +1,9 @@
+package tenants
+
+import "github.com/semantrix/semaroute/internal/migrate"
+
+// Migrations upgrade the state file from the format of older releases, oldest
+// first. Add one whenever a release changes how states are written.
+var Migrations = []migrate.Migration{
+	{Version: 1, Description: "baseline", Apply: migrate.Baseline},
+}
@@ -0,0 +1,64 @@
+package tenants
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Scopes an API key can carry.
+const (
+	// ScopeModelsRead allows the read-only endpoints: models, routing info,
+	// metrics and usage.
+	ScopeModelsRead = "models:read"
+	// ScopeChatWrite allows the endpoints that run inference.
+	ScopeChatWrite = "chat:write"
+	// ScopeVoucherKey allows redeeming gatekeeper vouchers for provider API
+	// keys. No key gets it by default.
+	ScopeVoucherKey = "vouchers:key"
+	// ScopeAdmin allows the admin API and everything else.
+	ScopeAdmin = "admin:*"
+)
+
+// defaultScopes are granted to keys configured without scopes, which keeps the
+// access of keys from before scopes existed.
+var defaultScopes = Scopes{ScopeModelsRead, ScopeChatWrite}
+
+// knownScopes lists the scopes keys may be configured with.
+var knownScopes = map[string]bool{
+	ScopeModelsRead: true,
+	ScopeChatWrite:  true,
+	ScopeVoucherKey: true,
+	ScopeAdmin:      true,
+	"models:*":      true,
+	"chat:*":        true,
+}
+
+// Scopes is the set of scopes of an API key.
+type Scopes []string
+
+// Allows reports whether the scopes grant the required one. A scope ending in
+// ":*" grants every scope of its resource, and admin:* grants all scopes.
+func (s Scopes) Allows(required string) bool {
+	for _, scope := range s {
+		if scope == required || scope == ScopeAdmin {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(scope, "*"); ok && strings.HasPrefix(required, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseScopes validates configured scopes, defaulting to defaultScopes.
+func parseScopes(scopes []string) (Scopes, error) {
+	if len(scopes) == 0 {
+		return defaultScopes, nil
+	}
+	for _, scope := range scopes {
+		if !knownScopes[scope] {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	return Scopes(scopes), nil
+}
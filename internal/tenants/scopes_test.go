@@ -0,0 +1,80 @@
+package tenants
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScopesAllow(t *testing.T) {
+	tests := []struct {
+		scopes   Scopes
+		required string
+		want     bool
+	}{
+		{Scopes{ScopeChatWrite}, ScopeChatWrite, true},
+		{Scopes{ScopeChatWrite}, ScopeModelsRead, false},
+		{Scopes{"models:*"}, ScopeModelsRead, true},
+		{Scopes{"chat:*"}, ScopeModelsRead, false},
+		{Scopes{ScopeAdmin}, ScopeVoucherKey, true},
+		{Scopes{ScopeAdmin}, ScopeAdmin, true},
+		{Scopes{"chat:*", ScopeModelsRead}, ScopeAdmin, false},
+		{defaultScopes, ScopeVoucherKey, false},
+		{nil, ScopeModelsRead, false},
+	}
+	for _, test := range tests {
+		if got := test.scopes.Allows(test.required); got != test.want {
+			t.Errorf("%v.Allows(%q) = %v, want %v", test.scopes, test.required, got, test.want)
+		}
+	}
+}
+
+func TestNewRegistryScopesKeys(t *testing.T) {
+	registry, err := NewRegistry(Config{RequireAPIKey: true, Tenants: []TenantConfig{{
+		ID:      "ops",
+		APIKeys: []string{"sk-default"},
+		Keys:    []KeyConfig{{Key: "sk-admin", Scopes: []string{ScopeAdmin}}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, _ := registry.Authenticate("sk-default"); key.Scopes.Allows(ScopeAdmin) || !key.Scopes.Allows(ScopeChatWrite) {
+		t.Fatalf("key without scopes has %v, want %v", key.Scopes, defaultScopes)
+	}
+	if key, _ := registry.Authenticate("sk-admin"); !key.Scopes.Allows(ScopeAdmin) {
+		t.Fatalf("admin key has %v", key.Scopes)
+	}
+	if _, ok := registry.Authenticate("sk-unknown"); ok {
+		t.Fatal("Authenticate() accepted an unknown key")
+	}
+
+	_, err = NewRegistry(Config{RequireAPIKey: true, Tenants: []TenantConfig{{ID: "ops", Keys: []KeyConfig{{Key: "sk-admin", Scopes: []string{"admin"}}}}}})
+	if err == nil {
+		t.Fatal("NewRegistry() accepted an unknown scope")
+	}
+}
+
+func TestNewRegistryRequiresAPIKeyForKeyRestrictions(t *testing.T) {
+	tests := map[string]KeyConfig{
+		"scopes":    {Key: "sk-acme", Scopes: []string{ScopeModelsRead}},
+		"residency": {Key: "sk-acme", Residency: "eu-only"},
+		"access":    {Key: "sk-acme", Access: Access{DenyModels: []string{"gpt-4o"}}},
+		"priority":  {Key: "sk-acme", Priority: "high"},
+	}
+	for name, key := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := Config{Tenants: []TenantConfig{{ID: "acme", Keys: []KeyConfig{key}}}}
+			if _, err := NewRegistry(config); err == nil || !strings.Contains(err.Error(), "require_api_key") {
+				t.Fatalf("NewRegistry() without require_api_key error = %v", err)
+			}
+			config.RequireAPIKey = true
+			if _, err := NewRegistry(config); err != nil {
+				t.Fatalf("NewRegistry() with require_api_key error = %v", err)
+			}
+		})
+	}
+
+	plain := Config{Tenants: []TenantConfig{{ID: "acme", APIKeys: []string{"sk-plain"}, Keys: []KeyConfig{{Key: "sk-keyed"}}}}}
+	if _, err := NewRegistry(plain); err != nil {
+		t.Fatalf("NewRegistry() with unrestricted keys error = %v", err)
+	}
+}
@@ -0,0 +1,442 @@
+// Package tenants identifies the tenant a request is made for from its API key.
+package tenants
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/language"
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+// State is the lifecycle state of a tenant.
+type State string
+
+// Tenant states. Suspended and deleted tenants are refused service, but their
+// keys, budgets and usage are kept so they can be reactivated at any time.
+const (
+	StateActive    State = "active"
+	StateSuspended State = "suspended"
+	StateDeleted   State = "deleted"
+)
+
+// Valid reports whether s is a known state.
+func (s State) Valid() bool {
+	return s == StateActive || s == StateSuspended || s == StateDeleted
+}
+
+// ErrUnknownTenant is returned when changing the state of an unknown tenant.
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+// Config holds the tenant registry.
+type Config struct {
+	// RequireAPIKey rejects /v1 requests without a valid tenant API key.
+	// Without it, requests with an unknown or missing key fall back to the
+	// X-Semaroute-Tenant header, which is only safe behind a trusted proxy.
+	RequireAPIKey bool           `mapstructure:"require_api_key"`
+	Tenants       []TenantConfig `mapstructure:"tenants"`
+
+	// SuspendedMessage is returned to suspended tenants without a message of
+	// their own.
+	SuspendedMessage string `mapstructure:"suspended_message"`
+
+	// StateFile persists state changes made at runtime, which take precedence
+	// over the configured states. Empty keeps them in memory only.
+	StateFile string `mapstructure:"state_file"`
+
+	// RequireAdminKey restricts the admin API to keys with the admin:* scope.
+	RequireAdminKey bool `mapstructure:"require_admin_key"`
+
+	// Defaults are the global generation defaults, applied when neither the
+	// request nor its tenant sets a parameter.
+	Defaults Defaults `mapstructure:"defaults"`
+}
+
+// Defaults are generation parameters applied to chat requests that omit them.
+// Zero values are unset.
+type Defaults struct {
+	Temperature    float64  `mapstructure:"temperature"`
+	MaxTokens      int      `mapstructure:"max_tokens"`
+	Stop           []string `mapstructure:"stop"`
+	SystemPreamble string   `mapstructure:"system_preamble"` // system message for requests without one
+}
+
+// validate rejects out-of-range parameters.
+func (d Defaults) validate() error {
+	if d.Temperature < 0 || d.Temperature > 2 {
+		return fmt.Errorf("default temperature %v is outside 0-2", d.Temperature)
+	}
+	if d.MaxTokens < 0 {
+		return fmt.Errorf("default max_tokens %d is negative", d.MaxTokens)
+	}
+	return nil
+}
+
+// validPriority reports whether a configured priority tier is known; empty
+// leaves it unset.
+func validPriority(priority string) bool {
+	switch priority {
+	case "", models.PriorityHigh, models.PriorityNormal, models.PriorityLow:
+		return true
+	}
+	return false
+}
+
+// TenantConfig describes one tenant.
+type TenantConfig struct {
+	ID      string   `mapstructure:"id"`
+	Name    string   `mapstructure:"name"`
+	APIKeys []string `mapstructure:"api_keys"` // keys with the default scopes
+
+	// Keys are API keys with explicit scopes.
+	Keys []KeyConfig `mapstructure:"keys"`
+
+	State   State  `mapstructure:"state"`   // defaults to active
+	Message string `mapstructure:"message"` // returned while suspended
+
+	// Defaults override the global generation defaults for the tenant.
+	Defaults Defaults `mapstructure:"defaults"`
+
+	// CaptureLogprobs opts the tenant in to the capture of token
+	// distributions for evaluation (usage.logprobs).
+	CaptureLogprobs bool `mapstructure:"capture_logprobs"`
+
+	// Deterministic routes every request of the tenant in deterministic
+	// mode, as the X-Semaroute-Deterministic header does per request.
+	Deterministic bool `mapstructure:"deterministic"`
+
+	// DatasetConsent allows the tenant's completions to be sampled, with
+	// personal data scrubbed, into the fine-tuning dataset (dataset).
+	DatasetConsent bool `mapstructure:"dataset_consent"`
+
+	// Residency keeps every request of the tenant within the provider
+	// regions of a residency tag, e.g. eu-only (residency.zones).
+	Residency string `mapstructure:"residency"`
+
+	// Language is the ISO 639-1 code of the language the tenant's
+	// responses must be in, e.g. fr (language_enforcement).
+	Language string `mapstructure:"language"`
+
+	// Access restricts the models and providers the tenant's requests are
+	// routed to.
+	Access Access `mapstructure:"access"`
+
+	// Priority is the tier of the tenant's requests under saturation: high,
+	// normal (default) or low (rate_limit.priority).
+	Priority string `mapstructure:"priority"`
+}
+
+// KeyConfig describes an API key and what it may be used for.
+type KeyConfig struct {
+	Key       string   `mapstructure:"key"`
+	Scopes    []string `mapstructure:"scopes"`    // defaults to models:read and chat:write
+	Residency string   `mapstructure:"residency"` // residency tag applied to the key's requests, in addition to the tenant's
+	Access    Access   `mapstructure:"access"`    // model and provider restrictions, in addition to the tenant's
+	Priority  string   `mapstructure:"priority"`  // priority tier of the key's requests, in place of the tenant's
+}
+
+// restricts reports whether the key sets anything beyond its tenant: scopes,
+// residency, access lists or priority.
+func (k KeyConfig) restricts() bool {
+	return len(k.Scopes) > 0 || k.Residency != "" || !k.Access.IsZero() || k.Priority != ""
+}
+
+// Key is an authenticated API key.
+type Key struct {
+	Tenant    *Tenant
+	Scopes    Scopes
+	Residency string
+	Access    Access
+	Priority  string
+}
+
+// Tenant is a configured tenant.
+type Tenant struct {
+	ID              string
+	Name            string
+	Defaults        Defaults
+	CaptureLogprobs bool
+	Deterministic   bool
+	DatasetConsent  bool
+	Residency       string
+	Language        string
+	Access          Access
+	Priority        string
+}
+
+// Status is the lifecycle state of a tenant.
+type Status struct {
+	Tenant    string    `json:"tenant"`
+	Name      string    `json:"name,omitempty"`
+	State     State     `json:"state"`
+	Message   string    `json:"message,omitempty"`
+	ChangedAt time.Time `json:"changed_at,omitempty"` // zero for configured states
+}
+
+// Registry maps API keys to tenants. Keys are held as SHA-256 digests, so
+// lookups do not compare secrets byte by byte and keys are not kept in memory
+// longer than needed.
+type Registry struct {
+	requireAPIKey    bool
+	requireAdminKey  bool
+	suspendedMessage string
+	stateFile        string
+	defaults         Defaults
+	tenants          map[string]*Tenant
+	keys             map[[sha256.Size]byte]*Key
+
+	mutex    sync.RWMutex
+	statuses map[string]Status
+}
+
+// NewRegistry builds the registry, rejecting duplicate tenant IDs and keys.
+func NewRegistry(config Config) (*Registry, error) {
+	r := &Registry{
+		requireAPIKey:    config.RequireAPIKey,
+		requireAdminKey:  config.RequireAdminKey,
+		suspendedMessage: config.SuspendedMessage,
+		stateFile:        config.StateFile,
+		defaults:         config.Defaults,
+		tenants:          make(map[string]*Tenant),
+		keys:             make(map[[sha256.Size]byte]*Key),
+		statuses:         make(map[string]Status),
+	}
+	if r.suspendedMessage == "" {
+		r.suspendedMessage = "This account is suspended."
+	}
+	if err := config.Defaults.validate(); err != nil {
+		return nil, err
+	}
+
+	for _, tenantConfig := range config.Tenants {
+		if tenantConfig.ID == "" {
+			return nil, fmt.Errorf("tenant needs an id")
+		}
+		if _, exists := r.tenants[tenantConfig.ID]; exists {
+			return nil, fmt.Errorf("duplicate tenant %q", tenantConfig.ID)
+		}
+		if err := tenantConfig.Defaults.validate(); err != nil {
+			return nil, fmt.Errorf("tenant %q: %w", tenantConfig.ID, err)
+		}
+		if tenantConfig.Language != "" && !language.Supported(tenantConfig.Language) {
+			return nil, fmt.Errorf("tenant %q: unsupported language %q", tenantConfig.ID, tenantConfig.Language)
+		}
+		if err := tenantConfig.Access.validate(); err != nil {
+			return nil, fmt.Errorf("tenant %q: %w", tenantConfig.ID, err)
+		}
+		if !validPriority(tenantConfig.Priority) {
+			return nil, fmt.Errorf("tenant %q has unknown priority %q", tenantConfig.ID, tenantConfig.Priority)
+		}
+		tenant := &Tenant{
+			ID:              tenantConfig.ID,
+			Name:            tenantConfig.Name,
+			Defaults:        tenantConfig.Defaults,
+			CaptureLogprobs: tenantConfig.CaptureLogprobs,
+			Deterministic:   tenantConfig.Deterministic,
+			DatasetConsent:  tenantConfig.DatasetConsent,
+			Residency:       tenantConfig.Residency,
+			Language:        tenantConfig.Language,
+			Access:          tenantConfig.Access,
+			Priority:        tenantConfig.Priority,
+		}
+		r.tenants[tenant.ID] = tenant
+
+		state := tenantConfig.State
+		if state == "" {
+			state = StateActive
+		}
+		if !state.Valid() {
+			return nil, fmt.Errorf("tenant %q has unknown state %q", tenant.ID, state)
+		}
+		r.statuses[tenant.ID] = Status{Tenant: tenant.ID, Name: tenant.Name, State: state, Message: tenantConfig.Message}
+
+		keyConfigs := tenantConfig.Keys
+		for _, key := range tenantConfig.APIKeys {
+			keyConfigs = append(keyConfigs, KeyConfig{Key: key})
+		}
+		for _, keyConfig := range keyConfigs {
+			if keyConfig.Key == "" {
+				continue
+			}
+			scopes, err := parseScopes(keyConfig.Scopes)
+			if err != nil {
+				return nil, fmt.Errorf("tenant %q: %w", tenant.ID, err)
+			}
+			if err := keyConfig.Access.validate(); err != nil {
+				return nil, fmt.Errorf("tenant %q: key: %w", tenant.ID, err)
+			}
+			if !validPriority(keyConfig.Priority) {
+				return nil, fmt.Errorf("tenant %q: key has unknown priority %q", tenant.ID, keyConfig.Priority)
+			}
+			// Without required keys, a client drops the key to shed its restrictions
+			if !config.RequireAPIKey && keyConfig.restricts() {
+				return nil, fmt.Errorf("tenant %q: key scopes, residency, access and priority need tenancy.require_api_key", tenant.ID)
+			}
+			digest := sha256.Sum256([]byte(keyConfig.Key))
+			if _, exists := r.keys[digest]; exists {
+				return nil, fmt.Errorf("tenant %q reuses an API key of another tenant", tenant.ID)
+			}
+			r.keys[digest] = &Key{Tenant: tenant, Scopes: scopes, Residency: keyConfig.Residency, Access: keyConfig.Access, Priority: keyConfig.Priority}
+		}
+	}
+
+	if err := r.loadStates(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Authenticate returns the API key with its tenant and scopes.
+func (r *Registry) Authenticate(key string) (*Key, bool) {
+	if key == "" {
+		return nil, false
+	}
+	found, ok := r.keys[sha256.Sum256([]byte(key))]
+	return found, ok
+}
+
+// Get returns a tenant by ID.
+func (r *Registry) Get(id string) (*Tenant, bool) {
+	tenant, found := r.tenants[id]
+	return tenant, found
+}
+
+// Defaults returns the generation defaults of a tenant and the global ones.
+// Unknown tenants have no defaults of their own.
+func (r *Registry) Defaults(id string) (tenant, global Defaults) {
+	if t, found := r.tenants[id]; found {
+		tenant = t.Defaults
+	}
+	return tenant, r.defaults
+}
+
+// RequireAPIKey reports whether requests must carry a tenant API key.
+func (r *Registry) RequireAPIKey() bool {
+	return r.requireAPIKey
+}
+
+// HasKeys reports whether any tenant API key is configured.
+func (r *Registry) HasKeys() bool {
+	return len(r.keys) > 0
+}
+
+// RequireAdminKey reports whether the admin API requires a key with the
+// admin:* scope.
+func (r *Registry) RequireAdminKey() bool {
+	return r.requireAdminKey
+}
+
+// Status returns the lifecycle state of a tenant. The message of a suspended
+// tenant falls back to the configured default.
+func (r *Registry) Status(id string) (Status, bool) {
+	r.mutex.RLock()
+	status, found := r.statuses[id]
+	r.mutex.RUnlock()
+
+	if found && status.State == StateSuspended && status.Message == "" {
+		status.Message = r.suspendedMessage
+	}
+	return status, found
+}
+
+// Statuses returns the lifecycle states of all tenants, sorted by ID.
+func (r *Registry) Statuses() []Status {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	statuses := make([]Status, 0, len(r.statuses))
+	for _, status := range r.statuses {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Tenant < statuses[j].Tenant })
+	return statuses
+}
+
+// SetState changes the state of a tenant, taking effect on its next request.
+// The change is saved to the state file, if there is one.
+func (r *Registry) SetState(id string, state State, message string) (Status, error) {
+	if !state.Valid() {
+		return Status{}, fmt.Errorf("unknown state %q", state)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	status, found := r.statuses[id]
+	if !found {
+		return Status{}, ErrUnknownTenant
+	}
+	status.State = state
+	status.Message = message
+	status.ChangedAt = time.Now()
+
+	previous := r.statuses[id]
+	r.statuses[id] = status
+	if err := r.saveStates(); err != nil {
+		r.statuses[id] = previous
+		return Status{}, err
+	}
+	return status, nil
+}
+
+// loadStates applies the state changes saved in the state file.
+func (r *Registry) loadStates() error {
+	if r.stateFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(r.stateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read tenant states: %w", err)
+	}
+
+	var saved []Status
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("failed to parse tenant states: %w", err)
+	}
+	for _, status := range saved {
+		current, found := r.statuses[status.Tenant]
+		if !found || !status.State.Valid() {
+			continue // tenant removed from the configuration since
+		}
+		current.State = status.State
+		current.Message = status.Message
+		current.ChangedAt = status.ChangedAt
+		r.statuses[status.Tenant] = current
+	}
+	return nil
+}
+
+// saveStates writes the states changed at runtime to the state file. The
+// caller must hold the write lock.
+func (r *Registry) saveStates() error {
+	if r.stateFile == "" {
+		return nil
+	}
+
+	var changed []Status
+	for _, status := range r.statuses {
+		if !status.ChangedAt.IsZero() {
+			changed = append(changed, status)
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i].Tenant < changed[j].Tenant })
+
+	data, err := json.MarshalIndent(changed, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := r.stateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save tenant states: %w", err)
+	}
+	return os.Rename(tmp, r.stateFile)
+}
@@ -0,0 +1,39 @@
+package tokenizer
+
+import "strings"
+
+// contextWindows lists known context window sizes by model prefix. Longer
+// prefixes are listed before shorter ones that they share a stem with.
+var contextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"gpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo-16k", 16385},
+	{"gpt-3.5-turbo", 16385},
+	{"o1", 200000},
+	{"o3", 200000},
+	{"claude-3", 200000},
+	{"claude-2", 100000},
+	{"claude-instant", 100000},
+	{"ibm/granite-3", 131072},
+	{"ibm/granite", 8192},
+	{"meta-llama/llama-3-1", 131072},
+	{"meta-llama/llama-3-3", 131072},
+	{"meta-llama/llama-3", 8192},
+	{"mistralai/mixtral-8x7b", 32768},
+}
+
+// ContextWindow returns the known context window size for a model, or 0 if
+// the model is not known.
+func ContextWindow(model string) int {
+	for _, entry := range contextWindows {
+		if strings.HasPrefix(model, entry.prefix) {
+			return entry.tokens
+		}
+	}
+	return 0
+}
@@ -0,0 +1,98 @@
+package tokenizer
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+// TokenCounter counts tokens for a family of models.
+type TokenCounter interface {
+	// Name identifies the encoding, e.g. "cl100k_base".
+	Name() string
+
+	// CountText returns the number of tokens in text for the model.
+	CountText(model, text string) int
+
+	// CountMessages returns the number of prompt tokens for a conversation,
+	// including chat framing.
+	CountMessages(model string, messages []models.Message) int
+}
+
+// Matcher reports whether a counter applies to a provider's model.
+type Matcher func(provider, model string) bool
+
+// registration pairs a counter with the models it applies to.
+type registration struct {
+	match   Matcher
+	counter TokenCounter
+}
+
+var (
+	registry      []registration
+	registryMutex sync.RWMutex
+
+	// defaultCounter is used for models no registered counter matches.
+	defaultCounter TokenCounter = HeuristicCounter{Label: "heuristic", CharsPerToken: 4.0}
+)
+
+func init() {
+	Register(ProviderMatcher("watsonx"), HeuristicCounter{Label: "heuristic", CharsPerToken: 4.0})
+	Register(ProviderMatcher("anthropic"), HeuristicCounter{Label: "claude-heuristic", CharsPerToken: 3.5})
+	Register(ProviderMatcher("openai"), BPEHeuristicCounter{Encoding: "cl100k_base"})
+	Register(PrefixMatcher("gpt-", "text-embedding-"), BPEHeuristicCounter{Encoding: "cl100k_base"})
+	Register(PrefixMatcher("gpt-4o", "o1", "o3", "o4"), BPEHeuristicCounter{Encoding: "o200k_base"})
+}
+
+// Register adds a counter for the models matched by match. Counters
+// registered later take precedence over earlier ones, so a new model family
+// can override the built-in counters.
+func Register(match Matcher, counter TokenCounter) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	registry = append([]registration{{match: match, counter: counter}}, registry...)
+}
+
+// PrefixMatcher matches models starting with any of the prefixes.
+func PrefixMatcher(prefixes ...string) Matcher {
+	return func(provider, model string) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(model, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ProviderMatcher matches every model of the named provider.
+func ProviderMatcher(name string) Matcher {
+	return func(provider, model string) bool {
+		return provider == name
+	}
+}
+
+// CounterFor returns the token counter for a provider's model.
+func CounterFor(provider, model string) TokenCounter {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	for _, reg := range registry {
+		if reg.match(provider, model) {
+			return reg.counter
+		}
+	}
+	return defaultCounter
+}
+
+// CountText returns the number of tokens in text for a provider's model.
+func CountText(provider, model, text string) int {
+	return CounterFor(provider, model).CountText(model, text)
+}
+
+// CountMessages returns the number of prompt tokens for a conversation with a provider's model.
+func CountMessages(provider, model string, messages []models.Message) int {
+	return CounterFor(provider, model).CountMessages(model, messages)
+}
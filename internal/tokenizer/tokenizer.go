@@ -0,0 +1,233 @@
+package tokenizer
+
+import (
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+// Chat formats wrap every message in a few framing tokens
+// (role, separators) and prime the assistant reply.
+const (
+	tokensPerMessage = 3
+	tokensPerName    = 1
+	tokensPerReply   = 3
+
+	// tokensPerImage approximates a high-detail image tile set; the exact
+	// count depends on the image size, which is not known before upload.
+	tokensPerImage = 765
+)
+
+// BPEHeuristicCounter estimates the token counts of a tiktoken encoding such
+// as cl100k_base. It splits text like the encoding does but does not apply
+// the BPE merge tables, so counts of long words and unusual text can be off
+// by a few tokens.
+type BPEHeuristicCounter struct {
+	Encoding string
+}
+
+// Name returns the encoding name, marked as an estimate.
+func (c BPEHeuristicCounter) Name() string {
+	return c.Encoding + "-heuristic"
+}
+
+// CountText returns the estimated number of tokens in text.
+func (c BPEHeuristicCounter) CountText(model, text string) int {
+	return countBPE(text)
+}
+
+// CountMessages returns the estimated number of prompt tokens for a conversation.
+func (c BPEHeuristicCounter) CountMessages(model string, messages []models.Message) int {
+	return countMessages(c, model, messages)
+}
+
+// HeuristicCounter estimates tokens from the character count, for model
+// families without a public tokenizer.
+type HeuristicCounter struct {
+	Label         string
+	CharsPerToken float64
+}
+
+// Name returns the counter label.
+func (c HeuristicCounter) Name() string {
+	return c.Label
+}
+
+// CountText returns the estimated number of tokens in text.
+func (c HeuristicCounter) CountText(model, text string) int {
+	if text == "" {
+		return 0
+	}
+	return int(math.Ceil(float64(utf8.RuneCountInString(text)) / c.CharsPerToken))
+}
+
+// CountMessages returns the estimated number of prompt tokens for a conversation.
+func (c HeuristicCounter) CountMessages(model string, messages []models.Message) int {
+	return countMessages(c, model, messages)
+}
+
+// countMessages counts a conversation with counter, including the
+// per-message framing added by chat formats.
+func countMessages(counter TokenCounter, model string, messages []models.Message) int {
+	if len(messages) == 0 {
+		return 0
+	}
+
+	total := tokensPerReply
+	for _, msg := range messages {
+		total += tokensPerMessage
+		total += counter.CountText(model, msg.Role)
+		total += counter.CountText(model, msg.Content.Text())
+		total += tokensPerImage * len(msg.Content.Images())
+		if msg.Name != "" {
+			total += tokensPerName + counter.CountText(model, msg.Name)
+		}
+	}
+
+	return total
+}
+
+// countBPE approximates tiktoken counts. The text is split with the same
+// pre-tokenization rules as cl100k_base, and each piece is then costed by the
+// number of BPE merges it typically needs. Short words and number groups are
+// almost always a single token; long words and non-Latin text are split further.
+func countBPE(text string) int {
+	total := 0
+	for _, piece := range splitPieces(text) {
+		total += pieceTokens(piece)
+	}
+	return total
+}
+
+// pieceTokens estimates the number of BPE tokens for one pre-tokenized piece.
+func pieceTokens(piece string) int {
+	runes := []rune(strings.TrimLeft(piece, " "))
+	if len(runes) == 0 {
+		return 1
+	}
+
+	switch {
+	case unicode.IsLetter(runes[0]) || (len(runes) > 1 && unicode.IsLetter(runes[1])):
+		if !isASCII(piece) {
+			// Non-Latin scripts encode to roughly one token per character
+			return len(runes)
+		}
+		if len(runes) <= 7 {
+			return 1
+		}
+		return int(math.Ceil(float64(len(runes)) / 4))
+	case unicode.IsSpace(runes[0]):
+		return 1
+	default:
+		// Digit groups and punctuation runs
+		return int(math.Ceil(float64(len(runes)) / 3))
+	}
+}
+
+// splitPieces splits text following the cl100k_base pre-tokenization pattern:
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+func splitPieces(text string) []string {
+	runes := []rune(text)
+	var pieces []string
+
+	for i := 0; i < len(runes); {
+		end := matchPiece(runes, i)
+		pieces = append(pieces, string(runes[i:end]))
+		i = end
+	}
+
+	return pieces
+}
+
+// matchPiece returns the end of the piece starting at i.
+func matchPiece(runes []rune, i int) int {
+	n := len(runes)
+	r := runes[i]
+
+	// Contractions
+	if r == '\'' && i+1 < n {
+		for _, suffix := range []string{"s", "t", "re", "ve", "m", "ll", "d"} {
+			if hasFoldPrefix(runes[i+1:], suffix) {
+				return i + 1 + len(suffix)
+			}
+		}
+	}
+
+	// Letters, optionally preceded by one non-letter, non-digit character
+	if unicode.IsLetter(r) {
+		return scanWhile(runes, i, unicode.IsLetter)
+	}
+	if r != '\r' && r != '\n' && !unicode.IsNumber(r) && i+1 < n && unicode.IsLetter(runes[i+1]) {
+		return scanWhile(runes, i+1, unicode.IsLetter)
+	}
+
+	// Up to three digits
+	if unicode.IsNumber(r) {
+		end := i
+		for end < n && end-i < 3 && unicode.IsNumber(runes[end]) {
+			end++
+		}
+		return end
+	}
+
+	// Punctuation, optionally preceded by a space and followed by newlines
+	start := i
+	if r == ' ' && i+1 < n && isPunct(runes[i+1]) {
+		start = i + 1
+	}
+	if isPunct(runes[start]) {
+		end := scanWhile(runes, start, isPunct)
+		return scanWhile(runes, end, isNewline)
+	}
+
+	// Whitespace up to and including the last newline
+	end := scanWhile(runes, i, unicode.IsSpace)
+	for j := end - 1; j >= i; j-- {
+		if isNewline(runes[j]) {
+			return j + 1
+		}
+	}
+
+	// Whitespace not followed by a word, or all but the last space before one
+	if end < n && end-i > 1 {
+		return end - 1
+	}
+	return end
+}
+
+// scanWhile returns the index of the first rune from i that does not match.
+func scanWhile(runes []rune, i int, match func(rune) bool) int {
+	for i < len(runes) && match(runes[i]) {
+		i++
+	}
+	return i
+}
+
+// hasFoldPrefix reports whether runes start with prefix, ignoring case.
+func hasFoldPrefix(runes []rune, prefix string) bool {
+	if len(runes) < len(prefix) {
+		return false
+	}
+	return strings.EqualFold(string(runes[:len(prefix)]), prefix)
+}
+
+func isPunct(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+func isNewline(r rune) bool {
+	return r == '\r' || r == '\n'
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package tokenizer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+// TestBPEHeuristicCloseToTiktoken compares the estimates with the counts
+// tiktoken's cl100k_base encoding gives for the same text.
+func TestBPEHeuristicCloseToTiktoken(t *testing.T) {
+	counter := BPEHeuristicCounter{Encoding: "cl100k_base"}
+
+	tests := []struct {
+		text     string
+		tiktoken int
+	}{
+		{"hello world", 2},
+		{"Hello, world!", 4},
+		{"tiktoken is great!", 6},
+		{"antidisestablishmentarianism", 6},
+		{"2 + 2 = 4", 7},
+		{"お誕生日おめでとう", 9},
+	}
+	for _, tt := range tests {
+		got := counter.CountText("gpt-4", tt.text)
+		if diff := got - tt.tiktoken; diff < -1 || diff > 1 {
+			t.Errorf("CountText(%q) = %d, tiktoken counts %d", tt.text, got, tt.tiktoken)
+		}
+	}
+}
+
+func TestSplitPiecesFollowsCl100kPattern(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Hello, world!", []string{"Hello", ",", " world", "!"}},
+		{"I'm here", []string{"I", "'m", " here"}},
+		{"12345", []string{"123", "45"}},
+		{"a  b", []string{"a", " ", " b"}},
+		{"end.\n\nNext", []string{"end", ".\n\n", "Next"}},
+	}
+	for _, tt := range tests {
+		if got := splitPieces(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitPieces(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCountMessagesAddsChatFraming(t *testing.T) {
+	counter := BPEHeuristicCounter{Encoding: "cl100k_base"}
+	messages := []models.Message{{Role: "user", Content: models.TextContent("Hello!")}}
+
+	// tiktoken: 3 per message + "user" (1) + "Hello!" (2) + 3 priming the reply
+	if got := counter.CountMessages("gpt-4", messages); got != 9 {
+		t.Fatalf("CountMessages() = %d, want 9", got)
+	}
+	if got := counter.CountMessages("gpt-4", nil); got != 0 {
+		t.Fatalf("CountMessages(nil) = %d, want 0", got)
+	}
+}
+
+func TestCounterFor(t *testing.T) {
+	tests := []struct {
+		provider, model, want string
+	}{
+		{"openai", "gpt-4", "cl100k_base-heuristic"},
+		{"openai", "gpt-4o-mini", "o200k_base-heuristic"},
+		{"azure", "gpt-3.5-turbo", "cl100k_base-heuristic"},
+		{"anthropic", "claude-3-opus", "claude-heuristic"},
+		{"watsonx", "granite-13b", "heuristic"},
+		{"custom", "llama-3", "heuristic"},
+	}
+	for _, tt := range tests {
+		if got := CounterFor(tt.provider, tt.model).Name(); got != tt.want {
+			t.Errorf("CounterFor(%q, %q) = %s, want %s", tt.provider, tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestHeuristicCounterRoundsUp(t *testing.T) {
+	counter := HeuristicCounter{Label: "heuristic", CharsPerToken: 4}
+	for text, want := range map[string]int{"": 0, "abc": 1, "abcd": 1, "abcde": 2, "日本語の": 1} {
+		if got := counter.CountText("m", text); got != want {
+			t.Errorf("CountText(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
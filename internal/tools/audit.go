@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AuditEntry records a single tool invocation with its full input and output.
+type AuditEntry struct {
+	Timestamp time.Time       `json:"timestamp"`
+	TenantID  string          `json:"tenant_id"`
+	CallID    string          `json:"call_id"`
+	Tool      string          `json:"tool"`
+	BatchID   string          `json:"batch_id,omitempty"`
+	Index     int             `json:"index"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	Output    json.RawMessage `json:"output,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	QueueWait time.Duration   `json:"queue_wait,omitempty"`
+	Duration  time.Duration   `json:"duration"`
+}
+
+// AuditLogger writes tool audit entries to a dedicated logger.
+type AuditLogger struct {
+	logger *zap.Logger
+}
+
+// NewAuditLogger creates a new audit logger.
+func NewAuditLogger(logger *zap.Logger) *AuditLogger {
+	return &AuditLogger{
+		logger: logger.Named("tool_audit"),
+	}
+}
+
+// Record writes an audit entry. Failed and rejected calls are logged at warn level.
+func (a *AuditLogger) Record(entry AuditEntry) {
+	fields := []zap.Field{
+		zap.Time("timestamp", entry.Timestamp),
+		zap.String("tenant_id", entry.TenantID),
+		zap.String("call_id", entry.CallID),
+		zap.String("tool", entry.Tool),
+		zap.ByteString("input", entry.Input),
+		zap.ByteString("output", entry.Output),
+		zap.Duration("duration", entry.Duration),
+	}
+	if entry.BatchID != "" {
+		fields = append(fields,
+			zap.String("batch_id", entry.BatchID),
+			zap.Int("index", entry.Index),
+			zap.Duration("queue_wait", entry.QueueWait))
+	}
+
+	if entry.Error != "" {
+		a.logger.Warn("Tool call failed", append(fields, zap.String("error", entry.Error))...)
+		return
+	}
+	a.logger.Info("Tool call executed", fields...)
+}
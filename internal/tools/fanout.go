@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultMaxParallel bounds concurrent tool executions when not configured.
+const defaultMaxParallel = 4
+
+// ExecuteAll runs the tool calls emitted by a single model turn concurrently,
+// bounded by the configured parallelism and overall fan-out deadline. Results
+// are returned in the same order as calls so the follow-up message matches
+// the order the model emitted them in.
+func (g *Guard) ExecuteAll(ctx context.Context, tenantID string, calls []Call) []Result {
+	results := make([]Result, len(calls))
+	if len(calls) == 0 {
+		return results
+	}
+
+	maxParallel := g.config.MaxParallel
+	if maxParallel <= 0 {
+		maxParallel = defaultMaxParallel
+	}
+
+	if g.config.FanOutTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.config.FanOutTimeout)
+		defer cancel()
+	}
+
+	batchID := fmt.Sprintf("batch-%d", time.Now().UnixNano())
+	semaphore := make(chan struct{}, maxParallel)
+	var wg sync.WaitGroup
+
+	for i, call := range calls {
+		wg.Add(1)
+		go func(index int, call Call) {
+			defer wg.Done()
+
+			queued := time.Now()
+			atomic.AddInt64(&g.queued, 1)
+			select {
+			case semaphore <- struct{}{}:
+				atomic.AddInt64(&g.queued, -1)
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				atomic.AddInt64(&g.queued, -1)
+				// The deadline passed before a slot was free
+				results[index] = g.reject(tenantID, call, batchID, index, time.Since(queued),
+					fmt.Errorf("tool %s not started: %w", call.Name, ctx.Err()))
+				return
+			}
+
+			results[index] = g.executeInBatch(ctx, tenantID, call, batchID, index, time.Since(queued))
+		}(i, call)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// QueueDepth returns the number of fan-out tool calls waiting for a free slot.
+func (g *Guard) QueueDepth() int {
+	return int(atomic.LoadInt64(&g.queued))
+}
+
+// executeInBatch runs a single call that is part of a fan-out batch.
+func (g *Guard) executeInBatch(ctx context.Context, tenantID string, call Call, batchID string, index int, queueWait time.Duration) Result {
+	start := time.Now()
+	result := Result{CallID: call.ID, Name: call.Name}
+
+	output, err := g.execute(ctx, tenantID, call)
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Output = output
+	}
+
+	g.record(AuditEntry{
+		Timestamp: start,
+		TenantID:  tenantID,
+		CallID:    call.ID,
+		Tool:      call.Name,
+		BatchID:   batchID,
+		Index:     index,
+		Input:     call.Arguments,
+		Output:    result.Output,
+		Error:     result.Error,
+		QueueWait: queueWait,
+		Duration:  result.Duration,
+	})
+
+	return result
+}
+
+// reject records a call that could not be started and returns its failed result.
+func (g *Guard) reject(tenantID string, call Call, batchID string, index int, queueWait time.Duration, err error) Result {
+	g.record(AuditEntry{
+		Timestamp: time.Now(),
+		TenantID:  tenantID,
+		CallID:    call.ID,
+		Tool:      call.Name,
+		BatchID:   batchID,
+		Index:     index,
+		Input:     call.Arguments,
+		Error:     err.Error(),
+		QueueWait: queueWait,
+	})
+
+	return Result{
+		CallID: call.ID,
+		Name:   call.Name,
+		Error:  err.Error(),
+	}
+}
@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestExecuteAllIsolatesFailures(t *testing.T) {
+	guard, err := NewGuard(Config{
+		Enabled:     true,
+		MaxParallel: 4,
+		Tools: map[string]ToolConfig{
+			"slow_timeout": {Timeout: 10 * time.Millisecond},
+		},
+		Tenants: map[string]TenantConfig{"acme": {AllowedTools: []string{"*"}}},
+	}, zap.NewNop(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{}, 4)
+	guard.Register("fail", func(ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
+		started <- struct{}{}
+		return nil, errors.New("backend down")
+	})
+	guard.Register("slow", func(ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
+		started <- struct{}{}
+		// Outlives the failing and timed out siblings; its context must not
+		// be cancelled by them
+		select {
+		case <-time.After(50 * time.Millisecond):
+			return json.RawMessage(`{"ok":true}`), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+	guard.Register("slow_timeout", func(ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	guard.Register("echo", func(ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
+		started <- struct{}{}
+		return args, nil
+	})
+
+	calls := []Call{
+		{ID: "1", Name: "fail", Arguments: json.RawMessage(`{}`)},
+		{ID: "2", Name: "slow", Arguments: json.RawMessage(`{}`)},
+		{ID: "3", Name: "slow_timeout", Arguments: json.RawMessage(`{}`)},
+		{ID: "4", Name: "missing", Arguments: json.RawMessage(`{}`)},
+		{ID: "5", Name: "echo", Arguments: json.RawMessage(`{"n":5}`)},
+	}
+	results := guard.ExecuteAll(context.Background(), "acme", calls)
+
+	if len(results) != len(calls) {
+		t.Fatalf("%d results, want %d", len(results), len(calls))
+	}
+	for i, result := range results {
+		if result.CallID != calls[i].ID || result.Name != calls[i].Name {
+			t.Fatalf("result %d is for %s %s, want the calls' order", i, result.CallID, result.Name)
+		}
+	}
+	wantErrors := map[int]string{0: "backend down", 2: "timed out", 3: "tool not found"}
+	for i, want := range wantErrors {
+		if !strings.Contains(results[i].Error, want) {
+			t.Errorf("result %d error = %q, want one containing %q", i, results[i].Error, want)
+		}
+	}
+	if results[1].Error != "" || string(results[1].Output) != `{"ok":true}` {
+		t.Errorf("slow sibling = %+v, want it to finish despite the failures", results[1])
+	}
+	if results[4].Error != "" || string(results[4].Output) != `{"n":5}` {
+		t.Errorf("echo = %+v", results[4])
+	}
+	if len(started) != 4 {
+		t.Errorf("%d executors started, want every registered one", len(started))
+	}
+}
+
+func TestExecuteAllRejectsCallsPastTheFanOutDeadline(t *testing.T) {
+	guard, err := NewGuard(Config{
+		Enabled:       true,
+		MaxParallel:   1,
+		FanOutTimeout: 20 * time.Millisecond,
+		Tenants:       map[string]TenantConfig{"acme": {AllowedTools: []string{"*"}}},
+	}, zap.NewNop(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guard.Register("block", func(ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	results := guard.ExecuteAll(context.Background(), "acme", []Call{{ID: "1", Name: "block"}, {ID: "2", Name: "block"}})
+	for i, result := range results {
+		if result.Error == "" {
+			t.Errorf("result %d succeeded past the deadline", i)
+		}
+	}
+	if guard.QueueDepth() != 0 {
+		t.Errorf("QueueDepth() = %d after the batch", guard.QueueDepth())
+	}
+}
@@ -0,0 +1,266 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/semantrix/semaroute/internal/observability"
+	"go.uber.org/zap"
+)
+
+// defaultMaxTurns bounds the model turns of tool calls run per request when
+// not configured.
+const defaultMaxTurns = 5
+
+var (
+	// ErrToolNotFound is returned when a call targets a tool that has no registered executor.
+	ErrToolNotFound = errors.New("tool not found")
+
+	// ErrToolNotAllowed is returned when the tenant's allowlist does not include the tool.
+	ErrToolNotAllowed = errors.New("tool not allowed for tenant")
+
+	// ErrRateLimited is returned when the tenant exceeded the tool's call rate.
+	ErrRateLimited = errors.New("tool rate limit exceeded")
+)
+
+// Executor runs a tool with JSON arguments and returns its JSON output.
+type Executor func(ctx context.Context, args json.RawMessage) (json.RawMessage, error)
+
+// Call represents a single tool invocation requested by a model.
+type Call struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// Result represents the outcome of a tool invocation.
+type Result struct {
+	CallID   string          `json:"call_id"`
+	Name     string          `json:"name"`
+	Output   json.RawMessage `json:"output,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Duration time.Duration   `json:"duration"`
+}
+
+// Config holds configuration for guarded server-side tool execution.
+type Config struct {
+	Enabled        bool                    `mapstructure:"enabled"`
+	DefaultTimeout time.Duration           `mapstructure:"default_timeout"`
+	MaxTurns       int                     `mapstructure:"max_turns"`       // model turns of tool calls run per request
+	MaxParallel    int                     `mapstructure:"max_parallel"`    // concurrent calls per fan-out batch
+	FanOutTimeout  time.Duration           `mapstructure:"fan_out_timeout"` // deadline for a whole batch
+	Tools          map[string]ToolConfig   `mapstructure:"tools"`
+	Tenants        map[string]TenantConfig `mapstructure:"tenants"`
+}
+
+// ToolConfig holds the endpoint, limits and argument schema for a single
+// tool.
+type ToolConfig struct {
+	URL        string            `mapstructure:"url"`     // endpoint the arguments are POSTed to
+	Headers    map[string]string `mapstructure:"headers"` // sent with every call, e.g. Authorization
+	Timeout    time.Duration     `mapstructure:"timeout"`
+	RateLimit  int               `mapstructure:"rate_limit"`  // max calls per rate window, 0 = unlimited
+	RateWindow time.Duration     `mapstructure:"rate_window"` // defaults to one minute
+	// Schema is the JSON schema for the arguments, as a JSON document. It is
+	// kept as a string because config keys are lowercased on load, which
+	// would break keywords such as maxLength and camelCase property names.
+	Schema string `mapstructure:"schema"`
+}
+
+// TenantConfig lists the tools a tenant may execute.
+type TenantConfig struct {
+	AllowedTools []string `mapstructure:"allowed_tools"`
+}
+
+// Guard enforces tenant allowlists, argument validation, timeouts and rate
+// limits around server-side tool execution, and audits every invocation.
+type Guard struct {
+	config    Config
+	executors map[string]Executor
+	limiter   *rateLimiter
+	audit     *AuditLogger
+	metrics   *observability.Metrics
+	schemas   map[string]map[string]interface{} // parsed argument schemas by tool
+	queued    int64                             // fan-out calls waiting for a free slot
+	mutex     sync.RWMutex
+}
+
+// NewGuard creates a new tool guard, with an HTTP executor for each tool
+// that has an endpoint. It fails if a tool's schema is not valid JSON.
+func NewGuard(config Config, logger *zap.Logger, metrics *observability.Metrics) (*Guard, error) {
+	if config.DefaultTimeout <= 0 {
+		config.DefaultTimeout = 10 * time.Second
+	}
+	if config.MaxTurns <= 0 {
+		config.MaxTurns = defaultMaxTurns
+	}
+
+	guard := &Guard{
+		config:    config,
+		executors: make(map[string]Executor),
+		limiter:   newRateLimiter(),
+		audit:     NewAuditLogger(logger),
+		metrics:   metrics,
+		schemas:   make(map[string]map[string]interface{}),
+	}
+
+	for name, toolConfig := range config.Tools {
+		if toolConfig.Schema == "" {
+			continue
+		}
+		schema, err := ParseSchema(toolConfig.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("tool %s: %w", name, err)
+		}
+		guard.schemas[name] = schema
+	}
+
+	// Timeouts are applied per call by the guard
+	client := &http.Client{}
+	for name, toolConfig := range config.Tools {
+		if toolConfig.URL != "" {
+			guard.Register(name, HTTPExecutor(toolConfig.URL, toolConfig.Headers, client))
+		}
+	}
+	return guard, nil
+}
+
+// Register registers the executor for a tool.
+func (g *Guard) Register(name string, executor Executor) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.executors[name] = executor
+}
+
+// Handles reports whether the guard runs the named tool for the tenant: tool
+// execution is enabled, the tool has an executor, and the tenant may use it.
+// Calls of other tools are left to the client.
+func (g *Guard) Handles(tenantID, toolName string) bool {
+	if !g.config.Enabled {
+		return false
+	}
+
+	g.mutex.RLock()
+	_, exists := g.executors[toolName]
+	g.mutex.RUnlock()
+	return exists && g.IsAllowed(tenantID, toolName)
+}
+
+// IsAllowed returns true if the tenant may execute the named tool.
+func (g *Guard) IsAllowed(tenantID, toolName string) bool {
+	tenant, exists := g.config.Tenants[tenantID]
+	if !exists {
+		return false
+	}
+
+	for _, allowed := range tenant.AllowedTools {
+		if allowed == toolName || allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Execute runs a single tool call on behalf of a tenant after all guards pass.
+func (g *Guard) Execute(ctx context.Context, tenantID string, call Call) (Result, error) {
+	start := time.Now()
+	result := Result{CallID: call.ID, Name: call.Name}
+
+	output, err := g.execute(ctx, tenantID, call)
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Output = output
+	}
+
+	g.record(AuditEntry{
+		Timestamp: start,
+		TenantID:  tenantID,
+		CallID:    call.ID,
+		Tool:      call.Name,
+		Input:     call.Arguments,
+		Output:    result.Output,
+		Error:     result.Error,
+		Duration:  result.Duration,
+	})
+
+	return result, err
+}
+
+// record writes an audit entry and the per-tool latency metric.
+func (g *Guard) record(entry AuditEntry) {
+	g.audit.Record(entry)
+
+	if g.metrics != nil {
+		status := "success"
+		if entry.Error != "" {
+			status = "error"
+		}
+		g.metrics.RecordToolCall(entry.Tool, status, entry.Duration)
+	}
+}
+
+// execute applies the guards in order and runs the executor.
+func (g *Guard) execute(ctx context.Context, tenantID string, call Call) (json.RawMessage, error) {
+	if !g.config.Enabled {
+		return nil, fmt.Errorf("server-side tool execution is disabled")
+	}
+
+	g.mutex.RLock()
+	executor, exists := g.executors[call.Name]
+	g.mutex.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrToolNotFound, call.Name)
+	}
+
+	if !g.IsAllowed(tenantID, call.Name) {
+		return nil, fmt.Errorf("%w: %s", ErrToolNotAllowed, call.Name)
+	}
+
+	toolConfig := g.config.Tools[call.Name]
+
+	if schema, ok := g.schemas[call.Name]; ok {
+		if err := ValidateArguments(schema, call.Arguments); err != nil {
+			return nil, fmt.Errorf("invalid arguments for tool %s: %w", call.Name, err)
+		}
+	}
+
+	if toolConfig.RateLimit > 0 {
+		window := toolConfig.RateWindow
+		if window <= 0 {
+			window = time.Minute
+		}
+		if !g.limiter.Allow(tenantID+"/"+call.Name, toolConfig.RateLimit, window) {
+			return nil, fmt.Errorf("%w: %s", ErrRateLimited, call.Name)
+		}
+	}
+
+	timeout := toolConfig.Timeout
+	if timeout <= 0 {
+		timeout = g.config.DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	output, err := executor(ctx, call.Arguments)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("tool %s timed out after %v", call.Name, timeout)
+		}
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// GetConfig returns the guard configuration.
+func (g *Guard) GetConfig() Config {
+	return g.config
+}
@@ -0,0 +1,53 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxOutputBytes bounds the output read from a tool endpoint.
+const maxOutputBytes = 1 << 20
+
+// HTTPExecutor returns an executor that POSTs the arguments as JSON to a
+// tool endpoint and takes its JSON response body as the output. Responses
+// other than 2xx fail the call; their body is the error.
+func HTTPExecutor(url string, headers map[string]string, client *http.Client) Executor {
+	return func(ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
+		if len(args) == 0 {
+			args = json.RawMessage(`{}`)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(args))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxOutputBytes+1))
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("tool endpoint returned %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+		}
+		if len(body) > maxOutputBytes {
+			return nil, fmt.Errorf("tool output exceeds %d bytes", maxOutputBytes)
+		}
+		if !json.Valid(body) {
+			return nil, fmt.Errorf("tool endpoint returned invalid JSON")
+		}
+		return body, nil
+	}
+}
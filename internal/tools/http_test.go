@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPExecutor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tool-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch string(body) {
+		case `{"query":"weather"}`:
+			w.Write([]byte(`{"results":["sunny"]}`))
+		case `{}`:
+			w.Write([]byte(`not json`))
+		default:
+			http.Error(w, "bad query", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	headers := map[string]string{"Authorization": "Bearer tool-token"}
+	tests := []struct {
+		name    string
+		headers map[string]string
+		args    string
+		want    string
+		wantErr string
+	}{
+		{name: "output", headers: headers, args: `{"query":"weather"}`, want: `{"results":["sunny"]}`},
+		{name: "error status", headers: headers, args: `{"query":"other"}`, wantErr: "returned 400: bad query"},
+		{name: "missing header", args: `{"query":"weather"}`, wantErr: "returned 401"},
+		{name: "invalid output", headers: headers, wantErr: "invalid JSON"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			executor := HTTPExecutor(server.URL, test.headers, server.Client())
+			output, err := executor(context.Background(), json.RawMessage(test.args))
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil || string(output) != test.want {
+				t.Fatalf("output = %s, %v, want %s", output, err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often windows that have ended are evicted.
+const sweepInterval = time.Minute
+
+// rateLimiter implements fixed-window call counting per key.
+type rateLimiter struct {
+	windows   map[string]*rateWindow
+	lastSweep time.Time
+	mutex     sync.Mutex
+}
+
+// rateWindow tracks the calls made in the current window.
+type rateWindow struct {
+	start  time.Time
+	length time.Duration
+	count  int
+}
+
+// newRateLimiter creates a new fixed-window rate limiter.
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		windows:   make(map[string]*rateWindow),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow records a call for the key and reports whether it is within the limit.
+func (l *rateLimiter) Allow(key string, limit int, window time.Duration) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	w, exists := l.windows[key]
+	if !exists || now.Sub(w.start) >= window {
+		w = &rateWindow{start: now, length: window}
+		l.windows[key] = w
+	}
+
+	if w.count >= limit {
+		return false
+	}
+	w.count++
+	return true
+}
+
+// sweep evicts the windows that have ended, so keys of tenants and tools no
+// longer called are forgotten. The caller must hold the mutex.
+func (l *rateLimiter) sweep(now time.Time) {
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= w.length {
+			delete(l.windows, key)
+		}
+	}
+	l.lastSweep = now
+}
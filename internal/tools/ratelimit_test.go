@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllowsUpToTheLimit(t *testing.T) {
+	limiter := newRateLimiter()
+	for i := 0; i < 3; i++ {
+		if !limiter.Allow("acme/search", 3, time.Minute) {
+			t.Fatalf("call %d denied", i+1)
+		}
+	}
+	if limiter.Allow("acme/search", 3, time.Minute) {
+		t.Fatal("call past the limit allowed")
+	}
+	if !limiter.Allow("globex/search", 3, time.Minute) {
+		t.Fatal("another tenant's call denied")
+	}
+}
+
+func TestRateLimiterEvictsEndedWindows(t *testing.T) {
+	limiter := newRateLimiter()
+	limiter.Allow("acme/search", 1, time.Millisecond)
+	limiter.Allow("acme/fetch", 1, time.Millisecond)
+	limiter.Allow("globex/search", 1, time.Hour)
+
+	time.Sleep(2 * time.Millisecond)
+	limiter.lastSweep = time.Now().Add(-sweepInterval)
+	limiter.Allow("globex/fetch", 1, time.Hour)
+
+	if len(limiter.windows) != 2 {
+		t.Fatalf("%d windows tracked, want the 2 still running", len(limiter.windows))
+	}
+	if _, exists := limiter.windows["globex/search"]; !exists {
+		t.Fatal("running window evicted")
+	}
+	if limiter.Allow("globex/search", 1, time.Hour) {
+		t.Fatal("running window's count was lost")
+	}
+}
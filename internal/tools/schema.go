@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseSchema decodes a tool's argument schema from its JSON document.
+func ParseSchema(document string) (map[string]interface{}, error) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(document), &schema); err != nil {
+		return nil, fmt.Errorf("schema is not a valid JSON object: %w", err)
+	}
+	return schema, nil
+}
+
+// ValidateArguments validates JSON tool arguments against a JSON schema.
+// The supported subset covers what tool definitions use in practice: type,
+// properties, required, additionalProperties, items, enum, minimum/maximum
+// and minLength/maxLength.
+func ValidateArguments(schema map[string]interface{}, args json.RawMessage) error {
+	var value interface{}
+	if len(args) == 0 {
+		value = map[string]interface{}{}
+	} else if err := json.Unmarshal(args, &value); err != nil {
+		return fmt.Errorf("arguments are not valid JSON: %w", err)
+	}
+
+	return validateValue(schema, value, "$")
+}
+
+// validateValue validates a decoded JSON value against a schema node.
+func validateValue(schema map[string]interface{}, value interface{}, path string) error {
+	if expected, ok := schema["type"].(string); ok {
+		if !matchesType(expected, value) {
+			return fmt.Errorf("%s: expected %s", path, expected)
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, candidate := range enum {
+			if fmt.Sprint(candidate) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value not in enum", path)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return validateObject(schema, v, path)
+	case []interface{}:
+		if items, ok := asSchema(schema["items"]); ok {
+			for i, item := range v {
+				if err := validateValue(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if min, ok := asNumber(schema["minLength"]); ok && length < min {
+			return fmt.Errorf("%s: shorter than %v characters", path, min)
+		}
+		if max, ok := asNumber(schema["maxLength"]); ok && length > max {
+			return fmt.Errorf("%s: longer than %v characters", path, max)
+		}
+	case float64:
+		if min, ok := asNumber(schema["minimum"]); ok && v < min {
+			return fmt.Errorf("%s: less than minimum %v", path, min)
+		}
+		if max, ok := asNumber(schema["maximum"]); ok && v > max {
+			return fmt.Errorf("%s: greater than maximum %v", path, max)
+		}
+	}
+
+	return nil
+}
+
+// validateObject validates object properties, required keys and additional properties.
+func validateObject(schema map[string]interface{}, object map[string]interface{}, path string) error {
+	for _, name := range asStrings(schema["required"]) {
+		if _, exists := object[name]; !exists {
+			return fmt.Errorf("%s: missing required property %q", path, name)
+		}
+	}
+
+	properties, _ := asSchema(schema["properties"])
+	for name, value := range object {
+		propertySchema, known := asSchema(properties[name])
+		if !known {
+			if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+				return fmt.Errorf("%s: unexpected property %q", path, name)
+			}
+			continue
+		}
+		if err := validateValue(propertySchema, value, path+"."+name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// matchesType reports whether a decoded JSON value has the given schema type.
+func matchesType(expected string, value interface{}) bool {
+	switch strings.ToLower(expected) {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == float64(int64(n))
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	default:
+		return true
+	}
+}
+
+// asSchema converts a schema node decoded from YAML or JSON into a map.
+func asSchema(node interface{}) (map[string]interface{}, bool) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		return n, true
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(n))
+		for k, v := range n {
+			converted[fmt.Sprint(k)] = v
+		}
+		return converted, true
+	default:
+		return nil, false
+	}
+}
+
+// asNumber converts a numeric schema keyword into a float64.
+func asNumber(node interface{}) (float64, bool) {
+	switch n := node.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// asStrings converts a list schema keyword into a string slice.
+func asStrings(node interface{}) []string {
+	switch n := node.(type) {
+	case []string:
+		return n
+	case []interface{}:
+		result := make([]string, 0, len(n))
+		for _, v := range n {
+			result = append(result, fmt.Sprint(v))
+		}
+		return result
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestValidateArguments(t *testing.T) {
+	schema, err := ParseSchema(`{
+		"type": "object",
+		"required": ["query"],
+		"additionalProperties": false,
+		"properties": {
+			"query": {"type": "string", "minLength": 1, "maxLength": 3},
+			"limit": {"type": "integer", "minimum": 1, "maximum": 10},
+			"mode": {"enum": ["fast", "exact"]},
+			"tags": {"type": "array", "items": {"type": "string"}}
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		args  string
+		valid bool
+	}{
+		{"minimal", `{"query": "abc"}`, true},
+		{"characters not bytes", `{"query": "日本語"}`, true},
+		{"too long", `{"query": "abcd"}`, false},
+		{"too short", `{"query": ""}`, false},
+		{"missing required", `{"limit": 2}`, false},
+		{"unexpected property", `{"query": "a", "other": true}`, false},
+		{"not an integer", `{"query": "a", "limit": 1.5}`, false},
+		{"above maximum", `{"query": "a", "limit": 11}`, false},
+		{"not in enum", `{"query": "a", "mode": "slow"}`, false},
+		{"wrong item type", `{"query": "a", "tags": ["x", 1]}`, false},
+		{"invalid JSON", `{"query":`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateArguments(schema, json.RawMessage(tt.args))
+			if (err == nil) != tt.valid {
+				t.Fatalf("ValidateArguments(%s) error = %v, want valid %v", tt.args, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestNewGuardRejectsInvalidSchema(t *testing.T) {
+	_, err := NewGuard(Config{
+		Tools: map[string]ToolConfig{"search": {Schema: "type: object"}},
+	}, zap.NewNop(), nil)
+	if err == nil {
+		t.Fatal("NewGuard() accepted a schema that is not JSON")
+	}
+}
@@ -0,0 +1,48 @@
+package usage
+
+import "fmt"
+
+// Policies for counting the spend of aborted requests in usage totals.
+const (
+	// AbortedCostProrate counts what an aborted request consumed: its prompt
+	// and the output produced before it was cut short.
+	AbortedCostProrate = "prorate"
+	// AbortedCostFull counts an aborted request as if it had completed, up
+	// to its max_tokens, as providers may keep generating after the abort.
+	AbortedCostFull = "full"
+	// AbortedCostExclude leaves the spend of aborted requests out.
+	AbortedCostExclude = "exclude"
+)
+
+// Reasons a request was aborted.
+const (
+	AbortClientDisconnect = "client_disconnect"
+	AbortDeadline         = "deadline"
+)
+
+// AbortedCostPolicy returns the configured policy for aborted requests,
+// prorate by default.
+func (c Config) AbortedCostPolicy() (string, error) {
+	switch c.AbortedCost {
+	case "":
+		return AbortedCostProrate, nil
+	case AbortedCostProrate, AbortedCostFull, AbortedCostExclude:
+		return c.AbortedCost, nil
+	default:
+		return "", fmt.Errorf("aborted_cost must be %s, %s or %s, got %q",
+			AbortedCostProrate, AbortedCostFull, AbortedCostExclude, c.AbortedCost)
+	}
+}
+
+// AbortedCost returns the cost counted for an aborted request under policy,
+// given the estimated cost of what it consumed and of its full completion.
+func AbortedCost(policy string, consumed, full float64) float64 {
+	switch policy {
+	case AbortedCostFull:
+		return full
+	case AbortedCostExclude:
+		return 0
+	default:
+		return consumed
+	}
+}
@@ -0,0 +1,73 @@
+package usage
+
+import (
+	"github.com/semantrix/semaroute/internal/models"
+)
+
+// LogprobsConfig configures the capture of output token distributions for
+// evaluation. Only responses to requests that asked for logprobs, from tenants
+// that opted in, are captured.
+type LogprobsConfig struct {
+	Enabled    bool    `mapstructure:"enabled"`
+	SampleRate float64 `mapstructure:"sample_rate"` // fraction of eligible responses captured
+	MaxTokens  int     `mapstructure:"max_tokens"`  // token positions kept per response
+	MaxTopK    int     `mapstructure:"max_top_k"`   // alternatives kept per position
+}
+
+// TokenDistribution is a generated token with the most likely alternatives
+// at its position.
+type TokenDistribution struct {
+	Position int           `json:"position"`
+	Token    string        `json:"token"`
+	Logprob  float64       `json:"logprob"`
+	Top      []Alternative `json:"top,omitempty"`
+}
+
+// Alternative is an alternative token and its log probability.
+type Alternative struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+}
+
+// CaptureLogprobs returns the token distributions of a response to keep,
+// within the configured caps. Responses longer than MaxTokens positions are
+// sampled at evenly spaced positions, so the capture covers the whole output
+// rather than its start.
+func CaptureLogprobs(config LogprobsConfig, logprobs *models.Logprobs) []TokenDistribution {
+	if logprobs == nil || len(logprobs.Content) == 0 {
+		return nil
+	}
+	maxTokens := config.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = 256
+	}
+	maxTopK := config.MaxTopK
+	if maxTopK <= 0 {
+		maxTopK = 5
+	}
+
+	total := len(logprobs.Content)
+	kept := total
+	if kept > maxTokens {
+		kept = maxTokens
+	}
+
+	distributions := make([]TokenDistribution, 0, kept)
+	for i := 0; i < kept; i++ {
+		position := i * total / kept
+		token := logprobs.Content[position]
+		distribution := TokenDistribution{
+			Position: position,
+			Token:    token.Token,
+			Logprob:  token.Logprob,
+		}
+		for j, top := range token.TopLogprobs {
+			if j == maxTopK {
+				break
+			}
+			distribution.Top = append(distribution.Top, Alternative{Token: top.Token, Logprob: top.Logprob})
+		}
+		distributions = append(distributions, distribution)
+	}
+	return distributions
+}
@@ -0,0 +1,10 @@
+package usage
+
+import "github.com/semantrix/semaroute/internal/migrate"
+
+// Migrations upgrade the record file, and the daily rollups next to it, from
+// the format of older releases, oldest first. Add one whenever a release
+// changes how they are written.
+var Migrations = []migrate.Migration{
+	{Version: 1, Description: "baseline", Apply: migrate.Baseline},
+}
@@ -0,0 +1,221 @@
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// dateLayout names a UTC day in rollups.
+const dateLayout = "2006-01-02"
+
+// Totals aggregates usage records.
+type Totals struct {
+	Requests         int64   `json:"requests"`
+	CacheHits        int64   `json:"cache_hits"`
+	Aborted          int64   `json:"aborted"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// Add adds other to the totals.
+func (t *Totals) Add(other Totals) {
+	t.Requests += other.Requests
+	t.CacheHits += other.CacheHits
+	t.Aborted += other.Aborted
+	t.PromptTokens += other.PromptTokens
+	t.CompletionTokens += other.CompletionTokens
+	t.Cost += other.Cost
+}
+
+// addRecord adds one record to the totals.
+func (t *Totals) addRecord(record Record) {
+	t.Requests++
+	if record.Hit {
+		t.CacheHits++
+	}
+	if record.Aborted != "" {
+		t.Aborted++
+	}
+	t.PromptTokens += int64(record.PromptTokens)
+	t.CompletionTokens += int64(record.CompletionTokens)
+	t.Cost += record.Cost
+}
+
+// ModelUsage is the usage of one model.
+type ModelUsage struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Totals
+}
+
+// DailyUsage is a tenant's usage on one UTC day.
+type DailyUsage struct {
+	Date string `json:"date"`
+	Totals
+	Models []ModelUsage `json:"models,omitempty"`
+}
+
+// dayRollup accumulates one tenant's usage on one day.
+type dayRollup struct {
+	totals Totals
+	models map[string]*ModelUsage // keyed by provider and model
+}
+
+// rollups holds per-tenant daily totals. They are updated as records are
+// added, so tenant dashboards read pre-aggregated data, and are saved next to
+// the record file so they outlive the records kept for cache warming.
+type rollups struct {
+	days    int
+	tenants map[string]map[string]*dayRollup // tenant, then date
+	through time.Time                        // time of the newest record folded in
+}
+
+// newRollups creates empty rollups keeping the given number of days.
+func newRollups(days int) *rollups {
+	return &rollups{days: days, tenants: make(map[string]map[string]*dayRollup)}
+}
+
+// add folds a record into its tenant's day.
+func (r *rollups) add(record Record) {
+	days, exists := r.tenants[record.Tenant]
+	if !exists {
+		days = make(map[string]*dayRollup)
+		r.tenants[record.Tenant] = days
+	}
+
+	date := record.Time.UTC().Format(dateLayout)
+	day, exists := days[date]
+	if !exists {
+		day = &dayRollup{models: make(map[string]*ModelUsage)}
+		days[date] = day
+	}
+	day.totals.addRecord(record)
+
+	modelKey := record.Provider + "/" + record.Model
+	model, exists := day.models[modelKey]
+	if !exists {
+		model = &ModelUsage{Provider: record.Provider, Model: record.Model}
+		day.models[modelKey] = model
+	}
+	model.addRecord(record)
+
+	if record.Time.After(r.through) {
+		r.through = record.Time
+	}
+}
+
+// daily returns a tenant's usage for each day from from to to inclusive.
+// Days without usage have zero totals.
+func (r *rollups) daily(tenant string, from, to time.Time) []DailyUsage {
+	days := r.tenants[tenant]
+	from = from.UTC().Truncate(24 * time.Hour)
+	to = to.UTC()
+
+	var result []DailyUsage
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		usage := DailyUsage{Date: date.Format(dateLayout)}
+		if day, exists := days[usage.Date]; exists {
+			usage.Totals = day.totals
+			usage.Models = day.modelUsage()
+		}
+		result = append(result, usage)
+	}
+	return result
+}
+
+// modelUsage returns the day's per-model usage, most requested first.
+func (d *dayRollup) modelUsage() []ModelUsage {
+	models := make([]ModelUsage, 0, len(d.models))
+	for _, model := range d.models {
+		models = append(models, *model)
+	}
+	sort.Slice(models, func(i, j int) bool {
+		if models[i].Requests != models[j].Requests {
+			return models[i].Requests > models[j].Requests
+		}
+		return models[i].Provider+"/"+models[i].Model < models[j].Provider+"/"+models[j].Model
+	})
+	return models
+}
+
+// prune drops days older than the retention period.
+func (r *rollups) prune(now time.Time) {
+	cutoff := now.UTC().AddDate(0, 0, -r.days).Format(dateLayout)
+	for tenant, days := range r.tenants {
+		for date := range days {
+			if date < cutoff {
+				delete(days, date)
+			}
+		}
+		if len(days) == 0 {
+			delete(r.tenants, tenant)
+		}
+	}
+}
+
+// rollupFile is the saved form of rollups.
+type rollupFile struct {
+	Through time.Time               `json:"through"`
+	Tenants map[string][]DailyUsage `json:"tenants"`
+}
+
+// load reads saved rollups. A missing file holds no rollups.
+func (r *rollups) load(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read usage rollups: %w", err)
+	}
+
+	var file rollupFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse usage rollups: %w", err)
+	}
+
+	for tenant, days := range file.Tenants {
+		dates := make(map[string]*dayRollup, len(days))
+		for _, usage := range days {
+			day := &dayRollup{totals: usage.Totals, models: make(map[string]*ModelUsage, len(usage.Models))}
+			for _, model := range usage.Models {
+				model := model
+				day.models[model.Provider+"/"+model.Model] = &model
+			}
+			dates[usage.Date] = day
+		}
+		r.tenants[tenant] = dates
+	}
+	r.through = file.Through
+	r.prune(time.Now())
+	return nil
+}
+
+// save replaces the rollup file.
+func (r *rollups) save(path string) error {
+	r.prune(time.Now())
+
+	file := rollupFile{Through: r.through, Tenants: make(map[string][]DailyUsage, len(r.tenants))}
+	for tenant, days := range r.tenants {
+		saved := make([]DailyUsage, 0, len(days))
+		for date, day := range days {
+			saved = append(saved, DailyUsage{Date: date, Totals: day.totals, Models: day.modelUsage()})
+		}
+		sort.Slice(saved, func(i, j int) bool { return saved[i].Date < saved[j].Date })
+		file.Tenants[tenant] = saved
+	}
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save usage rollups: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
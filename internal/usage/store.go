@@ -0,0 +1,300 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Config holds configuration for the usage store.
+type Config struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	Path       string `mapstructure:"path"`        // JSON-lines file; empty keeps records in memory only
+	MaxRecords int    `mapstructure:"max_records"` // records kept in memory and reloaded at startup
+	RollupDays int    `mapstructure:"rollup_days"` // days of per-tenant daily totals kept
+
+	// AbortedCost is how the spend of requests cut short by a client
+	// disconnect or deadline counts: prorate (default), full or exclude
+	AbortedCost string `mapstructure:"aborted_cost"`
+
+	// Logprobs captures sampled output token distributions with the records
+	Logprobs LogprobsConfig `mapstructure:"logprobs"`
+}
+
+// Record describes one served or aborted chat completion.
+type Record struct {
+	Time             time.Time `json:"time"`
+	Tenant           string    `json:"tenant,omitempty"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	Cost             float64   `json:"cost,omitempty"` // estimated spend in USD
+
+	// HedgeCost is the estimated spend of the hedged attempt cancelled in
+	// favour of the one that served the request. It is not part of Cost,
+	// which is what the request itself consumed.
+	HedgeCost float64 `json:"hedge_cost,omitempty"`
+
+	// CacheKey and Response are set for cacheable requests, so the response
+	// cache can be rebuilt from recent traffic.
+	CacheKey string          `json:"cache_key,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+
+	// Hit is true when the response was served from the cache.
+	Hit bool `json:"hit,omitempty"`
+
+	// Aborted is why a request was cut short before it completed, e.g.
+	// client_disconnect or deadline. Its tokens are those consumed before
+	// the abort and its Cost follows the aborted_cost policy.
+	Aborted string `json:"aborted,omitempty"`
+
+	// Logprobs holds the captured token distributions of the first choice,
+	// and LogprobTokens the number of tokens they were sampled from.
+	Logprobs      []TokenDistribution `json:"logprobs,omitempty"`
+	LogprobTokens int                 `json:"logprob_tokens,omitempty"`
+}
+
+// Entry is a cacheable response and how often it was requested.
+type Entry struct {
+	CacheKey string
+	Response json.RawMessage
+	Count    int
+	LastSeen time.Time
+}
+
+// Store keeps the most recent usage records in memory and appends them to a
+// file, so they survive restarts.
+type Store struct {
+	maxRecords int
+	records    []Record
+	file       *os.File
+	mutex      sync.RWMutex
+
+	// Per-tenant daily totals, kept beyond the retained records
+	rollups    *rollups
+	rollupPath string
+}
+
+// NewStore creates a usage store, loading the most recent records from the
+// configured file. The file is rewritten at startup when it holds more than
+// MaxRecords records, which keeps it bounded.
+func NewStore(config Config) (*Store, error) {
+	maxRecords := config.MaxRecords
+	if maxRecords <= 0 {
+		maxRecords = 100000
+	}
+
+	rollupDays := config.RollupDays
+	if rollupDays <= 0 {
+		rollupDays = 90
+	}
+
+	s := &Store{maxRecords: maxRecords, rollups: newRollups(rollupDays)}
+	if config.Path == "" {
+		return s, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0o755); err != nil {
+		return nil, err
+	}
+	records, total, err := readRecords(config.Path, maxRecords)
+	if err != nil {
+		return nil, err
+	}
+	s.records = records
+
+	// Fold in the records written since the rollups were last saved
+	s.rollupPath = config.Path + ".daily"
+	if err := s.rollups.load(s.rollupPath); err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if record.Time.After(s.rollups.through) {
+			s.rollups.add(record)
+		}
+	}
+	if err := s.rollups.save(s.rollupPath); err != nil {
+		return nil, err
+	}
+
+	if total > len(records) {
+		if err := writeRecords(config.Path, records); err != nil {
+			return nil, err
+		}
+	}
+
+	s.file, err = os.OpenFile(config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage store: %w", err)
+	}
+
+	return s, nil
+}
+
+// Add records a served request.
+func (s *Store) Add(record Record) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.records = append(s.records, record)
+	s.rollups.add(record)
+	if len(s.records) > s.maxRecords {
+		s.records = s.records[len(s.records)-s.maxRecords:]
+	}
+
+	if s.file == nil {
+		return nil
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// Records returns the records at or after since, oldest first.
+func (s *Store) Records(since time.Time) []Record {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	start := sort.Search(len(s.records), func(i int) bool {
+		return !s.records[i].Time.Before(since)
+	})
+	return append([]Record(nil), s.records[start:]...)
+}
+
+// Daily returns a tenant's usage for each UTC day from from to to inclusive,
+// from the daily rollups.
+func (s *Store) Daily(tenant string, from, to time.Time) []DailyUsage {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.rollups.daily(tenant, from, to)
+}
+
+// TopResponses returns the cacheable responses requested at least minCount
+// times since the given time, most requested first, at most limit entries.
+// Each entry carries the most recent response for its cache key.
+func (s *Store) TopResponses(since time.Time, minCount, limit int) []Entry {
+	entries := make(map[string]*Entry)
+	for _, record := range s.Records(since) {
+		if record.CacheKey == "" {
+			continue
+		}
+		entry, exists := entries[record.CacheKey]
+		if !exists {
+			entry = &Entry{CacheKey: record.CacheKey}
+			entries[record.CacheKey] = entry
+		}
+		entry.Count++
+		if len(record.Response) > 0 {
+			entry.Response = record.Response
+			entry.LastSeen = record.Time
+		}
+	}
+
+	top := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Count >= minCount && len(entry.Response) > 0 {
+			top = append(top, *entry)
+		}
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].LastSeen.After(top[j].LastSeen)
+	})
+	if limit > 0 && len(top) > limit {
+		top = top[:limit]
+	}
+	return top
+}
+
+// Close saves the daily rollups and closes the store file.
+func (s *Store) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	saveErr := s.rollups.save(s.rollupPath)
+	err := s.file.Close()
+	s.file = nil
+	if err == nil {
+		err = saveErr
+	}
+	return err
+}
+
+// readRecords reads the last max records of a JSON-lines file, returning
+// them with the total number of records in the file. A missing file holds
+// no records; unreadable lines are skipped.
+func readRecords(path string, max int) ([]Record, int, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read usage store: %w", err)
+	}
+	defer file.Close()
+
+	var records []Record
+	total := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		total++
+		records = append(records, record)
+		if len(records) > 2*max {
+			records = append([]Record(nil), records[len(records)-max:]...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read usage store: %w", err)
+	}
+
+	if len(records) > max {
+		records = records[len(records)-max:]
+	}
+	return records, total, nil
+}
+
+// writeRecords replaces the file with the given records.
+func writeRecords(path string, records []Record) error {
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to compact usage store: %w", err)
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,164 @@
+package usage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreKeepsMostRecentRecords(t *testing.T) {
+	store, err := NewStore(Config{MaxRecords: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().Add(-time.Hour)
+	for i, model := range []string{"a", "b", "c"} {
+		if err := store.Add(Record{Time: start.Add(time.Duration(i) * time.Minute), Model: model}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records := store.Records(time.Time{})
+	if len(records) != 2 || records[0].Model != "b" || records[1].Model != "c" {
+		t.Fatalf("Records() = %+v, want b and c", records)
+	}
+	if since := store.Records(start.Add(90 * time.Second)); len(since) != 1 || since[0].Model != "c" {
+		t.Fatalf("Records(since) = %+v, want c", since)
+	}
+}
+
+func TestStoreSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage", "records.jsonl")
+	now := time.Now().UTC()
+
+	store, err := NewStore(Config{Path: path, MaxRecords: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range []Record{
+		{Time: now, Tenant: "acme", Provider: "openai", Model: "gpt-4o", PromptTokens: 10, CompletionTokens: 5, Cost: 0.01},
+		{Time: now, Tenant: "acme", Provider: "openai", Model: "gpt-4o", Hit: true},
+		{Time: now, Tenant: "globex", Provider: "anthropic", Model: "claude-3", Aborted: AbortClientDisconnect},
+	} {
+		if err := store.Add(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Records appended after the rollups were saved are folded in on load
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, _ := json.Marshal(Record{Time: now.Add(time.Second), Tenant: "acme", Provider: "openai", Model: "gpt-4o", PromptTokens: 1})
+	file.Write(append(line, '\n'))
+	file.WriteString("not json\n")
+	file.Close()
+
+	reopened, err := NewStore(Config{Path: path, MaxRecords: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	if records := reopened.Records(time.Time{}); len(records) != 4 {
+		t.Fatalf("%d records after restart, want 4 with the unreadable line skipped", len(records))
+	}
+	days := reopened.Daily("acme", now, now)
+	if len(days) != 1 {
+		t.Fatalf("Daily() = %+v, want one day", days)
+	}
+	totals := days[0].Totals
+	if totals.Requests != 3 || totals.CacheHits != 1 || totals.PromptTokens != 11 || totals.CompletionTokens != 5 || totals.Cost != 0.01 {
+		t.Fatalf("acme totals = %+v, want each record counted once", totals)
+	}
+	if globex := reopened.Daily("globex", now, now)[0].Totals; globex.Requests != 1 || globex.Aborted != 1 {
+		t.Fatalf("globex totals = %+v", globex)
+	}
+}
+
+func TestStoreCompactsFileAtStartup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.jsonl")
+	var lines []string
+	for i := 0; i < 5; i++ {
+		line, _ := json.Marshal(Record{Time: time.Now(), PromptTokens: i})
+		lines = append(lines, string(line))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewStore(Config{Path: path, MaxRecords: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(kept) != 2 || !strings.Contains(kept[0], `"prompt_tokens":3`) || !strings.Contains(kept[1], `"prompt_tokens":4`) {
+		t.Fatalf("compacted file = %q, want the last two records", kept)
+	}
+}
+
+func TestDailyFillsDaysWithoutUsage(t *testing.T) {
+	store, err := NewStore(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := time.Now().UTC()
+	store.Add(Record{Time: today, Tenant: "acme", Provider: "openai", Model: "gpt-4o"})
+	store.Add(Record{Time: today, Tenant: "acme", Provider: "openai", Model: "gpt-4o"})
+	store.Add(Record{Time: today, Tenant: "acme", Provider: "anthropic", Model: "claude-3"})
+
+	days := store.Daily("acme", today.AddDate(0, 0, -2), today)
+	if len(days) != 3 {
+		t.Fatalf("Daily() returned %d days, want 3", len(days))
+	}
+	if days[0].Requests != 0 || days[1].Requests != 0 || days[2].Requests != 3 {
+		t.Fatalf("requests per day = %d, %d, %d, want 0, 0, 3", days[0].Requests, days[1].Requests, days[2].Requests)
+	}
+	if models := days[2].Models; len(models) != 2 || models[0].Model != "gpt-4o" || models[0].Requests != 2 {
+		t.Fatalf("models = %+v, want gpt-4o first", models)
+	}
+}
+
+func TestTopResponses(t *testing.T) {
+	store, err := NewStore(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	add := func(key, response string, at time.Time) {
+		store.Add(Record{Time: at, CacheKey: key, Response: json.RawMessage(response)})
+	}
+	add("stale", `{"v":6}`, now.Add(-2*time.Hour))
+	add("stale", `{"v":6}`, now.Add(-2*time.Hour))
+	add("popular", `{"v":1}`, now.Add(-3*time.Minute))
+	add("popular", `{"v":2}`, now.Add(-2*time.Minute))
+	add("popular", "", now.Add(-time.Minute)) // a cache hit carries no response
+	add("pair", `{"v":3}`, now.Add(-2*time.Minute))
+	add("pair", `{"v":4}`, now.Add(-time.Minute))
+	add("once", `{"v":5}`, now)
+	store.Add(Record{Time: now})
+
+	top := store.TopResponses(now.Add(-time.Hour), 2, 10)
+	if len(top) != 2 || top[0].CacheKey != "popular" || top[1].CacheKey != "pair" {
+		t.Fatalf("TopResponses() = %+v, want popular then pair", top)
+	}
+	if top[0].Count != 3 || string(top[0].Response) != `{"v":2}` {
+		t.Fatalf("popular = count %d, response %s, want 3 and the latest response", top[0].Count, top[0].Response)
+	}
+	if limited := store.TopResponses(now.Add(-time.Hour), 1, 1); len(limited) != 1 || limited[0].CacheKey != "popular" {
+		t.Fatalf("TopResponses(limit 1) = %+v", limited)
+	}
+}
@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -17,17 +18,193 @@ type ChatCompletionRequest struct {
 	PresencePenalty float64 `json:"presence_penalty,omitempty"`
 	FrequencyPenalty float64 `json:"frequency_penalty,omitempty"`
 	User        string    `json:"user,omitempty"`
+	Tools       []Tool    `json:"tools,omitempty"`
+	ToolChoice  *ToolChoice `json:"tool_choice,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	N           int       `json:"n,omitempty"`
+	Seed        *int      `json:"seed,omitempty"`
+	Logprobs    bool      `json:"logprobs,omitempty"`
+	TopLogprobs int       `json:"top_logprobs,omitempty"`
+	LogitBias   map[string]float64 `json:"logit_bias,omitempty"`
+	Thinking    *Thinking `json:"thinking,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Routing     *RoutingHints `json:"routing,omitempty"`
 	RequestID   string    `json:"request_id,omitempty"`
 }
 
+// RoutingHints constrain how a request is routed. A request whose
+// constraints no provider satisfies is rejected with 422.
+type RoutingHints struct {
+	PreferProviders  []string `json:"prefer_providers,omitempty"`
+	ExcludeProviders []string `json:"exclude_providers,omitempty"`
+	MaxCost          float64  `json:"max_cost,omitempty"`       // estimated, in USD
+	MaxLatencyMs     int      `json:"max_latency_ms,omitempty"` // estimated
+	RequireStreaming bool     `json:"require_streaming,omitempty"`
+	Residency        string   `json:"residency,omitempty"` // residency tag, e.g. eu-only
+	Priority         string   `json:"priority,omitempty"`  // normal (default) or deferred
+	Language         string   `json:"language,omitempty"`  // ISO 639-1 code the response must be in
+}
+
 // Message represents a single message in a conversation.
 type Message struct {
 	Role      string `json:"role"`
-	Content   string `json:"content"`
+	Content   Content `json:"content"`
 	Name      string `json:"name,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+	ReasoningSignature string   `json:"reasoning_signature,omitempty"`
+	RedactedReasoning  []string `json:"redacted_reasoning,omitempty"`
 	Timestamp time.Time `json:"timestamp,omitempty"`
 }
 
+// Content is message content in the OpenAI format: a plain string, or an
+// array of parts mixing text and images.
+type Content struct {
+	Parts []ContentPart
+}
+
+// ContentPart is one part of a multimodal message. Type is "text" or "image_url".
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL references an image by URL or as a base64 data URL.
+type ImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// TextContent returns content holding a single text part.
+func TextContent(text string) Content {
+	if text == "" {
+		return Content{}
+	}
+	return Content{Parts: []ContentPart{{Type: "text", Text: text}}}
+}
+
+// Text returns the concatenated text parts.
+func (c Content) Text() string {
+	var text string
+	for _, part := range c.Parts {
+		if part.Type == "text" {
+			text += part.Text
+		}
+	}
+	return text
+}
+
+// MarshalJSON encodes text-only content as a string and anything else as parts.
+func (c Content) MarshalJSON() ([]byte, error) {
+	for _, part := range c.Parts {
+		if part.Type != "text" {
+			return json.Marshal(c.Parts)
+		}
+	}
+	return json.Marshal(c.Text())
+}
+
+// UnmarshalJSON decodes a string, an array of parts, or null.
+func (c *Content) UnmarshalJSON(data []byte) error {
+	var text *string
+	if err := json.Unmarshal(data, &text); err == nil {
+		if text == nil {
+			*c = Content{}
+		} else {
+			*c = TextContent(*text)
+		}
+		return nil
+	}
+
+	var parts []ContentPart
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return err
+	}
+	*c = Content{Parts: parts}
+	return nil
+}
+
+// ResponseFormat requests JSON output: {"type": "json_object"} for any JSON
+// object, or {"type": "json_schema", "json_schema": {...}} for a given schema.
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+// JSONSchema is a named JSON Schema the response must conform to.
+type JSONSchema struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Schema      json.RawMessage `json:"schema,omitempty"`
+	Strict      bool            `json:"strict,omitempty"`
+}
+
+// Tool is a function the model may call, in the OpenAI tools format.
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction describes a callable function and its JSON Schema parameters.
+type ToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ToolChoice controls whether and which tool the model calls. In JSON it is
+// either "auto", "none" or "required", or an object naming one function.
+type ToolChoice struct {
+	Type     string              `json:"type"`
+	Function *ToolChoiceFunction `json:"function,omitempty"`
+}
+
+// ToolChoiceFunction names the function the model must call.
+type ToolChoiceFunction struct {
+	Name string `json:"name"`
+}
+
+// UnmarshalJSON decodes either the string or the object form.
+func (c *ToolChoice) UnmarshalJSON(data []byte) error {
+	var mode string
+	if err := json.Unmarshal(data, &mode); err == nil {
+		*c = ToolChoice{Type: mode}
+		return nil
+	}
+
+	type plain ToolChoice
+	var choice plain
+	if err := json.Unmarshal(data, &choice); err != nil {
+		return err
+	}
+	*c = ToolChoice(choice)
+	return nil
+}
+
+// MarshalJSON encodes modes as strings and named functions as objects.
+func (c ToolChoice) MarshalJSON() ([]byte, error) {
+	if c.Function == nil {
+		return json.Marshal(c.Type)
+	}
+	type plain ToolChoice
+	return json.Marshal(plain(c))
+}
+
+// ToolCall is a function call requested by the model.
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction names the called function and its JSON-encoded arguments.
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
 // ChatCompletionResponse represents a successful chat completion response.
 type ChatCompletionResponse struct {
 	ID      string   `json:"id"`
@@ -36,14 +213,75 @@ type ChatCompletionResponse struct {
 	Usage   Usage    `json:"usage"`
 	Created int64    `json:"created"`
 	Provider string  `json:"provider"`
+	Alias    string  `json:"alias,omitempty"` // model alias the request named, resolved to Model
+	Fallback *FallbackInfo `json:"fallback,omitempty"` // set when a fallback served the request
+	Hedge    *HedgeInfo    `json:"hedge,omitempty"`    // set when the request was hedged
 	RequestID string `json:"request_id,omitempty"`
 }
 
+// FallbackInfo reports which fallback served a request after the routed
+// provider failed.
+type FallbackInfo struct {
+	Hop      int    `json:"hop"`      // position in the fallback chain, from 1
+	From     string `json:"from"`     // provider/model that failed
+	Attempts int    `json:"attempts"` // attempts made on the serving hop
+}
+
+// ProviderAttempt is one provider request made for a chat completion, listed
+// in the error details when every provider failed.
+type ProviderAttempt struct {
+	Provider   string `json:"provider"`
+	Model      string `json:"model"`
+	Hop        int    `json:"hop,omitempty"`         // position in the fallback chain, 0 for the routed provider
+	Hedge      bool   `json:"hedge,omitempty"`       // reissued after the hedging delay
+	ErrorClass string `json:"error_class,omitempty"` // empty for attempts that succeeded
+	Error      string `json:"error,omitempty"`
+	ElapsedMs  int64  `json:"elapsed_ms"`
+}
+
+// HedgeInfo reports a request reissued to a second provider because the
+// routed one had not answered within the hedging delay.
+type HedgeInfo struct {
+	Primary string `json:"primary"` // provider/model the request was routed to
+	Hedge   string `json:"hedge"`   // provider/model it was reissued to
+	Winner  string `json:"winner"`  // primary or hedge
+}
+
 // Choice represents a single completion choice.
 type Choice struct {
 	Index   int     `json:"index"`
 	Message Message `json:"message"`
+	Logprobs *Logprobs `json:"logprobs,omitempty"`
 	FinishReason string `json:"finish_reason"`
+	StopSequence string `json:"stop_sequence,omitempty"`
+}
+
+// Thinking enables extended thinking, where the model reasons before
+// answering within a budget of BudgetTokens output tokens.
+type Thinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens,omitempty"`
+}
+
+// Logprobs holds the log probabilities of the generated tokens.
+type Logprobs struct {
+	Content []TokenLogprob `json:"content"`
+}
+
+// TokenLogprob is the log probability of one generated token and, when
+// requested, of the most likely alternatives at that position.
+type TokenLogprob struct {
+	Token       string       `json:"token"`
+	Logprob     float64      `json:"logprob"`
+	Bytes       []int        `json:"bytes,omitempty"`
+	TopLogprobs []TopLogprob `json:"top_logprobs,omitempty"`
+}
+
+// TopLogprob is the log probability of an alternative token.
+type TopLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+	Bytes   []int   `json:"bytes,omitempty"`
 }
 
 // Usage represents token usage statistics.
@@ -51,6 +289,14 @@ type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+	PromptTokensDetails *PromptTokensDetails `json:"prompt_tokens_details,omitempty"`
+}
+
+// PromptTokensDetails breaks down prompt tokens served from the provider's
+// prompt cache. Both counts are included in PromptTokens.
+type PromptTokensDetails struct {
+	CachedTokens        int `json:"cached_tokens"`
+	CacheCreationTokens int `json:"cache_creation_tokens,omitempty"`
 }
 
 // ErrorResponse represents an error response from the API.
@@ -89,8 +335,23 @@ type ProviderHealth struct {
 // ModelsResponse represents the available models from all providers.
 type ModelsResponse struct {
 	Models   []ModelInfo `json:"models"`
-	Total    int         `json:"total"`
+	Total    int         `json:"total"` // models matching the filters, across all pages
 	Providers []string   `json:"providers"`
+	ProviderStatus []ProviderModelsStatus `json:"provider_status"`
+	HasMore  bool        `json:"has_more,omitempty"`
+	NextOffset int       `json:"next_offset,omitempty"`
+}
+
+// ProviderModelsStatus reports whether a provider's model list could be
+// fetched. When it could not, the last list fetched successfully is served
+// with Stale set.
+type ProviderModelsStatus struct {
+	Provider  string    `json:"provider"`
+	Status    string    `json:"status"` // succeeded or failed
+	Error     string    `json:"error,omitempty"`
+	Stale     bool      `json:"stale,omitempty"`
+	FetchedAt time.Time `json:"fetched_at,omitempty"` // when the served list was fetched
+	Models    int       `json:"models"`
 }
 
 // ModelInfo represents information about a specific model.
@@ -102,6 +363,12 @@ type ModelInfo struct {
 	ContextSize int      `json:"context_size,omitempty"`
 	MaxTokens   int      `json:"max_tokens,omitempty"`
 	SupportedFeatures []string `json:"supported_features,omitempty"`
+	InputPer1K  float64  `json:"input_per_1k,omitempty"` // USD, from the pricing catalog
+	OutputPer1K float64  `json:"output_per_1k,omitempty"`
+	// Fine-tuned models name their base model, owner and training date
+	BaseModel    string `json:"base_model,omitempty"`
+	Owner        string `json:"owner,omitempty"`
+	TrainingDate string `json:"training_date,omitempty"`
 }
 
 // RoutingInfoResponse represents information about routing decisions.
@@ -110,9 +377,18 @@ type RoutingInfoResponse struct {
 	RoutingPolicy  string         `json:"routing_policy"`
 	Decision       RoutingDecision `json:"decision"`
 	Alternatives   []RoutingDecision `json:"alternatives,omitempty"`
+	Parameters     []ParameterSource `json:"parameters,omitempty"` // generation defaults in effect for the caller
 	Timestamp     time.Time       `json:"timestamp"`
 }
 
+// ParameterSource reports where the value of a generation parameter comes
+// from: the request, the tenant's defaults or the global defaults.
+type ParameterSource struct {
+	Name   string      `json:"name"`
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+}
+
 // RoutingDecision represents a routing decision made by the system.
 type RoutingDecision struct {
 	ProviderName    string    `json:"provider_name"`
@@ -122,6 +398,8 @@ type RoutingDecision struct {
 	EstimatedLatency time.Duration `json:"estimated_latency,omitempty"`
 	Confidence      float64   `json:"confidence"`
 	Fallback        bool      `json:"fallback"`
+	Spillover       string    `json:"spillover,omitempty"` // provider the request spilled over from while rate limited
+	Category        string    `json:"category,omitempty"`  // content category the request was routed by
 }
 
 // MetricsResponse represents system metrics.
@@ -166,3 +444,290 @@ type CacheMetrics struct {
 	Size         int64   `json:"size"`
 	MaxSize      int64   `json:"max_size"`
 }
+
+// RouteResponse represents a gatekeeper routing decision returned instead of a completion.
+type RouteResponse struct {
+	RequestID     string          `json:"request_id,omitempty"`
+	Provider      string          `json:"provider"`
+	Model         string          `json:"model"`
+	BaseURL       string          `json:"base_url,omitempty"`
+	CredentialRef string          `json:"credential_ref"`
+	Token         string          `json:"token"`
+	ExpiresAt     time.Time       `json:"expires_at"`
+	Decision      RoutingDecision `json:"decision"`
+}
+
+// RoutingExplanation is the routing decision for a request, made without
+// executing it, with how the routing policy weighed every provider.
+type RoutingExplanation struct {
+	RequestID     string             `json:"request_id,omitempty"`
+	RoutingPolicy string             `json:"routing_policy"`
+	Model         string             `json:"model"` // model the providers were weighed for, after alias resolution
+	Alias         string             `json:"alias,omitempty"`
+	Decision      *RoutingDecision   `json:"decision,omitempty"` // nil when routing fails
+	Error         *ErrorDetails      `json:"error,omitempty"`    // why routing fails
+	Candidates    []RoutingCandidate `json:"candidates"`
+}
+
+// RoutingCandidate reports how the routing policy weighed one provider.
+type RoutingCandidate struct {
+	Provider         string        `json:"provider"`
+	Rank             int           `json:"rank,omitempty"`  // 1 for the policy's first choice; 0 when excluded or the policy does not rank
+	Score            *float64      `json:"score,omitempty"` // only for policies that score providers
+	EstimatedCost    float64       `json:"estimated_cost,omitempty"`
+	EstimatedLatency time.Duration `json:"estimated_latency,omitempty"`
+	ErrorRate        float64       `json:"error_rate,omitempty"` // observed recently, for policies that track it
+	Excluded         string        `json:"excluded,omitempty"`   // why the provider could not be chosen
+}
+
+// VoucherRedeemRequest exchanges a gatekeeper token for provider access.
+type VoucherRedeemRequest struct {
+	Token string `json:"token"`
+}
+
+// VoucherRedemption is returned when a gatekeeper token is redeemed. Depending on
+// the redemption mode it carries either the provider key or a proxy URL.
+type VoucherRedemption struct {
+	Provider  string    `json:"provider"`
+	Model     string    `json:"model"`
+	APIKey    string    `json:"api_key,omitempty"`
+	BaseURL   string    `json:"base_url,omitempty"`
+	ProxyURL  string    `json:"proxy_url,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// TokenizeRequest asks for the token count of a conversation or a piece of text.
+type TokenizeRequest struct {
+	Model    string    `json:"model"`
+	Provider string    `json:"provider,omitempty"` // defaults to the first provider serving the model
+	Messages []Message `json:"messages,omitempty"`
+	Input    string    `json:"input,omitempty"`
+}
+
+// TokenizeResponse reports a token count and the encoding used to compute it.
+type TokenizeResponse struct {
+	Model         string `json:"model"`
+	Provider      string `json:"provider,omitempty"`
+	Encoding      string `json:"encoding"`
+	Tokens        int    `json:"tokens"`
+	ContextWindow int    `json:"context_window,omitempty"`
+}
+
+// EmbeddingRequest represents an embeddings request from a client.
+type EmbeddingRequest struct {
+	Model      string         `json:"model"`
+	Input      EmbeddingInput `json:"input"`
+	Dimensions int            `json:"dimensions,omitempty"`
+	User       string         `json:"user,omitempty"`
+	RequestID  string         `json:"request_id,omitempty"`
+}
+
+// EmbeddingInput is the text to embed. It accepts a single string or an array of strings.
+type EmbeddingInput = StringList
+
+// StringList is a list of strings that may also be given as a single string in JSON.
+type StringList []string
+
+// UnmarshalJSON decodes either a string or an array of strings.
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = StringList{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*l = many
+	return nil
+}
+
+// EmbeddingResponse represents a successful embeddings response.
+type EmbeddingResponse struct {
+	Object    string          `json:"object"`
+	Model     string          `json:"model"`
+	Data      []EmbeddingData `json:"data"`
+	Usage     Usage           `json:"usage"`
+	Provider  string          `json:"provider"`
+	RequestID string          `json:"request_id,omitempty"`
+}
+
+// EmbeddingData is the vector for one input.
+type EmbeddingData struct {
+	Object    string    `json:"object"`
+	Index     int       `json:"index"`
+	Embedding []float64 `json:"embedding"`
+}
+
+// DocumentRequest asks for a long document generated section by section.
+type DocumentRequest struct {
+	Model     string    `json:"model"`
+	Messages  []Message `json:"messages"`
+	Sections  int       `json:"sections,omitempty"` // maximum number of sections
+	Stream    bool      `json:"stream,omitempty"`   // stream progress events
+	User      string    `json:"user,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+// DocumentSection is one generated section of a document.
+type DocumentSection struct {
+	Index    int    `json:"index"`
+	Title    string `json:"title"`
+	Content  string `json:"content"`
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Usage    Usage  `json:"usage"`
+}
+
+// DocumentResponse is an assembled document.
+type DocumentResponse struct {
+	Outline   []string          `json:"outline"`
+	Sections  []DocumentSection `json:"sections"`
+	Content   string            `json:"content"`
+	Usage     Usage             `json:"usage"`
+	RequestID string            `json:"request_id,omitempty"`
+}
+
+// DocumentEvent reports progress of a streamed document generation. Type is
+// "plan", "section", "done" or "error".
+type DocumentEvent struct {
+	Type     string            `json:"type"`
+	Outline  []string          `json:"outline,omitempty"`
+	Section  *DocumentSection  `json:"section,omitempty"`
+	Total    int               `json:"total,omitempty"`
+	Document *DocumentResponse `json:"document,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// ImageGenerationRequest represents an image generation request from a client.
+type ImageGenerationRequest struct {
+	Model          string `json:"model"`
+	Prompt         string `json:"prompt"`
+	N              int    `json:"n,omitempty"`
+	Size           string `json:"size,omitempty"`
+	Quality        string `json:"quality,omitempty"`
+	Style          string `json:"style,omitempty"`
+	ResponseFormat string `json:"response_format,omitempty"`
+	User           string `json:"user,omitempty"`
+	RequestID      string `json:"request_id,omitempty"`
+}
+
+// ImageGenerationResponse represents a successful image generation response.
+type ImageGenerationResponse struct {
+	Created   int64       `json:"created"`
+	Data      []ImageData `json:"data"`
+	Provider  string      `json:"provider"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// ImageData is a single generated image.
+type ImageData struct {
+	URL           string `json:"url,omitempty"`
+	B64JSON       string `json:"b64_json,omitempty"`
+	RevisedPrompt string `json:"revised_prompt,omitempty"`
+}
+
+// CompletionRequest is a legacy prompt-style completion request.
+type CompletionRequest struct {
+	Model            string     `json:"model"`
+	Prompt           StringList `json:"prompt"`
+	MaxTokens        int        `json:"max_tokens,omitempty"`
+	Temperature      float64    `json:"temperature,omitempty"`
+	TopP             float64    `json:"top_p,omitempty"`
+	Stop             StringList `json:"stop,omitempty"`
+	PresencePenalty  float64    `json:"presence_penalty,omitempty"`
+	FrequencyPenalty float64    `json:"frequency_penalty,omitempty"`
+	Echo             bool       `json:"echo,omitempty"`
+	Stream           bool       `json:"stream,omitempty"`
+	User             string     `json:"user,omitempty"`
+	RequestID        string     `json:"request_id,omitempty"`
+}
+
+// CompletionResponse is a legacy completion response (object "text_completion").
+type CompletionResponse struct {
+	ID        string             `json:"id"`
+	Object    string             `json:"object"`
+	Created   int64              `json:"created"`
+	Model     string             `json:"model"`
+	Choices   []CompletionChoice `json:"choices"`
+	Usage     *Usage             `json:"usage,omitempty"`
+	Provider  string             `json:"provider,omitempty"`
+	RequestID string             `json:"request_id,omitempty"`
+}
+
+// CompletionChoice is a single legacy completion choice.
+type CompletionChoice struct {
+	Text         string      `json:"text"`
+	Index        int         `json:"index"`
+	Logprobs     interface{} `json:"logprobs"`
+	FinishReason string      `json:"finish_reason"`
+}
+
+// TranscriptionResponse is the JSON response of an audio transcription.
+type TranscriptionResponse struct {
+	Text      string  `json:"text"`
+	Language  string  `json:"language,omitempty"`
+	Duration  float64 `json:"duration,omitempty"`
+	Provider  string  `json:"provider"`
+	RequestID string  `json:"request_id,omitempty"`
+}
+
+// SpeechRequest asks for text to be synthesized as speech.
+type SpeechRequest struct {
+	Model          string  `json:"model"`
+	Input          string  `json:"input"`
+	Voice          string  `json:"voice"`
+	ResponseFormat string  `json:"response_format,omitempty"`
+	Speed          float64 `json:"speed,omitempty"`
+	RequestID      string  `json:"request_id,omitempty"`
+}
+
+// UsageTotals aggregates a tenant's usage. Cost is the estimated spend in USD
+// from the pricing catalog.
+type UsageTotals struct {
+	Requests         int64   `json:"requests"`
+	CacheHits        int64   `json:"cache_hits"`
+	Aborted          int64   `json:"aborted"` // requests cut short by a client disconnect or deadline
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	Cost             float64 `json:"cost_usd"`
+}
+
+// UsageSummaryResponse is a tenant's usage over a range of days.
+type UsageSummaryResponse struct {
+	Tenant string `json:"tenant"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	UsageTotals
+}
+
+// UsageDay is a tenant's usage on one UTC day.
+type UsageDay struct {
+	Date string `json:"date"`
+	UsageTotals
+}
+
+// UsageDailyResponse is a tenant's usage per day, oldest first.
+type UsageDailyResponse struct {
+	Tenant string     `json:"tenant"`
+	Days   []UsageDay `json:"days"`
+}
+
+// ModelUsage is a tenant's usage of one model.
+type ModelUsage struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	UsageTotals
+}
+
+// UsageTopModelsResponse lists a tenant's most used models over a range of days.
+type UsageTopModelsResponse struct {
+	Tenant string       `json:"tenant"`
+	From   string       `json:"from"`
+	To     string       `json:"to"`
+	By     string       `json:"by"`
+	Models []ModelUsage `json:"models"`
+}
@@ -0,0 +1,100 @@
+// Package gatekeeper is a client for semaroute's gatekeeper (route-only) mode.
+// Applications ask the router where to send a request, then redeem the
+// returned voucher for provider access instead of holding provider keys.
+package gatekeeper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	v1 "github.com/semantrix/semaroute/pkg/api/v1"
+)
+
+// Client talks to a semaroute server running in gatekeeper mode.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the semaroute server at baseURL. If
+// httpClient is nil, http.DefaultClient is used.
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// Route asks the router for a routing decision and a voucher for req.
+func (c *Client) Route(ctx context.Context, req v1.ChatCompletionRequest) (*v1.RouteResponse, error) {
+	var resp v1.RouteResponse
+	if err := c.post(ctx, "/v1/route", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Redeem exchanges a voucher for the provider key or a proxy URL. Vouchers
+// are single use.
+func (c *Client) Redeem(ctx context.Context, token string) (*v1.VoucherRedemption, error) {
+	var resp v1.VoucherRedemption
+	if err := c.post(ctx, "/v1/vouchers/redeem", v1.VoucherRedeemRequest{Token: token}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Acquire routes req and immediately redeems the voucher.
+func (c *Client) Acquire(ctx context.Context, req v1.ChatCompletionRequest) (*v1.RouteResponse, *v1.VoucherRedemption, error) {
+	route, err := c.Route(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	redemption, err := c.Redeem(ctx, route.Token)
+	if err != nil {
+		return route, nil, err
+	}
+
+	return route, redemption, nil
+}
+
+// post sends a JSON request to the server and decodes the JSON response into out.
+func (c *Client) post(ctx context.Context, path string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("semaroute returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package plugin
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// authTokenBytes is the number of random bytes in an auth token, which is
+// sent hex encoded.
+const authTokenBytes = 32
+
+// authTimeout bounds how long a plugin waits for a connection's token.
+const authTimeout = 5 * time.Second
+
+// newAuthToken returns a fresh random auth token for a plugin launch.
+func newAuthToken() (string, error) {
+	token := make([]byte, authTokenBytes)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate plugin auth token: %w", err)
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// validAuthToken reports whether token has the form of an auth token.
+func validAuthToken(token string) bool {
+	_, err := hex.DecodeString(token)
+	return err == nil && len(token) == 2*authTokenBytes
+}
+
+// sendAuthToken sends the token as the first line on a connection to a
+// plugin.
+func sendAuthToken(conn net.Conn, token string, timeout time.Duration) error {
+	conn.SetWriteDeadline(time.Now().Add(timeout))
+	defer conn.SetWriteDeadline(time.Time{})
+
+	if _, err := io.WriteString(conn, token+"\n"); err != nil {
+		return fmt.Errorf("failed to authenticate to plugin: %w", err)
+	}
+	return nil
+}
+
+// authenticate reads the first line of a connection and reports whether it
+// is the token. Exactly the token's length is read, so the JSON-RPC that
+// follows is left on the connection.
+func authenticate(conn net.Conn, token string) bool {
+	conn.SetReadDeadline(time.Now().Add(authTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	line := make([]byte, len(token)+1)
+	if _, err := io.ReadFull(conn, line); err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(line[:len(token)], []byte(token)) == 1 && line[len(token)] == '\n'
+}
@@ -0,0 +1,134 @@
+package plugin
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/semantrix/semaroute/pkg/api/v1"
+)
+
+// testPlugin is a provider plugin serving a single echo model.
+type testPlugin struct{}
+
+func (testPlugin) Name() string                  { return "test" }
+func (testPlugin) Configure(config Config) error { return nil }
+func (testPlugin) Models() ([]string, error)     { return []string{"echo"}, nil }
+func (testPlugin) CostEstimate(req v1.ChatCompletionRequest) (float64, error) {
+	return 0, nil
+}
+func (testPlugin) LatencyEstimate(req v1.ChatCompletionRequest) (time.Duration, error) {
+	return 0, nil
+}
+func (testPlugin) ChatCompletion(req v1.ChatCompletionRequest, deadline time.Time) (*v1.ChatCompletionResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+// TestMain runs the test binary as the test plugin when it is launched as
+// one, so Start can be tested end to end.
+func TestMain(m *testing.M) {
+	if os.Getenv(MagicCookieKey) == MagicCookieValue {
+		if err := Serve(testPlugin{}); err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestStartAuthenticatesToPlugin(t *testing.T) {
+	client, err := Start(os.Args[0], 10*time.Second)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer client.Kill()
+
+	if client.Name() != "test" {
+		t.Fatalf("Name() = %q", client.Name())
+	}
+	models, err := client.Models()
+	if err != nil || len(models) != 1 || models[0] != "echo" {
+		t.Fatalf("Models() = %v, %v", models, err)
+	}
+}
+
+func TestServeRouterRejectsUnauthenticatedConnections(t *testing.T) {
+	token, err := newAuthToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName(serviceName, &rpcServer{impl: testPlugin{}}); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go serveRouter(listener, token, server)
+
+	other, err := newAuthToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejected := map[string]string{
+		"no token":    `{"method":"Provider.Name","params":[{}],"id":0}` + strings.Repeat(" ", 2*authTokenBytes) + "\n",
+		"wrong token": other + "\n",
+		"no newline":  token + " ",
+	}
+	for name, first := range rejected {
+		t.Run(name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", listener.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			io.WriteString(conn, first)
+
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			if n, err := conn.Read(make([]byte, 1)); err == nil {
+				t.Fatalf("read %d bytes from an unauthenticated connection", n)
+			}
+		})
+	}
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sendAuthToken(conn, token, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	client := jsonrpc.NewClient(conn)
+	defer client.Close()
+	var name string
+	if err := client.Call(serviceName+".Name", Empty{}, &name); err != nil || name != "test" {
+		t.Fatalf("Name() = %q, %v after authenticating", name, err)
+	}
+}
+
+func TestServeRequiresAuthToken(t *testing.T) {
+	t.Setenv(MagicCookieKey, MagicCookieValue)
+	t.Setenv(AuthTokenKey, "")
+	if err := Serve(testPlugin{}); err == nil || !strings.Contains(err.Error(), "auth token") {
+		t.Fatalf("Serve() error = %v, want a missing auth token", err)
+	}
+}
+
+func TestParseHandshakeRejectsOldProtocol(t *testing.T) {
+	if _, _, err := parseHandshake("1|tcp|127.0.0.1:1234|jsonrpc\n"); err == nil {
+		t.Fatal("parseHandshake() accepted protocol version 1, which has no auth token")
+	}
+	network, address, err := parseHandshake("2|tcp|127.0.0.1:1234|jsonrpc\n")
+	if err != nil || network != "tcp" || address != "127.0.0.1:1234" {
+		t.Fatalf("parseHandshake() = %q, %q, %v", network, address, err)
+	}
+}
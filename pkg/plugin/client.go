@@ -0,0 +1,238 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/semantrix/semaroute/pkg/api/v1"
+)
+
+// Client manages a running plugin process and its RPC connection.
+type Client struct {
+	path string
+	cmd  *exec.Cmd
+	rpc  *rpc.Client
+	name string
+}
+
+// Discover returns the executable files in dir, sorted by name.
+func Discover(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if info.Mode().Perm()&0111 == 0 {
+			continue // Not executable
+		}
+		paths = append(paths, filepath.Join(dir, entry.Name()))
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Start launches the plugin binary at path and completes the handshake.
+func Start(path string, handshakeTimeout time.Duration) (*Client, error) {
+	cmd, conn, err := launch(path, handshakeTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &Client{
+		path: path,
+		cmd:  cmd,
+		rpc:  jsonrpc.NewClient(conn),
+	}
+
+	if err := client.rpc.Call(serviceName+".Name", Empty{}, &client.name); err != nil {
+		client.Kill()
+		return nil, fmt.Errorf("failed to query plugin name: %w", err)
+	}
+	if client.name == "" {
+		client.Kill()
+		return nil, fmt.Errorf("plugin %s returned an empty name", path)
+	}
+
+	return client, nil
+}
+
+// launch starts the plugin binary at path, waits for its handshake line and
+// connects to the address it announces, authenticating with a token only
+// this launch knows.
+func launch(path string, handshakeTimeout time.Duration) (*exec.Cmd, net.Conn, error) {
+	token, err := newAuthToken()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cmd := exec.Command(path)
+	cmd.Env = append(os.Environ(), MagicCookieKey+"="+MagicCookieValue, AuthTokenKey+"="+token)
+	cmd.Stderr = os.Stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to attach to plugin stdout: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failed to start plugin: %w", err)
+	}
+
+	// Wait for the handshake line
+	lineChan := make(chan string, 1)
+	errChan := make(chan error, 1)
+	go func() {
+		reader := bufio.NewReader(stdout)
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			errChan <- err
+			return
+		}
+		lineChan <- line
+
+		// Keep draining stdout so a chatty plugin never blocks on a full pipe
+		io.Copy(os.Stderr, reader)
+	}()
+
+	var line string
+	select {
+	case line = <-lineChan:
+	case err := <-errChan:
+		cmd.Process.Kill()
+		return nil, nil, fmt.Errorf("plugin exited before handshake: %w", err)
+	case <-time.After(handshakeTimeout):
+		cmd.Process.Kill()
+		return nil, nil, fmt.Errorf("plugin handshake timed out after %v", handshakeTimeout)
+	}
+
+	network, address, err := parseHandshake(line)
+	if err != nil {
+		cmd.Process.Kill()
+		return nil, nil, err
+	}
+
+	conn, err := net.DialTimeout(network, address, handshakeTimeout)
+	if err != nil {
+		cmd.Process.Kill()
+		return nil, nil, fmt.Errorf("failed to connect to plugin: %w", err)
+	}
+	if err := sendAuthToken(conn, token, handshakeTimeout); err != nil {
+		conn.Close()
+		cmd.Process.Kill()
+		return nil, nil, err
+	}
+
+	return cmd, conn, nil
+}
+
+// parseHandshake parses the "<version>|<network>|<address>|jsonrpc" handshake line.
+func parseHandshake(line string) (network, address string, err error) {
+	parts := strings.Split(strings.TrimSpace(line), "|")
+	if len(parts) != 4 {
+		return "", "", fmt.Errorf("invalid plugin handshake: %q", line)
+	}
+
+	version, err := strconv.Atoi(parts[0])
+	if err != nil || version != ProtocolVersion {
+		return "", "", fmt.Errorf("unsupported plugin protocol version %q (expected %d)", parts[0], ProtocolVersion)
+	}
+	if parts[3] != "jsonrpc" {
+		return "", "", fmt.Errorf("unsupported plugin RPC protocol %q", parts[3])
+	}
+
+	return parts[1], parts[2], nil
+}
+
+// Name returns the provider name reported by the plugin.
+func (c *Client) Name() string {
+	return c.name
+}
+
+// Path returns the path of the plugin binary.
+func (c *Client) Path() string {
+	return c.path
+}
+
+// Configure sends the provider configuration to the plugin.
+func (c *Client) Configure(config Config) error {
+	return c.rpc.Call(serviceName+".Configure", config, &Empty{})
+}
+
+// Models returns the models served by the plugin.
+func (c *Client) Models() ([]string, error) {
+	var models []string
+	err := c.rpc.Call(serviceName+".Models", Empty{}, &models)
+	return models, err
+}
+
+// CostEstimate returns the plugin's cost estimate for a request.
+func (c *Client) CostEstimate(req v1.ChatCompletionRequest) (float64, error) {
+	var cost float64
+	err := c.rpc.Call(serviceName+".CostEstimate", req, &cost)
+	return cost, err
+}
+
+// LatencyEstimate returns the plugin's latency estimate for a request.
+func (c *Client) LatencyEstimate(req v1.ChatCompletionRequest) (time.Duration, error) {
+	var latency time.Duration
+	err := c.rpc.Call(serviceName+".LatencyEstimate", req, &latency)
+	return latency, err
+}
+
+// ChatCompletion executes a chat completion in the plugin, honoring ctx cancellation.
+func (c *Client) ChatCompletion(ctx context.Context, req v1.ChatCompletionRequest) (*v1.ChatCompletionResponse, error) {
+	args := ChatArgs{Request: req}
+	if deadline, ok := ctx.Deadline(); ok {
+		args.Deadline = deadline
+	}
+
+	var response v1.ChatCompletionResponse
+	call := c.rpc.Go(serviceName+".ChatCompletion", args, &response, make(chan *rpc.Call, 1))
+
+	select {
+	case <-call.Done:
+		if call.Error != nil {
+			return nil, call.Error
+		}
+		return &response, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Kill closes the RPC connection and terminates the plugin process.
+func (c *Client) Kill() error {
+	if c.rpc != nil {
+		c.rpc.Close()
+	}
+	if c.cmd.Process == nil {
+		return nil
+	}
+	if err := c.cmd.Process.Kill(); err != nil {
+		return err
+	}
+	c.cmd.Wait()
+	return nil
+}
@@ -0,0 +1,92 @@
+// Package plugin implements out-of-process provider and routing policy
+// plugins for semaroute.
+//
+// Plugins are standalone executables. The router launches each binary found
+// in the configured plugin directory, the plugin announces the address it is
+// listening on with a single handshake line on stdout, and the router then
+// talks to it over JSON-RPC. The handshake follows the hashicorp/go-plugin
+// layout so the same operational model applies:
+//
+//	<protocol version>|<network>|<address>|jsonrpc
+//
+// Stdout is reserved for the handshake; plugins should log to stderr.
+//
+// Anything on the host can connect to the announced address, so the router
+// authenticates itself. Each launch gets a fresh random token in the
+// SEMAROUTE_PLUGIN_AUTH_TOKEN environment variable, and the router sends it,
+// followed by a newline, as the first bytes on the connection. The plugin
+// closes connections that do not start with the token before reading any
+// JSON-RPC from them.
+//
+// Provider plugins call Serve and routing policy plugins call ServePolicy. A
+// routing policy can also run as a standalone service with
+// ListenAndServePolicy, which the router dials instead of launching.
+package plugin
+
+import (
+	"time"
+
+	v1 "github.com/semantrix/semaroute/pkg/api/v1"
+)
+
+const (
+	// ProtocolVersion is the plugin protocol version spoken by this package.
+	// Version 2 added the auth token.
+	//
+	// The transport is JSON-RPC over net/rpc, not the gRPC transport of
+	// hashicorp/go-plugin: plugins need no protobuf toolchain or generated
+	// stubs, and one in another language only needs a JSON-RPC client. The
+	// handshake's last field names the transport, so a gRPC transport can be
+	// added beside it later.
+	ProtocolVersion = 2
+
+	// MagicCookieKey and MagicCookieValue guard against plugins being executed directly.
+	MagicCookieKey   = "SEMAROUTE_PLUGIN_MAGIC_COOKIE"
+	MagicCookieValue = "5a1d0f3e-semaroute-provider-plugin"
+
+	// AuthTokenKey is the environment variable carrying the token the router
+	// authenticates with to a plugin it launched.
+	AuthTokenKey = "SEMAROUTE_PLUGIN_AUTH_TOKEN"
+
+	// serviceName is the RPC service name plugins register under.
+	serviceName = "Provider"
+)
+
+// Config is the provider configuration passed to a plugin after startup.
+type Config struct {
+	APIKey  string            `json:"api_key,omitempty"`
+	BaseURL string            `json:"base_url,omitempty"`
+	Timeout time.Duration     `json:"timeout,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"` // static headers for outbound requests
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// ProviderPlugin is the interface provider plugins implement.
+type ProviderPlugin interface {
+	// Name returns the unique provider name the plugin registers as.
+	Name() string
+
+	// Configure applies the provider configuration from the router.
+	Configure(config Config) error
+
+	// Models returns the list of models served by the plugin.
+	Models() ([]string, error)
+
+	// CostEstimate returns an estimated cost for the request.
+	CostEstimate(req v1.ChatCompletionRequest) (float64, error)
+
+	// LatencyEstimate returns an estimated latency for the request.
+	LatencyEstimate(req v1.ChatCompletionRequest) (time.Duration, error)
+
+	// ChatCompletion creates a synchronous chat completion.
+	ChatCompletion(req v1.ChatCompletionRequest, deadline time.Time) (*v1.ChatCompletionResponse, error)
+}
+
+// Empty is used for RPC methods without arguments.
+type Empty struct{}
+
+// ChatArgs carries a chat completion request and its deadline over RPC.
+type ChatArgs struct {
+	Request  v1.ChatCompletionRequest `json:"request"`
+	Deadline time.Time                `json:"deadline,omitempty"`
+}
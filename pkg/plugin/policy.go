@@ -0,0 +1,222 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"time"
+
+	v1 "github.com/semantrix/semaroute/pkg/api/v1"
+)
+
+// policyServiceName is the RPC service name routing policy plugins register
+// under.
+const policyServiceName = "Policy"
+
+// Candidate is a provider a routing policy plugin may choose.
+type Candidate struct {
+	Name            string        `json:"name"`
+	Healthy         bool          `json:"healthy"`
+	Models          []string      `json:"models,omitempty"`
+	CostEstimate    float64       `json:"cost_estimate,omitempty"`    // for the request, if known
+	LatencyEstimate time.Duration `json:"latency_estimate,omitempty"` // for the request, if known
+}
+
+// RouteArgs asks a routing policy plugin to route a request.
+type RouteArgs struct {
+	Request    v1.ChatCompletionRequest `json:"request"`
+	Tenant     string                   `json:"tenant,omitempty"`
+	Headers    map[string]string        `json:"headers,omitempty"` // first value of each request header
+	Candidates []Candidate              `json:"candidates"`
+	Deadline   time.Time                `json:"deadline,omitempty"`
+}
+
+// RouteDecision is a routing policy plugin's decision. A plugin that has no
+// opinion on a request sets Defer, and the router's fallback policy routes it.
+type RouteDecision struct {
+	Provider   string  `json:"provider,omitempty"`
+	Model      string  `json:"model,omitempty"` // empty keeps the requested model
+	Reason     string  `json:"reason,omitempty"`
+	Confidence float64 `json:"confidence,omitempty"`
+	Defer      bool    `json:"defer,omitempty"`
+}
+
+// RouteOutcome reports how a routed request went.
+type RouteOutcome struct {
+	Provider string        `json:"provider"`
+	Model    string        `json:"model"`
+	Success  bool          `json:"success"`
+	Latency  time.Duration `json:"latency"`
+}
+
+// PolicyPlugin is the interface routing policy plugins implement.
+type PolicyPlugin interface {
+	// Name returns the policy name, shown in routing decisions.
+	Name() string
+
+	// Configure applies the options from the router's policy configuration.
+	Configure(options map[string]interface{}) error
+
+	// Route decides which candidate serves the request.
+	Route(args RouteArgs) (RouteDecision, error)
+
+	// Observe receives the outcome of a request the router routed, for
+	// policies that learn from results.
+	Observe(outcome RouteOutcome)
+}
+
+// ServePolicy runs a routing policy plugin launched by the router until the
+// router disconnects. It is meant to be called from the plugin binary's main
+// function.
+func ServePolicy(impl PolicyPlugin) error {
+	if os.Getenv(MagicCookieKey) != MagicCookieValue {
+		return fmt.Errorf("this binary is a semaroute plugin and must be launched by semaroute")
+	}
+	return serve(policyServiceName, &policyRPCServer{impl: impl})
+}
+
+// ListenAndServePolicy serves a routing policy plugin as a standalone
+// service at address, for routers configured to dial it rather than launch
+// it. Each router connection is served until it closes.
+func ListenAndServePolicy(address string, impl PolicyPlugin) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName(policyServiceName, &policyRPCServer{impl: impl}); err != nil {
+		return fmt.Errorf("failed to register plugin service: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	defer listener.Close()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return fmt.Errorf("failed to accept router connection: %w", err)
+		}
+		go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
+
+// policyRPCServer exposes a PolicyPlugin as net/rpc methods.
+type policyRPCServer struct {
+	impl PolicyPlugin
+}
+
+func (s *policyRPCServer) Name(args Empty, reply *string) error {
+	*reply = s.impl.Name()
+	return nil
+}
+
+func (s *policyRPCServer) Configure(args map[string]interface{}, reply *Empty) error {
+	return s.impl.Configure(args)
+}
+
+func (s *policyRPCServer) Route(args RouteArgs, reply *RouteDecision) error {
+	decision, err := s.impl.Route(args)
+	if err != nil {
+		return err
+	}
+	*reply = decision
+	return nil
+}
+
+func (s *policyRPCServer) Observe(args RouteOutcome, reply *Empty) error {
+	s.impl.Observe(args)
+	return nil
+}
+
+// PolicyClient talks to a routing policy plugin, either launched by the
+// router or running as a service.
+type PolicyClient struct {
+	cmd  *exec.Cmd // nil for a dialed service
+	rpc  *rpc.Client
+	name string
+}
+
+// StartPolicy launches the routing policy plugin binary at path and completes
+// the handshake.
+func StartPolicy(path string, handshakeTimeout time.Duration) (*PolicyClient, error) {
+	cmd, conn, err := launch(path, handshakeTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return newPolicyClient(cmd, conn)
+}
+
+// DialPolicy connects to a routing policy plugin service at address.
+func DialPolicy(address string, timeout time.Duration) (*PolicyClient, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to policy plugin: %w", err)
+	}
+	return newPolicyClient(nil, conn)
+}
+
+// newPolicyClient queries the plugin's name over conn.
+func newPolicyClient(cmd *exec.Cmd, conn net.Conn) (*PolicyClient, error) {
+	client := &PolicyClient{cmd: cmd, rpc: jsonrpc.NewClient(conn)}
+	if err := client.rpc.Call(policyServiceName+".Name", Empty{}, &client.name); err != nil {
+		client.Kill()
+		return nil, fmt.Errorf("failed to query plugin name: %w", err)
+	}
+	if client.name == "" {
+		client.Kill()
+		return nil, fmt.Errorf("policy plugin returned an empty name")
+	}
+	return client, nil
+}
+
+// Name returns the policy name reported by the plugin.
+func (c *PolicyClient) Name() string {
+	return c.name
+}
+
+// Configure sends the policy options to the plugin.
+func (c *PolicyClient) Configure(options map[string]interface{}) error {
+	return c.rpc.Call(policyServiceName+".Configure", options, &Empty{})
+}
+
+// Route asks the plugin to route a request, honoring ctx cancellation.
+func (c *PolicyClient) Route(ctx context.Context, args RouteArgs) (RouteDecision, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		args.Deadline = deadline
+	}
+
+	var decision RouteDecision
+	call := c.rpc.Go(policyServiceName+".Route", args, &decision, make(chan *rpc.Call, 1))
+
+	select {
+	case <-call.Done:
+		if call.Error != nil {
+			return RouteDecision{}, call.Error
+		}
+		return decision, nil
+	case <-ctx.Done():
+		return RouteDecision{}, ctx.Err()
+	}
+}
+
+// Observe reports the outcome of a request without waiting for the plugin.
+func (c *PolicyClient) Observe(outcome RouteOutcome) {
+	c.rpc.Go(policyServiceName+".Observe", outcome, &Empty{}, make(chan *rpc.Call, 1))
+}
+
+// Kill closes the RPC connection and, if the router launched the plugin,
+// terminates its process.
+func (c *PolicyClient) Kill() error {
+	c.rpc.Close()
+	if c.cmd == nil || c.cmd.Process == nil {
+		return nil
+	}
+	if err := c.cmd.Process.Kill(); err != nil {
+		return err
+	}
+	c.cmd.Wait()
+	return nil
+}
@@ -0,0 +1,118 @@
+package plugin
+
+import (
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"time"
+
+	v1 "github.com/semantrix/semaroute/pkg/api/v1"
+)
+
+// Serve runs a provider plugin until the router disconnects. It is meant to
+// be called from the plugin binary's main function.
+func Serve(impl ProviderPlugin) error {
+	if os.Getenv(MagicCookieKey) != MagicCookieValue {
+		return fmt.Errorf("this binary is a semaroute plugin and must be launched by semaroute")
+	}
+
+	return serve(serviceName, &rpcServer{impl: impl})
+}
+
+// serve registers receiver as the named RPC service, announces a local
+// address with the handshake line and serves the router's connection.
+func serve(name string, receiver interface{}) error {
+	// The token is removed so processes the plugin starts do not inherit it
+	token := os.Getenv(AuthTokenKey)
+	os.Unsetenv(AuthTokenKey)
+	if !validAuthToken(token) {
+		return fmt.Errorf("missing plugin auth token; the plugin must be launched by a semaroute speaking protocol version %d", ProtocolVersion)
+	}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName(name, receiver); err != nil {
+		return fmt.Errorf("failed to register plugin service: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	defer listener.Close()
+
+	// Announce the address to the router
+	fmt.Printf("%d|%s|%s|jsonrpc\n", ProtocolVersion, listener.Addr().Network(), listener.Addr().String())
+
+	return serveRouter(listener, token, server)
+}
+
+// serveRouter accepts connections until one authenticates with the token,
+// and serves the RPC server on it. The router holds a single connection for
+// the plugin's lifetime.
+func serveRouter(listener net.Listener, token string, server *rpc.Server) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return fmt.Errorf("failed to accept router connection: %w", err)
+		}
+		if !authenticate(conn, token) {
+			fmt.Fprintf(os.Stderr, "plugin: rejected unauthenticated connection from %s\n", conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
+		server.ServeCodec(jsonrpc.NewServerCodec(conn))
+		return nil
+	}
+}
+
+// rpcServer exposes a ProviderPlugin as net/rpc methods.
+type rpcServer struct {
+	impl ProviderPlugin
+}
+
+func (s *rpcServer) Name(args Empty, reply *string) error {
+	*reply = s.impl.Name()
+	return nil
+}
+
+func (s *rpcServer) Configure(args Config, reply *Empty) error {
+	return s.impl.Configure(args)
+}
+
+func (s *rpcServer) Models(args Empty, reply *[]string) error {
+	models, err := s.impl.Models()
+	if err != nil {
+		return err
+	}
+	*reply = models
+	return nil
+}
+
+func (s *rpcServer) CostEstimate(args v1.ChatCompletionRequest, reply *float64) error {
+	cost, err := s.impl.CostEstimate(args)
+	if err != nil {
+		return err
+	}
+	*reply = cost
+	return nil
+}
+
+func (s *rpcServer) LatencyEstimate(args v1.ChatCompletionRequest, reply *time.Duration) error {
+	latency, err := s.impl.LatencyEstimate(args)
+	if err != nil {
+		return err
+	}
+	*reply = latency
+	return nil
+}
+
+func (s *rpcServer) ChatCompletion(args ChatArgs, reply *v1.ChatCompletionResponse) error {
+	response, err := s.impl.ChatCompletion(args.Request, args.Deadline)
+	if err != nil {
+		return err
+	}
+	*reply = *response
+	return nil
+}
@@ -0,0 +1,50 @@
+#!/bin/sh
+# Checks an edge build against the sidecar budget: time from launch until
+# /health answers, and resident memory once idle.
+#
+#   scripts/edge-check.sh [binary] [config]
+#
+# Limits can be overridden with MAX_STARTUP_MS and MAX_RSS_KB.
+set -eu
+
+BINARY=${1:-bin/semaroute-edge}
+CONFIG=${2:-config.edge.yaml}
+PORT=${PORT:-8080} # server.port of the config
+MAX_STARTUP_MS=${MAX_STARTUP_MS:-250}
+MAX_RSS_KB=${MAX_RSS_KB:-20480}
+
+OPENAI_API_KEY=${OPENAI_API_KEY:-edge-check} \
+	"$BINARY" -config "$CONFIG" -profile edge >/dev/null 2>&1 &
+PID=$!
+trap 'kill $PID 2>/dev/null || true' EXIT
+
+start=$(date +%s%N)
+i=0
+until curl -sf -o /dev/null "http://localhost:$PORT/health"; do
+	i=$((i + 1))
+	if [ $i -gt 1000 ] || ! kill -0 $PID 2>/dev/null; then
+		echo "FAIL: server did not become healthy" >&2
+		exit 1
+	fi
+	sleep 0.005
+done
+startup_ms=$(( ($(date +%s%N) - start) / 1000000 ))
+
+# Let the initial health checks settle before sampling memory
+sleep 2
+rss_kb=$(awk '/^VmRSS:/ {print $2}' /proc/$PID/status)
+
+echo "startup: ${startup_ms}ms (limit ${MAX_STARTUP_MS}ms)"
+echo "idle RSS: ${rss_kb}KB (limit ${MAX_RSS_KB}KB)"
+echo "binary: $(wc -c <"$BINARY") bytes"
+
+status=0
+if [ "$startup_ms" -gt "$MAX_STARTUP_MS" ]; then
+	echo "FAIL: startup over budget" >&2
+	status=1
+fi
+if [ "$rss_kb" -gt "$MAX_RSS_KB" ]; then
+	echo "FAIL: idle memory over budget" >&2
+	status=1
+fi
+exit $status